@@ -0,0 +1,84 @@
+package insider
+
+import "sort"
+
+// AdjustedStanding corrects a team's points for the opponents it has faced.
+// ExpectedPoints is what the simulation model would have given the team in the
+// matches it played; PointsPerExpected above 1 means it did better than that.
+type AdjustedStanding struct {
+	Standing
+	ExpectedPoints      float64 `json:"expected_points"`
+	PointsPerExpected   float64 `json:"points_per_expected_point"`
+	AvgOpponentStrength float64 `json:"avg_opponent_strength"`
+}
+
+// AdjustedStandings is the table ordered by points per expected point.
+// Teams that have not played yet go to the bottom. Ranks, zones and places
+// follow this order.
+func (l *League) AdjustedStandings() ([]AdjustedStanding, error) {
+	standings, err := l.CalculateStandings()
+	if err != nil {
+		return nil, err
+	}
+
+	strengths := make(map[int]int)
+	names := make(map[int]string)
+	for _, t := range l.Teams() {
+		strengths[t.ID] = t.Strength
+		names[t.ID] = t.Name
+	}
+	cfg := l.config()
+	advantages, err := l.homeAdvantages()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := l.db.Query("SELECT home_team_id, away_team_id FROM matches WHERE played = TRUE AND COALESCE(administrative, '') != 'annulled'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expected := make(map[int]float64)
+	opponents := make(map[int]int)
+	for rows.Next() {
+		var home, away int
+		if err := rows.Scan(&home, &away); err != nil {
+			return nil, err
+		}
+		homeWin, draw, awayWin := advantages.params(cfg.Simulation, names[home]).Probabilities(strengths[home], strengths[away])
+		expected[home] += cfg.Sport.expectedPoints(homeWin, draw, awayWin)
+		expected[away] += cfg.Sport.expectedPoints(awayWin, draw, homeWin)
+		opponents[home] += strengths[away]
+		opponents[away] += strengths[home]
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	table := make([]AdjustedStanding, 0, len(standings))
+	for _, s := range standings {
+		row := AdjustedStanding{Standing: s, ExpectedPoints: expected[s.TeamID]}
+		if s.Played > 0 {
+			row.AvgOpponentStrength = float64(opponents[s.TeamID]) / float64(s.Played)
+		}
+		if expected[s.TeamID] > 0 {
+			row.PointsPerExpected = float64(s.Points) / expected[s.TeamID]
+		}
+		table = append(table, row)
+	}
+
+	// standings are already in table order, so ties keep it
+	sort.SliceStable(table, func(i, j int) bool {
+		a, b := table[i], table[j]
+		if (a.Played > 0) != (b.Played > 0) {
+			return a.Played > 0
+		}
+		return a.PointsPerExpected > b.PointsPerExpected
+	})
+	for i := range table {
+		table[i].Rank = i + 1
+	}
+	l.placeRows(len(table), func(i int) *Standing { return &table[i].Standing })
+	return table, nil
+}
@@ -0,0 +1,258 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Administrative decisions overrule the pitch: a match is awarded to one
+// side, annulled so it counts for nothing, or ordered to be replayed. Unlike
+// a score edit each one needs a reason, is kept in an audit trail with the
+// result it replaced, and leaves a flag on the match.
+
+// Administrative actions, the match flag is the past tense
+const (
+	ActionAward  = "award"
+	ActionAnnul  = "annul"
+	ActionReplay = "replay"
+
+	AdminAwarded       = "awarded"
+	AdminAnnulled      = "annulled"
+	AdminReplayOrdered = "replay_ordered"
+)
+
+// awardedGoals is the winner's score in an awarded match, 3-0 as in football
+const awardedGoals = 3
+
+// ErrInvalidDecision is returned for a decision that cannot be applied
+var ErrInvalidDecision = errors.New("invalid administrative decision")
+
+// AdministrativeDecision is one entry of the audit trail. PreviousResult is
+// the score the decision replaced, if the match had one.
+type AdministrativeDecision struct {
+	ID             int       `json:"id"`
+	MatchID        int       `json:"match_id"`
+	Action         string    `json:"action"`
+	AwardedTo      string    `json:"awarded_to,omitempty"`
+	Reason         string    `json:"reason"`
+	PreviousResult string    `json:"previous_result,omitempty"`
+	Result         string    `json:"result,omitempty"`
+	DecidedAt      time.Time `json:"decided_at"`
+}
+
+// DecisionRequest is the body of POST /matches/{id}/administrative. Winner,
+// "home" or "away", is only for awards.
+type DecisionRequest struct {
+	Action string `json:"action"`
+	Winner string `json:"winner,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (l *League) createAdministrativeTable() error {
+	createDecisions := `
+	CREATE TABLE IF NOT EXISTS administrative_decisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+		action TEXT NOT NULL,
+		awarded_to_team_id INTEGER REFERENCES teams(id) ON DELETE RESTRICT,
+		reason TEXT NOT NULL,
+		previous_result TEXT,
+		result TEXT,
+		decided_at TIMESTAMP NOT NULL
+	);`
+
+	if _, err := l.db.Exec(createDecisions); err != nil {
+		return fmt.Errorf("error creating administrative_decisions table: %v", err)
+	}
+	return nil
+}
+
+func (d DecisionRequest) validate() error {
+	if d.Reason == "" {
+		return fmt.Errorf("%w: a reason is required", ErrInvalidDecision)
+	}
+	switch d.Action {
+	case ActionAward:
+		if d.Winner != "home" && d.Winner != "away" {
+			return fmt.Errorf("%w: winner must be home or away", ErrInvalidDecision)
+		}
+	case ActionAnnul, ActionReplay:
+		if d.Winner != "" {
+			return fmt.Errorf("%w: only an award has a winner", ErrInvalidDecision)
+		}
+	default:
+		return fmt.Errorf("%w: action must be %s, %s or %s", ErrInvalidDecision, ActionAward, ActionAnnul, ActionReplay)
+	}
+	return nil
+}
+
+// Decide applies an administrative decision to a match. An award sets the
+// awarded score, an annulment keeps the match on record but out of the
+// table, and a replay clears the result and events so the match is played
+// again. Standings follow at once.
+func (l *League) Decide(matchID int, req DecisionRequest) (*AdministrativeDecision, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	m, err := scanMatch(tx.QueryRow(matchSelect+" WHERE m.id = ?", matchID))
+	if err != nil {
+		return nil, err
+	}
+	d := &AdministrativeDecision{MatchID: m.ID, Action: req.Action, Reason: req.Reason, DecidedAt: time.Now().UTC()}
+	if m.Played && m.Administrative != AdminAnnulled {
+		d.PreviousResult = fmt.Sprintf("%d-%d", m.HomeGoals, m.AwayGoals)
+	}
+
+	var awardedTo *int
+	switch req.Action {
+	case ActionAward:
+		homeGoals, awayGoals, winner := awardedGoals, 0, m.HomeTeamID
+		d.AwardedTo = m.HomeTeam
+		if req.Winner == "away" {
+			homeGoals, awayGoals, winner = 0, awardedGoals, m.AwayTeamID
+			d.AwardedTo = m.AwayTeam
+		}
+		awardedTo = &winner
+		d.Result = fmt.Sprintf("%d-%d", homeGoals, awayGoals)
+		_, err = tx.Exec(`
+			UPDATE matches SET home_goals = ?, away_goals = ?, played = TRUE, postponed = FALSE, live = FALSE, minute = 0,
+				administrative = ? WHERE id = ?`,
+			homeGoals, awayGoals, AdminAwarded, m.ID)
+	case ActionAnnul:
+		// an annulled match is over, whether it was played or not
+		_, err = tx.Exec(`
+			UPDATE matches SET played = TRUE, postponed = FALSE, live = FALSE, minute = 0, administrative = ? WHERE id = ?`,
+			AdminAnnulled, m.ID)
+	case ActionReplay:
+		if !m.Played && !m.Live {
+			return nil, fmt.Errorf("%w: match %d has not been played", ErrInvalidDecision, m.ID)
+		}
+		_, err = tx.Exec(`
+			UPDATE matches SET home_goals = 0, away_goals = 0, played = FALSE, live = FALSE, minute = 0,
+				commentary = '', administrative = ? WHERE id = ?`,
+			AdminReplayOrdered, m.ID)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM match_events WHERE match_id = ?", m.ID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO administrative_decisions (match_id, action, awarded_to_team_id, reason, previous_result, result, decided_at)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?)`,
+		d.MatchID, d.Action, awardedTo, d.Reason, d.PreviousResult, d.Result, d.DecidedAt)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	d.ID = int(id)
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	l.touch()
+	l.afterResult()
+	return d, nil
+}
+
+// Decisions lists the audit trail oldest first, for one match when matchID
+// is not 0
+func (l *League) Decisions(matchID int) ([]AdministrativeDecision, error) {
+	query := `
+		SELECT d.id, d.match_id, d.action, COALESCE(t.name, ''), d.reason,
+			COALESCE(d.previous_result, ''), COALESCE(d.result, ''), d.decided_at
+		FROM administrative_decisions d
+		LEFT JOIN teams t ON t.id = d.awarded_to_team_id`
+	args := []any{}
+	if matchID != 0 {
+		query += " WHERE d.match_id = ?"
+		args = append(args, matchID)
+	}
+	rows, err := l.db.Query(query+" ORDER BY d.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	decisions := []AdministrativeDecision{}
+	for rows.Next() {
+		var d AdministrativeDecision
+		if err := rows.Scan(&d.ID, &d.MatchID, &d.Action, &d.AwardedTo, &d.Reason, &d.PreviousResult, &d.Result, &d.DecidedAt); err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, rows.Err()
+}
+
+// GET /matches/{id}/administrative lists the decisions on a match, POST
+// makes one (admin token)
+func (l *League) handleMatchDecisions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		decisions, err := l.Decisions(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(decisions)
+	case http.MethodPost:
+		if !isAdmin(r) {
+			http.Error(w, "Admin token required", http.StatusUnauthorized)
+			return
+		}
+		var req DecisionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		decision, err := l.Decide(id, req)
+		switch {
+		case err == sql.ErrNoRows:
+			http.Error(w, "Match not found", http.StatusNotFound)
+			return
+		case errors.Is(err, ErrInvalidDecision):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(decision)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /administrative is the whole audit trail
+func (l *League) handleDecisions(w http.ResponseWriter, r *http.Request) {
+	decisions, err := l.Decisions(0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(decisions)
+}
@@ -0,0 +1,65 @@
+package insider_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestAdministrativeDecisions(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 1)
+	h.SimulateWeek(1)
+
+	var week []insider.Match
+	h.Get("/matches?week=1", &week)
+	if len(week) < 2 {
+		t.Fatalf("%d matches in week 1, need two", len(week))
+	}
+	awarded, annulled := week[0], week[1]
+
+	if status := h.Do(http.MethodPost, "/matches/1/administrative", insider.DecisionRequest{Action: insider.ActionAnnul}, true, nil); status != http.StatusBadRequest {
+		t.Errorf("decision without a reason: status %d", status)
+	}
+	h.Post(fmt.Sprintf("/matches/%d/administrative", awarded.ID), insider.DecisionRequest{Action: insider.ActionAward, Winner: "away", Reason: "ineligible player"}, nil)
+	h.Post(fmt.Sprintf("/matches/%d/administrative", annulled.ID), insider.DecisionRequest{Action: insider.ActionAnnul, Reason: "abandoned"}, nil)
+
+	played := make(map[string]int)
+	points := make(map[string]int)
+	for _, s := range h.Standings() {
+		played[s.TeamName], points[s.TeamName] = s.Played, s.Points
+	}
+	if points[awarded.AwayTeam] < 3 {
+		t.Errorf("%s has %d points after being awarded a match", awarded.AwayTeam, points[awarded.AwayTeam])
+	}
+	total := 0
+	for _, n := range played {
+		total += n
+	}
+	if want := 2 * (len(week) - 1); total != want {
+		t.Errorf("%d team games counted, want %d without the annulled match", total, want)
+	}
+
+	var detail insider.MatchDetail
+	h.Get(fmt.Sprintf("/matches/%d", awarded.ID), &detail)
+	if detail.Administrative != insider.AdminAwarded || detail.AwayGoals != insider.AwardedGoals || detail.HomeGoals != 0 {
+		t.Errorf("awarded match is %s %d-%d", detail.Administrative, detail.HomeGoals, detail.AwayGoals)
+	}
+	if len(detail.Decisions) != 1 || detail.Decisions[0].PreviousResult == "" {
+		t.Errorf("audit trail %+v", detail.Decisions)
+	}
+
+	h.Post(fmt.Sprintf("/matches/%d/administrative", awarded.ID), insider.DecisionRequest{Action: insider.ActionReplay, Reason: "appeal upheld"}, nil)
+	h.Get(fmt.Sprintf("/matches/%d", awarded.ID), &detail)
+	if detail.Played || detail.Administrative != insider.AdminReplayOrdered || len(detail.Events) != 0 {
+		t.Errorf("replayed match: played=%v flag=%s events=%d", detail.Played, detail.Administrative, len(detail.Events))
+	}
+
+	var trail []insider.AdministrativeDecision
+	h.Get("/administrative", &trail)
+	if len(trail) != 3 {
+		t.Errorf("%d decisions in the audit trail, want 3", len(trail))
+	}
+}
@@ -0,0 +1,179 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrTeamNameTaken is returned when a rename would clash with another team
+var ErrTeamNameTaken = errors.New("team name already in use")
+
+// TeamAlias is a name a team used before a rename
+type TeamAlias struct {
+	Name      string    `json:"name"`
+	RenamedAt time.Time `json:"renamed_at"`
+}
+
+func (l *League) createAliasTable() error {
+	createAliases := `
+	CREATE TABLE IF NOT EXISTS team_aliases (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
+		name TEXT,
+		renamed_at TIMESTAMP
+	);`
+
+	if _, err := l.db.Exec(createAliases); err != nil {
+		return fmt.Errorf("error creating team_aliases table: %v", err)
+	}
+	return nil
+}
+
+// resolveTeam finds a team by its current name or any former one and returns
+// its stable id and current name. Current names win over aliases.
+func (l *League) resolveTeam(name string) (int, string, error) {
+	return resolveTeamIn(l.db, name)
+}
+
+// resolveTeamIn is resolveTeam within a transaction
+func resolveTeamIn(q interface {
+	QueryRow(string, ...any) *sql.Row
+}, name string) (int, string, error) {
+	var id int
+	var current string
+	err := q.QueryRow("SELECT id, name FROM teams WHERE name = ?", name).Scan(&id, &current)
+	if err == sql.ErrNoRows {
+		err = q.QueryRow(`
+			SELECT t.id, t.name FROM team_aliases a
+			JOIN teams t ON t.id = a.team_id
+			WHERE a.name = ?
+			ORDER BY a.renamed_at DESC LIMIT 1`, name).Scan(&id, &current)
+	}
+	return id, current, err
+}
+
+// RenameTeam gives a team a new name. The old one is kept as an alias; matches
+// reference the team id, so they follow the rename.
+func (l *League) RenameTeam(oldName, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("new name cannot be empty")
+	}
+
+	id, current, err := l.resolveTeam(oldName)
+	if err != nil {
+		return err
+	}
+	if current == newName {
+		return nil
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var taken int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM teams WHERE name = ?", newName).Scan(&taken); err != nil {
+		return err
+	}
+	if taken > 0 {
+		return fmt.Errorf("%w: %s", ErrTeamNameTaken, newName)
+	}
+
+	_, err = tx.Exec("INSERT INTO team_aliases (team_id, name, renamed_at) VALUES (?, ?, ?)", id, current, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE teams SET name = ? WHERE id = ?", newName, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.touch()
+	return l.loadTeams()
+}
+
+// TeamAliases lists the former names of a team, most recent first
+func (l *League) TeamAliases(name string) (string, []TeamAlias, error) {
+	id, current, err := l.resolveTeam(name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	rows, err := l.db.Query("SELECT name, renamed_at FROM team_aliases WHERE team_id = ? ORDER BY renamed_at DESC, id DESC", id)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+
+	aliases := []TeamAlias{}
+	for rows.Next() {
+		var a TeamAlias
+		if err := rows.Scan(&a.Name, &a.RenamedAt); err != nil {
+			return "", nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return current, aliases, rows.Err()
+}
+
+func (l *League) handleRenameTeam(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(body.Name) == "" {
+		http.Error(w, "New name cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	oldName := r.PathValue("name")
+	err := l.RenameTeam(oldName, body.Name)
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrTeamNameTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("%s renamed to %s", oldName, body.Name)})
+}
+
+func (l *League) handleTeamAliases(w http.ResponseWriter, r *http.Request) {
+	current, aliases, err := l.TeamAliases(r.PathValue("name"))
+	if err == sql.ErrNoRows {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"team":    current,
+		"aliases": aliases,
+	})
+}
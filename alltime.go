@@ -0,0 +1,308 @@
+package insider
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Every archived fixture counts as a past season next to the current one:
+// the seasons archived with POST /seasons, and fixtures replaced before
+// seasons existed or halfway through one. A season only has a champion and
+// relegated teams once all of its matches were played, so fixtures replaced
+// halfway only add to the all-time table.
+
+// pastSeason is the final (or latest) table of one season
+type pastSeason struct {
+	ID        string
+	Complete  bool
+	Standings []Standing
+}
+
+// AllTimeStanding sums a team's results over every season
+type AllTimeStanding struct {
+	Standing
+	Seasons int `json:"seasons"`
+	Titles  int `json:"titles"`
+}
+
+// TeamTitles lists the seasons a team won
+type TeamTitles struct {
+	TeamID   int      `json:"team_id"`
+	TeamName string   `json:"team_name"`
+	Titles   int      `json:"titles"`
+	Seasons  []string `json:"seasons"`
+}
+
+// AllTimeScorer is a player's goals over every season they scored in
+type AllTimeScorer struct {
+	TopScorer
+	Seasons int `json:"seasons"`
+}
+
+// Relegation is a team finishing a completed season in the relegation zone
+type Relegation struct {
+	Season   string `json:"season"`
+	TeamID   int    `json:"team_id"`
+	TeamName string `json:"team_name"`
+	Position int    `json:"position"`
+}
+
+// seasonSideSQL sums the home or the away side of a matches table by season
+// and team. Matches that do not count, unplayed or annulled, add nothing to
+// the results but still tell whether the season is complete.
+const seasonSideSQL = `
+	SELECT {season} AS archive_id, {side}_team_id AS team_id, COUNT(*) AS matches, SUM(played) AS played,
+		SUM({counted}) AS counted,
+		SUM({counted} AND {side}_goals > {other}_goals) AS wins,
+		SUM({counted} AND {side}_goals = {other}_goals) AS draws,
+		SUM({counted} AND {side}_goals < {other}_goals) AS losses,
+		SUM(CASE WHEN {counted} THEN {side}_goals ELSE 0 END) AS goals_for,
+		SUM(CASE WHEN {counted} THEN {other}_goals ELSE 0 END) AS goals_against
+	FROM {table}
+	GROUP BY {group}`
+
+// seasonSide fills seasonSideSQL for archived_matches or for the current
+// matches, which are archive 0
+func seasonSide(table, side, other string) string {
+	season, group, counted := "archive_id", "archive_id, "+side+"_team_id", "played"
+	if table == "matches" {
+		season, group, counted = "0", side+"_team_id", "(played AND COALESCE(administrative, '') != 'annulled')"
+	}
+	return strings.NewReplacer(
+		"{season}", season, "{side}", side, "{other}", other,
+		"{counted}", counted, "{table}", table, "{group}", group,
+	).Replace(seasonSideSQL)
+}
+
+// seasonSides has a row per season, team and side. Grouping each side on
+// its own lets the archive indexes hand the rows over in order.
+var seasonSides = "WITH sides AS (" + strings.Join([]string{
+	seasonSide("archived_matches", "home", "away"),
+	seasonSide("archived_matches", "away", "home"),
+	seasonSide("matches", "home", "away"),
+	seasonSide("matches", "away", "home"),
+}, "\n\tUNION ALL") + ")"
+
+// seasons builds the table of every archived fixture, oldest first, and of
+// the current one last. The database adds the results up, so only a row per
+// team and season comes back however long the history. Points follow the
+// sport as configured now; the archive does not record which rules a season
+// was played under, so point multipliers are left out and every match
+// counts once.
+func (l *League) seasons() ([]pastSeason, error) {
+	// teams that went to another division still have their seasons here
+	names, err := l.allTeamNames()
+	if err != nil {
+		return nil, err
+	}
+	sport := l.config().Sport
+
+	rows, err := l.db.Query(seasonSides + `
+		SELECT archive_id, team_id, SUM(counted), SUM(wins), SUM(draws), SUM(losses),
+			SUM(goals_for), SUM(goals_against), SUM(played) = SUM(matches)
+		FROM sides
+		GROUP BY archive_id, team_id
+		ORDER BY archive_id = 0, archive_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// archives in id order, then the current season
+	var seasons []pastSeason
+	lastArchive := -1
+	for rows.Next() {
+		var archiveID int
+		var complete bool
+		s := Standing{}
+		if err := rows.Scan(&archiveID, &s.TeamID, &s.Played, &s.Wins, &s.Draws, &s.Losses, &s.GoalsFor, &s.GoalsAgainst, &complete); err != nil {
+			return nil, err
+		}
+		if archiveID != lastArchive {
+			id := "current"
+			if archiveID != 0 {
+				id = strconv.Itoa(archiveID)
+			}
+			seasons = append(seasons, pastSeason{ID: id, Complete: true})
+			lastArchive = archiveID
+		}
+		season := &seasons[len(seasons)-1]
+		season.Complete = season.Complete && complete
+
+		s.TeamName = names[s.TeamID]
+		s.GoalDifference = s.GoalsFor - s.GoalsAgainst
+		s.Points = s.Wins*sport.WinPoints + s.Draws*sport.DrawPoints + s.Losses*sport.LossPoints
+		season.Standings = append(season.Standings, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range seasons {
+		sport.sortStandings(seasons[i].Standings)
+	}
+	return seasons, nil
+}
+
+// AllTimeTable adds up every season, ordered like a normal table
+func (l *League) AllTimeTable() ([]AllTimeStanding, error) {
+	seasons, err := l.seasons()
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[int]*AllTimeStanding)
+	for _, season := range seasons {
+		for i, s := range season.Standings {
+			t := totals[s.TeamID]
+			if t == nil {
+				t = &AllTimeStanding{Standing: Standing{TeamID: s.TeamID, TeamName: s.TeamName}}
+				totals[s.TeamID] = t
+			}
+			if s.Played > 0 {
+				t.Seasons++
+			}
+			t.Played += s.Played
+			t.Wins += s.Wins
+			t.Draws += s.Draws
+			t.Losses += s.Losses
+			t.GoalsFor += s.GoalsFor
+			t.GoalsAgainst += s.GoalsAgainst
+			t.GoalDifference += s.GoalDifference
+			t.Points += s.Points
+			if season.Complete && i == 0 {
+				t.Titles++
+			}
+		}
+	}
+
+	standings := make([]Standing, 0, len(totals))
+	for _, t := range totals {
+		standings = append(standings, t.Standing)
+	}
+	cfg := l.config()
+	cfg.Sport.sortStandings(standings)
+	cfg.Sport.rankStandings(standings, cfg.SharedRanks)
+	table := make([]AllTimeStanding, 0, len(standings))
+	for _, s := range standings {
+		t := totals[s.TeamID]
+		t.Rank = s.Rank
+		table = append(table, *t)
+	}
+	return table, nil
+}
+
+// Titles counts the champions of completed seasons, most titles first
+func (l *League) Titles() ([]TeamTitles, error) {
+	seasons, err := l.seasons()
+	if err != nil {
+		return nil, err
+	}
+
+	byTeam := make(map[int]*TeamTitles)
+	var order []int
+	for _, season := range seasons {
+		if !season.Complete || len(season.Standings) == 0 {
+			continue
+		}
+		champion := season.Standings[0]
+		t := byTeam[champion.TeamID]
+		if t == nil {
+			t = &TeamTitles{TeamID: champion.TeamID, TeamName: champion.TeamName}
+			byTeam[champion.TeamID] = t
+			order = append(order, champion.TeamID)
+		}
+		t.Titles++
+		t.Seasons = append(t.Seasons, season.ID)
+	}
+
+	titles := make([]TeamTitles, 0, len(order))
+	for _, id := range order {
+		titles = append(titles, *byTeam[id])
+	}
+	// first to win keeps the place on equal titles
+	sort.SliceStable(titles, func(i, j int) bool {
+		return titles[i].Titles > titles[j].Titles
+	})
+	return titles, nil
+}
+
+// Relegations lists the teams that finished a completed season in the zone
+// named "relegation", as it is configured now
+func (l *League) Relegations() ([]Relegation, error) {
+	relegations := []Relegation{}
+	var zone *Zone
+	for _, z := range l.config().Zones {
+		if z.Name == "relegation" {
+			z := z
+			zone = &z
+		}
+	}
+	if zone == nil {
+		return relegations, nil
+	}
+
+	seasons, err := l.seasons()
+	if err != nil {
+		return nil, err
+	}
+	for _, season := range seasons {
+		if !season.Complete {
+			continue
+		}
+		for pos := zone.From; pos <= zone.To && pos <= len(season.Standings); pos++ {
+			s := season.Standings[pos-1]
+			relegations = append(relegations, Relegation{Season: season.ID, TeamID: s.TeamID, TeamName: s.TeamName, Position: pos})
+		}
+	}
+	return relegations, nil
+}
+
+// AllTimeScorers counts the goals with a known scorer in every archived
+// fixture and the current one, own goals left out. Goals from before
+// scorers were archived have no player and do not count.
+func (l *League) AllTimeScorers() ([]AllTimeScorer, error) {
+	rows, err := l.db.Query(`
+		WITH goals AS (
+			SELECT archive_id, player_id FROM archived_match_events
+			WHERE type = ? AND COALESCE(detail, '') != ?
+			UNION ALL
+			SELECT 0, player_id FROM match_events
+			WHERE type = ? AND COALESCE(detail, '') != ?
+		)
+		SELECT p.name, t.name, COUNT(*) AS total, COUNT(DISTINCT g.archive_id)
+		FROM goals g
+		JOIN players p ON p.id = g.player_id
+		JOIN teams t ON t.id = p.team_id
+		GROUP BY p.id
+		ORDER BY total DESC, p.name`, EventGoal, ownGoalDetail, EventGoal, ownGoalDetail)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scorers := []AllTimeScorer{}
+	for rows.Next() {
+		var s AllTimeScorer
+		if err := rows.Scan(&s.Player, &s.TeamName, &s.Goals, &s.Seasons); err != nil {
+			return nil, err
+		}
+		scorers = append(scorers, s)
+	}
+	return scorers, rows.Err()
+}
+
+// handleAllTime serves one of the /alltime lists
+func handleAllTime[T any](list func() (T, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := list()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(result)
+	}
+}
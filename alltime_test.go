@@ -0,0 +1,240 @@
+package insider
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// archiveSeasons fills the archive with double round robins of made up
+// results, the last season only half played
+func archiveSeasons(tb testing.TB, l *League, seasons int, rng *rand.Rand) {
+	tb.Helper()
+
+	teams := l.Teams()
+	tx, err := l.db.Begin()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer tx.Rollback()
+	insert, err := tx.Prepare(`
+		INSERT INTO archived_matches (archive_id, home_team_id, away_team_id, home_goals, away_goals, played, week)
+		VALUES (?, ?, ?, ?, ?, ?, 0)`)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer insert.Close()
+
+	for season := 1; season <= seasons; season++ {
+		if _, err := tx.Exec("INSERT INTO fixture_archives (id, reason) VALUES (?, 'test')", season); err != nil {
+			tb.Fatal(err)
+		}
+		n := 0
+		for _, home := range teams {
+			for _, away := range teams {
+				if home.ID == away.ID {
+					continue
+				}
+				n++
+				played := season < seasons || n%2 == 0
+				if _, err := insert.Exec(season, home.ID, away.ID, rng.Intn(5), rng.Intn(4), played); err != nil {
+					tb.Fatal(err)
+				}
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// seasonsByScan is how the seasons were built before the database summed
+// them: every match is read and added up in Go. It stays as the reference
+// for the aggregate query and the baseline for its benchmark.
+func seasonsByScan(l *League) ([]pastSeason, error) {
+	names := make(map[int]string)
+	for _, t := range l.Teams() {
+		names[t.ID] = t.Name
+	}
+	sport := l.config().Sport
+
+	rows, err := l.db.Query(`
+		SELECT * FROM (
+			SELECT archive_id, home_team_id, away_team_id, home_goals, away_goals, played, '' AS administrative
+			FROM archived_matches
+			UNION ALL
+			SELECT 0, home_team_id, away_team_id, home_goals, away_goals, played, COALESCE(administrative, '')
+			FROM matches
+		)
+		ORDER BY archive_id = 0, archive_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seasons []pastSeason
+	var standings map[int]*Standing
+	lastArchive := -1
+	finish := func() {
+		if standings == nil {
+			return
+		}
+		season := &seasons[len(seasons)-1]
+		for _, s := range standings {
+			s.GoalDifference = s.GoalsFor - s.GoalsAgainst
+			season.Standings = append(season.Standings, *s)
+		}
+		l.config().Sport.sortStandings(season.Standings)
+	}
+	for rows.Next() {
+		var archiveID, home, away, homeGoals, awayGoals int
+		var played bool
+		var administrative string
+		if err := rows.Scan(&archiveID, &home, &away, &homeGoals, &awayGoals, &played, &administrative); err != nil {
+			return nil, err
+		}
+		if archiveID != lastArchive {
+			finish()
+			id := "current"
+			if archiveID != 0 {
+				id = strconv.Itoa(archiveID)
+			}
+			seasons = append(seasons, pastSeason{ID: id, Complete: true})
+			standings = make(map[int]*Standing)
+			lastArchive = archiveID
+		}
+		for _, id := range []int{home, away} {
+			if standings[id] == nil {
+				standings[id] = &Standing{TeamID: id, TeamName: names[id]}
+			}
+		}
+		if !played {
+			seasons[len(seasons)-1].Complete = false
+			continue
+		}
+		if administrative != AdminAnnulled {
+			// week 0 has no multiplier, all-time tables count every match once
+			sport.recordResult(standings[home], standings[away], 0, homeGoals, awayGoals)
+		}
+	}
+	finish()
+	return seasons, rows.Err()
+}
+
+func TestSeasonsAddUpEveryMatch(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, 6, 1)
+	archiveSeasons(t, l, 5, rand.New(rand.NewSource(1)))
+	if err := l.SimulateWeek(1); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := l.seasons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := seasonsByScan(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("aggregated seasons differ from the match by match sums\ngot:  %+v\nwant: %+v", got, want)
+	}
+	if len(got) != 6 || !got[0].Complete || got[4].Complete || got[5].ID != "current" {
+		t.Errorf("seasons %+v", got)
+	}
+}
+
+// go test -run '^$' -bench Seasons compares the aggregate query with adding
+// up every match in Go, on 20 teams and 264 seasons (100,320 matches)
+func BenchmarkSeasons(b *testing.B) {
+	teams := make([]Team, 20)
+	for i := range teams {
+		teams[i] = Team{Name: fmt.Sprintf("Team %d", i+1), Strength: 40 + 2*i}
+	}
+	l := newTestLeague(b, teams, 38, 1)
+	archiveSeasons(b, l, 264, rand.New(rand.NewSource(1)))
+
+	for _, bm := range []struct {
+		name    string
+		seasons func(*League) ([]pastSeason, error)
+	}{
+		{"aggregate", (*League).seasons},
+		{"scan", seasonsByScan},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := bm.seasons(l); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestAllTimeScorers(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, FixtureWeeks(len(snapshotTeams)), 1)
+	if err := l.AddPlayers("Alpha FC", []string{"A. Striker"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddPlayers("Bravo United", []string{"B. Striker"}); err != nil {
+		t.Fatal(err)
+	}
+	// headToHead is the first match between Alpha FC and Bravo United
+	headToHead := func() Match {
+		matches, err := l.Matches()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range matches {
+			if m.HomeTeam == "Alpha FC" && m.AwayTeam == "Bravo United" || m.HomeTeam == "Bravo United" && m.AwayTeam == "Alpha FC" {
+				return m
+			}
+		}
+		t.Fatal("Alpha FC never meets Bravo United")
+		return Match{}
+	}
+
+	// a season where A. Striker and B. Striker score once each
+	playByStrength(t, l, 1, FixtureWeeks(len(snapshotTeams)))
+	m := headToHead()
+	scorers := []Scorer{{Player: "A. Striker", Minute: 10}, {Player: "B. Striker", Minute: 20}}
+	if err := l.UpdateMatchResult(m.ID, 1, 1, scorers); err != nil {
+		t.Fatal(err)
+	}
+	next, err := l.ArchiveSeason("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.StartSeason(next.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// and one where A. Striker scores twice, next to an own goal
+	m = headToHead()
+	scorers = []Scorer{
+		{Player: "A. Striker", Minute: 5},
+		{Player: "A. Striker", Minute: 50},
+		{Player: "B. Striker", Minute: 80, OwnGoal: true},
+	}
+	home, away := 3, 0
+	if m.AwayTeam == "Alpha FC" {
+		home, away = 0, 3
+	}
+	if err := l.UpdateMatchResult(m.ID, home, away, scorers); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := l.AllTimeScorers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []AllTimeScorer{
+		{TopScorer: TopScorer{Player: "A. Striker", TeamName: "Alpha FC", Goals: 3}, Seasons: 2},
+		{TopScorer: TopScorer{Player: "B. Striker", TeamName: "Bravo United", Goals: 1}, Seasons: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("all-time scorers %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,230 @@
+package insider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	AnnouncementChampion  = "champion"
+	AnnouncementRelegated = "relegated"
+)
+
+// Announcement is a news item published when a team's fate is settled
+type Announcement struct {
+	ID        int       `json:"id"`
+	Kind      string    `json:"kind"`
+	TeamID    int       `json:"team_id"`
+	TeamName  string    `json:"team_name"`
+	Message   string    `json:"message"`
+	Week      int       `json:"week"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// clinch is a team that can no longer leave a band of positions
+type clinch struct {
+	kind         string
+	team         string
+	weeksToSpare int
+}
+
+func (l *League) createAnnouncementTable() error {
+	// one announcement per kind and team, so a clinch is only published once
+	createAnnouncements := `
+	CREATE TABLE IF NOT EXISTS announcements (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
+		message TEXT,
+		week INTEGER,
+		created_at TIMESTAMP,
+		UNIQUE (kind, team_id)
+	);`
+
+	if _, err := l.db.Exec(createAnnouncements); err != nil {
+		return fmt.Errorf("error creating announcements table: %v", err)
+	}
+	return nil
+}
+
+// clinches finds the teams whose final position is certain to be first, or
+// in the relegation zone when there is one. Tiebreakers are unknown until
+// the end, so a team that can still draw level on points is not clinched.
+func (s *seasonState) clinches(relegation *Zone) []clinch {
+	played, remaining := s.current()
+	standings := s.standings(played)
+
+	// weeks to spare are those after the week being played
+	latest := s.latestWeek()
+	weeks := make(map[int]bool)
+	for _, m := range remaining {
+		if m.Week > latest {
+			weeks[m.Week] = true
+		}
+	}
+	most, _ := s.sport.pointsLeft(remaining)
+	maxPoints := func(st Standing) int {
+		return st.Points + most[st.TeamName]
+	}
+
+	// certainIn reports whether the team will finish between positions from
+	// and to whatever happens
+	certainIn := func(team Standing, from, to int) bool {
+		surelyAbove, maybeAbove := 0, 0
+		for _, other := range standings {
+			if other.TeamName == team.TeamName {
+				continue
+			}
+			if other.Points > maxPoints(team) {
+				surelyAbove++
+			}
+			if maxPoints(other) >= team.Points {
+				maybeAbove++
+			}
+		}
+		return surelyAbove >= from-1 && maybeAbove <= to-1
+	}
+
+	var found []clinch
+	for _, st := range standings {
+		if certainIn(st, 1, 1) {
+			found = append(found, clinch{AnnouncementChampion, st.TeamName, len(weeks)})
+		}
+		if relegation != nil && certainIn(st, relegation.From, relegation.To) {
+			found = append(found, clinch{AnnouncementRelegated, st.TeamName, len(weeks)})
+		}
+	}
+	return found
+}
+
+func (c clinch) message() string {
+	var spare string
+	switch c.weeksToSpare {
+	case 0:
+	case 1:
+		spare = " with a week to spare"
+	default:
+		spare = fmt.Sprintf(" with %d weeks to spare", c.weeksToSpare)
+	}
+	if c.kind == AnnouncementChampion {
+		return c.team + " are champions" + spare
+	}
+	return c.team + " are relegated" + spare
+}
+
+// relegationZone is the config zone named relegation, nil without one
+func (l *League) relegationZone() *Zone {
+	for _, z := range l.config().Zones {
+		if z.Name == "relegation" {
+			return &z
+		}
+	}
+	return nil
+}
+
+// announceClinches publishes a news item and a webhook for every clinch not
+// announced before. It runs after each result, the table makes sure each one
+// fires exactly once even if results come in concurrently.
+func (l *League) announceClinches() error {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return err
+	}
+	clinches := state.clinches(l.relegationZone())
+	if len(clinches) == 0 {
+		return nil
+	}
+
+	ids := make(map[string]int)
+	for _, t := range l.Teams() {
+		ids[t.Name] = t.ID
+	}
+	week := state.latestWeek()
+
+	var published []Announcement
+	for _, c := range clinches {
+		a := Announcement{
+			Kind:      c.kind,
+			TeamID:    ids[c.team],
+			TeamName:  c.team,
+			Message:   c.message(),
+			Week:      week,
+			CreatedAt: time.Now().UTC(),
+		}
+		res, err := l.db.Exec(
+			"INSERT OR IGNORE INTO announcements (kind, team_id, message, week, created_at) VALUES (?, ?, ?, ?, ?)",
+			a.Kind, a.TeamID, a.Message, a.Week, a.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		a.ID = int(id)
+		published = append(published, a)
+	}
+
+	for _, a := range published {
+		l.notifyWebhooks(EventTypeAnnouncement, a)
+	}
+	return nil
+}
+
+// afterResult runs the follow-ups of a recorded result. The result itself is
+// already saved, so failures here are only printed.
+func (l *League) afterResult() {
+	if err := l.annotateResults(); err != nil {
+		fmt.Println("Result probabilities failed:", err)
+	}
+	if err := l.announceClinches(); err != nil {
+		fmt.Println("Announcements failed:", err)
+	}
+	if err := l.updateStorylines(); err != nil {
+		fmt.Println("Storylines failed:", err)
+	}
+	if err := l.certifySeason(); err != nil {
+		fmt.Println("Season certificate failed:", err)
+	}
+	if err := l.playoffAtSeasonEnd(); err != nil {
+		fmt.Println("Relegation playoff failed:", err)
+	}
+}
+
+// Announcements lists the news items, newest first
+func (l *League) Announcements() ([]Announcement, error) {
+	rows, err := l.db.Query(`
+		SELECT a.id, a.kind, a.team_id, t.name, a.message, a.week, a.created_at
+		FROM announcements a JOIN teams t ON t.id = a.team_id
+		ORDER BY a.id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	announcements := []Announcement{}
+	for rows.Next() {
+		var a Announcement
+		if err := rows.Scan(&a.ID, &a.Kind, &a.TeamID, &a.TeamName, &a.Message, &a.Week, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, a)
+	}
+	return announcements, rows.Err()
+}
+
+// GET /news
+func (l *League) handleNews(w http.ResponseWriter, r *http.Request) {
+	announcements, err := l.Announcements()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(announcements)
+}
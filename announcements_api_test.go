@@ -0,0 +1,86 @@
+package insider_test
+
+import (
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+// clinchNews is /news without the storylines
+func clinchNews(h *leaguetest.Harness) []insider.Announcement {
+	h.T.Helper()
+	var news, clinches []insider.Announcement
+	h.Get("/news", &news)
+	for _, a := range news {
+		if a.Kind == insider.AnnouncementChampion || a.Kind == insider.AnnouncementRelegated {
+			clinches = append(clinches, a)
+		}
+	}
+	return clinches
+}
+
+func TestClinchAnnouncements(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 2)
+	cfg := *h.League.Config()
+	cfg.Zones = []insider.Zone{{Name: "relegation", From: 4, To: 4}}
+	cfg.Webhooks = []string{"http://127.0.0.1:1/hook"}
+	h.League.StoreConfig(&cfg)
+
+	// the stronger side wins every match. With a week left Bravo United,
+	// three points behind, can still draw level with Alpha FC, and Delta SC
+	// with Charlie Town, so nothing is certain yet.
+	insider.PlayByStrength(t, h.League, 1, 5)
+	if news := clinchNews(h); len(news) != 0 {
+		t.Fatalf("announced %+v with a week left", news)
+	}
+
+	insider.PlayByStrength(t, h.League, 6, 6)
+	news := clinchNews(h)
+	kinds := make(map[string]insider.Announcement)
+	for _, a := range news {
+		kinds[a.Kind] = a
+	}
+	champion, relegated := kinds[insider.AnnouncementChampion], kinds[insider.AnnouncementRelegated]
+	if champion.TeamName != "Alpha FC" || champion.Week != 6 || champion.Message != "Alpha FC are champions" {
+		t.Errorf("champion announcement %+v", champion)
+	}
+	if relegated.TeamName != "Delta SC" || relegated.Week != 6 || relegated.Message != "Delta SC are relegated" {
+		t.Errorf("relegation announcement %+v", relegated)
+	}
+	if len(news) != 2 {
+		t.Errorf("%d announcements, want 2: %+v", len(news), news)
+	}
+
+	// a corrected score and another pass find the same clinches, each is
+	// still only published once
+	insider.PlayByStrength(t, h.League, 6, 6)
+	if err := h.League.AnnounceClinches(); err != nil {
+		t.Fatal(err)
+	}
+	if news := clinchNews(h); len(news) != 2 {
+		t.Errorf("%d announcements after the season, want 2", len(news))
+	}
+	published := 0
+	for _, e := range insider.WebhookEvents(t, h.League, insider.EventTypeAnnouncement) {
+		if kind := e.Data.(map[string]any)["kind"]; kind == insider.AnnouncementChampion || kind == insider.AnnouncementRelegated {
+			published++
+		}
+	}
+	if published != 2 {
+		t.Errorf("%d clinch webhooks, want 2", published)
+	}
+}
+
+func TestNoClinchOnLevelPoints(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 2)
+	// every match drawn: nobody can be sure of anything, even at the end
+	for _, m := range h.Matches() {
+		if err := h.League.UpdateMatchResult(m.ID, 1, 1, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if news := clinchNews(h); len(news) != 0 {
+		t.Errorf("announced %+v on a level table", news)
+	}
+}
@@ -0,0 +1,67 @@
+package insider
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// playByStrength enters the results of weeks from to to, the stronger side
+// winning 2-0 every time
+func playByStrength(t *testing.T, l *League, from, to int) {
+	t.Helper()
+	strength := make(map[string]int)
+	for _, team := range l.Teams() {
+		strength[team.Name] = team.Strength
+	}
+	matches, err := l.Matches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range matches {
+		if m.Week < from || m.Week > to {
+			continue
+		}
+		home, away := 2, 0
+		if strength[m.AwayTeam] > strength[m.HomeTeam] {
+			home, away = 0, 2
+		}
+		if err := l.UpdateMatchResult(m.ID, home, away, nil); err != nil {
+			t.Fatalf("result of match %d: %v", m.ID, err)
+		}
+	}
+}
+
+// webhookEvents decodes the queued payloads of an event type, oldest first
+func webhookEvents(t *testing.T, l *League, eventType string) []WebhookPayload {
+	t.Helper()
+	rows, err := l.db.Query("SELECT payload FROM outbox WHERE event_type = ? ORDER BY id", eventType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var events []WebhookPayload
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&payload); err != nil {
+			t.Fatal(err)
+		}
+		var e WebhookPayload
+		if err := json.Unmarshal([]byte(payload), &e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestClinchMessage(t *testing.T) {
+	for c, want := range map[clinch]string{
+		{AnnouncementChampion, "Alpha FC", 0}:  "Alpha FC are champions",
+		{AnnouncementChampion, "Alpha FC", 1}:  "Alpha FC are champions with a week to spare",
+		{AnnouncementRelegated, "Delta SC", 3}: "Delta SC are relegated with 3 weeks to spare",
+	} {
+		if got := c.message(); got != want {
+			t.Errorf("%+v: %q, want %q", c, got, want)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package insider
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API tokens are keys for programs talking to the league, handed out and
+// revoked by an admin instead of sharing the one --admin-token. Each has
+// scopes saying what it may do and an optional limit of requests per minute.
+// Like user tokens they go in the "Authorization: Bearer" header and only a
+// hash is stored. Revoked tokens stay listed with the time they were
+// revoked.
+
+// ErrInvalidAPIToken is returned for a token with no name, an unknown scope
+// or a negative rate limit
+var ErrInvalidAPIToken = errors.New("invalid API token")
+
+// API token scopes. Admin implies write, write implies read.
+const (
+	// ScopeRead only allows GET requests, and the POSTs that just compute
+	ScopeRead = "read"
+	// ScopeWrite allows every request that needs no admin token
+	ScopeWrite = "write"
+	// ScopeAdmin allows what the admin token allows
+	ScopeAdmin = "admin"
+)
+
+var apiTokenScopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// lastUsedEvery is how often the last use of a token is written down, so a
+// busy token does not cost a write on every request
+const lastUsedEvery = time.Minute
+
+// APIToken is a key for the API. Token is only set when it is created.
+// LastUsedAt is brought up to date at most once every lastUsedEvery.
+type APIToken struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	RateLimit  int        `json:"rate_limit"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (l *League) createAPITokenTable() error {
+	createTokens := `
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		token_hash TEXT UNIQUE NOT NULL,
+		scopes TEXT NOT NULL,
+		rate_limit INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL,
+		last_used_at TIMESTAMP,
+		revoked_at TIMESTAMP
+	);`
+
+	if _, err := l.db.Exec(createTokens); err != nil {
+		return fmt.Errorf("error creating api_tokens table: %v", err)
+	}
+	return nil
+}
+
+// allows tells whether the token's scopes cover scope
+func (t *APIToken) allows(scope string) bool {
+	rank := slices.Index(apiTokenScopes, scope)
+	for _, s := range t.Scopes {
+		if slices.Index(apiTokenScopes, s) >= rank {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the parts of a token an admin chooses
+func (t *APIToken) validate() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return fmt.Errorf("%w: name cannot be empty", ErrInvalidAPIToken)
+	}
+	if len(t.Scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is needed, one of %s", ErrInvalidAPIToken, strings.Join(apiTokenScopes, ", "))
+	}
+	for _, s := range t.Scopes {
+		if !slices.Contains(apiTokenScopes, s) {
+			return fmt.Errorf("%w: unknown scope %q, expected one of %s", ErrInvalidAPIToken, s, strings.Join(apiTokenScopes, ", "))
+		}
+	}
+	if t.RateLimit < 0 {
+		return fmt.Errorf("%w: rate_limit cannot be negative", ErrInvalidAPIToken)
+	}
+	return nil
+}
+
+// CreateAPIToken stores a new token and returns it with its secret, which
+// cannot be shown again
+func (l *League) CreateAPIToken(t APIToken) (*APIToken, error) {
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	t.Token = hex.EncodeToString(raw)
+	t.CreatedAt = time.Now().UTC()
+	t.LastUsedAt, t.RevokedAt = nil, nil
+
+	scopes, err := json.Marshal(t.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	res, err := l.db.Exec("INSERT INTO api_tokens (name, token_hash, scopes, rate_limit, created_at) VALUES (?, ?, ?, ?, ?)",
+		t.Name, hashToken(t.Token), string(scopes), t.RateLimit, t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	t.ID = int(id)
+	return &t, nil
+}
+
+const apiTokenSelect = "SELECT id, name, scopes, rate_limit, created_at, last_used_at, revoked_at FROM api_tokens"
+
+func scanAPIToken(row rowScanner) (*APIToken, error) {
+	var t APIToken
+	var scopes string
+	var lastUsed, revoked sql.NullTime
+	if err := row.Scan(&t.ID, &t.Name, &scopes, &t.RateLimit, &t.CreatedAt, &lastUsed, &revoked); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &t.Scopes); err != nil {
+		return nil, err
+	}
+	if lastUsed.Valid {
+		t.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		t.RevokedAt = &revoked.Time
+	}
+	return &t, nil
+}
+
+// APITokens lists every token, revoked ones included, oldest first
+func (l *League) APITokens() ([]APIToken, error) {
+	rows, err := l.db.Query(apiTokenSelect + " ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// APIToken finds a token by id, sql.ErrNoRows when there is none
+func (l *League) APIToken(id int) (*APIToken, error) {
+	return scanAPIToken(l.db.QueryRow(apiTokenSelect+" WHERE id = ?", id))
+}
+
+// UpdateAPIToken changes the name, scopes and rate limit of a token. The
+// secret stays the same.
+func (l *League) UpdateAPIToken(id int, changes APIToken) (*APIToken, error) {
+	if err := changes.validate(); err != nil {
+		return nil, err
+	}
+	scopes, err := json.Marshal(changes.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	res, err := l.db.Exec("UPDATE api_tokens SET name = ?, scopes = ?, rate_limit = ? WHERE id = ?",
+		changes.Name, string(scopes), changes.RateLimit, id)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return l.APIToken(id)
+}
+
+// RevokeAPIToken stops a token from working. Revoking it again keeps the
+// first time.
+func (l *League) RevokeAPIToken(id int) (*APIToken, error) {
+	res, err := l.db.Exec("UPDATE api_tokens SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?", time.Now().UTC(), id)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return l.APIToken(id)
+}
+
+// rateLimiter counts the requests of every token in fixed one minute windows
+type rateLimiter struct {
+	mu      sync.Mutex
+	windows map[int]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// allow takes one request from the token's allowance for now. When the
+// limit is reached it returns how long until the next window.
+func (rl *rateLimiter) allow(id, limit int, now time.Time) (bool, time.Duration) {
+	if limit == 0 {
+		return true, 0
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.windows == nil {
+		rl.windows = make(map[int]*rateWindow)
+	}
+	w := rl.windows[id]
+	if w == nil || now.Sub(w.start) >= time.Minute {
+		w = &rateWindow{start: now}
+		rl.windows[id] = w
+	}
+	if w.count >= limit {
+		return false, w.start.Add(time.Minute).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+type apiTokenKey struct{}
+
+// requestAPIToken is the API token the request was made with, nil for none
+func requestAPIToken(r *http.Request) *APIToken {
+	t, _ := r.Context().Value(apiTokenKey{}).(*APIToken)
+	return t
+}
+
+// apiTokens checks requests made with an API token: revoked tokens, scopes
+// and rate limits are enforced here, and the token is handed on in the
+// context for isAdmin. The admin token is marked in the context too; other
+// bearer tokens, of users, pass through untouched.
+func (l *League) apiTokens(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || secret == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if l.isAdminToken(secret) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminKey{}, true)))
+			return
+		}
+		t, err := scanAPIToken(l.db.QueryRow(apiTokenSelect+" WHERE token_hash = ?", hashToken(secret)))
+		if err == sql.ErrNoRows {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		setUsageClient(r, fmt.Sprintf("token:%d:%s", t.ID, t.Name))
+		if t.RevokedAt != nil {
+			http.Error(w, "API token revoked", http.StatusUnauthorized)
+			return
+		}
+		now := time.Now().UTC()
+		if ok, wait := l.limiter.allow(t.ID, t.RateLimit, now); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= lastUsedEvery {
+			if _, err := l.db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", now, t.ID); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !readOnlyExempt[r.URL.Path] && !t.allows(ScopeWrite) {
+			http.Error(w, "API token is read-only", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiTokenKey{}, t)))
+	})
+}
+
+// requireAdmin writes a 401 and returns false when the request has no admin
+// rights
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !isAdmin(r) {
+		http.Error(w, "Admin token required", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// GET /admin/tokens lists the API tokens, POST creates one:
+// {"name": "scoreboard", "scopes": ["read"], "rate_limit": 60}
+func (l *League) handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := l.APITokens()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(tokens)
+	case http.MethodPost:
+		var req APIToken
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		t, err := l.CreateAPIToken(req)
+		if errors.Is(err, ErrInvalidAPIToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(t)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /admin/tokens/{id} shows a token, POST changes its name, scopes and
+// rate limit, DELETE revokes it
+func (l *League) handleAPIToken(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid token id", http.StatusBadRequest)
+		return
+	}
+
+	var t *APIToken
+	switch r.Method {
+	case http.MethodGet:
+		t, err = l.APIToken(id)
+	case http.MethodPost:
+		// fields left out keep their value
+		t, err = l.APIToken(id)
+		if err == nil {
+			if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			t, err = l.UpdateAPIToken(id, *t)
+		}
+	case http.MethodDelete:
+		t, err = l.RevokeAPIToken(id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "API token not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrInvalidAPIToken):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(t)
+}
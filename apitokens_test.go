@@ -0,0 +1,98 @@
+package insider_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestAPITokens(t *testing.T) {
+	h := leaguetest.New(t, nil, 1)
+
+	var reader, admin insider.APIToken
+	if status := h.Do(http.MethodPost, "/admin/tokens", insider.APIToken{Name: "scoreboard", Scopes: []string{insider.ScopeRead}, RateLimit: 3}, true, &reader); status != http.StatusCreated {
+		t.Fatalf("create read token: status %d", status)
+	}
+	if status := h.Do(http.MethodPost, "/admin/tokens", insider.APIToken{Name: "ops", Scopes: []string{insider.ScopeAdmin}}, true, &admin); status != http.StatusCreated {
+		t.Fatalf("create admin token: status %d", status)
+	}
+	if status := h.Do(http.MethodPost, "/admin/tokens", insider.APIToken{Name: "bad", Scopes: []string{"root"}}, true, nil); status != http.StatusBadRequest {
+		t.Errorf("unknown scope: status %d, want 400", status)
+	}
+	if status := h.DoWithToken(http.MethodGet, "/admin/tokens", nil, reader.Token, nil); status != http.StatusUnauthorized {
+		t.Errorf("read token listing tokens: status %d, want 401", status)
+	}
+
+	// the read token may look but not touch, three requests a minute
+	for _, step := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/standings", http.StatusOK},
+		{http.MethodPost, "/simulate/week/1", http.StatusForbidden},
+		{http.MethodGet, "/standings", http.StatusTooManyRequests},
+	} {
+		if status := h.DoWithToken(step.method, step.path, nil, reader.Token, nil); status != step.want {
+			t.Errorf("read token %s %s: status %d, want %d", step.method, step.path, status, step.want)
+		}
+	}
+
+	// the admin token manages tokens, until it is revoked
+	var tokens []insider.APIToken
+	if status := h.DoWithToken(http.MethodGet, "/admin/tokens", nil, admin.Token, &tokens); status != http.StatusOK {
+		t.Fatalf("list with admin token: status %d", status)
+	}
+	for _, tok := range tokens {
+		if tok.Token != "" {
+			t.Errorf("%s: secret listed", tok.Name)
+		}
+		if tok.Name == "ops" && tok.LastUsedAt == nil {
+			t.Errorf("ops: last_used_at not set")
+		}
+	}
+	var revoked insider.APIToken
+	if status := h.Do(http.MethodDelete, "/admin/tokens/"+strconv.Itoa(admin.ID), nil, true, &revoked); status != http.StatusOK || revoked.RevokedAt == nil {
+		t.Fatalf("revoke: status %d, %+v", status, revoked)
+	}
+	if status := h.DoWithToken(http.MethodGet, "/standings", nil, admin.Token, nil); status != http.StatusUnauthorized {
+		t.Errorf("revoked token: status %d, want 401", status)
+	}
+}
+
+func TestAPITokenLastUsedOncePerMinute(t *testing.T) {
+	h := leaguetest.New(t, nil, 1)
+	var token insider.APIToken
+	if status := h.Do(http.MethodPost, "/admin/tokens", insider.APIToken{Name: "poller", Scopes: []string{insider.ScopeRead}}, true, &token); status != http.StatusCreated {
+		t.Fatalf("create token: status %d", status)
+	}
+	lastUsed := func() time.Time {
+		t.Helper()
+		if status := h.DoWithToken(http.MethodGet, "/standings", nil, token.Token, nil); status != http.StatusOK {
+			t.Fatalf("GET /standings: status %d", status)
+		}
+		tok, err := h.League.APIToken(token.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.LastUsedAt == nil {
+			t.Fatal("last_used_at not set")
+		}
+		return *tok.LastUsedAt
+	}
+
+	first := lastUsed()
+	if again := lastUsed(); !again.Equal(first) {
+		t.Errorf("last use written again within a minute: %v, then %v", first, again)
+	}
+	// a minute on, the next request is written down
+	if _, err := h.League.DB().Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", first.Add(-2*time.Minute), token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if later := lastUsed(); !later.After(first) {
+		t.Errorf("last use %v a minute on, want after %v", later, first)
+	}
+}
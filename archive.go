@@ -0,0 +1,98 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrSeasonStarted is returned when the fixture would be replaced after
+// matches have already been played
+var ErrSeasonStarted = errors.New("matches have already been played, use force=true to regenerate the fixture")
+
+func (l *League) createArchiveTables() error {
+	createArchives := `
+	CREATE TABLE IF NOT EXISTS fixture_archives (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		reason TEXT,
+		archived_at TIMESTAMP
+	);`
+
+	createArchivedEvents := `
+	CREATE TABLE IF NOT EXISTS archived_match_events (
+		archive_id INTEGER REFERENCES fixture_archives(id) ON DELETE CASCADE,
+		match_id INTEGER,
+		minute INTEGER,
+		team_id INTEGER REFERENCES teams(id) ON DELETE RESTRICT,
+		type TEXT,
+		detail TEXT
+	);`
+
+	if _, err := l.db.Exec(createArchives); err != nil {
+		return fmt.Errorf("error creating fixture_archives table: %v", err)
+	}
+	if _, err := l.db.Exec(archivedMatchesTable("archived_matches")); err != nil {
+		return fmt.Errorf("error creating archived_matches table: %v", err)
+	}
+	if _, err := l.db.Exec(createArchivedEvents); err != nil {
+		return fmt.Errorf("error creating archived_match_events table: %v", err)
+	}
+	return nil
+}
+
+// archiveFixture copies every current match and its events into a new
+// archive and returns its id
+func archiveFixture(tx *sql.Tx, reason string) (int64, error) {
+	res, err := tx.Exec("INSERT INTO fixture_archives (reason, archived_at) VALUES (?, ?)", reason, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	archiveID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO archived_matches
+			(archive_id, match_id, home_team_id, away_team_id, home_goals, away_goals, played, week, postponed, kickoff, commentary)
+		SELECT ?, id, home_team_id, away_team_id, home_goals, away_goals, played, week, postponed, kickoff, commentary
+		FROM matches`, archiveID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO archived_match_events (archive_id, match_id, minute, team_id, type, detail, player_id)
+		SELECT ?, match_id, minute, team_id, type, detail, player_id
+		FROM match_events`, archiveID)
+	return archiveID, err
+}
+
+// POST /fixture/generate replaces the schedule. Once play has started it needs
+// ?force=true, which is an admin only operation.
+func (l *League) handleGenerateFixture(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	force := r.URL.Query().Get("force") == "true"
+	if force && !isAdmin(r) {
+		http.Error(w, "force=true requires an admin token", http.StatusUnauthorized)
+		return
+	}
+
+	if err := l.GenerateFixture(force); err != nil {
+		if errors.Is(err, ErrSeasonStarted) || errors.Is(err, ErrSeasonPlanned) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Fixture generated successfully"})
+}
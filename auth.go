@@ -0,0 +1,55 @@
+package insider
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// SetAdminToken sets the token that protects destructive operations, from
+// --admin-token or LEAGUE_ADMIN_TOKEN; empty disables admin operations. The
+// leagues the server opens later get the same token.
+func (l *League) SetAdminToken(token string) {
+	l.adminToken = token
+}
+
+type adminKey struct{}
+
+// isAdmin tells whether the request was made with the admin token or an API
+// token with the admin scope, as found by apiTokens
+func isAdmin(r *http.Request) bool {
+	if t := requestAPIToken(r); t != nil {
+		return t.allows(ScopeAdmin)
+	}
+	admin, _ := r.Context().Value(adminKey{}).(bool)
+	return admin
+}
+
+// isAdminToken compares a bearer token with the admin token in constant time
+func (l *League) isAdminToken(token string) bool {
+	if l.adminToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(l.adminToken)) == 1
+}
+
+// readOnlyExempt are the POST endpoints that only compute a prediction and
+// change nothing, so a read-only server keeps them
+var readOnlyExempt = map[string]bool{
+	"/analysis/compare":    true,
+	"/certificates/verify": true,
+	"/jobs/simulate":       true,
+	"/ties/simulate":       true,
+}
+
+// readOnly rejects every request that could change the league, for
+// --read-only. Changes come as POST, PUT or DELETE, so anything but GET and
+// HEAD is refused, apart from the POSTs in readOnlyExempt.
+func readOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !readOnlyExempt[r.URL.Path] {
+			http.Error(w, "Server is read-only", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,55 @@
+package insider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The server as --read-only runs it, admin token and all
+func TestReadOnly(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, 6, 1)
+	l.SetAdminToken("demo-admin")
+	server := httptest.NewServer(readOnly(NewMux(l)))
+	t.Cleanup(server.Close)
+
+	for _, step := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/standings", "", http.StatusOK},
+		{http.MethodHead, "/matches", "", http.StatusOK},
+		{http.MethodPost, "/simulate/week/1", "", http.StatusForbidden},
+		{http.MethodPut, "/settings/rules", `{}`, http.StatusForbidden},
+		{http.MethodPut, "/fixture", `{}`, http.StatusForbidden},
+		{http.MethodDelete, "/admin/tokens/1", "", http.StatusForbidden},
+		{http.MethodDelete, "/matches/1/script", "", http.StatusForbidden},
+		// predictions change nothing and stay open
+		{http.MethodPost, "/ties/simulate", `{"ties": [{"first": "Alpha FC", "second": "Delta SC"}]}`, http.StatusOK},
+	} {
+		req, err := http.NewRequest(step.method, server.URL+step.path, strings.NewReader(step.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer demo-admin")
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != step.want {
+			t.Errorf("%s %s: status %d, want %d", step.method, step.path, resp.StatusCode, step.want)
+		}
+	}
+
+	matches, err := l.Matches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range matches {
+		if m.Played {
+			t.Errorf("match %d played on a read-only server", m.ID)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package insider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// ErrSeasonNotFinished is returned when awards are asked for before every
+// match has been played
+var ErrSeasonNotFinished = errors.New("season is not finished yet")
+
+// Award goes to one team. Value is the number the award was decided on.
+type Award struct {
+	TeamName string `json:"team_name"`
+	Value    int    `json:"value"`
+	Detail   string `json:"detail"`
+}
+
+// SeasonAwards are decided from the final table. There are no players yet,
+// so there is no golden boot either.
+type SeasonAwards struct {
+	Champion     Award  `json:"champion"`
+	BestDefense  Award  `json:"best_defense"`
+	MostImproved *Award `json:"most_improved,omitempty"`
+}
+
+// Awards computes the awards of the finished season. Most improved compares
+// the final position with the pre-season one by strength; it is left out
+// when no team finished above its expected position.
+func (l *League) Awards() (*SeasonAwards, error) {
+	var unplayed int
+	if err := l.db.QueryRow("SELECT COUNT(*) FROM matches WHERE played = FALSE").Scan(&unplayed); err != nil {
+		return nil, err
+	}
+	if unplayed > 0 {
+		return nil, fmt.Errorf("%w: %d matches left", ErrSeasonNotFinished, unplayed)
+	}
+
+	standings, err := l.CalculateStandings()
+	if err != nil {
+		return nil, err
+	}
+	if len(standings) == 0 {
+		return nil, ErrSeasonNotFinished
+	}
+
+	awards := &SeasonAwards{}
+	champion := standings[0]
+	awards.Champion = Award{
+		TeamName: champion.TeamName,
+		Value:    champion.Points,
+		Detail:   fmt.Sprintf("%d points from %d matches", champion.Points, champion.Played),
+	}
+
+	// standings are in table order, so the higher placed team wins a tie
+	best := standings[0]
+	for _, s := range standings[1:] {
+		if s.GoalsAgainst < best.GoalsAgainst {
+			best = s
+		}
+	}
+	awards.BestDefense = Award{
+		TeamName: best.TeamName,
+		Value:    best.GoalsAgainst,
+		Detail:   fmt.Sprintf("%d goals conceded", best.GoalsAgainst),
+	}
+
+	expected := expectedPositions(l.Teams())
+	for i, s := range standings {
+		gain := expected[s.TeamName] - (i + 1)
+		if gain > 0 && (awards.MostImproved == nil || gain > awards.MostImproved.Value) {
+			awards.MostImproved = &Award{
+				TeamName: s.TeamName,
+				Value:    gain,
+				Detail:   fmt.Sprintf("finished %d, expected %d", i+1, expected[s.TeamName]),
+			}
+		}
+	}
+	return awards, nil
+}
+
+// expectedPositions ranks teams by strength, which is where the model expects
+// them to finish before a ball is kicked
+func expectedPositions(teams []Team) map[string]int {
+	sort.SliceStable(teams, func(i, j int) bool {
+		if teams[i].Strength != teams[j].Strength {
+			return teams[i].Strength > teams[j].Strength
+		}
+		return teams[i].Name < teams[j].Name
+	})
+	positions := make(map[string]int, len(teams))
+	for i, t := range teams {
+		positions[t.Name] = i + 1
+	}
+	return positions
+}
+
+// GET /seasons/{id}/awards. Only the current season is kept, so the only
+// id is "current".
+func (l *League) handleSeasonAwards(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("id") != "current" {
+		http.Error(w, "Season not found", http.StatusNotFound)
+		return
+	}
+
+	awards, err := l.Awards()
+	if errors.Is(err, ErrSeasonNotFinished) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(awards)
+}
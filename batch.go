@@ -0,0 +1,121 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ResultUpdate is one item of a batch of manual results
+type ResultUpdate struct {
+	ID        int      `json:"id"`
+	HomeGoals int      `json:"home_goals"`
+	AwayGoals int      `json:"away_goals"`
+	Scorers   []Scorer `json:"scorers,omitempty"`
+}
+
+// ResultError says why one item of a batch was refused
+type ResultError struct {
+	Index int    `json:"index"`
+	ID    int    `json:"id"`
+	Error string `json:"error"`
+}
+
+// BatchError is returned when a batch is refused as a whole because of its
+// items; every refused item is listed, not only the first
+type BatchError struct {
+	Items []ResultError
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of the results are invalid", len(e.Items))
+}
+
+// UpdateMatchResults applies a batch of results in one transaction: either
+// all of them are recorded or none is. The follow-ups of a single update,
+// retroactive recalculations and announcements, run once the batch commits.
+func (l *League) UpdateMatchResults(updates []ResultUpdate) error {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	type applied struct {
+		previous, match Match
+		latestWeek      int
+	}
+	var done []applied
+	batchErr := &BatchError{}
+	seen := make(map[int]bool)
+	for i, u := range updates {
+		if seen[u.ID] {
+			batchErr.Items = append(batchErr.Items, ResultError{Index: i, ID: u.ID, Error: "match appears twice in the batch"})
+			continue
+		}
+		seen[u.ID] = true
+
+		previous, match, latestWeek, err := l.applyResult(tx, u.ID, u.HomeGoals, u.AwayGoals, u.Scorers)
+		switch {
+		case err == sql.ErrNoRows:
+			batchErr.Items = append(batchErr.Items, ResultError{Index: i, ID: u.ID, Error: "match not found"})
+		case errors.Is(err, ErrInvalidScorers), errors.Is(err, ErrInvalidResult):
+			batchErr.Items = append(batchErr.Items, ResultError{Index: i, ID: u.ID, Error: err.Error()})
+		case err != nil:
+			return err
+		default:
+			done = append(done, applied{previous, match, latestWeek})
+		}
+	}
+	if len(batchErr.Items) > 0 {
+		return batchErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.touch()
+	for _, a := range done {
+		if retroactive(a.previous, a.latestWeek) {
+			l.recalculate(a.match, &a.previous)
+		}
+	}
+	if len(done) > 0 {
+		l.afterResult()
+	}
+	return nil
+}
+
+// POST /matches/results records many results at once:
+// [{"id": 1, "home_goals": 2, "away_goals": 0}, ...]. One bad item refuses
+// the whole batch with a 400 listing every bad item by index.
+func (l *League) handleMatchResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var updates []ResultUpdate
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(updates) == 0 {
+		http.Error(w, "No results given", http.StatusBadRequest)
+		return
+	}
+
+	err := l.UpdateMatchResults(updates)
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{"error": batchErr.Error(), "items": batchErr.Items})
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"message": "Matches updated successfully", "updated": len(updates)})
+}
@@ -0,0 +1,63 @@
+package insider_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestBatchResultsAllOrNothing(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 3)
+	matches := h.Matches()
+	first, second := matches[0], matches[1]
+
+	// one bad item keeps the good ones out too
+	err := h.League.UpdateMatchResults([]insider.ResultUpdate{
+		{ID: first.ID, HomeGoals: 2, AwayGoals: 1},
+		{ID: second.ID, HomeGoals: -1, AwayGoals: 0},
+		{ID: 9999, HomeGoals: 1, AwayGoals: 1},
+		{ID: first.ID, HomeGoals: 0, AwayGoals: 0},
+	})
+	var batchErr *insider.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("err = %v, want a BatchError", err)
+	}
+	if len(batchErr.Items) != 3 {
+		t.Fatalf("items = %+v, want 3", batchErr.Items)
+	}
+	for i, want := range []int{1, 2, 3} {
+		if batchErr.Items[i].Index != want {
+			t.Errorf("item %d refused, want %d", batchErr.Items[i].Index, want)
+		}
+	}
+	for _, m := range h.Matches() {
+		if m.Played {
+			t.Fatalf("match %d was recorded by a refused batch", m.ID)
+		}
+	}
+
+	updates := []insider.ResultUpdate{
+		{ID: first.ID, HomeGoals: 2, AwayGoals: 1},
+		{ID: second.ID, HomeGoals: 0, AwayGoals: 3},
+	}
+	if status := h.Do(http.MethodPost, "/matches/results", updates, true, nil); status != http.StatusOK {
+		t.Fatalf("POST /matches/results: status %d", status)
+	}
+	results := make(map[int]insider.Match)
+	for _, m := range h.Matches() {
+		results[m.ID] = m
+	}
+	for _, u := range updates {
+		m := results[u.ID]
+		if !m.Played || m.HomeGoals != u.HomeGoals || m.AwayGoals != u.AwayGoals {
+			t.Errorf("match %d: %d-%d played=%v, want %d-%d", m.ID, m.HomeGoals, m.AwayGoals, m.Played, u.HomeGoals, u.AwayGoals)
+		}
+	}
+
+	if status := h.Do(http.MethodPost, "/matches/results", []insider.ResultUpdate{}, true, nil); status != http.StatusBadRequest {
+		t.Errorf("empty batch: status %d, want 400", status)
+	}
+}
@@ -0,0 +1,103 @@
+package insider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Kickoffs are stored as RFC 3339 text in the match's own offset, so the
+// first ten characters are the calendar date where the match is played.
+// idx_matches_kickoff_date indexes exactly that expression.
+
+// ErrInvalidDateRange is returned for a from or to that is not a date, or a
+// range that ends before it starts
+var ErrInvalidDateRange = errors.New("invalid date range")
+
+const dateLayout = "2006-01-02"
+
+// kickoffDate is the indexed expression, keep it in sync with createIndexes
+const kickoffDate = "substr(m.kickoff, 1, 10)"
+
+// MatchesBetween lists the matches with a kickoff from one date to another,
+// both inclusive and either one optional, in kickoff order. Matches without a
+// kickoff are not on the calendar and never match.
+func (l *League) MatchesBetween(from, to string) ([]Match, error) {
+	for _, d := range []string{from, to} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, d); err != nil {
+			return nil, fmt.Errorf("%w: %q, expected YYYY-MM-DD", ErrInvalidDateRange, d)
+		}
+	}
+	if from != "" && to != "" && to < from {
+		return nil, fmt.Errorf("%w: %s is before %s", ErrInvalidDateRange, to, from)
+	}
+
+	query := matchSelect + " WHERE m.kickoff IS NOT NULL"
+	var args []interface{}
+	if from != "" {
+		query += " AND " + kickoffDate + " >= ?"
+		args = append(args, from)
+	}
+	if to != "" {
+		query += " AND " + kickoffDate + " <= ?"
+		args = append(args, to)
+	}
+	rows, err := l.db.Query(query+" ORDER BY m.kickoff, m.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []Match{}
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// GET /matches/today, by the league's clock
+func (l *League) handleMatchesToday(w http.ResponseWriter, r *http.Request) {
+	today := l.clock.Now().Format(dateLayout)
+	matches, err := l.MatchesBetween(today, today)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(matches)
+}
+
+// StandingsOn is the table after every match played on or before a date.
+// In clock mode matches without a kickoff count on their virtual date.
+func (l *League) StandingsOn(on string) ([]Standing, error) {
+	if l.virtual == nil {
+		matches, err := l.MatchesBetween("", on)
+		if err != nil {
+			return nil, err
+		}
+		return l.standingsFrom(matches, false), nil
+	}
+
+	if _, err := time.Parse(dateLayout, on); err != nil {
+		return nil, fmt.Errorf("%w: %q, expected YYYY-MM-DD", ErrInvalidDateRange, on)
+	}
+	matches, err := l.Matches()
+	if err != nil {
+		return nil, err
+	}
+	var before []Match
+	for _, m := range matches {
+		if l.virtual.kickoff(m).Format(dateLayout) <= on {
+			before = append(before, m)
+		}
+	}
+	return l.standingsFrom(before, false), nil
+}
@@ -0,0 +1,224 @@
+package insider
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// When the last match of a season is played the server signs a certificate
+// of the final table and the awards with its Ed25519 key, so a prediction
+// competition can prove the results it scored against. The certificate
+// carries the SHA-256 of every result, one line per match in id order:
+// "id,week,home,away,home_goals,away_goals\n". A correction after the end
+// gets a new certificate; earlier ones stay in the table for the record.
+
+// SeasonCertificate is the signed part of a certificate
+type SeasonCertificate struct {
+	IssuedAt      time.Time     `json:"issued_at"`
+	Sport         string        `json:"sport"`
+	Matches       int           `json:"matches"`
+	ResultsSHA256 string        `json:"results_sha256"`
+	Standings     []Standing    `json:"standings"`
+	Awards        *SeasonAwards `json:"awards"`
+}
+
+// SignedCertificate is a certificate as issued: the exact bytes signed, the
+// key and the signature, both base64
+type SignedCertificate struct {
+	ID          int             `json:"id"`
+	Certificate json.RawMessage `json:"certificate"`
+	Algorithm   string          `json:"algorithm"`
+	PublicKey   string          `json:"public_key"`
+	Signature   string          `json:"signature"`
+}
+
+// ErrNoCertificate is returned before the first certificate is issued
+var ErrNoCertificate = errors.New("no certificate issued, the season is not finished")
+
+func (l *League) createCertificateTable() error {
+	createCertificates := `
+	CREATE TABLE IF NOT EXISTS season_certificates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		results_sha256 TEXT NOT NULL,
+		certificate TEXT NOT NULL,
+		public_key TEXT NOT NULL,
+		signature TEXT NOT NULL,
+		issued_at TIMESTAMP NOT NULL
+	);`
+
+	if _, err := l.db.Exec(createCertificates); err != nil {
+		return fmt.Errorf("error creating season_certificates table: %v", err)
+	}
+	return nil
+}
+
+// loadSigningKey reads the base64 Ed25519 seed in path, or writes a new one
+// there when the file does not exist yet
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		seed := base64.StdEncoding.EncodeToString(key.Seed()) + "\n"
+		if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%s does not hold a base64 Ed25519 seed", path)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// resultsDigest hashes every match result in id order
+func resultsDigest(matches []Match) string {
+	h := sha256.New()
+	for _, m := range matches {
+		fmt.Fprintf(h, "%d,%d,%s,%s,%d,%d\n", m.ID, m.Week, m.HomeTeam, m.AwayTeam, m.HomeGoals, m.AwayGoals)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// certifySeason issues a certificate once the season is finished, and again
+// when a result changes afterwards. Without a signing key it does nothing.
+func (l *League) certifySeason() error {
+	if l.signingKey == nil {
+		return nil
+	}
+	awards, err := l.Awards()
+	if errors.Is(err, ErrSeasonNotFinished) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	matches, err := l.Matches()
+	if err != nil {
+		return err
+	}
+	digest := resultsDigest(matches)
+	var latest string
+	err = l.db.QueryRow("SELECT results_sha256 FROM season_certificates ORDER BY id DESC LIMIT 1").Scan(&latest)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if latest == digest {
+		return nil
+	}
+
+	standings, err := l.CalculateStandings()
+	if err != nil {
+		return err
+	}
+	c := SeasonCertificate{
+		IssuedAt:      l.clock.Now().UTC(),
+		Sport:         l.config().Sport.Name,
+		Matches:       len(matches),
+		ResultsSHA256: digest,
+		Standings:     standings,
+		Awards:        awards,
+	}
+	body, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	publicKey := base64.StdEncoding.EncodeToString(l.signingKey.Public().(ed25519.PublicKey))
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(l.signingKey, body))
+	_, err = l.db.Exec("INSERT INTO season_certificates (results_sha256, certificate, public_key, signature, issued_at) VALUES (?, ?, ?, ?, ?)",
+		digest, string(body), publicKey, signature, c.IssuedAt)
+	return err
+}
+
+// Certificate is the latest certificate issued
+func (l *League) Certificate() (*SignedCertificate, error) {
+	var c SignedCertificate
+	var body string
+	err := l.db.QueryRow("SELECT id, certificate, public_key, signature FROM season_certificates ORDER BY id DESC LIMIT 1").
+		Scan(&c.ID, &body, &c.PublicKey, &c.Signature)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoCertificate
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Certificate = json.RawMessage(body)
+	c.Algorithm = "ed25519"
+	return &c, nil
+}
+
+// VerifyCertificate checks the signature of a certificate against a public
+// key; the key in the certificate itself proves nothing
+func VerifyCertificate(c SignedCertificate, publicKey ed25519.PublicKey) bool {
+	signature, err := base64.StdEncoding.DecodeString(c.Signature)
+	if err != nil || c.Algorithm != "ed25519" {
+		return false
+	}
+	return ed25519.Verify(publicKey, c.Certificate, signature)
+}
+
+// GET /seasons/{id}/certificate, like the awards only "current" exists
+func (l *League) handleSeasonCertificate(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("id") != "current" {
+		http.Error(w, "Season not found", http.StatusNotFound)
+		return
+	}
+	c, err := l.Certificate()
+	if errors.Is(err, ErrNoCertificate) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(c)
+}
+
+// GET /certificates/key publishes the key certificates are signed with,
+// POST /certificates/verify checks a certificate against it
+func (l *League) handleCertificateKey(w http.ResponseWriter, r *http.Request) {
+	if l.signingKey == nil {
+		http.Error(w, "Certificates are not signed on this server", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"algorithm":  "ed25519",
+		"public_key": base64.StdEncoding.EncodeToString(l.signingKey.Public().(ed25519.PublicKey)),
+	})
+}
+
+func (l *League) handleVerifyCertificate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if l.signingKey == nil {
+		http.Error(w, "Certificates are not signed on this server", http.StatusNotFound)
+		return
+	}
+	var c SignedCertificate
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	valid := VerifyCertificate(c, l.signingKey.Public().(ed25519.PublicKey))
+	json.NewEncoder(w).Encode(map[string]bool{"valid": valid})
+}
@@ -0,0 +1,72 @@
+package insider_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestSeasonCertificate(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 5)
+	path := filepath.Join(t.TempDir(), "league.key")
+	key, err := insider.LoadSigningKey(path)
+	if err != nil {
+		t.Fatalf("new key: %v", err)
+	}
+	if again, err := insider.LoadSigningKey(path); err != nil || !again.Equal(key) {
+		t.Fatalf("key not read back: %v", err)
+	}
+	h.League.SetSigningKey(key)
+	public := key.Public().(ed25519.PublicKey)
+
+	if status := h.Do(http.MethodGet, "/seasons/current/certificate", nil, false, nil); status != http.StatusNotFound {
+		t.Errorf("certificate before the end: status %d, want 404", status)
+	}
+	if err := h.League.SimulateAll(); err != nil {
+		t.Fatalf("simulate: %v", err)
+	}
+
+	var signed insider.SignedCertificate
+	h.Get("/seasons/current/certificate", &signed)
+	if !insider.VerifyCertificate(signed, public) {
+		t.Fatal("certificate does not verify")
+	}
+	var c insider.SeasonCertificate
+	if err := json.Unmarshal(signed.Certificate, &c); err != nil {
+		t.Fatal(err)
+	}
+	matches, err := h.League.Matches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ResultsSHA256 != insider.ResultsDigest(matches) || c.Awards == nil || c.Awards.Champion.TeamName != c.Standings[0].TeamName {
+		t.Errorf("certificate: %+v", c)
+	}
+
+	var verdict map[string]bool
+	if status := h.Do(http.MethodPost, "/certificates/verify", signed, false, &verdict); status != http.StatusOK || !verdict["valid"] {
+		t.Errorf("verify: status %d, %v", status, verdict)
+	}
+	forged := signed
+	forged.Certificate = bytes.Replace(signed.Certificate, []byte(`"points":`), []byte(`"points":1`), 1)
+	if insider.VerifyCertificate(forged, public) {
+		t.Error("tampered certificate verifies")
+	}
+
+	// a corrected result supersedes the certificate
+	m := matches[0]
+	if err := h.League.UpdateMatchResult(m.ID, m.HomeGoals+1, m.AwayGoals, nil); err != nil {
+		t.Fatalf("correct result: %v", err)
+	}
+	var corrected insider.SignedCertificate
+	h.Get("/seasons/current/certificate", &corrected)
+	if corrected.ID == signed.ID || !insider.VerifyCertificate(corrected, public) {
+		t.Errorf("after a correction: certificate %d, verifies %v", corrected.ID, insider.VerifyCertificate(corrected, public))
+	}
+}
@@ -0,0 +1,357 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The champions tournament is a knockout between the champions of the
+// leagues of the server, played by the first league once every season is
+// over. Divisions below the first league and the playoff's lower league
+// send nobody: their winners are promoted instead. The bracket works as the
+// cup's, seeded by points per game so leagues of any size compare, and is
+// played through in one go with extra time and penalties for level ties.
+// One tournament is played per season of the first league and each is kept,
+// bracket and all, as the history.
+
+var (
+	// ErrNoChampionsTournament is returned when there are not two leagues to
+	// take champions from
+	ErrNoChampionsTournament = errors.New("no champions tournament")
+	// ErrChampionsPlayed is returned when this season's tournament has been
+	// played
+	ErrChampionsPlayed = errors.New("the champions tournament of this season has been played")
+)
+
+// ChampionsEntrant is a league's champion in the tournament. Team is the
+// team's name, with its league's after it when two champions share a name.
+type ChampionsEntrant struct {
+	Seed int `json:"seed"`
+	// League is the id under /leagues, 0 for the first league
+	League        int     `json:"league"`
+	LeagueName    string  `json:"league_name"`
+	Team          string  `json:"team"`
+	Points        int     `json:"points"`
+	Played        int     `json:"played"`
+	PointsPerGame float64 `json:"points_per_game"`
+	strength      int
+}
+
+// ChampionsTournament is one tournament played, first round first
+type ChampionsTournament struct {
+	ID             int                `json:"id"`
+	SeasonID       int                `json:"season_id"`
+	Entrants       []ChampionsEntrant `json:"entrants"`
+	Rounds         []CupRound         `json:"rounds"`
+	Champion       string             `json:"champion"`
+	ChampionLeague int                `json:"champion_league"`
+	PlayedAt       time.Time          `json:"played_at"`
+}
+
+func (l *League) createChampionsTable() error {
+	createChampions := `
+	CREATE TABLE IF NOT EXISTS champions_tournaments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		season_id INTEGER NOT NULL UNIQUE,
+		entrants TEXT NOT NULL,
+		rounds TEXT NOT NULL,
+		champion TEXT NOT NULL,
+		champion_league INTEGER NOT NULL,
+		played_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (season_id) REFERENCES seasons(id) ON DELETE CASCADE
+	);`
+
+	if _, err := l.db.Exec(createChampions); err != nil {
+		return fmt.Errorf("error creating champions_tournaments table: %v", err)
+	}
+	return nil
+}
+
+// championsEntrants takes the leader of every finished league that is not a
+// division below another, best seed first
+func (l *League) championsEntrants() ([]ChampionsEntrant, error) {
+	cfg := l.config()
+	below := make(map[int]bool)
+	for _, d := range cfg.Divisions {
+		below[d.League] = true
+	}
+	if cfg.RelegationPlayoff.Position > 0 {
+		below[cfg.RelegationPlayoff.LowerLeague] = true
+	}
+
+	candidates := []LeagueInfo{{ID: 0, Name: "First league"}}
+	for _, info := range l.Leagues() {
+		if !below[info.ID] {
+			candidates = append(candidates, info)
+		}
+	}
+	if len(candidates) < 2 {
+		return nil, fmt.Errorf("%w: it needs the champions of at least 2 leagues, create one with POST /leagues", ErrNoChampionsTournament)
+	}
+
+	var entrants []ChampionsEntrant
+	for _, info := range candidates {
+		league, err := l.divisionLeague(info.ID)
+		if err != nil {
+			return nil, err
+		}
+		finished, err := league.seasonFinished()
+		if err != nil {
+			return nil, err
+		}
+		if !finished {
+			return nil, fmt.Errorf("%w: league %d", ErrSeasonNotFinished, info.ID)
+		}
+		standings, err := league.CalculateStandings()
+		if err != nil {
+			return nil, err
+		}
+		top := standings[0]
+		e := ChampionsEntrant{League: info.ID, LeagueName: info.Name, Team: top.TeamName, Points: top.Points, Played: top.Played}
+		if top.Played > 0 {
+			e.PointsPerGame = float64(top.Points) / float64(top.Played)
+		}
+		for _, t := range league.Teams() {
+			if t.ID == top.TeamID {
+				e.strength = t.Strength
+			}
+		}
+		entrants = append(entrants, e)
+	}
+
+	names := make(map[string]int)
+	for _, e := range entrants {
+		names[e.Team]++
+	}
+	for i := range entrants {
+		if names[entrants[i].Team] > 1 {
+			entrants[i].Team = fmt.Sprintf("%s (%s)", entrants[i].Team, entrants[i].LeagueName)
+		}
+	}
+	// the stronger side breaks a tie on points per game, then the older league
+	sort.SliceStable(entrants, func(i, j int) bool {
+		if entrants[i].PointsPerGame != entrants[j].PointsPerGame {
+			return entrants[i].PointsPerGame > entrants[j].PointsPerGame
+		}
+		return entrants[i].strength > entrants[j].strength
+	})
+	for i := range entrants {
+		entrants[i].Seed = i + 1
+	}
+	return entrants, nil
+}
+
+// playChampionsBracket draws the entrants into a cup bracket and plays it
+// through, each tie hosted by the winner of the upper tie before it
+func playChampionsBracket(e tieEngine, params SimParams, advantages homeAdvantages, entrants []ChampionsEntrant) []CupRound {
+	teams := make(map[string]Team, len(entrants))
+	for _, en := range entrants {
+		teams[en.Team] = Team{Name: en.Team, Strength: en.strength}
+	}
+
+	rounds := cupRounds(len(entrants))
+	order := bracketOrder(1 << rounds)
+	played := make([]CupRound, 0, rounds)
+	for round := 1; round <= rounds; round++ {
+		ties := make([]CupTie, 1<<(rounds-round))
+		for slot := range ties {
+			t := &ties[slot]
+			t.Round, t.Slot = round, slot
+			if round == 1 {
+				t.HomeTeam = entrants[order[2*slot]-1].Team
+				if seed := order[2*slot+1]; seed <= len(entrants) {
+					t.AwayTeam = entrants[seed-1].Team
+				}
+			} else {
+				before := played[round-2].Ties
+				t.HomeTeam, t.AwayTeam = before[2*slot].Winner, before[2*slot+1].Winner
+			}
+			if t.AwayTeam == "" {
+				t.Played, t.DecidedBy, t.Winner = true, DecidedByBye, t.HomeTeam
+				continue
+			}
+			playCupTie(e, params, advantages, t, teams[t.HomeTeam], teams[t.AwayTeam])
+		}
+		played = append(played, CupRound{Round: round, Name: cupRoundName(round, rounds), Ties: ties})
+	}
+	return played
+}
+
+// PlayChampionsTournament plays this season's tournament between the
+// champions of the leagues once all of them are finished, with the seed's
+// own stream when one is given
+func (l *League) PlayChampionsTournament(seed *int64) (*ChampionsTournament, error) {
+	if l.leagues == nil {
+		return nil, fmt.Errorf("%w: only the first league of the server plays it", ErrNoChampionsTournament)
+	}
+	season, err := currentSeason(l.db)
+	if err != nil {
+		return nil, err
+	}
+	var played int
+	if err := l.db.QueryRow("SELECT COUNT(*) FROM champions_tournaments WHERE season_id = ?", season.ID).Scan(&played); err != nil {
+		return nil, err
+	}
+	if played > 0 {
+		return nil, ErrChampionsPlayed
+	}
+	entrants, err := l.championsEntrants()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := l.config()
+	advantages, err := l.homeAdvantages()
+	if err != nil {
+		return nil, err
+	}
+	stream, flavorStream := l.seededStreams(seed)
+	engine := tieEngine{cfg: cfg, rng: rand.New(rand.NewSource(stream.Int63())), flavor: rand.New(rand.NewSource(flavorStream.Int63()))}
+	t := &ChampionsTournament{
+		SeasonID: season.ID,
+		Entrants: entrants,
+		Rounds:   playChampionsBracket(engine, cfg.Simulation, advantages, entrants),
+		PlayedAt: l.clock.Now().UTC(),
+	}
+	final := t.Rounds[len(t.Rounds)-1].Ties[0]
+	t.Champion = final.Winner
+	for _, e := range entrants {
+		if e.Team == t.Champion {
+			t.ChampionLeague = e.League
+		}
+	}
+
+	encodedEntrants, err := json.Marshal(t.Entrants)
+	if err != nil {
+		return nil, err
+	}
+	encodedRounds, err := json.Marshal(t.Rounds)
+	if err != nil {
+		return nil, err
+	}
+	res, err := l.db.Exec(`
+		INSERT INTO champions_tournaments (season_id, entrants, rounds, champion, champion_league, played_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		t.SeasonID, string(encodedEntrants), string(encodedRounds), t.Champion, t.ChampionLeague, t.PlayedAt)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	t.ID = int(id)
+	return t, nil
+}
+
+const championsSelect = `
+	SELECT id, season_id, entrants, rounds, champion, champion_league, played_at FROM champions_tournaments`
+
+func scanChampionsTournament(row rowScanner) (ChampionsTournament, error) {
+	var t ChampionsTournament
+	var entrants, rounds string
+	if err := row.Scan(&t.ID, &t.SeasonID, &entrants, &rounds, &t.Champion, &t.ChampionLeague, &t.PlayedAt); err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal([]byte(entrants), &t.Entrants); err != nil {
+		return t, fmt.Errorf("champions tournament %d: %v", t.ID, err)
+	}
+	if err := json.Unmarshal([]byte(rounds), &t.Rounds); err != nil {
+		return t, fmt.Errorf("champions tournament %d: %v", t.ID, err)
+	}
+	return t, nil
+}
+
+// ChampionsTournaments lists the tournaments played, newest first
+func (l *League) ChampionsTournaments() ([]ChampionsTournament, error) {
+	rows, err := l.db.Query(championsSelect + " ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tournaments := []ChampionsTournament{}
+	for rows.Next() {
+		t, err := scanChampionsTournament(rows)
+		if err != nil {
+			return nil, err
+		}
+		tournaments = append(tournaments, t)
+	}
+	return tournaments, rows.Err()
+}
+
+// ChampionsTournament loads one tournament, sql.ErrNoRows if there is none
+func (l *League) ChampionsTournament(id int) (ChampionsTournament, error) {
+	return scanChampionsTournament(l.db.QueryRow(championsSelect+" WHERE id = ?", id))
+}
+
+// GET /champions lists the champions tournaments; POST plays this season's
+// once every league is finished, ?seed= for a repeatable one (admin only)
+func (l *League) handleChampions(w http.ResponseWriter, r *http.Request) {
+	if l.leagues == nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		tournaments, err := l.ChampionsTournaments()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(tournaments)
+	case http.MethodPost:
+		if !requireAdmin(w, r) {
+			return
+		}
+		seed, err := seedParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		t, err := l.PlayChampionsTournament(seed)
+		switch {
+		case errors.Is(err, ErrNoChampionsTournament), errors.Is(err, ErrChampionsPlayed), errors.Is(err, ErrSeasonNotFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(t)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /champions/{id} shows one tournament with its bracket
+func (l *League) handleChampionsTournament(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || l.leagues == nil {
+		http.Error(w, "Tournament not found", http.StatusNotFound)
+		return
+	}
+	t, err := l.ChampionsTournament(id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Tournament not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(t)
+}
@@ -0,0 +1,132 @@
+package insider_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+// addLeague creates another league of the server and plays its season by
+// strength unless play is false
+func addLeague(t *testing.T, h *leaguetest.Harness, name string, teams []insider.Team, play bool) insider.LeagueInfo {
+	t.Helper()
+	var info insider.LeagueInfo
+	if status := h.Do(http.MethodPost, "/leagues", insider.LeagueRequest{Name: name, Teams: teams}, true, &info); status != http.StatusCreated {
+		t.Fatalf("POST /leagues: status %d", status)
+	}
+	if play {
+		league, err := h.League.DivisionLeague(info.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		insider.PlayByStrength(t, league, 1, info.Weeks)
+	}
+	return info
+}
+
+func TestChampionsTournament(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 12)
+	if status := h.Do(http.MethodPost, "/champions", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("tournament of one league: status %d, want 409", status)
+	}
+
+	// both sides called Alpha FC win their leagues with every game won
+	sunday := addLeague(t, h, "Sunday league", []insider.Team{{Name: "Alpha FC", Strength: 80}, {Name: "Reds", Strength: 60}}, true)
+	weekday := addLeague(t, h, "Weekday league", []insider.Team{{Name: "Yellows", Strength: 50}, {Name: "Greys", Strength: 45}, {Name: "Pinks", Strength: 40}}, true)
+	// a division sends nobody
+	lower := addLeague(t, h, "Second division", []insider.Team{{Name: "Browns", Strength: 90}, {Name: "Blacks", Strength: 30}}, true)
+	cfg := *h.League.Config()
+	cfg.Divisions = []insider.Division{{League: lower.ID, Slots: 1}}
+	h.League.StoreConfig(&cfg)
+
+	if status := h.Do(http.MethodPost, "/champions", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("tournament before the first league finished: status %d, want 409", status)
+	}
+	insider.PlayByStrength(t, h.League, 1, h.League.TotalWeeks())
+	if status := h.Do(http.MethodPost, "/champions?seed=3", nil, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("tournament without the admin token: status %d, want 401", status)
+	}
+
+	var played insider.ChampionsTournament
+	if status := h.Do(http.MethodPost, "/champions?seed=3", nil, true, &played); status != http.StatusCreated {
+		t.Fatalf("POST /champions: status %d", status)
+	}
+	// level on points per game, the stronger Alpha FC is the top seed
+	want := []struct {
+		team   string
+		league int
+	}{{"Alpha FC (First league)", 0}, {"Alpha FC (Sunday league)", sunday.ID}, {"Yellows", weekday.ID}}
+	if len(played.Entrants) != len(want) {
+		t.Fatalf("entrants %+v, want %d", played.Entrants, len(want))
+	}
+	for i, w := range want {
+		e := played.Entrants[i]
+		if e.Seed != i+1 || e.Team != w.team || e.League != w.league || e.PointsPerGame != 3 {
+			t.Errorf("seed %d: %+v, want %s of league %d on 3 points a game", i+1, e, w.team, w.league)
+		}
+	}
+
+	if len(played.Rounds) != 2 || played.Rounds[1].Name != "Final" {
+		t.Fatalf("rounds %+v, want a semi-final round and the final", played.Rounds)
+	}
+	semis := played.Rounds[0].Ties
+	if semis[0].HomeTeam != want[0].team || semis[0].DecidedBy != insider.DecidedByBye {
+		t.Errorf("top seed's tie %+v, want a bye", semis[0])
+	}
+	if semis[1].HomeTeam != want[1].team || semis[1].AwayTeam != want[2].team || !semis[1].Played {
+		t.Errorf("second tie %+v, want seed 2 at home to seed 3", semis[1])
+	}
+	final := played.Rounds[1].Ties[0]
+	if final.HomeTeam != want[0].team || final.AwayTeam != semis[1].Winner || !final.Played {
+		t.Errorf("final %+v, want the top seed against the winner of %s v %s", final, semis[1].HomeTeam, semis[1].AwayTeam)
+	}
+	if played.Champion != final.Winner {
+		t.Errorf("champion %s, the final was won by %s", played.Champion, final.Winner)
+	}
+	for i, w := range want {
+		if w.team == played.Champion && played.ChampionLeague != want[i].league {
+			t.Errorf("champion league %d, want %d", played.ChampionLeague, want[i].league)
+		}
+	}
+
+	if status := h.Do(http.MethodPost, "/champions", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("second tournament in a season: status %d, want 409", status)
+	}
+	var history []insider.ChampionsTournament
+	h.Get("/champions", &history)
+	if len(history) != 1 || history[0].ID != played.ID || history[0].Champion != played.Champion {
+		t.Errorf("history %+v", history)
+	}
+	var stored insider.ChampionsTournament
+	h.Get("/champions/"+strconv.Itoa(played.ID), &stored)
+	if stored.Champion != played.Champion || len(stored.Rounds) != 2 || stored.Rounds[1].Ties[0].Winner != final.Winner {
+		t.Errorf("stored tournament %+v", stored)
+	}
+	if status := h.Do(http.MethodGet, "/champions/99", nil, false, nil); status != http.StatusNotFound {
+		t.Errorf("unknown tournament: status %d, want 404", status)
+	}
+	if status := h.Do(http.MethodGet, sunday.Path+"/champions", nil, false, nil); status != http.StatusNotFound {
+		t.Errorf("tournament of another league: status %d, want 404", status)
+	}
+}
+
+func TestChampionsTournamentSeed(t *testing.T) {
+	var champions []string
+	for range 2 {
+		h := leaguetest.New(t, insider.SnapshotTeams, 12)
+		addLeague(t, h, "Sunday league", []insider.Team{{Name: "Reds", Strength: 80}, {Name: "Blues", Strength: 60}}, true)
+		insider.PlayByStrength(t, h.League, 1, h.League.TotalWeeks())
+		var played insider.ChampionsTournament
+		if status := h.Do(http.MethodPost, "/champions?seed=7", nil, true, &played); status != http.StatusCreated {
+			t.Fatalf("POST /champions: status %d", status)
+		}
+		final := played.Rounds[0].Ties[0]
+		champions = append(champions, final.Winner+" "+strconv.Itoa(final.HomeGoals)+"-"+strconv.Itoa(final.AwayGoals))
+	}
+	if champions[0] != champions[1] {
+		t.Errorf("the same seed played %s and %s", champions[0], champions[1])
+	}
+}
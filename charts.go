@@ -0,0 +1,77 @@
+package insider
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Charts are shaped for plotting rather than reading: one x axis shared by
+// every series, and one array of values per team lined up with it.
+
+// PointsSeries is one team's line on the points progression chart
+type PointsSeries struct {
+	Team      string `json:"team"`
+	Points    []int  `json:"points"`
+	Positions []int  `json:"positions"`
+}
+
+// PointsProgression is the season race: the table as it stood after every
+// week, teams in the order of the latest one
+type PointsProgression struct {
+	Weeks  []int          `json:"weeks"`
+	Series []PointsSeries `json:"series"`
+}
+
+// PointsProgression takes a snapshot of the table after each week up to the
+// latest with a result. A match played out of turn counts in its own week,
+// so a postponed match moves its teams' lines at the week it belonged to.
+func (l *League) PointsProgression() (*PointsProgression, error) {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return nil, err
+	}
+
+	chart := &PointsProgression{Weeks: []int{}, Series: []PointsSeries{}}
+	lines := make(map[string]*PointsSeries, len(state.teams))
+	var table []Standing
+	for week := 1; week <= state.latestWeek(); week++ {
+		played, _ := state.split(week)
+		table = state.standings(played)
+		chart.Weeks = append(chart.Weeks, week)
+		for _, s := range table {
+			line := lines[s.TeamName]
+			if line == nil {
+				line = &PointsSeries{Team: s.TeamName}
+				lines[s.TeamName] = line
+			}
+			line.Points = append(line.Points, s.Points)
+			line.Positions = append(line.Positions, s.Rank)
+		}
+	}
+
+	// before the first result every team has an empty line
+	if table == nil {
+		for _, name := range state.teams {
+			chart.Series = append(chart.Series, PointsSeries{Team: name, Points: []int{}, Positions: []int{}})
+		}
+		return chart, nil
+	}
+	for _, s := range table {
+		chart.Series = append(chart.Series, *lines[s.TeamName])
+	}
+	return chart, nil
+}
+
+// GET /charts/points-progression
+func (l *League) handlePointsProgression(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	chart, err := l.PointsProgression()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(chart)
+}
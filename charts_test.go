@@ -0,0 +1,50 @@
+package insider_test
+
+import (
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestPointsProgressionFollowsTheTable(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 3)
+	var empty insider.PointsProgression
+	h.Get("/charts/points-progression", &empty)
+	if len(empty.Weeks) != 0 || len(empty.Series) != len(insider.SnapshotTeams) {
+		t.Fatalf("before any result: %+v", empty)
+	}
+
+	for week := 1; week <= 2; week++ {
+		if err := h.League.SimulateWeek(week); err != nil {
+			t.Fatalf("simulate week %d: %v", week, err)
+		}
+	}
+	var chart insider.PointsProgression
+	h.Get("/charts/points-progression", &chart)
+	if len(chart.Weeks) != 2 || chart.Weeks[0] != 1 || chart.Weeks[1] != 2 {
+		t.Fatalf("weeks = %v, want [1 2]", chart.Weeks)
+	}
+
+	standings, err := h.League.CalculateStandings()
+	if err != nil {
+		t.Fatalf("standings: %v", err)
+	}
+	if len(chart.Series) != len(standings) {
+		t.Fatalf("%d series for %d teams", len(chart.Series), len(standings))
+	}
+	for i, line := range chart.Series {
+		if line.Team != standings[i].TeamName {
+			t.Errorf("series %d is %s, table has %s there", i, line.Team, standings[i].TeamName)
+		}
+		if len(line.Points) != 2 || len(line.Positions) != 2 {
+			t.Fatalf("%s: %d points and %d positions for 2 weeks", line.Team, len(line.Points), len(line.Positions))
+		}
+		if line.Points[0] > line.Points[1] {
+			t.Errorf("%s lost points: %v", line.Team, line.Points)
+		}
+		if line.Points[1] != standings[i].Points {
+			t.Errorf("%s ends on %d points, table has %d", line.Team, line.Points[1], standings[i].Points)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package insider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// In clock mode the server keeps a virtual time that runs speed times as
+// fast as the real one and plays every match once its kickoff has passed.
+// A match kicks off at its own kickoff if it has one, otherwise week n
+// starts n-1 weeks after the clock's season start.
+//
+// Everything that asks what time it is in the league, whether a kickoff has
+// passed, which matches are today, the stamp on a calendar or an export,
+// asks the league's Clock. That is the system clock, or the virtual clock
+// in clock mode, so both modes run the same code.
+
+// Clock tells the time
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the real time
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// clockTick is how often, in real time, the clock looks for due matches
+const clockTick = time.Second
+
+// ErrClockOff is returned when the clock is used without clock mode
+var ErrClockOff = errors.New("clock mode is off, start the server with --clock-speed")
+
+// ErrInvalidClock is returned for a speed or time the clock cannot take
+var ErrInvalidClock = errors.New("invalid clock setting")
+
+type virtualClock struct {
+	// advancing keeps the ticker and admin updates from playing a week twice
+	advancing sync.Mutex
+	mu        sync.Mutex
+	// seasonStart is the virtual kickoff of week 1
+	seasonStart time.Time
+	// real drives the virtual time, which was virtual when real said realAt
+	real    Clock
+	virtual time.Time
+	realAt  time.Time
+	speed   float64
+	paused  bool
+}
+
+func newVirtualClock(real Clock, seasonStart time.Time, speed float64) *virtualClock {
+	return &virtualClock{real: real, seasonStart: seasonStart, virtual: seasonStart, realAt: real.Now(), speed: speed}
+}
+
+// Now is the current virtual time
+func (c *virtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now()
+}
+
+// now is the current virtual time, c.mu must be held
+func (c *virtualClock) now() time.Time {
+	if c.paused {
+		return c.virtual
+	}
+	elapsed := c.real.Now().Sub(c.realAt)
+	return c.virtual.Add(time.Duration(float64(elapsed) * c.speed))
+}
+
+// rebase freezes the virtual time reached so far before a setting changes
+func (c *virtualClock) rebase() {
+	c.virtual = c.now()
+	c.realAt = c.real.Now()
+}
+
+// kickoff is when a match starts on the virtual calendar
+func (c *virtualClock) kickoff(m Match) time.Time {
+	if t, ok := storedKickoff(m); ok {
+		return t
+	}
+	return c.seasonStart.AddDate(0, 0, 7*(m.Week-1))
+}
+
+// ClockState is what GET /admin/clock reports
+type ClockState struct {
+	Now         time.Time  `json:"now"`
+	Speed       float64    `json:"speed"`
+	Paused      bool       `json:"paused"`
+	SeasonStart time.Time  `json:"season_start"`
+	NextKickoff *time.Time `json:"next_kickoff,omitempty"`
+}
+
+// ClockUpdate changes the clock; fields left out stay as they are. Now jumps
+// the virtual time, backwards too, but played matches stay played.
+type ClockUpdate struct {
+	Paused *bool      `json:"paused"`
+	Speed  *float64   `json:"speed"`
+	Now    *time.Time `json:"now"`
+}
+
+// ClockState reports the virtual time and the next match due
+func (l *League) ClockState() (*ClockState, error) {
+	if l.virtual == nil {
+		return nil, ErrClockOff
+	}
+	matches, err := l.Matches()
+	if err != nil {
+		return nil, err
+	}
+
+	c := l.virtual
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := &ClockState{Now: c.now(), Speed: c.speed, Paused: c.paused, SeasonStart: c.seasonStart}
+	for _, m := range matches {
+		if m.Played || m.Postponed || m.Live {
+			continue
+		}
+		if k := c.kickoff(m); state.NextKickoff == nil || k.Before(*state.NextKickoff) {
+			state.NextKickoff = &k
+		}
+	}
+	return state, nil
+}
+
+// UpdateClock pauses, resumes, speeds up or moves the clock
+func (l *League) UpdateClock(u ClockUpdate) (*ClockState, error) {
+	if l.virtual == nil {
+		return nil, ErrClockOff
+	}
+	if u.Speed != nil && *u.Speed <= 0 {
+		return nil, fmt.Errorf("%w: speed must be positive, pause the clock to stop it", ErrInvalidClock)
+	}
+
+	c := l.virtual
+	c.mu.Lock()
+	c.rebase()
+	if u.Now != nil {
+		c.virtual = *u.Now
+	}
+	if u.Speed != nil {
+		c.speed = *u.Speed
+	}
+	if u.Paused != nil {
+		c.paused = *u.Paused
+	}
+	c.mu.Unlock()
+
+	l.advanceClock()
+	return l.ClockState()
+}
+
+// advanceClock plays the matches whose kickoff has passed, week by week.
+// Weeks with nothing due are left alone, so polling does not touch the league.
+func (l *League) advanceClock() {
+	// a config reload can switch to live mode, then admins enter the results
+	if l.config().Live {
+		return
+	}
+	// a frozen league keeps its due matches until the freeze is lifted
+	if l.frozen.Load() != nil {
+		return
+	}
+	c := l.virtual
+	c.advancing.Lock()
+	defer c.advancing.Unlock()
+
+	matches, err := l.Matches()
+	if err != nil {
+		fmt.Println("Clock failed to load matches:", err)
+		return
+	}
+	now := c.Now()
+	due := func(m Match) bool {
+		return !c.kickoff(m).After(now)
+	}
+
+	weeks := make(map[int]bool)
+	for _, m := range matches {
+		if !m.Played && !m.Postponed && !m.Live && due(m) {
+			weeks[m.Week] = true
+		}
+	}
+	for week := 1; week <= l.weeks; week++ {
+		if !weeks[week] {
+			continue
+		}
+		if err := l.simulateWeek(week, due, l.rng, l.flavor); err != nil {
+			fmt.Printf("Clock failed to play week %d: %v\n", week, err)
+			return
+		}
+	}
+}
+
+// runClock advances the clock until the server stops
+func (l *League) runClock() {
+	l.advanceClock()
+	ticker := time.NewTicker(clockTick)
+	defer ticker.Stop()
+	for range ticker.C {
+		l.advanceClock()
+	}
+}
+
+// GET /admin/clock shows the virtual time, POST changes it
+// {"paused": true, "speed": 7, "now": "2025-08-30T15:00:00Z"}
+func (l *League) handleClock(w http.ResponseWriter, r *http.Request) {
+	var state *ClockState
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		state, err = l.ClockState()
+	case http.MethodPost:
+		if !isAdmin(r) {
+			http.Error(w, "Admin token required", http.StatusUnauthorized)
+			return
+		}
+		var u ClockUpdate
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		state, err = l.UpdateClock(u)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case errors.Is(err, ErrClockOff):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, ErrInvalidClock):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(state)
+}
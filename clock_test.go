@@ -0,0 +1,57 @@
+package insider
+
+import (
+	"testing"
+	"time"
+)
+
+// manualClock only moves when the test moves it
+type manualClock struct {
+	t time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.t
+}
+
+func TestVirtualClockPlaysDueWeeks(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, 6, 3)
+	seasonStart := time.Date(2025, 8, 2, 15, 0, 0, 0, time.UTC)
+	real := &manualClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l.virtual = newVirtualClock(real, seasonStart, 7)
+	l.clock = l.virtual
+
+	matches, err := l.Matches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var week2 Match
+	for _, m := range matches {
+		if m.Week == 2 {
+			week2 = m
+			break
+		}
+	}
+	if !l.predictionOpen(week2) {
+		t.Fatal("week 2 closed before the season started")
+	}
+
+	// a real day is a virtual week, so week 2 kicks off now
+	real.t = real.t.Add(24 * time.Hour)
+	if got, want := l.clock.Now(), seasonStart.AddDate(0, 0, 7); !got.Equal(want) {
+		t.Fatalf("virtual time %v, want %v", got, want)
+	}
+	if l.predictionOpen(week2) {
+		t.Error("week 2 still open at kickoff")
+	}
+
+	l.advanceClock()
+	if matches, err = l.Matches(); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range matches {
+		if m.Played != (m.Week <= 2) {
+			t.Errorf("week %d match %d: played %v", m.Week, m.ID, m.Played)
+		}
+	}
+}
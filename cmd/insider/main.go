@@ -0,0 +1,9 @@
+// Command insider serves the league API on :8080, see the README for the
+// flags
+package main
+
+import "insider"
+
+func main() {
+	insider.Main()
+}
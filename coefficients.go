@@ -0,0 +1,140 @@
+package insider
+
+import (
+	"math"
+	"sort"
+)
+
+// A coefficient rates a team on its recent seasons the way European
+// football rates clubs, so a good run is rewarded for a few years rather
+// than only while it lasts. A season is worth a point for every team the
+// club finished above plus one, and a title bonus for the champion of a
+// complete season. The newest of the last coefficientSeasons seasons counts
+// in full and each one before it a fifth less. The current season counts
+// as it stands once a match is played; archived fixtures replaced halfway
+// do not count. Ties are played on demand and never stored, so cup runs add
+// nothing yet.
+
+const (
+	coefficientSeasons = 5
+	// titleBonus is added to the champion's points for the season
+	titleBonus = 2
+)
+
+// CoefficientSeason is what one season added to a coefficient
+type CoefficientSeason struct {
+	Season   string  `json:"season"`
+	Position int     `json:"position"`
+	Points   int     `json:"points"`
+	Weight   float64 `json:"weight"`
+}
+
+// TeamCoefficient is a team's weighted record over the recent seasons
+type TeamCoefficient struct {
+	Rank        int                 `json:"rank"`
+	TeamID      int                 `json:"team_id"`
+	TeamName    string              `json:"team_name"`
+	Coefficient float64             `json:"coefficient"`
+	Seasons     []CoefficientSeason `json:"seasons"`
+}
+
+// Coefficients ranks every team by coefficient, best first. Teams level on
+// coefficient keep the order of the latest season.
+func (l *League) Coefficients() ([]TeamCoefficient, error) {
+	seasons, err := l.seasons()
+	if err != nil {
+		return nil, err
+	}
+
+	// the seasons that count, newest first
+	var counted []pastSeason
+	for i := len(seasons) - 1; i >= 0 && len(counted) < coefficientSeasons; i-- {
+		s := seasons[i]
+		if s.ID == "current" && !s.Complete {
+			started := false
+			for _, st := range s.Standings {
+				started = started || st.Played > 0
+			}
+			if !started {
+				continue
+			}
+		} else if !s.Complete {
+			continue
+		}
+		counted = append(counted, s)
+	}
+
+	byTeam := make(map[int]*TeamCoefficient)
+	var order []int
+	for _, t := range l.Teams() {
+		byTeam[t.ID] = &TeamCoefficient{TeamID: t.ID, TeamName: t.Name, Seasons: []CoefficientSeason{}}
+		order = append(order, t.ID)
+	}
+	for n, season := range counted {
+		weight := 1 - float64(n)/coefficientSeasons
+		for i, s := range season.Standings {
+			c := byTeam[s.TeamID]
+			if c == nil {
+				continue
+			}
+			points := len(season.Standings) - i
+			if i == 0 && season.Complete {
+				points += titleBonus
+			}
+			c.Coefficient += weight * float64(points)
+			c.Seasons = append(c.Seasons, CoefficientSeason{Season: season.ID, Position: i + 1, Points: points, Weight: weight})
+		}
+	}
+	if len(counted) > 0 {
+		order = order[:0]
+		for _, s := range counted[0].Standings {
+			if byTeam[s.TeamID] != nil {
+				order = append(order, s.TeamID)
+			}
+		}
+	}
+
+	coefficients := make([]TeamCoefficient, 0, len(order))
+	for _, id := range order {
+		c := byTeam[id]
+		c.Coefficient = math.Round(c.Coefficient*1000) / 1000
+		coefficients = append(coefficients, *c)
+	}
+	sort.SliceStable(coefficients, func(i, j int) bool {
+		return coefficients[i].Coefficient > coefficients[j].Coefficient
+	})
+	for i := range coefficients {
+		coefficients[i].Rank = i + 1
+	}
+	return coefficients, nil
+}
+
+// seedPairings puts the team with the better coefficient at home in the
+// second leg of every tie. Names the league does not know are left for
+// SimulateTies to refuse.
+func (l *League) seedPairings(pairings []TiePairing) ([]TiePairing, error) {
+	coefficients, err := l.Coefficients()
+	if err != nil {
+		return nil, err
+	}
+	rank := make(map[string]int, len(coefficients))
+	for _, c := range coefficients {
+		rank[c.TeamName] = c.Rank
+	}
+	resolved := func(name string) string {
+		if _, current, err := l.resolveTeam(name); err == nil {
+			return current
+		}
+		return name
+	}
+
+	seeded := make([]TiePairing, len(pairings))
+	for i, p := range pairings {
+		first, second := rank[resolved(p.First)], rank[resolved(p.Second)]
+		if first != 0 && second != 0 && first < second {
+			p.First, p.Second = p.Second, p.First
+		}
+		seeded[i] = p
+	}
+	return seeded, nil
+}
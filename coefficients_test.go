@@ -0,0 +1,62 @@
+package insider
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestCoefficientsWeighRecentSeasons(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, 6, 1)
+	// seasons 1 to 6 complete, 7 replaced halfway, the current one unplayed
+	archiveSeasons(t, l, 7, rand.New(rand.NewSource(3)))
+
+	seasons, err := l.seasons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the five counted are archives 6 down to 2
+	want := make(map[string]float64)
+	for n, id := range []int{6, 5, 4, 3, 2} {
+		season := seasons[id-1]
+		weight := 1 - float64(n)/coefficientSeasons
+		for i, s := range season.Standings {
+			points := len(season.Standings) - i
+			if i == 0 {
+				points += titleBonus
+			}
+			want[s.TeamName] += weight * float64(points)
+		}
+	}
+
+	coefficients, err := l.Coefficients()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(coefficients) != len(snapshotTeams) {
+		t.Fatalf("%d coefficients for %d teams", len(coefficients), len(snapshotTeams))
+	}
+	for i, c := range coefficients {
+		if math.Abs(c.Coefficient-want[c.TeamName]) > 0.001 {
+			t.Errorf("%s: coefficient %v, want %v", c.TeamName, c.Coefficient, want[c.TeamName])
+		}
+		if len(c.Seasons) != coefficientSeasons || c.Seasons[0].Season != "6" || c.Seasons[0].Weight != 1 {
+			t.Errorf("%s: seasons %+v", c.TeamName, c.Seasons)
+		}
+		if c.Rank != i+1 || (i > 0 && c.Coefficient > coefficients[i-1].Coefficient) {
+			t.Errorf("%s ranked %d with %v", c.TeamName, c.Rank, c.Coefficient)
+		}
+	}
+
+	// seeding hands the better side the second leg at home
+	best, worst := coefficients[0].TeamName, coefficients[len(coefficients)-1].TeamName
+	seeded, err := l.seedPairings([]TiePairing{{First: best, Second: worst}, {First: worst, Second: best}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range seeded {
+		if p.First != worst || p.Second != best {
+			t.Errorf("seeded pairing %+v, want %s hosting the second leg", p, best)
+		}
+	}
+}
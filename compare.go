@@ -0,0 +1,176 @@
+package insider
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+)
+
+// PredictedRow is one team in a Monte Carlo predicted table
+type PredictedRow struct {
+	TeamName         string  `json:"team_name"`
+	ExpectedPoints   float64 `json:"expected_points"`
+	ExpectedPosition float64 `json:"expected_position"`
+	TitleProbability float64 `json:"title_probability"`
+}
+
+type ModelTable struct {
+	Params SimParams      `json:"params"`
+	Table  []PredictedRow `json:"table"`
+}
+
+// TeamDivergence is how far the two models disagree about one team
+type TeamDivergence struct {
+	TeamName       string  `json:"team_name"`
+	PointsDiff     float64 `json:"points_diff"`
+	PositionDiff   float64 `json:"position_diff"`
+	TitleDiff      float64 `json:"title_probability_diff"`
+	PositionTVDist float64 `json:"position_total_variation"`
+}
+
+type Divergence struct {
+	MeanAbsPointsDiff float64          `json:"mean_abs_points_diff"`
+	MaxAbsPointsDiff  float64          `json:"max_abs_points_diff"`
+	RankCorrelation   float64          `json:"rank_correlation"`
+	Teams             []TeamDivergence `json:"teams"`
+}
+
+type ModelComparison struct {
+	Runs       int        `json:"runs"`
+	A          ModelTable `json:"a"`
+	B          ModelTable `json:"b"`
+	Divergence Divergence `json:"divergence"`
+}
+
+// CompareModels predicts the rest of the season under two parameter sets.
+// Both sides draw from generators with the same seed, so differences come from
+// the parameters rather than from sampling noise.
+func (l *League) CompareModels(a, b SimParams, runs int) (*ModelComparison, error) {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return nil, err
+	}
+	played, remaining := state.current()
+
+	seed := l.predict.Int63()
+	summaryA := monteCarlo(rand.New(rand.NewSource(seed)), a, state, played, remaining, runs)
+	summaryB := monteCarlo(rand.New(rand.NewSource(seed)), b, state, played, remaining, runs)
+
+	comparison := &ModelComparison{
+		Runs: runs,
+		A:    ModelTable{Params: a, Table: predictedTable(state.teams, summaryA)},
+		B:    ModelTable{Params: b, Table: predictedTable(state.teams, summaryB)},
+	}
+
+	rowsA := make(map[string]PredictedRow)
+	for _, row := range comparison.A.Table {
+		rowsA[row.TeamName] = row
+	}
+	rowsB := make(map[string]PredictedRow)
+	for _, row := range comparison.B.Table {
+		rowsB[row.TeamName] = row
+	}
+
+	div := &comparison.Divergence
+	for _, team := range state.teams {
+		ra, rb := rowsA[team], rowsB[team]
+		td := TeamDivergence{
+			TeamName:     team,
+			PointsDiff:   rb.ExpectedPoints - ra.ExpectedPoints,
+			PositionDiff: rb.ExpectedPosition - ra.ExpectedPosition,
+			TitleDiff:    rb.TitleProbability - ra.TitleProbability,
+		}
+		for position := 1; position <= len(state.teams); position++ {
+			td.PositionTVDist += math.Abs(summaryA.probability(team, position) - summaryB.probability(team, position))
+		}
+		td.PositionTVDist /= 2
+
+		div.MeanAbsPointsDiff += math.Abs(td.PointsDiff)
+		div.MaxAbsPointsDiff = math.Max(div.MaxAbsPointsDiff, math.Abs(td.PointsDiff))
+		div.Teams = append(div.Teams, td)
+	}
+	if len(state.teams) > 0 {
+		div.MeanAbsPointsDiff /= float64(len(state.teams))
+	}
+	div.RankCorrelation = spearman(comparison.A.Table, comparison.B.Table)
+
+	return comparison, nil
+}
+
+// predictedTable turns a summary into a table ordered by expected points
+func predictedTable(teams []string, summary *simulationSummary) []PredictedRow {
+	table := make([]PredictedRow, 0, len(teams))
+	for _, team := range teams {
+		row := PredictedRow{
+			TeamName:         team,
+			ExpectedPoints:   summary.expected(summary.Points[team]),
+			TitleProbability: summary.probability(team, 1),
+		}
+		for position := 1; position <= len(teams); position++ {
+			row.ExpectedPosition += float64(position) * summary.probability(team, position)
+		}
+		table = append(table, row)
+	}
+	sort.SliceStable(table, func(i, j int) bool {
+		return table[i].ExpectedPoints > table[j].ExpectedPoints
+	})
+	return table
+}
+
+// spearman is the rank correlation of two orderings of the same teams
+func spearman(a, b []PredictedRow) float64 {
+	n := len(a)
+	if n < 2 {
+		return 1
+	}
+	rankB := make(map[string]int, n)
+	for i, row := range b {
+		rankB[row.TeamName] = i
+	}
+	sum := 0.0
+	for i, row := range a {
+		d := float64(i - rankB[row.TeamName])
+		sum += d * d
+	}
+	return 1 - 6*sum/float64(n*(n*n-1))
+}
+
+// POST /analysis/compare with {"a": {...params}, "b": {...params}, "runs": n}
+func (l *League) handleCompareModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := struct {
+		A    SimParams `json:"a"`
+		B    SimParams `json:"b"`
+		Runs int       `json:"runs"`
+	}{A: l.config().Simulation, B: l.config().Simulation, Runs: defaultRuns}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := req.A.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("model a: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := req.B.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("model b: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Runs < 1 || req.Runs > maxRuns {
+		http.Error(w, fmt.Sprintf("runs must be between 1 and %d", maxRuns), http.StatusBadRequest)
+		return
+	}
+
+	comparison, err := l.CompareModels(req.A, req.B, req.Runs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(comparison)
+}
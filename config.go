@@ -0,0 +1,210 @@
+package insider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// Zone marks a band of table positions, e.g. champion or relegation
+type Zone struct {
+	Name string `json:"name"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+// Config holds the settings that can change while the server is running
+type Config struct {
+	Sport        Sport     `json:"sport"`
+	Simulation   SimParams `json:"simulation"`
+	VARFrequency float64   `json:"var_frequency"`
+	Zones        []Zone    `json:"zones"`
+	Webhooks     []string  `json:"webhooks"`
+	// Live leagues are tracked, not simulated
+	Live bool `json:"live"`
+	// SharedRanks gives exactly tied teams the same rank in the table
+	SharedRanks bool `json:"shared_ranks"`
+	// AwayGoalsRule settles level two-legged ties on away goals
+	AwayGoalsRule bool `json:"away_goals_rule"`
+	// Pressure weakens popular teams in big matches, see popularity.go
+	Pressure bool `json:"pressure"`
+	// Managers adds the managers' tactics to strength and lets boards sack them
+	Managers bool `json:"managers"`
+	// PredictionPoints scores the users' guesses in the prediction game
+	PredictionPoints PredictionPoints `json:"prediction_points"`
+	// Travel tires away sides on long trips, see travel.go
+	Travel Travel `json:"travel"`
+	// HomeAdvantages sets or learns a home advantage per team, see
+	// homeadvantage.go
+	HomeAdvantages HomeAdvantages `json:"home_advantages"`
+	// DynamicStrength moves strengths with results, see rating.go
+	DynamicStrength DynamicStrength `json:"dynamic_strength"`
+	// Squads shapes and names the generated squads, see squads.go
+	Squads SquadConfig `json:"squads"`
+	// RelegationPlayoff plays off a place against a lower division, see
+	// playoff.go
+	RelegationPlayoff RelegationPlayoff `json:"relegation_playoff"`
+	// Divisions are the leagues below this one, top first, see divisions.go
+	Divisions []Division `json:"divisions"`
+	// Seed reseeds the random streams when the config is loaded with a
+	// seed it did not have before
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Sport:            defaultSport,
+		Simulation:       defaultSimParams,
+		VARFrequency:     defaultVARFrequency,
+		PredictionPoints: defaultPredictionPoints,
+		DynamicStrength:  defaultDynamicStrength,
+		Squads:           defaultSquadConfig,
+	}
+}
+
+func (c Config) Validate() error {
+	if err := c.Sport.Validate(); err != nil {
+		return fmt.Errorf("sport: %v", err)
+	}
+	if err := c.Simulation.Validate(); err != nil {
+		return fmt.Errorf("simulation: %v", err)
+	}
+	if c.VARFrequency < 0 || c.VARFrequency > 1 {
+		return fmt.Errorf("var_frequency must be between 0 and 1, got %v", c.VARFrequency)
+	}
+	for _, z := range c.Zones {
+		if z.Name == "" {
+			return fmt.Errorf("zone name cannot be empty")
+		}
+		if z.From < 1 || z.To < z.From {
+			return fmt.Errorf("zone %s: invalid positions %d-%d", z.Name, z.From, z.To)
+		}
+	}
+	if err := c.Travel.Validate(); err != nil {
+		return fmt.Errorf("travel: %v", err)
+	}
+	if err := c.HomeAdvantages.Validate(); err != nil {
+		return fmt.Errorf("home_advantages: %v", err)
+	}
+	if err := c.DynamicStrength.Validate(); err != nil {
+		return fmt.Errorf("dynamic_strength: %v", err)
+	}
+	if err := c.Squads.Validate(); err != nil {
+		return fmt.Errorf("squads: %v", err)
+	}
+	if err := c.RelegationPlayoff.Validate(); err != nil {
+		return fmt.Errorf("relegation_playoff: %v", err)
+	}
+	if err := validateDivisions(c.Divisions); err != nil {
+		return fmt.Errorf("divisions: %v", err)
+	}
+	if err := c.PredictionPoints.Validate(); err != nil {
+		return fmt.Errorf("prediction_points: %v", err)
+	}
+	for _, target := range c.Webhooks {
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook url %q", target)
+		}
+	}
+	return nil
+}
+
+// LoadConfig reads a JSON config file on top of base. Fields missing from
+// the file keep the base value.
+func LoadConfig(path string, base Config) (Config, error) {
+	cfg := base
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("error decoding %s: %v", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// config returns the settings currently in effect
+func (l *League) config() *Config {
+	return l.cfg.Load()
+}
+
+// ReloadConfig re-reads the config file and swaps it in. On error the
+// running config is kept.
+func (l *League) ReloadConfig() error {
+	if l.configFile == "" {
+		return fmt.Errorf("no config file, start the server with --config")
+	}
+	l.configMu.Lock()
+	base := l.baseConfig
+	l.configMu.Unlock()
+	cfg, err := LoadConfig(l.configFile, base)
+	if err != nil {
+		return err
+	}
+	previous := l.cfg.Swap(&cfg)
+	if cfg.Seed != nil && (previous == nil || previous.Seed == nil || *previous.Seed != *cfg.Seed) {
+		l.Seed(*cfg.Seed)
+	}
+	return nil
+}
+
+func (l *League) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAdmin(r) {
+		http.Error(w, "Admin token required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := l.ReloadConfig(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Config reloaded",
+		"config":  l.config(),
+	})
+}
+
+// GET /config shows the settings in effect and the seed of the random
+// streams, null when they were not seeded. POST /config?seed=n reseeds them
+// (admin only) to replay a run.
+func (l *League) handleConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !requireAdmin(w, r) {
+			return
+		}
+		seed, err := seedParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if seed == nil {
+			http.Error(w, "seed parameter required", http.StatusBadRequest)
+			return
+		}
+		l.Seed(*seed)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// webhook addresses can carry credentials
+	cfg := *l.config()
+	if !isAdmin(r) {
+		cfg.Webhooks = nil
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"seed":   l.seed.Load(),
+		"config": cfg,
+	})
+}
@@ -0,0 +1,536 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+)
+
+// The cup is a single-elimination knockout next to the league, drawn from
+// the league's teams and seeded by the current table: the bracket has the
+// next power of two of slots, the top seeds get byes into round two and 1
+// can only meet 2 in the final. Ties are single matches hosted by the
+// winner of the upper tie before them. A level tie goes to thirty minutes of
+// extra time and then penalties, as in the second leg of a two-legged tie.
+// Cup matches never count in the league table.
+
+// how a cup tie was decided, besides extra time and penalties
+const (
+	DecidedByNormalTime = "normal_time"
+	// DecidedByBye is a first round tie with one team in it
+	DecidedByBye = "bye"
+)
+
+var (
+	// ErrNoCup is returned before a cup is drawn
+	ErrNoCup = errors.New("no cup has been drawn")
+	// ErrCupStarted is returned when drawing a cup over one already being
+	// played
+	ErrCupStarted = errors.New("the cup has started, use replace=true")
+	// ErrCupRound is returned when a round cannot be played yet or again
+	ErrCupRound = errors.New("the round cannot be played")
+)
+
+// CupPenalties is the shootout of a cup tie, the home side kicking first
+type CupPenalties struct {
+	HomeGoals int           `json:"home_goals"`
+	AwayGoals int           `json:"away_goals"`
+	Kicks     []PenaltyKick `json:"kicks"`
+}
+
+// CupTie is one tie of the bracket. The teams of a later round are filled
+// in as the ties before it are won; HomeGoals and AwayGoals are after normal
+// time.
+type CupTie struct {
+	ID        int           `json:"id"`
+	Round     int           `json:"round"`
+	Slot      int           `json:"slot"`
+	HomeTeam  string        `json:"home_team,omitempty"`
+	AwayTeam  string        `json:"away_team,omitempty"`
+	Played    bool          `json:"played"`
+	HomeGoals int           `json:"home_goals"`
+	AwayGoals int           `json:"away_goals"`
+	ExtraTime *ExtraTime    `json:"extra_time,omitempty"`
+	Penalties *CupPenalties `json:"penalties,omitempty"`
+	DecidedBy string        `json:"decided_by,omitempty"`
+	Winner    string        `json:"winner,omitempty"`
+	// Events are kept when the sport tracks them, extra time included
+	Events []MatchEvent `json:"events,omitempty"`
+
+	homeID, awayID int
+}
+
+// CupRound is one round of the bracket
+type CupRound struct {
+	Round int      `json:"round"`
+	Name  string   `json:"name"`
+	Ties  []CupTie `json:"ties"`
+}
+
+// CupBracket is the whole cup, first round first
+type CupBracket struct {
+	Teams    int        `json:"teams"`
+	Rounds   []CupRound `json:"rounds"`
+	Champion string     `json:"champion,omitempty"`
+}
+
+func (l *League) createCupTable() error {
+	createCupTies := `
+	CREATE TABLE IF NOT EXISTS cup_ties (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		round INTEGER NOT NULL,
+		slot INTEGER NOT NULL,
+		home_team_id INTEGER,
+		away_team_id INTEGER,
+		played BOOLEAN NOT NULL DEFAULT FALSE,
+		home_goals INTEGER NOT NULL DEFAULT 0,
+		away_goals INTEGER NOT NULL DEFAULT 0,
+		extra_home_goals INTEGER,
+		extra_away_goals INTEGER,
+		home_penalties INTEGER,
+		away_penalties INTEGER,
+		penalty_kicks TEXT,
+		events TEXT,
+		decided_by TEXT NOT NULL DEFAULT '',
+		winner_team_id INTEGER,
+		UNIQUE (round, slot),
+		FOREIGN KEY (home_team_id) REFERENCES teams(id),
+		FOREIGN KEY (away_team_id) REFERENCES teams(id),
+		FOREIGN KEY (winner_team_id) REFERENCES teams(id)
+	);`
+
+	if _, err := l.db.Exec(createCupTies); err != nil {
+		return fmt.Errorf("error creating cup_ties table: %v", err)
+	}
+	return l.addColumnIfMissing("cup_ties", "events", "TEXT")
+}
+
+// bracketOrder lists the seeds of a bracket of size slots from top to
+// bottom, so that seed pairs 2i and 2i+1 meet in the first round and the
+// better seeds stay apart until the last rounds: 1 8 4 5 2 7 3 6 for eight
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for n := 2; n <= size; n *= 2 {
+		next := make([]int, 0, n)
+		for _, seed := range order {
+			next = append(next, seed, n+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// cupRounds is the number of rounds for a number of teams
+func cupRounds(teams int) int {
+	rounds := 0
+	for size := 1; size < teams; size *= 2 {
+		rounds++
+	}
+	return rounds
+}
+
+// cupRoundName names a round by the ties left in it
+func cupRoundName(round, rounds int) string {
+	switch rounds - round {
+	case 0:
+		return "Final"
+	case 1:
+		return "Semi-finals"
+	case 2:
+		return "Quarter-finals"
+	}
+	return fmt.Sprintf("Round of %d", 1<<(rounds-round+1))
+}
+
+// DrawCup draws a new cup from the league's teams in table order. A cup with
+// ties played is only replaced when replace is set.
+func (l *League) DrawCup(replace bool) (*CupBracket, error) {
+	standings, err := l.CalculateStandings()
+	if err != nil {
+		return nil, err
+	}
+	if len(standings) < 2 {
+		return nil, fmt.Errorf("a cup needs at least 2 teams")
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var played int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM cup_ties WHERE played = TRUE AND decided_by != ?", DecidedByBye).Scan(&played); err != nil {
+		return nil, err
+	}
+	if played > 0 && !replace {
+		return nil, fmt.Errorf("%w: %d ties played", ErrCupStarted, played)
+	}
+	if _, err := tx.Exec("DELETE FROM cup_ties"); err != nil {
+		return nil, err
+	}
+
+	rounds := cupRounds(len(standings))
+	for round := 1; round <= rounds; round++ {
+		for slot := 0; slot < 1<<(rounds-round); slot++ {
+			if _, err := tx.Exec("INSERT INTO cup_ties (round, slot) VALUES (?, ?)", round, slot); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	order := bracketOrder(1 << rounds)
+	for slot := 0; slot < len(order)/2; slot++ {
+		home := standings[order[2*slot]-1].TeamID
+		if order[2*slot+1] > len(standings) {
+			if _, err := tx.Exec("UPDATE cup_ties SET home_team_id = ?, played = TRUE, decided_by = ?, winner_team_id = ? WHERE round = 1 AND slot = ?",
+				home, DecidedByBye, home, slot); err != nil {
+				return nil, err
+			}
+			if err := advanceCupWinner(tx, 1, slot, rounds, home); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		away := standings[order[2*slot+1]-1].TeamID
+		if _, err := tx.Exec("UPDATE cup_ties SET home_team_id = ?, away_team_id = ? WHERE round = 1 AND slot = ?", home, away, slot); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return l.CupBracket()
+}
+
+// advanceCupWinner puts the winner of a tie into the next round, at home
+// when it came from the upper tie
+func advanceCupWinner(tx *sql.Tx, round, slot, rounds, winner int) error {
+	if round == rounds {
+		return nil
+	}
+	column := "home_team_id"
+	if slot%2 == 1 {
+		column = "away_team_id"
+	}
+	_, err := tx.Exec("UPDATE cup_ties SET "+column+" = ? WHERE round = ? AND slot = ?", winner, round+1, slot/2)
+	return err
+}
+
+const cupTieSelect = `
+	SELECT c.id, c.round, c.slot, COALESCE(c.home_team_id, 0), COALESCE(h.name, ''), COALESCE(c.away_team_id, 0), COALESCE(a.name, ''),
+		c.played, c.home_goals, c.away_goals, c.extra_home_goals, c.extra_away_goals, c.home_penalties, c.away_penalties,
+		COALESCE(c.penalty_kicks, ''), COALESCE(c.events, ''), c.decided_by, COALESCE(w.name, '')
+	FROM cup_ties c
+	LEFT JOIN teams h ON h.id = c.home_team_id
+	LEFT JOIN teams a ON a.id = c.away_team_id
+	LEFT JOIN teams w ON w.id = c.winner_team_id`
+
+func scanCupTie(row rowScanner) (CupTie, error) {
+	var t CupTie
+	var extraHome, extraAway, homePens, awayPens sql.NullInt64
+	var kicks, events string
+	if err := row.Scan(&t.ID, &t.Round, &t.Slot, &t.homeID, &t.HomeTeam, &t.awayID, &t.AwayTeam,
+		&t.Played, &t.HomeGoals, &t.AwayGoals, &extraHome, &extraAway, &homePens, &awayPens,
+		&kicks, &events, &t.DecidedBy, &t.Winner); err != nil {
+		return t, err
+	}
+	if events != "" {
+		if err := json.Unmarshal([]byte(events), &t.Events); err != nil {
+			return t, err
+		}
+	}
+	if extraHome.Valid {
+		t.ExtraTime = &ExtraTime{HomeGoals: int(extraHome.Int64), AwayGoals: int(extraAway.Int64)}
+	}
+	if homePens.Valid {
+		t.Penalties = &CupPenalties{HomeGoals: int(homePens.Int64), AwayGoals: int(awayPens.Int64), Kicks: []PenaltyKick{}}
+		if kicks != "" {
+			if err := json.Unmarshal([]byte(kicks), &t.Penalties.Kicks); err != nil {
+				return t, err
+			}
+		}
+	}
+	return t, nil
+}
+
+// cupTies lists the ties matching where, or all of them, in bracket order
+func cupTies(q interface {
+	Query(string, ...any) (*sql.Rows, error)
+}, where string, args ...any) ([]CupTie, error) {
+	query := cupTieSelect
+	if where != "" {
+		query += " WHERE " + where
+	}
+	rows, err := q.Query(query+" ORDER BY c.round, c.slot", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ties := []CupTie{}
+	for rows.Next() {
+		t, err := scanCupTie(rows)
+		if err != nil {
+			return nil, err
+		}
+		ties = append(ties, t)
+	}
+	return ties, rows.Err()
+}
+
+// CupBracket is the cup as drawn, with the results so far
+func (l *League) CupBracket() (*CupBracket, error) {
+	ties, err := cupTies(l.db, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(ties) == 0 {
+		return nil, ErrNoCup
+	}
+
+	rounds := ties[len(ties)-1].Round
+	bracket := &CupBracket{Rounds: []CupRound{}}
+	for _, t := range ties {
+		if t.Round == 1 {
+			bracket.Teams++
+			if t.awayID != 0 {
+				bracket.Teams++
+			}
+		}
+		if len(bracket.Rounds) < t.Round {
+			bracket.Rounds = append(bracket.Rounds, CupRound{Round: t.Round, Name: cupRoundName(t.Round, rounds), Ties: []CupTie{}})
+		}
+		round := &bracket.Rounds[t.Round-1]
+		round.Ties = append(round.Ties, t)
+		if t.Round == rounds && t.Played {
+			bracket.Champion = t.Winner
+		}
+	}
+	return bracket, nil
+}
+
+// CupResults lists the ties played so far, byes left out, in bracket order
+func (l *League) CupResults() ([]CupTie, error) {
+	var drawn int
+	if err := l.db.QueryRow("SELECT COUNT(*) FROM cup_ties").Scan(&drawn); err != nil {
+		return nil, err
+	}
+	if drawn == 0 {
+		return nil, ErrNoCup
+	}
+	return cupTies(l.db, "c.played = TRUE AND c.decided_by != ?", DecidedByBye)
+}
+
+// playCupTie plays a single match with the home side's advantage, then extra
+// time and penalties as long as it is level
+func playCupTie(e tieEngine, params SimParams, advantages homeAdvantages, t *CupTie, home, away Team) {
+	params = advantages.params(params, home.Name)
+	t.HomeGoals, t.AwayGoals, t.Events = e.play(params, home, away)
+	t.Played = true
+
+	winner := func(homeGoals, awayGoals int) string {
+		switch {
+		case homeGoals > awayGoals:
+			return home.Name
+		case awayGoals > homeGoals:
+			return away.Name
+		}
+		return ""
+	}
+	if t.Winner = winner(t.HomeGoals, t.AwayGoals); t.Winner != "" {
+		t.DecidedBy = DecidedByNormalTime
+		return
+	}
+
+	var extraEvents []MatchEvent
+	t.ExtraTime, extraEvents = e.extraTime(params, home, away)
+	t.Events = append(t.Events, extraEvents...)
+	if t.Winner = winner(t.ExtraTime.HomeGoals, t.ExtraTime.AwayGoals); t.Winner != "" {
+		t.DecidedBy = DecidedByExtraTime
+		return
+	}
+
+	t.Penalties = &CupPenalties{}
+	t.Penalties.HomeGoals, t.Penalties.AwayGoals, t.Penalties.Kicks, t.Winner = penaltyShootout(e.rng, home.Name, away.Name)
+	t.DecidedBy = DecidedByPenalties
+}
+
+// SimulateCupRound plays every tie of a round once the rounds before it are
+// over, with the seed's own stream when one is given
+func (l *League) SimulateCupRound(round int, seed *int64) ([]CupTie, error) {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var rounds int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(round), 0) FROM cup_ties").Scan(&rounds); err != nil {
+		return nil, err
+	}
+	if rounds == 0 {
+		return nil, ErrNoCup
+	}
+	if round < 1 || round > rounds {
+		return nil, sql.ErrNoRows
+	}
+	var open int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM cup_ties WHERE round < ? AND played = FALSE", round).Scan(&open); err != nil {
+		return nil, err
+	}
+	if open > 0 {
+		return nil, fmt.Errorf("%w: %d ties of earlier rounds are still to play", ErrCupRound, open)
+	}
+	ties, err := cupTies(tx, "c.round = ? AND c.played = FALSE", round)
+	if err != nil {
+		return nil, err
+	}
+	if len(ties) == 0 {
+		return nil, fmt.Errorf("%w: round %d has been played", ErrCupRound, round)
+	}
+
+	teams := make(map[int]Team)
+	for _, t := range l.Teams() {
+		teams[t.ID] = t
+	}
+	cfg := l.config()
+	advantages, err := l.homeAdvantages()
+	if err != nil {
+		return nil, err
+	}
+	stream, flavorStream := l.seededStreams(seed)
+	engine := tieEngine{cfg: cfg, rng: rand.New(rand.NewSource(stream.Int63())), flavor: rand.New(rand.NewSource(flavorStream.Int63()))}
+
+	for i := range ties {
+		t := &ties[i]
+		home, away := teams[t.homeID], teams[t.awayID]
+		playCupTie(engine, cfg.Simulation, advantages, t, home, away)
+		winnerID := home.ID
+		if t.Winner == away.Name {
+			winnerID = away.ID
+		}
+
+		var extraHome, extraAway, homePens, awayPens, kicks, events any
+		if t.Events != nil {
+			encoded, err := json.Marshal(t.Events)
+			if err != nil {
+				return nil, err
+			}
+			events = string(encoded)
+		}
+		if t.ExtraTime != nil {
+			extraHome, extraAway = t.ExtraTime.HomeGoals, t.ExtraTime.AwayGoals
+		}
+		if t.Penalties != nil {
+			homePens, awayPens = t.Penalties.HomeGoals, t.Penalties.AwayGoals
+			encoded, err := json.Marshal(t.Penalties.Kicks)
+			if err != nil {
+				return nil, err
+			}
+			kicks = string(encoded)
+		}
+		if _, err := tx.Exec(`UPDATE cup_ties SET played = TRUE, home_goals = ?, away_goals = ?, extra_home_goals = ?, extra_away_goals = ?,
+			home_penalties = ?, away_penalties = ?, penalty_kicks = ?, events = ?, decided_by = ?, winner_team_id = ? WHERE id = ?`,
+			t.HomeGoals, t.AwayGoals, extraHome, extraAway, homePens, awayPens, kicks, events, t.DecidedBy, winnerID, t.ID); err != nil {
+			return nil, err
+		}
+		if err := advanceCupWinner(tx, round, t.Slot, rounds, winnerID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ties, nil
+}
+
+// cupError answers the errors of the cup endpoints
+func cupError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNoCup):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err == sql.ErrNoRows:
+		http.Error(w, "Round not found", http.StatusNotFound)
+	case errors.Is(err, ErrCupStarted), errors.Is(err, ErrCupRound):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GET /cup/bracket shows the cup; POST draws a new one from the current
+// table, ?replace=true over a cup already being played (admin only)
+func (l *League) handleCupBracket(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		bracket, err := l.CupBracket()
+		if err != nil {
+			cupError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(bracket)
+	case http.MethodPost:
+		if !requireAdmin(w, r) {
+			return
+		}
+		bracket, err := l.DrawCup(r.URL.Query().Get("replace") == "true")
+		if err != nil {
+			cupError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(bracket)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// POST /cup/simulate/round/{n} plays round n of the cup, ?seed= for a
+// repeatable round (admin only)
+func (l *League) handleSimulateCupRound(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	round, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil {
+		http.Error(w, "Invalid round", http.StatusBadRequest)
+		return
+	}
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ties, err := l.SimulateCupRound(round, seed)
+	if err != nil {
+		cupError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(ties)
+}
+
+// GET /cup/results lists the cup ties played so far
+func (l *League) handleCupResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ties, err := l.CupResults()
+	if err != nil {
+		cupError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(ties)
+}
@@ -0,0 +1,92 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestCupPlaysToAChampion(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 11)
+	if status := h.Do(http.MethodGet, "/cup/bracket", nil, false, nil); status != http.StatusNotFound {
+		t.Errorf("bracket before the draw: status %d, want 404", status)
+	}
+
+	var bracket insider.CupBracket
+	if status := h.Do(http.MethodPost, "/cup/bracket", nil, true, &bracket); status != http.StatusCreated {
+		t.Fatalf("POST /cup/bracket: status %d", status)
+	}
+	if bracket.Teams != 4 || len(bracket.Rounds) != 2 || bracket.Rounds[1].Name != "Final" {
+		t.Fatalf("bracket %+v", bracket)
+	}
+	// the table is level before a match, so it goes by name
+	semi := bracket.Rounds[0].Ties
+	if semi[0].HomeTeam != "Alpha FC" || semi[0].AwayTeam != "Delta SC" || semi[1].HomeTeam != "Bravo United" {
+		t.Errorf("semi-finals %+v", semi)
+	}
+
+	if status := h.Do(http.MethodPost, "/cup/simulate/round/2", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("final before the semi-finals: status %d, want 409", status)
+	}
+	if status := h.Do(http.MethodPost, "/cup/simulate/round/3", nil, true, nil); status != http.StatusNotFound {
+		t.Errorf("round 3 of 2: status %d, want 404", status)
+	}
+
+	var played []insider.CupTie
+	if status := h.Do(http.MethodPost, "/cup/simulate/round/1?seed=5", nil, true, &played); status != http.StatusOK {
+		t.Fatalf("round 1: status %d", status)
+	}
+	for _, tie := range played {
+		if tie.Winner != tie.HomeTeam && tie.Winner != tie.AwayTeam {
+			t.Errorf("tie %+v won by an outsider", tie)
+		}
+		level := tie.HomeGoals == tie.AwayGoals
+		if level != (tie.ExtraTime != nil) {
+			t.Errorf("tie %+v: extra time should follow a level score", tie)
+		}
+	}
+	if status := h.Do(http.MethodPost, "/cup/simulate/round/1", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("round 1 again: status %d, want 409", status)
+	}
+
+	h.Get("/cup/bracket", &bracket)
+	// ties go through the match engine, and keep its goals as events
+	for _, tie := range bracket.Rounds[0].Ties {
+		goals := tie.HomeGoals + tie.AwayGoals
+		if tie.ExtraTime != nil {
+			goals += tie.ExtraTime.HomeGoals + tie.ExtraTime.AwayGoals
+		}
+		scored := 0
+		for _, e := range tie.Events {
+			if e.Type == insider.EventGoal {
+				scored++
+			}
+		}
+		if scored != goals {
+			t.Errorf("tie %s v %s: %d goal events for %d goals", tie.HomeTeam, tie.AwayTeam, scored, goals)
+		}
+	}
+	final := bracket.Rounds[1].Ties[0]
+	if final.HomeTeam != played[0].Winner || final.AwayTeam != played[1].Winner {
+		t.Errorf("final %s v %s, want the semi-final winners %s v %s", final.HomeTeam, final.AwayTeam, played[0].Winner, played[1].Winner)
+	}
+	h.Do(http.MethodPost, "/cup/simulate/round/2", nil, true, nil)
+	h.Get("/cup/bracket", &bracket)
+	if bracket.Champion == "" || bracket.Champion != bracket.Rounds[1].Ties[0].Winner {
+		t.Errorf("champion %q after the final %+v", bracket.Champion, bracket.Rounds[1].Ties[0])
+	}
+
+	var results []insider.CupTie
+	h.Get("/cup/results", &results)
+	if len(results) != 3 {
+		t.Errorf("%d results, want 3", len(results))
+	}
+	if status := h.Do(http.MethodPost, "/cup/bracket", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("redraw a played cup: status %d, want 409", status)
+	}
+	if status := h.Do(http.MethodPost, "/cup/bracket?replace=true", nil, true, nil); status != http.StatusCreated {
+		t.Errorf("redraw with replace: status %d", status)
+	}
+}
@@ -0,0 +1,82 @@
+package insider
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBracketOrder(t *testing.T) {
+	if got, want := bracketOrder(8), []int{1, 8, 4, 5, 2, 7, 3, 6}; !slices.Equal(got, want) {
+		t.Errorf("bracketOrder(8) = %v, want %v", got, want)
+	}
+	for teams, want := range map[int]int{2: 1, 4: 2, 5: 3, 8: 3, 9: 4} {
+		if got := cupRounds(teams); got != want {
+			t.Errorf("cupRounds(%d) = %d, want %d", teams, got, want)
+		}
+	}
+}
+
+func TestCupByesAndShootouts(t *testing.T) {
+	teams := append(slices.Clone(snapshotTeams), Team{Name: "Echo Rovers", Strength: 55})
+	l := newTestLeague(t, teams, FixtureWeeks(len(teams)), 3)
+	bracket, err := l.DrawCup(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bracket.Teams != 5 || len(bracket.Rounds) != 3 {
+		t.Fatalf("bracket of %d teams in %d rounds", bracket.Teams, len(bracket.Rounds))
+	}
+	byes := 0
+	for _, tie := range bracket.Rounds[0].Ties {
+		if tie.DecidedBy == DecidedByBye {
+			byes++
+			if tie.AwayTeam != "" || tie.Winner != tie.HomeTeam {
+				t.Errorf("bye %+v", tie)
+			}
+		}
+	}
+	if byes != 3 {
+		t.Errorf("%d byes, want 3", byes)
+	}
+	// seeds 2 and 3 both had byes and meet in round 2 already
+	if second := bracket.Rounds[1].Ties[1]; second.HomeTeam == "" || second.AwayTeam == "" {
+		t.Errorf("round 2 tie %+v should be filled by byes", second)
+	}
+
+	for round := 1; round <= 3; round++ {
+		if _, err := l.SimulateCupRound(round, nil); err != nil {
+			t.Fatalf("round %d: %v", round, err)
+		}
+	}
+	results, err := l.CupResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Errorf("%d results, want 4 with the byes left out", len(results))
+	}
+
+	// a shootout is only reached through extra time, and stored kick by kick
+	home, away := Team{Name: "Home", Strength: 60}, Team{Name: "Away", Strength: 60}
+	advantages, err := l.homeAdvantages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := l.config().Simulation
+	for seed := int64(0); seed < 200; seed++ {
+		rng, flavor := l.seededStreams(&seed)
+		var tie CupTie
+		playCupTie(tieEngine{cfg: l.config(), rng: rng, flavor: flavor}, params, advantages, &tie, home, away)
+		if tie.Penalties == nil {
+			continue
+		}
+		if tie.ExtraTime == nil || tie.ExtraTime.HomeGoals != tie.ExtraTime.AwayGoals || tie.DecidedBy != DecidedByPenalties {
+			t.Fatalf("shootout without a level extra time: %+v", tie)
+		}
+		if tie.Penalties.HomeGoals == tie.Penalties.AwayGoals || len(tie.Penalties.Kicks) == 0 {
+			t.Fatalf("shootout %+v", tie.Penalties)
+		}
+		return
+	}
+	t.Error("no tie went to penalties in 200 seeds")
+}
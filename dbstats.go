@@ -0,0 +1,380 @@
+package insider
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// The server opens SQLite through timedDriver, a thin wrapper around the
+// sqlite3 driver that times every statement, inside transactions too. A
+// query is timed until its rows are closed, so slow scans count. Statements
+// are grouped into families by their text with literals taken out and
+// lists of parameters folded, so an IN list of any length is one family.
+// Past maxFamilies every new shape is counted under otherFamily, which keeps
+// the map bounded whatever statements are built at run time.
+
+// DriverName is the database/sql driver the server opens its databases
+// with: SQLite, every statement timed
+const DriverName = "sqlite3-timed"
+
+// maxFamilyLength cuts long statements down to a readable family name
+const maxFamilyLength = 160
+
+// maxFamilies caps the families kept apart
+const maxFamilies = 500
+
+// otherFamily collects the statements past maxFamilies
+const otherFamily = "(other statements)"
+
+func init() {
+	sql.Register(DriverName, &timedDriver{parent: &sqlite3.SQLiteDriver{}})
+}
+
+// QueryFamilyStats is the latency of one kind of statement
+type QueryFamilyStats struct {
+	Query   string  `json:"query"`
+	Count   int64   `json:"count"`
+	Errors  int64   `json:"errors"`
+	Slow    int64   `json:"slow"`
+	TotalMS float64 `json:"total_ms"`
+	MeanMS  float64 `json:"mean_ms"`
+	MaxMS   float64 `json:"max_ms"`
+}
+
+// queryStats collects timings for every family. SlowThreshold is read on
+// every statement; zero turns the slow query log off.
+type queryStats struct {
+	mu            sync.Mutex
+	families      map[string]*QueryFamilyStats
+	slowThreshold time.Duration
+}
+
+// dbStats is shared by every connection of the timed driver
+var dbStats = &queryStats{families: make(map[string]*QueryFamilyStats)}
+
+// SetSlowThreshold sets how long a statement may take before it is logged
+func (s *queryStats) SetSlowThreshold(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slowThreshold = d
+}
+
+var (
+	spaces   = regexp.MustCompile(`\s+`)
+	literals = regexp.MustCompile(`'(?:[^']|'')*'|\b\d+(?:\.\d+)?\b`)
+	// parameterLists and rowLists find "?, ?, ?" and "(?, ...), (?, ...)"
+	parameterLists = regexp.MustCompile(`\?(?: ?, ?\?)+`)
+	rowLists       = regexp.MustCompile(`\(\?, \.\.\.\)(?: ?, ?\(\?, \.\.\.\))+`)
+)
+
+// queryFamily reduces a statement to its shape
+func queryFamily(query string) string {
+	family := strings.TrimSpace(spaces.ReplaceAllString(query, " "))
+	family = literals.ReplaceAllString(family, "?")
+	family = parameterLists.ReplaceAllString(family, "?, ...")
+	family = rowLists.ReplaceAllString(family, "(?, ...), ...")
+	if len(family) > maxFamilyLength {
+		family = family[:maxFamilyLength] + "..."
+	}
+	return family
+}
+
+// record adds one timed statement and logs it if it was slow
+func (s *queryStats) record(query string, elapsed time.Duration, err error) {
+	family := queryFamily(query)
+	ms := float64(elapsed) / float64(time.Millisecond)
+
+	s.mu.Lock()
+	f := s.families[family]
+	if f == nil && len(s.families) >= maxFamilies {
+		family = otherFamily
+		f = s.families[family]
+	}
+	if f == nil {
+		f = &QueryFamilyStats{Query: family}
+		s.families[family] = f
+	}
+	f.Count++
+	f.TotalMS += ms
+	if ms > f.MaxMS {
+		f.MaxMS = ms
+	}
+	if err != nil && err != driver.ErrSkip {
+		f.Errors++
+	}
+	slow := s.slowThreshold > 0 && elapsed >= s.slowThreshold
+	if slow {
+		f.Slow++
+	}
+	s.mu.Unlock()
+
+	if slow {
+		fmt.Printf("Slow query (%s): %s\n", elapsed.Round(time.Microsecond), family)
+	}
+}
+
+// Snapshot lists the families, most total time first
+func (s *queryStats) Snapshot() []QueryFamilyStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make([]QueryFamilyStats, 0, len(s.families))
+	for _, f := range s.families {
+		snapshot := *f
+		snapshot.MeanMS = snapshot.TotalMS / float64(snapshot.Count)
+		stats = append(stats, snapshot)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].TotalMS != stats[j].TotalMS {
+			return stats[i].TotalMS > stats[j].TotalMS
+		}
+		return stats[i].Query < stats[j].Query
+	})
+	return stats
+}
+
+// Reset forgets every timing, the threshold stays
+func (s *queryStats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.families = make(map[string]*QueryFamilyStats)
+}
+
+type timedDriver struct {
+	parent driver.Driver
+}
+
+func (d *timedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.parent.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &timedConn{conn: conn}, nil
+}
+
+// timedConn passes everything to the sqlite3 connection. Where that has no
+// context variant driver.ErrSkip lets database/sql fall back as it would.
+type timedConn struct {
+	conn driver.Conn
+}
+
+func (c *timedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *timedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &timedStmt{stmt: stmt, query: query}, nil
+}
+
+func (c *timedConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *timedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *timedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var tx driver.Tx
+	var err error
+	if b, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.conn.Begin()
+	}
+	dbStats.record("BEGIN", time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+	return &timedTx{tx: tx}, nil
+}
+
+func (c *timedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	dbStats.record(query, time.Since(start), err)
+	return res, err
+}
+
+func (c *timedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	if err != nil {
+		dbStats.record(query, time.Since(start), err)
+		return nil, err
+	}
+	return &timedRows{rows: rows, query: query, start: start}, nil
+}
+
+func (c *timedConn) Ping(ctx context.Context) error {
+	if p, ok := c.conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+type timedTx struct {
+	tx driver.Tx
+}
+
+func (t *timedTx) Commit() error {
+	start := time.Now()
+	err := t.tx.Commit()
+	dbStats.record("COMMIT", time.Since(start), err)
+	return err
+}
+
+func (t *timedTx) Rollback() error {
+	start := time.Now()
+	err := t.tx.Rollback()
+	dbStats.record("ROLLBACK", time.Since(start), err)
+	return err
+}
+
+type timedStmt struct {
+	stmt  driver.Stmt
+	query string
+}
+
+func (s *timedStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *timedStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *timedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	start := time.Now()
+	res, err := s.stmt.Exec(args)
+	dbStats.record(s.query, time.Since(start), err)
+	return res, err
+}
+
+func (s *timedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.stmt.Query(args)
+	if err != nil {
+		dbStats.record(s.query, time.Since(start), err)
+		return nil, err
+	}
+	return &timedRows{rows: rows, query: s.query, start: start}, nil
+}
+
+func (s *timedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := s.stmt.(driver.StmtExecContext)
+	if !ok {
+		values, err := namedToValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Exec(values)
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, args)
+	dbStats.record(s.query, time.Since(start), err)
+	return res, err
+}
+
+func (s *timedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := s.stmt.(driver.StmtQueryContext)
+	if !ok {
+		values, err := namedToValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Query(values)
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, args)
+	if err != nil {
+		dbStats.record(s.query, time.Since(start), err)
+		return nil, err
+	}
+	return &timedRows{rows: rows, query: s.query, start: start}, nil
+}
+
+// namedToValues drops the names for statements without context support
+func namedToValues(named []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(named))
+	for i, n := range named {
+		if n.Name != "" {
+			return nil, fmt.Errorf("named parameter %s is not supported", n.Name)
+		}
+		values[i] = n.Value
+	}
+	return values, nil
+}
+
+// timedRows records the query once the rows are closed
+type timedRows struct {
+	rows  driver.Rows
+	query string
+	start time.Time
+	err   error
+}
+
+func (r *timedRows) Columns() []string {
+	return r.rows.Columns()
+}
+
+func (r *timedRows) Next(dest []driver.Value) error {
+	err := r.rows.Next(dest)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return err
+}
+
+func (r *timedRows) Close() error {
+	err := r.rows.Close()
+	dbStats.record(r.query, time.Since(r.start), r.err)
+	return err
+}
+
+// GET /admin/db-stats lists statement latencies by family, DELETE resets them
+func handleDBStats(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		http.Error(w, "Admin token required", http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(dbStats.Snapshot())
+	case http.MethodDelete:
+		dbStats.Reset()
+		json.NewEncoder(w).Encode(map[string]string{"message": "Query stats reset"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,86 @@
+package insider_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+// TestDBStatsCounts runs statements through the timed driver, which every
+// test database is opened with, and reads the counters over HTTP
+func TestDBStatsCounts(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 1)
+	db := h.League.DB()
+	insider.DBStats.Reset()
+
+	for i := 0; i < 3; i++ {
+		if _, err := db.Exec("UPDATE teams SET strength = strength WHERE id = ?", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows, err := db.Query("SELECT name FROM teams WHERE strength > 65")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	if _, err := db.Exec("SELECT * FROM no_such_table"); err == nil {
+		t.Fatal("query on a missing table worked")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	tx, err = db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var stats []insider.QueryFamilyStats
+	if status := h.Do(http.MethodGet, "/admin/db-stats", nil, true, &stats); status != http.StatusOK {
+		t.Fatalf("GET /admin/db-stats: status %d", status)
+	}
+	byQuery := make(map[string]insider.QueryFamilyStats)
+	for _, f := range stats {
+		byQuery[f.Query] = f
+	}
+	want := map[string][2]int64{
+		"UPDATE teams SET strength = strength WHERE id = ?": {3, 0},
+		"SELECT name FROM teams WHERE strength > ?":         {1, 0},
+		"SELECT * FROM no_such_table":                       {1, 1},
+		"BEGIN":                                             {2, 0},
+		"ROLLBACK":                                          {1, 0},
+		"COMMIT":                                            {1, 0},
+	}
+	for query, counts := range want {
+		f := byQuery[query]
+		if f.Count != counts[0] || f.Errors != counts[1] {
+			t.Errorf("%s: count %d, errors %d; want %d, %d", query, f.Count, f.Errors, counts[0], counts[1])
+		}
+		if f.Count > 0 && (f.MeanMS < 0 || f.MaxMS < f.MeanMS || f.TotalMS < f.MaxMS) {
+			t.Errorf("%s: timings %+v", query, f)
+		}
+	}
+
+	if status := h.Do(http.MethodGet, "/admin/db-stats", nil, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("db stats without a token: status %d", status)
+	}
+	if status := h.Do(http.MethodDelete, "/admin/db-stats", nil, true, nil); status != http.StatusOK {
+		t.Fatalf("DELETE /admin/db-stats: status %d", status)
+	}
+	for _, f := range insider.DBStats.Snapshot() {
+		if strings.HasPrefix(f.Query, "UPDATE teams SET strength") {
+			t.Errorf("%s still counted after a reset", f.Query)
+		}
+	}
+}
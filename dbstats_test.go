@@ -0,0 +1,40 @@
+package insider
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestQueryFamily(t *testing.T) {
+	tests := []struct {
+		query, family string
+	}{
+		{"SELECT name FROM teams\n\t\tWHERE id = 3", "SELECT name FROM teams WHERE id = ?"},
+		{"SELECT * FROM teams WHERE name = 'O''Neill FC'", "SELECT * FROM teams WHERE name = ?"},
+		{"SELECT * FROM matches WHERE id IN (?, ?, ?)", "SELECT * FROM matches WHERE id IN (?, ...)"},
+		{"SELECT * FROM matches WHERE id IN (?,?)", "SELECT * FROM matches WHERE id IN (?, ...)"},
+		{"INSERT INTO players (team_id, name) VALUES (?, ?), (?, ?), (1, 'x')", "INSERT INTO players (team_id, name) VALUES (?, ...), ..."},
+	}
+	for _, tt := range tests {
+		if got := queryFamily(tt.query); got != tt.family {
+			t.Errorf("queryFamily(%q) = %q, want %q", tt.query, got, tt.family)
+		}
+	}
+}
+
+func TestQueryStatsCap(t *testing.T) {
+	s := &queryStats{families: make(map[string]*QueryFamilyStats)}
+	for i := 0; i < maxFamilies+10; i++ {
+		s.record(fmt.Sprintf("SELECT c%d FROM t", i), time.Millisecond, nil)
+	}
+	stats := s.Snapshot()
+	if len(stats) != maxFamilies+1 {
+		t.Errorf("%d families, want %d", len(stats), maxFamilies+1)
+	}
+	for _, f := range stats {
+		if f.Query == otherFamily && f.Count != 10 {
+			t.Errorf("%d statements past the cap, want 10", f.Count)
+		}
+	}
+}
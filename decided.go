@@ -0,0 +1,158 @@
+package insider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// outcomes simulation can be stopped on
+const (
+	OutcomeTitle      = "title"
+	OutcomeRelegation = "relegation"
+)
+
+// ErrInvalidOutcome is returned for an outcome that cannot be waited for
+var ErrInvalidOutcome = errors.New("invalid outcome")
+
+// DecidedResult is where POST /simulate/until-decided stopped. Decided is
+// false when the matches left cannot be simulated, e.g. postponed ones.
+type DecidedResult struct {
+	Outcome        string     `json:"outcome"`
+	Decided        bool       `json:"decided"`
+	Week           int        `json:"week"`
+	Teams          []string   `json:"teams"`
+	WeeksSimulated []int      `json:"weeks_simulated"`
+	Standings      []Standing `json:"standings"`
+}
+
+// decidedTeams reports the teams an outcome has settled on, once it has. At
+// the end of the season the tiebreakers settle what points could not.
+func (s *seasonState) decidedTeams(outcome string, relegation *Zone) ([]string, bool) {
+	kind, from, to := AnnouncementChampion, 1, 1
+	if outcome == OutcomeRelegation {
+		kind, from, to = AnnouncementRelegated, relegation.From, min(relegation.To, len(s.teams))
+	}
+
+	played, remaining := s.current()
+	if len(remaining) == 0 {
+		standings := s.standings(played)
+		var teams []string
+		for _, st := range standings[from-1 : to] {
+			teams = append(teams, st.TeamName)
+		}
+		return teams, true
+	}
+
+	var teams []string
+	for _, c := range s.clinches(relegation) {
+		if c.kind == kind {
+			teams = append(teams, c.team)
+		}
+	}
+	return teams, len(teams) == to-from+1
+}
+
+// SimulateUntilDecided plays week by week and stops as soon as the outcome
+// is mathematically settled. Nothing is played if it already is.
+func (l *League) SimulateUntilDecided(outcome string) (*DecidedResult, error) {
+	if l.config().Live {
+		return nil, ErrLiveMode
+	}
+	var relegation *Zone
+	switch outcome {
+	case OutcomeTitle:
+	case OutcomeRelegation:
+		relegation = l.relegationZone()
+		if relegation == nil || relegation.From > len(l.Teams()) {
+			return nil, fmt.Errorf("%w: the config has no relegation zone", ErrInvalidOutcome)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q, expected %s or %s", ErrInvalidOutcome, outcome, OutcomeTitle, OutcomeRelegation)
+	}
+
+	result := &DecidedResult{Outcome: outcome, WeeksSimulated: []int{}}
+	for week := 0; week <= l.weeks; week++ {
+		// week 0 only checks the league as it is
+		if week > 0 {
+			open, err := l.hasOpenMatches(week)
+			if err != nil {
+				return nil, err
+			}
+			if !open {
+				continue
+			}
+			if err := l.SimulateWeek(week); err != nil {
+				return nil, err
+			}
+			result.WeeksSimulated = append(result.WeeksSimulated, week)
+		}
+
+		state, err := l.loadSeasonState()
+		if err != nil {
+			return nil, err
+		}
+		if teams, ok := state.decidedTeams(outcome, relegation); ok {
+			result.Decided = true
+			result.Week = state.latestWeek()
+			result.Teams = teams
+			break
+		}
+	}
+
+	if result.Teams == nil {
+		result.Teams = []string{}
+	}
+	standings, err := l.CalculateStandings()
+	if err != nil {
+		return nil, err
+	}
+	result.Standings = standings
+	return result, nil
+}
+
+// hasOpenMatches reports whether a week still has matches to simulate
+func (l *League) hasOpenMatches(week int) (bool, error) {
+	matches, err := l.Matches()
+	if err != nil {
+		return false, err
+	}
+	for _, m := range matches {
+		if m.Week == week && !m.Played && !m.Postponed && !m.Live {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// POST /simulate/until-decided {"outcome": "relegation"}, the title when the
+// body is empty
+func (l *League) handleSimulateUntilDecided(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body := struct {
+		Outcome string `json:"outcome"`
+	}{Outcome: OutcomeTitle}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := l.SimulateUntilDecided(body.Outcome)
+	switch {
+	case errors.Is(err, ErrInvalidOutcome):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, ErrLiveMode):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,121 @@
+package insider_test
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+// clearGaps wins a match 2-0 for a side at least 15 stronger and draws
+// the rest 1-1, so a leader and a bottom side can pull away early
+type clearGaps struct{}
+
+func (clearGaps) Score(rng *rand.Rand, p insider.SimParams, homeStrength, awayStrength int) (int, int, bool) {
+	switch {
+	case homeStrength-awayStrength >= 15:
+		return 2, 0, false
+	case awayStrength-homeStrength >= 15:
+		return 0, 2, false
+	}
+	return 1, 1, false
+}
+
+func (clearGaps) Probabilities(p insider.SimParams, homeStrength, awayStrength int) (float64, float64, float64) {
+	switch {
+	case homeStrength-awayStrength >= 15:
+		return 1, 0, 0
+	case awayStrength-homeStrength >= 15:
+		return 0, 0, 1
+	}
+	return 0, 1, 0
+}
+
+// Alpha FC beats everyone and Delta SC loses to everyone, the middle two draw
+var gappedTeams = []insider.Team{
+	{Name: "Alpha FC", Strength: 85},
+	{Name: "Bravo United", Strength: 65},
+	{Name: "Charlie Town", Strength: 60},
+	{Name: "Delta SC", Strength: 20},
+}
+
+func TestSimulateUntilDecided(t *testing.T) {
+	for _, tc := range []struct {
+		outcome string
+		team    string
+	}{
+		{insider.OutcomeTitle, "Alpha FC"},
+		{insider.OutcomeRelegation, "Delta SC"},
+	} {
+		t.Run(tc.outcome, func(t *testing.T) {
+			h := leaguetest.NewWithSimulator(t, gappedTeams, 5, clearGaps{})
+			cfg := *h.League.Config()
+			cfg.Zones = []insider.Zone{{Name: "relegation", From: 4, To: 4}}
+			h.League.StoreConfig(&cfg)
+
+			var result insider.DecidedResult
+			h.Post("/simulate/until-decided", map[string]string{"outcome": tc.outcome}, &result)
+			if !result.Decided || len(result.Teams) != 1 || result.Teams[0] != tc.team {
+				t.Fatalf("decided %v for %v, want %s", result.Decided, result.Teams, tc.team)
+			}
+			if result.Week >= h.League.TotalWeeks() {
+				t.Errorf("decided in week %d of %d, want before the last week", result.Week, h.League.TotalWeeks())
+			}
+			for i, week := range result.WeeksSimulated {
+				if week != i+1 {
+					t.Fatalf("weeks simulated %v, want 1 to %d", result.WeeksSimulated, result.Week)
+				}
+			}
+			if len(result.WeeksSimulated) != result.Week {
+				t.Errorf("weeks simulated %v, want 1 to %d", result.WeeksSimulated, result.Week)
+			}
+			for _, m := range h.Matches() {
+				if m.Played != (m.Week <= result.Week) {
+					t.Errorf("week %d %s v %s played %v after stopping in week %d", m.Week, m.HomeTeam, m.AwayTeam, m.Played, result.Week)
+				}
+			}
+
+			// the week before, the outcome was still open
+			prefix := leaguetest.NewWithSimulator(t, gappedTeams, 5, clearGaps{})
+			prefix.League.StoreConfig(&cfg)
+			for week := 1; week < result.Week; week++ {
+				prefix.SimulateWeek(week)
+			}
+			var open insider.DecidedResult
+			prefix.Post("/simulate/until-decided", map[string]string{"outcome": tc.outcome}, &open)
+			if len(open.WeeksSimulated) == 0 || open.Week != result.Week {
+				t.Errorf("from week %d it simulated %v and stopped in week %d, want week %d", result.Week-1, open.WeeksSimulated, open.Week, result.Week)
+			}
+
+			// once decided nothing more is played
+			var again insider.DecidedResult
+			h.Post("/simulate/until-decided", map[string]string{"outcome": tc.outcome}, &again)
+			if !again.Decided || len(again.WeeksSimulated) != 0 || again.Week != result.Week {
+				t.Errorf("second call simulated %v and reported week %d, want nothing and week %d", again.WeeksSimulated, again.Week, result.Week)
+			}
+		})
+	}
+}
+
+func TestSimulateUntilDecidedRefusals(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 5)
+	for _, body := range []any{
+		map[string]string{"outcome": "promotion"},
+		// the default config has no relegation zone
+		map[string]string{"outcome": insider.OutcomeRelegation},
+	} {
+		if status := h.Do(http.MethodPost, "/simulate/until-decided", body, true, nil); status != http.StatusBadRequest {
+			t.Errorf("%v: status %d, want %d", body, status, http.StatusBadRequest)
+		}
+	}
+	if len(h.Matches()) == 0 {
+		t.Fatal("no fixtures")
+	}
+	for _, m := range h.Matches() {
+		if m.Played {
+			t.Fatalf("week %d %s v %s played by a refused request", m.Week, m.HomeTeam, m.AwayTeam)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A league run on its own, by another server, can join this one as a new
+// division below the last. Its active teams, their squads and the matches
+// of its current season, events included, are copied into a league of this
+// server with ids of its own; archived seasons stay in the database it came
+// from. The division list is kept in the settings table, so the import
+// survives a restart; a --config file that lists divisions goes on top of
+// it and has to name the imported league as well.
+
+// divisionsKey is the settings row holding the divisions
+const divisionsKey = "divisions"
+
+// ErrInvalidImport is returned for a league database that cannot join as a
+// division
+var ErrInvalidImport = errors.New("invalid division import")
+
+// importedMatch is a match of the league being imported, by its old ids
+type importedMatch struct {
+	id, homeTeamID, awayTeamID int
+	homeGoals, awayGoals       int
+	played, postponed, live    bool
+	week, minute               int
+	commentary, kickoff, admin sql.NullString
+}
+
+// importedEvent is an event of an imported match, by the old ids
+type importedEvent struct {
+	matchID, teamID  int
+	minute, playerID sql.NullInt64
+	kind, detail     sql.NullString
+}
+
+// importedLeague is what is read from the database being imported
+type importedLeague struct {
+	teams   []Team
+	players []Player
+	matches []importedMatch
+	events  []importedEvent
+}
+
+// readImport reads the teams, squads and current matches of a league
+// database
+func readImport(src *sql.DB) (*importedLeague, error) {
+	imp := &importedLeague{}
+	rows, err := src.Query("SELECT id, name, strength, metadata FROM teams WHERE active = TRUE ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var team Team
+		var metadata sql.NullString
+		if err := rows.Scan(&team.ID, &team.Name, &team.Strength, &metadata); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if metadata.Valid && metadata.String != "" {
+			if err := json.Unmarshal([]byte(metadata.String), &team.Metadata); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("invalid metadata for team %s: %v", team.Name, err)
+			}
+		}
+		imp.teams = append(imp.teams, team)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = src.Query("SELECT id, team_id, name, position, rating FROM players ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var p Player
+		if err := rows.Scan(&p.ID, &p.TeamID, &p.Name, &p.Position, &p.Rating); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		imp.players = append(imp.players, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = src.Query(`
+		SELECT id, home_team_id, away_team_id, COALESCE(home_goals, 0), COALESCE(away_goals, 0),
+			COALESCE(played, FALSE), COALESCE(week, 0), commentary, COALESCE(postponed, FALSE), kickoff,
+			COALESCE(live, FALSE), COALESCE(minute, 0), administrative
+		FROM matches ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var m importedMatch
+		if err := rows.Scan(&m.id, &m.homeTeamID, &m.awayTeamID, &m.homeGoals, &m.awayGoals,
+			&m.played, &m.week, &m.commentary, &m.postponed, &m.kickoff, &m.live, &m.minute, &m.admin); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		imp.matches = append(imp.matches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = src.Query("SELECT match_id, minute, team_id, type, detail, player_id FROM match_events ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e importedEvent
+		if err := rows.Scan(&e.matchID, &e.minute, &e.teamID, &e.kind, &e.detail, &e.playerID); err != nil {
+			return nil, err
+		}
+		imp.events = append(imp.events, e)
+	}
+	return imp, rows.Err()
+}
+
+// check refuses a league that could not be scheduled, whose matches name
+// teams it no longer has, or whose teams share a name with a team of the
+// divisions it joins
+func (imp *importedLeague) check(divisions []*League, slots int) error {
+	report := ValidateProposal(LeagueProposal{Teams: imp.teams})
+	if !report.Valid {
+		var problems []string
+		for _, p := range report.Problems {
+			problems = append(problems, p.Field+": "+p.Detail)
+		}
+		return fmt.Errorf("%w: %s", ErrInvalidImport, strings.Join(problems, "; "))
+	}
+
+	ids := make(map[int]bool, len(imp.teams))
+	for _, t := range imp.teams {
+		ids[t.ID] = true
+	}
+	for _, m := range imp.matches {
+		if !ids[m.homeTeamID] || !ids[m.awayTeamID] {
+			return fmt.Errorf("%w: match %d is between teams %d and %d, not both active", ErrInvalidImport, m.id, m.homeTeamID, m.awayTeamID)
+		}
+	}
+
+	for _, division := range divisions {
+		for _, t := range division.Teams() {
+			for _, imported := range imp.teams {
+				if imported.Name == t.Name {
+					return fmt.Errorf("%w: league %d already has a team called %s", ErrInvalidImport, division.id, t.Name)
+				}
+			}
+		}
+	}
+
+	bottom := divisions[len(divisions)-1]
+	if slots < 0 || slots > len(imp.teams) || slots > len(bottom.Teams()) {
+		return fmt.Errorf("%w: %d slots between leagues %d and the import with %d and %d teams",
+			ErrInvalidImport, slots, bottom.id, len(bottom.Teams()), len(imp.teams))
+	}
+	return nil
+}
+
+// ImportDivision copies the league in src into a new league of this server
+// and puts it below the last division, swapping slots teams with it at the
+// end of a season. The teams get new ids; the matches, events and squads
+// follow them. A source without matches starts on a fixture of its own.
+func (l *League) ImportDivision(src *sql.DB, name string, slots int) (*LeagueInfo, error) {
+	if l.leagues == nil {
+		return nil, fmt.Errorf("%w: only the first league has divisions", ErrNoDivisions)
+	}
+	imp, err := readImport(src)
+	if err != nil {
+		return nil, fmt.Errorf("reading the league to import: %v", err)
+	}
+	chain := l.divisionChain()
+	divisions := make([]*League, len(chain))
+	for i, d := range chain {
+		if divisions[i], err = l.divisionLeague(d.League); err != nil {
+			return nil, err
+		}
+	}
+	if err := imp.check(divisions, slots); err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now().UTC()
+	res, err := l.db.Exec("INSERT INTO leagues (name, created_at) VALUES (?, ?)", name, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	id64, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	id := int(id64)
+	if name == "" {
+		name = fmt.Sprintf("League %d", id)
+		if _, err := l.db.Exec("UPDATE leagues SET name = ? WHERE id = ?", name, id); err != nil {
+			return nil, err
+		}
+	}
+
+	teams := make([]Team, len(imp.teams))
+	for i, t := range imp.teams {
+		teams[i] = Team{Name: t.Name, Strength: t.Strength, Metadata: t.Metadata}
+	}
+	if err := l.openLeague(id, name, createdAt, teams); err != nil {
+		l.db.Exec("DELETE FROM leagues WHERE id = ?", id)
+		return nil, err
+	}
+	other, err := l.divisionLeague(id)
+	if err != nil {
+		return nil, err
+	}
+	err = other.copyImport(imp)
+	if err == nil {
+		err = l.setDivisions(append(slices.Clone(chain[1:]), Division{League: id, Slots: slots}))
+	}
+	if err != nil {
+		l.leagues.mu.Lock()
+		delete(l.leagues.leagues, id)
+		l.leagues.mu.Unlock()
+		other.db.Close()
+		l.db.Exec("DELETE FROM leagues WHERE id = ?", id)
+		return nil, err
+	}
+
+	info := l.leagues.info(id)
+	return &info, nil
+}
+
+// importDivisionFile imports the league database at path for
+// --import-division, named after the file. A league of that name is taken
+// to be an earlier import of it, so starting again with the flag imports
+// nothing.
+func (l *League) importDivisionFile(path string, slots int) error {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, info := range l.Leagues() {
+		if info.Name == name {
+			fmt.Printf("League %s already exists, %s was not imported\n", name, path)
+			return nil
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	src, err := sql.Open(DriverName, "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := l.ImportDivision(src, name, slots)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %s as %s with %d teams, below the last division\n", path, info.Path, info.Teams)
+	return nil
+}
+
+// copyImport puts the squads, matches and events of an imported league in
+// place of the fixture the new league was created with, mapping the old
+// team, player and match ids to the new ones
+func (l *League) copyImport(imp *importedLeague) error {
+	teamIDs := make(map[int]int, len(imp.teams))
+	for _, old := range imp.teams {
+		for _, t := range l.Teams() {
+			if t.Name == old.Name {
+				teamIDs[old.ID] = t.ID
+			}
+		}
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	playerIDs := make(map[int64]int64, len(imp.players))
+	for _, p := range imp.players {
+		teamID, ok := teamIDs[p.TeamID]
+		if !ok {
+			// the squad of a team that left the imported league
+			continue
+		}
+		res, err := tx.Exec("INSERT INTO players (team_id, name, position, rating) VALUES (?, ?, ?, ?)",
+			teamID, p.Name, p.Position, p.Rating)
+		if err != nil {
+			return err
+		}
+		if playerIDs[int64(p.ID)], err = res.LastInsertId(); err != nil {
+			return err
+		}
+	}
+
+	if len(imp.matches) > 0 {
+		if _, err := tx.Exec("DELETE FROM matches"); err != nil {
+			return err
+		}
+		matchIDs := make(map[int]int, len(imp.matches))
+		for _, m := range imp.matches {
+			res, err := tx.Exec(`
+				INSERT INTO matches (home_team_id, away_team_id, home_goals, away_goals, played, week,
+					commentary, postponed, kickoff, live, minute, administrative)
+				VALUES (?, ?, ?, ?, ?, ?, COALESCE(?, ''), ?, ?, ?, ?, ?)`,
+				teamIDs[m.homeTeamID], teamIDs[m.awayTeamID], m.homeGoals, m.awayGoals, m.played, m.week,
+				m.commentary, m.postponed, m.kickoff, m.live, m.minute, m.admin)
+			if err != nil {
+				return err
+			}
+			id, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			matchIDs[m.id] = int(id)
+		}
+		for _, e := range imp.events {
+			matchID, ok := matchIDs[e.matchID]
+			if !ok {
+				continue
+			}
+			// a scorer who left with another team is forgotten, as when a
+			// player is removed
+			var playerID sql.NullInt64
+			if id, ok := playerIDs[e.playerID.Int64]; ok && e.playerID.Valid {
+				playerID = sql.NullInt64{Int64: id, Valid: true}
+			}
+			if _, err := tx.Exec("INSERT INTO match_events (match_id, minute, team_id, type, detail, player_id) VALUES (?, ?, ?, ?, ?, ?)",
+				matchID, e.minute, teamIDs[e.teamID], e.kind, e.detail, playerID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.touch()
+	return nil
+}
+
+// loadDivisions puts the stored divisions under the running config, if any
+// were stored
+func (l *League) loadDivisions() error {
+	var value string
+	err := l.db.QueryRow("SELECT value FROM settings WHERE key = ?", divisionsKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var divisions []Division
+	if err := json.Unmarshal([]byte(value), &divisions); err != nil {
+		return fmt.Errorf("invalid %s setting: %v", divisionsKey, err)
+	}
+	return l.updateBase(func(cfg *Config) { cfg.Divisions = divisions })
+}
+
+// setDivisions stores the divisions and puts them in effect
+func (l *League) setDivisions(divisions []Division) error {
+	if err := l.updateBase(func(cfg *Config) { cfg.Divisions = divisions }); err != nil {
+		return err
+	}
+	value, err := json.Marshal(divisions)
+	if err != nil {
+		return err
+	}
+	_, err = l.db.Exec(`
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, divisionsKey, string(value))
+	return err
+}
@@ -0,0 +1,129 @@
+package insider_test
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+var importedTeams = []insider.Team{{Name: "Reds", Strength: 70}, {Name: "Blues", Strength: 65}, {Name: "Greens", Strength: 60}, {Name: "Whites", Strength: 55}}
+
+func TestImportDivision(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 12)
+	// a league run on its own, halfway through its season
+	src := leaguetest.New(t, importedTeams, 13)
+	if _, err := src.League.GenerateSquads(); err != nil {
+		t.Fatal(err)
+	}
+	for week := 1; week <= 3; week++ {
+		src.SimulateWeek(week)
+	}
+
+	info, err := h.League.ImportDivision(src.League.DB(), "Imported", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var divisions []insider.DivisionInfo
+	h.Get("/divisions", &divisions)
+	if len(divisions) != 2 || divisions[1].Path != info.Path || divisions[1].Teams != 4 || divisions[1].Slots != 1 {
+		t.Fatalf("divisions %+v, want the import below the first league", divisions)
+	}
+
+	var table []insider.Standing
+	h.Get(info.Path+"/standings", &table)
+	want := src.Standings()
+	for i := range want {
+		if table[i].TeamName != want[i].TeamName || table[i].Points != want[i].Points ||
+			table[i].GoalsFor != want[i].GoalsFor || table[i].GoalsAgainst != want[i].GoalsAgainst {
+			t.Errorf("row %d: %+v, want %+v", i+1, table[i], want[i])
+		}
+	}
+
+	// the matches, their events and the squads come along under new ids
+	var matches []insider.Match
+	h.Get(info.Path+"/matches", &matches)
+	srcMatches := src.Matches()
+	if len(matches) != len(srcMatches) {
+		t.Fatalf("%d matches imported, want %d", len(matches), len(srcMatches))
+	}
+	events := 0
+	for i, m := range matches {
+		s := srcMatches[i]
+		if m.Week != s.Week || m.HomeTeam != s.HomeTeam || m.AwayTeam != s.AwayTeam || m.Played != s.Played ||
+			m.HomeGoals != s.HomeGoals || m.AwayGoals != s.AwayGoals {
+			t.Errorf("match %d: %+v, want %+v", i+1, m, s)
+		}
+		if !m.Played {
+			continue
+		}
+		var got, orig insider.MatchDetail
+		h.Get(info.Path+"/matches/"+strconv.Itoa(m.ID), &got)
+		src.Get("/matches/"+strconv.Itoa(s.ID), &orig)
+		if len(got.Events) != len(orig.Events) {
+			t.Errorf("match %d: %d events, want %d", i+1, len(got.Events), len(orig.Events))
+		}
+		events += len(got.Events)
+	}
+	if events == 0 {
+		t.Error("no events imported")
+	}
+	var players, srcPlayers []insider.Player
+	h.Get(info.Path+"/teams/Reds/players", &players)
+	src.Get("/teams/Reds/players", &srcPlayers)
+	if len(players) == 0 || len(players) != len(srcPlayers) {
+		t.Errorf("%d Reds players imported, want %d", len(players), len(srcPlayers))
+	}
+
+	// the imported division takes part in promotion
+	h.SimulateSeason()
+	h.Do(http.MethodPost, info.Path+"/simulate/all", nil, false, nil)
+	upper := h.Standings()
+	h.Get(info.Path+"/standings", &table)
+	var moves []insider.TeamMove
+	if status := h.Do(http.MethodPost, "/divisions/promote", nil, true, &moves); status != http.StatusOK {
+		t.Fatalf("POST /divisions/promote: status %d", status)
+	}
+	if len(moves) != 2 || moves[0].Team != upper[3].TeamName || moves[0].To != info.ID ||
+		moves[1].Team != table[0].TeamName || moves[1].From != info.ID {
+		t.Errorf("moves %+v, want %s down and %s up", moves, upper[3].TeamName, table[0].TeamName)
+	}
+
+	// the division list is kept with the league
+	reopened := insider.NewLeague(h.League.DB(), nil, 6, nil, nil)
+	if err := reopened.InitDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	if divisions := reopened.Config().Divisions; len(divisions) != 1 || divisions[0].League != info.ID || divisions[0].Slots != 1 {
+		t.Errorf("divisions %+v after reopening", divisions)
+	}
+}
+
+func TestImportDivisionRefusals(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 12)
+	clash := leaguetest.New(t, []insider.Team{{Name: "Reds", Strength: 70}, {Name: "Alpha FC", Strength: 60}}, 13)
+	src := leaguetest.New(t, importedTeams, 13)
+
+	for _, tc := range []struct {
+		name  string
+		src   *leaguetest.Harness
+		slots int
+	}{
+		{"team name taken", clash, 1},
+		{"more slots than teams", src, 5},
+		{"negative slots", src, -1},
+	} {
+		if _, err := h.League.ImportDivision(tc.src.League.DB(), tc.name, tc.slots); !errors.Is(err, insider.ErrInvalidImport) {
+			t.Errorf("%s: %v, want ErrInvalidImport", tc.name, err)
+		}
+	}
+	if leagues := h.League.Leagues(); len(leagues) != 0 {
+		t.Errorf("leagues %+v after refused imports", leagues)
+	}
+	if divisions := h.League.Config().Divisions; len(divisions) != 0 {
+		t.Errorf("divisions %+v after refused imports", divisions)
+	}
+}
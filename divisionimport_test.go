@@ -0,0 +1,40 @@
+package insider
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportDivisionFileOnce(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, 6, 1)
+
+	path := filepath.Join(t.TempDir(), "sunday.db")
+	db, err := sql.Open(DriverName, path+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	teams := []Team{{Name: "Reds", Strength: 70}, {Name: "Blues", Strength: 65}, {Name: "Greens", Strength: 60}, {Name: "Whites", Strength: 55}}
+	if err := NewLeague(db, teams, 6, nil, nil).InitDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// a restart with the flag still set imports nothing more
+	for range 2 {
+		if err := l.importDivisionFile(path, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	leagues := l.Leagues()
+	if len(leagues) != 1 || leagues[0].Name != "sunday" || leagues[0].Teams != 4 {
+		t.Fatalf("leagues %+v, want sunday once", leagues)
+	}
+	if divisions := l.config().Divisions; len(divisions) != 1 || divisions[0].League != leagues[0].ID {
+		t.Errorf("divisions %+v", divisions)
+	}
+
+	if err := l.importDivisionFile(filepath.Join(t.TempDir(), "missing.db"), 1); err == nil {
+		t.Error("imported a missing file")
+	}
+}
@@ -0,0 +1,563 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// Divisions chain the first league to other leagues of the server below it.
+// The config of the first league lists them top first, each with the
+// number of places swapped with the division above. Once every division
+// has played its last match, POST /divisions/promote archives their seasons,
+// sends the bottom teams of each division down and the top teams of the one
+// below up, and starts the next seasons with fresh fixtures. A relegation
+// playoff between the first league and its playoff division moves one more
+// pair when the lower side wins it. A team that leaves a division stays in
+// its database for the old matches, inactive, and comes back to life if it
+// returns.
+
+// places between divisions in the standings
+const (
+	PlacePromotion  = "promotion"
+	PlaceRelegation = "relegation"
+	PlacePlayoff    = "playoff"
+)
+
+// ErrNoDivisions is returned when the first league has no divisions below it
+var ErrNoDivisions = errors.New("no divisions below this league")
+
+// Division is a league below the one before it in the config's "divisions"
+type Division struct {
+	// League is the id under /leagues
+	League int `json:"league"`
+	// Slots is how many teams go up to the division above, and down from
+	// it, at the end of a season
+	Slots int `json:"slots"`
+}
+
+func validateDivisions(divisions []Division) error {
+	seen := make(map[int]bool)
+	for _, d := range divisions {
+		if d.League < 1 {
+			return fmt.Errorf("league must be a league id, got %d", d.League)
+		}
+		if seen[d.League] {
+			return fmt.Errorf("league %d is listed twice", d.League)
+		}
+		seen[d.League] = true
+		if d.Slots < 0 {
+			return fmt.Errorf("league %d: slots cannot be negative, got %d", d.League, d.Slots)
+		}
+	}
+	return nil
+}
+
+// divisionChain is every division, the first league first with id 0; the
+// slots of each are shared with the one above it
+func (l *League) divisionChain() []Division {
+	top := l
+	if l.parent != nil {
+		top = l.parent
+	}
+	return append([]Division{{}}, top.config().Divisions...)
+}
+
+// divisionPlaces maps the table positions of this league to their place
+// between divisions, for a table of the given number of teams
+func (l *League) divisionPlaces(teams int) map[int]string {
+	chain := l.divisionChain()
+	places := make(map[int]string)
+	for i, d := range chain {
+		if d.League != l.id {
+			continue
+		}
+		if i > 0 {
+			for p := 1; p <= d.Slots && p <= teams; p++ {
+				places[p] = PlacePromotion
+			}
+		}
+		if i+1 < len(chain) {
+			for p := teams; p > teams-chain[i+1].Slots && p > 0; p-- {
+				places[p] = PlaceRelegation
+			}
+		}
+	}
+
+	top := l
+	if l.parent != nil {
+		top = l.parent
+	}
+	playoff := top.config().RelegationPlayoff
+	position := 0
+	switch {
+	case playoff.Position == 0:
+	case l.parent == nil:
+		position = teams + 1 - playoff.Position
+	case l.id == playoff.LowerLeague:
+		position = playoff.Position
+	}
+	if position >= 1 && position <= teams && places[position] == "" {
+		places[position] = PlacePlayoff
+	}
+	return places
+}
+
+// TeamMove is a team changing division
+type TeamMove struct {
+	Team string `json:"team"`
+	// From and To are league ids, 0 for the first league
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// DivisionInfo is one division of the chain
+type DivisionInfo struct {
+	League int    `json:"league"`
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Teams  int    `json:"teams"`
+	// Slots are swapped with the division above
+	Slots int `json:"slots"`
+}
+
+// divisionLeague finds a division's league, the first league for id 0
+func (l *League) divisionLeague(id int) (*League, error) {
+	if id == 0 {
+		return l, nil
+	}
+	if l.leagues == nil {
+		return nil, ErrNoDivisions
+	}
+	l.leagues.mu.Lock()
+	defer l.leagues.mu.Unlock()
+	o := l.leagues.leagues[id]
+	if o == nil {
+		return nil, fmt.Errorf("%w: league %d does not exist", ErrNoDivisions, id)
+	}
+	return o.league, nil
+}
+
+// Divisions lists the chain of divisions, top first
+func (l *League) Divisions() ([]DivisionInfo, error) {
+	divisions := []DivisionInfo{}
+	if l.leagues == nil {
+		return divisions, nil
+	}
+	for _, d := range l.divisionChain() {
+		league, err := l.divisionLeague(d.League)
+		if err != nil {
+			return nil, err
+		}
+		info := DivisionInfo{League: d.League, Name: "First league", Path: "/", Teams: len(league.Teams()), Slots: d.Slots}
+		if d.League != 0 {
+			other := l.leagues.info(d.League)
+			info.Name, info.Path = other.Name, other.Path
+		}
+		divisions = append(divisions, info)
+	}
+	return divisions, nil
+}
+
+// ErrInvalidPromotion is returned for moves the divisions cannot take
+var ErrInvalidPromotion = errors.New("invalid promotion")
+
+// promotionKey is the settings row of a promotion under way
+const promotionKey = "promotion"
+
+// stages of a promotion
+const (
+	stageArchive = "archive"
+	stageMove    = "move"
+	stageStart   = "start"
+)
+
+// promotion is a PromoteDivisions under way. It is stored in the first
+// league once the moves are known and dropped when the next seasons have
+// started, so a call that fails half way is finished by the next one from
+// the stage it reached. Every step of a stage can safely run twice.
+type promotion struct {
+	Leagues []int      `json:"leagues"`
+	Moves   []TeamMove `json:"moves"`
+	Stage   string     `json:"stage"`
+}
+
+// loadPromotion is the promotion under way, nil when there is none
+func (l *League) loadPromotion() (*promotion, error) {
+	var value string
+	err := l.db.QueryRow("SELECT value FROM settings WHERE key = ?", promotionKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p promotion
+	if err := json.Unmarshal([]byte(value), &p); err != nil {
+		return nil, fmt.Errorf("invalid %s setting: %v", promotionKey, err)
+	}
+	return &p, nil
+}
+
+func (l *League) savePromotion(p *promotion) error {
+	value, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = l.db.Exec(`
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, promotionKey, string(value))
+	return err
+}
+
+// PromoteDivisions ends the season of every division and moves the teams
+// between them. Every check is made before anything changes; after that a
+// failure leaves the promotion stored, and calling again completes it.
+func (l *League) PromoteDivisions() ([]TeamMove, error) {
+	if l.leagues == nil {
+		return nil, fmt.Errorf("%w: the config has no divisions", ErrNoDivisions)
+	}
+	p, err := l.loadPromotion()
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		if p, err = l.planPromotion(); err != nil {
+			return nil, err
+		}
+		if err := l.savePromotion(p); err != nil {
+			return nil, err
+		}
+	}
+	return l.completePromotion(p)
+}
+
+// planPromotion checks the divisions and works out the moves. The
+// relegation playoff, when it has not been played at the end of the
+// seasons, is the only thing it records, and only once the moves either
+// result leads to are known to fit.
+func (l *League) planPromotion() (*promotion, error) {
+	if len(l.config().Divisions) == 0 {
+		return nil, fmt.Errorf("%w: the config has no divisions", ErrNoDivisions)
+	}
+	chain := l.divisionChain()
+	leagues := make(map[int]*League, len(chain))
+	tables := make([][]Standing, len(chain))
+	p := &promotion{Moves: []TeamMove{}, Stage: stageArchive}
+	for i, d := range chain {
+		league, err := l.divisionLeague(d.League)
+		if err != nil {
+			return nil, err
+		}
+		finished, err := league.seasonFinished()
+		if err != nil {
+			return nil, err
+		}
+		if !finished {
+			return nil, fmt.Errorf("%w: league %d", ErrSeasonUnfinished, d.League)
+		}
+		if tables[i], err = league.CalculateStandings(); err != nil {
+			return nil, err
+		}
+		leagues[d.League] = league
+		p.Leagues = append(p.Leagues, d.League)
+	}
+
+	for i := 1; i < len(chain); i++ {
+		upper, lower := tables[i-1], tables[i]
+		slots := chain[i].Slots
+		if slots > len(upper) || slots > len(lower) {
+			return nil, fmt.Errorf("%w: %d slots between leagues %d and %d with %d and %d teams",
+				ErrNoDivisions, slots, chain[i-1].League, chain[i].League, len(upper), len(lower))
+		}
+		for _, s := range upper[len(upper)-slots:] {
+			p.Moves = append(p.Moves, TeamMove{Team: s.TeamName, From: chain[i-1].League, To: chain[i].League, Kind: PlaceRelegation})
+		}
+		for _, s := range lower[:slots] {
+			p.Moves = append(p.Moves, TeamMove{Team: s.TeamName, From: chain[i].League, To: chain[i-1].League, Kind: PlacePromotion})
+		}
+	}
+	if err := checkMoves(leagues, p.Moves); err != nil {
+		return nil, err
+	}
+
+	// the playoff counts as the last place: a win for the lower side swaps
+	// one more pair between the first two divisions
+	playoff := l.config().RelegationPlayoff
+	if playoff.Position == 0 {
+		return p, nil
+	}
+	swaps := len(chain) > 1 && playoff.LowerLeague == chain[1].League
+	played, err := l.playedPlayoff()
+	if err != nil {
+		return nil, err
+	}
+	if played == nil && swaps {
+		upper, lower := tables[0], tables[1]
+		if playoff.Position > len(upper) || playoff.Position > len(lower) {
+			return nil, fmt.Errorf("%w: playoff position %d is past the %d and %d teams of the divisions",
+				ErrInvalidPromotion, playoff.Position, len(upper), len(lower))
+		}
+		swap := playoffSwap(chain[1].League, upper[len(upper)-playoff.Position].TeamName, lower[playoff.Position-1].TeamName)
+		if err := checkMoves(leagues, append(slices.Clone(p.Moves), swap...)); err != nil {
+			return nil, err
+		}
+	}
+	if played == nil {
+		if played, err = l.PlayRelegationPlayoff(); err != nil {
+			return nil, err
+		}
+	}
+	if swaps && played.LowerLeague == chain[1].League && played.Winner == played.LowerTeam {
+		p.Moves = append(p.Moves, playoffSwap(chain[1].League, played.UpperTeam, played.LowerTeam)...)
+		if err := checkMoves(leagues, p.Moves); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// playedPlayoff is the relegation playoff of the current season, nil before
+// it is played
+func (l *League) playedPlayoff() (*PlayoffResult, error) {
+	season, err := currentSeason(l.db)
+	if err != nil {
+		return nil, err
+	}
+	playoffs, err := l.RelegationPlayoffs()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range playoffs {
+		if p.SeasonID == season.ID {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+// playoffSwap moves the playoff's upper team down to lowerLeague and its
+// lower team up to the first league
+func playoffSwap(lowerLeague int, upperTeam, lowerTeam string) []TeamMove {
+	return []TeamMove{
+		{Team: upperTeam, From: 0, To: lowerLeague, Kind: PlacePlayoff},
+		{Team: lowerTeam, From: lowerLeague, To: 0, Kind: PlacePlayoff},
+	}
+}
+
+// checkMoves makes sure every team moves once and finds no other team of its
+// name where it arrives
+func checkMoves(leagues map[int]*League, moves []TeamMove) error {
+	moving := make(map[int]map[string]bool)
+	for _, m := range moves {
+		if moving[m.From] == nil {
+			moving[m.From] = make(map[string]bool)
+		}
+		if moving[m.From][m.Team] {
+			return fmt.Errorf("%w: %s would leave league %d twice", ErrInvalidPromotion, m.Team, m.From)
+		}
+		moving[m.From][m.Team] = true
+	}
+	for _, m := range moves {
+		for _, t := range leagues[m.To].Teams() {
+			if t.Name == m.Team && !moving[m.To][m.Team] {
+				return fmt.Errorf("%w: league %d already has a team called %s", ErrInvalidPromotion, m.To, m.Team)
+			}
+		}
+	}
+	return nil
+}
+
+// completePromotion runs a promotion from the stage it reached: archive
+// every season, move the teams, start the next seasons
+func (l *League) completePromotion(p *promotion) ([]TeamMove, error) {
+	leagues := make(map[int]*League, len(p.Leagues))
+	for _, id := range p.Leagues {
+		league, err := l.divisionLeague(id)
+		if err != nil {
+			return nil, err
+		}
+		leagues[id] = league
+	}
+
+	// archive first: a team may only leave once its matches are in the
+	// archive. A league already on its planned season was archived before.
+	if p.Stage == stageArchive {
+		for _, id := range p.Leagues {
+			season, err := currentSeason(leagues[id].db)
+			if err != nil {
+				return nil, err
+			}
+			if season.Status == SeasonPlanned {
+				continue
+			}
+			if _, err := leagues[id].ArchiveSeason(""); err != nil {
+				return nil, fmt.Errorf("league %d: %v", id, err)
+			}
+		}
+		p.Stage = stageMove
+		if err := l.savePromotion(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.Stage == stageMove {
+		for _, m := range p.Moves {
+			if err := moveTeam(leagues[m.From], leagues[m.To], m.Team); err != nil {
+				return nil, fmt.Errorf("moving %s from league %d to league %d: %v", m.Team, m.From, m.To, err)
+			}
+		}
+		p.Stage = stageStart
+		if err := l.savePromotion(p); err != nil {
+			return nil, err
+		}
+	}
+
+	// every swap is one team each way, so the fixtures keep their length
+	for _, id := range p.Leagues {
+		season, err := currentSeason(leagues[id].db)
+		if err != nil {
+			return nil, err
+		}
+		if season.Status != SeasonPlanned {
+			continue
+		}
+		if _, err := leagues[id].StartSeason(season.ID); err != nil {
+			return nil, fmt.Errorf("league %d: %v", id, err)
+		}
+	}
+	if _, err := l.db.Exec("DELETE FROM settings WHERE key = ?", promotionKey); err != nil {
+		return nil, err
+	}
+	return p.Moves, nil
+}
+
+// moveTeam takes a team and its squad out of one division and into another,
+// bringing back the team it was there before. The team joins the new
+// division before it leaves the old one, so a failure in between leaves it
+// in both; calling again finishes the move, as does calling once it is done.
+func moveTeam(from, to *League, name string) error {
+	var team Team
+	for _, t := range from.Teams() {
+		if t.Name == name {
+			team = t
+		}
+	}
+	if team.ID == 0 {
+		for _, t := range to.Teams() {
+			if t.Name == name {
+				return nil
+			}
+		}
+		return sql.ErrNoRows
+	}
+	if err := joinDivision(to, team, from); err != nil {
+		return err
+	}
+	if _, err := from.db.Exec("UPDATE teams SET active = FALSE WHERE id = ?", team.ID); err != nil {
+		return err
+	}
+	return from.loadTeams()
+}
+
+// joinDivision adds team, with its squad in from, to the teams of to. A team
+// of the name already active there is taken to be this one, joined by an
+// earlier try: checkMoves rules out any other before a promotion starts.
+func joinDivision(to *League, team Team, from *League) error {
+	players, err := from.Players(team.Name)
+	if err != nil {
+		return err
+	}
+	metadata, err := json.Marshal(team.Metadata)
+	if err != nil {
+		return err
+	}
+
+	tx, err := to.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int
+	var active bool
+	err = tx.QueryRow("SELECT id, active FROM teams WHERE name = ?", team.Name).Scan(&id, &active)
+	switch {
+	case err == sql.ErrNoRows:
+		res, err := tx.Exec("INSERT INTO teams (name, strength, metadata) VALUES (?, ?, ?)", team.Name, team.Strength, string(metadata))
+		if err != nil {
+			return err
+		}
+		id64, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		id = int(id64)
+	case err != nil:
+		return err
+	case active:
+		return nil
+	default:
+		if _, err := tx.Exec("UPDATE teams SET strength = ?, metadata = ?, active = TRUE WHERE id = ?", team.Strength, string(metadata), id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM players WHERE team_id = ?", id); err != nil {
+			return err
+		}
+	}
+	for _, p := range players {
+		if _, err := tx.Exec("INSERT INTO players (team_id, name, position, rating) VALUES (?, ?, ?, ?)",
+			id, p.Name, p.Position, p.Rating); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return to.loadTeams()
+}
+
+// GET /divisions lists the divisions, top first
+func (l *League) handleDivisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	divisions, err := l.Divisions()
+	if errors.Is(err, ErrNoDivisions) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(divisions)
+}
+
+// POST /divisions/promote moves the teams between the finished divisions and
+// starts their next seasons (admin only)
+func (l *League) handlePromoteDivisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	moves, err := l.PromoteDivisions()
+	switch {
+	case errors.Is(err, ErrNoDivisions), errors.Is(err, ErrSeasonUnfinished), errors.Is(err, ErrSeasonPlanned),
+		errors.Is(err, ErrInvalidPromotion):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(moves)
+}
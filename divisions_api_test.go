@@ -0,0 +1,194 @@
+package insider_test
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestPromotionAndRelegation(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 12)
+	var lower insider.LeagueInfo
+	req := insider.LeagueRequest{
+		Name:  "Second division",
+		Teams: []insider.Team{{Name: "Reds", Strength: 70}, {Name: "Blues", Strength: 65}, {Name: "Greens", Strength: 60}, {Name: "Whites", Strength: 55}},
+	}
+	if status := h.Do(http.MethodPost, "/leagues", req, true, &lower); status != http.StatusCreated {
+		t.Fatalf("POST /leagues: status %d", status)
+	}
+	if status := h.Do(http.MethodPost, "/divisions/promote", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("promote without divisions: status %d, want 409", status)
+	}
+	cfg := *h.League.Config()
+	cfg.Divisions = []insider.Division{{League: lower.ID, Slots: 1}}
+	h.League.StoreConfig(&cfg)
+
+	var divisions []insider.DivisionInfo
+	h.Get("/divisions", &divisions)
+	if len(divisions) != 2 || divisions[1].Path != lower.Path || divisions[1].Slots != 1 {
+		t.Errorf("divisions %+v", divisions)
+	}
+
+	h.SimulateSeason()
+	if status := h.Do(http.MethodPost, "/divisions/promote", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("promote before the lower division finished: status %d, want 409", status)
+	}
+	h.Do(http.MethodPost, lower.Path+"/simulate/all", nil, false, nil)
+
+	upper := h.Standings()
+	var lowerTable []insider.Standing
+	h.Get(lower.Path+"/standings", &lowerTable)
+	if upper[3].Place != insider.PlaceRelegation || upper[2].Place != "" || upper[0].Place != "" {
+		t.Errorf("top division places %q %q %q %q", upper[0].Place, upper[1].Place, upper[2].Place, upper[3].Place)
+	}
+	if lowerTable[0].Place != insider.PlacePromotion || lowerTable[3].Place != "" {
+		t.Errorf("lower division places %q ... %q", lowerTable[0].Place, lowerTable[3].Place)
+	}
+	relegated, promoted := upper[3].TeamName, lowerTable[0].TeamName
+
+	var moves []insider.TeamMove
+	if status := h.Do(http.MethodPost, "/divisions/promote", nil, true, &moves); status != http.StatusOK {
+		t.Fatalf("POST /divisions/promote: status %d", status)
+	}
+	if len(moves) != 2 || moves[0].Team != relegated || moves[1].Team != promoted {
+		t.Errorf("moves %+v, want %s down and %s up", moves, relegated, promoted)
+	}
+
+	names := func(teams []insider.Team) []string {
+		var names []string
+		for _, team := range teams {
+			names = append(names, team.Name)
+		}
+		return names
+	}
+	var teams []insider.Team
+	h.Get("/teams", &teams)
+	if got := names(teams); len(got) != 4 || !slices.Contains(got, promoted) || slices.Contains(got, relegated) {
+		t.Errorf("top division teams %v after %s went down and %s came up", got, relegated, promoted)
+	}
+	h.Get(lower.Path+"/teams", &teams)
+	if got := names(teams); len(got) != 4 || !slices.Contains(got, relegated) || slices.Contains(got, promoted) {
+		t.Errorf("lower division teams %v", got)
+	}
+
+	// both divisions start over with the new teams
+	for _, path := range []string{"", lower.Path} {
+		var matches []insider.Match
+		h.Get(path+"/matches", &matches)
+		if len(matches) != 12 || matches[0].Played {
+			t.Errorf("%s/matches: %d matches after promotion", path, len(matches))
+		}
+	}
+	var seasons []insider.Season
+	h.Get("/seasons", &seasons)
+	if len(seasons) != 2 || seasons[1].Status != insider.SeasonActive {
+		t.Fatalf("seasons %+v", seasons)
+	}
+	var archived []insider.Standing
+	h.Get("/standings?season="+strconv.Itoa(seasons[0].ID), &archived)
+	if len(archived) != 4 || archived[3].TeamName != relegated {
+		t.Errorf("archived table %+v still ends with %s", archived, relegated)
+	}
+}
+
+// secondDivision adds a league of teams below the first, swapping one place
+func secondDivision(t *testing.T, h *leaguetest.Harness, teams []insider.Team) (insider.LeagueInfo, *insider.League) {
+	t.Helper()
+	var info insider.LeagueInfo
+	if status := h.Do(http.MethodPost, "/leagues", insider.LeagueRequest{Name: "Second division", Teams: teams}, true, &info); status != http.StatusCreated {
+		t.Fatalf("POST /leagues: status %d", status)
+	}
+	cfg := *h.League.Config()
+	cfg.Divisions = []insider.Division{{League: info.ID, Slots: 1}}
+	h.League.StoreConfig(&cfg)
+	lower, err := h.League.DivisionLeague(info.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info, lower
+}
+
+func TestPromotionResumes(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 12)
+	_, lower := secondDivision(t, h, []insider.Team{{Name: "Reds", Strength: 70}, {Name: "Blues", Strength: 65}, {Name: "Greens", Strength: 60}, {Name: "Whites", Strength: 55}})
+	insider.PlayByStrength(t, h.League, 1, 6)
+	insider.PlayByStrength(t, lower, 1, 6)
+
+	// a promotion that archived the first league and got Delta SC into the
+	// second division before it failed
+	p, err := h.League.PlanPromotion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.League.SavePromotion(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.League.ArchiveSeason(""); err != nil {
+		t.Fatal(err)
+	}
+	var delta insider.Team
+	for _, team := range h.League.Teams() {
+		if team.Name == "Delta SC" {
+			delta = team
+		}
+	}
+	if err := insider.JoinDivision(lower, delta, h.League); err != nil {
+		t.Fatal(err)
+	}
+
+	var moves []insider.TeamMove
+	if status := h.Do(http.MethodPost, "/divisions/promote", nil, true, &moves); status != http.StatusOK {
+		t.Fatalf("POST /divisions/promote: status %d", status)
+	}
+	if len(moves) != 2 || moves[0].Team != "Delta SC" || moves[1].Team != "Reds" {
+		t.Errorf("moves %+v, want Delta SC down and Reds up", moves)
+	}
+	for _, l := range []*insider.League{h.League, lower} {
+		if n := insider.SeasonCount(t, l); n != 2 {
+			t.Errorf("%d seasons after the promotion, want 2", n)
+		}
+		if len(l.Teams()) != 4 {
+			t.Errorf("%d teams after the promotion, want 4", len(l.Teams()))
+		}
+	}
+	for _, team := range lower.Teams() {
+		if team.Name == "Reds" {
+			t.Error("Reds are still in the second division")
+		}
+	}
+	if p, err := h.League.LoadPromotion(); err != nil || p != nil {
+		t.Errorf("promotion %+v still stored, err %v", p, err)
+	}
+
+	// a finished move is a move done
+	if err := insider.MoveTeam(h.League, lower, "Delta SC"); err != nil {
+		t.Errorf("moving Delta SC again: %v", err)
+	}
+}
+
+func TestPromotionChecksFirst(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 12)
+	// the second division has a Delta SC of its own, at the bottom
+	_, lower := secondDivision(t, h, []insider.Team{{Name: "Reds", Strength: 70}, {Name: "Blues", Strength: 65}, {Name: "Greens", Strength: 60}, {Name: "Delta SC", Strength: 55}})
+	insider.PlayByStrength(t, h.League, 1, 6)
+	insider.PlayByStrength(t, lower, 1, 6)
+
+	if status := h.Do(http.MethodPost, "/divisions/promote", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("Delta SC going down to a Delta SC: status %d, want 409", status)
+	}
+	for _, l := range []*insider.League{h.League, lower} {
+		if n := insider.SeasonCount(t, l); n != 1 {
+			t.Errorf("%d seasons after a refused promotion", n)
+		}
+		if len(l.Teams()) != 4 {
+			t.Errorf("%d teams after a refused promotion", len(l.Teams()))
+		}
+	}
+	if p, err := h.League.LoadPromotion(); err != nil || p != nil {
+		t.Errorf("refused promotion stored as %+v, err %v", p, err)
+	}
+}
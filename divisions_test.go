@@ -0,0 +1,28 @@
+package insider
+
+import (
+	"testing"
+)
+
+func TestDivisionPlacesWithPlayoff(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, 6, 1)
+	cfg := *l.config()
+	cfg.Divisions = []Division{{League: 1, Slots: 1}}
+	cfg.RelegationPlayoff = RelegationPlayoff{LowerLeague: 1, Position: 2}
+	l.cfg.Store(&cfg)
+
+	places := l.divisionPlaces(6)
+	if places[6] != PlaceRelegation || places[5] != PlacePlayoff || places[4] != "" || places[1] != "" {
+		t.Errorf("places %v", places)
+	}
+}
+
+// seasonCount is how many seasons a league has had
+func seasonCount(t *testing.T, l *League) int {
+	t.Helper()
+	seasons, err := l.Seasons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(seasons)
+}
@@ -0,0 +1,165 @@
+package insider
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Elo imports turn a rating list, such as a clubelo.com or eloratings.net
+// export, into teams. Ratings live on a scale of their own (1500 to 2000 for
+// most top flight clubs), so they are normalized onto the strength scale;
+// only the order and spacing of the clubs carry over.
+
+// defaultEloScale spreads the imported clubs over the strengths of the
+// default teams and a little beyond
+const defaultEloScale = "linear:40-90"
+
+// eloAnchor is the strength the average rating maps to with points:N
+const eloAnchor = 65
+
+// eloNameColumns and eloRatingColumns are the headers recognised, in order
+var (
+	eloNameColumns   = []string{"club", "team", "name"}
+	eloRatingColumns = []string{"elo", "rating", "points"}
+)
+
+// eloScale maps a rating to a strength
+type eloScale struct {
+	// linear: the lowest rating gets min, the highest max
+	min, max int
+	// points: perPoint Elo points per strength point around eloAnchor
+	perPoint float64
+}
+
+// parseEloScale reads --elo-scale, linear:MIN-MAX or points:N
+func parseEloScale(spec string) (eloScale, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "linear":
+		lo, hi, ok := strings.Cut(arg, "-")
+		minStrength, err1 := strconv.Atoi(lo)
+		maxStrength, err2 := strconv.Atoi(hi)
+		if !ok || err1 != nil || err2 != nil || minStrength < MinStrength || maxStrength > MaxStrength || maxStrength < minStrength {
+			return eloScale{}, fmt.Errorf("invalid strength range %q, expected MIN-MAX with %d <= MIN <= MAX <= %d", arg, MinStrength, MaxStrength)
+		}
+		return eloScale{min: minStrength, max: maxStrength}, nil
+	case "points":
+		perPoint, err := strconv.ParseFloat(arg, 64)
+		if err != nil || !(perPoint > 0) || math.IsInf(perPoint, 1) {
+			return eloScale{}, fmt.Errorf("invalid Elo points per strength %q", arg)
+		}
+		return eloScale{perPoint: perPoint}, nil
+	}
+	return eloScale{}, fmt.Errorf("unknown Elo scale %q, expected linear:MIN-MAX or points:N", spec)
+}
+
+// strengths normalizes ratings onto the strength scale; with points:N a
+// club far from the average is held at the end of the scale
+func (s eloScale) strengths(ratings []float64) []int {
+	if s.perPoint == 0 {
+		return NormalizeStrengths(ratings, s.min, s.max)
+	}
+	sum := 0.0
+	for _, r := range ratings {
+		sum += r
+	}
+	strengths := make([]int, len(ratings))
+	for i, r := range ratings {
+		strengths[i] = clampStrength(eloAnchor + (r-sum/float64(len(ratings)))/s.perPoint)
+	}
+	return strengths
+}
+
+// LoadEloTeams reads a CSV of club ratings and normalizes them to strengths.
+//
+// The header needs a club, team or name column and an elo, rating or points
+// column; every other column (country, level...) is kept as team metadata.
+func LoadEloTeams(path, scaleSpec string) ([]Team, error) {
+	scale, err := parseEloScale(scaleSpec)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	teams, ratings, err := readEloCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	for i, strength := range scale.strengths(ratings) {
+		teams[i].Strength = strength
+	}
+
+	if err := validateTeams(teams); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+func readEloCSV(r io.Reader) ([]Team, []float64, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("missing header row: %v", err)
+	}
+	for i, col := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(col))
+	}
+	nameCol, ratingCol := findColumn(header, eloNameColumns), findColumn(header, eloRatingColumns)
+	if nameCol < 0 || ratingCol < 0 {
+		return nil, nil, fmt.Errorf("header must contain a club, team or name column and an elo, rating or points column")
+	}
+
+	var teams []Team
+	var ratings []float64
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// ParseFloat takes NaN and Inf, which would poison the whole scale
+		rating, err := strconv.ParseFloat(strings.TrimSpace(record[ratingCol]), 64)
+		if err != nil || math.IsNaN(rating) || math.IsInf(rating, 0) {
+			return nil, nil, fmt.Errorf("line %d: invalid rating %q", line, record[ratingCol])
+		}
+		team := Team{Name: strings.TrimSpace(record[nameCol])}
+		for i, value := range record {
+			if i == nameCol || i == ratingCol || value == "" {
+				continue
+			}
+			if team.Metadata == nil {
+				team.Metadata = make(map[string]string)
+			}
+			team.Metadata[header[i]] = value
+		}
+		teams = append(teams, team)
+		ratings = append(ratings, rating)
+	}
+	return teams, ratings, nil
+}
+
+// findColumn is the index of the first of names in header, -1 if none is
+func findColumn(header, names []string) int {
+	for _, name := range names {
+		for i, col := range header {
+			if col == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,116 @@
+package insider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadEloCSVHeaders(t *testing.T) {
+	tests := []struct {
+		name, csv    string
+		teams        []string
+		ratings      []float64
+		metadataKey  string
+		metadataWant string
+	}{
+		{"clubelo export", "Rank,Club,Country,Level,Elo,From,To\n1,Man City,ENG,1,2050.5,2024-01-01,2024-01-02\n2,Arsenal,ENG,1,1980,2024-01-01,2024-01-02\n",
+			[]string{"Man City", "Arsenal"}, []float64{2050.5, 1980}, "country", "ENG"},
+		{"headers in any case and spacing", " TEAM , Rating \nAlpha, 1600\nBravo,1500\n",
+			[]string{"Alpha", "Bravo"}, []float64{1600, 1500}, "", ""},
+		// club wins over name and elo over points, whatever the column order
+		{"first name in order wins", "name,points,club,elo\nAlpha,3,Alpha FC,1700\nBravo,1,Bravo United,1650\n",
+			[]string{"Alpha FC", "Bravo United"}, []float64{1700, 1650}, "name", "Alpha"},
+	}
+	for _, tt := range tests {
+		teams, ratings, err := readEloCSV(strings.NewReader(tt.csv))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(teams) != len(tt.teams) {
+			t.Errorf("%s: %d teams, want %d", tt.name, len(teams), len(tt.teams))
+			continue
+		}
+		for i := range teams {
+			if teams[i].Name != tt.teams[i] || ratings[i] != tt.ratings[i] {
+				t.Errorf("%s: team %d is %q at %g, want %q at %g", tt.name, i, teams[i].Name, ratings[i], tt.teams[i], tt.ratings[i])
+			}
+		}
+		if tt.metadataKey == "" {
+			if teams[0].Metadata != nil {
+				t.Errorf("%s: metadata %v without extra columns", tt.name, teams[0].Metadata)
+			}
+		} else if got := teams[0].Metadata[tt.metadataKey]; got != tt.metadataWant {
+			t.Errorf("%s: metadata %q = %q, want %q", tt.name, tt.metadataKey, got, tt.metadataWant)
+		}
+	}
+}
+
+func TestReadEloCSVRejects(t *testing.T) {
+	for name, csv := range map[string]string{
+		"empty file":        "",
+		"no name column":    "country,elo\nENG,1800\n",
+		"no rating column":  "club,country\nArsenal,ENG\n",
+		"rating not number": "club,elo\nArsenal,high\n",
+		"NaN rating":        "club,elo\nArsenal,NaN\nChelsea,1800\n",
+		"Inf rating":        "club,elo\nArsenal,+Inf\nChelsea,1800\n",
+		"negative Inf":      "club,elo\nArsenal,-inf\nChelsea,1800\n",
+		"short row":         "club,country,elo\nArsenal,ENG\n",
+	} {
+		if _, _, err := readEloCSV(strings.NewReader(csv)); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestEloScales(t *testing.T) {
+	ratings := []float64{1500, 1750, 2000}
+	linear, err := parseEloScale("linear:40-90")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := linear.strengths(ratings); got[0] != 40 || got[1] != 65 || got[2] != 90 {
+		t.Errorf("linear:40-90 = %v", got)
+	}
+	// 25 Elo points per strength point around the average of 1750
+	points, err := parseEloScale("points:25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := points.strengths(ratings); got[0] != eloAnchor-10 || got[1] != eloAnchor || got[2] != eloAnchor+10 {
+		t.Errorf("points:25 = %v", got)
+	}
+
+	for _, spec := range []string{"", "linear", "linear:90-40", "linear:40", "points:0", "points:-5", "points:NaN", "points:Inf", "log:2"} {
+		if _, err := parseEloScale(spec); err == nil {
+			t.Errorf("scale %q accepted", spec)
+		}
+	}
+}
+
+func TestLoadEloTeams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "elo.csv")
+	if err := os.WriteFile(path, []byte("club,elo\nAlpha FC,1900\nBravo United,1600\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	teams, err := LoadEloTeams(path, defaultEloScale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(teams) != 2 || teams[0].Strength != 90 || teams[1].Strength != 40 {
+		t.Errorf("teams %+v", teams)
+	}
+	if _, err := LoadEloTeams(path, "points:0"); err == nil {
+		t.Error("a bad scale was accepted")
+	}
+
+	// one club is no league
+	if err := os.WriteFile(path, []byte("club,elo\nAlpha FC,1900\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadEloTeams(path, defaultEloScale); err == nil {
+		t.Error("a single club was accepted")
+	}
+}
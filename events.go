@@ -0,0 +1,257 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"insider/matchengine"
+)
+
+// MatchEvent is something that happened during a match, e.g. a goal
+type MatchEvent struct {
+	Minute int    `json:"minute"`
+	Team   string `json:"team"`
+	Type   string `json:"type"`
+	Detail string `json:"detail,omitempty"`
+	// Player is only known for manually entered goals, see players.go
+	Player   string `json:"player,omitempty"`
+	playerID int
+}
+
+// Event types. Only goals change the score, the VAR ones are flavor.
+const (
+	EventGoal           = matchengine.Goal
+	EventDisallowedGoal = matchengine.DisallowedGoal
+	EventVAROverturn    = matchengine.VAROverturn
+)
+
+// defaultVARFrequency is the chance per match of a goal being ruled out
+const defaultVARFrequency = 0.15
+
+// MatchDetail is a match with everything that was recorded about it
+type MatchDetail struct {
+	Match
+	Events     []MatchEvent `json:"events"`
+	Commentary string       `json:"commentary"`
+	DramaTags  []string     `json:"drama_tags"`
+	// Travel is set when the config has both teams' cities
+	Travel *MatchTravel `json:"travel,omitempty"`
+	// Decisions is the audit trail of a match decided off the pitch
+	Decisions []AdministrativeDecision `json:"administrative_decisions,omitempty"`
+}
+
+// matchEvents names the sides of engine events after the teams of m
+func matchEvents(m Match, events []matchengine.Event) []MatchEvent {
+	named := make([]MatchEvent, 0, len(events))
+	for _, e := range events {
+		team := m.HomeTeam
+		if e.Side == matchengine.Away {
+			team = m.AwayTeam
+		}
+		named = append(named, MatchEvent{Minute: e.Minute, Team: team, Type: e.Type, Detail: e.Detail})
+	}
+	return named
+}
+
+// GenerateCommentary writes a short match report from the events
+func GenerateCommentary(m Match, events []MatchEvent) string {
+	var lines []string
+	home, away := 0, 0
+	for _, e := range events {
+		switch e.Type {
+		case EventDisallowedGoal:
+			lines = append(lines, fmt.Sprintf("%s thought they had scored on %d' but VAR ruled it out for %s.", e.Team, e.Minute, e.Detail))
+			continue
+		case EventVAROverturn:
+			lines = append(lines, fmt.Sprintf("%s were awarded a %d' %s, only for VAR to overturn it.", e.Team, e.Minute, e.Detail))
+			continue
+		case EventGoal:
+		default:
+			continue
+		}
+
+		scorer, other := e.Team, m.AwayTeam
+		if e.Team == m.AwayTeam {
+			other = m.HomeTeam
+		}
+		before := home - away
+		if e.Team == m.HomeTeam {
+			home++
+		} else {
+			away++
+			before = -before
+		}
+		how := fmt.Sprintf("%s %d' %s", minuteArticle(e.Minute), e.Minute, e.Detail)
+		if e.Player != "" {
+			how += " by " + e.Player
+		}
+
+		switch {
+		case home+away == 1:
+			lines = append(lines, fmt.Sprintf("%s took the lead through %s.", scorer, how))
+		case before == 0:
+			lines = append(lines, fmt.Sprintf("%s went ahead with %s.", scorer, how))
+		case before == -1:
+			lines = append(lines, fmt.Sprintf("%s equalised with %s.", scorer, how))
+		case before < -1:
+			lines = append(lines, fmt.Sprintf("%s pulled one back against %s with %s.", scorer, other, how))
+		default:
+			lines = append(lines, fmt.Sprintf("%s made it %d-%d with %s.", scorer, home, away, how))
+		}
+	}
+
+	switch {
+	case m.HomeGoals == 0 && m.AwayGoals == 0:
+		lines = append(lines, fmt.Sprintf("A goalless draw between %s and %s.", m.HomeTeam, m.AwayTeam))
+	case m.HomeGoals > m.AwayGoals:
+		lines = append(lines, fmt.Sprintf("Full time: %s %d-%d %s, a home win.", m.HomeTeam, m.HomeGoals, m.AwayGoals, m.AwayTeam))
+	case m.HomeGoals < m.AwayGoals:
+		lines = append(lines, fmt.Sprintf("Full time: %s %d-%d %s, %s win away.", m.HomeTeam, m.HomeGoals, m.AwayGoals, m.AwayTeam, m.AwayTeam))
+	default:
+		lines = append(lines, fmt.Sprintf("Full time: %s %d-%d %s, the points are shared.", m.HomeTeam, m.HomeGoals, m.AwayGoals, m.AwayTeam))
+	}
+	return strings.Join(lines, " ")
+}
+
+// DramaTags labels what made a match worth talking about
+func DramaTags(m Match, events []MatchEvent) []string {
+	tags := []string{}
+	if !m.Played {
+		return tags
+	}
+
+	home, away := 0, 0
+	homeTrailed, awayTrailed := false, false
+	lateWinner := false
+	varDrama := false
+	for _, e := range events {
+		switch e.Type {
+		case EventDisallowedGoal, EventVAROverturn:
+			varDrama = true
+		case EventGoal:
+			level := home == away
+			if e.Team == m.HomeTeam {
+				home++
+			} else {
+				away++
+			}
+			homeTrailed = homeTrailed || home < away
+			awayTrailed = awayTrailed || away < home
+			// the goal broke a tie late on and nobody scored after it
+			lateWinner = level && e.Minute >= 85
+		}
+	}
+
+	if varDrama {
+		tags = append(tags, "var_drama")
+	}
+	if lateWinner && m.HomeGoals != m.AwayGoals {
+		tags = append(tags, "late_winner")
+	}
+	if (m.HomeGoals > m.AwayGoals && homeTrailed) || (m.AwayGoals > m.HomeGoals && awayTrailed) {
+		tags = append(tags, "comeback")
+	}
+	if m.HomeGoals+m.AwayGoals >= 5 {
+		tags = append(tags, "goal_fest")
+	}
+	if m.HomeGoals == 0 && m.AwayGoals == 0 {
+		tags = append(tags, "stalemate")
+	}
+	return tags
+}
+
+// minuteArticle picks "a" or "an" for how the minute is read out (an 8', an 11')
+func minuteArticle(minute int) string {
+	if minute == 8 || minute == 11 || minute == 18 || (minute >= 80 && minute <= 89) {
+		return "an"
+	}
+	return "a"
+}
+
+// saveMatchEvents replaces the stored events and commentary of a match
+func saveMatchEvents(tx *sql.Tx, m Match, events []MatchEvent) error {
+	if _, err := tx.Exec("DELETE FROM match_events WHERE match_id = ?", m.ID); err != nil {
+		return err
+	}
+	for _, e := range events {
+		teamID := m.HomeTeamID
+		if e.Team == m.AwayTeam {
+			teamID = m.AwayTeamID
+		}
+		_, err := tx.Exec(
+			`INSERT INTO match_events (match_id, minute, team_id, type, detail, player_id) VALUES (?, ?, ?, ?, ?, NULLIF(?, 0))`,
+			m.ID, e.Minute, teamID, e.Type, e.Detail, e.playerID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec("UPDATE matches SET commentary = ? WHERE id = ?", GenerateCommentary(m, events), m.ID)
+	return err
+}
+
+func (l *League) MatchDetail(id int) (*MatchDetail, error) {
+	m, err := scanMatch(l.db.QueryRow(matchSelect+" WHERE m.id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	d := &MatchDetail{Match: m, Events: []MatchEvent{}}
+	err = l.db.QueryRow("SELECT COALESCE(commentary, '') FROM matches WHERE id = ?", id).Scan(&d.Commentary)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := l.db.Query(`
+		SELECT e.minute, t.name, e.type, e.detail, COALESCE(p.name, '')
+		FROM match_events e
+		JOIN teams t ON t.id = e.team_id
+		LEFT JOIN players p ON p.id = e.player_id
+		WHERE e.match_id = ?
+		ORDER BY e.minute, e.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e MatchEvent
+		if err := rows.Scan(&e.Minute, &e.Team, &e.Type, &e.Detail, &e.Player); err != nil {
+			return nil, err
+		}
+		d.Events = append(d.Events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	d.DramaTags = DramaTags(d.Match, d.Events)
+	d.Travel = l.config().Travel.trip(m.HomeTeam, m.AwayTeam)
+	if m.Administrative != "" {
+		if d.Decisions, err = l.Decisions(m.ID); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (l *League) handleMatchDetail(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	detail, err := l.MatchDetail(id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Match not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(detail)
+}
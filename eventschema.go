@@ -0,0 +1,117 @@
+package insider
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Every payload pushed to consumers carries its event_type and the
+// schema_version of its data. A version is only bumped when a field is
+// removed, renamed or changes meaning; new fields are added in place.
+
+// outbound event types
+const (
+	EventTypeAnnouncement  = "announcement"
+	EventTypeRecalculation = "recalculation"
+	EventTypeStoryline     = "storyline"
+)
+
+// EventSchema describes the data of one outbound event type
+type EventSchema struct {
+	EventType     string        `json:"event_type"`
+	SchemaVersion int           `json:"schema_version"`
+	Description   string        `json:"description"`
+	Fields        []SchemaField `json:"fields"`
+	data          interface{}
+}
+
+// SchemaField is one field of an event's data
+type SchemaField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// eventSchemas lists every event type that is pushed out
+var eventSchemas = []EventSchema{
+	{
+		EventType:     EventTypeAnnouncement,
+		SchemaVersion: 1,
+		Description:   "A team's fate is settled: kind is champion or relegated",
+		data:          Announcement{},
+	},
+	{
+		EventType:     EventTypeRecalculation,
+		SchemaVersion: 1,
+		Description:   "A result was rewritten after later weeks were played, or replaced an earlier one: rebuild the weeks_affected",
+		data:          Recalculation{},
+	},
+	{
+		EventType:     EventTypeStoryline,
+		SchemaVersion: 1,
+		Description:   "A storyline started (active) or ended: kind is title_race, relegation_six_pointer or unbeaten_run",
+		data:          Storyline{},
+	},
+}
+
+// schemaVersion is the current version of an event type, 0 if unknown
+func schemaVersion(eventType string) int {
+	for _, s := range eventSchemas {
+		if s.EventType == eventType {
+			return s.SchemaVersion
+		}
+	}
+	return 0
+}
+
+// schemaFields lists the JSON fields of a struct in declaration order
+func schemaFields(data interface{}) []SchemaField {
+	t := reflect.TypeOf(data)
+	fields := []SchemaField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, SchemaField{Name: name, Type: schemaType(f.Type)})
+	}
+	return fields
+}
+
+// schemaType names a Go type the way JSON consumers see it
+func schemaType(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Time{}) {
+		return "timestamp"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int64:
+		return "integer"
+	case reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		return "array of " + schemaType(t.Elem())
+	case reflect.Pointer:
+		return schemaType(t.Elem()) + " or null"
+	}
+	return "object"
+}
+
+// GET /events/schema
+func handleEventSchema(w http.ResponseWriter, r *http.Request) {
+	schemas := make([]EventSchema, 0, len(eventSchemas))
+	for _, s := range eventSchemas {
+		s.Fields = schemaFields(s.data)
+		schemas = append(schemas, s)
+	}
+	json.NewEncoder(w).Encode(schemas)
+}
@@ -0,0 +1,62 @@
+package insider
+
+import (
+	"crypto/ed25519"
+	"database/sql"
+	"time"
+)
+
+// Hooks for the black-box tests of package insider_test, which run the API
+// through leaguetest and reach in here only for what the API does not show.
+
+var (
+	SnapshotTeams           = snapshotTeams
+	PlayByStrength          = playByStrength
+	WebhookEvents           = webhookEvents
+	SeasonCount             = seasonCount
+	LoadSigningKey          = loadSigningKey
+	ResultsDigest           = resultsDigest
+	JoinDivision            = joinDivision
+	MoveTeam                = moveTeam
+	DBStats                 = dbStats
+	SportPresets            = sportPresets
+	DefaultPredictionPoints = defaultPredictionPoints
+)
+
+const (
+	AwardedGoals      = awardedGoals
+	MaxOutboxAttempts = maxOutboxAttempts
+	OutboxBackoff     = outboxBackoff
+	MaxOutboxBackoff  = maxOutboxBackoff
+	MaxTies           = maxTies
+)
+
+func (l *League) Config() *Config                      { return l.config() }
+func (l *League) StoreConfig(cfg *Config)              { l.cfg.Store(cfg) }
+func (l *League) DB() *sql.DB                          { return l.db }
+func (l *League) Version() int64                       { return l.version.Load() }
+func (l *League) TotalWeeks() int                      { return l.weeks }
+func (l *League) Frozen() *FreezeState                 { return l.frozen.Load() }
+func (l *League) SetClock(c Clock)                     { l.clock = c }
+func (l *League) SetSigningKey(k ed25519.PrivateKey)   { l.signingKey = k }
+func (l *League) SetPlanProblems(p []QueryPlanProblem) { l.planProblems = p }
+func (l *League) LoadTeams() error                     { return l.loadTeams() }
+func (l *League) CreateIndexes() error                 { return l.createIndexes() }
+func (l *League) AnnounceClinches() error              { return l.announceClinches() }
+
+func (l *League) NotifyWebhooks(eventType string, data any) { l.notifyWebhooks(eventType, data) }
+func (l *League) DeliverOutbox(now time.Time) (int, error)  { return l.deliverOutbox(now) }
+func (l *League) DivisionLeague(id int) (*League, error)    { return l.divisionLeague(id) }
+func (l *League) LoadPromotion() (*promotion, error)        { return l.loadPromotion() }
+func (l *League) SavePromotion(p *promotion) error          { return l.savePromotion(p) }
+func (l *League) PlanPromotion() (*promotion, error)        { return l.planPromotion() }
+
+func (s MatchScript) Apply(homeGoals, awayGoals int, overtime bool) (int, int) {
+	return s.apply(homeGoals, awayGoals, overtime)
+}
+
+func (p PredictionPoints) Score(guessHome, guessAway, home, away int) int {
+	return p.score(guessHome, guessAway, home, away)
+}
+
+func (t Travel) Trip(homeTeam, awayTeam string) *MatchTravel { return t.trip(homeTeam, awayTeam) }
@@ -0,0 +1,106 @@
+package insider
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// recentResults is how many played matches the feed shows
+const recentResults = 5
+
+// Feed is everything about a user's favorite team in one response
+type Feed struct {
+	Team          string        `json:"team"`
+	Position      int           `json:"position"`
+	Points        int           `json:"points"`
+	Played        int           `json:"played"`
+	RecentResults []TeamFixture `json:"recent_results"`
+	NextFixture   *TeamFixture  `json:"next_fixture,omitempty"`
+	// Monte Carlo estimates; relegation needs a "relegation" zone in the config
+	TitleProbability      float64  `json:"title_probability"`
+	RelegationProbability *float64 `json:"relegation_probability,omitempty"`
+}
+
+// TeamFeed gathers the table position, latest results, next fixture and
+// the title and relegation chances of one team
+func (l *League) TeamFeed(teamID int, runs int) (*Feed, error) {
+	standings, err := l.CalculateStandings()
+	if err != nil {
+		return nil, err
+	}
+	feed := &Feed{RecentResults: []TeamFixture{}}
+	for i, s := range standings {
+		if s.TeamID == teamID {
+			feed.Team = s.TeamName
+			feed.Position = i + 1
+			feed.Points = s.Points
+			feed.Played = s.Played
+		}
+	}
+
+	fixtures, err := l.TeamFixtures(teamID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fixtures {
+		f := fixtures[i]
+		if f.Played {
+			feed.RecentResults = append(feed.RecentResults, f)
+		} else if feed.NextFixture == nil && !f.Postponed {
+			feed.NextFixture = &f
+		}
+	}
+	// latest first
+	if len(feed.RecentResults) > recentResults {
+		feed.RecentResults = feed.RecentResults[len(feed.RecentResults)-recentResults:]
+	}
+	for i, j := 0, len(feed.RecentResults)-1; i < j; i, j = i+1, j-1 {
+		feed.RecentResults[i], feed.RecentResults[j] = feed.RecentResults[j], feed.RecentResults[i]
+	}
+
+	prediction, err := l.PredictMonteCarlo(runs)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range prediction.Teams {
+		if p.TeamName != feed.Team {
+			continue
+		}
+		feed.TitleProbability = p.TitleProbability
+		for _, z := range l.config().Zones {
+			if z.Name != "relegation" {
+				continue
+			}
+			prob := 0.0
+			for pos := z.From; pos <= z.To && pos <= len(p.PositionProbabilities); pos++ {
+				prob += p.PositionProbabilities[pos-1]
+			}
+			feed.RelegationProbability = &prob
+		}
+	}
+	return feed, nil
+}
+
+// GET /me/feed for the signed in user's favorite team, ?runs= as in /predict
+func (l *League) handleFeed(w http.ResponseWriter, r *http.Request) {
+	user := l.requireUser(w, r)
+	if user == nil {
+		return
+	}
+	if !user.favoriteID.Valid {
+		http.Error(w, "No favorite team yet, set one with POST /me/favorite", http.StatusConflict)
+		return
+	}
+	runs, err := runsParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	feed, err := l.TeamFeed(int(user.favoriteID.Int64), runs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(feed)
+}
@@ -0,0 +1,69 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestFeed(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 5)
+	cfg := *h.League.Config()
+	cfg.Zones = []insider.Zone{{Name: "relegation", From: 4, To: 4}}
+	h.League.StoreConfig(&cfg)
+
+	var signup struct {
+		Token string `json:"token"`
+	}
+	if status := h.Do(http.MethodPost, "/users", map[string]string{"name": "ipek"}, false, &signup); status != http.StatusCreated {
+		t.Fatalf("POST /users: status %d", status)
+	}
+	if status := h.DoWithToken(http.MethodGet, "/me/feed", nil, "", nil); status != http.StatusUnauthorized {
+		t.Errorf("feed without a token: status %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := h.DoWithToken(http.MethodGet, "/me/feed", nil, signup.Token, nil); status != http.StatusConflict {
+		t.Errorf("feed without a favorite: status %d, want %d", status, http.StatusConflict)
+	}
+	if status := h.DoWithToken(http.MethodPost, "/me/favorite", map[string]string{"team": "Delta SC"}, signup.Token, nil); status != http.StatusOK {
+		t.Fatalf("POST /me/favorite: status %d", status)
+	}
+
+	// the weakest side loses its first two matches
+	insider.PlayByStrength(t, h.League, 1, 2)
+
+	var feed insider.Feed
+	if status := h.DoWithToken(http.MethodGet, "/me/feed?runs=200", nil, signup.Token, &feed); status != http.StatusOK {
+		t.Fatalf("GET /me/feed: status %d", status)
+	}
+	if feed.Team != "Delta SC" || feed.Position != 4 || feed.Points != 0 || feed.Played != 2 {
+		t.Errorf("feed for %s: position %d, %d points from %d played, want Delta SC 4th with 0 from 2",
+			feed.Team, feed.Position, feed.Points, feed.Played)
+	}
+	if len(feed.RecentResults) != 2 {
+		t.Fatalf("%d recent results, want 2", len(feed.RecentResults))
+	}
+	if feed.RecentResults[0].Week != 2 || feed.RecentResults[1].Week != 1 {
+		t.Errorf("recent results from weeks %d and %d, want the latest first",
+			feed.RecentResults[0].Week, feed.RecentResults[1].Week)
+	}
+	for _, f := range feed.RecentResults {
+		if f.Result != "L" {
+			t.Errorf("week %d result %q, want L", f.Week, f.Result)
+		}
+	}
+	if feed.NextFixture == nil || feed.NextFixture.Week != 3 || feed.NextFixture.Played {
+		t.Errorf("next fixture %+v, want the unplayed week 3 match", feed.NextFixture)
+	}
+	if feed.TitleProbability < 0 || feed.TitleProbability > 1 {
+		t.Errorf("title probability %v", feed.TitleProbability)
+	}
+	if feed.RelegationProbability == nil || *feed.RelegationProbability <= feed.TitleProbability {
+		t.Errorf("relegation probability %v next to title probability %v", feed.RelegationProbability, feed.TitleProbability)
+	}
+
+	if status := h.DoWithToken(http.MethodGet, "/me/feed?runs=nope", nil, signup.Token, nil); status != http.StatusBadRequest {
+		t.Errorf("feed with bad runs: status %d, want %d", status, http.StatusBadRequest)
+	}
+}
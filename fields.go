@@ -0,0 +1,118 @@
+package insider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Sparse fieldsets: ?fields=team_name,points on a list keeps only those
+// fields of every item, in the order they were asked for. Fields left out of
+// an item because they are empty stay left out.
+
+// ErrUnknownField is returned for a ?fields= name the items do not have
+var ErrUnknownField = errors.New("unknown field")
+
+// fieldSelection is the fields asked for, nil for all of them
+type fieldSelection []string
+
+// parseFields reads ?fields= for items of type T
+func parseFields[T any](r *http.Request) (fieldSelection, error) {
+	param := r.URL.Query().Get("fields")
+	if param == "" {
+		return nil, nil
+	}
+	known := make(map[string]bool)
+	jsonFieldNames(reflect.TypeOf((*T)(nil)).Elem(), known)
+
+	fields := fieldSelection{}
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || slices.Contains(fields, name) {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("%w %q", ErrUnknownField, name)
+		}
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// jsonFieldNames collects the JSON names of a struct, embedded ones included
+func jsonFieldNames(t reflect.Type, names map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			jsonFieldNames(f.Type, names)
+			continue
+		}
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[name] = true
+	}
+}
+
+// encode marshals one item with only the selected fields
+func (fields fieldSelection) encode(item any) ([]byte, error) {
+	data, err := json.Marshal(item)
+	if err != nil || fields == nil {
+		return data, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for _, name := range fields {
+		raw, ok := all[name]
+		if !ok {
+			continue
+		}
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(raw)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// writeFields encodes a list honouring ?fields=, an unknown field is a 400
+func writeFields[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	fields, err := parseFields[T](r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if fields == nil {
+		json.NewEncoder(w).Encode(items)
+		return
+	}
+
+	out := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		data, err := fields.encode(item)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out = append(out, data)
+	}
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,53 @@
+package insider_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestFieldsOverHTTP(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 5)
+	h.SimulateWeek(1)
+
+	var rows []json.RawMessage
+	h.Get("/standings?fields=points,team_name", &rows)
+	if len(rows) != len(insider.SnapshotTeams) {
+		t.Fatalf("%d standings rows", len(rows))
+	}
+	for _, row := range rows {
+		if !strings.HasPrefix(string(row), `{"points":`) || !strings.Contains(string(row), `,"team_name":`) || strings.Count(string(row), ":") != 2 {
+			t.Errorf("standings row %s", row)
+		}
+	}
+
+	// /matches streams its array, with and without a week
+	for _, path := range []string{"/matches?fields=away_team,home_team", "/matches?week=2&fields=away_team,home_team"} {
+		rows = nil
+		h.Get(path, &rows)
+		if len(rows) == 0 {
+			t.Fatalf("GET %s: no matches", path)
+		}
+		for _, row := range rows {
+			var m map[string]string
+			if err := json.Unmarshal(row, &m); err != nil || len(m) != 2 || !strings.HasPrefix(string(row), `{"away_team":`) {
+				t.Errorf("GET %s: row %s", path, row)
+			}
+		}
+	}
+	rows = nil
+	h.Get("/matches?week=99&fields=id", &rows)
+	if len(rows) != 0 {
+		t.Errorf("fields of a week without matches: %s", rows)
+	}
+
+	for _, path := range []string{"/standings?fields=points,goals", "/matches?fields=score", "/matches?week=1&fields=id,score"} {
+		if status := h.Do(http.MethodGet, path, nil, false, nil); status != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want 400", path, status)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package insider
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		query string
+		want  fieldSelection
+		err   bool
+	}{
+		{"", nil, false},
+		{"?fields=points,team_name", fieldSelection{"points", "team_name"}, false},
+		{"?fields=+points+,,points,rank", fieldSelection{"points", "rank"}, false},
+		{"?fields=points,goals", nil, true},
+		{"?fields=TeamName", nil, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/standings"+tt.query, nil)
+		got, err := parseFields[Standing](r)
+		if tt.err {
+			if !errors.Is(err, ErrUnknownField) {
+				t.Errorf("%q: error %v, want ErrUnknownField", tt.query, err)
+			}
+			continue
+		}
+		if err != nil || strings.Join(got, ",") != strings.Join(tt.want, ",") || (got == nil) != (tt.want == nil) {
+			t.Errorf("%q: got %q, %v; want %q", tt.query, got, err, tt.want)
+		}
+	}
+}
+
+func TestFieldsEncode(t *testing.T) {
+	m := Match{ID: 7, HomeTeam: "Alpha FC", AwayTeam: "Bravo United", Week: 2}
+	data, err := fieldSelection{"week", "kickoff", "away_team", "id"}.encode(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the order asked for, and the empty kickoff stays left out
+	if want := `{"week":2,"away_team":"Bravo United","id":7}`; string(data) != want {
+		t.Errorf("encoded %s, want %s", data, want)
+	}
+}
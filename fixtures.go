@@ -0,0 +1,151 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+)
+
+// Probabilities of each result from the point of view of one side
+type Probabilities struct {
+	Win  float64 `json:"win"`
+	Draw float64 `json:"draw"`
+	Loss float64 `json:"loss"`
+}
+
+// TeamFixture is one match seen from a single team's side
+type TeamFixture struct {
+	MatchID        int            `json:"match_id"`
+	Week           int            `json:"week"`
+	Venue          string         `json:"venue"`
+	Opponent       string         `json:"opponent"`
+	Played         bool           `json:"played"`
+	Postponed      bool           `json:"postponed,omitempty"`
+	Kickoff        string         `json:"kickoff,omitempty"`
+	GoalsFor       *int           `json:"goals_for,omitempty"`
+	GoalsAgainst   *int           `json:"goals_against,omitempty"`
+	Result         string         `json:"result,omitempty"`
+	Administrative string         `json:"administrative,omitempty"`
+	Probabilities  *Probabilities `json:"probabilities,omitempty"`
+	// Difficulty rates the fixture from 1, easiest, to 5
+	Difficulty int `json:"difficulty"`
+}
+
+// difficulty rates a fixture 1 to 5 by the opponent's share of the expected
+// result, a draw counting half to each side. It follows from the opponent's
+// strength and the venue, on the same model the simulation uses.
+func difficulty(p Probabilities) int {
+	share := p.Loss + p.Draw/2
+	return min(5, 1+int(share*5))
+}
+
+// teamStrengths loads every team's strength keyed by name
+func (l *League) teamStrengths() (map[string]int, error) {
+	rows, err := l.db.Query("SELECT name, strength FROM teams")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	strengths := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var strength int
+		if err := rows.Scan(&name, &strength); err != nil {
+			return nil, err
+		}
+		strengths[name] = strength
+	}
+	return strengths, rows.Err()
+}
+
+// TeamFixtures returns every match of a team in chronological order.
+// Played matches carry the result, upcoming ones the predicted probabilities;
+// all of them are rated for difficulty with today's strengths.
+func (l *League) TeamFixtures(teamID int) ([]TeamFixture, error) {
+	strengths, err := l.teamStrengths()
+	if err != nil {
+		return nil, err
+	}
+	params := l.config().Simulation
+	home, err := l.homeAdvantages()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := l.db.Query(matchSelect+`
+		WHERE m.home_team_id = ? OR m.away_team_id = ?
+		ORDER BY m.week, m.id`, teamID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fixtures := []TeamFixture{}
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		f := TeamFixture{MatchID: m.ID, Week: m.Week, Played: m.Played, Postponed: m.Postponed, Kickoff: m.Kickoff, Administrative: m.Administrative}
+		goalsFor, goalsAgainst := m.HomeGoals, m.AwayGoals
+		if m.HomeTeamID == teamID {
+			f.Venue = "home"
+			f.Opponent = m.AwayTeam
+		} else {
+			f.Venue = "away"
+			f.Opponent = m.HomeTeam
+			goalsFor, goalsAgainst = goalsAgainst, goalsFor
+		}
+
+		if m.Played && m.Administrative != AdminAnnulled {
+			f.GoalsFor = &goalsFor
+			f.GoalsAgainst = &goalsAgainst
+			switch {
+			case goalsFor > goalsAgainst:
+				f.Result = "W"
+			case goalsFor < goalsAgainst:
+				f.Result = "L"
+			default:
+				f.Result = "D"
+			}
+		}
+
+		homeWin, draw, awayWin := home.params(params, m.HomeTeam).Probabilities(strengths[m.HomeTeam], strengths[m.AwayTeam])
+		p := Probabilities{Win: homeWin, Draw: draw, Loss: awayWin}
+		if f.Venue == "away" {
+			p.Win, p.Loss = awayWin, homeWin
+		}
+		f.Difficulty = difficulty(p)
+		if !m.Played {
+			f.Probabilities = &p
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, rows.Err()
+}
+
+func (l *League) handleTeamFixtures(w http.ResponseWriter, r *http.Request) {
+	// former names still lead to the team
+	teamID, team, err := l.resolveTeam(r.PathValue("name"))
+	if err == sql.ErrNoRows {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	fixtures, err := l.TeamFixtures(teamID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"team":     team,
+		"fixtures": fixtures,
+	})
+}
@@ -0,0 +1,167 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// A league mirroring a published calendar can upload its whole schedule
+// instead of the drawn one. The document names every match by its teams,
+// week and optional date; it has to be a complete double round robin over
+// the league's weeks, checked with the same rules as a drawn fixture, and
+// replaces the current matches the way /fixture/generate does. GET /fixture
+// gives the schedule in the same shape, so it can be edited and sent back.
+
+// ScheduleEntry is one match of a schedule document
+type ScheduleEntry struct {
+	HomeTeam string `json:"home_team"`
+	AwayTeam string `json:"away_team"`
+	Week     int    `json:"week"`
+	// Date is YYYY-MM-DD or RFC 3339, left out for no kickoff
+	Date string `json:"date,omitempty"`
+}
+
+// ScheduleDocument is a complete fixture
+type ScheduleDocument struct {
+	Matches []ScheduleEntry `json:"matches"`
+}
+
+// Schedule is the current fixture as a schedule document, in week order
+func (l *League) Schedule() (*ScheduleDocument, error) {
+	matches, err := l.Matches()
+	if err != nil {
+		return nil, err
+	}
+	doc := &ScheduleDocument{Matches: make([]ScheduleEntry, 0, len(matches))}
+	for _, m := range matches {
+		doc.Matches = append(doc.Matches, ScheduleEntry{HomeTeam: m.HomeTeam, AwayTeam: m.AwayTeam, Week: m.Week, Date: m.Kickoff})
+	}
+	sort.SliceStable(doc.Matches, func(i, j int) bool { return doc.Matches[i].Week < doc.Matches[j].Week })
+	return doc, nil
+}
+
+// UploadSchedule replaces the fixture with doc once it checks out. Every
+// problem is reported, joined, under ErrInvalidSchedule. After play has
+// started it refuses with ErrSeasonStarted unless force is set.
+func (l *League) UploadSchedule(doc ScheduleDocument, force bool) error {
+	teams := l.Teams()
+	teamIDs := make([]int, len(teams))
+	for i, t := range teams {
+		teamIDs[i] = t.ID
+	}
+
+	var problems []error
+	matches := make([]Match, 0, len(doc.Matches))
+	kickoffs := make([]string, 0, len(doc.Matches))
+	for i, e := range doc.Matches {
+		m := Match{Week: e.Week}
+		for _, side := range []struct {
+			name string
+			id   *int
+		}{{e.HomeTeam, &m.HomeTeamID}, {e.AwayTeam, &m.AwayTeamID}} {
+			id, _, err := l.resolveTeam(side.name)
+			if err == sql.ErrNoRows {
+				problems = append(problems, fmt.Errorf("match %d: unknown team %q", i+1, side.name))
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			*side.id = id
+		}
+		if e.Week < 1 || e.Week > l.weeks {
+			problems = append(problems, fmt.Errorf("match %d: week %d, must be between 1 and %d", i+1, e.Week, l.weeks))
+		}
+		kickoff := ""
+		if e.Date != "" {
+			parsed, err := parseKickoff(e.Date)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("match %d: %v", i+1, err))
+			}
+			kickoff = parsed.Format(time.RFC3339)
+		}
+		matches = append(matches, m)
+		kickoffs = append(kickoffs, kickoff)
+	}
+	if len(problems) == 0 {
+		if err := ValidateFixture(matches, teamIDs, meetingsPerPair); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidSchedule, errors.Join(problems...))
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := currentSeason(tx)
+	if err != nil {
+		return err
+	}
+	if current.Status == SeasonPlanned {
+		return fmt.Errorf("%w: start season %d first", ErrSeasonPlanned, current.ID)
+	}
+	if err := clearFixture(tx, force, "schedule uploaded"); err != nil {
+		return err
+	}
+	for i, m := range matches {
+		if _, err := tx.Exec("INSERT INTO matches (home_team_id, away_team_id, week, kickoff) VALUES (?, ?, ?, NULLIF(?, ''))",
+			m.HomeTeamID, m.AwayTeamID, m.Week, kickoffs[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.touch()
+	return nil
+}
+
+// GET /fixture is the schedule as a document; PUT replaces it with one,
+// ?force=true once matches are played (admin only):
+// {"matches": [{"home_team": "Alpha FC", "away_team": "Delta SC", "week": 1, "date": "2026-08-15"}, ...]}
+func (l *League) handleFixture(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		doc, err := l.Schedule()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(doc)
+	case http.MethodPut:
+		if !requireAdmin(w, r) {
+			return
+		}
+		var doc ScheduleDocument
+		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err := l.UploadSchedule(doc, r.URL.Query().Get("force") == "true")
+		switch {
+		case errors.Is(err, ErrInvalidSchedule):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, ErrSeasonStarted), errors.Is(err, ErrSeasonPlanned):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"message": "Schedule uploaded", "matches": len(doc.Matches)})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
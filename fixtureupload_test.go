@@ -0,0 +1,67 @@
+package insider_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestUploadSchedule(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 4)
+	var doc insider.ScheduleDocument
+	h.Get("/fixture", &doc)
+	if len(doc.Matches) != 12 || doc.Matches[0].Week != 1 {
+		t.Fatalf("schedule %+v", doc.Matches)
+	}
+
+	// the published calendar plays the weeks in reverse, each on a date
+	weeks := h.League.TotalWeeks()
+	for i := range doc.Matches {
+		doc.Matches[i].Week = weeks + 1 - doc.Matches[i].Week
+		doc.Matches[i].Date = "2026-08-" + []string{"01", "08", "15", "22", "29", "30"}[doc.Matches[i].Week-1]
+	}
+	if status := h.Do(http.MethodPut, "/fixture", doc, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("upload without a token: status %d, want 401", status)
+	}
+	if status := h.Do(http.MethodPut, "/fixture", doc, true, nil); status != http.StatusOK {
+		t.Fatalf("PUT /fixture: status %d", status)
+	}
+	for _, m := range h.Matches() {
+		want := "2026-08-" + []string{"01", "08", "15", "22", "29", "30"}[m.Week-1]
+		if !strings.HasPrefix(m.Kickoff, want) {
+			t.Errorf("match %d in week %d kicks off %q, want %s", m.ID, m.Week, m.Kickoff, want)
+		}
+	}
+
+	clash := insider.ScheduleDocument{Matches: append([]insider.ScheduleEntry(nil), doc.Matches...)}
+	clash.Matches[2].Week = clash.Matches[0].Week
+	if status := h.Do(http.MethodPut, "/fixture", clash, true, nil); status != http.StatusBadRequest {
+		t.Errorf("two matches of a team in one week: status %d, want 400", status)
+	}
+	short := insider.ScheduleDocument{Matches: doc.Matches[:11]}
+	if status := h.Do(http.MethodPut, "/fixture", short, true, nil); status != http.StatusBadRequest {
+		t.Errorf("a pairing missing: status %d, want 400", status)
+	}
+	unknown := insider.ScheduleDocument{Matches: append([]insider.ScheduleEntry(nil), doc.Matches...)}
+	unknown.Matches[0].HomeTeam = "Nobody"
+	err := h.League.UploadSchedule(unknown, false)
+	if err == nil || !strings.Contains(err.Error(), `unknown team "Nobody"`) {
+		t.Errorf("unknown team: %v", err)
+	}
+
+	h.SimulateWeek(1)
+	if status := h.Do(http.MethodPut, "/fixture", doc, true, nil); status != http.StatusConflict {
+		t.Errorf("upload after a result: status %d, want 409", status)
+	}
+	if status := h.Do(http.MethodPut, "/fixture?force=true", doc, true, nil); status != http.StatusOK {
+		t.Errorf("forced upload: status %d", status)
+	}
+	for _, m := range h.Matches() {
+		if m.Played {
+			t.Fatalf("match %d kept its result through the upload", m.ID)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// An admin can freeze the league while a dispute is looked into or during
+// maintenance. A frozen league keeps serving reads, and the POST endpoints
+// that only compute something, but refuses every other change with a 423
+// naming the reason; the virtual clock stops playing matches too. The freeze
+// is kept in the settings table, so it outlasts a restart, and each league
+// of the server freezes on its own.
+
+// freezeKey is the settings row holding the FreezeState
+const freezeKey = "freeze"
+
+// ErrFrozen is returned for a change to a frozen league
+var ErrFrozen = errors.New("league is frozen")
+
+// FreezeState is why and since when the league is frozen
+type FreezeState struct {
+	Reason   string    `json:"reason"`
+	FrozenAt time.Time `json:"frozen_at"`
+}
+
+// frozenError is ErrFrozen with the reason
+func frozenError(f *FreezeState) error {
+	if f.Reason == "" {
+		return ErrFrozen
+	}
+	return fmt.Errorf("%w: %s", ErrFrozen, f.Reason)
+}
+
+// loadFreeze picks up a freeze from before the restart
+func (l *League) loadFreeze() error {
+	var value string
+	err := l.db.QueryRow("SELECT value FROM settings WHERE key = ?", freezeKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f FreezeState
+	if err := json.Unmarshal([]byte(value), &f); err != nil {
+		return fmt.Errorf("invalid %s setting: %v", freezeKey, err)
+	}
+	l.frozen.Store(&f)
+	return nil
+}
+
+// Freeze stops every change to the league until Unfreeze. Freezing a frozen
+// league only updates the reason.
+func (l *League) Freeze(reason string) (*FreezeState, error) {
+	f := &FreezeState{Reason: reason, FrozenAt: l.clock.Now().UTC()}
+	if current := l.frozen.Load(); current != nil {
+		f.FrozenAt = current.FrozenAt
+	}
+	value, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	_, err = l.db.Exec(`
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, freezeKey, string(value))
+	if err != nil {
+		return nil, err
+	}
+	l.frozen.Store(f)
+	return f, nil
+}
+
+// Unfreeze lets changes through again
+func (l *League) Unfreeze() error {
+	if _, err := l.db.Exec("DELETE FROM settings WHERE key = ?", freezeKey); err != nil {
+		return err
+	}
+	l.frozen.Store(nil)
+	return nil
+}
+
+// freezeGuard refuses the changes to a frozen league. /admin/freeze stays
+// open to lift the freeze, and the other leagues under /leagues/{id} have
+// guards of their own.
+func (l *League) freezeGuard(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f := l.frozen.Load()
+		if f == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || readOnlyExempt[r.URL.Path] {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		if _, pattern := mux.Handler(r); pattern == "/admin/freeze" || pattern == "/leagues/{id}/" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, frozenError(f).Error(), http.StatusLocked)
+	})
+}
+
+// LeagueStatus is the state of the league at a glance
+type LeagueStatus struct {
+	Frozen bool         `json:"frozen"`
+	Freeze *FreezeState `json:"freeze,omitempty"`
+	Live   bool         `json:"live"`
+	Season Season       `json:"season"`
+	// Played and Matches count the matches of the current season
+	Played  int `json:"played"`
+	Matches int `json:"matches"`
+}
+
+// Status reports whether the league is frozen and how far the season is
+func (l *League) Status() (*LeagueStatus, error) {
+	season, err := currentSeason(l.db)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := l.Matches()
+	if err != nil {
+		return nil, err
+	}
+	status := &LeagueStatus{Live: l.config().Live, Season: season, Matches: len(matches)}
+	for _, m := range matches {
+		if m.Played {
+			status.Played++
+		}
+	}
+	if f := l.frozen.Load(); f != nil {
+		status.Frozen, status.Freeze = true, f
+	}
+	return status, nil
+}
+
+// GET /status
+func (l *League) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	status, err := l.Status()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+// GET /admin/freeze shows the freeze, POST freezes the league with
+// {"reason": "result under review"} and DELETE lifts it (admin only)
+func (l *League) handleFreeze(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]any{"frozen": l.frozen.Load() != nil, "freeze": l.frozen.Load()})
+	case http.MethodPost:
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		// the reason is optional, and so is the body
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		f, err := l.Freeze(req.Reason)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(f)
+	case http.MethodDelete:
+		if err := l.Unfreeze(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"message": "League unfrozen"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
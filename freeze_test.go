@@ -0,0 +1,65 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestFreezeBlocksChanges(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 3)
+	if status := h.Do(http.MethodPost, "/admin/freeze", nil, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("freeze without a token: status %d, want 401", status)
+	}
+	var state insider.FreezeState
+	if status := h.Do(http.MethodPost, "/admin/freeze", map[string]string{"reason": "result under review"}, true, &state); status != http.StatusOK {
+		t.Fatalf("POST /admin/freeze: status %d", status)
+	}
+	if state.Reason != "result under review" || state.FrozenAt.IsZero() {
+		t.Errorf("freeze %+v", state)
+	}
+
+	if status := h.Do(http.MethodPost, "/simulate/week/1", nil, false, nil); status != http.StatusLocked {
+		t.Errorf("simulate while frozen: status %d, want 423", status)
+	}
+	if status := h.Do(http.MethodPost, "/fixture/generate", nil, true, nil); status != http.StatusLocked {
+		t.Errorf("new fixture while frozen: status %d, want 423", status)
+	}
+	if err := h.League.SimulateWeek(1); err == nil {
+		t.Error("SimulateWeek played a frozen league")
+	}
+	if len(h.Standings()) != 4 {
+		t.Error("standings unavailable while frozen")
+	}
+	for _, m := range h.Matches() {
+		if m.Played {
+			t.Fatalf("match %d played while frozen", m.ID)
+		}
+	}
+
+	var status insider.LeagueStatus
+	h.Get("/status", &status)
+	if !status.Frozen || status.Freeze == nil || status.Freeze.Reason != "result under review" || status.Matches != 12 {
+		t.Errorf("status %+v", status)
+	}
+
+	// the freeze outlasts a restart
+	reopened := insider.NewLeague(h.League.DB(), insider.SnapshotTeams, insider.FixtureWeeks(len(insider.SnapshotTeams)), nil, nil)
+	if err := reopened.InitDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	if f := reopened.Frozen(); f == nil || f.Reason != "result under review" {
+		t.Errorf("freeze after a restart %+v", f)
+	}
+
+	if status := h.Do(http.MethodDelete, "/admin/freeze", nil, true, nil); status != http.StatusOK {
+		t.Fatalf("DELETE /admin/freeze: status %d", status)
+	}
+	h.SimulateWeek(1)
+	h.Get("/status", &status)
+	if status.Frozen || status.Played != 2 {
+		t.Errorf("status after the freeze %+v", status)
+	}
+}
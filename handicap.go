@@ -0,0 +1,189 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+var (
+	// ErrHandicapsLocked is returned when handicaps are changed mid-season
+	ErrHandicapsLocked = errors.New("handicaps can only be set before the first match is played")
+	// ErrInvalidHandicap is returned for an unknown team or a handicap out
+	// of range
+	ErrInvalidHandicap = errors.New("invalid handicap")
+)
+
+// Handicap is the head start (or deduction, when negative) a team gets in
+// the handicap table
+type Handicap struct {
+	TeamID   int    `json:"team_id"`
+	TeamName string `json:"team_name"`
+	Points   int    `json:"points"`
+}
+
+// HandicapStanding is a standings row with the handicap added. The real table
+// is untouched, Points stays what the team has earned.
+type HandicapStanding struct {
+	Standing
+	Handicap    int `json:"handicap"`
+	TotalPoints int `json:"total_points"`
+}
+
+func (l *League) createHandicapTable() error {
+	createHandicaps := `
+	CREATE TABLE IF NOT EXISTS handicaps (
+		team_id INTEGER PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+		points INTEGER NOT NULL
+	);`
+
+	if _, err := l.db.Exec(createHandicaps); err != nil {
+		return fmt.Errorf("error creating handicaps table: %v", err)
+	}
+	return nil
+}
+
+// Handicaps lists the teams that have one, by team id
+func (l *League) Handicaps() ([]Handicap, error) {
+	rows, err := l.db.Query(`
+		SELECT h.team_id, t.name, h.points FROM handicaps h
+		JOIN teams t ON t.id = h.team_id
+		ORDER BY h.team_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	handicaps := []Handicap{}
+	for rows.Next() {
+		var h Handicap
+		if err := rows.Scan(&h.TeamID, &h.TeamName, &h.Points); err != nil {
+			return nil, err
+		}
+		handicaps = append(handicaps, h)
+	}
+	return handicaps, rows.Err()
+}
+
+// SetHandicaps replaces all handicaps, keyed by team name (former names work
+// too). Teams left out have none. Once a match is played they are fixed.
+func (l *League) SetHandicaps(points map[string]int) error {
+	byTeam := make(map[int]int)
+	for name, p := range points {
+		if p < -100 || p > 100 {
+			return fmt.Errorf("%w: handicap for %s must be between -100 and 100", ErrInvalidHandicap, name)
+		}
+		id, _, err := l.resolveTeam(name)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: unknown team %s", ErrInvalidHandicap, name)
+		}
+		if err != nil {
+			return err
+		}
+		byTeam[id] = p
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var started int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM matches WHERE played = TRUE OR live = TRUE").Scan(&started); err != nil {
+		return err
+	}
+	if started > 0 {
+		return ErrHandicapsLocked
+	}
+
+	if _, err := tx.Exec("DELETE FROM handicaps"); err != nil {
+		return err
+	}
+	for id, p := range byTeam {
+		if p == 0 {
+			continue
+		}
+		if _, err := tx.Exec("INSERT INTO handicaps (team_id, points) VALUES (?, ?)", id, p); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// HandicapStandings is the table ordered by points plus handicap. Ties keep
+// the real table's order; ranks, zones and places follow the new one.
+func (l *League) HandicapStandings() ([]HandicapStanding, error) {
+	standings, err := l.CalculateStandings()
+	if err != nil {
+		return nil, err
+	}
+	handicaps, err := l.Handicaps()
+	if err != nil {
+		return nil, err
+	}
+	byTeam := make(map[int]int)
+	for _, h := range handicaps {
+		byTeam[h.TeamID] = h.Points
+	}
+
+	table := make([]HandicapStanding, 0, len(standings))
+	for _, s := range standings {
+		table = append(table, HandicapStanding{
+			Standing:    s,
+			Handicap:    byTeam[s.TeamID],
+			TotalPoints: s.Points + byTeam[s.TeamID],
+		})
+	}
+	sort.SliceStable(table, func(i, j int) bool {
+		return table[i].TotalPoints > table[j].TotalPoints
+	})
+	for i := range table {
+		table[i].Rank = i + 1
+	}
+	l.placeRows(len(table), func(i int) *Standing { return &table[i].Standing })
+	return table, nil
+}
+
+// GET /handicaps lists them, POST /handicaps with {"Beta FC": 6, "Delta FC": 3}
+// sets them before the season starts. Setting is admin only.
+func (l *League) handleHandicaps(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !isAdmin(r) {
+			http.Error(w, "Admin token required", http.StatusUnauthorized)
+			return
+		}
+		var body map[string]int
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err := l.SetHandicaps(body)
+		switch {
+		case errors.Is(err, ErrHandicapsLocked):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case errors.Is(err, ErrInvalidHandicap):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	handicaps, err := l.Handicaps()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(handicaps)
+}
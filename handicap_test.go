@@ -0,0 +1,40 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestSetHandicaps(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 6)
+	if status := h.Do(http.MethodPost, "/handicaps", map[string]int{"Delta SC": 6}, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("handicaps without the admin token: status %d, want 401", status)
+	}
+	for _, body := range []map[string]int{{"Delta SC": 101}, {"Nobody FC": 3}} {
+		if status := h.Do(http.MethodPost, "/handicaps", body, true, nil); status != http.StatusBadRequest {
+			t.Errorf("handicaps %v: status %d, want 400", body, status)
+		}
+	}
+
+	var handicaps []insider.Handicap
+	h.Post("/handicaps", map[string]int{"Delta SC": 6, "Alpha FC": -3}, &handicaps)
+	if len(handicaps) != 2 {
+		t.Errorf("handicaps %+v, want two", handicaps)
+	}
+
+	// a failing database is the server's fault, not the request's
+	if _, err := h.League.DB().Exec("DROP TABLE handicaps"); err != nil {
+		t.Fatal(err)
+	}
+	if status := h.Do(http.MethodPost, "/handicaps", map[string]int{"Delta SC": 3}, true, nil); status != http.StatusInternalServerError {
+		t.Errorf("handicaps without their table: status %d, want 500", status)
+	}
+
+	h.SimulateWeek(1)
+	if status := h.Do(http.MethodPost, "/handicaps", map[string]int{"Delta SC": 3}, true, nil); status != http.StatusConflict {
+		t.Errorf("handicaps after a match: status %d, want 409", status)
+	}
+}
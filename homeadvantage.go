@@ -0,0 +1,129 @@
+package insider
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+)
+
+// simulation.home_advantage is the same for every ground. The
+// home_advantages block sets it per team, and with learn it is estimated from
+// the home results so far: every home game shows how much better the team
+// did than the strengths alone say. Few games say little, so a learned value
+// starts at the league-wide one and moves away as results come in. A value
+// set for a team wins over a learned one.
+
+// learnPrior is how many home games count as much as the league-wide value
+const learnPrior = 10
+
+// HomeAdvantages is the "home_advantages" block of the config. Teams are
+// keyed by name.
+type HomeAdvantages struct {
+	Teams map[string]int `json:"teams"`
+	Learn bool           `json:"learn"`
+}
+
+// Home advantage sources
+const (
+	HomeAdvantageDefault = "default"
+	HomeAdvantageConfig  = "config"
+	HomeAdvantageLearned = "learned"
+)
+
+// TeamHomeAdvantage is the home advantage in effect for one team
+type TeamHomeAdvantage struct {
+	TeamName      string `json:"team_name"`
+	HomeAdvantage int    `json:"home_advantage"`
+	Source        string `json:"source"`
+	HomeGames     int    `json:"home_games"`
+}
+
+func (h HomeAdvantages) Validate() error {
+	for team, a := range h.Teams {
+		if a < -100 || a > 100 {
+			return fmt.Errorf("%s: home advantage must be between -100 and 100", team)
+		}
+	}
+	return nil
+}
+
+// homeAdvantages maps the teams that do not use the league-wide value to
+// their own
+type homeAdvantages map[string]int
+
+// params is p with homeTeam's advantage
+func (h homeAdvantages) params(p SimParams, homeTeam string) SimParams {
+	if a, ok := h[homeTeam]; ok {
+		p.HomeAdvantage = a
+	}
+	return p
+}
+
+// resolve works out the home advantage of teams from the played matches
+func (h HomeAdvantages) resolve(p SimParams, teams []string, strengths map[string]int, played []Match) []TeamHomeAdvantage {
+	// residual goal difference per home game: what the result says beyond
+	// the strengths, a side's range growing by one goal per StrengthPerGoal
+	residual := make(map[string]float64)
+	games := make(map[string]int)
+	for _, m := range played {
+		expected := float64(strengths[m.HomeTeam]-strengths[m.AwayTeam]) / float64(2*p.StrengthPerGoal)
+		residual[m.HomeTeam] += float64(m.HomeGoals-m.AwayGoals) - expected
+		games[m.HomeTeam]++
+	}
+
+	resolved := make([]TeamHomeAdvantage, 0, len(teams))
+	for _, name := range teams {
+		t := TeamHomeAdvantage{TeamName: name, HomeAdvantage: p.HomeAdvantage, Source: HomeAdvantageDefault, HomeGames: games[name]}
+		if a, ok := h.Teams[name]; ok {
+			t.HomeAdvantage, t.Source = a, HomeAdvantageConfig
+		} else if h.Learn && games[name] > 0 {
+			n := float64(games[name])
+			estimate := 2 * float64(p.StrengthPerGoal) * residual[name] / n
+			weight := n / (n + learnPrior)
+			learned := float64(p.HomeAdvantage) + weight*(estimate-float64(p.HomeAdvantage))
+			t.HomeAdvantage = int(math.Round(math.Max(-100, math.Min(100, learned))))
+			t.Source = HomeAdvantageLearned
+		}
+		resolved = append(resolved, t)
+	}
+	return resolved
+}
+
+// homeAdvantages is the home advantage of every team not on the league-wide
+// value, for the current config and results
+func (l *League) homeAdvantages() (homeAdvantages, error) {
+	cfg := l.config()
+	if !cfg.HomeAdvantages.Learn {
+		return homeAdvantages(cfg.HomeAdvantages.Teams), nil
+	}
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return nil, err
+	}
+	return state.home, nil
+}
+
+// newHomeAdvantages keeps the teams off the league-wide value
+func newHomeAdvantages(resolved []TeamHomeAdvantage) homeAdvantages {
+	h := make(homeAdvantages)
+	for _, t := range resolved {
+		if t.Source != HomeAdvantageDefault {
+			h[t.TeamName] = t.HomeAdvantage
+		}
+	}
+	return h
+}
+
+// GET /home-advantages lists every team's home advantage and where it comes
+// from
+func (l *League) handleHomeAdvantages(w http.ResponseWriter, r *http.Request) {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	cfg := l.config()
+	played, _ := state.current()
+	json.NewEncoder(w).Encode(cfg.HomeAdvantages.resolve(cfg.Simulation, state.teams, state.strengths, played))
+}
@@ -0,0 +1,40 @@
+package insider
+
+import "testing"
+
+func TestResolveHomeAdvantages(t *testing.T) {
+	teams := []string{"Alpha FC", "Bravo United", "Charlie Town"}
+	strengths := map[string]int{"Alpha FC": 60, "Bravo United": 60, "Charlie Town": 60}
+	var played []Match
+	for i := 0; i < 10; i++ {
+		// Alpha win every home game by three, Bravo only draw at home
+		played = append(played,
+			Match{HomeTeam: "Alpha FC", AwayTeam: "Bravo United", HomeGoals: 3, Played: true},
+			Match{HomeTeam: "Bravo United", AwayTeam: "Alpha FC", Played: true},
+		)
+	}
+
+	h := HomeAdvantages{Teams: map[string]int{"Charlie Town": 25}, Learn: true}
+	got := make(map[string]TeamHomeAdvantage)
+	for _, a := range h.resolve(defaultSimParams, teams, strengths, played) {
+		got[a.TeamName] = a
+	}
+
+	// three goals a game is 120 strength, halfway there after ten games
+	if a := got["Alpha FC"]; a.Source != HomeAdvantageLearned || a.HomeAdvantage != 65 || a.HomeGames != 10 {
+		t.Errorf("Alpha FC: %+v", a)
+	}
+	if a := got["Bravo United"]; a.Source != HomeAdvantageLearned || a.HomeAdvantage != 5 {
+		t.Errorf("Bravo United: %+v", a)
+	}
+	if a := got["Charlie Town"]; a.Source != HomeAdvantageConfig || a.HomeAdvantage != 25 {
+		t.Errorf("Charlie Town: %+v", a)
+	}
+
+	// without learn only the configured team is off the league-wide value
+	h.Learn = false
+	advantages := newHomeAdvantages(h.resolve(defaultSimParams, teams, strengths, played))
+	if len(advantages) != 1 || advantages.params(defaultSimParams, "Alpha FC").HomeAdvantage != defaultSimParams.HomeAdvantage {
+		t.Errorf("advantages without learn: %v", advantages)
+	}
+}
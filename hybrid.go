@@ -0,0 +1,124 @@
+package insider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// A hybrid prediction is a Monte Carlo prediction with some of the remaining
+// matches settled in advance, a postponed match awarded 3-0 or a result the
+// admin wants to see the season through with. The locked matches count as
+// played from the start of every run, the rest are simulated as usual. Like
+// a what-if it is never stored and never touches the cached prediction.
+
+// ErrInvalidLock is returned for a locked result that cannot be used
+var ErrInvalidLock = errors.New("invalid locked result")
+
+// LockedResult is the score a remaining match is settled with
+type LockedResult struct {
+	ID        int `json:"id"`
+	HomeGoals int `json:"home_goals"`
+	AwayGoals int `json:"away_goals"`
+}
+
+// HybridPrediction is the prediction with the matches that were locked;
+// current_points count them, the expected home and away points do not
+type HybridPrediction struct {
+	*MonteCarloPrediction
+	Locked []Match `json:"locked"`
+}
+
+// PredictHybrid simulates the rest of the season runs times with the locked
+// matches settled. A seed makes the prediction repeatable; without one the
+// league's own stream is used.
+func (l *League) PredictHybrid(locked []LockedResult, runs int, seed *int64) (*HybridPrediction, error) {
+	cfg := l.config()
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return nil, err
+	}
+
+	played, remaining := state.current()
+	byID := make(map[int]LockedResult, len(locked))
+	for _, lock := range locked {
+		if _, ok := byID[lock.ID]; ok {
+			return nil, fmt.Errorf("%w: match %d is locked twice", ErrInvalidLock, lock.ID)
+		}
+		if lock.HomeGoals < 0 || lock.AwayGoals < 0 {
+			return nil, fmt.Errorf("%w: goals cannot be negative", ErrInvalidLock)
+		}
+		// level games go to overtime, a draw cannot be awarded
+		if cfg.Simulation.Overtime && lock.HomeGoals == lock.AwayGoals {
+			return nil, fmt.Errorf("%w: match %d cannot end level", ErrInvalidLock, lock.ID)
+		}
+		byID[lock.ID] = lock
+	}
+
+	settled := append([]Match{}, played...)
+	var open, settledLocks []Match
+	for _, m := range remaining {
+		lock, ok := byID[m.ID]
+		if !ok {
+			open = append(open, m)
+			continue
+		}
+		m.Played, m.HomeGoals, m.AwayGoals = true, lock.HomeGoals, lock.AwayGoals
+		settled = append(settled, m)
+		settledLocks = append(settledLocks, m)
+		delete(byID, m.ID)
+	}
+	for _, lock := range locked {
+		if _, ok := byID[lock.ID]; ok {
+			return nil, fmt.Errorf("%w: match %d is not a remaining match", ErrInvalidLock, lock.ID)
+		}
+	}
+
+	rng, _ := l.seededStreams(seed)
+	summary := monteCarlo(rng, cfg.Simulation, state, settled, open, runs)
+	prediction := monteCarloPrediction(state, settled, open, summary)
+	prediction.StateVersion = l.version.Load()
+	if settledLocks == nil {
+		settledLocks = []Match{}
+	}
+	return &HybridPrediction{MonteCarloPrediction: prediction, Locked: settledLocks}, nil
+}
+
+// POST /predict/hybrid?runs=n&seed=42 with the locked results,
+// [{"id": 12, "home_goals": 3, "away_goals": 0}, ...] (admin only)
+func (l *League) handlePredictHybrid(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	runs, err := runsParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var locked []LockedResult
+	if err := json.NewDecoder(r.Body).Decode(&locked); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	prediction, err := l.PredictHybrid(locked, runs, seed)
+	if errors.Is(err, ErrInvalidLock) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(prediction)
+}
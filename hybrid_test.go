@@ -0,0 +1,72 @@
+package insider_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestHybridPredictionKeepsLockedResults(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 2)
+	for week := 1; week <= 3; week++ {
+		if err := h.League.SimulateWeek(week); err != nil {
+			t.Fatalf("simulate week %d: %v", week, err)
+		}
+	}
+	var locked []insider.LockedResult
+	var playedID int
+	for _, m := range h.Matches() {
+		if m.Played {
+			playedID = m.ID
+			continue
+		}
+		locked = append(locked, insider.LockedResult{ID: m.ID, HomeGoals: 3})
+	}
+
+	// with every match locked the season has a single ending
+	var all insider.HybridPrediction
+	if status := h.Do(http.MethodPost, "/predict/hybrid?runs=50&seed=7", locked, true, &all); status != http.StatusOK {
+		t.Fatalf("POST /predict/hybrid: status %d", status)
+	}
+	if all.Runs != 50 || len(all.Locked) != len(locked) {
+		t.Fatalf("%d runs with %d locked matches", all.Runs, len(all.Locked))
+	}
+	for _, p := range all.Teams {
+		if p.ExpectedPoints != float64(p.CurrentPoints) || p.RemainingHome+p.RemainingAway != 0 {
+			t.Errorf("%s: %.2f expected from %d, %d matches left", p.TeamName, p.ExpectedPoints, p.CurrentPoints, p.RemainingHome+p.RemainingAway)
+		}
+	}
+
+	// with one match locked the rest is simulated, the same seed giving the
+	// same prediction
+	one := locked[:1]
+	first, err := h.League.PredictHybrid(one, 200, &[]int64{7}[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := h.League.PredictHybrid(one, 200, &[]int64{7}[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Locked) != 1 || first.Locked[0].HomeGoals != 3 {
+		t.Errorf("locked %+v", first.Locked)
+	}
+	for i := range first.Teams {
+		if first.Teams[i].ExpectedPoints != again.Teams[i].ExpectedPoints {
+			t.Errorf("%s: %.2f then %.2f expected points", first.Teams[i].TeamName, first.Teams[i].ExpectedPoints, again.Teams[i].ExpectedPoints)
+		}
+	}
+
+	if _, err := h.League.PredictHybrid([]insider.LockedResult{{ID: playedID, HomeGoals: 1}}, 10, nil); !errors.Is(err, insider.ErrInvalidLock) {
+		t.Errorf("locking a played match: err = %v", err)
+	}
+	if status := h.Do(http.MethodPost, "/predict/hybrid", []insider.LockedResult{{ID: locked[0].ID, HomeGoals: -1}}, true, nil); status != http.StatusBadRequest {
+		t.Errorf("negative goals: status %d, want 400", status)
+	}
+	if status := h.Do(http.MethodPost, "/predict/hybrid", one, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("without a token: status %d, want 401", status)
+	}
+}
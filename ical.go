@@ -0,0 +1,257 @@
+package insider
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// iCal feeds of the fixtures, for the whole league or one team, that a
+// calendar app can subscribe to. Apps cannot send headers, so a subscription
+// is a token in the URL. It points at the team id, so the feed keeps working
+// after a rename, and like user tokens only its hash is stored.
+
+// ErrUnknownSubscription is returned for a calendar token never handed out
+var ErrUnknownSubscription = errors.New("unknown calendar subscription")
+
+// CalendarSubscription is a token for an iCal feed, of one team or of the
+// whole league when Team is empty
+type CalendarSubscription struct {
+	Token string `json:"token"`
+	Team  string `json:"team,omitempty"`
+	URL   string `json:"url"`
+}
+
+func (l *League) createCalendarTokenTable() error {
+	createTokens := `
+	CREATE TABLE IF NOT EXISTS calendar_tokens (
+		token_hash TEXT PRIMARY KEY,
+		team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
+		created_at TIMESTAMP
+	);`
+
+	if _, err := l.db.Exec(createTokens); err != nil {
+		return fmt.Errorf("error creating calendar_tokens table: %v", err)
+	}
+	return nil
+}
+
+// storedKickoff is the kickoff saved with the match, stored as RFC 3339 or a
+// date alone for midnight UTC
+func storedKickoff(m Match) (time.Time, bool) {
+	if m.Kickoff == "" {
+		return time.Time{}, false
+	}
+	start, err := parseKickoff(m.Kickoff)
+	return start, err == nil
+}
+
+// calendarKickoff also places matches without a kickoff in clock mode
+func (l *League) calendarKickoff(m Match) (time.Time, bool) {
+	if l.virtual != nil {
+		return l.virtual.kickoff(m), true
+	}
+	return storedKickoff(m)
+}
+
+// writeCalendar lists the matches that have a kickoff. Once a match is
+// played its result is in the summary and the description.
+func writeCalendar(w io.Writer, name string, sport Sport, matches []Match, stamp time.Time, kickoff func(Match) (time.Time, bool)) error {
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format+"\r\n", args...)
+	}
+	escape := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//LeagueCase//Fixtures//EN")
+	line("X-WR-CALNAME:%s", escape.Replace(name))
+	for _, m := range matches {
+		start, ok := kickoff(m)
+		if !ok {
+			continue
+		}
+		summary := fmt.Sprintf("%s vs %s", m.HomeTeam, m.AwayTeam)
+		description := fmt.Sprintf("Week %d", m.Week)
+		switch {
+		case m.Administrative == AdminAnnulled:
+			description += "\nAnnulled"
+		case m.Played:
+			summary = fmt.Sprintf("%s %d-%d %s", m.HomeTeam, m.HomeGoals, m.AwayGoals, m.AwayTeam)
+			description += fmt.Sprintf("\nFull time: %s", summary)
+			if m.Administrative == AdminAwarded {
+				description += " (awarded)"
+			}
+		case m.Postponed:
+			description += "\nPostponed"
+		}
+
+		line("BEGIN:VEVENT")
+		line("UID:match-%d@leaguecase", m.ID)
+		line("DTSTAMP:%s", stamp.UTC().Format("20060102T150405Z"))
+		line("DTSTART:%s", start.UTC().Format("20060102T150405Z"))
+		// match length plus a break
+		line("DURATION:PT%dM", sport.MatchMinutes+30)
+		line("SUMMARY:%s", escape.Replace(summary))
+		line("DESCRIPTION:%s", escape.Replace(description))
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Subscribe hands out a calendar token for a team, or for the whole league
+// when team is empty. Former names work too. The token cannot be shown again.
+func (l *League) Subscribe(team string) (*CalendarSubscription, error) {
+	var teamID *int
+	sub := &CalendarSubscription{}
+	if team != "" {
+		id, current, err := l.resolveTeam(team)
+		if err != nil {
+			return nil, err
+		}
+		teamID, sub.Team = &id, current
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	sub.Token = hex.EncodeToString(raw)
+	sub.URL = "/calendar/" + sub.Token + ".ics"
+
+	_, err := l.db.Exec("INSERT INTO calendar_tokens (token_hash, team_id, created_at) VALUES (?, ?, ?)",
+		hashToken(sub.Token), teamID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// subscription finds the team of a calendar token, 0 for the whole league
+func (l *League) subscription(token string) (int, error) {
+	var teamID sql.NullInt64
+	err := l.db.QueryRow("SELECT team_id FROM calendar_tokens WHERE token_hash = ?", hashToken(token)).Scan(&teamID)
+	if err == sql.ErrNoRows {
+		return 0, ErrUnknownSubscription
+	}
+	return int(teamID.Int64), err
+}
+
+// serveCalendar writes the feed of a team, or of the league for teamID 0
+func (l *League) serveCalendar(w http.ResponseWriter, teamID int) {
+	query, args, name := matchSelect, []any{}, "League fixtures"
+	if teamID != 0 {
+		query += " WHERE m.home_team_id = ? OR m.away_team_id = ?"
+		args = append(args, teamID, teamID)
+		for _, t := range l.Teams() {
+			if t.ID == teamID {
+				name = t.Name + " fixtures"
+			}
+		}
+	}
+	rows, err := l.db.Query(query+" ORDER BY m.week, m.id", args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var b strings.Builder
+	if err := writeCalendar(&b, name, l.config().Sport, matches, l.clock.Now(), l.calendarKickoff); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	io.WriteString(w, b.String())
+}
+
+// GET /calendar.ics is every fixture of the league
+func (l *League) handleLeagueCalendar(w http.ResponseWriter, r *http.Request) {
+	l.serveCalendar(w, 0)
+}
+
+// GET /teams/{name}/calendar.ics, former names still lead to the team
+func (l *League) handleTeamCalendar(w http.ResponseWriter, r *http.Request) {
+	teamID, _, err := l.resolveTeam(r.PathValue("name"))
+	if err == sql.ErrNoRows {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	l.serveCalendar(w, teamID)
+}
+
+// GET /calendar/{token}.ics is the feed a subscription token stands for
+func (l *League) handleSubscriptionCalendar(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutSuffix(r.PathValue("file"), ".ics")
+	if !ok {
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return
+	}
+	teamID, err := l.subscription(token)
+	if errors.Is(err, ErrUnknownSubscription) {
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	l.serveCalendar(w, teamID)
+}
+
+// POST /calendar/subscriptions {"team": "Alpha FC"} hands out a token for a
+// team's feed, or for the league's without a team
+func (l *League) handleCreateSubscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Team string `json:"team"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, err := l.Subscribe(req.Team)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sub)
+}
@@ -0,0 +1,59 @@
+package insider_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestCalendarSubscriptionSurvivesRename(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 1)
+	if _, err := h.League.DB().Exec("UPDATE matches SET kickoff = '2026-11-01T15:00:00Z'"); err != nil {
+		t.Fatal(err)
+	}
+
+	var sub insider.CalendarSubscription
+	if status := h.Do(http.MethodPost, "/calendar/subscriptions", map[string]string{"team": "Alpha FC"}, false, &sub); status != http.StatusCreated {
+		t.Fatalf("subscribe: status %d", status)
+	}
+	h.SimulateWeek(1)
+	h.Post("/teams/Alpha%20FC/rename", map[string]string{"name": "Alpha City"}, nil)
+
+	calendar := func(path string) (int, string) {
+		resp, err := h.Server.Client().Get(h.Server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	status, ics := calendar(sub.URL)
+	if status != http.StatusOK {
+		t.Fatalf("GET %s: status %d", sub.URL, status)
+	}
+	if !strings.Contains(ics, "X-WR-CALNAME:Alpha City fixtures") {
+		t.Errorf("feed does not follow the rename:\n%s", ics)
+	}
+	if got, want := strings.Count(ics, "BEGIN:VEVENT"), 2*(len(insider.SnapshotTeams)-1); got != want {
+		t.Errorf("%d events, want %d", got, want)
+	}
+	if !strings.Contains(ics, `DESCRIPTION:Week 1\nFull time: `) {
+		t.Errorf("no result in the played week:\n%s", ics)
+	}
+
+	if status, _ := calendar("/calendar/unknown.ics"); status != http.StatusNotFound {
+		t.Errorf("unknown token: status %d", status)
+	}
+	if status, old := calendar("/teams/Alpha%20FC/calendar.ics"); status != http.StatusOK || !strings.Contains(old, "X-WR-CALNAME:Alpha City fixtures") {
+		t.Errorf("former name: status %d\n%s", status, old)
+	}
+}
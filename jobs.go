@@ -0,0 +1,335 @@
+package insider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	maxJobRuns = 1000000
+	// jobChunk is how many runs happen between progress updates and
+	// pause/cancel checks
+	jobChunk = 500
+	// finished jobs beyond this many are forgotten, oldest first
+	maxJobHistory = 100
+)
+
+const (
+	JobRunning   = "running"
+	JobPaused    = "paused"
+	JobDone      = "done"
+	JobCancelled = "cancelled"
+)
+
+// ErrJobFinished is returned when pausing, resuming or cancelling a job that
+// has already stopped
+var ErrJobFinished = errors.New("job has already finished")
+
+// Job is a long simulation running in the background. Jobs live in memory
+// only and are lost on restart.
+type Job struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	id        int
+	kind      string
+	state     string
+	runs      int
+	completed int
+	createdAt time.Time
+	// active is the running time before the last pause; resumedAt is when
+	// the current running stretch began
+	active     time.Duration
+	resumedAt  time.Time
+	finishedAt time.Time
+	result     interface{}
+}
+
+// JobStatus is what GET /jobs/{id} reports
+type JobStatus struct {
+	ID         int         `json:"id"`
+	Kind       string      `json:"kind"`
+	State      string      `json:"state"`
+	Runs       int         `json:"runs"`
+	Completed  int         `json:"completed"`
+	Progress   float64     `json:"progress"`
+	ETASeconds *float64    `json:"eta_seconds,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+}
+
+func newJob(id int, kind string, runs int) *Job {
+	now := time.Now().UTC()
+	j := &Job{id: id, kind: kind, state: JobRunning, runs: runs, createdAt: now, resumedAt: now}
+	j.cond = sync.NewCond(&j.mu)
+	return j
+}
+
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	s := JobStatus{
+		ID:        j.id,
+		Kind:      j.kind,
+		State:     j.state,
+		Runs:      j.runs,
+		Completed: j.completed,
+		Progress:  float64(j.completed) / float64(j.runs),
+		CreatedAt: j.createdAt,
+		Result:    j.result,
+	}
+	if !j.finishedAt.IsZero() {
+		finished := j.finishedAt
+		s.FinishedAt = &finished
+	}
+
+	// the ETA extrapolates the time spent running so far; paused time does not count
+	if (j.state == JobRunning || j.state == JobPaused) && j.completed > 0 {
+		active := j.active
+		if j.state == JobRunning {
+			active += time.Since(j.resumedAt)
+		}
+		perRun := active.Seconds() / float64(j.completed)
+		eta := perRun * float64(j.runs-j.completed)
+		s.ETASeconds = &eta
+	}
+	return s
+}
+
+// proceed blocks while the job is paused and reports whether it should keep going
+func (j *Job) proceed() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for j.state == JobPaused {
+		j.cond.Wait()
+	}
+	return j.state == JobRunning
+}
+
+func (j *Job) advance(runs int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	// a chunk that was in flight when the job got cancelled does not count
+	if j.state != JobCancelled {
+		j.completed += runs
+	}
+}
+
+func (j *Job) finish(result interface{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.state != JobRunning {
+		return
+	}
+	j.state = JobDone
+	j.result = result
+	j.finishedAt = time.Now().UTC()
+}
+
+func (j *Job) Pause() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch j.state {
+	case JobPaused:
+		return nil
+	case JobRunning:
+		j.active += time.Since(j.resumedAt)
+		j.state = JobPaused
+		return nil
+	}
+	return ErrJobFinished
+}
+
+func (j *Job) Resume() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch j.state {
+	case JobRunning:
+		return nil
+	case JobPaused:
+		j.state = JobRunning
+		j.resumedAt = time.Now()
+		j.cond.Broadcast()
+		return nil
+	}
+	return ErrJobFinished
+}
+
+func (j *Job) Cancel() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.state != JobRunning && j.state != JobPaused {
+		return ErrJobFinished
+	}
+	j.state = JobCancelled
+	j.finishedAt = time.Now().UTC()
+	j.cond.Broadcast()
+	return nil
+}
+
+func (j *Job) finished() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state == JobDone || j.state == JobCancelled
+}
+
+// addJob registers a job under the next id and drops the oldest finished
+// jobs once there are too many
+func (l *League) addJob(kind string, runs int) *Job {
+	l.jobsMu.Lock()
+	defer l.jobsMu.Unlock()
+
+	l.nextJobID++
+	job := newJob(l.nextJobID, kind, runs)
+	l.jobs[job.id] = job
+
+	if len(l.jobs) > maxJobHistory {
+		ids := make([]int, 0, len(l.jobs))
+		for id := range l.jobs {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			if len(l.jobs) <= maxJobHistory {
+				break
+			}
+			if l.jobs[id].finished() {
+				delete(l.jobs, id)
+			}
+		}
+	}
+	return job
+}
+
+func (l *League) job(id int) *Job {
+	l.jobsMu.Lock()
+	defer l.jobsMu.Unlock()
+	return l.jobs[id]
+}
+
+// StartMonteCarloJob predicts the rest of the season like
+// /predict?mode=montecarlo, in the background. The season is read when the job
+// starts; results recorded afterwards are not picked up.
+func (l *League) StartMonteCarloJob(runs int) (*Job, error) {
+	// stamped on the result, a later edit makes it visibly stale
+	version := l.version.Load()
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return nil, err
+	}
+	params := l.config().Simulation
+	rng := rand.New(rand.NewSource(l.predict.Int63()))
+
+	job := l.addJob("montecarlo", runs)
+	go func() {
+		played, remaining := state.current()
+		base := state.standings(played)
+		summary := newSimulationSummary(state.teams)
+		for summary.Runs < runs {
+			if !job.proceed() {
+				return
+			}
+			n := min(jobChunk, runs-summary.Runs)
+			summary.simulate(rng, params, state, base, remaining, n)
+			job.advance(n)
+		}
+		prediction := monteCarloPrediction(state, played, remaining, summary)
+		prediction.StateVersion = version
+		job.finish(prediction)
+	}()
+	return job, nil
+}
+
+// POST /jobs/simulate with {"runs": n} starts a Monte Carlo prediction job
+func (l *League) handleStartJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := struct {
+		Runs int `json:"runs"`
+	}{Runs: defaultRuns}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Runs < 1 || req.Runs > maxJobRuns {
+		http.Error(w, fmt.Sprintf("runs must be between 1 and %d", maxJobRuns), http.StatusBadRequest)
+		return
+	}
+
+	job, err := l.StartMonteCarloJob(req.Runs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job.Status())
+}
+
+func (l *League) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	l.jobsMu.Lock()
+	jobs := make([]*Job, 0, len(l.jobs))
+	for _, job := range l.jobs {
+		jobs = append(jobs, job)
+	}
+	l.jobsMu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		s := job.Status()
+		// results can be large, fetch them from /jobs/{id}
+		s.Result = nil
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	json.NewEncoder(w).Encode(statuses)
+}
+
+func (l *League) jobFromPath(w http.ResponseWriter, r *http.Request) *Job {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid job id", http.StatusBadRequest)
+		return nil
+	}
+	job := l.job(id)
+	if job == nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+	}
+	return job
+}
+
+func (l *League) handleJob(w http.ResponseWriter, r *http.Request) {
+	if job := l.jobFromPath(w, r); job != nil {
+		json.NewEncoder(w).Encode(job.Status())
+	}
+}
+
+// handleJobAction serves POST /jobs/{id}/pause, /resume and /cancel
+func (l *League) handleJobAction(action func(*Job) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		job := l.jobFromPath(w, r)
+		if job == nil {
+			return
+		}
+		if err := action(job); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		json.NewEncoder(w).Encode(job.Status())
+	}
+}
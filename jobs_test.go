@@ -0,0 +1,113 @@
+package insider
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJobStates(t *testing.T) {
+	j := newJob(1, "montecarlo", 1000)
+	for _, step := range []struct {
+		name   string
+		action func() error
+		want   string
+	}{
+		{"pause", j.Pause, JobPaused},
+		{"pause again", j.Pause, JobPaused},
+		{"resume", j.Resume, JobRunning},
+		{"resume again", j.Resume, JobRunning},
+		{"pause", j.Pause, JobPaused},
+		{"cancel while paused", j.Cancel, JobCancelled},
+	} {
+		if err := step.action(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if s := j.Status(); s.State != step.want {
+			t.Fatalf("after %s: state %s, want %s", step.name, s.State, step.want)
+		}
+	}
+
+	// a stopped job stays stopped
+	j.advance(jobChunk)
+	j.finish("late result")
+	for name, action := range map[string]func() error{"pause": j.Pause, "resume": j.Resume, "cancel": j.Cancel} {
+		if err := action(); !errors.Is(err, ErrJobFinished) {
+			t.Errorf("%s a cancelled job: %v, want ErrJobFinished", name, err)
+		}
+	}
+	if s := j.Status(); s.State != JobCancelled || s.Completed != 0 || s.Result != nil || s.FinishedAt == nil {
+		t.Errorf("cancelled job %+v", s)
+	}
+
+	done := newJob(2, "montecarlo", jobChunk)
+	done.advance(jobChunk)
+	done.finish("result")
+	if err := done.Pause(); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("pause a done job: %v, want ErrJobFinished", err)
+	}
+	if s := done.Status(); s.State != JobDone || s.Progress != 1 || s.Result != "result" || s.ETASeconds != nil {
+		t.Errorf("done job %+v", s)
+	}
+}
+
+// waitForJob polls until the job stops
+func waitForJob(t *testing.T, j *Job) JobStatus {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for !j.finished() {
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s after 30s", j.Status().State)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return j.Status()
+}
+
+func TestJobPauseAndResume(t *testing.T) {
+	const runs = 100 * jobChunk
+	l := newTestLeague(t, snapshotTeams, 6, 2)
+	job, err := l.StartMonteCarloJob(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := job.Pause(); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+
+	// a chunk in flight may still land, then the job holds still
+	time.Sleep(20 * time.Millisecond)
+	paused := job.Status()
+	time.Sleep(20 * time.Millisecond)
+	if again := job.Status(); again.State != JobPaused || again.Completed != paused.Completed {
+		t.Fatalf("paused job moved from %d to %d runs, state %s", paused.Completed, again.Completed, again.State)
+	}
+	if paused.Completed >= runs || paused.Completed%jobChunk != 0 {
+		t.Fatalf("paused after %d of %d runs", paused.Completed, runs)
+	}
+
+	if err := job.Resume(); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	s := waitForJob(t, job)
+	prediction, ok := s.Result.(*MonteCarloPrediction)
+	if s.State != JobDone || s.Completed != runs || !ok || prediction.Runs != runs {
+		t.Errorf("resumed job ended %s after %d runs with %+v, want %d runs", s.State, s.Completed, s.Result, runs)
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, 6, 3)
+	job, err := l.StartMonteCarloJob(100 * jobChunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := job.Cancel(); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	s := waitForJob(t, job)
+	time.Sleep(20 * time.Millisecond)
+	if again := job.Status(); s.State != JobCancelled || again.Completed != s.Completed || again.Result != nil {
+		t.Errorf("cancelled job %+v, then %+v", s, again)
+	}
+}
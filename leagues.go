@@ -0,0 +1,289 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// One server can run more leagues than the one it was started with. The
+// first league keeps the root paths; every other league has a database of
+// its own and the same API under /leagues/{id}, so /leagues/2/standings is
+// the table of league 2. The leagues table of the first league's database
+// lists the others, and they are opened again at startup. A new league
+// starts from the first league's config as it is when the league is
+// created.
+
+// ErrInvalidLeague is returned for a league that cannot be created, with
+// the ProposalReport saying why
+var ErrInvalidLeague = errors.New("invalid league")
+
+var memoryLeagues atomic.Int64
+
+// leagueRegistry holds the other leagues of the server by id
+type leagueRegistry struct {
+	mu      sync.Mutex
+	leagues map[int]*otherLeague
+	// driver and dsn open the database of a league; the default dsn keeps it
+	// in memory, main puts it in a file next to league.db
+	driver string
+	dsn    func(id int) string
+	// background starts the outbox worker of every league opened
+	background bool
+}
+
+type otherLeague struct {
+	name      string
+	createdAt time.Time
+	league    *League
+	handler   http.Handler
+}
+
+func newLeagueRegistry() *leagueRegistry {
+	return &leagueRegistry{
+		leagues: make(map[int]*otherLeague),
+		driver:  DriverName,
+		dsn: func(id int) string {
+			return fmt.Sprintf("file:league%d-%d?mode=memory&cache=shared&_foreign_keys=on", memoryLeagues.Add(1), id)
+		},
+	}
+}
+
+// LeagueRequest is the body of POST /leagues
+type LeagueRequest struct {
+	Name  string `json:"name"`
+	Teams []Team `json:"teams"`
+	// Weeks is left out to take the computed length
+	Weeks int `json:"weeks,omitempty"`
+}
+
+// LeagueInfo is one of the other leagues of the server
+type LeagueInfo struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Teams     int       `json:"teams"`
+	Weeks     int       `json:"weeks"`
+	CreatedAt time.Time `json:"created_at"`
+	// Path is where its API is served
+	Path string `json:"path"`
+}
+
+func (l *League) createLeaguesTable() error {
+	createLeagues := `
+	CREATE TABLE IF NOT EXISTS leagues (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);`
+
+	if _, err := l.db.Exec(createLeagues); err != nil {
+		return fmt.Errorf("error creating leagues table: %v", err)
+	}
+	return nil
+}
+
+// CreateLeague sets up a new league with its own teams and fixture. A
+// proposal that does not validate is refused with ErrInvalidLeague and the
+// report.
+func (l *League) CreateLeague(req LeagueRequest) (*LeagueInfo, *ProposalReport, error) {
+	report := ValidateProposal(LeagueProposal{Teams: req.Teams, Weeks: req.Weeks})
+	if !report.Valid {
+		return nil, report, ErrInvalidLeague
+	}
+
+	createdAt := time.Now().UTC()
+	res, err := l.db.Exec("INSERT INTO leagues (name, created_at) VALUES (?, ?)", req.Name, createdAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	id64, err := res.LastInsertId()
+	if err != nil {
+		return nil, nil, err
+	}
+	id := int(id64)
+	if req.Name == "" {
+		req.Name = fmt.Sprintf("League %d", id)
+		if _, err := l.db.Exec("UPDATE leagues SET name = ? WHERE id = ?", req.Name, id); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	teams := make([]Team, len(req.Teams))
+	for i, t := range req.Teams {
+		teams[i] = Team{Name: t.Name, Strength: t.Strength, Metadata: t.Metadata}
+	}
+	if err := l.openLeague(id, req.Name, createdAt, teams); err != nil {
+		l.db.Exec("DELETE FROM leagues WHERE id = ?", id)
+		return nil, nil, err
+	}
+	info := l.leagues.info(id)
+	return &info, report, nil
+}
+
+// openLeague opens the database of league id, creating it with teams when it
+// is new, and serves it under /leagues/{id}
+func (l *League) openLeague(id int, name string, createdAt time.Time, teams []Team) error {
+	db, err := sql.Open(l.leagues.driver, l.leagues.dsn(id))
+	if err != nil {
+		return err
+	}
+
+	other := NewLeague(db, teams, FixtureWeeks(len(teams)), nil, l.config().Simulation.Simulator)
+	l.configMu.Lock()
+	other.baseConfig = l.baseConfig
+	l.configMu.Unlock()
+	cfg := *l.config()
+	other.cfg.Store(&cfg)
+	other.signingKey = l.signingKey
+	other.adminToken = l.adminToken
+	// only the first league has others
+	other.leagues = nil
+	other.parent = l
+	other.id = id
+	if err := other.InitDatabase(); err != nil {
+		db.Close()
+		return fmt.Errorf("league %d: %v", id, err)
+	}
+	other.weeks = FixtureWeeks(len(other.Teams()))
+
+	prefix := "/leagues/" + strconv.Itoa(id)
+	l.leagues.mu.Lock()
+	l.leagues.leagues[id] = &otherLeague{
+		name:      name,
+		createdAt: createdAt,
+		league:    other,
+		handler:   http.StripPrefix(prefix, other.freezeGuard(leagueRoutes(other))),
+	}
+	l.leagues.mu.Unlock()
+	if l.leagues.background {
+		go other.runOutbox()
+	}
+	return nil
+}
+
+// OpenLeagues opens every league created before the server started
+func (l *League) OpenLeagues() error {
+	rows, err := l.db.Query("SELECT id, name, created_at FROM leagues ORDER BY id")
+	if err != nil {
+		return err
+	}
+	type stored struct {
+		id        int
+		name      string
+		createdAt time.Time
+	}
+	var leagues []stored
+	for rows.Next() {
+		var s stored
+		if err := rows.Scan(&s.id, &s.name, &s.createdAt); err != nil {
+			rows.Close()
+			return err
+		}
+		leagues = append(leagues, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, s := range leagues {
+		if err := l.openLeague(s.id, s.name, s.createdAt, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *leagueRegistry) info(id int) LeagueInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o := r.leagues[id]
+	return LeagueInfo{
+		ID:        id,
+		Name:      o.name,
+		Teams:     len(o.league.Teams()),
+		Weeks:     o.league.weeks,
+		CreatedAt: o.createdAt,
+		Path:      "/leagues/" + strconv.Itoa(id),
+	}
+}
+
+// Leagues lists the other leagues of the server by id
+func (l *League) Leagues() []LeagueInfo {
+	leagues := []LeagueInfo{}
+	if l.leagues == nil {
+		return leagues
+	}
+	l.leagues.mu.Lock()
+	ids := make([]int, 0, len(l.leagues.leagues))
+	for id := range l.leagues.leagues {
+		ids = append(ids, id)
+	}
+	l.leagues.mu.Unlock()
+
+	sort.Ints(ids)
+	for _, id := range ids {
+		leagues = append(leagues, l.leagues.info(id))
+	}
+	return leagues
+}
+
+// GET /leagues lists the other leagues; POST creates one (admin only):
+// {"name": "Sunday league", "teams": [{"name": "A", "strength": 70}, ...], "weeks": 6}
+func (l *League) handleLeagues(w http.ResponseWriter, r *http.Request) {
+	if l.leagues == nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(l.Leagues())
+	case http.MethodPost:
+		if !requireAdmin(w, r) {
+			return
+		}
+		var req LeagueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		info, report, err := l.CreateLeague(req)
+		if errors.Is(err, ErrInvalidLeague) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(report)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(info)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// /leagues/{id}/... is the API of another league
+func (l *League) handleOtherLeague(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || l.leagues == nil {
+		http.Error(w, "League not found", http.StatusNotFound)
+		return
+	}
+	l.leagues.mu.Lock()
+	other := l.leagues.leagues[id]
+	l.leagues.mu.Unlock()
+	if other == nil {
+		http.Error(w, "League not found", http.StatusNotFound)
+		return
+	}
+	other.handler.ServeHTTP(w, r)
+}
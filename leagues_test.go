@@ -0,0 +1,65 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestLeaguesAreIndependent(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 3)
+	req := insider.LeagueRequest{
+		Name:  "Sunday league",
+		Teams: []insider.Team{{Name: "Reds", Strength: 70}, {Name: "Blues", Strength: 65}, {Name: "Greens", Strength: 60}, {Name: "Whites", Strength: 55}},
+		Weeks: 6,
+	}
+	var info insider.LeagueInfo
+	if status := h.Do(http.MethodPost, "/leagues", req, true, &info); status != http.StatusCreated {
+		t.Fatalf("POST /leagues: status %d", status)
+	}
+	if info.Name != "Sunday league" || info.Teams != 4 || info.Weeks != 6 || info.Path != "/leagues/1" {
+		t.Errorf("created %+v", info)
+	}
+
+	var teams []insider.Team
+	h.Get(info.Path+"/teams", &teams)
+	if len(teams) != 4 || teams[0].Name != "Reds" {
+		t.Errorf("league teams %+v", teams)
+	}
+	if status := h.Do(http.MethodPost, info.Path+"/simulate/all", nil, false, nil); status != http.StatusOK {
+		t.Fatalf("simulate the new league: status %d", status)
+	}
+	var standings []insider.Standing
+	h.Get(info.Path+"/standings", &standings)
+	if len(standings) != 4 || standings[0].Played != 6 {
+		t.Errorf("new league table %+v", standings)
+	}
+
+	// the first league has not moved
+	h.Get("/standings", &standings)
+	if len(standings) != len(insider.SnapshotTeams) || standings[0].Played != 0 {
+		t.Errorf("first league table %+v", standings)
+	}
+
+	var leagues []insider.LeagueInfo
+	h.Get("/leagues", &leagues)
+	if len(leagues) != 1 || leagues[0].ID != info.ID {
+		t.Errorf("leagues %+v", leagues)
+	}
+
+	req.Teams = req.Teams[:1]
+	if status := h.Do(http.MethodPost, "/leagues", req, true, nil); status != http.StatusBadRequest {
+		t.Errorf("a league of one team: status %d, want 400", status)
+	}
+	if status := h.Do(http.MethodPost, "/leagues", req, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("without a token: status %d, want 401", status)
+	}
+	if status := h.Do(http.MethodGet, "/leagues/9/standings", nil, false, nil); status != http.StatusNotFound {
+		t.Errorf("unknown league: status %d, want 404", status)
+	}
+	if status := h.Do(http.MethodGet, info.Path+"/leagues", nil, false, nil); status != http.StatusNotFound {
+		t.Errorf("leagues of a league: status %d, want 404", status)
+	}
+}
@@ -0,0 +1,154 @@
+// Package leaguetest runs the league API on an httptest server for
+// black-box tests, in this repository and in programs embedding the league.
+package leaguetest
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"insider"
+)
+
+// AdminToken is the admin token every harness server accepts
+const AdminToken = "harness-admin"
+
+var databases atomic.Int64
+
+// Harness runs the whole HTTP API on an httptest server, backed by an
+// in-memory database with a seeded league. The League is there for
+// assertions the API does not expose.
+type Harness struct {
+	T      testing.TB
+	League *insider.League
+	Server *httptest.Server
+}
+
+// New starts a server for teams, insider.DefaultTeams when nil, with both
+// random streams seeded from seed. Everything is closed when the test ends.
+func New(t testing.TB, teams []insider.Team, seed int64) *Harness {
+	t.Helper()
+	return NewWithSimulator(t, teams, seed, nil)
+}
+
+// NewWithSimulator is New for a league whose scores are all drawn by
+// simulator, see insider.NewLeague
+func NewWithSimulator(t testing.TB, teams []insider.Team, seed int64, simulator insider.MatchSimulator) *Harness {
+	t.Helper()
+	if teams == nil {
+		teams = insider.DefaultTeams()
+	}
+
+	// every harness gets its own shared in-memory database
+	dsn := fmt.Sprintf("file:leaguetest%d?mode=memory&cache=shared&_foreign_keys=on", databases.Add(1))
+	db, err := sql.Open(insider.DriverName, dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	league := insider.NewLeague(db, teams, insider.FixtureWeeks(len(teams)), &seed, simulator)
+	if err := league.InitDatabase(); err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+
+	league.SetAdminToken(AdminToken)
+
+	server := httptest.NewServer(insider.NewMux(league))
+	t.Cleanup(server.Close)
+	return &Harness{T: t, League: league, Server: server}
+}
+
+// Do sends a request with body encoded as JSON when it is not nil, as admin
+// when admin is set, and decodes a 2xx response into out when it is not nil.
+// It returns the status code.
+func (h *Harness) Do(method, path string, body any, admin bool, out any) int {
+	h.T.Helper()
+	token := ""
+	if admin {
+		token = AdminToken
+	}
+	return h.DoWithToken(method, path, body, token, out)
+}
+
+// DoWithToken is Do with any bearer token, none when it is empty
+func (h *Harness) DoWithToken(method, path string, body any, token string, out any) int {
+	h.T.Helper()
+
+	var reader bytes.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			h.T.Fatalf("%s %s: encode body: %v", method, path, err)
+		}
+		reader.Reset(encoded)
+	}
+	req, err := http.NewRequest(method, h.Server.URL+path, &reader)
+	if err != nil {
+		h.T.Fatalf("%s %s: %v", method, path, err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := h.Server.Client().Do(req)
+	if err != nil {
+		h.T.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode/100 == 2 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			h.T.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// Get decodes GET path into out and fails the test on anything but 200
+func (h *Harness) Get(path string, out any) {
+	h.T.Helper()
+	if status := h.Do(http.MethodGet, path, nil, false, out); status != http.StatusOK {
+		h.T.Fatalf("GET %s: status %d", path, status)
+	}
+}
+
+// Post sends body as admin and fails the test on anything but 200
+func (h *Harness) Post(path string, body, out any) {
+	h.T.Helper()
+	if status := h.Do(http.MethodPost, path, body, true, out); status != http.StatusOK {
+		h.T.Fatalf("POST %s: status %d", path, status)
+	}
+}
+
+// SimulateWeek plays one week through the API
+func (h *Harness) SimulateWeek(week int) {
+	h.T.Helper()
+	h.Post(fmt.Sprintf("/simulate/week/%d", week), nil, nil)
+}
+
+// SimulateSeason plays every remaining week through the API
+func (h *Harness) SimulateSeason() {
+	h.T.Helper()
+	h.Post("/simulate/all", nil, nil)
+}
+
+// Standings is GET /standings
+func (h *Harness) Standings() []insider.Standing {
+	h.T.Helper()
+	var standings []insider.Standing
+	h.Get("/standings", &standings)
+	return standings
+}
+
+// Matches is GET /matches
+func (h *Harness) Matches() []insider.Match {
+	h.T.Helper()
+	var matches []insider.Match
+	h.Get("/matches", &matches)
+	return matches
+}
@@ -0,0 +1,55 @@
+package leaguetest
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+)
+
+var teams = []insider.Team{
+	{Name: "Alpha FC", Strength: 85},
+	{Name: "Bravo United", Strength: 70},
+	{Name: "Charlie Town", Strength: 60},
+	{Name: "Delta SC", Strength: 50},
+}
+
+func TestHarnessPlaysASeason(t *testing.T) {
+	first := New(t, teams, 42)
+	second := New(t, teams, 42)
+
+	first.SimulateWeek(1)
+	for _, m := range first.Matches() {
+		if m.Played != (m.Week == 1) {
+			t.Fatalf("after week 1, match %d of week %d has played=%v", m.ID, m.Week, m.Played)
+		}
+	}
+	first.SimulateSeason()
+	second.SimulateSeason()
+
+	standings := first.Standings()
+	if len(standings) != len(teams) {
+		t.Fatalf("%d teams in the table, want %d", len(standings), len(teams))
+	}
+	games := 0
+	for _, s := range standings {
+		games += s.Played
+	}
+	if want := len(teams) * (len(teams) - 1) * 2; games != want {
+		t.Errorf("%d team games played, want %d", games, want)
+	}
+
+	// the same seed gives the same season however the weeks were requested
+	again := second.Standings()
+	for i := range standings {
+		if standings[i].TeamName != again[i].TeamName || standings[i].Points != again[i].Points {
+			t.Errorf("position %d: %s on %d, then %s on %d", i+1,
+				standings[i].TeamName, standings[i].Points, again[i].TeamName, again[i].Points)
+		}
+	}
+
+	// admin routes take the harness token
+	if status := first.Do(http.MethodPost, "/admin/reload-config", nil, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("reload without token: status %d", status)
+	}
+}
@@ -0,0 +1,141 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	StatusScheduled = "scheduled"
+	StatusLive      = "live"
+	StatusFinished  = "finished"
+	StatusPostponed = "postponed"
+	StatusAnnulled  = "annulled"
+)
+
+// ErrLiveMode is returned when simulating a league that tracks real matches
+var ErrLiveMode = errors.New("league is in live mode, scores are entered instead of simulated")
+
+// ErrInvalidLiveScore is returned for a live update that cannot be applied
+var ErrInvalidLiveScore = errors.New("invalid live score")
+
+func matchStatus(m Match) string {
+	switch {
+	case m.Administrative == AdminAnnulled:
+		return StatusAnnulled
+	case m.Played:
+		return StatusFinished
+	case m.Postponed:
+		return StatusPostponed
+	case m.Live:
+		return StatusLive
+	}
+	return StatusScheduled
+}
+
+// UpdateLiveScore records the score of a match in progress. The score may go
+// down as well, goals do get taken back.
+func (l *League) UpdateLiveScore(id, minute, homeGoals, awayGoals int) error {
+	if homeGoals < 0 || awayGoals < 0 {
+		return fmt.Errorf("%w: goals cannot be negative", ErrInvalidLiveScore)
+	}
+	if minute < 0 {
+		return fmt.Errorf("%w: minute cannot be negative", ErrInvalidLiveScore)
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	m, err := scanMatch(tx.QueryRow(matchSelect+" WHERE m.id = ?", id))
+	if err != nil {
+		return err
+	}
+	if m.Played {
+		return ErrMatchPlayed
+	}
+	if m.Postponed {
+		return fmt.Errorf("%w: match is postponed", ErrInvalidLiveScore)
+	}
+
+	_, err = tx.Exec(
+		"UPDATE matches SET home_goals = ?, away_goals = ?, minute = ?, live = TRUE WHERE id = ?",
+		homeGoals, awayGoals, minute, id,
+	)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.matchesChanged()
+	return nil
+}
+
+// POST /matches/{id}/live with {"minute": 57, "home_goals": 1, "away_goals": 0}
+// updates a match in progress; "finished": true records the final score.
+// Admin only.
+func (l *League) handleLiveScore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAdmin(r) {
+		http.Error(w, "Admin token required", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Minute    int  `json:"minute"`
+		HomeGoals int  `json:"home_goals"`
+		AwayGoals int  `json:"away_goals"`
+		Finished  bool `json:"finished"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if body.Finished {
+		if body.HomeGoals < 0 || body.AwayGoals < 0 {
+			http.Error(w, "Goals cannot be negative", http.StatusBadRequest)
+			return
+		}
+		err = l.UpdateMatchResult(id, body.HomeGoals, body.AwayGoals, nil)
+	} else {
+		err = l.UpdateLiveScore(id, body.Minute, body.HomeGoals, body.AwayGoals)
+	}
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "Match not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrMatchPlayed):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, ErrInvalidLiveScore):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	m, err := scanMatch(l.db.QueryRow(matchSelect+" WHERE m.id = ?", id))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(m)
+}
@@ -0,0 +1,68 @@
+package insider_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestLiveScore(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 7)
+	cfg := *h.League.Config()
+	cfg.Live = true
+	h.League.StoreConfig(&cfg)
+	if status := h.Do(http.MethodPost, "/simulate/week/1", nil, false, nil); status != http.StatusConflict {
+		t.Errorf("simulate in live mode: status %d, want 409", status)
+	}
+
+	id := h.Matches()[0].ID
+	path := fmt.Sprintf("/matches/%d/live", id)
+	score := map[string]any{"minute": 30, "home_goals": 1, "away_goals": 0}
+	if status := h.Do(http.MethodPost, path, score, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("live score without a token: status %d, want 401", status)
+	}
+	var m insider.Match
+	if status := h.Do(http.MethodPost, path, score, true, &m); status != http.StatusOK {
+		t.Fatalf("POST %s: status %d", path, status)
+	}
+	if m.Status != insider.StatusLive || m.Minute != 30 || m.HomeGoals != 1 || m.Played {
+		t.Errorf("live match %+v", m)
+	}
+
+	// a disallowed goal takes the score back down
+	h.Post(path, map[string]any{"minute": 35, "home_goals": 0, "away_goals": 0}, &m)
+	if m.HomeGoals != 0 || m.Minute != 35 {
+		t.Errorf("score after the goal was taken back %+v", m)
+	}
+	if status := h.Do(http.MethodPost, path, map[string]any{"minute": 40, "home_goals": -1}, true, nil); status != http.StatusBadRequest {
+		t.Errorf("negative goals: status %d, want 400", status)
+	}
+
+	// the live score only counts in the table when asked for
+	h.Post(path, map[string]any{"minute": 60, "home_goals": 2, "away_goals": 0}, &m)
+	for _, s := range h.Standings() {
+		if s.Played != 0 {
+			t.Errorf("%s has played %d before the final whistle", s.TeamName, s.Played)
+		}
+	}
+	var live []insider.Standing
+	h.Get("/standings?live=true", &live)
+	if live[0].TeamName != m.HomeTeam || live[0].Points != 3 {
+		t.Errorf("live table leader %s on %d, want %s on 3", live[0].TeamName, live[0].Points, m.HomeTeam)
+	}
+
+	var final insider.Match
+	h.Post(path, map[string]any{"home_goals": 2, "away_goals": 1, "finished": true}, &final)
+	if final.Status != insider.StatusFinished || final.Live || final.HomeGoals != 2 || final.AwayGoals != 1 {
+		t.Errorf("finished match %+v", final)
+	}
+	if status := h.Do(http.MethodPost, path, score, true, nil); status != http.StatusConflict {
+		t.Errorf("live score for a finished match: status %d, want 409", status)
+	}
+	if status := h.Do(http.MethodPost, "/matches/999/live", score, true, nil); status != http.StatusNotFound {
+		t.Errorf("unknown match: status %d, want 404", status)
+	}
+}
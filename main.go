@@ -1,21 +1,35 @@
-package main
+package insider
 
 import (
+	"crypto/ed25519"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"math/rand"
 	"net/http"
+	"os"
+	"os/signal"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"insider/matchengine"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // Interfaces
-type MatchSimulator interface {
-	Simulate() error
-}
+
+// MatchSimulator draws match scores. The config's simulation.model picks
+// one by name; more can be added with matchengine.RegisterModel, or one
+// handed to NewLeague is used whatever the config names.
+type MatchSimulator = matchengine.Model
 
 type StandingsCalculator interface {
 	CalculateStandings() ([]Standing, error)
@@ -23,23 +37,62 @@ type StandingsCalculator interface {
 
 // Team struct 
 type Team struct {
-	Name     string `json:"name"`
-	Strength int    `json:"strength"`
+	ID       int               `json:"id"`
+	Name     string            `json:"name"`
+	Strength int               `json:"strength"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // Match struct 
 type Match struct {
-	ID        int    `json:"id"`
-	HomeTeam  string `json:"home_team"`
-	AwayTeam  string `json:"away_team"`
+	ID         int    `json:"id"`
+	HomeTeamID int    `json:"home_team_id"`
+	HomeTeam   string `json:"home_team"`
+	AwayTeamID int    `json:"away_team_id"`
+	AwayTeam   string `json:"away_team"`
 	HomeGoals int    `json:"home_goals"`
 	AwayGoals int    `json:"away_goals"`
 	Played    bool   `json:"played"`
 	Week      int    `json:"week"`
+	Postponed bool   `json:"postponed,omitempty"`
+	Kickoff   string `json:"kickoff,omitempty"`
+	// Live matches carry the score so far and the minute it was entered at
+	Live   bool   `json:"live,omitempty"`
+	Minute int    `json:"minute,omitempty"`
+	Status string `json:"status"`
+	// Administrative flags a match decided off the pitch, see administrative.go
+	Administrative string `json:"administrative,omitempty"`
+}
+
+// matchSelect is the query scanMatch expects; filters go after it with the
+// matches table aliased as m
+const matchSelect = `
+	SELECT m.id, m.home_team_id, h.name, m.away_team_id, a.name, m.home_goals, m.away_goals,
+		m.played, m.week, m.postponed, COALESCE(m.kickoff, ''), m.live, m.minute, COALESCE(m.administrative, '')
+	FROM matches m
+	JOIN teams h ON h.id = m.home_team_id
+	JOIN teams a ON a.id = m.away_team_id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMatch(row rowScanner) (Match, error) {
+	var m Match
+	err := row.Scan(&m.ID, &m.HomeTeamID, &m.HomeTeam, &m.AwayTeamID, &m.AwayTeam, &m.HomeGoals, &m.AwayGoals,
+		&m.Played, &m.Week, &m.Postponed, &m.Kickoff, &m.Live, &m.Minute, &m.Administrative)
+	m.Status = matchStatus(m)
+	return m, err
 }
 
-// Standing struct remains the same
+// Standing is one row of a table. Tables are ordered as in Sport.sortStandings;
+// Rank is the 1-based position in that order, shared by exactly tied teams
+// when the config sets shared_ranks. Zone and Form are only filled in for the
+// league table: the config zone the position falls in, and the last results
+// as W, D or L, oldest first.
 type Standing struct {
+	Rank           int    `json:"rank"`
+	TeamID         int    `json:"team_id"`
 	TeamName       string `json:"team_name"`
 	Played         int    `json:"played"`
 	Wins           int    `json:"wins"`
@@ -49,20 +102,133 @@ type Standing struct {
 	GoalsAgainst   int    `json:"goals_against"`
 	GoalDifference int    `json:"goal_difference"`
 	Points         int    `json:"points"`
+	Zone           string `json:"zone,omitempty"`
+	// Place is a promotion, relegation or playoff place between divisions
+	Place string `json:"place,omitempty"`
+	Form  string `json:"form"`
 }
 
 type League struct {
 	db     *sql.DB
+	// teams mirrors the teams table, guarded by teamsMu since renames reload it
+	teamsMu sync.RWMutex
 	teams  []Team
 	weeks  int
+	rng    *rand.Rand
+	// flavor drives things that never change a result (events, commentary)
+	// so adding them does not shift the simulated scores
+	flavor *rand.Rand
+	// predict drives predictions, which change nothing either, so polling
+	// them between weeks does not shift the results rng deals out
+	predict *rand.Rand
+	// seed is what rng and flavor were last seeded with, nil for sources
+	// a seed cannot reproduce
+	seed atomic.Pointer[int64]
+	// cfg is swapped as a whole when the config file is reloaded
+	cfg        atomic.Pointer[Config]
+	configFile string
+	// baseConfig is what the config file is loaded over, guarded by
+	// configMu since stored rules can change it
+	configMu   sync.Mutex
+	baseConfig Config
+	// version goes up whenever a result changes, see touch
+	version atomic.Int64
+	// the latest Monte Carlo prediction, valid for one version and config
+	predictionMu sync.Mutex
+	prediction   *cachedPrediction
+	// its runs, carried over to the next one, see warmstart.go
+	warm *warmRuns
+	// background simulation jobs by id
+	jobsMu    sync.Mutex
+	jobs      map[int]*Job
+	nextJobID int
+	metrics   simulationMetrics
+	// in-memory copy of the matches table, see readmodel.go
+	mirrorMu sync.Mutex
+	mirror   atomic.Pointer[[]Match]
+	// clock is the league's time, the virtual clock in clock mode
+	clock Clock
+	// virtual plays matches on a virtual calendar, nil unless in clock mode
+	virtual *virtualClock
+	// requests per API token in the current minute
+	limiter rateLimiter
+	// frozen is set while an admin holds the league still, see freeze.go
+	frozen atomic.Pointer[FreezeState]
+	// requests per client and endpoint over the last hour
+	usage usageTracker
+	// signs the season certificates, nil leaves them out
+	signingKey ed25519.PrivateKey
+	// bearer token for admin operations, empty disables them
+	adminToken string
+	// hot queries found scanning large tables at startup, see queryplan.go
+	planProblems []QueryPlanProblem
+	// outboxWake tells the outbox worker there is something to deliver
+	outboxWake chan struct{}
+	// leagues are the other leagues of the server, see leagues.go; nil in
+	// those leagues themselves
+	leagues *leagueRegistry
+	// parent is the first league in the others, nil in the first, and id
+	// the others' id under /leagues
+	parent *League
+	id     int
+	// playoffMu keeps the two divisions from both starting the relegation
+	// playoff, see playoff.go
+	playoffMu sync.Mutex
 }
 
-func NewLeague(db *sql.DB, teams []Team, totalWeeks int) *League {
-	return &League{
-		db:     db,
-		teams:  teams,
-		weeks:  totalWeeks,
+// NewLeague sets up a league over db. Its random streams start from seed,
+// or from the time when seed is nil; either way /config reports the seed, so
+// the run can be replayed. A simulator, when not nil, draws every score in
+// place of the model the config names; it outlasts config reloads, stored
+// rules and presets, which only ever name a model.
+func NewLeague(db *sql.DB, teams []Team, totalWeeks int, seed *int64, simulator MatchSimulator) *League {
+	l := &League{
+		db:         db,
+		teams:      teams,
+		weeks:      totalWeeks,
+		baseConfig: defaultConfig(),
+		jobs:       make(map[int]*Job),
+		clock:      systemClock{},
+		outboxWake: make(chan struct{}, 1),
+		leagues:    newLeagueRegistry(),
+	}
+	start := time.Now().UnixNano()
+	if seed != nil {
+		start = *seed
 	}
+	l.SetRandSources(rand.NewSource(start), rand.NewSource(start+1))
+	l.predict.Seed(start + 2)
+	l.seed.Store(&start)
+	l.baseConfig.Simulation.Simulator = simulator
+	cfg := l.baseConfig
+	l.cfg.Store(&cfg)
+	return l
+}
+
+// lockedSource makes a rand.Source safe to share between HTTP handlers
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// Seed resets the random generator so the same seed replays the same season
+func (l *League) Seed(seed int64) {
+	l.rng.Seed(seed)
+	l.flavor.Seed(seed + 1)
+	l.predict.Seed(seed + 2)
+	l.seed.Store(&seed)
 }
 
 func (l *League) InitDatabase() error {
@@ -70,37 +236,191 @@ func (l *League) InitDatabase() error {
 	CREATE TABLE IF NOT EXISTS teams (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		name TEXT UNIQUE,
-		strength INTEGER
-	);`
-
-	createMatches := `
-	CREATE TABLE IF NOT EXISTS matches (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		home_team TEXT,
-		away_team TEXT,
-		home_goals INTEGER DEFAULT 0,
-		away_goals INTEGER DEFAULT 0,
-		played BOOLEAN DEFAULT FALSE,
-		week INTEGER,
-		FOREIGN KEY (home_team) REFERENCES teams(name),
-		FOREIGN KEY (away_team) REFERENCES teams(name)
+		strength INTEGER,
+		metadata TEXT,
+		active BOOLEAN NOT NULL DEFAULT TRUE
 	);`
 
 	if _, err := l.db.Exec(createTeams); err != nil {
 		return fmt.Errorf("error creating teams table: %v", err)
 	}
 
-	if _, err := l.db.Exec(createMatches); err != nil {
+	// databases created by older versions
+	if err := l.addColumnIfMissing("teams", "metadata", "TEXT"); err != nil {
+		return err
+	}
+	if err := l.addColumnIfMissing("teams", "active", "BOOLEAN NOT NULL DEFAULT TRUE"); err != nil {
+		return err
+	}
+	if err := l.addColumnIfMissing("teams", "popularity", fmt.Sprintf("REAL NOT NULL DEFAULT %g", basePopularity)); err != nil {
+		return err
+	}
+	if err := l.migrateToTeamIDs(); err != nil {
+		return fmt.Errorf("error migrating matches to team ids: %v", err)
+	}
+
+	if _, err := l.db.Exec(matchesTable("matches")); err != nil {
 		return fmt.Errorf("error creating matches table: %v", err)
 	}
 
-	for _, team := range l.teams {
-		_, err := l.db.Exec("INSERT OR IGNORE INTO teams (name, strength) VALUES (?, ?)", 
-			team.Name, team.Strength)
-		if err != nil {
-			return fmt.Errorf("error inserting team: %v", err)
+	if _, err := l.db.Exec(matchEventsTable("match_events")); err != nil {
+		return fmt.Errorf("error creating match_events table: %v", err)
+	}
+	if err := l.addColumnIfMissing("matches", "live", "BOOLEAN DEFAULT FALSE"); err != nil {
+		return err
+	}
+	if err := l.addColumnIfMissing("matches", "minute", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := l.addColumnIfMissing("matches", "administrative", "TEXT"); err != nil {
+		return err
+	}
+
+	if err := l.createArchiveTables(); err != nil {
+		return err
+	}
+
+	// Teams are only seeded into an empty database, afterwards the database wins
+	var teamCount int
+	if err := l.db.QueryRow("SELECT COUNT(*) FROM teams").Scan(&teamCount); err != nil {
+		return fmt.Errorf("error checking teams count: %v", err)
+	}
+	if teamCount == 0 {
+		for _, team := range l.teams {
+			metadata, err := json.Marshal(team.Metadata)
+			if err != nil {
+				return fmt.Errorf("error encoding team metadata: %v", err)
+			}
+			_, err = l.db.Exec("INSERT INTO teams (name, strength, metadata) VALUES (?, ?, ?)",
+				team.Name, team.Strength, string(metadata))
+			if err != nil {
+				return fmt.Errorf("error inserting team: %v", err)
+			}
 		}
 	}
+	if err := l.createAliasTable(); err != nil {
+		return err
+	}
+
+	if err := l.createUserTable(); err != nil {
+		return err
+	}
+
+	if err := l.createHandicapTable(); err != nil {
+		return err
+	}
+
+	if err := l.createAnnouncementTable(); err != nil {
+		return err
+	}
+
+	if err := l.createScriptTable(); err != nil {
+		return err
+	}
+
+	if err := l.createPresetTable(); err != nil {
+		return err
+	}
+
+	if err := l.createPlayerTable(); err != nil {
+		return err
+	}
+
+	if err := l.createManagerTable(); err != nil {
+		return err
+	}
+
+	if err := l.createPredictionGameTable(); err != nil {
+		return err
+	}
+
+	if err := l.createAdministrativeTable(); err != nil {
+		return err
+	}
+
+	if err := l.createStorylineTable(); err != nil {
+		return err
+	}
+
+	if err := l.createCalendarTokenTable(); err != nil {
+		return err
+	}
+
+	if err := l.createAPITokenTable(); err != nil {
+		return err
+	}
+
+	if err := l.createMatchProbabilityTable(); err != nil {
+		return err
+	}
+
+	if err := l.createMultiverseTables(); err != nil {
+		return err
+	}
+
+	if err := l.createStrengthChangeTable(); err != nil {
+		return err
+	}
+
+	if err := l.createCertificateTable(); err != nil {
+		return err
+	}
+
+	if err := l.createRatingTable(); err != nil {
+		return err
+	}
+
+	if err := l.createSettingsTable(); err != nil {
+		return err
+	}
+
+	if err := l.createOutboxTable(); err != nil {
+		return err
+	}
+
+	if err := l.createSeasonsTable(); err != nil {
+		return err
+	}
+
+	if err := l.createLeaguesTable(); err != nil {
+		return err
+	}
+
+	if err := l.createCupTable(); err != nil {
+		return err
+	}
+
+	if err := l.createPlayoffsTable(); err != nil {
+		return err
+	}
+
+	if err := l.createChampionsTable(); err != nil {
+		return err
+	}
+
+	if err := l.createIndexes(); err != nil {
+		return err
+	}
+
+	if err := l.loadTeams(); err != nil {
+		return fmt.Errorf("error loading teams: %v", err)
+	}
+
+	if err := l.loadLeagueRules(); err != nil {
+		return err
+	}
+
+	if err := l.loadDivisions(); err != nil {
+		return err
+	}
+
+	if err := l.loadFreeze(); err != nil {
+		return err
+	}
+
+	if err := l.appointManagers(); err != nil {
+		return err
+	}
 
 	var count int
 	err := l.db.QueryRow("SELECT COUNT(*) FROM matches").Scan(&count)
@@ -108,8 +428,13 @@ func (l *League) InitDatabase() error {
 		return fmt.Errorf("error checking matches count: %v", err)
 	}
 
-	if count == 0 {
-		if err := l.GenerateFixture(); err != nil {
+	// a planned season waits for its start
+	current, err := currentSeason(l.db)
+	if err != nil {
+		return err
+	}
+	if count == 0 && current.Status != SeasonPlanned {
+		if err := l.GenerateFixture(false); err != nil {
 			return fmt.Errorf("error generating fixture: %v", err)
 		}
 	}
@@ -117,58 +442,220 @@ func (l *League) InitDatabase() error {
 	return nil
 }
 
-func (l *League) GenerateFixture() error {
-	if _, err := l.db.Exec("DELETE FROM matches"); err != nil {
+// loadTeams refreshes the in-memory team list from the database. Teams that
+// went to another division stay in the table for their old matches but are
+// no longer loaded.
+func (l *League) loadTeams() error {
+	rows, err := l.db.Query("SELECT id, name, strength, metadata FROM teams WHERE active = TRUE ORDER BY id")
+	if err != nil {
 		return err
 	}
+	defer rows.Close()
 
-	var matches []Match
-	teamCount := len(l.teams)
-	//totalMatches := teamCount * (teamCount - 1)
-	//matchesPerWeek := totalMatches / l.weeks
-
-	for i := 0; i < teamCount; i++ {
-		for j := 0; j < teamCount; j++ {
-			if i != j {
-				week := (i + j) % l.weeks
-				if week == 0 {
-					week = l.weeks
-				}
-				matches = append(matches, Match{
-					HomeTeam: l.teams[i].Name,
-					AwayTeam: l.teams[j].Name,
-					Week:     week,
-				})
+	var teams []Team
+	for rows.Next() {
+		var team Team
+		var metadata sql.NullString
+		if err := rows.Scan(&team.ID, &team.Name, &team.Strength, &metadata); err != nil {
+			return err
+		}
+		if metadata.Valid && metadata.String != "" {
+			if err := json.Unmarshal([]byte(metadata.String), &team.Metadata); err != nil {
+				return fmt.Errorf("invalid metadata for team %s: %v", team.Name, err)
 			}
 		}
+		teams = append(teams, team)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	l.teamsMu.Lock()
+	l.teams = teams
+	l.teamsMu.Unlock()
+	return nil
+}
+
+// allTeamNames maps the id of every team ever in the league to its name,
+// inactive ones included
+func (l *League) allTeamNames() (map[int]string, error) {
+	rows, err := l.db.Query("SELECT id, name FROM teams")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
+
+// Teams returns a copy of the current team list
+func (l *League) Teams() []Team {
+	l.teamsMu.RLock()
+	defer l.teamsMu.RUnlock()
+	return append([]Team(nil), l.teams...)
+}
+
+// strengths maps team ids to strengths from the in-memory list, so a week
+// does not look every team up again
+func (l *League) strengths() map[int]int {
+	l.teamsMu.RLock()
+	defer l.teamsMu.RUnlock()
+	strengths := make(map[int]int, len(l.teams))
+	for _, t := range l.teams {
+		strengths[t.ID] = t.Strength
+	}
+	return strengths
+}
+
+// addColumnIfMissing upgrades tables created by older versions
+func (l *League) addColumnIfMissing(table, column, definition string) error {
+	exists, err := l.hasColumn(table, column)
+	if err != nil || exists {
+		return err
+	}
+
+	if _, err := l.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("error adding %s.%s: %v", table, column, err)
 	}
+	return nil
+}
+
+// GenerateFixture replaces the schedule with a fresh one. Once a match has been
+// played it refuses with ErrSeasonStarted unless force is set, and the old
+// matches are copied to the archive before they are removed. A planned
+// season gets its fixture when it is started instead.
+func (l *League) GenerateFixture(force bool) error {
 	tx, err := l.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	current, err := currentSeason(tx)
+	if err != nil {
+		return err
+	}
+	if current.Status == SeasonPlanned {
+		return fmt.Errorf("%w: start season %d instead", ErrSeasonPlanned, current.ID)
+	}
+	if err := l.generateFixture(tx, force); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.touch()
+	return nil
+}
+
+// generateFixture is GenerateFixture inside tx
+func (l *League) generateFixture(tx *sql.Tx, force bool) error {
+	if err := clearFixture(tx, force, "fixture regenerated"); err != nil {
+		return err
+	}
+
+	teams := l.Teams()
+	teamIDs := make([]int, len(teams))
+	for i, t := range teams {
+		teamIDs[i] = t.ID
+	}
+	matches := scheduleFixture(teamIDs)
+
 	for _, match := range matches {
 		_, err := tx.Exec(
-			`INSERT INTO matches (home_team, away_team, week) VALUES (?, ?, ?)`,
-			match.HomeTeam, match.AwayTeam, match.Week,
+			`INSERT INTO matches (home_team_id, away_team_id, week) VALUES (?, ?, ?)`,
+			match.HomeTeamID, match.AwayTeamID, match.Week,
 		)
 		if err != nil {
 			return err
 		}
 	}
+	return nil
+}
+
+// clearFixture makes way for a new fixture: the matches go to the archive
+// under reason and the season is cleared. Once a match has been played it
+// refuses with ErrSeasonStarted unless force is set.
+func clearFixture(tx *sql.Tx, force bool, reason string) error {
+	var played int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM matches WHERE played = TRUE").Scan(&played); err != nil {
+		return err
+	}
+	if played > 0 && !force {
+		return ErrSeasonStarted
+	}
 
-	return tx.Commit()
+	var existing int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM matches").Scan(&existing); err != nil {
+		return err
+	}
+	if existing > 0 {
+		if _, err := archiveFixture(tx, reason); err != nil {
+			return fmt.Errorf("error archiving fixture: %v", err)
+		}
+	}
+	return clearSeason(tx)
+}
+
+// clearSeason removes the matches and everything that only holds for them
+func clearSeason(tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM matches"); err != nil {
+		return err
+	}
+	// a new fixture is a new season, its clinches get announced again
+	if _, err := tx.Exec("DELETE FROM announcements"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM storylines"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM match_probabilities"); err != nil {
+		return err
+	}
+	// ratings start over from the strengths the new season begins with
+	if _, err := tx.Exec("DELETE FROM rating_updates"); err != nil {
+		return err
+	}
+	return nil
 }
 
 func (l *League) SimulateWeek(week int) error {
+	return l.simulateWeek(week, nil, l.rng, l.flavor)
+}
+
+// SimulateWeekSeeded plays the week from streams of its own, so the same
+// seed gives the same results whatever was drawn before
+func (l *League) SimulateWeekSeeded(week int, seed int64) error {
+	rng, flavor := l.seededStreams(&seed)
+	return l.simulateWeek(week, nil, rng, flavor)
+}
+
+// simulateWeek plays the week's open matches, only those due says yes to when
+// it is set, drawing scores from rng and events from flavor
+func (l *League) simulateWeek(week int, due func(Match) bool, rng, flavor *rand.Rand) error {
+	if l.config().Live {
+		return ErrLiveMode
+	}
+	if f := l.frozen.Load(); f != nil {
+		return frozenError(f)
+	}
+
 	tx, err := l.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	rows, err := tx.Query("SELECT id, home_team, away_team FROM matches WHERE week = ? AND played = FALSE", week)
+	rows, err := tx.Query(matchSelect+" WHERE m.week = ? AND m.played = FALSE AND m.postponed = FALSE AND m.live = FALSE ORDER BY m.id", week)
 	if err != nil {
 		return err
 	}
@@ -176,30 +663,81 @@ func (l *League) SimulateWeek(week int) error {
 
 	var matches []Match
 	for rows.Next() {
-		var m Match
-		if err := rows.Scan(&m.ID, &m.HomeTeam, &m.AwayTeam); err != nil {
+		m, err := scanMatch(rows)
+		if err != nil {
 			return err
 		}
+		if due != nil && !due(m) {
+			continue
+		}
 		matches = append(matches, m)
 	}
 
-	for _, match := range matches {
-		// team strengths
-		var homeStrength, awayStrength int
-		err := tx.QueryRow("SELECT strength FROM teams WHERE name = ?", match.HomeTeam).Scan(&homeStrength)
-		if err != nil {
+	// the whole week is played with the same settings even if a reload happens
+	cfg := l.config()
+
+	scripts, err := weekScripts(tx, week)
+	if err != nil {
+		return err
+	}
+
+	var fans *popularityReplay
+	if cfg.Pressure {
+		if fans, err = l.popularity(); err != nil {
 			return err
 		}
-		err = tx.QueryRow("SELECT strength FROM teams WHERE name = ?", match.AwayTeam).Scan(&awayStrength)
-		if err != nil {
+	}
+	advantages, err := l.homeAdvantages()
+	if err != nil {
+		return err
+	}
+	var managers map[int]Manager
+	if cfg.Managers {
+		if managers, err = currentManagers(tx); err != nil {
 			return err
 		}
+	}
+
+	strengths := l.strengths()
+	overtime := 0
+	for i, match := range matches {
+		homeStrength, awayStrength := strengths[match.HomeTeamID], strengths[match.AwayTeamID]
+		if fans != nil {
+			home, away := fans.current[match.HomeTeamID], fans.current[match.AwayTeamID]
+			if isBigMatch(home, away) {
+				homeStrength -= pressure(home)
+				awayStrength -= pressure(away)
+			}
+		}
+		if trip := cfg.Travel.trip(match.HomeTeam, match.AwayTeam); trip != nil {
+			awayStrength -= trip.Fatigue
+		}
+		if m, ok := managers[match.HomeTeamID]; ok {
+			homeStrength += m.strengthModifier(week)
+		}
+		if m, ok := managers[match.AwayTeamID]; ok {
+			awayStrength += m.strengthModifier(week)
+		}
 
-		// Simulate match with home advantage (+10)
-		homeAdvantage := 10
-		match.HomeGoals = rand.Intn((homeStrength+homeAdvantage)/20 + 1)
-		match.AwayGoals = rand.Intn(awayStrength/20 + 1)
+		engine := matchengine.Engine{
+			Params:       advantages.params(cfg.Simulation, match.HomeTeam),
+			Minutes:      cfg.Sport.MatchMinutes,
+			VARFrequency: cfg.VARFrequency,
+			Rand:         rng,
+			Flavor:       flavor,
+		}
+		if script, ok := scripts[match.ID]; ok {
+			engine.Script = func(homeGoals, awayGoals int) (int, int) {
+				return script.apply(homeGoals, awayGoals, engine.Params.Overtime)
+			}
+		}
+		result := engine.Play(homeStrength, awayStrength)
+		match.HomeGoals, match.AwayGoals = result.HomeGoals, result.AwayGoals
 		match.Played = true
+		matches[i] = match
+		if result.Overtime {
+			overtime++
+		}
 
 		// Update match in database
 		_, err = tx.Exec(
@@ -209,87 +747,192 @@ func (l *League) SimulateWeek(week int) error {
 		if err != nil {
 			return err
 		}
+
+		var events []MatchEvent
+		if cfg.Sport.TrackEvents {
+			events = matchEvents(match, result.Events)
+		}
+		if err := saveMatchEvents(tx, match, events); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.touch()
+	l.metrics.observe(cfg, matches, overtime)
+	if len(matches) > 0 {
+		if cfg.DynamicStrength.Enabled {
+			if err := l.updateRatings(week, matches, cfg); err != nil {
+				fmt.Println("Rating update failed:", err)
+			}
+		}
+		l.afterResult()
+		if cfg.Managers {
+			if err := l.reviewManagers(week); err != nil {
+				fmt.Println("Manager review failed:", err)
+			}
+		}
 	}
+	return nil
+}
 
-	return tx.Commit()
+// SimulateAll plays every remaining week in order
+func (l *League) SimulateAll() error {
+	for week := 1; week <= l.weeks; week++ {
+		if err := l.SimulateWeek(week); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (l *League) CalculateStandings() ([]Standing, error) {
-	// all teams
-	rows, err := l.db.Query("SELECT name FROM teams")
+	return l.calculateStandings(false)
+}
+
+// LiveStandings also counts the matches in progress at their current score
+func (l *League) LiveStandings() ([]Standing, error) {
+	return l.calculateStandings(true)
+}
+
+func (l *League) calculateStandings(includeLive bool) ([]Standing, error) {
+	matches, err := l.Matches()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return l.standingsFrom(matches, includeLive), nil
+}
 
-	standingsMap := make(map[string]*Standing)
-	for rows.Next() {
-		var name string
-		if err := rows.Scan(&name); err != nil {
-			return nil, err
-		}
-		standingsMap[name] = &Standing{TeamName: name}
+// standingsFrom builds the table out of the given matches only
+func (l *League) standingsFrom(matches []Match, includeLive bool) []Standing {
+	standingsMap := make(map[int]*Standing)
+	for _, t := range l.Teams() {
+		standingsMap[t.ID] = &Standing{TeamID: t.ID, TeamName: t.Name}
 	}
 
 	// all played matches
-	matchRows, err := l.db.Query("SELECT home_team, away_team, home_goals, away_goals FROM matches WHERE played = TRUE")
-	if err != nil {
-		return nil, err
-	}
-	defer matchRows.Close()
-
-	for matchRows.Next() {
-		var homeTeam, awayTeam string
-		var homeGoals, awayGoals int
-		if err := matchRows.Scan(&homeTeam, &awayTeam, &homeGoals, &awayGoals); err != nil {
-			return nil, err
+	cfg := l.config()
+	var played []Match
+	for _, m := range matches {
+		// an annulled match counts for nothing
+		if m.Administrative == AdminAnnulled {
+			continue
+		}
+		// and neither does one against a team that has left the league
+		if standingsMap[m.HomeTeamID] == nil || standingsMap[m.AwayTeamID] == nil {
+			continue
 		}
+		if m.Played || (includeLive && m.Live) {
+			cfg.Sport.recordResult(standingsMap[m.HomeTeamID], standingsMap[m.AwayTeamID], m.Week, m.HomeGoals, m.AwayGoals)
+		}
+		if m.Played {
+			played = append(played, m)
+		}
+	}
 
-		home := standingsMap[homeTeam]
-		away := standingsMap[awayTeam]
+	// form goes by when matches were played, not by id
+	sort.SliceStable(played, func(i, j int) bool {
+		return played[i].Week < played[j].Week
+	})
+	for _, m := range played {
+		home, away := standingsMap[m.HomeTeamID], standingsMap[m.AwayTeamID]
+		home.Form = addForm(home.Form, m.HomeGoals, m.AwayGoals)
+		away.Form = addForm(away.Form, m.AwayGoals, m.HomeGoals)
+	}
 
-		home.Played++
-		away.Played++
+	var standings []Standing
+	for _, s := range standingsMap {
+		s.GoalDifference = s.GoalsFor - s.GoalsAgainst
+		standings = append(standings, *s)
+	}
 
-		home.GoalsFor += homeGoals
-		home.GoalsAgainst += awayGoals
+	cfg.Sport.sortStandings(standings)
+	cfg.Sport.rankStandings(standings, cfg.SharedRanks)
+	l.placeRows(len(standings), func(i int) *Standing { return &standings[i] })
 
-		away.GoalsFor += awayGoals
-		away.GoalsAgainst += homeGoals
+	return standings
+}
 
-		if homeGoals > awayGoals {
-			home.Wins++
-			home.Points += 3
-			away.Losses++
-		} else if homeGoals < awayGoals {
-			away.Wins++
-			away.Points += 3
-			home.Losses++
-		} else {
-			home.Draws++
-			away.Draws++
-			home.Points++
-			away.Points++
+// placeRows gives the rows of a table in its final order the zone and the
+// division place of their position; row is the Standing of row i. Tables
+// re-sorted from the league table call it again after sorting.
+func (l *League) placeRows(n int, row func(i int) *Standing) {
+	zones := l.config().Zones
+	places := l.divisionPlaces(n)
+	for i := 0; i < n; i++ {
+		s := row(i)
+		s.Zone = ""
+		for _, z := range zones {
+			if i+1 >= z.From && i+1 <= z.To {
+				s.Zone = z.Name
+				break
+			}
 		}
+		s.Place = places[i+1]
 	}
+}
 
-	var standings []Standing
-	for _, s := range standingsMap {
-		s.GoalDifference = s.GoalsFor - s.GoalsAgainst
-		standings = append(standings, *s)
+// formLength is how many results Form shows
+const formLength = 5
+
+// addForm appends a result to a form string, keeping the latest formLength
+func addForm(form string, goalsFor, goalsAgainst int) string {
+	result := "D"
+	switch {
+	case goalsFor > goalsAgainst:
+		result = "W"
+	case goalsFor < goalsAgainst:
+		result = "L"
 	}
+	form += result
+	if len(form) > formLength {
+		form = form[len(form)-formLength:]
+	}
+	return form
+}
 
+// rankStandings numbers a sorted table. With shared set, teams level on
+// every tiebreaker but the name get the same rank and the next one is
+// skipped (1, 2, 2, 4); the name only orders them.
+func (s Sport) rankStandings(standings []Standing, shared bool) {
+	for i := range standings {
+		standings[i].Rank = i + 1
+		if !shared || i == 0 {
+			continue
+		}
+		if s.compareStandings(standings[i-1], standings[i]) == 0 {
+			standings[i].Rank = standings[i-1].Rank
+		}
+	}
+}
+
+// sortStandings orders by the sport's tiebreakers, points first. The team
+// name breaks the remaining ties so the table never depends on map order.
+// GET /rules lists the same order.
+func (s Sport) sortStandings(standings []Standing) {
 	sort.SliceStable(standings, func(i, j int) bool {
-		if standings[i].Points == standings[j].Points {
-			return standings[i].GoalDifference > standings[j].GoalDifference
+		a, b := standings[i], standings[j]
+		if c := s.compareStandings(a, b); c != 0 {
+			return c < 0
 		}
-		return standings[i].Points > standings[j].Points
+		return a.TeamName < b.TeamName
 	})
-
-	return standings, nil
 }
 
 func (l *League) PredictStandings() ([]Standing, error) {
+	return l.predictStandings(l.predict)
+}
+
+// PredictStandingsSeeded predicts from a seed of its own, the same seed
+// giving the same table for the same results
+func (l *League) PredictStandingsSeeded(seed int64) ([]Standing, error) {
+	rng, _ := l.seededStreams(&seed)
+	return l.predictStandings(rng)
+}
+
+func (l *League) predictStandings(rng *rand.Rand) ([]Standing, error) {
 	// Get the current standings
 	currentStandings, err := l.CalculateStandings()
 	if err != nil {
@@ -297,68 +940,50 @@ func (l *League) PredictStandings() ([]Standing, error) {
 	}
 
 	// Get the remaining matches
-	rows, err := l.db.Query("SELECT home_team, away_team FROM matches WHERE played = FALSE")
+	rows, err := l.db.Query("SELECT id, home_team_id, away_team_id, week FROM matches WHERE played = FALSE ORDER BY week, id")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	scripts, err := l.unplayedScripts()
+	if err != nil {
+		return nil, err
+	}
 
 	// I create a map for easier access
-	teamMap := make(map[string]*Standing)
+	teamMap := make(map[int]*Standing)
 	for i := range currentStandings {
-		teamMap[currentStandings[i].TeamName] = &currentStandings[i]
+		teamMap[currentStandings[i].TeamID] = &currentStandings[i]
 	}
 
 	// Simulate remaining matches
+	strengths := l.strengths()
+	advantages, err := l.homeAdvantages()
+	if err != nil {
+		return nil, err
+	}
 	for rows.Next() {
-		var homeTeam, awayTeam string
-		if err := rows.Scan(&homeTeam, &awayTeam); err != nil {
+		var id, homeTeam, awayTeam, week int
+		if err := rows.Scan(&id, &homeTeam, &awayTeam, &week); err != nil {
 			return nil, err
 		}
 
-		// Get team powers
-		var homeStrength, awayStrength int
-		err := l.db.QueryRow("SELECT strength FROM teams WHERE name = ?", homeTeam).Scan(&homeStrength)
-		if err != nil {
-			return nil, err
-		}
-		err = l.db.QueryRow("SELECT strength FROM teams WHERE name = ?", awayTeam).Scan(&awayStrength)
-		if err != nil {
-			return nil, err
+		// a fixture of a team that has left the league counts for nothing
+		home, away := teamMap[homeTeam], teamMap[awayTeam]
+		if home == nil || away == nil {
+			continue
 		}
 
-		// Simulate match with home advantage (+10)
-		homeAdvantage := 10
-		homeGoals := rand.Intn((homeStrength+homeAdvantage)/20 + 1)
-		awayGoals := rand.Intn(awayStrength/20 + 1)
+		cfg := l.config()
+		params := advantages.params(cfg.Simulation, home.TeamName)
+		homeGoals, awayGoals := params.Score(rng, strengths[homeTeam], strengths[awayTeam])
+		homeGoals, awayGoals = scripts[id].apply(homeGoals, awayGoals, params.Overtime)
 
 		// Update predicted standings
-		home := teamMap[homeTeam]
-		away := teamMap[awayTeam]
-
-		home.Played++
-		away.Played++
-
-		home.GoalsFor += homeGoals
-		home.GoalsAgainst += awayGoals
-
-		away.GoalsFor += awayGoals
-		away.GoalsAgainst += homeGoals
-
-		if homeGoals > awayGoals {
-			home.Wins++
-			home.Points += 3
-			away.Losses++
-		} else if homeGoals < awayGoals {
-			away.Wins++
-			away.Points += 3
-			home.Losses++
-		} else {
-			home.Draws++
-			away.Draws++
-			home.Points++
-			away.Points++
-		}
+		cfg.Sport.recordResult(home, away, week, homeGoals, awayGoals)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
 	// Calculate goal differences
@@ -366,108 +991,410 @@ func (l *League) PredictStandings() ([]Standing, error) {
 		currentStandings[i].GoalDifference = currentStandings[i].GoalsFor - currentStandings[i].GoalsAgainst
 	}
 
-	// Sorting
-	sort.SliceStable(currentStandings, func(i, j int) bool {
-		if currentStandings[i].Points == currentStandings[j].Points {
-			return currentStandings[i].GoalDifference > currentStandings[j].GoalDifference
-		}
-		return currentStandings[i].Points > currentStandings[j].Points
-	})
+	// Sorting, and the ranks and zones of the predicted order
+	cfg := l.config()
+	cfg.Sport.sortStandings(currentStandings)
+	cfg.Sport.rankStandings(currentStandings, cfg.SharedRanks)
+	l.placeRows(len(currentStandings), func(i int) *Standing { return &currentStandings[i] })
 
 	return currentStandings, nil
 }
 
-func (l *League) UpdateMatchResult(matchID, homeGoals, awayGoals int) error {
+// UpdateMatchResult enters a final score by hand, optionally with a scorer
+// for every goal
+func (l *League) UpdateMatchResult(matchID, homeGoals, awayGoals int, scorers []Scorer) error {
 	tx, err := l.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	// I get the current result to calculate the difference
-	var currentHomeGoals, currentAwayGoals int
-	var played bool
-	err = tx.QueryRow("SELECT home_goals, away_goals, played FROM matches WHERE id = ?", matchID).
-		Scan(&currentHomeGoals, &currentAwayGoals, &played)
+	previous, match, latestWeek, err := l.applyResult(tx, matchID, homeGoals, awayGoals, scorers)
 	if err != nil {
 		return err
 	}
 
-	// Update the match
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.touch()
+	if retroactive(previous, latestWeek) {
+		l.recalculate(match, &previous)
+	}
+	l.afterResult()
+	return nil
+}
+
+// applyResult writes a manual result inside tx. It returns the match before
+// and after, and the latest week played apart from it, so the caller can
+// tell whether the edit was retroactive once it commits.
+func (l *League) applyResult(tx *sql.Tx, matchID, homeGoals, awayGoals int, scorers []Scorer) (previous, match Match, latestWeek int, err error) {
+	if homeGoals < 0 || awayGoals < 0 {
+		return previous, match, 0, fmt.Errorf("%w: goals cannot be negative", ErrInvalidResult)
+	}
+
+	// the result as it was, and whether later weeks build on it
+	previous, err = scanMatch(tx.QueryRow(matchSelect+" WHERE m.id = ?", matchID))
+	if err != nil {
+		return previous, match, 0, err
+	}
+	err = tx.QueryRow("SELECT COALESCE(MAX(week), 0) FROM matches WHERE played = TRUE AND id != ?", matchID).Scan(&latestWeek)
+	if err != nil {
+		return previous, match, 0, err
+	}
+
+	// Update the match, a result also settles a postponement
 	_, err = tx.Exec(
-		`UPDATE matches SET home_goals = ?, away_goals = ?, played = TRUE WHERE id = ?`,
+		`UPDATE matches SET home_goals = ?, away_goals = ?, played = TRUE, postponed = FALSE, live = FALSE, minute = 0 WHERE id = ?`,
 		homeGoals, awayGoals, matchID,
 	)
 	if err != nil {
-		return err
+		return previous, match, 0, err
 	}
 
-	return tx.Commit()
+	// A manual result has no minute by minute events, only the goals if the
+	// scorers are given
+	match, err = scanMatch(tx.QueryRow(matchSelect+" WHERE m.id = ?", matchID))
+	if err != nil {
+		return previous, match, 0, err
+	}
+	// a goal can come in overtime too
+	cfg := l.config()
+	maxMinute := cfg.Sport.MatchMinutes
+	if cfg.Simulation.Overtime {
+		maxMinute += matchengine.OvertimeMinutes
+	}
+	events, err := scorerEvents(tx, match, scorers, maxMinute)
+	if err != nil {
+		return previous, match, 0, err
+	}
+	if err := saveMatchEvents(tx, match, events); err != nil {
+		return previous, match, 0, err
+	}
+	return previous, match, latestWeek, nil
 }
 
-func main() {
+// Main reads the flags and runs the server, see cmd/insider
+func Main() {
+	teamsFile := flag.String("teams", "", "CSV or JSON file with the teams to create a new league with")
+	normalize := flag.String("normalize-strengths", "", "rescale the strengths in --teams onto the 1-100 scale: linear:MIN-MAX or points:N, as for --elo-scale")
+	eloFile := flag.String("elo", "", "CSV of club Elo ratings to create a new league with, normalized to strengths by --elo-scale")
+	eloScale := flag.String("elo-scale", defaultEloScale, "how --elo ratings map to strengths: linear:MIN-MAX or points:N (N Elo points per strength point)")
+	adminToken := flag.String("admin-token", os.Getenv("LEAGUE_ADMIN_TOKEN"), "token required for admin operations")
+	varFrequency := flag.Float64("var-frequency", defaultVARFrequency, "chance per simulated match of a VAR incident (0 to 1)")
+	configFile := flag.String("config", "", "JSON config file, reloaded on SIGHUP or POST /admin/reload-config")
+	sportName := flag.String("sport", "football", "rules and scoring preset: football, basketball or hockey")
+	model := flag.String("model", "", "score model: uniform (default) or poisson; the config's simulation.model overrides it")
+	live := flag.Bool("live", false, "track a real league: scores are entered by admins instead of simulated")
+	readOnlyMode := flag.Bool("read-only", false, "reject every request that changes the league, for public demos")
+	clockSpeed := flag.Float64("clock-speed", 0, "play matches on a virtual clock running this many times faster than real time (7: a real day is a virtual week)")
+	slowQuery := flag.Duration("slow-query", 100*time.Millisecond, "log statements taking at least this long, 0 to turn the log off")
+	clockStart := flag.String("clock-start", "", "virtual kickoff of week 1 in clock mode, YYYY-MM-DD or RFC 3339 (default now)")
+	randSource := flag.String("rand-source", "", "random source for the simulation: seed:N, crypto or replay:FILE (default seeded from the time)")
+	recordRand := flag.String("record-rand", "", "write every random number drawn to this file, for --rand-source replay:FILE")
+	checkPlans := flag.Bool("check-query-plans", false, "explain the hot queries at startup and report not ready on /readyz if one scans a large table")
+	signingKey := flag.String("signing-key", "league.key", "file with the Ed25519 seed season certificates are signed with, created when missing; empty to sign nothing")
+	generateSquads := flag.Bool("generate-squads", true, "give every team without players a generated squad at startup")
+	largeTable := flag.Int("large-table-rows", 10000, "tables with at least this many rows must not be scanned by hot queries")
+	importDivision := flag.String("import-division", "", "league database to add below the last division at startup, named after the file; skipped once a league has that name")
+	importSlots := flag.Int("import-slots", 1, "teams an --import-division league swaps with the division above it each season")
+	flag.Parse()
+
+	preset, err := lookupSport(*sportName)
+	if err != nil {
+		panic(err)
+	}
+	baseConfig := defaultConfig()
+	baseConfig.Sport = preset.Sport
+	baseConfig.Simulation = preset.Simulation
+	baseConfig.Simulation.Model = *model
+	baseConfig.VARFrequency = *varFrequency
+	baseConfig.Live = *live
+	if err := baseConfig.Validate(); err != nil {
+		panic(err)
+	}
+
 	// Initialize teams
-	teams := []Team{
-		{"Alpha FC", 85},
-		{"Bravo United", 70},
-		{"Charlie Town", 60},
-		{"Delta SC", 50},
+	teams := defaultTeams
+	if *teamsFile != "" {
+		loaded, err := LoadTeams(*teamsFile, *normalize)
+		if err != nil {
+			panic(fmt.Errorf("failed to load teams: %v", err))
+		}
+		teams = loaded
+	}
+	if *eloFile != "" {
+		if *teamsFile != "" {
+			panic(fmt.Errorf("--teams and --elo cannot be used together"))
+		}
+		loaded, err := LoadEloTeams(*eloFile, *eloScale)
+		if err != nil {
+			panic(fmt.Errorf("failed to import Elo ratings: %v", err))
+		}
+		teams = loaded
 	}
 
 	// Open database
-	db, err := sql.Open("sqlite3", "./league.db")
+	// foreign keys are off in SQLite unless every connection turns them on
+	// every statement is timed, see dbstats.go
+	dbStats.SetSlowThreshold(*slowQuery)
+	db, err := sql.Open(DriverName, "./league.db?_foreign_keys=on")
 	if err != nil {
 		panic(fmt.Errorf("failed to open database: %v", err))
 	}
 	defer db.Close()
 
-	// Assume that league with 6 weeks
-	league := NewLeague(db, teams, 6)
+	// Every team plays each other twice, one match per week
+	league := NewLeague(db, teams, FixtureWeeks(len(teams)), nil, nil)
+	league.baseConfig = baseConfig
+	league.cfg.Store(&baseConfig)
+	league.SetAdminToken(*adminToken)
+	if *randSource != "" || *recordRand != "" {
+		rngSource, flavorSource := rand.NewSource(time.Now().UnixNano()), rand.NewSource(time.Now().UnixNano()+1)
+		if *randSource != "" {
+			if rngSource, flavorSource, err = parseRandSource(*randSource); err != nil {
+				panic(fmt.Errorf("failed to set up random source: %v", err))
+			}
+		}
+		if *recordRand != "" {
+			f, err := os.Create(*recordRand)
+			if err != nil {
+				panic(fmt.Errorf("failed to create %s: %v", *recordRand, err))
+			}
+			defer f.Close()
+			rngSource, flavorSource = recordRandSources(rngSource, flavorSource, f)
+		}
+		league.SetRandSources(rngSource, flavorSource)
+		if seed, ok := randSourceSeed(*randSource); ok {
+			league.predict.Seed(seed + 2)
+			league.seed.Store(&seed)
+		}
+	}
+	if *configFile != "" {
+		league.configFile = *configFile
+		if err := league.ReloadConfig(); err != nil {
+			panic(fmt.Errorf("failed to load config: %v", err))
+		}
+	}
+
+	// SIGHUP reloads the config without dropping the listener
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := league.ReloadConfig(); err != nil {
+				fmt.Println("Config reload failed:", err)
+				continue
+			}
+			fmt.Println("Config reloaded")
+		}
+	}()
+	if *signingKey != "" {
+		if league.signingKey, err = loadSigningKey(*signingKey); err != nil {
+			panic(fmt.Errorf("failed to load signing key: %v", err))
+		}
+	}
 	if err := league.InitDatabase(); err != nil {
 		panic(fmt.Errorf("failed to initialize database: %v", err))
 	}
+	if stored := league.Teams(); len(stored) != len(teams) {
+		if *teamsFile != "" {
+			fmt.Printf("Database already has %d teams, %s was not imported\n", len(stored), *teamsFile)
+		}
+		league.weeks = FixtureWeeks(len(stored))
+	}
 
-	// HTTP Handlers
-	http.HandleFunc("/teams", func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(teams)
-	})
+	if *generateSquads {
+		if _, err := league.GenerateSquads(); err != nil {
+			panic(fmt.Errorf("failed to generate squads: %v", err))
+		}
+	}
 
-	http.HandleFunc("/matches", func(w http.ResponseWriter, r *http.Request) {
-		weekStr := r.URL.Query().Get("week")
-		var rows *sql.Rows
-		var err error
+	// the other leagues live in files of their own next to league.db
+	league.leagues.dsn = func(id int) string {
+		return fmt.Sprintf("./league-%d.db?_foreign_keys=on", id)
+	}
+	league.leagues.background = true
+	if err := league.OpenLeagues(); err != nil {
+		panic(fmt.Errorf("failed to open leagues: %v", err))
+	}
+	if *importDivision != "" {
+		if err := league.importDivisionFile(*importDivision, *importSlots); err != nil {
+			panic(fmt.Errorf("failed to import %s: %v", *importDivision, err))
+		}
+	}
+	if *checkPlans {
+		problems, err := league.CheckQueryPlans(*largeTable)
+		if err != nil {
+			panic(fmt.Errorf("failed to check query plans: %v", err))
+		}
+		for _, p := range problems {
+			fmt.Printf("Query plan: %q scans %s (%d rows): %s\n", p.Query, p.Table, p.Rows, strings.Join(p.Plan, "; "))
+		}
+		league.planProblems = problems
+	}
 
-		if weekStr != "" {
-			week, err := strconv.Atoi(weekStr)
+	if *clockSpeed < 0 {
+		panic(fmt.Errorf("--clock-speed cannot be negative"))
+	}
+	if *clockSpeed > 0 {
+		if *live {
+			panic(fmt.Errorf("--clock-speed simulates matches, it cannot be used with --live"))
+		}
+		start := league.clock.Now()
+		if *clockStart != "" {
+			start, err = parseKickoff(*clockStart)
 			if err != nil {
-				http.Error(w, "Invalid week parameter", http.StatusBadRequest)
-				return
+				panic(fmt.Errorf("--clock-start: %v", err))
 			}
-			rows, err = db.Query("SELECT id, home_team, away_team, home_goals, away_goals, played, week FROM matches WHERE week = ?", week)
-		} else {
-			rows, err = db.Query("SELECT id, home_team, away_team, home_goals, away_goals, played, week FROM matches")
+		}
+		league.virtual = newVirtualClock(league.clock, start, *clockSpeed)
+		league.clock = league.virtual
+		go league.runClock()
+		fmt.Printf("Clock mode: virtual time runs %gx from %s\n", *clockSpeed, start.Format(time.RFC3339))
+	}
+
+	go league.runOutbox()
+
+	handler := NewMux(league)
+	if *readOnlyMode {
+		handler = readOnly(handler)
+		fmt.Println("Read-only mode: changes are rejected")
+	}
+
+	fmt.Println("Server running on http://localhost:8080")
+	http.ListenAndServe(":8080", handler)
+}
+
+// newMux routes the HTTP API to the league, behind the API token checks and
+// usage tracking
+func NewMux(league *League) http.Handler {
+	mux := leagueRoutes(league)
+	return league.trackUsage(mux, league.apiTokens(league.freezeGuard(mux)))
+}
+
+// leagueRoutes are the routes of one league, the other leagues of the server
+// get them under /leagues/{id}
+func leagueRoutes(league *League) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/teams", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(league.Teams())
+	})
+
+	mux.HandleFunc("/teams/{name}/fixtures", league.handleTeamFixtures)
+	mux.HandleFunc("/teams/{name}/calendar.ics", league.handleTeamCalendar)
+	mux.HandleFunc("/calendar.ics", league.handleLeagueCalendar)
+	mux.HandleFunc("/calendar/subscriptions", league.handleCreateSubscription)
+	mux.HandleFunc("/calendar/{file}", league.handleSubscriptionCalendar)
+	mux.HandleFunc("/teams/{name}/rename", league.handleRenameTeam)
+	mux.HandleFunc("/teams/{name}/aliases", league.handleTeamAliases)
+	mux.HandleFunc("/teams/{name}/players", league.handleTeamPlayers)
+	mux.HandleFunc("/teams/{name}/players/generate", league.handleGenerateSquad)
+	mux.HandleFunc("/squads/generate", league.handleGenerateSquads)
+	mux.HandleFunc("/teams/{name}/popularity", league.handleTeamPopularity)
+	mux.HandleFunc("/teams/{name}/manager", league.handleTeamManager)
+	mux.HandleFunc("/teams/{name}/strength", league.handleTeamStrength)
+	mux.HandleFunc("/teams/{name}/strength/rollback", league.handleStrengthRollback)
+	mux.HandleFunc("/ratings/history", league.handleRatingHistory)
+
+	mux.HandleFunc("/matches", func(w http.ResponseWriter, r *http.Request) {
+		// ?fields=home_team,away_team keeps only those fields of every match
+		fields, err := parseFields[Match](r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		season, err := seasonParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
+		var all []Match
+		// date ranges go to the kickoff index, everything else to the mirror
+		from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+		switch {
+		case season != nil && (from != "" || to != ""):
+			http.Error(w, "season cannot be combined with from and to", http.StatusBadRequest)
+			return
+		case season != nil:
+			all, err = league.SeasonMatches(*season)
+		case from != "" || to != "":
+			all, err = league.MatchesBetween(from, to)
+		default:
+			all, err = league.Matches()
+		}
+		if err == sql.ErrNoRows {
+			http.Error(w, "Season not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, ErrInvalidDateRange) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		defer rows.Close()
+
+		weekStr := r.URL.Query().Get("week")
+		if weekStr == "" {
+			streamMatches(w, all, fields)
+			return
+		}
+		week, err := strconv.Atoi(weekStr)
+		if err != nil {
+			http.Error(w, "Invalid week parameter", http.StatusBadRequest)
+			return
+		}
 
 		var matches []Match
-		for rows.Next() {
-			var m Match
-			if err := rows.Scan(&m.ID, &m.HomeTeam, &m.AwayTeam, &m.HomeGoals, &m.AwayGoals, &m.Played, &m.Week); err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
+		for _, m := range all {
+			if m.Week == week {
+				matches = append(matches, m)
 			}
-			matches = append(matches, m)
 		}
-
-		json.NewEncoder(w).Encode(matches)
+		streamMatches(w, matches, fields)
 	})
 
-	http.HandleFunc("/simulate/week/", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/matches/by-week", league.handleMatchesByWeek)
+	mux.HandleFunc("/weeks", league.handleWeeks)
+	mux.HandleFunc("/weeks/{n}/diff", league.handleWeekDiff)
+	mux.HandleFunc("/surprises", league.handleSurprises)
+	mux.HandleFunc("/multiverse", league.handleMultiverses)
+	mux.HandleFunc("/multiverse/{id}", league.handleMultiverse)
+	mux.HandleFunc("/multiverse/{id}/universes/{n}", league.handleUniverse)
+	mux.HandleFunc("/matches/today", league.handleMatchesToday)
+	mux.HandleFunc("/matches/{id}", league.handleMatchDetail)
+	mux.HandleFunc("/matches/{id}/postpone", league.handlePostpone)
+	mux.HandleFunc("/matches/{id}/reschedule", league.handleReschedule)
+	mux.HandleFunc("/matches/{id}/simulate", league.handleSimulateMatch)
+	mux.HandleFunc("/matches/{id}/live", league.handleLiveScore)
+	mux.HandleFunc("/matches/{id}/script", league.handleMatchScript)
+	mux.HandleFunc("/matches/{id}/administrative", league.handleMatchDecisions)
+	mux.HandleFunc("/administrative", league.handleDecisions)
+
+	mux.HandleFunc("/fixture", league.handleFixture)
+	mux.HandleFunc("/fixture/generate", league.handleGenerateFixture)
+	mux.HandleFunc("/fixture/validate", league.handleValidateFixture)
+	mux.HandleFunc("/leagues/validate", handleValidateProposal)
+	mux.HandleFunc("/leagues", league.handleLeagues)
+	mux.HandleFunc("/leagues/{id}/", league.handleOtherLeague)
+	mux.HandleFunc("/admin/reload-config", league.handleReloadConfig)
+	mux.HandleFunc("/config", league.handleConfig)
+	mux.HandleFunc("/settings/rules", league.handleLeagueRules)
+	mux.HandleFunc("/admin/clock", league.handleClock)
+	mux.HandleFunc("/admin/freeze", league.handleFreeze)
+	mux.HandleFunc("/admin/db-stats", handleDBStats)
+	mux.HandleFunc("/admin/tokens", league.handleAPITokens)
+	mux.HandleFunc("/admin/usage", league.handleUsage)
+	mux.HandleFunc("/admin/tokens/{id}", league.handleAPIToken)
+	mux.HandleFunc("/admin/outbox", league.handleOutbox)
+	mux.HandleFunc("/admin/outbox/{id}/requeue", league.handleRequeueDelivery)
+
+	mux.HandleFunc("/simulate/week/", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -480,7 +1407,25 @@ func main() {
 			return
 		}
 
-		if err := league.SimulateWeek(week); err != nil {
+		seed, err := seedParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if seed != nil {
+			err = league.SimulateWeekSeeded(week, *seed)
+		} else {
+			err = league.SimulateWeek(week)
+		}
+		if err != nil {
+			if errors.Is(err, ErrLiveMode) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if errors.Is(err, ErrFrozen) {
+				http.Error(w, err.Error(), http.StatusLocked)
+				return
+			}
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -488,50 +1433,189 @@ func main() {
 		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Week %d simulated successfully", week)})
 	})
 
-	http.HandleFunc("/simulate/all", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/simulate/all", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		for week := 1; week <= league.weeks; week++ {
-			if err := league.SimulateWeek(week); err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err := league.SimulateAll(); err != nil {
+			if errors.Is(err, ErrLiveMode) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if errors.Is(err, ErrFrozen) {
+				http.Error(w, err.Error(), http.StatusLocked)
 				return
 			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
 		json.NewEncoder(w).Encode(map[string]string{"message": "All weeks simulated successfully"})
 	})
 
-	http.HandleFunc("/standings", func(w http.ResponseWriter, r *http.Request) {
-		standings, err := league.CalculateStandings()
+	mux.HandleFunc("/simulate/until-decided", league.handleSimulateUntilDecided)
+
+	// every table takes ?fields=team_name,points to keep only those fields
+	mux.HandleFunc("/standings", func(w http.ResponseWriter, r *http.Request) {
+		// ?season=3 is the final table of that season, the current one by
+		// default
+		season, err := seasonParam(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if season != nil {
+			standings, err := league.SeasonStandings(*season)
+			if err == sql.ErrNoRows {
+				http.Error(w, "Season not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeFields(w, r, standings)
 			return
 		}
-		json.NewEncoder(w).Encode(standings)
-	})
 
-	http.HandleFunc("/predict", func(w http.ResponseWriter, r *http.Request) {
-		standings, err := league.PredictStandings()
+		// ?adjusted=true corrects points for the opponents faced so far
+		if r.URL.Query().Get("adjusted") == "true" {
+			table, err := league.AdjustedStandings()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeFields(w, r, table)
+			return
+		}
+
+		// ?handicap=true adds the handicap points set before the season
+		if r.URL.Query().Get("handicap") == "true" {
+			table, err := league.HandicapStandings()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeFields(w, r, table)
+			return
+		}
+
+		// ?on=2025-12-25 is the table after the matches played up to that day
+		if on := r.URL.Query().Get("on"); on != "" {
+			standings, err := league.StandingsOn(on)
+			if errors.Is(err, ErrInvalidDateRange) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeFields(w, r, standings)
+			return
+		}
+
+		// ?half=1 or ?half=2 is the table of that half of the fixture alone
+		if half := r.URL.Query().Get("half"); half != "" {
+			// anything but a number is 0, which is refused as well
+			n, _ := strconv.Atoi(half)
+			standings, err := league.HalfStandings(n)
+			if errors.Is(err, ErrInvalidHalf) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeFields(w, r, standings)
+			return
+		}
+
+		calculate := league.CalculateStandings
+		// ?live=true counts matches in progress at their current score
+		if r.URL.Query().Get("live") == "true" {
+			calculate = league.LiveStandings
+		}
+		standings, err := calculate()
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		json.NewEncoder(w).Encode(standings)
+		writeFields(w, r, standings)
 	})
 
-	http.HandleFunc("/match/update", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/handicaps", league.handleHandicaps)
+	mux.HandleFunc("/metrics", league.handleMetrics)
+	mux.HandleFunc("/readyz", league.handleReady)
+	mux.HandleFunc("/status", league.handleStatus)
+	mux.HandleFunc("/titlerace", league.handleTitleRace)
+	mux.HandleFunc("/stats/overperformance", league.handleOverperformance)
+	mux.HandleFunc("/charts/points-progression", league.handlePointsProgression)
+	mux.HandleFunc("/news", league.handleNews)
+	mux.HandleFunc("/storylines", league.handleStorylines)
+	mux.HandleFunc("/home-advantages", league.handleHomeAdvantages)
+	mux.HandleFunc("/whatif/requirements", league.handleRequirements)
+	mux.HandleFunc("/seasons", league.handleSeasons)
+	mux.HandleFunc("/seasons/{id}/start", league.handleStartSeason)
+	mux.HandleFunc("/seasons/{id}/awards", league.handleSeasonAwards)
+	mux.HandleFunc("/seasons/{id}/archive.zip", league.handleSeasonArchive)
+	mux.HandleFunc("/seasons/{id}/certificate", league.handleSeasonCertificate)
+	mux.HandleFunc("/certificates/key", league.handleCertificateKey)
+	mux.HandleFunc("/certificates/verify", league.handleVerifyCertificate)
+	mux.HandleFunc("/alltime/table", handleAllTime(league.AllTimeTable))
+	mux.HandleFunc("/alltime/titles", handleAllTime(league.Titles))
+	mux.HandleFunc("/alltime/relegations", handleAllTime(league.Relegations))
+	mux.HandleFunc("/alltime/scorers", handleAllTime(league.AllTimeScorers))
+	mux.HandleFunc("/alltime/coefficients", handleAllTime(league.Coefficients))
+	mux.HandleFunc("/ties/simulate", league.handleSimulateTies)
+	mux.HandleFunc("/cup/bracket", league.handleCupBracket)
+	mux.HandleFunc("/cup/simulate/round/{n}", league.handleSimulateCupRound)
+	mux.HandleFunc("/cup/results", league.handleCupResults)
+	mux.HandleFunc("/playoffs", league.handlePlayoffs)
+	mux.HandleFunc("/champions", league.handleChampions)
+	mux.HandleFunc("/champions/{id}", league.handleChampionsTournament)
+	mux.HandleFunc("/divisions", league.handleDivisions)
+	mux.HandleFunc("/divisions/promote", league.handlePromoteDivisions)
+	mux.HandleFunc("/events/schema", handleEventSchema)
+	mux.HandleFunc("/rules", league.handleRules)
+	mux.HandleFunc("/stats/scorers", league.handleTopScorers)
+	mux.HandleFunc("/presets", league.handlePresets)
+	mux.HandleFunc("/presets/{name}", league.handlePreset)
+	mux.HandleFunc("/presets/{name}/apply", league.handleApplyPreset)
+	mux.HandleFunc("/analysis/compare", league.handleCompareModels)
+
+	mux.HandleFunc("/predict", league.handlePredict)
+	mux.HandleFunc("/predict/hybrid", league.handlePredictHybrid)
+
+	mux.HandleFunc("/users", league.handleCreateUser)
+	mux.HandleFunc("/me", league.handleMe)
+	mux.HandleFunc("/me/favorite", league.handleFavorite)
+	mux.HandleFunc("/me/feed", league.handleFeed)
+	mux.HandleFunc("/me/predictions", league.handleUserPredictions)
+	mux.HandleFunc("/predictions/leaderboard", league.handlePredictorLeaderboard)
+
+	mux.HandleFunc("/jobs", league.handleListJobs)
+	mux.HandleFunc("/jobs/simulate", league.handleStartJob)
+	mux.HandleFunc("/jobs/{id}", league.handleJob)
+	mux.HandleFunc("/jobs/{id}/pause", league.handleJobAction((*Job).Pause))
+	mux.HandleFunc("/jobs/{id}/resume", league.handleJobAction((*Job).Resume))
+	mux.HandleFunc("/jobs/{id}/cancel", league.handleJobAction((*Job).Cancel))
+
+	mux.HandleFunc("/matches/results", league.handleMatchResults)
+	mux.HandleFunc("/match/update", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
 		var match struct {
-			ID        int `json:"id"`
-			HomeGoals int `json:"home_goals"`
-			AwayGoals int `json:"away_goals"`
+			ID        int      `json:"id"`
+			HomeGoals int      `json:"home_goals"`
+			AwayGoals int      `json:"away_goals"`
+			Scorers   []Scorer `json:"scorers"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&match); err != nil {
@@ -539,7 +1623,12 @@ func main() {
 			return
 		}
 
-		if err := league.UpdateMatchResult(match.ID, match.HomeGoals, match.AwayGoals); err != nil {
+		err := league.UpdateMatchResult(match.ID, match.HomeGoals, match.AwayGoals, match.Scorers)
+		if errors.Is(err, ErrInvalidScorers) || errors.Is(err, ErrInvalidResult) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -547,6 +1636,5 @@ func main() {
 		json.NewEncoder(w).Encode(map[string]string{"message": "Match updated successfully"})
 	})
 
-	fmt.Println("Server running on http://localhost:8080")
-	http.ListenAndServe(":8080", nil)
-}
\ No newline at end of file
+	return mux
+}
@@ -0,0 +1,316 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Every team has a manager whose tactic quality, 0 to 10, adds to or takes
+// from the team's strength. With "managers": true in the config a run of
+// results without a win can cost the manager the job; the replacement gets
+// a few weeks of new manager bounce. Without it managers are only for show.
+
+const (
+	// maxTactic is the best tactic quality, the middle of the scale is neutral
+	maxTactic = 10
+	// sackingRun is how many games without a win put a manager at risk
+	sackingRun = 3
+	// sackingChance is the chance the board acts after such a run
+	sackingChance = 0.5
+	// bounce is the strength a new manager adds for bounceWeeks weeks
+	bounce      = 4
+	bounceWeeks = 3
+)
+
+// AnnouncementManagerSacked is the news item of a sacking
+const AnnouncementManagerSacked = "manager_sacked"
+
+var managerFirstNames = []string{"Arne", "Bruno", "Carlo", "Diego", "Erik", "Fabio", "Gerd", "Hans", "Ivan", "Jose", "Kenny", "Luis", "Marco", "Nuno", "Otto", "Pep"}
+var managerLastNames = []string{"Adler", "Berg", "Costa", "Dietz", "Eriksen", "Ferrer", "Graham", "Hoek", "Ivic", "Jansen", "Keller", "Lenz", "Moreno", "Novak", "Olsen", "Pardo"}
+
+// Manager is one spell in charge of a team. SackedWeek is nil while the
+// manager is still there.
+type Manager struct {
+	ID            int    `json:"id"`
+	TeamID        int    `json:"team_id"`
+	TeamName      string `json:"team_name"`
+	Name          string `json:"name"`
+	Tactic        int    `json:"tactic"`
+	AppointedWeek int    `json:"appointed_week"`
+	SackedWeek    *int   `json:"sacked_week,omitempty"`
+}
+
+// TeamManager is GET /teams/{name}/manager
+type TeamManager struct {
+	Team    string   `json:"team"`
+	Manager *Manager `json:"manager"`
+	// StrengthModifier applies to the team's next week with managers on
+	StrengthModifier int       `json:"strength_modifier"`
+	Previous         []Manager `json:"previous"`
+}
+
+func (l *League) createManagerTable() error {
+	createManagers := `
+	CREATE TABLE IF NOT EXISTS managers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		tactic INTEGER NOT NULL,
+		appointed_week INTEGER NOT NULL DEFAULT 0,
+		sacked_week INTEGER
+	);`
+
+	if _, err := l.db.Exec(createManagers); err != nil {
+		return fmt.Errorf("error creating managers table: %v", err)
+	}
+	return nil
+}
+
+// strengthModifier is what the manager adds to the team's strength in week
+func (m Manager) strengthModifier(week int) int {
+	modifier := m.Tactic - maxTactic/2
+	if m.AppointedWeek > 0 && week > m.AppointedWeek && week <= m.AppointedWeek+bounceWeeks {
+		modifier += bounce
+	}
+	return modifier
+}
+
+// firstManager is the manager a team starts with. It comes from the team
+// name rather than an rng so seeding a league never shifts its results.
+func firstManager(team string) (string, int) {
+	h := fnv.New32a()
+	h.Write([]byte(team))
+	n := int(h.Sum32())
+	name := managerFirstNames[n%len(managerFirstNames)] + " " + managerLastNames[n/len(managerFirstNames)%len(managerLastNames)]
+	return name, n / 256 % (maxTactic + 1)
+}
+
+// appointManagers gives every team without a manager its first one
+func (l *League) appointManagers() error {
+	for _, t := range l.Teams() {
+		var current int
+		err := l.db.QueryRow("SELECT COUNT(*) FROM managers WHERE team_id = ? AND sacked_week IS NULL", t.ID).Scan(&current)
+		if err != nil {
+			return err
+		}
+		if current > 0 {
+			continue
+		}
+		name, tactic := firstManager(t.Name)
+		if _, err := l.db.Exec("INSERT INTO managers (team_id, name, tactic) VALUES (?, ?, ?)", t.ID, name, tactic); err != nil {
+			return fmt.Errorf("error appointing manager: %v", err)
+		}
+	}
+	return nil
+}
+
+const managerSelect = `
+	SELECT m.id, m.team_id, t.name, m.name, m.tactic, m.appointed_week, m.sacked_week
+	FROM managers m JOIN teams t ON t.id = m.team_id`
+
+func scanManager(row rowScanner) (Manager, error) {
+	var m Manager
+	var sacked sql.NullInt64
+	if err := row.Scan(&m.ID, &m.TeamID, &m.TeamName, &m.Name, &m.Tactic, &m.AppointedWeek, &sacked); err != nil {
+		return m, err
+	}
+	if sacked.Valid {
+		week := int(sacked.Int64)
+		m.SackedWeek = &week
+	}
+	return m, nil
+}
+
+// currentManagers maps every team to the manager in charge
+func currentManagers(tx *sql.Tx) (map[int]Manager, error) {
+	rows, err := tx.Query(managerSelect + " WHERE m.sacked_week IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	managers := make(map[int]Manager)
+	for rows.Next() {
+		m, err := scanManager(rows)
+		if err != nil {
+			return nil, err
+		}
+		managers[m.TeamID] = m
+	}
+	return managers, rows.Err()
+}
+
+// TeamManager reports a team's manager and the ones before
+func (l *League) TeamManager(teamID int, name string) (*TeamManager, error) {
+	rows, err := l.db.Query(managerSelect+" WHERE m.team_id = ? ORDER BY m.id DESC", teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tm := &TeamManager{Team: name, Previous: []Manager{}}
+	for rows.Next() {
+		m, err := scanManager(rows)
+		if err != nil {
+			return nil, err
+		}
+		if m.SackedWeek == nil && tm.Manager == nil {
+			tm.Manager = &m
+		} else {
+			tm.Previous = append(tm.Previous, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if tm.Manager != nil {
+		week, err := l.nextWeek(teamID)
+		if err != nil {
+			return nil, err
+		}
+		tm.StrengthModifier = tm.Manager.strengthModifier(week)
+	}
+	return tm, nil
+}
+
+// nextWeek is the week of the team's next open match, or the one after the
+// season when there is none
+func (l *League) nextWeek(teamID int) (int, error) {
+	matches, err := l.Matches()
+	if err != nil {
+		return 0, err
+	}
+	next := l.weeks + 1
+	for _, m := range matches {
+		if (m.HomeTeamID == teamID || m.AwayTeamID == teamID) && !m.Played && m.Week < next {
+			next = m.Week
+		}
+	}
+	return next, nil
+}
+
+// winlessRun counts a team's latest games without a win since week
+func winlessRun(matches []Match, teamID, since int) int {
+	run := 0
+	for _, m := range matches {
+		if !m.Played || m.Week <= since || (m.HomeTeamID != teamID && m.AwayTeamID != teamID) {
+			continue
+		}
+		goalsFor, goalsAgainst := m.HomeGoals, m.AwayGoals
+		if m.AwayTeamID == teamID {
+			goalsFor, goalsAgainst = goalsAgainst, goalsFor
+		}
+		if goalsFor > goalsAgainst {
+			run = 0
+		} else {
+			run++
+		}
+	}
+	return run
+}
+
+// reviewManagers runs after a simulated week: a manager with sackingRun
+// games without a win may be replaced by one appointed that week
+func (l *League) reviewManagers(week int) error {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return err
+	}
+	played, _ := state.split(week)
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	managers, err := currentManagers(tx)
+	if err != nil {
+		return err
+	}
+
+	var sackings []Announcement
+	for _, t := range l.Teams() {
+		m, ok := managers[t.ID]
+		if !ok || winlessRun(played, t.ID, m.AppointedWeek) < sackingRun {
+			continue
+		}
+		if l.rng.Float64() >= sackingChance {
+			continue
+		}
+		a, err := sackManager(tx, l.rng, t, m, week)
+		if err != nil {
+			return err
+		}
+		sackings = append(sackings, a)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, a := range sackings {
+		l.notifyWebhooks(EventTypeAnnouncement, a)
+	}
+	return nil
+}
+
+// sackManager replaces a manager and writes the news item
+func sackManager(tx *sql.Tx, rng *rand.Rand, t Team, m Manager, week int) (Announcement, error) {
+	name := managerFirstNames[rng.Intn(len(managerFirstNames))] + " " + managerLastNames[rng.Intn(len(managerLastNames))]
+	tactic := rng.Intn(maxTactic + 1)
+
+	if _, err := tx.Exec("UPDATE managers SET sacked_week = ? WHERE id = ?", week, m.ID); err != nil {
+		return Announcement{}, err
+	}
+	if _, err := tx.Exec("INSERT INTO managers (team_id, name, tactic, appointed_week) VALUES (?, ?, ?, ?)", t.ID, name, tactic, week); err != nil {
+		return Announcement{}, err
+	}
+
+	// the news keeps the latest sacking per team, the managers table all of them
+	a := Announcement{
+		Kind:      AnnouncementManagerSacked,
+		TeamID:    t.ID,
+		TeamName:  t.Name,
+		Message:   fmt.Sprintf("%s have sacked %s after %d games without a win, %s takes over", t.Name, m.Name, sackingRun, name),
+		Week:      week,
+		CreatedAt: time.Now().UTC(),
+	}
+	res, err := tx.Exec(
+		"INSERT OR REPLACE INTO announcements (kind, team_id, message, week, created_at) VALUES (?, ?, ?, ?, ?)",
+		a.Kind, a.TeamID, a.Message, a.Week, a.CreatedAt,
+	)
+	if err != nil {
+		return Announcement{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Announcement{}, err
+	}
+	a.ID = int(id)
+	return a, nil
+}
+
+// GET /teams/{name}/manager
+func (l *League) handleTeamManager(w http.ResponseWriter, r *http.Request) {
+	teamID, team, err := l.resolveTeam(r.PathValue("name"))
+	if err == sql.ErrNoRows {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tm, err := l.TeamManager(teamID, team)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(tm)
+}
@@ -0,0 +1,87 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestManagerSacking(t *testing.T) {
+	// Delta SC loses every match whatever its managers add, and with seed 4
+	// the board acts on the run at least once
+	h := leaguetest.NewWithSimulator(t, gappedTeams, 4, clearGaps{})
+	cfg := *h.League.Config()
+	cfg.Managers = true
+	h.League.StoreConfig(&cfg)
+
+	var first insider.TeamManager
+	h.Get("/teams/Delta%20SC/manager", &first)
+	if first.Manager == nil || first.Manager.AppointedWeek != 0 || len(first.Previous) != 0 {
+		t.Fatalf("before the season %+v, want the first manager alone", first)
+	}
+	if first.StrengthModifier != first.Manager.Tactic-5 {
+		t.Errorf("strength modifier %d for tactic %d, want %d", first.StrengthModifier, first.Manager.Tactic, first.Manager.Tactic-5)
+	}
+
+	h.SimulateSeason()
+
+	var delta insider.TeamManager
+	h.Get("/teams/Delta%20SC/manager", &delta)
+	if len(delta.Previous) == 0 {
+		t.Fatalf("no sacking at Delta SC after a season without a win")
+	}
+	sacked := delta.Previous[len(delta.Previous)-1]
+	if sacked.Name != first.Manager.Name || sacked.SackedWeek == nil || *sacked.SackedWeek < 3 {
+		t.Errorf("first sacking %+v, want %s gone after at least three games", sacked, first.Manager.Name)
+	}
+	// each manager took over the week the one before was sacked
+	spells := append([]insider.Manager{*delta.Manager}, delta.Previous...)
+	for i := 0; i+1 < len(spells); i++ {
+		if spells[i+1].SackedWeek == nil || spells[i].AppointedWeek != *spells[i+1].SackedWeek {
+			t.Errorf("%s appointed in week %d after %+v", spells[i].Name, spells[i].AppointedWeek, spells[i+1])
+		}
+	}
+
+	var news []insider.Announcement
+	h.Get("/news", &news)
+	found := false
+	for _, a := range news {
+		if a.Kind == insider.AnnouncementManagerSacked && a.TeamName == "Delta SC" {
+			found = a.Week == *delta.Previous[0].SackedWeek
+		}
+	}
+	if !found {
+		t.Errorf("no news of the latest Delta SC sacking in %+v", news)
+	}
+
+	// the leader always wins, so its manager stays
+	var alpha insider.TeamManager
+	h.Get("/teams/Alpha%20FC/manager", &alpha)
+	if len(alpha.Previous) != 0 {
+		t.Errorf("Alpha FC sacked %+v", alpha.Previous)
+	}
+
+	if status := h.Do(http.MethodGet, "/teams/Nobody/manager", nil, false, nil); status != http.StatusNotFound {
+		t.Errorf("unknown team: status %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestManagersOffNeverSacked(t *testing.T) {
+	h := leaguetest.NewWithSimulator(t, gappedTeams, 3, clearGaps{})
+	h.SimulateSeason()
+
+	var delta insider.TeamManager
+	h.Get("/teams/Delta%20SC/manager", &delta)
+	if delta.Manager == nil || len(delta.Previous) != 0 {
+		t.Errorf("with managers off %+v, want the first manager still there", delta)
+	}
+	var news []insider.Announcement
+	h.Get("/news", &news)
+	for _, a := range news {
+		if a.Kind == insider.AnnouncementManagerSacked {
+			t.Errorf("sacking with managers off: %s", a.Message)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package matchengine plays a single match minute by minute.
+//
+// The final score is drawn first, from Params with Engine.Rand, exactly the
+// draw Params.Score makes. The minutes are then played towards it with
+// Engine.Flavor: in each minute every goal still to come lands with the same
+// chance, so goals end up spread uniformly over the match. Timing, incidents
+// and hooks never change a result, and a match played here ends the same as a
+// quick Params.Score with the same generator.
+package matchengine
+
+import "math/rand"
+
+// Side of the pitch an event belongs to
+type Side int
+
+const (
+	Home Side = iota
+	Away
+)
+
+// Event types. Only goals change the score, the VAR ones are flavor.
+const (
+	Goal           = "goal"
+	DisallowedGoal = "disallowed_goal"
+	VAROverturn    = "var_overturn"
+)
+
+const defaultMinutes = 90
+
+// OvertimeMinutes is how long overtime can run; it ends with the first goal
+const OvertimeMinutes = 15
+
+// Event is something that happened in a given minute
+type Event struct {
+	Minute int
+	Side   Side
+	Type   string
+	Detail string
+}
+
+// Hooks are called while the match is played. Both are optional.
+type Hooks struct {
+	// Event gets every event as it happens, with the score after it
+	Event func(e Event, home, away int)
+	// Minute is called at the end of every minute played
+	Minute func(minute, home, away int)
+}
+
+// Engine plays matches. Rand decides results and Flavor everything else;
+// when Flavor is nil Rand is used for both, which means incidents and timing
+// do shift the results of later matches.
+type Engine struct {
+	Params Params
+	// Minutes of regulation time, 90 when zero
+	Minutes int
+	// VARFrequency is the chance per match of a goal being ruled out, half of
+	// it the chance of a penalty being overturned
+	VARFrequency float64
+	Rand         *rand.Rand
+	Flavor       *rand.Rand
+	Hooks        Hooks
+	// Script, when set, turns the drawn score into the one to play. The draw
+	// still happens, so scripting a match does not shift later results.
+	Script func(homeGoals, awayGoals int) (int, int)
+}
+
+// Result of a played match. Events are in minute order.
+type Result struct {
+	HomeGoals int
+	AwayGoals int
+	Overtime  bool
+	Events    []Event
+}
+
+// Play plays one match between sides of the given strengths
+func (e *Engine) Play(homeStrength, awayStrength int) Result {
+	flavor := e.Flavor
+	if flavor == nil {
+		flavor = e.Rand
+	}
+	minutes := e.Minutes
+	if minutes <= 0 {
+		minutes = defaultMinutes
+	}
+
+	finalHome, finalAway, overtime := e.Params.score(e.Rand, homeStrength, awayStrength)
+	if e.Script != nil {
+		scriptedHome, scriptedAway := e.Script(finalHome, finalAway)
+		// a rewritten score was reached in regulation time
+		if scriptedHome != finalHome || scriptedAway != finalAway {
+			overtime = false
+		}
+		finalHome, finalAway = scriptedHome, scriptedAway
+	}
+	// the overtime goal is not part of regulation time
+	toComeHome, toComeAway := finalHome, finalAway
+	if overtime {
+		if finalHome > finalAway {
+			toComeHome--
+		} else {
+			toComeAway--
+		}
+	}
+
+	// incidents are decided up front and happen in their minute
+	var incidents []Event
+	randomSide := func() Side {
+		return Side(flavor.Intn(2))
+	}
+	if flavor.Float64() < e.VARFrequency {
+		incidents = append(incidents, Event{
+			Minute: flavor.Intn(minutes) + 1,
+			Side:   randomSide(),
+			Type:   DisallowedGoal,
+			Detail: disallowReasons[flavor.Intn(len(disallowReasons))],
+		})
+	}
+	if flavor.Float64() < e.VARFrequency/2 {
+		incidents = append(incidents, Event{Minute: flavor.Intn(minutes) + 1, Side: randomSide(), Type: VAROverturn, Detail: "penalty"})
+	}
+
+	result := Result{Overtime: overtime}
+	emit := func(ev Event) {
+		if ev.Type == Goal {
+			if ev.Side == Home {
+				result.HomeGoals++
+			} else {
+				result.AwayGoals++
+			}
+		}
+		result.Events = append(result.Events, ev)
+		if e.Hooks.Event != nil {
+			e.Hooks.Event(ev, result.HomeGoals, result.AwayGoals)
+		}
+	}
+	endMinute := func(minute int) {
+		if e.Hooks.Minute != nil {
+			e.Hooks.Minute(minute, result.HomeGoals, result.AwayGoals)
+		}
+	}
+
+	for minute := 1; minute <= minutes; minute++ {
+		left := minutes - minute + 1
+		homeNow := goalsThisMinute(flavor, toComeHome, left)
+		awayNow := goalsThisMinute(flavor, toComeAway, left)
+		toComeHome -= homeNow
+		toComeAway -= awayNow
+
+		for _, ev := range incidents {
+			if ev.Minute == minute {
+				emit(ev)
+			}
+		}
+		for i := 0; i < homeNow; i++ {
+			emit(Event{Minute: minute, Side: Home, Type: Goal, Detail: randomGoalKind(flavor)})
+		}
+		for i := 0; i < awayNow; i++ {
+			emit(Event{Minute: minute, Side: Away, Type: Goal, Detail: randomGoalKind(flavor)})
+		}
+		endMinute(minute)
+	}
+
+	if overtime {
+		winner := Home
+		if finalAway > finalHome {
+			winner = Away
+		}
+		goalMinute := minutes + flavor.Intn(OvertimeMinutes) + 1
+		for minute := minutes + 1; minute <= goalMinute; minute++ {
+			if minute == goalMinute {
+				emit(Event{Minute: minute, Side: winner, Type: Goal, Detail: randomGoalKind(flavor)})
+			}
+			endMinute(minute)
+		}
+	}
+	return result
+}
+
+// goalsThisMinute places each of the goals still to come in this minute with
+// chance 1/minutesLeft, so the last minute takes whatever is left
+func goalsThisMinute(rng *rand.Rand, toCome, minutesLeft int) int {
+	n := 0
+	for i := 0; i < toCome; i++ {
+		if rng.Intn(minutesLeft) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// goal kinds and how often they happen
+var goalKinds = []struct {
+	name   string
+	weight int
+}{
+	{"strike", 60},
+	{"header", 20},
+	{"penalty", 10},
+	{"free kick", 10},
+}
+
+func randomGoalKind(rng *rand.Rand) string {
+	total := 0
+	for _, k := range goalKinds {
+		total += k.weight
+	}
+	n := rng.Intn(total)
+	for _, k := range goalKinds {
+		if n < k.weight {
+			return k.name
+		}
+		n -= k.weight
+	}
+	return goalKinds[0].name
+}
+
+var disallowReasons = []string{"offside", "handball", "a foul in the build-up"}
@@ -0,0 +1,406 @@
+package matchengine
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+var football = Params{HomeAdvantage: 10, StrengthPerGoal: 20}
+
+func newEngine(params Params, seed int64) *Engine {
+	return &Engine{
+		Params:       params,
+		Minutes:      90,
+		VARFrequency: 0.5,
+		Rand:         rand.New(rand.NewSource(seed)),
+		Flavor:       rand.New(rand.NewSource(seed + 1)),
+	}
+}
+
+// Play has to end exactly where Params.Score would, match after match
+func TestPlayMatchesScore(t *testing.T) {
+	for _, params := range []Params{football, {HomeAdvantage: 6, StrengthPerGoal: 2, BaseScore: 70, Overtime: true}} {
+		engine := newEngine(params, 7)
+		rng := rand.New(rand.NewSource(7))
+		for i := 0; i < 500; i++ {
+			home, away := 40+i%50, 90-i%50
+			result := engine.Play(home, away)
+			wantHome, wantAway := params.Score(rng, home, away)
+			if result.HomeGoals != wantHome || result.AwayGoals != wantAway {
+				t.Fatalf("%+v match %d: Play gave %d-%d, Score %d-%d", params, i, result.HomeGoals, result.AwayGoals, wantHome, wantAway)
+			}
+		}
+	}
+}
+
+// Flavor only moves events around, never the score
+func TestFlavorDoesNotChangeResults(t *testing.T) {
+	a := newEngine(football, 3)
+	b := newEngine(football, 3)
+	b.Flavor = rand.New(rand.NewSource(99))
+	b.VARFrequency = 0
+	for i := 0; i < 200; i++ {
+		ra, rb := a.Play(85, 60), b.Play(85, 60)
+		if ra.HomeGoals != rb.HomeGoals || ra.AwayGoals != rb.AwayGoals {
+			t.Fatalf("match %d: %d-%d vs %d-%d", i, ra.HomeGoals, ra.AwayGoals, rb.HomeGoals, rb.AwayGoals)
+		}
+	}
+}
+
+func TestEventsAddUp(t *testing.T) {
+	engine := newEngine(Params{HomeAdvantage: 10, StrengthPerGoal: 15, Overtime: true}, 11)
+	for i := 0; i < 500; i++ {
+		result := engine.Play(70, 70)
+
+		home, away, last := 0, 0, 0
+		for _, e := range result.Events {
+			if e.Minute < last {
+				t.Fatalf("events out of order: %d after %d", e.Minute, last)
+			}
+			last = e.Minute
+			if e.Minute < 1 || e.Minute > engine.Minutes+OvertimeMinutes {
+				t.Fatalf("event in minute %d", e.Minute)
+			}
+			if e.Type != Goal {
+				if e.Minute > engine.Minutes {
+					t.Fatalf("%s in overtime", e.Type)
+				}
+				continue
+			}
+			if e.Side == Home {
+				home++
+			} else {
+				away++
+			}
+		}
+		if home != result.HomeGoals || away != result.AwayGoals {
+			t.Fatalf("goal events %d-%d, result %d-%d", home, away, result.HomeGoals, result.AwayGoals)
+		}
+		if result.HomeGoals == result.AwayGoals {
+			t.Fatalf("draw %d-%d with overtime", result.HomeGoals, result.AwayGoals)
+		}
+
+		// the overtime goal is the only one after regulation time
+		late := 0
+		for _, e := range result.Events {
+			if e.Minute > engine.Minutes {
+				late++
+			}
+		}
+		if result.Overtime != (late == 1) || late > 1 {
+			t.Fatalf("overtime %v with %d goals after minute %d", result.Overtime, late, engine.Minutes)
+		}
+	}
+}
+
+func TestHooks(t *testing.T) {
+	engine := newEngine(football, 5)
+	engine.VARFrequency = 1
+
+	var minutes []int
+	var events []Event
+	home, away := 0, 0
+	engine.Hooks = Hooks{
+		Minute: func(minute, h, a int) {
+			minutes = append(minutes, minute)
+			home, away = h, a
+		},
+		Event: func(e Event, h, a int) {
+			events = append(events, e)
+			if e.Type == Goal && h+a != home+away+countGoalsInMinute(events, e.Minute) {
+				t.Errorf("score %d-%d after goal in minute %d does not follow the running score", h, a, e.Minute)
+			}
+		},
+	}
+	result := engine.Play(85, 50)
+
+	if len(minutes) != 90 {
+		t.Fatalf("minute hook called %d times, want 90", len(minutes))
+	}
+	for i, m := range minutes {
+		if m != i+1 {
+			t.Fatalf("minute hook got %d at call %d", m, i)
+		}
+	}
+	if home != result.HomeGoals || away != result.AwayGoals {
+		t.Fatalf("last minute hook saw %d-%d, result %d-%d", home, away, result.HomeGoals, result.AwayGoals)
+	}
+	if len(events) != len(result.Events) {
+		t.Fatalf("event hook saw %d events, result has %d", len(events), len(result.Events))
+	}
+}
+
+func countGoalsInMinute(events []Event, minute int) int {
+	n := 0
+	for _, e := range events {
+		if e.Minute == minute && e.Type == Goal {
+			n++
+		}
+	}
+	return n
+}
+
+// Goals should be spread evenly, not bunched up at either end
+func TestGoalsSpreadOverTheMatch(t *testing.T) {
+	engine := newEngine(football, 21)
+	engine.VARFrequency = 0
+
+	firstHalf, total := 0, 0
+	for i := 0; i < 5000; i++ {
+		for _, e := range engine.Play(85, 85).Events {
+			total++
+			if e.Minute <= 45 {
+				firstHalf++
+			}
+		}
+	}
+	share := float64(firstHalf) / float64(total)
+	if math.Abs(share-0.5) > 0.02 {
+		t.Fatalf("%.3f of %d goals in the first half, want about half", share, total)
+	}
+}
+
+func TestProbabilities(t *testing.T) {
+	cases := []struct {
+		name         string
+		params       Params
+		home, away   int
+		wantNoDraws  bool
+		wantHomeBias bool
+	}{
+		{"equal sides", football, 70, 70, false, true},
+		{"strong away side", football, 50, 90, false, false},
+		{"overtime", Params{HomeAdvantage: 10, StrengthPerGoal: 15, Overtime: true}, 60, 50, true, true},
+		{"entertainment", Params{HomeAdvantage: 10, StrengthPerGoal: 20, Entertainment: true, Chaos: 1}, 50, 90, false, false},
+		{"poisson", Params{Model: ModelPoisson, HomeAdvantage: 10, StrengthPerGoal: 20}, 70, 70, false, true},
+		{"poisson strong away side", Params{Model: ModelPoisson, HomeAdvantage: 10, StrengthPerGoal: 20}, 50, 90, false, false},
+		{"poisson overtime", Params{Model: ModelPoisson, HomeAdvantage: 6, StrengthPerGoal: 2, BaseScore: 70, Overtime: true}, 60, 55, true, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			homeWin, draw, awayWin := tc.params.Probabilities(tc.home, tc.away)
+			if math.Abs(homeWin+draw+awayWin-1) > 1e-9 {
+				t.Fatalf("probabilities sum to %v", homeWin+draw+awayWin)
+			}
+			if tc.wantNoDraws && draw != 0 {
+				t.Fatalf("draw probability %v with overtime", draw)
+			}
+			if (homeWin > awayWin) != tc.wantHomeBias {
+				t.Fatalf("home %v away %v", homeWin, awayWin)
+			}
+
+			// and they describe what Score actually does
+			rng := rand.New(rand.NewSource(1))
+			const n = 200000
+			var wins, draws int
+			for i := 0; i < n; i++ {
+				h, a := tc.params.Score(rng, tc.home, tc.away)
+				switch {
+				case h > a:
+					wins++
+				case h == a:
+					draws++
+				}
+			}
+			if math.Abs(float64(wins)/n-homeWin) > 0.01 || math.Abs(float64(draws)/n-draw) > 0.01 {
+				t.Fatalf("sampled %v/%v, expected %v/%v", float64(wins)/n, float64(draws)/n, homeWin, draw)
+			}
+		})
+	}
+}
+
+// Entertainment mode gives the underdog more wins and both sides more goals,
+// without making it the favourite
+func TestEntertainment(t *testing.T) {
+	calm := football
+	wild := football
+	wild.Entertainment, wild.Chaos = true, 1
+
+	calmHome, _, calmAway := calm.Probabilities(90, 40)
+	wildHome, _, wildAway := wild.Probabilities(90, 40)
+	if wildAway <= calmAway {
+		t.Errorf("underdog wins %v with chaos, %v without", wildAway, calmAway)
+	}
+	if wildHome <= wildAway {
+		t.Errorf("favourite wins %v, underdog %v with chaos", wildHome, wildAway)
+	}
+	if calmHome <= wildHome {
+		t.Errorf("favourite wins %v with chaos, %v without", wildHome, calmHome)
+	}
+
+	goals := func(p Params) int {
+		rng := rand.New(rand.NewSource(1))
+		total := 0
+		for i := 0; i < 10000; i++ {
+			h, a := p.Score(rng, 70, 60)
+			total += h + a
+		}
+		return total
+	}
+	if calmGoals, wildGoals := goals(calm), goals(wild); wildGoals <= calmGoals {
+		t.Errorf("%d goals with chaos, %d without", wildGoals, calmGoals)
+	}
+
+	// without the flag chaos does nothing
+	off := football
+	off.Chaos = 1
+	if h, _, a := off.Probabilities(90, 40); h != calmHome || a != calmAway {
+		t.Errorf("chaos applied without entertainment: home %v away %v", h, a)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		params Params
+		ok     bool
+	}{
+		{football, true},
+		{Params{StrengthPerGoal: 0}, false},
+		{Params{StrengthPerGoal: 10, HomeAdvantage: 101}, false},
+		{Params{StrengthPerGoal: 10, BaseScore: -1}, false},
+		{Params{StrengthPerGoal: 10, Entertainment: true, Chaos: 1.5}, false},
+		{Params{StrengthPerGoal: 10, Model: ModelPoisson}, true},
+		{Params{StrengthPerGoal: 10, Model: "dice"}, false},
+	}
+	for _, tc := range cases {
+		if err := tc.params.Validate(); (err == nil) != tc.ok {
+			t.Errorf("Validate(%+v) = %v", tc.params, err)
+		}
+	}
+}
+
+func TestScript(t *testing.T) {
+	scripted := newEngine(football, 9)
+	plain := newEngine(football, 9)
+	for i := 0; i < 50; i++ {
+		if i == 10 {
+			scripted.Script = func(int, int) (int, int) { return 0, 7 }
+		} else {
+			scripted.Script = nil
+		}
+		got, want := scripted.Play(80, 60), plain.Play(80, 60)
+		if i == 10 {
+			if got.HomeGoals != 0 || got.AwayGoals != 7 || len(got.Events) < 7 {
+				t.Fatalf("scripted match ended %d-%d with %d events", got.HomeGoals, got.AwayGoals, len(got.Events))
+			}
+			continue
+		}
+		// the draw still happened, so later matches are untouched
+		if got.HomeGoals != want.HomeGoals || got.AwayGoals != want.AwayGoals {
+			t.Fatalf("match %d: %d-%d after a script, %d-%d without", i, got.HomeGoals, got.AwayGoals, want.HomeGoals, want.AwayGoals)
+		}
+	}
+}
+
+func TestShootout(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	homeWins := 0
+	for i := 0; i < 2000; i++ {
+		s := Shootout(rng, 0)
+		if s.HomeGoals == s.AwayGoals {
+			t.Fatalf("shootout ended level %d-%d", s.HomeGoals, s.AwayGoals)
+		}
+
+		home, away, homeTaken, awayTaken := 0, 0, 0, 0
+		for j, k := range s.Kicks {
+			if (j%2 == 0) != (k.Side == Home) {
+				t.Fatalf("kick %d taken by the wrong side", j)
+			}
+			if k.Side == Home {
+				homeTaken++
+			} else {
+				awayTaken++
+			}
+			if k.Scored && k.Side == Home {
+				home++
+			} else if k.Scored {
+				away++
+			}
+		}
+		if home != s.HomeGoals || away != s.AwayGoals {
+			t.Fatalf("kicks add up to %d-%d, result %d-%d", home, away, s.HomeGoals, s.AwayGoals)
+		}
+		// sudden death goes in pairs, the first five rounds may stop early
+		if homeTaken > shootoutRounds && homeTaken != awayTaken {
+			t.Fatalf("sudden death stopped after %d and %d kicks", homeTaken, awayTaken)
+		}
+		if s.Winner() == Home {
+			homeWins++
+		}
+	}
+	// kicking first is no advantage in this model
+	if homeWins < 900 || homeWins > 1100 {
+		t.Fatalf("home side won %d of 2000 shootouts", homeWins)
+	}
+}
+
+// The Poisson model keeps the uniform one's goals between even sides, and
+// lets a strong defence hold the other side below them
+func TestPoissonMeans(t *testing.T) {
+	uniform := Params{StrengthPerGoal: 20}
+	poisson := uniform
+	poisson.Model = ModelPoisson
+
+	goals := func(p Params, home, away int) (float64, float64) {
+		rng := rand.New(rand.NewSource(1))
+		const n = 100000
+		var h, a int
+		for i := 0; i < n; i++ {
+			hg, ag := p.Score(rng, home, away)
+			h += hg
+			a += ag
+		}
+		return float64(h) / n, float64(a) / n
+	}
+	if got, _ := goals(poisson, 60, 60); math.Abs(got-1.5) > 0.02 {
+		t.Errorf("even sides score %.3f, want 1.5", got)
+	}
+	strongHome, weakAway := goals(poisson, 90, 40)
+	evenHome, evenAway := goals(poisson, 40, 40)
+	if weakAway >= evenAway || strongHome <= evenHome {
+		t.Errorf("90 v 40 scores %.2f-%.2f, 40 v 40 %.2f-%.2f", strongHome, weakAway, evenHome, evenAway)
+	}
+}
+
+type fixedModel struct{}
+
+func (fixedModel) Score(rng *rand.Rand, p Params, homeStrength, awayStrength int) (int, int, bool) {
+	return 2, 1, false
+}
+
+func (fixedModel) Probabilities(p Params, homeStrength, awayStrength int) (float64, float64, float64) {
+	return 1, 0, 0
+}
+
+func TestRegisterModel(t *testing.T) {
+	RegisterModel("fixed", fixedModel{})
+	defer delete(models, "fixed")
+
+	p := Params{Model: "fixed", StrengthPerGoal: 20}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("registered model rejected: %v", err)
+	}
+	e := &Engine{Params: p, Rand: rand.New(rand.NewSource(1))}
+	if r := e.Play(40, 90); r.HomeGoals != 2 || r.AwayGoals != 1 {
+		t.Errorf("played %d-%d, want the model's 2-1", r.HomeGoals, r.AwayGoals)
+	}
+}
+
+func TestSimulatorOverridesName(t *testing.T) {
+	p := Params{Model: ModelPoisson, Simulator: fixedModel{}, StrengthPerGoal: 20}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if home, away := p.Score(rand.New(rand.NewSource(1)), 40, 90); home != 2 || away != 1 {
+		t.Errorf("scored %d-%d, want the simulator's 2-1", home, away)
+	}
+	if homeWin, _, _ := p.Probabilities(40, 90); homeWin != 1 {
+		t.Errorf("home win %.2f, want the simulator's 1", homeWin)
+	}
+	// without one the name counts again
+	p.Simulator = nil
+	if homeWin, _, _ := p.Probabilities(40, 90); homeWin > 0.5 {
+		t.Errorf("poisson gives 40 v 90 a home win %.2f of the time", homeWin)
+	}
+}
@@ -0,0 +1,208 @@
+package matchengine
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Model draws the final score of a match between sides of the given
+// strengths, after entertainment mode has stirred them, and knows the exact
+// odds of its own draws. Params.Model picks one by name, Params.Simulator
+// takes one as a value. Params are compared, so a Model has to be comparable:
+// a struct of plain fields or a pointer.
+type Model interface {
+	Score(rng *rand.Rand, p Params, homeStrength, awayStrength int) (homeGoals, awayGoals int, overtime bool)
+	Probabilities(p Params, homeStrength, awayStrength int) (homeWin, draw, awayWin float64)
+}
+
+// Names of the built-in models
+const (
+	ModelUniform = "uniform"
+	ModelPoisson = "poisson"
+)
+
+var models = map[string]Model{
+	ModelUniform: Uniform{},
+	ModelPoisson: Poisson{},
+}
+
+// RegisterModel makes a model available under name, replacing the one there.
+// It is meant for init functions: models are looked up without locking.
+func RegisterModel(name string, m Model) {
+	models[name] = m
+}
+
+// model is p's Simulator, else the model p names, the uniform one by default
+func (p Params) model() Model {
+	if p.Simulator != nil {
+		return p.Simulator
+	}
+	if m, ok := models[p.Model]; ok {
+		return m
+	}
+	return Uniform{}
+}
+
+// Uniform gives each side BaseScore plus uniformly between 0 and
+// strength/StrengthPerGoal goals. The side with the wider range is likelier
+// to win in overtime.
+type Uniform struct{}
+
+func (Uniform) Score(rng *rand.Rand, p Params, homeStrength, awayStrength int) (homeGoals, awayGoals int, overtime bool) {
+	homeMax := p.maxGoals(homeStrength + p.HomeAdvantage)
+	awayMax := p.maxGoals(awayStrength)
+	homeGoals = p.BaseScore + rng.Intn(homeMax)
+	awayGoals = p.BaseScore + rng.Intn(awayMax)
+
+	if p.Overtime && homeGoals == awayGoals {
+		if rng.Intn(homeMax+awayMax) < homeMax {
+			homeGoals++
+		} else {
+			awayGoals++
+		}
+		return homeGoals, awayGoals, true
+	}
+	return homeGoals, awayGoals, false
+}
+
+func (Uniform) Probabilities(p Params, homeStrength, awayStrength int) (homeWin, draw, awayWin float64) {
+	homeMax := p.maxGoals(homeStrength + p.HomeAdvantage)
+	awayMax := p.maxGoals(awayStrength)
+
+	total := float64(homeMax * awayMax)
+	for h := 0; h < homeMax; h++ {
+		for a := 0; a < awayMax; a++ {
+			switch {
+			case h > a:
+				homeWin++
+			case h < a:
+				awayWin++
+			default:
+				draw++
+			}
+		}
+	}
+	homeWin, draw, awayWin = homeWin/total, draw/total, awayWin/total
+
+	if p.Overtime {
+		homeShare := float64(homeMax) / float64(homeMax+awayMax)
+		homeWin += draw * homeShare
+		awayWin += draw * (1 - homeShare)
+		draw = 0
+	}
+	return homeWin, draw, awayWin
+}
+
+// Poisson draws each side's goals above BaseScore from a Poisson
+// distribution. The mean starts where the uniform model's does, at half of
+// attack/StrengthPerGoal, and is scaled by how the side's attack compares
+// with the other side's defence: twice attack/(attack+defence), so even
+// sides keep the uniform mean and a strong defence holds a side below it.
+// Both ratings are the team's strength, the home attack with HomeAdvantage.
+// Results are less capped than uniform ones: a big win stays possible, and
+// so does a shutout for a strong side.
+type Poisson struct{}
+
+// means are the expected goals above BaseScore of both sides
+func (Poisson) means(p Params, homeStrength, awayStrength int) (home, away float64) {
+	mean := func(attack, defence int) float64 {
+		if attack <= 0 {
+			return 0
+		}
+		if defence < 0 {
+			defence = 0
+		}
+		a := float64(attack)
+		return a / float64(2*p.StrengthPerGoal) * 2 * a / (a + float64(defence))
+	}
+	homeAttack := homeStrength + p.HomeAdvantage
+	return mean(homeAttack, awayStrength), mean(awayStrength, homeAttack)
+}
+
+// poisson draws from a Poisson distribution by inversion
+func poisson(rng *rand.Rand, mean float64) int {
+	if mean <= 0 {
+		return 0
+	}
+	u := rng.Float64()
+	k, pk := 0, math.Exp(-mean)
+	cumulative := pk
+	for u > cumulative && pk > 0 {
+		k++
+		pk *= mean / float64(k)
+		cumulative += pk
+	}
+	return k
+}
+
+// poissonPMF lists the chances of 0, 1, 2... goals until almost all of the
+// mass is covered
+func poissonPMF(mean float64) []float64 {
+	if mean <= 0 {
+		return []float64{1}
+	}
+	pmf := []float64{math.Exp(-mean)}
+	cumulative := pmf[0]
+	for k := 1; cumulative < 1-1e-12 && k < 1000; k++ {
+		next := pmf[k-1] * mean / float64(k)
+		pmf = append(pmf, next)
+		cumulative += next
+	}
+	return pmf
+}
+
+func (m Poisson) Score(rng *rand.Rand, p Params, homeStrength, awayStrength int) (homeGoals, awayGoals int, overtime bool) {
+	homeMean, awayMean := m.means(p, homeStrength, awayStrength)
+	homeGoals = p.BaseScore + poisson(rng, homeMean)
+	awayGoals = p.BaseScore + poisson(rng, awayMean)
+
+	if p.Overtime && homeGoals == awayGoals {
+		if rng.Float64() < poissonShare(homeMean, awayMean) {
+			homeGoals++
+		} else {
+			awayGoals++
+		}
+		return homeGoals, awayGoals, true
+	}
+	return homeGoals, awayGoals, false
+}
+
+// poissonShare is the home side's chance of the overtime goal
+func poissonShare(homeMean, awayMean float64) float64 {
+	if homeMean+awayMean == 0 {
+		return 0.5
+	}
+	return homeMean / (homeMean + awayMean)
+}
+
+func (m Poisson) Probabilities(p Params, homeStrength, awayStrength int) (homeWin, draw, awayWin float64) {
+	homeMean, awayMean := m.means(p, homeStrength, awayStrength)
+	home, away := poissonPMF(homeMean), poissonPMF(awayMean)
+
+	// the away side's chance of fewer goals than h, h, and more
+	below := 0.0
+	awayTotal := 0.0
+	for _, q := range away {
+		awayTotal += q
+	}
+	for h, ph := range home {
+		var at float64
+		if h < len(away) {
+			at = away[h]
+		}
+		homeWin += ph * below
+		draw += ph * at
+		awayWin += ph * (awayTotal - below - at)
+		below += at
+	}
+	total := homeWin + draw + awayWin
+	homeWin, draw, awayWin = homeWin/total, draw/total, awayWin/total
+
+	if p.Overtime {
+		homeShare := poissonShare(homeMean, awayMean)
+		homeWin += draw * homeShare
+		awayWin += draw * (1 - homeShare)
+		draw = 0
+	}
+	return homeWin, draw, awayWin
+}
@@ -0,0 +1,94 @@
+package matchengine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Params tune the scoring model. Model names it, see models.go; the uniform
+// one is used when it is empty. Simulator, when set, is used instead of the
+// named model; it is a Go value, so it never appears in the JSON.
+//
+// A side scores BaseScore plus some goals that grow with
+// strength/StrengthPerGoal, the home side with HomeAdvantage added.
+// With Overtime a level game goes on until one side scores once more.
+//
+// Entertainment mode stirs the strengths before that: the gap between the
+// sides shrinks by up to half and both get up to one more goal of range, by
+// Chaos from 0 to 1. The stronger side stays the favourite, so a long run
+// still ends in a plausible table, only with more upsets and goals.
+type Params struct {
+	Model           string  `json:"model,omitempty"`
+	Simulator       Model   `json:"-"`
+	HomeAdvantage   int     `json:"home_advantage"`
+	StrengthPerGoal int     `json:"strength_per_goal"`
+	BaseScore       int     `json:"base_score"`
+	Overtime        bool    `json:"overtime"`
+	Entertainment   bool    `json:"entertainment,omitempty"`
+	Chaos           float64 `json:"chaos,omitempty"`
+}
+
+// DefaultChaos is used in entertainment mode when chaos is not set
+const DefaultChaos = 0.3
+
+func (p Params) Validate() error {
+	if p.StrengthPerGoal < 1 {
+		return fmt.Errorf("strength_per_goal must be at least 1")
+	}
+	if p.HomeAdvantage < -100 || p.HomeAdvantage > 100 {
+		return fmt.Errorf("home_advantage must be between -100 and 100")
+	}
+	if p.BaseScore < 0 {
+		return fmt.Errorf("base_score cannot be negative")
+	}
+	if p.Chaos < 0 || p.Chaos > 1 {
+		return fmt.Errorf("chaos must be between 0 and 1")
+	}
+	if _, ok := models[p.Model]; !ok && p.Model != "" {
+		return fmt.Errorf("unknown model %q", p.Model)
+	}
+	return nil
+}
+
+// stir applies entertainment mode to the strengths of a match
+func (p Params) stir(homeStrength, awayStrength int) (int, int) {
+	if !p.Entertainment {
+		return homeStrength, awayStrength
+	}
+	chaos := p.Chaos
+	if chaos == 0 {
+		chaos = DefaultChaos
+	}
+	closer := int(math.Round(float64(homeStrength-awayStrength) * chaos / 4))
+	extra := int(math.Round(float64(p.StrengthPerGoal) * chaos))
+	return homeStrength - closer + extra, awayStrength + closer + extra
+}
+
+// maxGoals is the number of possible goal counts (0 up to the cap) for a side
+func (p Params) maxGoals(strength int) int {
+	n := strength/p.StrengthPerGoal + 1
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// Score draws a final score without playing the minutes. It is what the
+// engine plays towards, and fast enough for Monte Carlo runs.
+func (p Params) Score(rng *rand.Rand, homeStrength, awayStrength int) (homeGoals, awayGoals int) {
+	homeGoals, awayGoals, _ = p.score(rng, homeStrength, awayStrength)
+	return homeGoals, awayGoals
+}
+
+// score also reports whether overtime decided the match
+func (p Params) score(rng *rand.Rand, homeStrength, awayStrength int) (homeGoals, awayGoals int, overtime bool) {
+	homeStrength, awayStrength = p.stir(homeStrength, awayStrength)
+	return p.model().Score(rng, p, homeStrength, awayStrength)
+}
+
+// Probabilities gives the exact home win / draw / away win chances of Score
+func (p Params) Probabilities(homeStrength, awayStrength int) (homeWin, draw, awayWin float64) {
+	homeStrength, awayStrength = p.stir(homeStrength, awayStrength)
+	return p.model().Probabilities(p, homeStrength, awayStrength)
+}
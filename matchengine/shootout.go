@@ -0,0 +1,75 @@
+package matchengine
+
+import "math/rand"
+
+// DefaultConversion is the share of penalties scored in a shootout
+const DefaultConversion = 0.75
+
+// shootoutRounds are taken by each side before it goes to sudden death
+const shootoutRounds = 5
+
+// Kick is one penalty of a shootout
+type Kick struct {
+	Side   Side
+	Scored bool
+}
+
+// ShootoutResult lists the kicks in order. Home kicks first.
+type ShootoutResult struct {
+	HomeGoals int
+	AwayGoals int
+	Kicks     []Kick
+}
+
+// Winner is the side that won the shootout
+func (s ShootoutResult) Winner() Side {
+	if s.HomeGoals > s.AwayGoals {
+		return Home
+	}
+	return Away
+}
+
+// Shootout plays penalties until one side is ahead: five each, stopping as
+// soon as the other side cannot catch up, then one each in sudden death.
+// conversion is the chance of a single kick going in, DefaultConversion
+// when zero.
+func Shootout(rng *rand.Rand, conversion float64) ShootoutResult {
+	if conversion <= 0 {
+		conversion = DefaultConversion
+	}
+	var s ShootoutResult
+	kick := func(side Side) {
+		scored := rng.Float64() < conversion
+		s.Kicks = append(s.Kicks, Kick{Side: side, Scored: scored})
+		if scored {
+			if side == Home {
+				s.HomeGoals++
+			} else {
+				s.AwayGoals++
+			}
+		}
+	}
+
+	for round := 1; round <= shootoutRounds; round++ {
+		kick(Home)
+		if decided(s, round, round-1) {
+			return s
+		}
+		kick(Away)
+		if decided(s, round, round) {
+			return s
+		}
+	}
+	for s.HomeGoals == s.AwayGoals {
+		kick(Home)
+		kick(Away)
+	}
+	return s
+}
+
+// decided tells whether either side is out of reach in the first rounds,
+// given how many kicks each side has taken
+func decided(s ShootoutResult, homeTaken, awayTaken int) bool {
+	homeLeft, awayLeft := shootoutRounds-homeTaken, shootoutRounds-awayTaken
+	return s.HomeGoals+homeLeft < s.AwayGoals || s.AwayGoals+awayLeft < s.HomeGoals
+}
@@ -0,0 +1,144 @@
+package insider
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// simulationMetrics counts what the model has produced since the server
+// started, per sport. They are kept in memory on purpose: comparing them
+// between deployments is how drift in the model shows up.
+type simulationMetrics struct {
+	mu      sync.Mutex
+	bySport map[string]*sportMetrics
+}
+
+type sportMetrics struct {
+	matches  int
+	homeWins int
+	draws    int
+	awayWins int
+	overtime int
+	goals    int
+	// totals counts the matches by goals scored. The histogram buckets are
+	// laid over them when written, from the params of the latest week, so
+	// they follow a reloaded config.
+	totals map[int]int
+	params SimParams
+}
+
+// goalBuckets spreads ten buckets over the totals the model can produce, so
+// football and basketball scores both get a useful histogram
+func goalBuckets(params SimParams) []int {
+	low := 2 * params.BaseScore
+	high := 2 * (params.BaseScore + 100/params.StrengthPerGoal)
+	step := (high - low) / 10
+	if step < 1 {
+		step = 1
+	}
+	var bounds []int
+	for b := low; b < high; b += step {
+		bounds = append(bounds, b)
+	}
+	return append(bounds, high)
+}
+
+// observe records the matches of a simulated week. A week with nothing to
+// play leaves the metrics alone.
+func (m *simulationMetrics) observe(cfg *Config, matches []Match, overtime int) {
+	if len(matches) == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.bySport == nil {
+		m.bySport = make(map[string]*sportMetrics)
+	}
+	s := m.bySport[cfg.Sport.Name]
+	if s == nil {
+		s = &sportMetrics{totals: make(map[int]int)}
+		m.bySport[cfg.Sport.Name] = s
+	}
+	s.params = cfg.Simulation
+
+	s.overtime += overtime
+	for _, match := range matches {
+		s.matches++
+		switch {
+		case match.HomeGoals > match.AwayGoals:
+			s.homeWins++
+		case match.HomeGoals < match.AwayGoals:
+			s.awayWins++
+		default:
+			s.draws++
+		}
+		total := match.HomeGoals + match.AwayGoals
+		s.goals += total
+		s.totals[total]++
+	}
+}
+
+// writeTo writes the metrics in the Prometheus text format
+func (m *simulationMetrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sports := make([]string, 0, len(m.bySport))
+	for name := range m.bySport {
+		sports = append(sports, name)
+	}
+	sort.Strings(sports)
+
+	counter := func(name, help string, value func(*sportMetrics) int) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, sport := range sports {
+			fmt.Fprintf(w, "%s{sport=%q} %d\n", name, sport, value(m.bySport[sport]))
+		}
+	}
+	rate := func(name, help string, value func(*sportMetrics) int) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, sport := range sports {
+			// a rate needs a match to be a share of
+			if s := m.bySport[sport]; s.matches > 0 {
+				fmt.Fprintf(w, "%s{sport=%q} %g\n", name, sport, float64(value(s))/float64(s.matches))
+			}
+		}
+	}
+
+	counter("league_simulated_matches_total", "Matches simulated since start.", func(s *sportMetrics) int { return s.matches })
+	counter("league_simulated_home_wins_total", "Simulated matches won by the home side.", func(s *sportMetrics) int { return s.homeWins })
+	counter("league_simulated_draws_total", "Simulated matches ending level.", func(s *sportMetrics) int { return s.draws })
+	counter("league_simulated_away_wins_total", "Simulated matches won by the away side.", func(s *sportMetrics) int { return s.awayWins })
+	counter("league_simulated_overtime_total", "Simulated matches decided in overtime.", func(s *sportMetrics) int { return s.overtime })
+	rate("league_simulated_home_win_rate", "Share of simulated matches won by the home side.", func(s *sportMetrics) int { return s.homeWins })
+	rate("league_simulated_draw_rate", "Share of simulated matches ending level.", func(s *sportMetrics) int { return s.draws })
+
+	const goals = "league_simulated_match_goals"
+	fmt.Fprintf(w, "# HELP %s Goals per simulated match, both sides.\n# TYPE %s histogram\n", goals, goals)
+	for _, sport := range sports {
+		s := m.bySport[sport]
+		for _, bound := range goalBuckets(s.params) {
+			cumulative := 0
+			for total, n := range s.totals {
+				if total <= bound {
+					cumulative += n
+				}
+			}
+			fmt.Fprintf(w, "%s_bucket{sport=%q,le=%q} %d\n", goals, sport, strconv.Itoa(bound), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{sport=%q,le=\"+Inf\"} %d\n", goals, sport, s.matches)
+		fmt.Fprintf(w, "%s_sum{sport=%q} %d\n", goals, sport, s.goals)
+		fmt.Fprintf(w, "%s_count{sport=%q} %d\n", goals, sport, s.matches)
+	}
+}
+
+// GET /metrics in the Prometheus text format
+func (l *League) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	l.metrics.writeTo(w)
+}
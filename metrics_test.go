@@ -0,0 +1,43 @@
+package insider
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMetricsRatesAndBuckets(t *testing.T) {
+	var m simulationMetrics
+	cfg := defaultConfig()
+	m.observe(&cfg, nil, 0)
+	var out strings.Builder
+	m.writeTo(&out)
+	if strings.Contains(out.String(), "NaN") || strings.Contains(out.String(), "sport=") {
+		t.Errorf("a week without matches showed up:\n%s", out.String())
+	}
+
+	m.observe(&cfg, []Match{{HomeGoals: 2, AwayGoals: 1}, {HomeGoals: 1, AwayGoals: 1}}, 0)
+	out.Reset()
+	m.writeTo(&out)
+	for _, line := range []string{
+		`league_simulated_home_win_rate{sport="football"} 0.5`,
+		`league_simulated_draw_rate{sport="football"} 0.5`,
+		`league_simulated_match_goals_bucket{sport="football",le="+Inf"} 2`,
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("missing %s in\n%s", line, out.String())
+		}
+	}
+
+	// a reloaded config with higher scores moves the buckets along
+	reloaded := cfg
+	reloaded.Simulation.BaseScore = 50
+	m.observe(&reloaded, []Match{{HomeGoals: 55, AwayGoals: 50}}, 0)
+	out.Reset()
+	m.writeTo(&out)
+	for _, bound := range goalBuckets(reloaded.Simulation) {
+		if !strings.Contains(out.String(), `le="`+strconv.Itoa(bound)+`"`) {
+			t.Errorf("bucket %d of the reloaded config missing in\n%s", bound, out.String())
+		}
+	}
+}
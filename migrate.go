@@ -0,0 +1,285 @@
+package insider
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Matches, events and archives reference teams by id, so renaming a team never
+// touches them. Deleting a team that has matches is refused.
+
+func matchesTable(name string) string {
+	return `
+	CREATE TABLE IF NOT EXISTS ` + name + ` (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		home_team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE RESTRICT,
+		away_team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE RESTRICT,
+		home_goals INTEGER DEFAULT 0,
+		away_goals INTEGER DEFAULT 0,
+		played BOOLEAN DEFAULT FALSE,
+		week INTEGER,
+		commentary TEXT DEFAULT '',
+		postponed BOOLEAN DEFAULT FALSE,
+		kickoff TEXT,
+		live BOOLEAN DEFAULT FALSE,
+		minute INTEGER DEFAULT 0,
+		administrative TEXT
+	);`
+}
+
+func matchEventsTable(name string) string {
+	return `
+	CREATE TABLE IF NOT EXISTS ` + name + ` (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+		minute INTEGER,
+		team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE RESTRICT,
+		type TEXT,
+		detail TEXT
+	);`
+}
+
+func archivedMatchesTable(name string) string {
+	return `
+	CREATE TABLE IF NOT EXISTS ` + name + ` (
+		archive_id INTEGER REFERENCES fixture_archives(id) ON DELETE CASCADE,
+		match_id INTEGER,
+		home_team_id INTEGER REFERENCES teams(id) ON DELETE RESTRICT,
+		away_team_id INTEGER REFERENCES teams(id) ON DELETE RESTRICT,
+		home_goals INTEGER,
+		away_goals INTEGER,
+		played BOOLEAN,
+		week INTEGER,
+		postponed BOOLEAN,
+		kickoff TEXT,
+		commentary TEXT
+	);`
+}
+
+// requiredIndexes keep the hot queries off full table scans, see
+// queryplan.go. Databases from before an index was added get it on the next
+// start.
+var requiredIndexes = []struct{ name, on string }{
+	{"idx_matches_week_played", "matches(week, played)"},
+	{"idx_matches_played", "matches(played)"},
+	{"idx_matches_home_team", "matches(home_team_id)"},
+	{"idx_matches_away_team", "matches(away_team_id)"},
+	{"idx_matches_kickoff_date", "matches(substr(kickoff, 1, 10))"},
+	{"idx_match_events_match", "match_events(match_id)"},
+	{"idx_match_events_team", "match_events(team_id)"},
+	{"idx_match_events_player", "match_events(player_id)"},
+	{"idx_managers_team", "managers(team_id)"},
+	{"idx_user_predictions_match", "user_predictions(match_id)"},
+	{"idx_administrative_decisions_match", "administrative_decisions(match_id)"},
+	{"idx_team_aliases_team", "team_aliases(team_id)"},
+	{"idx_team_aliases_name", "team_aliases(name)"},
+	{"idx_archived_matches_home", "archived_matches(archive_id, home_team_id, home_goals, away_goals, played)"},
+	{"idx_archived_matches_away", "archived_matches(archive_id, away_team_id, away_goals, home_goals, played)"},
+	{"idx_outbox_status_next", "outbox(status, next_attempt_at)"},
+}
+
+// createIndexes creates the required indexes that are missing. An existing
+// database missing some gets a line per index created.
+func (l *League) createIndexes() error {
+	existing := make(map[string]bool)
+	rows, err := l.db.Query(`SELECT name FROM sqlite_master WHERE type = 'index' AND name LIKE 'idx\_%' ESCAPE '\'`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, index := range requiredIndexes {
+		if existing[index.name] {
+			continue
+		}
+		if _, err := l.db.Exec("CREATE INDEX " + index.name + " ON " + index.on); err != nil {
+			return fmt.Errorf("error creating index %s: %v", index.name, err)
+		}
+		if len(existing) > 0 {
+			fmt.Printf("Created missing index %s\n", index.name)
+		}
+	}
+	return nil
+}
+
+func (l *League) hasColumn(table, column string) (bool, error) {
+	rows, err := l.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// migrateToTeamIDs rebuilds tables from versions that stored team names in
+// matches, events and archives. It runs once, the next start finds nothing to do.
+func (l *League) migrateToTeamIDs() error {
+	legacyMatches, err := l.hasColumn("matches", "home_team")
+	if err != nil {
+		return err
+	}
+	legacyEvents, err := l.hasColumn("match_events", "team")
+	if err != nil {
+		return err
+	}
+	legacyArchive, err := l.hasColumn("archived_matches", "home_team")
+	if err != nil {
+		return err
+	}
+	if !legacyMatches && !legacyEvents && !legacyArchive {
+		return nil
+	}
+
+	// columns that old databases may still be missing
+	if legacyMatches {
+		for _, col := range [][2]string{
+			{"commentary", "TEXT DEFAULT ''"},
+			{"postponed", "BOOLEAN DEFAULT FALSE"},
+			{"kickoff", "TEXT"},
+		} {
+			if err := l.addColumnIfMissing("matches", col[0], col[1]); err != nil {
+				return err
+			}
+		}
+	}
+	if err := l.createArchiveTables(); err != nil {
+		return err
+	}
+
+	// Foreign keys have to be off while tables are swapped, and the pragma is
+	// per connection, so the whole migration runs on one
+	ctx := context.Background()
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if legacyMatches {
+		err := rebuildTable(tx, "matches", matchesTable("matches_new"), `
+			INSERT INTO matches_new
+				(id, home_team_id, away_team_id, home_goals, away_goals, played, week, commentary, postponed, kickoff)
+			SELECT m.id, h.id, a.id, m.home_goals, m.away_goals, m.played, m.week, m.commentary, m.postponed, m.kickoff
+			FROM matches m
+			JOIN teams h ON h.name = m.home_team
+			JOIN teams a ON a.name = m.away_team`)
+		if err != nil {
+			return err
+		}
+	}
+
+	if legacyEvents {
+		// events of matches that were archived belong with the archive now
+		_, err := tx.Exec(`
+			INSERT INTO archived_match_events (archive_id, match_id, minute, team_id, type, detail)
+			SELECT am.archive_id, e.match_id, e.minute, t.id, e.type, e.detail
+			FROM match_events e
+			JOIN archived_matches am ON am.match_id = e.match_id
+			JOIN teams t ON t.name = e.team
+			WHERE e.match_id NOT IN (SELECT id FROM matches)`)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM match_events WHERE match_id NOT IN (SELECT id FROM matches)")
+		if err != nil {
+			return err
+		}
+
+		err = rebuildTable(tx, "match_events", matchEventsTable("match_events_new"), `
+			INSERT INTO match_events_new (id, match_id, minute, team_id, type, detail)
+			SELECT e.id, e.match_id, e.minute, t.id, e.type, e.detail
+			FROM match_events e
+			JOIN teams t ON t.name = e.team`)
+		if err != nil {
+			return err
+		}
+	}
+
+	if legacyArchive {
+		err := rebuildTable(tx, "archived_matches", archivedMatchesTable("archived_matches_new"), `
+			INSERT INTO archived_matches_new
+				(archive_id, match_id, home_team_id, away_team_id, home_goals, away_goals, played, week, postponed, kickoff, commentary)
+			SELECT am.archive_id, am.match_id, h.id, a.id, am.home_goals, am.away_goals, am.played, am.week, am.postponed, am.kickoff, am.commentary
+			FROM archived_matches am
+			JOIN teams h ON h.name = am.home_team
+			JOIN teams a ON a.name = am.away_team`)
+		if err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	broken := rows.Next()
+	rows.Close()
+	if broken {
+		return fmt.Errorf("foreign key check failed after migration")
+	}
+
+	return tx.Commit()
+}
+
+// rebuildTable copies a table into its new layout and swaps it in. Every row
+// has to survive the copy, a team name that no longer exists aborts it.
+func rebuildTable(tx *sql.Tx, table, create, copyRows string) error {
+	var before int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&before); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(create); err != nil {
+		return fmt.Errorf("error creating new %s: %v", table, err)
+	}
+	res, err := tx.Exec(copyRows)
+	if err != nil {
+		return fmt.Errorf("error copying %s: %v", table, err)
+	}
+	copied, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(copied) != before {
+		return fmt.Errorf("%d rows of %s reference unknown teams", before-int(copied), table)
+	}
+	if _, err := tx.Exec("DROP TABLE " + table); err != nil {
+		return err
+	}
+	_, err = tx.Exec("ALTER TABLE " + table + "_new RENAME TO " + table)
+	return err
+}
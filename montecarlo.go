@@ -0,0 +1,275 @@
+package insider
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+const (
+	defaultRuns = 1000
+	maxRuns     = 20000
+)
+
+// runsParam reads the optional ?runs= query parameter
+func runsParam(r *http.Request) (int, error) {
+	runsStr := r.URL.Query().Get("runs")
+	if runsStr == "" {
+		return defaultRuns, nil
+	}
+	runs, err := strconv.Atoi(runsStr)
+	if err != nil || runs < 1 || runs > maxRuns {
+		return 0, fmt.Errorf("runs must be between 1 and %d", maxRuns)
+	}
+	return runs, nil
+}
+
+// seasonState is an in-memory copy of the league that simulations can replay
+// without touching the database
+type seasonState struct {
+	teams     []string
+	strengths map[string]int
+	matches   []Match
+	// sport is taken from the config when the state is loaded
+	sport Sport
+	// home holds the teams with a home advantage of their own
+	home homeAdvantages
+	// scripts of the unplayed matches, by match id
+	scripts map[int]MatchScript
+	// popularity by team as it is now, only with pressure on
+	popularity map[string]float64
+}
+
+func (l *League) loadSeasonState() (*seasonState, error) {
+	cfg := l.config()
+	state := &seasonState{strengths: make(map[string]int), sport: cfg.Sport}
+	for _, t := range l.Teams() {
+		state.teams = append(state.teams, t.Name)
+		state.strengths[t.Name] = t.Strength
+	}
+
+	matches, err := l.Matches()
+	if err != nil {
+		return nil, err
+	}
+	// a copy without annulled matches or ones against teams that have left,
+	// in week order
+	for _, m := range matches {
+		_, home := state.strengths[m.HomeTeam]
+		_, away := state.strengths[m.AwayTeam]
+		if m.Administrative != AdminAnnulled && home && away {
+			state.matches = append(state.matches, m)
+		}
+	}
+	sort.SliceStable(state.matches, func(i, j int) bool {
+		return state.matches[i].Week < state.matches[j].Week
+	})
+	if state.scripts, err = l.unplayedScripts(); err != nil {
+		return nil, err
+	}
+	if cfg.Pressure {
+		fans, err := l.popularity()
+		if err != nil {
+			return nil, err
+		}
+		state.popularity = make(map[string]float64, len(state.teams))
+		for _, t := range l.Teams() {
+			state.popularity[t.Name] = fans.current[t.ID]
+		}
+	}
+	played, _ := state.current()
+	state.home = newHomeAdvantages(cfg.HomeAdvantages.resolve(cfg.Simulation, state.teams, state.strengths, played))
+	return state, nil
+}
+
+// matchStrengths are the strengths a remaining match is played with. A big
+// match takes its pressure off both sides as a played week would, judged on
+// the popularity now rather than on what the season brings until then.
+func (s *seasonState) matchStrengths(m Match) (home, away int) {
+	home, away = s.strengths[m.HomeTeam], s.strengths[m.AwayTeam]
+	if s.popularity == nil {
+		return home, away
+	}
+	homeFans, awayFans := s.popularity[m.HomeTeam], s.popularity[m.AwayTeam]
+	if isBigMatch(homeFans, awayFans) {
+		home -= pressure(homeFans)
+		away -= pressure(awayFans)
+	}
+	return home, away
+}
+
+// split separates the matches already played up to and including week from
+// the ones still to be decided. Results after that week count as unplayed.
+func (s *seasonState) split(week int) (played, remaining []Match) {
+	for _, m := range s.matches {
+		if m.Played && m.Week <= week {
+			played = append(played, m)
+		} else {
+			remaining = append(remaining, m)
+		}
+	}
+	return played, remaining
+}
+
+// current splits on every result recorded so far
+func (s *seasonState) current() (played, remaining []Match) {
+	return s.split(math.MaxInt)
+}
+
+// latestWeek is the highest week that has at least one result
+func (s *seasonState) latestWeek() int {
+	latest := 0
+	for _, m := range s.matches {
+		if m.Played && m.Week > latest {
+			latest = m.Week
+		}
+	}
+	return latest
+}
+
+// standings builds a sorted table from played matches
+func (s *seasonState) standings(played []Match) []Standing {
+	standingsMap := make(map[string]*Standing, len(s.teams))
+	for _, name := range s.teams {
+		standingsMap[name] = &Standing{TeamName: name}
+	}
+	for _, m := range played {
+		s.sport.recordResult(standingsMap[m.HomeTeam], standingsMap[m.AwayTeam], m.Week, m.HomeGoals, m.AwayGoals)
+	}
+
+	standings := make([]Standing, 0, len(s.teams))
+	for _, name := range s.teams {
+		s := standingsMap[name]
+		s.GoalDifference = s.GoalsFor - s.GoalsAgainst
+		standings = append(standings, *s)
+	}
+	s.sport.sortStandings(standings)
+	s.sport.rankStandings(standings, false)
+	return standings
+}
+
+// positionRange is the best and worst final position a team can still reach
+type positionRange struct {
+	best, worst int
+}
+
+// positionRanges bounds every team's final position from the points still
+// available. Teams level on points may finish either way round, so only a
+// rival that is sure to finish with more points is surely above.
+func (s *seasonState) positionRanges(played, remaining []Match) map[string]positionRange {
+	points := make(map[string]int)
+	for _, st := range s.standings(played) {
+		points[st.TeamName] = st.Points
+	}
+	most, fewest := s.sport.pointsLeft(remaining)
+	maxPoints := func(team string) int {
+		return points[team] + most[team]
+	}
+	minPoints := func(team string) int {
+		return points[team] + fewest[team]
+	}
+
+	ranges := make(map[string]positionRange, len(s.teams))
+	for _, team := range s.teams {
+		r := positionRange{best: 1, worst: 1}
+		for _, other := range s.teams {
+			if other == team {
+				continue
+			}
+			if minPoints(other) > maxPoints(team) {
+				r.best++
+			}
+			if maxPoints(other) >= minPoints(team) {
+				r.worst++
+			}
+		}
+		ranges[team] = r
+	}
+	return ranges
+}
+
+// simulationSummary aggregates many simulated endings of the same season
+type simulationSummary struct {
+	Runs int
+	// Positions[team][i] counts the runs where team finished in position i+1
+	Positions map[string][]int
+	// Points[team] sums the final points over all runs
+	Points map[string]int
+	// HomePoints and AwayPoints sum the points won in the remaining home and
+	// away matches only
+	HomePoints map[string]int
+	AwayPoints map[string]int
+}
+
+// expected divides a per-run total by the number of runs
+func (s *simulationSummary) expected(total int) float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(total) / float64(s.Runs)
+}
+
+// probability of finishing in the given 1-based position
+func (s *simulationSummary) probability(team string, position int) float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Positions[team][position-1]) / float64(s.Runs)
+}
+
+// monteCarlo plays the remaining matches runs times on top of the played ones
+func monteCarlo(rng *rand.Rand, params SimParams, state *seasonState, played, remaining []Match, runs int) *simulationSummary {
+	summary := newSimulationSummary(state.teams)
+	summary.simulate(rng, params, state, state.standings(played), remaining, runs)
+	return summary
+}
+
+func newSimulationSummary(teams []string) *simulationSummary {
+	summary := &simulationSummary{
+		Positions: make(map[string][]int, len(teams)),
+		Points:    make(map[string]int, len(teams)),
+
+		HomePoints: make(map[string]int, len(teams)),
+		AwayPoints: make(map[string]int, len(teams)),
+	}
+	for _, name := range teams {
+		summary.Positions[name] = make([]int, len(teams))
+	}
+	return summary
+}
+
+// simulate adds runs more simulated endings starting from the base table.
+// Calling it in chunks gives the same result as one call with the total.
+func (s *simulationSummary) simulate(rng *rand.Rand, params SimParams, state *seasonState, base []Standing, remaining []Match, runs int) {
+	for run := 0; run < runs; run++ {
+		table := make([]Standing, len(base))
+		copy(table, base)
+		teamMap := make(map[string]*Standing, len(table))
+		for i := range table {
+			teamMap[table[i].TeamName] = &table[i]
+		}
+
+		for _, m := range remaining {
+			p := state.home.params(params, m.HomeTeam)
+			homeStrength, awayStrength := state.matchStrengths(m)
+			homeGoals, awayGoals := p.Score(rng, homeStrength, awayStrength)
+			homeGoals, awayGoals = state.scripts[m.ID].apply(homeGoals, awayGoals, p.Overtime)
+			state.sport.recordResult(teamMap[m.HomeTeam], teamMap[m.AwayTeam], m.Week, homeGoals, awayGoals)
+			s.HomePoints[m.HomeTeam] += state.sport.matchPoints(m.Week, homeGoals, awayGoals)
+			s.AwayPoints[m.AwayTeam] += state.sport.matchPoints(m.Week, awayGoals, homeGoals)
+		}
+		for i := range table {
+			table[i].GoalDifference = table[i].GoalsFor - table[i].GoalsAgainst
+		}
+		state.sport.sortStandings(table)
+
+		for i, st := range table {
+			s.Positions[st.TeamName][i]++
+			s.Points[st.TeamName] += st.Points
+		}
+		s.Runs++
+	}
+}
@@ -0,0 +1,395 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// A multiverse forks the season as it stands into a number of universes and
+// plays each one to the end with its own seed. Unlike a Monte Carlo run,
+// which only leaves its mark on averages, every universe is kept: its final
+// table and every result in it, to be looked at one by one or side by side.
+// The league itself is not touched.
+
+const maxUniverses = 100
+
+// ErrInvalidUniverses is returned for a universe count out of range
+var ErrInvalidUniverses = errors.New("invalid number of universes")
+
+// Universe is one way the rest of the season could go. Results only holds
+// the matches that were simulated, and is left out of the comparison.
+type Universe struct {
+	Universe  int        `json:"universe"`
+	Seed      int64      `json:"seed"`
+	Champion  string     `json:"champion"`
+	Standings []Standing `json:"standings"`
+	Results   []Match    `json:"results,omitempty"`
+}
+
+// TeamAcrossUniverses is where a team finished in each universe
+type TeamAcrossUniverses struct {
+	TeamName      string  `json:"team_name"`
+	Positions     []int   `json:"positions"`
+	Points        []int   `json:"points"`
+	AveragePoints float64 `json:"average_points"`
+	BestPosition  int     `json:"best_position"`
+	WorstPosition int     `json:"worst_position"`
+	Titles        int     `json:"titles"`
+}
+
+// Multiverse is a set of universes forked from the same state. Teams
+// compares them, ordered by average finishing position.
+type Multiverse struct {
+	ID            int                   `json:"id"`
+	Universes     int                   `json:"universes"`
+	Seed          int64                 `json:"seed"`
+	StateVersion  int64                 `json:"state_version"`
+	PlayedMatches int                   `json:"played_matches"`
+	CreatedAt     time.Time             `json:"created_at"`
+	Teams         []TeamAcrossUniverses `json:"teams,omitempty"`
+	Worlds        []Universe            `json:"worlds,omitempty"`
+}
+
+func (l *League) createMultiverseTables() error {
+	createMultiverses := `
+	CREATE TABLE IF NOT EXISTS multiverses (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		universes INTEGER NOT NULL,
+		seed INTEGER NOT NULL,
+		state_version INTEGER NOT NULL,
+		played_matches INTEGER NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);`
+	createUniverses := `
+	CREATE TABLE IF NOT EXISTS multiverse_universes (
+		multiverse_id INTEGER NOT NULL REFERENCES multiverses(id) ON DELETE CASCADE,
+		universe INTEGER NOT NULL,
+		seed INTEGER NOT NULL,
+		champion TEXT NOT NULL,
+		standings TEXT NOT NULL,
+		results TEXT NOT NULL,
+		PRIMARY KEY (multiverse_id, universe)
+	);`
+
+	if _, err := l.db.Exec(createMultiverses); err != nil {
+		return fmt.Errorf("error creating multiverses table: %v", err)
+	}
+	if _, err := l.db.Exec(createUniverses); err != nil {
+		return fmt.Errorf("error creating multiverse_universes table: %v", err)
+	}
+	return nil
+}
+
+// playUniverse plays the remaining matches once, from its own seed
+func (s *seasonState) playUniverse(params SimParams, played, remaining []Match, n int, seed int64) Universe {
+	rng := rand.New(rand.NewSource(seed))
+	results := make([]Match, 0, len(remaining))
+	for _, m := range remaining {
+		homeStrength, awayStrength := s.matchStrengths(m)
+		m.HomeGoals, m.AwayGoals = s.home.params(params, m.HomeTeam).Score(rng, homeStrength, awayStrength)
+		m.Played, m.Postponed, m.Live, m.Minute = true, false, false, 0
+		m.Status = matchStatus(m)
+		results = append(results, m)
+	}
+	standings := s.standings(append(append([]Match{}, played...), results...))
+	return Universe{Universe: n, Seed: seed, Champion: standings[0].TeamName, Standings: standings, Results: results}
+}
+
+// CreateMultiverse forks the league into n universes and plays each to the
+// end. Universe i is played from seed+i; without a seed one is drawn.
+func (l *League) CreateMultiverse(n int, seed *int64) (*Multiverse, error) {
+	if n < 1 || n > maxUniverses {
+		return nil, fmt.Errorf("%w: n must be between 1 and %d", ErrInvalidUniverses, maxUniverses)
+	}
+	version := l.version.Load()
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return nil, err
+	}
+	mv := &Multiverse{Universes: n, StateVersion: version, CreatedAt: time.Now().UTC()}
+	if seed != nil {
+		mv.Seed = *seed
+	} else {
+		mv.Seed = l.rng.Int63()
+	}
+
+	ids := make(map[string]int)
+	for _, t := range l.Teams() {
+		ids[t.Name] = t.ID
+	}
+	params := l.config().Simulation
+	played, remaining := state.current()
+	mv.PlayedMatches = len(played)
+	for i := 1; i <= n; i++ {
+		u := state.playUniverse(params, played, remaining, i, mv.Seed+int64(i))
+		for j := range u.Standings {
+			u.Standings[j].TeamID = ids[u.Standings[j].TeamName]
+		}
+		mv.Worlds = append(mv.Worlds, u)
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("INSERT INTO multiverses (universes, seed, state_version, played_matches, created_at) VALUES (?, ?, ?, ?, ?)",
+		mv.Universes, mv.Seed, mv.StateVersion, mv.PlayedMatches, mv.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	mv.ID = int(id)
+	for _, u := range mv.Worlds {
+		standings, err := json.Marshal(u.Standings)
+		if err != nil {
+			return nil, err
+		}
+		results, err := json.Marshal(u.Results)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec("INSERT INTO multiverse_universes (multiverse_id, universe, seed, champion, standings, results) VALUES (?, ?, ?, ?, ?, ?)",
+			mv.ID, u.Universe, u.Seed, u.Champion, string(standings), string(results)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	mv.compare()
+	return mv, nil
+}
+
+// compare lines the universes up team by team and drops their results
+func (mv *Multiverse) compare() {
+	byTeam := make(map[string]*TeamAcrossUniverses)
+	var teams []*TeamAcrossUniverses
+	for i := range mv.Worlds {
+		u := &mv.Worlds[i]
+		u.Results = nil
+		for pos, s := range u.Standings {
+			t := byTeam[s.TeamName]
+			if t == nil {
+				t = &TeamAcrossUniverses{TeamName: s.TeamName, BestPosition: pos + 1, WorstPosition: pos + 1}
+				byTeam[s.TeamName] = t
+				teams = append(teams, t)
+			}
+			t.Positions = append(t.Positions, pos+1)
+			t.Points = append(t.Points, s.Points)
+			t.BestPosition = min(t.BestPosition, pos+1)
+			t.WorstPosition = max(t.WorstPosition, pos+1)
+			if pos == 0 {
+				t.Titles++
+			}
+		}
+	}
+
+	average := func(values []int) float64 {
+		total := 0
+		for _, v := range values {
+			total += v
+		}
+		return float64(total) / float64(len(values))
+	}
+	for _, t := range teams {
+		t.AveragePoints = average(t.Points)
+	}
+	sort.SliceStable(teams, func(i, j int) bool {
+		return average(teams[i].Positions) < average(teams[j].Positions)
+	})
+	mv.Teams = make([]TeamAcrossUniverses, 0, len(teams))
+	for _, t := range teams {
+		mv.Teams = append(mv.Teams, *t)
+	}
+}
+
+const multiverseSelect = "SELECT id, universes, seed, state_version, played_matches, created_at FROM multiverses"
+
+func scanMultiverse(row rowScanner) (*Multiverse, error) {
+	var mv Multiverse
+	err := row.Scan(&mv.ID, &mv.Universes, &mv.Seed, &mv.StateVersion, &mv.PlayedMatches, &mv.CreatedAt)
+	return &mv, err
+}
+
+// Multiverses lists every multiverse without its universes, newest first
+func (l *League) Multiverses() ([]Multiverse, error) {
+	rows, err := l.db.Query(multiverseSelect + " ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	multiverses := []Multiverse{}
+	for rows.Next() {
+		mv, err := scanMultiverse(rows)
+		if err != nil {
+			return nil, err
+		}
+		multiverses = append(multiverses, *mv)
+	}
+	return multiverses, rows.Err()
+}
+
+// universes reads the universes of a multiverse, with their results when
+// withResults is set, in order; universe 0 is all of them
+func (l *League) universes(id, universe int, withResults bool) ([]Universe, error) {
+	query := "SELECT universe, seed, champion, standings, results FROM multiverse_universes WHERE multiverse_id = ?"
+	args := []any{id}
+	if universe != 0 {
+		query += " AND universe = ?"
+		args = append(args, universe)
+	}
+	rows, err := l.db.Query(query+" ORDER BY universe", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var universes []Universe
+	for rows.Next() {
+		var u Universe
+		var standings, results string
+		if err := rows.Scan(&u.Universe, &u.Seed, &u.Champion, &standings, &results); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(standings), &u.Standings); err != nil {
+			return nil, err
+		}
+		if withResults {
+			if err := json.Unmarshal([]byte(results), &u.Results); err != nil {
+				return nil, err
+			}
+		}
+		universes = append(universes, u)
+	}
+	return universes, rows.Err()
+}
+
+// Multiverse reads a multiverse with the comparison of its universes,
+// sql.ErrNoRows when there is none
+func (l *League) Multiverse(id int) (*Multiverse, error) {
+	mv, err := scanMultiverse(l.db.QueryRow(multiverseSelect+" WHERE id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	if mv.Worlds, err = l.universes(id, 0, false); err != nil {
+		return nil, err
+	}
+	mv.compare()
+	return mv, nil
+}
+
+// Universe reads one universe with all of its results, sql.ErrNoRows when
+// there is none
+func (l *League) Universe(id, universe int) (*Universe, error) {
+	universes, err := l.universes(id, universe, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(universes) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &universes[0], nil
+}
+
+// POST /multiverse?n=10 forks the season into n universes, ?seed= makes
+// them repeatable; GET /multiverse lists the multiverses
+func (l *League) handleMultiverses(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		multiverses, err := l.Multiverses()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(multiverses)
+	case http.MethodPost:
+		q := r.URL.Query()
+		n := 10
+		if s := q.Get("n"); s != "" {
+			var err error
+			if n, err = strconv.Atoi(s); err != nil {
+				http.Error(w, "Invalid n parameter", http.StatusBadRequest)
+				return
+			}
+		}
+		var seed *int64
+		if s := q.Get("seed"); s != "" {
+			v, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid seed", http.StatusBadRequest)
+				return
+			}
+			seed = &v
+		}
+
+		mv, err := l.CreateMultiverse(n, seed)
+		if errors.Is(err, ErrInvalidUniverses) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(mv)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /multiverse/{id} compares the universes of a multiverse
+func (l *League) handleMultiverse(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid multiverse id", http.StatusBadRequest)
+		return
+	}
+	mv, err := l.Multiverse(id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Multiverse not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(mv)
+}
+
+// GET /multiverse/{id}/universes/{n} is one universe with its results
+func (l *League) handleUniverse(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid multiverse id", http.StatusBadRequest)
+		return
+	}
+	n, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || n < 1 {
+		http.Error(w, "Invalid universe", http.StatusBadRequest)
+		return
+	}
+	u, err := l.Universe(id, n)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Universe not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(u)
+}
@@ -0,0 +1,53 @@
+package insider_test
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestMultiverse(t *testing.T) {
+	h := leaguetest.New(t, nil, 3)
+	h.SimulateWeek(1)
+	before := h.Matches()
+
+	var first, second insider.Multiverse
+	if status := h.Do(http.MethodPost, "/multiverse?n=4&seed=9", nil, false, &first); status != http.StatusCreated {
+		t.Fatalf("create: status %d", status)
+	}
+	if status := h.Do(http.MethodPost, "/multiverse?n=4&seed=9", nil, false, &second); status != http.StatusCreated {
+		t.Fatalf("create again: status %d", status)
+	}
+	if !reflect.DeepEqual(first.Teams, second.Teams) {
+		t.Errorf("same seed, different universes:\n%+v\n%+v", first.Teams, second.Teams)
+	}
+	if !reflect.DeepEqual(h.Matches(), before) {
+		t.Errorf("creating a multiverse changed the league")
+	}
+
+	titles := 0
+	for _, team := range first.Teams {
+		if len(team.Positions) != 4 {
+			t.Errorf("%s: %d positions, want 4", team.TeamName, len(team.Positions))
+		}
+		titles += team.Titles
+	}
+	if titles != 4 {
+		t.Errorf("%d titles over 4 universes", titles)
+	}
+
+	var stored insider.Multiverse
+	h.Get(fmt.Sprintf("/multiverse/%d", first.ID), &stored)
+	if !reflect.DeepEqual(stored.Teams, first.Teams) {
+		t.Errorf("stored comparison differs:\n%+v\n%+v", stored.Teams, first.Teams)
+	}
+	var u insider.Universe
+	h.Get(fmt.Sprintf("/multiverse/%d/universes/2", first.ID), &u)
+	if unplayed := len(before) - 2; len(u.Results) != unplayed || u.Champion != u.Standings[0].TeamName {
+		t.Errorf("universe 2: %d results, want %d; champion %s", len(u.Results), unplayed, u.Champion)
+	}
+}
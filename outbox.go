@@ -0,0 +1,305 @@
+package insider
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Webhook deliveries go through the outbox table: an event is stored once
+// per target and a single worker posts it, retrying a failed post after
+// 2s, 4s, 8s... up to an hour apart. The rows outlive a restart, so a
+// delivery that was still waiting is picked up by the next worker. After
+// maxOutboxAttempts failures the delivery is left as failed for an admin to
+// requeue. Backoff runs on real time, also in clock mode.
+
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxFailed    = "failed"
+)
+
+const (
+	maxOutboxAttempts = 8
+	outboxBackoff     = 2 * time.Second
+	maxOutboxBackoff  = time.Hour
+	// outboxTick is how often the worker looks for retries that are due
+	outboxTick = time.Second
+	// outboxBatch is the most deliveries one pass of the worker posts
+	outboxBatch = 50
+)
+
+// ErrNotFailed is returned when requeueing a delivery that has not failed
+var ErrNotFailed = errors.New("only failed deliveries can be requeued")
+
+// OutboxDelivery is one event on its way to one webhook
+type OutboxDelivery struct {
+	ID            int        `json:"id"`
+	EventType     string     `json:"event_type"`
+	Target        string     `json:"target"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+func (l *League) createOutboxTable() error {
+	createOutbox := `
+	CREATE TABLE IF NOT EXISTS outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_type TEXT NOT NULL,
+		target TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		delivered_at TIMESTAMP
+	);`
+
+	if _, err := l.db.Exec(createOutbox); err != nil {
+		return fmt.Errorf("error creating outbox table: %v", err)
+	}
+	return nil
+}
+
+// enqueue stores a payload for every target and wakes the worker
+func (l *League) enqueue(eventType string, body []byte, targets []string) error {
+	now := time.Now().UTC()
+	for _, target := range targets {
+		if _, err := l.db.Exec("INSERT INTO outbox (event_type, target, payload, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?)",
+			eventType, target, string(body), now, now); err != nil {
+			return err
+		}
+	}
+	select {
+	case l.outboxWake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// outboxRetryDelay is how long to wait after the given number of failures
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxBackoff
+	for i := 1; i < attempts && delay < maxOutboxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxOutboxBackoff)
+}
+
+// deliverOutbox posts the pending deliveries due by now, oldest first, and
+// returns how many went through
+func (l *League) deliverOutbox(now time.Time) (int, error) {
+	rows, err := l.db.Query(`
+		SELECT id, target, payload, attempts FROM outbox
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY id LIMIT ?`, OutboxPending, now, outboxBatch)
+	if err != nil {
+		return 0, err
+	}
+	type due struct {
+		id       int
+		target   string
+		payload  string
+		attempts int
+	}
+	var batch []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.id, &d.target, &d.payload, &d.attempts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, d := range batch {
+		err := postWebhook(d.target, []byte(d.payload))
+		if err == nil {
+			delivered++
+			_, err = l.db.Exec("UPDATE outbox SET status = ?, attempts = ?, next_attempt_at = NULL, last_error = '', delivered_at = ? WHERE id = ?",
+				OutboxDelivered, d.attempts+1, time.Now().UTC(), d.id)
+			if err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		attempts := d.attempts + 1
+		status, next := OutboxPending, sql.NullTime{Time: now.Add(outboxRetryDelay(attempts)), Valid: true}
+		if attempts >= maxOutboxAttempts {
+			status, next = OutboxFailed, sql.NullTime{}
+			fmt.Printf("Webhook %s failed %d times, giving up: %v\n", d.target, attempts, err)
+		}
+		if _, err := l.db.Exec("UPDATE outbox SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?",
+			status, attempts, next, err.Error(), d.id); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// postWebhook posts one payload; anything but a 2xx answer is a failure
+func postWebhook(target string, body []byte) error {
+	resp, err := webhookClient.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("answered %s", resp.Status)
+	}
+	return nil
+}
+
+// runOutbox delivers the outbox until the server stops, as soon as something
+// is enqueued and every outboxTick for the retries
+func (l *League) runOutbox() {
+	ticker := time.NewTicker(outboxTick)
+	defer ticker.Stop()
+	for {
+		if _, err := l.deliverOutbox(time.Now().UTC()); err != nil {
+			fmt.Println("Outbox delivery failed:", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-l.outboxWake:
+		}
+	}
+}
+
+// OutboxDeliveries lists the deliveries with the given status, or all of
+// them, newest first
+func (l *League) OutboxDeliveries(status string, limit int) ([]OutboxDelivery, error) {
+	query := `SELECT id, event_type, target, status, attempts, next_attempt_at, last_error, created_at, delivered_at FROM outbox`
+	var args []any
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []OutboxDelivery{}
+	for rows.Next() {
+		var d OutboxDelivery
+		var next, delivered sql.NullTime
+		if err := rows.Scan(&d.ID, &d.EventType, &d.Target, &d.Status, &d.Attempts, &next, &d.LastError, &d.CreatedAt, &delivered); err != nil {
+			return nil, err
+		}
+		if next.Valid {
+			d.NextAttemptAt = &next.Time
+		}
+		if delivered.Valid {
+			d.DeliveredAt = &delivered.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RequeueDelivery gives a failed delivery a fresh set of attempts, starting
+// now
+func (l *League) RequeueDelivery(id int) error {
+	var status string
+	if err := l.db.QueryRow("SELECT status FROM outbox WHERE id = ?", id).Scan(&status); err != nil {
+		return err
+	}
+	if status != OutboxFailed {
+		return fmt.Errorf("%w: delivery %d is %s", ErrNotFailed, id, status)
+	}
+	if _, err := l.db.Exec("UPDATE outbox SET status = ?, attempts = 0, next_attempt_at = ? WHERE id = ?",
+		OutboxPending, time.Now().UTC(), id); err != nil {
+		return err
+	}
+	select {
+	case l.outboxWake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// GET /admin/outbox lists the webhook deliveries, ?status=failed for one
+// status and ?limit= for more than 100
+func (l *League) handleOutbox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", OutboxPending, OutboxDelivered, OutboxFailed:
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	limit := 100
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := l.OutboxDeliveries(status, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// POST /admin/outbox/{id}/requeue retries a failed delivery
+func (l *League) handleRequeueDelivery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	err = l.RequeueDelivery(id)
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrNotFailed):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Delivery requeued"})
+}
@@ -0,0 +1,86 @@
+package insider_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestOutboxRetriesAndRequeues(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 1)
+	var calls, failing atomic.Int64
+	failing.Store(2)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() > 0 {
+			failing.Add(-1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer hook.Close()
+	cfg := *h.League.Config()
+	cfg.Webhooks = []string{hook.URL}
+	h.League.StoreConfig(&cfg)
+
+	h.League.NotifyWebhooks(insider.EventTypeAnnouncement, map[string]string{"text": "hello"})
+	now := time.Now().UTC()
+	for i, wait := range []time.Duration{0, 0, insider.OutboxBackoff, insider.OutboxBackoff * 2} {
+		now = now.Add(wait)
+		delivered, err := h.League.DeliverOutbox(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the second pass comes before the first retry is due
+		if want := map[int]int{3: 1}[i]; delivered != want {
+			t.Errorf("pass %d delivered %d, want %d", i, delivered, want)
+		}
+	}
+	if calls.Load() != 3 {
+		t.Errorf("webhook called %d times, want 3", calls.Load())
+	}
+
+	// a league opened on the same database takes over what is still pending
+	failing.Store(insider.MaxOutboxAttempts)
+	h.League.NotifyWebhooks(insider.EventTypeAnnouncement, map[string]string{"text": "again"})
+	reopened := insider.NewLeague(h.League.DB(), insider.SnapshotTeams, insider.FixtureWeeks(len(insider.SnapshotTeams)), nil, nil)
+	if err := reopened.InitDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < insider.MaxOutboxAttempts; i++ {
+		now = now.Add(insider.MaxOutboxBackoff)
+		if _, err := reopened.DeliverOutbox(now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var failed []insider.OutboxDelivery
+	if status := h.Do(http.MethodGet, "/admin/outbox?status=failed", nil, true, &failed); status != http.StatusOK {
+		t.Fatalf("GET /admin/outbox: status %d", status)
+	}
+	if len(failed) != 1 || failed[0].Attempts != insider.MaxOutboxAttempts || failed[0].LastError == "" || failed[0].NextAttemptAt != nil {
+		t.Fatalf("failed deliveries: %+v", failed)
+	}
+
+	path := "/admin/outbox/" + strconv.Itoa(failed[0].ID) + "/requeue"
+	if status := h.Do(http.MethodPost, path, nil, true, nil); status != http.StatusOK {
+		t.Fatalf("requeue: status %d", status)
+	}
+	if status := h.Do(http.MethodPost, path, nil, true, nil); status != http.StatusConflict {
+		t.Errorf("requeue a pending delivery: status %d, want 409", status)
+	}
+	if status := h.Do(http.MethodPost, "/admin/outbox/999/requeue", nil, true, nil); status != http.StatusNotFound {
+		t.Errorf("requeue an unknown delivery: status %d, want 404", status)
+	}
+	if delivered, err := h.League.DeliverOutbox(time.Now().UTC().Add(time.Second)); err != nil || delivered != 1 {
+		t.Errorf("after requeue: delivered %d, err %v", delivered, err)
+	}
+	if status := h.Do(http.MethodGet, "/admin/outbox", nil, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("without a token: status %d, want 401", status)
+	}
+}
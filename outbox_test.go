@@ -0,0 +1,14 @@
+package insider
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutboxRetryDelay(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: 2 * time.Second, 2: 4 * time.Second, 5: 32 * time.Second, 20: time.Hour} {
+		if got := outboxRetryDelay(attempts); got != want {
+			t.Errorf("after %d failures: %v, want %v", attempts, got, want)
+		}
+	}
+}
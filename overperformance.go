@@ -0,0 +1,125 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// overperformanceThreshold is how far above or below the model, in wins per
+// match, a team has to be to get a label
+const overperformanceThreshold = 0.15
+
+// WeekPerformance is a team's running total after a week it played in
+type WeekPerformance struct {
+	Week           int     `json:"week"`
+	Points         int     `json:"points"`
+	ExpectedPoints float64 `json:"expected_points"`
+}
+
+// TeamPerformance compares the points a team has with the points the
+// simulation model expected from the same matches. Index is the difference in
+// wins per match played: 0.25 means a quarter of a win more than expected
+// every match.
+type TeamPerformance struct {
+	TeamID         int               `json:"team_id"`
+	TeamName       string            `json:"team_name"`
+	Played         int               `json:"played"`
+	Points         int               `json:"points"`
+	ExpectedPoints float64           `json:"expected_points"`
+	Index          float64           `json:"index"`
+	Label          string            `json:"label,omitempty"`
+	Weeks          []WeekPerformance `json:"weeks"`
+}
+
+// Overperformance rates every team against the model, best first. Expected
+// points are the ones stored with the pre-match odds when the result came
+// in, see surprise.go, so a later strength change leaves the history alone.
+// Results without odds, those decided off the pitch, are left out.
+func (l *League) Overperformance() ([]TeamPerformance, error) {
+	cfg := l.config()
+	teams := l.Teams()
+	byID := make(map[int]*TeamPerformance, len(teams))
+	for _, t := range teams {
+		byID[t.ID] = &TeamPerformance{TeamID: t.ID, TeamName: t.Name, Weeks: []WeekPerformance{}}
+	}
+
+	rows, err := l.db.Query(`
+		SELECT m.home_team_id, m.away_team_id, m.home_goals, m.away_goals, m.week,
+			p.home_win, p.draw, p.away_win, p.home_expected_points, p.away_expected_points
+		FROM matches m
+		JOIN match_probabilities p ON p.match_id = m.id
+		WHERE m.played = TRUE AND COALESCE(m.administrative, '') != 'annulled'
+		ORDER BY m.week, m.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	record := func(p *TeamPerformance, week, points int, expected float64) {
+		p.Played++
+		p.Points += points
+		p.ExpectedPoints += expected
+		if n := len(p.Weeks); n > 0 && p.Weeks[n-1].Week == week {
+			p.Weeks[n-1].Points = p.Points
+			p.Weeks[n-1].ExpectedPoints = p.ExpectedPoints
+			return
+		}
+		p.Weeks = append(p.Weeks, WeekPerformance{Week: week, Points: p.Points, ExpectedPoints: p.ExpectedPoints})
+	}
+	for rows.Next() {
+		var home, away, homeGoals, awayGoals, week int
+		var homeWin, draw, awayWin float64
+		var homeExpected, awayExpected sql.NullFloat64
+		if err := rows.Scan(&home, &away, &homeGoals, &awayGoals, &week,
+			&homeWin, &draw, &awayWin, &homeExpected, &awayExpected); err != nil {
+			return nil, err
+		}
+		// teams that have left the league since are not in the table
+		homeTeam, awayTeam := byID[home], byID[away]
+		if homeTeam == nil || awayTeam == nil {
+			continue
+		}
+		// odds stored before expected points were get them worked out
+		if !homeExpected.Valid {
+			k := float64(cfg.Sport.multiplier(week))
+			homeExpected.Float64 = k * cfg.Sport.expectedPoints(homeWin, draw, awayWin)
+			awayExpected.Float64 = k * cfg.Sport.expectedPoints(awayWin, draw, homeWin)
+		}
+		record(homeTeam, week, cfg.Sport.matchPoints(week, homeGoals, awayGoals), homeExpected.Float64)
+		record(awayTeam, week, cfg.Sport.matchPoints(week, awayGoals, homeGoals), awayExpected.Float64)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	table := make([]TeamPerformance, 0, len(teams))
+	for _, t := range teams {
+		p := byID[t.ID]
+		if p.Played > 0 && cfg.Sport.WinPoints > 0 {
+			p.Index = (float64(p.Points) - p.ExpectedPoints) / float64(p.Played*cfg.Sport.WinPoints)
+		}
+		switch {
+		case p.Index >= overperformanceThreshold:
+			p.Label = "surprise package"
+		case p.Index <= -overperformanceThreshold:
+			p.Label = "underachiever"
+		}
+		table = append(table, *p)
+	}
+	sort.SliceStable(table, func(i, j int) bool {
+		return table[i].Index > table[j].Index
+	})
+	return table, nil
+}
+
+// GET /stats/overperformance
+func (l *League) handleOverperformance(w http.ResponseWriter, r *http.Request) {
+	table, err := l.Overperformance()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(table)
+}
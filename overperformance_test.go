@@ -0,0 +1,53 @@
+package insider_test
+
+import (
+	"math"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestOverperformanceKeepsItsHistory(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 6)
+	h.SimulateWeek(1)
+	h.SimulateWeek(2)
+	var before []insider.TeamPerformance
+	h.Get("/stats/overperformance", &before)
+
+	// a strength edit changes the odds of the weeks to come, not of those
+	// already played
+	if _, err := h.League.SetStrength("Delta SC", 95, "test", "takeover"); err != nil {
+		t.Fatal(err)
+	}
+	var after []insider.TeamPerformance
+	h.Get("/stats/overperformance", &after)
+	expected := func(table []insider.TeamPerformance) map[string]float64 {
+		byName := make(map[string]float64)
+		for _, p := range table {
+			byName[p.TeamName] = p.ExpectedPoints
+		}
+		return byName
+	}
+	old, now := expected(before), expected(after)
+	for name, points := range old {
+		if math.Abs(now[name]-points) > 1e-9 {
+			t.Errorf("%s expected %.3f points before the strength change, %.3f after", name, points, now[name])
+		}
+	}
+
+	// a team gone from the league is skipped, not dereferenced
+	if _, err := h.League.DB().Exec("UPDATE teams SET active = FALSE WHERE name = 'Delta SC'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.League.LoadTeams(); err != nil {
+		t.Fatal(err)
+	}
+	table, err := h.League.Overperformance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table) != 3 {
+		t.Errorf("%d teams in the table, want 3", len(table))
+	}
+}
@@ -0,0 +1,11 @@
+package insider
+
+import "insider/matchengine"
+
+// SimParams tune the goal model, see matchengine.Params
+type SimParams = matchengine.Params
+
+var defaultSimParams = SimParams{
+	HomeAdvantage:   10,
+	StrengthPerGoal: 20,
+}
@@ -0,0 +1,83 @@
+package insider_test
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+// homeWins wins every match 3-0
+type homeWins struct{}
+
+func (homeWins) Score(rng *rand.Rand, p insider.SimParams, homeStrength, awayStrength int) (int, int, bool) {
+	return 3, 0, false
+}
+
+func (homeWins) Probabilities(p insider.SimParams, homeStrength, awayStrength int) (float64, float64, float64) {
+	return 1, 0, 0
+}
+
+// fixedScore plays every match to the score it holds; it is a slice, a
+// simulator Go cannot compare
+type fixedScore []int
+
+func (s fixedScore) Score(rng *rand.Rand, p insider.SimParams, homeStrength, awayStrength int) (int, int, bool) {
+	return s[0], s[1], false
+}
+
+func (s fixedScore) Probabilities(p insider.SimParams, homeStrength, awayStrength int) (float64, float64, float64) {
+	return 1, 0, 0
+}
+
+func TestSimulatorFromTheConstructor(t *testing.T) {
+	h := leaguetest.NewWithSimulator(t, insider.SnapshotTeams, 4, homeWins{})
+
+	h.SimulateWeek(1)
+	// presets only name a model, the simulator stays
+	if _, err := h.League.SavePreset(insider.ModelPreset{Name: "poisson", Params: insider.SimParams{Model: "poisson", HomeAdvantage: 10, StrengthPerGoal: 20}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.League.ApplyPreset("poisson"); err != nil {
+		t.Fatal(err)
+	}
+	h.SimulateWeek(2)
+	for _, m := range h.Matches() {
+		if m.Week <= 2 && (m.HomeGoals != 3 || m.AwayGoals != 0) {
+			t.Errorf("week %d: %s %d-%d %s, want the simulator's 3-0", m.Week, m.HomeTeam, m.HomeGoals, m.AwayGoals, m.AwayTeam)
+		}
+	}
+	if h.League.Config().Simulation.Model != "poisson" {
+		t.Errorf("model %q after the preset", h.League.Config().Simulation.Model)
+	}
+
+	// the leagues the server opens later play with it too
+	req := insider.LeagueRequest{Name: "Cup", Teams: []insider.Team{{Name: "Reds", Strength: 40}, {Name: "Blues", Strength: 90}}, Weeks: 2}
+	var info insider.LeagueInfo
+	if status := h.Do(http.MethodPost, "/leagues", req, true, &info); status != http.StatusCreated {
+		t.Fatalf("POST /leagues: status %d", status)
+	}
+	h.Post(info.Path+"/simulate/all", nil, nil)
+	var matches []insider.Match
+	h.Get(info.Path+"/matches", &matches)
+	for _, m := range matches {
+		if m.HomeGoals != 3 || m.AwayGoals != 0 {
+			t.Errorf("league %s: %s %d-%d %s, want the simulator's 3-0", info.Name, m.HomeTeam, m.HomeGoals, m.AwayGoals, m.AwayTeam)
+		}
+	}
+}
+
+func TestWarmPredictionsWithAnUncomparableSimulator(t *testing.T) {
+	h := leaguetest.NewWithSimulator(t, insider.SnapshotTeams, 4, fixedScore{2, 1})
+
+	var before, after insider.MonteCarloPrediction
+	h.Get("/predict?mode=montecarlo&runs=20", &before)
+	h.SimulateWeek(1)
+	// the runs of the matches left are kept, comparing their params
+	h.Get("/predict?mode=montecarlo&runs=20", &after)
+	if after.ReusedMatches == 0 {
+		t.Errorf("no match kept its runs after week 1: %+v", after)
+	}
+}
@@ -0,0 +1,287 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Players only score in manually entered results so far: simulated goals
+// have no scorer. A squad is registered per team, by hand or generated (see
+// squads.go), and scorers are checked against it.
+
+// ErrInvalidScorers is returned when the scorers of a result do not fit it
+var ErrInvalidScorers = errors.New("invalid scorers")
+
+// ErrInvalidPlayer is returned for a player without a name
+var ErrInvalidPlayer = errors.New("player names cannot be empty")
+
+// Player is a member of a team's squad
+type Player struct {
+	ID       int    `json:"id"`
+	TeamID   int    `json:"team_id"`
+	TeamName string `json:"team_name"`
+	Name     string `json:"name"`
+	// Position and Rating are set for generated players only
+	Position string `json:"position,omitempty"`
+	Rating   int    `json:"rating,omitempty"`
+}
+
+// Scorer is one goal of a manually entered result. Team is only needed when
+// both squads have a player of that name. An own goal counts for the other
+// side but the player still belongs to their own team.
+type Scorer struct {
+	Player  string `json:"player"`
+	Team    string `json:"team,omitempty"`
+	Minute  int    `json:"minute"`
+	OwnGoal bool   `json:"own_goal,omitempty"`
+}
+
+// TopScorer is one row of the scorers table
+type TopScorer struct {
+	Player   string `json:"player"`
+	TeamName string `json:"team_name"`
+	Goals    int    `json:"goals"`
+}
+
+// manually entered goals have no kind, own goals are marked as such
+const (
+	manualGoalDetail = "goal"
+	ownGoalDetail    = "own goal"
+)
+
+func (l *League) createPlayerTable() error {
+	createPlayers := `
+	CREATE TABLE IF NOT EXISTS players (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		UNIQUE (team_id, name)
+	);`
+
+	if _, err := l.db.Exec(createPlayers); err != nil {
+		return fmt.Errorf("error creating players table: %v", err)
+	}
+	if err := l.addColumnIfMissing("players", "position", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := l.addColumnIfMissing("players", "rating", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	// a removed player's goals still count, only the name is lost
+	if err := l.addColumnIfMissing("match_events", "player_id", "INTEGER REFERENCES players(id) ON DELETE SET NULL"); err != nil {
+		return err
+	}
+	// archived seasons keep their scorers for the all-time list
+	return l.addColumnIfMissing("archived_match_events", "player_id", "INTEGER REFERENCES players(id) ON DELETE SET NULL")
+}
+
+// Players lists a team's squad by name
+func (l *League) Players(teamName string) ([]Player, error) {
+	id, _, err := l.resolveTeam(teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := l.db.Query(`
+		SELECT p.id, p.team_id, t.name, p.name, p.position, p.rating FROM players p
+		JOIN teams t ON t.id = p.team_id
+		WHERE p.team_id = ?
+		ORDER BY p.name`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := []Player{}
+	for rows.Next() {
+		var p Player
+		if err := rows.Scan(&p.ID, &p.TeamID, &p.TeamName, &p.Name, &p.Position, &p.Rating); err != nil {
+			return nil, err
+		}
+		players = append(players, p)
+	}
+	return players, rows.Err()
+}
+
+// AddPlayers registers players with a team; names already in the squad are
+// skipped
+func (l *League) AddPlayers(teamName string, names []string) error {
+	id, _, err := l.resolveTeam(teamName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return ErrInvalidPlayer
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO players (team_id, name) VALUES (?, ?)", id, name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// scorerEvents checks the scorers against the squads and the final score and
+// turns them into goal events. Either every goal has a scorer or none does.
+func scorerEvents(tx *sql.Tx, m Match, scorers []Scorer, maxMinute int) ([]MatchEvent, error) {
+	if len(scorers) == 0 {
+		return nil, nil
+	}
+
+	events := make([]MatchEvent, 0, len(scorers))
+	home, away := 0, 0
+	for _, s := range scorers {
+		if s.Minute < 1 || s.Minute > maxMinute {
+			return nil, fmt.Errorf("%w: minute of %s must be between 1 and %d", ErrInvalidScorers, s.Player, maxMinute)
+		}
+		teams := []int{m.HomeTeamID, m.AwayTeamID}
+		if s.Team != "" {
+			// a former name still finds the team
+			teamID, _, err := resolveTeamIn(tx, s.Team)
+			if err == sql.ErrNoRows || (err == nil && teamID != m.HomeTeamID && teamID != m.AwayTeamID) {
+				return nil, fmt.Errorf("%w: %s is not playing in this match", ErrInvalidScorers, s.Team)
+			}
+			if err != nil {
+				return nil, err
+			}
+			teams = []int{teamID}
+		}
+
+		var found []Player
+		for _, teamID := range teams {
+			var p Player
+			err := tx.QueryRow("SELECT id, team_id FROM players WHERE team_id = ? AND name = ?", teamID, s.Player).Scan(&p.ID, &p.TeamID)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			found = append(found, p)
+		}
+		switch len(found) {
+		case 0:
+			return nil, fmt.Errorf("%w: %s is not in the squad of %s or %s", ErrInvalidScorers, s.Player, m.HomeTeam, m.AwayTeam)
+		case 2:
+			return nil, fmt.Errorf("%w: both teams have a %s, give the team", ErrInvalidScorers, s.Player)
+		}
+
+		// the goal goes to the side the player plays for, or the other one
+		homeGoal := (found[0].TeamID == m.HomeTeamID) != s.OwnGoal
+		e := MatchEvent{Minute: s.Minute, Type: EventGoal, Detail: manualGoalDetail, Player: s.Player, playerID: found[0].ID}
+		if s.OwnGoal {
+			e.Detail = ownGoalDetail
+		}
+		if homeGoal {
+			e.Team = m.HomeTeam
+			home++
+		} else {
+			e.Team = m.AwayTeam
+			away++
+		}
+		events = append(events, e)
+	}
+
+	if home != m.HomeGoals || away != m.AwayGoals {
+		return nil, fmt.Errorf("%w: the scorers add up to %d-%d, the result is %d-%d",
+			ErrInvalidScorers, home, away, m.HomeGoals, m.AwayGoals)
+	}
+	// events are stored in the order they happened
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Minute < events[j].Minute })
+	return events, nil
+}
+
+// TopScorers counts every goal with a known scorer in the current fixture,
+// own goals left out
+func (l *League) TopScorers() ([]TopScorer, error) {
+	rows, err := l.db.Query(`
+		SELECT p.name, t.name, COUNT(*) AS goals
+		FROM match_events e
+		JOIN players p ON p.id = e.player_id
+		JOIN teams t ON t.id = p.team_id
+		WHERE e.type = ? AND COALESCE(e.detail, '') != ?
+		GROUP BY p.id
+		ORDER BY goals DESC, p.name`, EventGoal, ownGoalDetail)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scorers := []TopScorer{}
+	for rows.Next() {
+		var s TopScorer
+		if err := rows.Scan(&s.Player, &s.TeamName, &s.Goals); err != nil {
+			return nil, err
+		}
+		scorers = append(scorers, s)
+	}
+	return scorers, rows.Err()
+}
+
+// GET /teams/{name}/players lists the squad, POST adds to it
+// {"names": ["A. Striker", "B. Keeper"]}
+func (l *League) handleTeamPlayers(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !isAdmin(r) {
+			http.Error(w, "Admin token required", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Names []string `json:"names"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err := l.AddPlayers(name, body.Names)
+		switch {
+		case err == sql.ErrNoRows:
+			http.Error(w, "Team not found", http.StatusNotFound)
+			return
+		case errors.Is(err, ErrInvalidPlayer):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	players, err := l.Players(name)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(players)
+}
+
+// GET /stats/scorers
+func (l *League) handleTopScorers(w http.ResponseWriter, r *http.Request) {
+	scorers, err := l.TopScorers()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(scorers)
+}
@@ -0,0 +1,96 @@
+package insider
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScorers(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, FixtureWeeks(len(snapshotTeams)), 3)
+	// both squads have a J. Doe, so goals by them need the team
+	if err := l.AddPlayers("Alpha FC", []string{"A. Striker", "J. Doe"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddPlayers("Bravo United", []string{"B. Striker", "J. Doe"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.RenameTeam("Alpha FC", "Alpha Athletic"); err != nil {
+		t.Fatal(err)
+	}
+	matches, err := l.Matches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var match Match
+	for _, m := range matches {
+		if m.HomeTeam == "Alpha Athletic" && m.AwayTeam == "Bravo United" {
+			match = m
+		}
+	}
+
+	tests := []struct {
+		name       string
+		home, away int
+		scorers    []Scorer
+		valid      bool
+	}{
+		{"every goal", 2, 1, []Scorer{{Player: "A. Striker", Minute: 3}, {Player: "B. Striker", Minute: 40}, {Player: "A. Striker", Minute: 88}}, true},
+		{"own goal", 1, 0, []Scorer{{Player: "B. Striker", Minute: 12, OwnGoal: true}}, true},
+		{"team by former name", 1, 0, []Scorer{{Player: "J. Doe", Team: "Alpha FC", Minute: 12}}, true},
+		{"ambiguous player", 1, 0, []Scorer{{Player: "J. Doe", Minute: 12}}, false},
+		{"team not playing", 1, 0, []Scorer{{Player: "A. Striker", Team: "Charlie Town", Minute: 12}}, false},
+		{"unknown player", 1, 0, []Scorer{{Player: "Nobody", Minute: 12}}, false},
+		{"goal missing", 2, 0, []Scorer{{Player: "A. Striker", Minute: 12}}, false},
+		{"after the final whistle", 1, 0, []Scorer{{Player: "A. Striker", Minute: 91}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := l.UpdateMatchResult(match.ID, tt.home, tt.away, tt.scorers)
+			if tt.valid && err != nil {
+				t.Fatal(err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidScorers) {
+				t.Fatalf("%v, want ErrInvalidScorers", err)
+			}
+		})
+	}
+
+	scorers, err := l.TopScorers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the last valid result counts, J. Doe's goal
+	if len(scorers) != 1 || scorers[0] != (TopScorer{Player: "J. Doe", TeamName: "Alpha Athletic", Goals: 1}) {
+		t.Errorf("top scorers %+v", scorers)
+	}
+}
+
+func TestScorersInOvertime(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, FixtureWeeks(len(snapshotTeams)), 3)
+	cfg := *l.config()
+	hockey := sportPresets["hockey"]
+	cfg.Sport, cfg.Simulation = hockey.Sport, hockey.Simulation
+	l.cfg.Store(&cfg)
+	if err := l.AddPlayers("Alpha FC", []string{"A. Striker"}); err != nil {
+		t.Fatal(err)
+	}
+	matches, err := l.Matches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var match Match
+	for _, m := range matches {
+		if m.HomeTeam == "Alpha FC" {
+			match = m
+			break
+		}
+	}
+
+	// hockey plays 60 minutes and up to 15 of overtime
+	if err := l.UpdateMatchResult(match.ID, 1, 0, []Scorer{{Player: "A. Striker", Minute: 64}}); err != nil {
+		t.Errorf("overtime winner: %v", err)
+	}
+	if err := l.UpdateMatchResult(match.ID, 1, 0, []Scorer{{Player: "A. Striker", Minute: 76}}); !errors.Is(err, ErrInvalidScorers) {
+		t.Errorf("goal after overtime: %v, want ErrInvalidScorers", err)
+	}
+}
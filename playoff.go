@@ -0,0 +1,320 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// A relegation playoff puts the Nth from bottom of this league against the
+// Nth from top of a lower division, one of the other leagues of the server,
+// for the last place in this league next season. It is a single match
+// hosted by the top division side, with extra time and penalties like a cup
+// tie, and it is played on its own as soon as both seasons are over:
+// whichever league records its last result starts it. One playoff is played
+// per season of this league.
+
+// RelegationPlayoff is the "relegation_playoff" block of the config
+type RelegationPlayoff struct {
+	// LowerLeague is the id of the lower division under /leagues/{id}
+	LowerLeague int `json:"lower_league"`
+	// Position is N; zero means no playoff
+	Position int `json:"position"`
+}
+
+func (p RelegationPlayoff) Validate() error {
+	if p.Position < 0 {
+		return fmt.Errorf("position cannot be negative, got %d", p.Position)
+	}
+	if p.Position > 0 && p.LowerLeague < 1 {
+		return fmt.Errorf("lower_league is required with a position")
+	}
+	return nil
+}
+
+var (
+	// ErrNoPlayoff is returned when the league has no relegation playoff to
+	// play
+	ErrNoPlayoff = errors.New("no relegation playoff")
+	// ErrPlayoffPlayed is returned when the season's playoff has been played
+	ErrPlayoffPlayed = errors.New("the relegation playoff of this season has been played")
+)
+
+// PlayoffResult is a relegation playoff played. The upper team is the home
+// side; Winner has the place in this league next season.
+type PlayoffResult struct {
+	ID            int           `json:"id"`
+	SeasonID      int           `json:"season_id"`
+	LowerLeague   int           `json:"lower_league"`
+	UpperTeam     string        `json:"upper_team"`
+	UpperPosition int           `json:"upper_position"`
+	LowerTeam     string        `json:"lower_team"`
+	LowerPosition int           `json:"lower_position"`
+	HomeGoals     int           `json:"home_goals"`
+	AwayGoals     int           `json:"away_goals"`
+	ExtraTime     *ExtraTime    `json:"extra_time,omitempty"`
+	Penalties     *CupPenalties `json:"penalties,omitempty"`
+	DecidedBy     string        `json:"decided_by"`
+	Winner        string        `json:"winner"`
+	PlayedAt      time.Time     `json:"played_at"`
+}
+
+func (l *League) createPlayoffsTable() error {
+	createPlayoffs := `
+	CREATE TABLE IF NOT EXISTS relegation_playoffs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		season_id INTEGER NOT NULL UNIQUE,
+		lower_league INTEGER NOT NULL,
+		upper_team TEXT NOT NULL,
+		upper_position INTEGER NOT NULL,
+		lower_team TEXT NOT NULL,
+		lower_position INTEGER NOT NULL,
+		home_goals INTEGER NOT NULL,
+		away_goals INTEGER NOT NULL,
+		extra_home_goals INTEGER,
+		extra_away_goals INTEGER,
+		home_penalties INTEGER,
+		away_penalties INTEGER,
+		penalty_kicks TEXT,
+		decided_by TEXT NOT NULL,
+		winner TEXT NOT NULL,
+		played_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (season_id) REFERENCES seasons(id) ON DELETE CASCADE
+	);`
+
+	if _, err := l.db.Exec(createPlayoffs); err != nil {
+		return fmt.Errorf("error creating relegation_playoffs table: %v", err)
+	}
+	return nil
+}
+
+// seasonFinished is whether the league has a fixture with every match
+// played, annulled matches aside
+func (l *League) seasonFinished() (bool, error) {
+	var total, open int
+	err := l.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(played = FALSE AND COALESCE(administrative, '') != 'annulled'), 0) FROM matches`).
+		Scan(&total, &open)
+	return total > 0 && open == 0, err
+}
+
+// lowerDivision is the league the playoff is against
+func (l *League) lowerDivision(id int) (*League, error) {
+	if l.leagues == nil {
+		return nil, fmt.Errorf("%w: only the first league of the server has a lower division", ErrNoPlayoff)
+	}
+	l.leagues.mu.Lock()
+	defer l.leagues.mu.Unlock()
+	o := l.leagues.leagues[id]
+	if o == nil {
+		return nil, fmt.Errorf("%w: league %d does not exist", ErrNoPlayoff, id)
+	}
+	return o.league, nil
+}
+
+// PlayRelegationPlayoff plays the playoff of the current season once this
+// league and the lower division have both played every match
+func (l *League) PlayRelegationPlayoff() (*PlayoffResult, error) {
+	cfg := l.config().RelegationPlayoff
+	if cfg.Position == 0 {
+		return nil, fmt.Errorf("%w: the config has no relegation_playoff", ErrNoPlayoff)
+	}
+	lower, err := l.lowerDivision(cfg.LowerLeague)
+	if err != nil {
+		return nil, err
+	}
+
+	l.playoffMu.Lock()
+	defer l.playoffMu.Unlock()
+
+	season, err := currentSeason(l.db)
+	if err != nil {
+		return nil, err
+	}
+	var played int
+	if err := l.db.QueryRow("SELECT COUNT(*) FROM relegation_playoffs WHERE season_id = ?", season.ID).Scan(&played); err != nil {
+		return nil, err
+	}
+	if played > 0 {
+		return nil, ErrPlayoffPlayed
+	}
+	for _, league := range []*League{l, lower} {
+		finished, err := league.seasonFinished()
+		if err != nil {
+			return nil, err
+		}
+		if !finished {
+			return nil, ErrSeasonNotFinished
+		}
+	}
+
+	upperTable, err := l.CalculateStandings()
+	if err != nil {
+		return nil, err
+	}
+	lowerTable, err := lower.CalculateStandings()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Position > len(upperTable) || cfg.Position > len(lowerTable) {
+		return nil, fmt.Errorf("%w: position %d is past the %d and %d teams of the divisions",
+			ErrNoPlayoff, cfg.Position, len(upperTable), len(lowerTable))
+	}
+	teamByID := func(league *League, id int) Team {
+		for _, t := range league.Teams() {
+			if t.ID == id {
+				return t
+			}
+		}
+		return Team{}
+	}
+	upperPosition, lowerPosition := len(upperTable)+1-cfg.Position, cfg.Position
+	home := teamByID(l, upperTable[upperPosition-1].TeamID)
+	away := teamByID(lower, lowerTable[lowerPosition-1].TeamID)
+
+	advantages, err := l.homeAdvantages()
+	if err != nil {
+		return nil, err
+	}
+	var tie CupTie
+	engine := tieEngine{cfg: l.config(), rng: rand.New(rand.NewSource(l.rng.Int63())), flavor: rand.New(rand.NewSource(l.flavor.Int63()))}
+	playCupTie(engine, engine.cfg.Simulation, advantages, &tie, home, away)
+
+	result := &PlayoffResult{
+		SeasonID:      season.ID,
+		LowerLeague:   cfg.LowerLeague,
+		UpperTeam:     home.Name,
+		UpperPosition: upperPosition,
+		LowerTeam:     away.Name,
+		LowerPosition: lowerPosition,
+		HomeGoals:     tie.HomeGoals,
+		AwayGoals:     tie.AwayGoals,
+		ExtraTime:     tie.ExtraTime,
+		Penalties:     tie.Penalties,
+		DecidedBy:     tie.DecidedBy,
+		Winner:        tie.Winner,
+		PlayedAt:      l.clock.Now().UTC(),
+	}
+	var extraHome, extraAway, homePens, awayPens, kicks any
+	if result.ExtraTime != nil {
+		extraHome, extraAway = result.ExtraTime.HomeGoals, result.ExtraTime.AwayGoals
+	}
+	if result.Penalties != nil {
+		homePens, awayPens = result.Penalties.HomeGoals, result.Penalties.AwayGoals
+		encoded, err := json.Marshal(result.Penalties.Kicks)
+		if err != nil {
+			return nil, err
+		}
+		kicks = string(encoded)
+	}
+	res, err := l.db.Exec(`
+		INSERT INTO relegation_playoffs (season_id, lower_league, upper_team, upper_position, lower_team, lower_position,
+			home_goals, away_goals, extra_home_goals, extra_away_goals, home_penalties, away_penalties, penalty_kicks,
+			decided_by, winner, played_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		result.SeasonID, result.LowerLeague, result.UpperTeam, result.UpperPosition, result.LowerTeam, result.LowerPosition,
+		result.HomeGoals, result.AwayGoals, extraHome, extraAway, homePens, awayPens, kicks,
+		result.DecidedBy, result.Winner, result.PlayedAt)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	result.ID = int(id)
+	return result, nil
+}
+
+// playoffAtSeasonEnd plays the relegation playoff of the first league once
+// both divisions are done. A lower division hands over to the first league.
+func (l *League) playoffAtSeasonEnd() error {
+	top := l
+	if l.parent != nil {
+		top = l.parent
+	}
+	if top.config().RelegationPlayoff.Position == 0 {
+		return nil
+	}
+	result, err := top.PlayRelegationPlayoff()
+	if errors.Is(err, ErrSeasonNotFinished) || errors.Is(err, ErrPlayoffPlayed) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Relegation playoff: %s %d-%d %s, %s keeps the place\n",
+		result.UpperTeam, result.HomeGoals, result.AwayGoals, result.LowerTeam, result.Winner)
+	return nil
+}
+
+// RelegationPlayoffs lists the playoffs played, newest first
+func (l *League) RelegationPlayoffs() ([]PlayoffResult, error) {
+	rows, err := l.db.Query(`
+		SELECT id, season_id, lower_league, upper_team, upper_position, lower_team, lower_position, home_goals, away_goals,
+			extra_home_goals, extra_away_goals, home_penalties, away_penalties, COALESCE(penalty_kicks, ''), decided_by, winner, played_at
+		FROM relegation_playoffs ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []PlayoffResult{}
+	for rows.Next() {
+		var p PlayoffResult
+		var extraHome, extraAway, homePens, awayPens sql.NullInt64
+		var kicks string
+		if err := rows.Scan(&p.ID, &p.SeasonID, &p.LowerLeague, &p.UpperTeam, &p.UpperPosition, &p.LowerTeam, &p.LowerPosition,
+			&p.HomeGoals, &p.AwayGoals, &extraHome, &extraAway, &homePens, &awayPens, &kicks, &p.DecidedBy, &p.Winner, &p.PlayedAt); err != nil {
+			return nil, err
+		}
+		if extraHome.Valid {
+			p.ExtraTime = &ExtraTime{HomeGoals: int(extraHome.Int64), AwayGoals: int(extraAway.Int64)}
+		}
+		if homePens.Valid {
+			p.Penalties = &CupPenalties{HomeGoals: int(homePens.Int64), AwayGoals: int(awayPens.Int64), Kicks: []PenaltyKick{}}
+			if kicks != "" {
+				if err := json.Unmarshal([]byte(kicks), &p.Penalties.Kicks); err != nil {
+					return nil, err
+				}
+			}
+		}
+		results = append(results, p)
+	}
+	return results, rows.Err()
+}
+
+// GET /playoffs lists the relegation playoffs; POST plays this season's when
+// it was not played on its own, e.g. with the config set after the season
+// (admin only)
+func (l *League) handlePlayoffs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		results, err := l.RelegationPlayoffs()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(results)
+	case http.MethodPost:
+		if !requireAdmin(w, r) {
+			return
+		}
+		result, err := l.PlayRelegationPlayoff()
+		switch {
+		case errors.Is(err, ErrNoPlayoff), errors.Is(err, ErrPlayoffPlayed), errors.Is(err, ErrSeasonNotFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(result)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
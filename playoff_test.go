@@ -0,0 +1,68 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestRelegationPlayoffAtSeasonEnd(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 8)
+	var lower insider.LeagueInfo
+	req := insider.LeagueRequest{
+		Name:  "Second division",
+		Teams: []insider.Team{{Name: "Reds", Strength: 70}, {Name: "Blues", Strength: 65}, {Name: "Greens", Strength: 60}, {Name: "Whites", Strength: 55}},
+	}
+	if status := h.Do(http.MethodPost, "/leagues", req, true, &lower); status != http.StatusCreated {
+		t.Fatalf("POST /leagues: status %d", status)
+	}
+
+	if status := h.Do(http.MethodPost, "/playoffs", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("playoff without the config: status %d, want 409", status)
+	}
+	cfg := *h.League.Config()
+	cfg.RelegationPlayoff = insider.RelegationPlayoff{LowerLeague: lower.ID, Position: 1}
+	h.League.StoreConfig(&cfg)
+
+	// the top division finishing first waits for the lower one
+	h.SimulateSeason()
+	var playoffs []insider.PlayoffResult
+	h.Get("/playoffs", &playoffs)
+	if len(playoffs) != 0 {
+		t.Fatalf("playoff played before the lower division finished: %+v", playoffs)
+	}
+	if status := h.Do(http.MethodPost, "/playoffs", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("playoff with the lower division unfinished: status %d, want 409", status)
+	}
+
+	if status := h.Do(http.MethodPost, lower.Path+"/simulate/all", nil, false, nil); status != http.StatusOK {
+		t.Fatalf("simulate the lower division: status %d", status)
+	}
+	h.Get("/playoffs", &playoffs)
+	if len(playoffs) != 1 {
+		t.Fatalf("%d playoffs after both seasons, want 1", len(playoffs))
+	}
+	p := playoffs[0]
+
+	upper := h.Standings()
+	var lowerTable []insider.Standing
+	h.Get(lower.Path+"/standings", &lowerTable)
+	if p.UpperTeam != upper[len(upper)-1].TeamName || p.UpperPosition != len(upper) {
+		t.Errorf("upper side %s (%d), want the bottom team %s", p.UpperTeam, p.UpperPosition, upper[len(upper)-1].TeamName)
+	}
+	if p.LowerTeam != lowerTable[0].TeamName || p.LowerPosition != 1 {
+		t.Errorf("lower side %s (%d), want the top team %s", p.LowerTeam, p.LowerPosition, lowerTable[0].TeamName)
+	}
+	if p.Winner != p.UpperTeam && p.Winner != p.LowerTeam {
+		t.Errorf("winner %q played no part", p.Winner)
+	}
+	if (p.HomeGoals == p.AwayGoals) != (p.ExtraTime != nil) {
+		t.Errorf("playoff %+v: extra time should follow a level score", p)
+	}
+
+	if status := h.Do(http.MethodPost, "/playoffs", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("second playoff in a season: status %d, want 409", status)
+	}
+}
@@ -0,0 +1,293 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Popularity is a fan score from 0 to 100. A team's first season starts on
+// basePopularity and every later one where the one before ended: archiving a
+// season keeps each team's final score in teams.popularity. Within a season
+// it is replayed from the results like form. Big matches, between two
+// popular teams, swing it twice as much, fill the stands and, with
+// "pressure" in the config, weigh on the favourites, in the predictions too.
+
+const (
+	basePopularity = 50.0
+	// bigMatchPopularity is what both teams need for a big match
+	bigMatchPopularity = 60.0
+	// defaultCapacity is used for teams without a "capacity" in their metadata
+	defaultCapacity = 30000
+	// maxPressure is the most strength a team can lose in a big match
+	maxPressure = 5
+)
+
+// popularity change per result
+const (
+	popularityWin  = 4.0
+	popularityDraw = 1.0
+	popularityLoss = -3.0
+	// popularityUpset is added for beating a more popular team
+	popularityUpset = 2.0
+)
+
+// PopularityChange is what one result did to a team's popularity
+type PopularityChange struct {
+	MatchID    int     `json:"match_id"`
+	Week       int     `json:"week"`
+	Opponent   string  `json:"opponent"`
+	Result     string  `json:"result"`
+	BigMatch   bool    `json:"big_match"`
+	Change     float64 `json:"change"`
+	Popularity float64 `json:"popularity"`
+}
+
+// Attendance is the crowd at one of a team's home matches
+type Attendance struct {
+	MatchID    int    `json:"match_id"`
+	Week       int    `json:"week"`
+	Opponent   string `json:"opponent"`
+	Attendance int    `json:"attendance"`
+	Capacity   int    `json:"capacity"`
+	BigMatch   bool   `json:"big_match"`
+}
+
+// TeamPopularity is GET /teams/{name}/popularity
+type TeamPopularity struct {
+	Team              string             `json:"team"`
+	Popularity        float64            `json:"popularity"`
+	History           []PopularityChange `json:"history"`
+	HomeAttendance    []Attendance       `json:"home_attendance"`
+	AverageAttendance int                `json:"average_attendance"`
+}
+
+// popularityReplay is every team's popularity after the played matches, with
+// what happened on the way
+type popularityReplay struct {
+	current    map[int]float64
+	history    map[int][]PopularityChange
+	attendance map[int][]Attendance
+}
+
+func isBigMatch(homePopularity, awayPopularity float64) bool {
+	return homePopularity >= bigMatchPopularity && awayPopularity >= bigMatchPopularity
+}
+
+// popularityChange is the result and the change for a team scoring goalsFor
+// against goalsAgainst, given both sides' popularity before the match
+func popularityChange(goalsFor, goalsAgainst int, own, opponent float64, big bool) (string, float64) {
+	result, change := "D", popularityDraw
+	switch {
+	case goalsFor > goalsAgainst:
+		result, change = "W", popularityWin
+		if opponent > own {
+			change += popularityUpset
+		}
+	case goalsFor < goalsAgainst:
+		result, change = "L", popularityLoss
+	}
+	if big {
+		change *= 2
+	}
+	return result, change
+}
+
+// attendance fills the stadium by how popular both sides are
+func attendance(capacity int, home, away float64, big bool) int {
+	fill := 0.35 + 0.45*home/100 + 0.15*away/100
+	if big {
+		fill += 0.1
+	}
+	fill = math.Min(fill, 1)
+	return int(math.Round(float64(capacity)*fill/10) * 10)
+}
+
+// capacity reads a team's stadium size from its metadata
+func capacity(t Team) int {
+	if c, err := strconv.Atoi(t.Metadata["capacity"]); err == nil && c > 0 {
+		return c
+	}
+	return defaultCapacity
+}
+
+// replayPopularity runs the played matches in week order from the
+// popularity each team started the season on
+func replayPopularity(teams []Team, start map[int]float64, matches []Match) *popularityReplay {
+	r := &popularityReplay{
+		current:    make(map[int]float64),
+		history:    make(map[int][]PopularityChange),
+		attendance: make(map[int][]Attendance),
+	}
+	capacities := make(map[int]int)
+	for _, t := range teams {
+		r.current[t.ID] = basePopularity
+		if p, ok := start[t.ID]; ok {
+			r.current[t.ID] = p
+		}
+		capacities[t.ID] = capacity(t)
+	}
+
+	var played []Match
+	for _, m := range matches {
+		if m.Played {
+			played = append(played, m)
+		}
+	}
+	sort.SliceStable(played, func(i, j int) bool {
+		return played[i].Week < played[j].Week
+	})
+
+	clamp := func(p float64) float64 { return math.Max(0, math.Min(100, p)) }
+	for _, m := range played {
+		home, away := r.current[m.HomeTeamID], r.current[m.AwayTeamID]
+		big := isBigMatch(home, away)
+
+		r.attendance[m.HomeTeamID] = append(r.attendance[m.HomeTeamID], Attendance{
+			MatchID: m.ID, Week: m.Week, Opponent: m.AwayTeam, BigMatch: big,
+			Capacity:   capacities[m.HomeTeamID],
+			Attendance: attendance(capacities[m.HomeTeamID], home, away, big),
+		})
+
+		homeResult, homeChange := popularityChange(m.HomeGoals, m.AwayGoals, home, away, big)
+		awayResult, awayChange := popularityChange(m.AwayGoals, m.HomeGoals, away, home, big)
+		r.current[m.HomeTeamID] = clamp(home + homeChange)
+		r.current[m.AwayTeamID] = clamp(away + awayChange)
+		r.history[m.HomeTeamID] = append(r.history[m.HomeTeamID], PopularityChange{
+			MatchID: m.ID, Week: m.Week, Opponent: m.AwayTeam, BigMatch: big,
+			Result: homeResult, Change: homeChange, Popularity: r.current[m.HomeTeamID],
+		})
+		r.history[m.AwayTeamID] = append(r.history[m.AwayTeamID], PopularityChange{
+			MatchID: m.ID, Week: m.Week, Opponent: m.HomeTeam, BigMatch: big,
+			Result: awayResult, Change: awayChange, Popularity: r.current[m.AwayTeamID],
+		})
+	}
+	return r
+}
+
+// pressure is the strength a team loses in a big match: the more popular,
+// the more is expected of it
+func pressure(popularity float64) int {
+	p := int((popularity - bigMatchPopularity) / 8)
+	return max(0, min(maxPressure, p))
+}
+
+// seasonStartPopularity reads what every team started the season on
+func seasonStartPopularity(q interface {
+	Query(string, ...any) (*sql.Rows, error)
+}) (map[int]float64, error) {
+	rows, err := q.Query("SELECT id, popularity FROM teams")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	start := make(map[int]float64)
+	for rows.Next() {
+		var id int
+		var p float64
+		if err := rows.Scan(&id, &p); err != nil {
+			return nil, err
+		}
+		start[id] = p
+	}
+	return start, rows.Err()
+}
+
+// popularity replays the current fixture from the mirror
+func (l *League) popularity() (*popularityReplay, error) {
+	start, err := seasonStartPopularity(l.db)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := l.Matches()
+	if err != nil {
+		return nil, err
+	}
+	return replayPopularity(l.Teams(), start, matches), nil
+}
+
+// carryPopularity keeps the popularity the season ended on for the next one.
+// It runs in the transaction that archives the season, before the matches
+// are cleared.
+func (l *League) carryPopularity(tx *sql.Tx) error {
+	start, err := seasonStartPopularity(tx)
+	if err != nil {
+		return err
+	}
+	rows, err := tx.Query(matchSelect + " ORDER BY m.id")
+	if err != nil {
+		return err
+	}
+	var matches []Match
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		matches = append(matches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, p := range replayPopularity(l.Teams(), start, matches).current {
+		if _, err := tx.Exec("UPDATE teams SET popularity = ? WHERE id = ?", p, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TeamPopularity reports one team's popularity, its history and crowds
+func (l *League) TeamPopularity(teamID int, name string) (*TeamPopularity, error) {
+	replay, err := l.popularity()
+	if err != nil {
+		return nil, err
+	}
+	p := &TeamPopularity{
+		Team:           name,
+		Popularity:     replay.current[teamID],
+		History:        replay.history[teamID],
+		HomeAttendance: replay.attendance[teamID],
+	}
+	if p.History == nil {
+		p.History = []PopularityChange{}
+	}
+	if p.HomeAttendance == nil {
+		p.HomeAttendance = []Attendance{}
+	}
+	total := 0
+	for _, a := range p.HomeAttendance {
+		total += a.Attendance
+	}
+	if len(p.HomeAttendance) > 0 {
+		p.AverageAttendance = total / len(p.HomeAttendance)
+	}
+	return p, nil
+}
+
+// GET /teams/{name}/popularity
+func (l *League) handleTeamPopularity(w http.ResponseWriter, r *http.Request) {
+	teamID, team, err := l.resolveTeam(r.PathValue("name"))
+	if err == sql.ErrNoRows {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p, err := l.TeamPopularity(teamID, team)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,73 @@
+package insider
+
+import (
+	"testing"
+)
+
+func TestPopularityCarriesOver(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, FixtureWeeks(len(snapshotTeams)), 2)
+	playByStrength(t, l, 1, FixtureWeeks(len(snapshotTeams)))
+	ended := make(map[string]float64)
+	for _, team := range l.Teams() {
+		p, err := l.TeamPopularity(team.ID, team.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ended[team.Name] = p.Popularity
+	}
+	// Alpha FC won every match, Delta SC lost every one
+	if ended["Alpha FC"] <= basePopularity || ended["Delta SC"] >= basePopularity {
+		t.Fatalf("popularity after the season %v", ended)
+	}
+
+	next, err := l.ArchiveSeason("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.StartSeason(next.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, team := range l.Teams() {
+		p, err := l.TeamPopularity(team.ID, team.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Popularity != ended[team.Name] || len(p.History) != 0 {
+			t.Errorf("%s starts the next season on %.0f with %d changes, the last one ended on %.0f",
+				team.Name, p.Popularity, len(p.History), ended[team.Name])
+		}
+	}
+}
+
+func TestPressureInPredictions(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, FixtureWeeks(len(snapshotTeams)), 2)
+	// the two favourites start the season adored, which makes their
+	// meetings big matches
+	if _, err := l.db.Exec("UPDATE teams SET popularity = 100 WHERE name IN ('Alpha FC', 'Bravo United')"); err != nil {
+		t.Fatal(err)
+	}
+	bigMatch := Match{HomeTeam: "Alpha FC", AwayTeam: "Bravo United"}
+	smallMatch := Match{HomeTeam: "Alpha FC", AwayTeam: "Charlie Town"}
+
+	for _, on := range []bool{false, true} {
+		cfg := *l.config()
+		cfg.Pressure = on
+		l.cfg.Store(&cfg)
+		state, err := l.loadSeasonState()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		home, away := state.matchStrengths(bigMatch)
+		wantHome, wantAway := 85, 70
+		if on {
+			wantHome, wantAway = 85-maxPressure, 70-maxPressure
+		}
+		if home != wantHome || away != wantAway {
+			t.Errorf("pressure %v: big match played at %d-%d, want %d-%d", on, home, away, wantHome, wantAway)
+		}
+		if home, away := state.matchStrengths(smallMatch); home != 85 || away != 60 {
+			t.Errorf("pressure %v: small match played at %d-%d", on, home, away)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package insider
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// TeamPrediction is one team's outlook averaged over many simulated seasons
+type TeamPrediction struct {
+	TeamName              string    `json:"team_name"`
+	CurrentPoints         int       `json:"current_points"`
+	ExpectedPoints        float64   `json:"expected_points"`
+	RemainingHome         int       `json:"remaining_home"`
+	RemainingAway         int       `json:"remaining_away"`
+	ExpectedHomePoints    float64   `json:"expected_home_points"`
+	ExpectedAwayPoints    float64   `json:"expected_away_points"`
+	TitleProbability      float64   `json:"title_probability"`
+	PositionProbabilities []float64 `json:"position_probabilities"`
+	// BestPosition and WorstPosition are worked out from the points left,
+	// not from the runs, so they hold whatever the simulations missed
+	BestPosition  int `json:"best_position"`
+	WorstPosition int `json:"worst_position"`
+}
+
+type MonteCarloPrediction struct {
+	Runs         int              `json:"runs"`
+	ComputedAt   time.Time        `json:"computed_at"`
+	StateVersion int64            `json:"state_version"`
+	Teams        []TeamPrediction `json:"teams"`
+	// ReusedMatches is how many remaining matches kept their simulated
+	// scores from the previous prediction
+	ReusedMatches int `json:"reused_matches"`
+}
+
+type cachedPrediction struct {
+	version    int64
+	cfg        *Config
+	prediction *MonteCarloPrediction
+}
+
+// touch marks the league as changed, which makes the cached prediction stale
+// and reloads the matches mirror
+func (l *League) touch() {
+	l.version.Add(1)
+	l.matchesChanged()
+}
+
+// PredictMonteCarlo simulates the rest of the season runs times. Every remaining
+// fixture is played with its real venue, so expected points are also reported
+// separately for the remaining home and away games.
+//
+// The latest prediction is kept until a result changes, the config is
+// reloaded or a different number of runs is asked for; until then it is
+// served as is, computed_at tells how old it is.
+//
+// A new prediction starts from the runs of the previous one and only draws
+// the matches that are new to it or whose odds or script changed.
+func (l *League) PredictMonteCarlo(runs int) (*MonteCarloPrediction, error) {
+	l.predictionMu.Lock()
+	defer l.predictionMu.Unlock()
+
+	version, cfg := l.version.Load(), l.config()
+	if c := l.prediction; c != nil && c.version == version && c.cfg == cfg && c.prediction.Runs == runs {
+		return c.prediction, nil
+	}
+
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return nil, err
+	}
+
+	played, remaining := state.current()
+	if !l.warm.fits(cfg, runs, state.teams) {
+		l.warm = newWarmRuns(cfg, runs, state.teams)
+	}
+	reused := l.warm.update(l.predict, cfg.Simulation, state, remaining)
+	summary := l.warm.summary(state.standings(played))
+	prediction := monteCarloPrediction(state, played, remaining, summary)
+	prediction.StateVersion, prediction.ReusedMatches = version, reused
+	l.prediction = &cachedPrediction{version: version, cfg: cfg, prediction: prediction}
+	return prediction, nil
+}
+
+// monteCarloPrediction turns a summary into per team outlooks
+func monteCarloPrediction(state *seasonState, played, remaining []Match, summary *simulationSummary) *MonteCarloPrediction {
+	current := state.standings(played)
+	ranges := state.positionRanges(played, remaining)
+
+	prediction := &MonteCarloPrediction{Runs: summary.Runs, ComputedAt: time.Now().UTC()}
+	for _, s := range current {
+		p := TeamPrediction{
+			TeamName:           s.TeamName,
+			CurrentPoints:      s.Points,
+			ExpectedPoints:     summary.expected(summary.Points[s.TeamName]),
+			ExpectedHomePoints: summary.expected(summary.HomePoints[s.TeamName]),
+			ExpectedAwayPoints: summary.expected(summary.AwayPoints[s.TeamName]),
+			TitleProbability:   summary.probability(s.TeamName, 1),
+			BestPosition:       ranges[s.TeamName].best,
+			WorstPosition:      ranges[s.TeamName].worst,
+		}
+		for _, m := range remaining {
+			if m.HomeTeam == s.TeamName {
+				p.RemainingHome++
+			} else if m.AwayTeam == s.TeamName {
+				p.RemainingAway++
+			}
+		}
+		for position := 1; position <= len(state.teams); position++ {
+			p.PositionProbabilities = append(p.PositionProbabilities, summary.probability(s.TeamName, position))
+		}
+		prediction.Teams = append(prediction.Teams, p)
+	}
+
+	sort.SliceStable(prediction.Teams, func(i, j int) bool {
+		return prediction.Teams[i].ExpectedPoints > prediction.Teams[j].ExpectedPoints
+	})
+	return prediction
+}
+
+// handlePredict serves a single simulated ending by default, or the aggregate
+// of many runs with ?mode=montecarlo
+func (l *League) handlePredict(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("mode") == "montecarlo" {
+		runs, err := runsParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		prediction, err := l.PredictMonteCarlo(runs)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(prediction)
+		return
+	}
+
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var standings []Standing
+	if seed != nil {
+		standings, err = l.PredictStandingsSeeded(*seed)
+	} else {
+		standings, err = l.PredictStandings()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(standings)
+}
@@ -0,0 +1,266 @@
+package insider
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A simulated ending can only land on a position the analytic range allows
+func TestMonteCarloStaysInPositionRange(t *testing.T) {
+	league := newTestLeague(t, snapshotTeams, 6, 3)
+	for week := 1; week <= 4; week++ {
+		if err := league.SimulateWeek(week); err != nil {
+			t.Fatalf("simulate week %d: %v", week, err)
+		}
+	}
+
+	prediction, err := league.PredictMonteCarlo(2000)
+	if err != nil {
+		t.Fatalf("predict: %v", err)
+	}
+	for _, p := range prediction.Teams {
+		if p.BestPosition < 1 || p.BestPosition > p.WorstPosition || p.WorstPosition > len(snapshotTeams) {
+			t.Errorf("%s: invalid range %d-%d", p.TeamName, p.BestPosition, p.WorstPosition)
+		}
+		for i, prob := range p.PositionProbabilities {
+			position := i + 1
+			if prob > 0 && (position < p.BestPosition || position > p.WorstPosition) {
+				t.Errorf("%s finished %d in %.1f%% of runs, outside %d-%d", p.TeamName, position, prob*100, p.BestPosition, p.WorstPosition)
+			}
+		}
+	}
+}
+
+// Runs carried over from the previous prediction describe the same season
+// as runs drawn from scratch
+func TestWarmStartAgreesWithFreshRuns(t *testing.T) {
+	const runs = 4000
+	league := newTestLeague(t, snapshotTeams, 6, 5)
+	for week := 1; week <= 2; week++ {
+		if err := league.SimulateWeek(week); err != nil {
+			t.Fatalf("simulate week %d: %v", week, err)
+		}
+	}
+	if _, err := league.PredictMonteCarlo(runs); err != nil {
+		t.Fatalf("predict: %v", err)
+	}
+	if err := league.SimulateWeek(3); err != nil {
+		t.Fatalf("simulate week 3: %v", err)
+	}
+
+	warm, err := league.PredictMonteCarlo(runs)
+	if err != nil {
+		t.Fatalf("predict: %v", err)
+	}
+	state, err := league.loadSeasonState()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	played, remaining := state.current()
+	if warm.ReusedMatches != len(remaining) {
+		t.Errorf("reused %d matches, want all %d remaining", warm.ReusedMatches, len(remaining))
+	}
+
+	fresh := monteCarlo(rand.New(rand.NewSource(1)), league.config().Simulation, state, played, remaining, runs)
+	for _, p := range warm.Teams {
+		if want := fresh.expected(fresh.Points[p.TeamName]); math.Abs(p.ExpectedPoints-want) > 0.25 {
+			t.Errorf("%s: %.2f expected points warm, %.2f fresh", p.TeamName, p.ExpectedPoints, want)
+		}
+		if want := fresh.probability(p.TeamName, 1); math.Abs(p.TitleProbability-want) > 0.05 {
+			t.Errorf("%s: title probability %.3f warm, %.3f fresh", p.TeamName, p.TitleProbability, want)
+		}
+	}
+}
+
+// After one more result a warm start only has to take that match out of
+// every run; from scratch the whole rest of the season is played again
+func BenchmarkPredictAfterResult(b *testing.B) {
+	const runs = 1000
+	teams := make([]Team, 20)
+	for i := range teams {
+		teams[i] = Team{Name: fmt.Sprintf("Team %d", i+1), Strength: 40 + 2*i}
+	}
+	l := newTestLeague(b, teams, 38, 1)
+	if err := l.SimulateWeek(1); err != nil {
+		b.Fatal(err)
+	}
+	state, err := l.loadSeasonState()
+	if err != nil {
+		b.Fatal(err)
+	}
+	params := l.config().Simulation
+	played, remaining := state.current()
+	rng := rand.New(rand.NewSource(1))
+
+	b.Run("warm", func(b *testing.B) {
+		w := newWarmRuns(l.config(), runs, state.teams)
+		w.update(rng, params, state, remaining)
+		base := state.standings(played)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			// the first match is played, then drawn again on the way back
+			w.update(rng, params, state, remaining[i%2:])
+			w.summary(base)
+		}
+	})
+	b.Run("cold", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			monteCarlo(rng, params, state, played, remaining[i%2:], runs)
+		}
+	})
+}
+
+// The cached prediction is served until something it was computed from
+// changes
+func TestPredictionCacheInvalidation(t *testing.T) {
+	league := newTestLeague(t, snapshotTeams, 6, 8)
+	if err := league.SimulateWeek(1); err != nil {
+		t.Fatalf("simulate week 1: %v", err)
+	}
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"simulation": {"home_advantage": 4}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	league.configFile = configFile
+
+	predict := func() *MonteCarloPrediction {
+		t.Helper()
+		p, err := league.PredictMonteCarlo(200)
+		if err != nil {
+			t.Fatalf("predict: %v", err)
+		}
+		return p
+	}
+	cached := predict()
+	if predict() != cached {
+		t.Fatal("unchanged league computed a new prediction")
+	}
+
+	matchID := 0
+	for _, m := range mustMatches(t, league) {
+		if m.Played {
+			matchID = m.ID
+			break
+		}
+	}
+	for _, c := range []struct {
+		change string
+		apply  func() error
+	}{
+		{"result edit", func() error { return league.UpdateMatchResult(matchID, 4, 0, nil) }},
+		{"rename", func() error { return league.RenameTeam("Delta SC", "Delta City") }},
+		{"strength change", func() error {
+			_, err := league.SetStrength("Alpha FC", 40, "test", "injuries")
+			return err
+		}},
+		{"config reload", league.ReloadConfig},
+	} {
+		if err := c.apply(); err != nil {
+			t.Fatalf("%s: %v", c.change, err)
+		}
+		next := predict()
+		if next == cached {
+			t.Errorf("%s served the cached prediction", c.change)
+		}
+		if predict() != next {
+			t.Errorf("after the %s the new prediction was not cached", c.change)
+		}
+		cached = next
+	}
+}
+
+func mustMatches(t *testing.T, l *League) []Match {
+	t.Helper()
+	matches, err := l.Matches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+// The fixtures left of a team that has left the league are not predicted
+func TestPredictWithoutDepartedTeam(t *testing.T) {
+	league := newTestLeague(t, snapshotTeams, 6, 4)
+	if err := league.SimulateWeek(1); err != nil {
+		t.Fatalf("simulate week 1: %v", err)
+	}
+	if _, err := league.db.Exec("UPDATE teams SET active = FALSE WHERE name = 'Delta SC'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := league.loadTeams(); err != nil {
+		t.Fatal(err)
+	}
+
+	predicted, err := league.PredictStandings()
+	if err != nil {
+		t.Fatalf("predict: %v", err)
+	}
+	if len(predicted) != 3 {
+		t.Fatalf("%d teams predicted, want 3", len(predicted))
+	}
+	for _, s := range predicted {
+		if s.TeamName == "Delta SC" || s.Played != 4 {
+			t.Errorf("%s predicted with %d matches, want 4", s.TeamName, s.Played)
+		}
+	}
+}
+
+func TestTitleRaceHandler(t *testing.T) {
+	league := newTestLeague(t, snapshotTeams, 6, 9)
+	get := func(query string) (*httptest.ResponseRecorder, *TitleRace) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		league.handleTitleRace(w, httptest.NewRequest(http.MethodGet, "/titlerace"+query, nil))
+		var race TitleRace
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&race); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w, &race
+	}
+	for _, query := range []string{"?runs=0", "?runs=many"} {
+		if w, _ := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("/titlerace%s: status %d, want 400", query, w.Code)
+		}
+	}
+
+	// the favourites win every match, Delta SC is out of it after four weeks
+	playByStrength(t, league, 1, 4)
+	w, race := get("?runs=200")
+	if w.Code != http.StatusOK {
+		t.Fatalf("/titlerace: status %d", w.Code)
+	}
+	if race.Leader != "Alpha FC" || race.Week != 4 || race.Runs != 200 || race.Decided {
+		t.Errorf("race %+v", race)
+	}
+	alive := make(map[string]bool)
+	total := 0.0
+	for _, c := range race.Contenders {
+		alive[c.TeamName] = true
+		total += c.TitleProbability
+		if c.TeamName == "Delta SC" || c.MaxPoints < 12 || len(c.History) != 5 {
+			t.Errorf("contender %+v", c)
+		}
+	}
+	if !alive["Alpha FC"] || math.Abs(total-1) > 1e-9 {
+		t.Errorf("contenders %+v share a title probability of %v", race.Contenders, total)
+	}
+	for _, m := range race.HeadToHead {
+		if !alive[m.HomeTeam] || !alive[m.AwayTeam] || m.Week <= 4 {
+			t.Errorf("head to head %+v", m)
+		}
+	}
+
+	playByStrength(t, league, 5, 6)
+	if _, race := get(""); !race.Decided || len(race.Contenders) != 1 || race.Contenders[0].TitleProbability != 1 {
+		t.Errorf("finished race %+v", race)
+	}
+}
@@ -0,0 +1,306 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// The prediction game lets signed in users guess the score of upcoming
+// matches. Guesses are scored when read, against the results and with the
+// points scheme in the config, so a changed scheme applies to every guess.
+
+// ErrPredictionClosed is returned for a match that has kicked off
+var ErrPredictionClosed = errors.New("predictions for this match are closed")
+
+// ErrInvalidPrediction is returned for a score that cannot happen
+var ErrInvalidPrediction = errors.New("invalid prediction")
+
+// PredictionPoints is the scheme guesses are scored with, each guess earns
+// the best one it meets
+type PredictionPoints struct {
+	ExactScore     int `json:"exact_score"`
+	GoalDifference int `json:"goal_difference"`
+	Result         int `json:"result"`
+}
+
+var defaultPredictionPoints = PredictionPoints{ExactScore: 5, GoalDifference: 3, Result: 2}
+
+func (p PredictionPoints) Validate() error {
+	if p.Result < 0 || p.GoalDifference < p.Result || p.ExactScore < p.GoalDifference {
+		return fmt.Errorf("points must satisfy exact_score >= goal_difference >= result >= 0")
+	}
+	return nil
+}
+
+// sameResult reports whether two scores are both home wins, draws or away wins
+func sameResult(guessHome, guessAway, home, away int) bool {
+	return (guessHome > guessAway) == (home > away) && (guessHome < guessAway) == (home < away)
+}
+
+// score is what a guess earns for a played match
+func (p PredictionPoints) score(guessHome, guessAway, home, away int) int {
+	switch {
+	case guessHome == home && guessAway == away:
+		return p.ExactScore
+	case guessHome-guessAway == home-away:
+		return p.GoalDifference
+	case sameResult(guessHome, guessAway, home, away):
+		return p.Result
+	}
+	return 0
+}
+
+// UserPrediction is one guess. Points is set once the match is played.
+type UserPrediction struct {
+	MatchID   int       `json:"match_id"`
+	Week      int       `json:"week"`
+	HomeTeam  string    `json:"home_team"`
+	AwayTeam  string    `json:"away_team"`
+	HomeGoals int       `json:"home_goals"`
+	AwayGoals int       `json:"away_goals"`
+	Result    *string   `json:"result,omitempty"`
+	Points    *int      `json:"points,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PredictorStanding is one row of the predictors leaderboard
+type PredictorStanding struct {
+	Rank        int    `json:"rank"`
+	User        string `json:"user"`
+	Points      int    `json:"points"`
+	Scored      int    `json:"scored"`
+	ExactScores int    `json:"exact_scores"`
+	Results     int    `json:"correct_results"`
+}
+
+func (l *League) createPredictionGameTable() error {
+	createPredictions := `
+	CREATE TABLE IF NOT EXISTS user_predictions (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+		home_goals INTEGER NOT NULL,
+		away_goals INTEGER NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (user_id, match_id)
+	);`
+
+	if _, err := l.db.Exec(createPredictions); err != nil {
+		return fmt.Errorf("error creating user_predictions table: %v", err)
+	}
+	return nil
+}
+
+// predictionOpen reports whether a match still takes guesses: not started,
+// and its kickoff, on the virtual clock in clock mode, still to come
+func (l *League) predictionOpen(m Match) bool {
+	if m.Played || m.Live {
+		return false
+	}
+	kickoff, ok := l.calendarKickoff(m)
+	return !ok || kickoff.After(l.clock.Now())
+}
+
+// SubmitPrediction stores a user's guess, replacing an earlier one
+func (l *League) SubmitPrediction(user *User, matchID, homeGoals, awayGoals int) (*UserPrediction, error) {
+	if homeGoals < 0 || awayGoals < 0 {
+		return nil, fmt.Errorf("%w: goals cannot be negative", ErrInvalidPrediction)
+	}
+	m, err := scanMatch(l.db.QueryRow(matchSelect+" WHERE m.id = ?", matchID))
+	if err != nil {
+		return nil, err
+	}
+	if !l.predictionOpen(m) {
+		return nil, ErrPredictionClosed
+	}
+
+	now := time.Now().UTC()
+	_, err = l.db.Exec(`
+		INSERT INTO user_predictions (user_id, match_id, home_goals, away_goals, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, match_id) DO UPDATE SET
+			home_goals = excluded.home_goals, away_goals = excluded.away_goals, updated_at = excluded.updated_at`,
+		user.ID, matchID, homeGoals, awayGoals, now)
+	if err != nil {
+		return nil, err
+	}
+	return &UserPrediction{
+		MatchID: m.ID, Week: m.Week, HomeTeam: m.HomeTeam, AwayTeam: m.AwayTeam,
+		HomeGoals: homeGoals, AwayGoals: awayGoals, UpdatedAt: now,
+	}, nil
+}
+
+// UserPredictions lists a user's guesses in week order, scored where played
+func (l *League) UserPredictions(user *User) ([]UserPrediction, error) {
+	rows, err := l.db.Query(`
+		SELECT p.match_id, p.home_goals, p.away_goals, p.updated_at
+		FROM user_predictions p WHERE p.user_id = ?`, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var guesses []UserPrediction
+	for rows.Next() {
+		var p UserPrediction
+		if err := rows.Scan(&p.MatchID, &p.HomeGoals, &p.AwayGoals, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		guesses = append(guesses, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	matches, err := l.Matches()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]Match, len(matches))
+	for _, m := range matches {
+		byID[m.ID] = m
+	}
+	scheme := l.config().PredictionPoints
+	predictions := []UserPrediction{}
+	for _, p := range guesses {
+		m := byID[p.MatchID]
+		p.Week, p.HomeTeam, p.AwayTeam = m.Week, m.HomeTeam, m.AwayTeam
+		if m.Played && m.Administrative != AdminAnnulled {
+			result := fmt.Sprintf("%d-%d", m.HomeGoals, m.AwayGoals)
+			points := scheme.score(p.HomeGoals, p.AwayGoals, m.HomeGoals, m.AwayGoals)
+			p.Result, p.Points = &result, &points
+		}
+		predictions = append(predictions, p)
+	}
+	sort.SliceStable(predictions, func(i, j int) bool {
+		if predictions[i].Week != predictions[j].Week {
+			return predictions[i].Week < predictions[j].Week
+		}
+		return predictions[i].MatchID < predictions[j].MatchID
+	})
+	return predictions, nil
+}
+
+// PredictorLeaderboard ranks every user with a scored guess. Users level on
+// points share a rank.
+func (l *League) PredictorLeaderboard() ([]PredictorStanding, error) {
+	rows, err := l.db.Query(`
+		SELECT u.name, p.home_goals, p.away_goals, m.home_goals, m.away_goals
+		FROM user_predictions p
+		JOIN users u ON u.id = p.user_id
+		JOIN matches m ON m.id = p.match_id
+		WHERE m.played = TRUE AND COALESCE(m.administrative, '') != 'annulled'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scheme := l.config().PredictionPoints
+	byUser := make(map[string]*PredictorStanding)
+	for rows.Next() {
+		var name string
+		var guessHome, guessAway, home, away int
+		if err := rows.Scan(&name, &guessHome, &guessAway, &home, &away); err != nil {
+			return nil, err
+		}
+		s := byUser[name]
+		if s == nil {
+			s = &PredictorStanding{User: name}
+			byUser[name] = s
+		}
+		s.Points += scheme.score(guessHome, guessAway, home, away)
+		s.Scored++
+		if guessHome == home && guessAway == away {
+			s.ExactScores++
+		}
+		if sameResult(guessHome, guessAway, home, away) {
+			s.Results++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	board := make([]PredictorStanding, 0, len(byUser))
+	for _, s := range byUser {
+		board = append(board, *s)
+	}
+	sort.Slice(board, func(i, j int) bool {
+		a, b := board[i], board[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.ExactScores != b.ExactScores {
+			return a.ExactScores > b.ExactScores
+		}
+		return a.User < b.User
+	})
+	for i := range board {
+		board[i].Rank = i + 1
+		if i > 0 && board[i].Points == board[i-1].Points {
+			board[i].Rank = board[i-1].Rank
+		}
+	}
+	return board, nil
+}
+
+// GET /me/predictions lists the user's guesses, POST adds or changes one
+// {"match_id": 3, "home_goals": 2, "away_goals": 1}
+func (l *League) handleUserPredictions(w http.ResponseWriter, r *http.Request) {
+	user := l.requireUser(w, r)
+	if user == nil {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		predictions, err := l.UserPredictions(user)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(predictions)
+	case http.MethodPost:
+		var body struct {
+			MatchID   int `json:"match_id"`
+			HomeGoals int `json:"home_goals"`
+			AwayGoals int `json:"away_goals"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		prediction, err := l.SubmitPrediction(user, body.MatchID, body.HomeGoals, body.AwayGoals)
+		switch {
+		case err == sql.ErrNoRows:
+			http.Error(w, "Match not found", http.StatusNotFound)
+			return
+		case errors.Is(err, ErrInvalidPrediction):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, ErrPredictionClosed):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(prediction)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /predictions/leaderboard
+func (l *League) handlePredictorLeaderboard(w http.ResponseWriter, r *http.Request) {
+	board, err := l.PredictorLeaderboard()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(board)
+}
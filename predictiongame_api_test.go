@@ -0,0 +1,77 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestPredictionGame(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 9)
+	var signup struct {
+		Token string `json:"token"`
+	}
+	if status := h.Do(http.MethodPost, "/users", map[string]string{"name": "ana"}, false, &signup); status != http.StatusCreated {
+		t.Fatalf("POST /users: status %d", status)
+	}
+	var other struct {
+		Token string `json:"token"`
+	}
+	h.Do(http.MethodPost, "/users", map[string]string{"name": "bo"}, false, &other)
+
+	if status := h.Do(http.MethodGet, "/me/predictions", nil, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("predictions without a user: status %d, want 401", status)
+	}
+	matches := h.Matches()
+	first := matches[0]
+	guess := func(token string, matchID, home, away int) int {
+		return h.DoWithToken(http.MethodPost, "/me/predictions",
+			map[string]int{"match_id": matchID, "home_goals": home, "away_goals": away}, token, nil)
+	}
+	if status := guess(signup.Token, first.ID, -1, 0); status != http.StatusBadRequest {
+		t.Errorf("negative guess: status %d, want 400", status)
+	}
+	if status := guess(signup.Token, 999, 1, 0); status != http.StatusNotFound {
+		t.Errorf("guess for an unknown match: status %d, want 404", status)
+	}
+	// the second guess replaces the first
+	guess(signup.Token, first.ID, 0, 0)
+	if status := guess(signup.Token, first.ID, 1, 0); status != http.StatusOK {
+		t.Fatalf("guess: status %d", status)
+	}
+	guess(other.Token, first.ID, 0, 5)
+
+	h.SimulateWeek(1)
+	if status := guess(signup.Token, first.ID, 2, 2); status != http.StatusConflict {
+		t.Errorf("guess after the match: status %d, want 409", status)
+	}
+
+	var played insider.Match
+	for _, m := range h.Matches() {
+		if m.ID == first.ID {
+			played = m
+		}
+	}
+	var mine []insider.UserPrediction
+	if status := h.DoWithToken(http.MethodGet, "/me/predictions", nil, signup.Token, &mine); status != http.StatusOK {
+		t.Fatalf("GET /me/predictions: status %d", status)
+	}
+	want := insider.DefaultPredictionPoints.Score(1, 0, played.HomeGoals, played.AwayGoals)
+	if len(mine) != 1 || mine[0].HomeGoals != 1 || mine[0].Points == nil || *mine[0].Points != want {
+		t.Fatalf("predictions %+v, want 1-0 scored %d", mine, want)
+	}
+
+	var board []insider.PredictorStanding
+	h.Get("/predictions/leaderboard", &board)
+	if len(board) != 2 || board[0].Scored != 1 {
+		t.Fatalf("leaderboard %+v", board)
+	}
+	wantOther := insider.DefaultPredictionPoints.Score(0, 5, played.HomeGoals, played.AwayGoals)
+	for _, s := range board {
+		if (s.User == "ana" && s.Points != want) || (s.User == "bo" && s.Points != wantOther) {
+			t.Errorf("%s on %d points", s.User, s.Points)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package insider
+
+import (
+	"testing"
+)
+
+func TestPredictionPointsScore(t *testing.T) {
+	p := defaultPredictionPoints
+	for _, c := range []struct {
+		guessHome, guessAway, home, away, want int
+	}{
+		{2, 1, 2, 1, 5},
+		{3, 2, 2, 1, 3},
+		{1, 1, 0, 0, 3},
+		{3, 0, 2, 1, 2},
+		{0, 1, 1, 0, 0},
+		{1, 1, 2, 1, 0},
+	} {
+		if got := p.score(c.guessHome, c.guessAway, c.home, c.away); got != c.want {
+			t.Errorf("%d-%d against %d-%d scores %d, want %d", c.guessHome, c.guessAway, c.home, c.away, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxPresetName keeps preset names usable in a URL
+const maxPresetName = 64
+
+// ErrInvalidPreset is returned for a preset without a usable name or params
+var ErrInvalidPreset = errors.New("invalid preset")
+
+// ErrPresetExists is returned when a preset name is already taken
+var ErrPresetExists = errors.New("a preset with this name already exists")
+
+// ErrPresetSport is returned when a preset was tuned for another sport
+var ErrPresetSport = errors.New("preset was tuned for another sport")
+
+// ModelPreset is a named set of simulation parameters. The JSON of a preset
+// from GET /presets/{name} can be posted to another league as it is.
+type ModelPreset struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Sport       string    `json:"sport"`
+	Params      SimParams `json:"params"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (l *League) createPresetTable() error {
+	createPresets := `
+	CREATE TABLE IF NOT EXISTS model_presets (
+		name TEXT PRIMARY KEY,
+		description TEXT NOT NULL DEFAULT '',
+		sport TEXT NOT NULL,
+		params TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);`
+
+	if _, err := l.db.Exec(createPresets); err != nil {
+		return fmt.Errorf("error creating model_presets table: %v", err)
+	}
+	return nil
+}
+
+// scanPreset reads a preset row, params are stored as JSON
+func scanPreset(row rowScanner) (ModelPreset, error) {
+	var p ModelPreset
+	var params string
+	if err := row.Scan(&p.Name, &p.Description, &p.Sport, &params, &p.CreatedAt); err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal([]byte(params), &p.Params); err != nil {
+		return p, fmt.Errorf("preset %s: %v", p.Name, err)
+	}
+	return p, nil
+}
+
+// Presets lists every saved preset by name
+func (l *League) Presets() ([]ModelPreset, error) {
+	rows, err := l.db.Query("SELECT name, description, sport, params, created_at FROM model_presets ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	presets := []ModelPreset{}
+	for rows.Next() {
+		p, err := scanPreset(rows)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, p)
+	}
+	return presets, rows.Err()
+}
+
+// Preset loads one preset, sql.ErrNoRows if there is none by that name
+func (l *League) Preset(name string) (ModelPreset, error) {
+	return scanPreset(l.db.QueryRow("SELECT name, description, sport, params, created_at FROM model_presets WHERE name = ?", name))
+}
+
+// SavePreset stores a new preset. An imported preset keeps its sport, a new
+// one is tagged with the sport being played.
+func (l *League) SavePreset(p ModelPreset) (ModelPreset, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" || len(p.Name) > maxPresetName || strings.Contains(p.Name, "/") {
+		return p, fmt.Errorf("%w: name must be 1 to %d characters without /", ErrInvalidPreset, maxPresetName)
+	}
+	if err := p.Params.Validate(); err != nil {
+		return p, fmt.Errorf("%w: %v", ErrInvalidPreset, err)
+	}
+	if p.Sport == "" {
+		p.Sport = l.config().Sport.Name
+	}
+	p.CreatedAt = time.Now().UTC()
+
+	params, err := json.Marshal(p.Params)
+	if err != nil {
+		return p, err
+	}
+	// the name is the primary key, so an existing one is left alone
+	res, err := l.db.Exec(
+		"INSERT OR IGNORE INTO model_presets (name, description, sport, params, created_at) VALUES (?, ?, ?, ?, ?)",
+		p.Name, p.Description, p.Sport, string(params), p.CreatedAt,
+	)
+	if err != nil {
+		return p, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return p, ErrPresetExists
+	}
+	return p, nil
+}
+
+// DeletePreset removes a preset, sql.ErrNoRows if there is none
+func (l *League) DeletePreset(name string) error {
+	res, err := l.db.Exec("DELETE FROM model_presets WHERE name = ?", name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ApplyPreset swaps the preset's parameters into the running config. Like
+// any runtime change it lasts until the config is reloaded or the server
+// restarts.
+func (l *League) ApplyPreset(name string) (*Config, error) {
+	p, err := l.Preset(name)
+	if err != nil {
+		return nil, err
+	}
+	cfg := *l.config()
+	if p.Sport != cfg.Sport.Name {
+		return nil, fmt.Errorf("%w: %s is for %s, the league plays %s", ErrPresetSport, p.Name, p.Sport, cfg.Sport.Name)
+	}
+	simulator := cfg.Simulation.Simulator
+	cfg.Simulation = p.Params
+	cfg.Simulation.Simulator = simulator
+	l.cfg.Store(&cfg)
+	return &cfg, nil
+}
+
+// GET /presets lists them, POST /presets saves one. Without params the
+// current simulation settings are saved.
+func (l *League) handlePresets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		presets, err := l.Presets()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(presets)
+	case http.MethodPost:
+		if !isAdmin(r) {
+			http.Error(w, "Admin token required", http.StatusUnauthorized)
+			return
+		}
+		p := ModelPreset{Params: l.config().Simulation}
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p, err := l.SavePreset(p)
+		switch {
+		case errors.Is(err, ErrInvalidPreset):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, ErrPresetExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(p)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /presets/{name} exports a preset, DELETE removes it
+func (l *League) handlePreset(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	switch r.Method {
+	case http.MethodGet:
+		p, err := l.Preset(name)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Preset not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(p)
+	case http.MethodDelete:
+		if !isAdmin(r) {
+			http.Error(w, "Admin token required", http.StatusUnauthorized)
+			return
+		}
+		err := l.DeletePreset(name)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Preset not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Preset %s deleted", name)})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// POST /presets/{name}/apply
+func (l *League) handleApplyPreset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAdmin(r) {
+		http.Error(w, "Admin token required", http.StatusUnauthorized)
+		return
+	}
+
+	cfg, err := l.ApplyPreset(r.PathValue("name"))
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "Preset not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrPresetSport):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": fmt.Sprintf("Preset %s applied", r.PathValue("name")),
+		"config":  cfg,
+	})
+}
@@ -0,0 +1,90 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestPresets(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 5)
+
+	// without params the running settings are saved
+	var current insider.ModelPreset
+	if status := h.Do(http.MethodPost, "/presets", map[string]string{"name": "current"}, true, &current); status != http.StatusCreated {
+		t.Fatalf("POST /presets: status %d", status)
+	}
+	if current.Sport != "football" || current.Params.HomeAdvantage != 10 || current.Params.StrengthPerGoal != 20 {
+		t.Errorf("saved %+v, want the running football settings", current)
+	}
+
+	tuned := map[string]any{
+		"name":        "calibrated",
+		"description": "fewer goals",
+		"params":      map[string]int{"home_advantage": 30, "strength_per_goal": 15},
+	}
+	for _, tc := range []struct {
+		body  any
+		admin bool
+		want  int
+	}{
+		{tuned, false, http.StatusUnauthorized},
+		{tuned, true, http.StatusCreated},
+		{tuned, true, http.StatusConflict},
+		{map[string]string{"name": "a/b"}, true, http.StatusBadRequest},
+		{map[string]any{"name": "flat", "params": map[string]int{"strength_per_goal": 0}}, true, http.StatusBadRequest},
+	} {
+		if status := h.Do(http.MethodPost, "/presets", tc.body, tc.admin, nil); status != tc.want {
+			t.Errorf("POST /presets %v as admin %v: status %d, want %d", tc.body, tc.admin, status, tc.want)
+		}
+	}
+
+	var presets []insider.ModelPreset
+	h.Get("/presets", &presets)
+	if len(presets) != 2 || presets[0].Name != "calibrated" || presets[1].Name != "current" {
+		t.Fatalf("presets %+v, want calibrated and current", presets)
+	}
+
+	// an exported preset is posted to another league as it is
+	var exported insider.ModelPreset
+	h.Get("/presets/calibrated", &exported)
+	other := leaguetest.New(t, insider.SnapshotTeams, 6)
+	if status := other.Do(http.MethodPost, "/presets", exported, true, nil); status != http.StatusCreated {
+		t.Fatalf("importing the exported preset: status %d", status)
+	}
+	var imported insider.ModelPreset
+	other.Get("/presets/calibrated", &imported)
+	if imported.Description != "fewer goals" || imported.Sport != "football" || imported.Params.HomeAdvantage != 30 || imported.Params.StrengthPerGoal != 15 {
+		t.Errorf("imported %+v, want the exported preset", imported)
+	}
+
+	if status := h.Do(http.MethodPost, "/presets/calibrated/apply", nil, true, nil); status != http.StatusOK {
+		t.Fatalf("apply: status %d", status)
+	}
+	if sim := h.League.Config().Simulation; sim.HomeAdvantage != 30 || sim.StrengthPerGoal != 15 {
+		t.Errorf("running settings %+v after apply, want the preset's", sim)
+	}
+
+	// a preset tuned for another sport is refused
+	if _, err := h.League.SavePreset(insider.ModelPreset{Name: "hoops", Sport: "basketball", Params: insider.SimParams{StrengthPerGoal: 2, BaseScore: 70}}); err != nil {
+		t.Fatal(err)
+	}
+	if status := h.Do(http.MethodPost, "/presets/hoops/apply", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("applying a basketball preset: status %d, want %d", status, http.StatusConflict)
+	}
+	if sim := h.League.Config().Simulation; sim.BaseScore != 0 {
+		t.Errorf("base score %d after a refused preset", sim.BaseScore)
+	}
+
+	if status := h.Do(http.MethodDelete, "/presets/calibrated", nil, true, nil); status != http.StatusOK {
+		t.Errorf("DELETE: status %d", status)
+	}
+	if status := h.Do(http.MethodGet, "/presets/calibrated", nil, false, nil); status != http.StatusNotFound {
+		t.Errorf("GET a deleted preset: status %d, want %d", status, http.StatusNotFound)
+	}
+	if status := h.Do(http.MethodPost, "/presets/nope/apply", nil, true, nil); status != http.StatusNotFound {
+		t.Errorf("applying a missing preset: status %d, want %d", status, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,159 @@
+package insider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// scheduleFormat is the one fixture format the scheduler plays
+const scheduleFormat = "double round robin"
+
+// LeagueProposal is a league a UI is about to set up
+type LeagueProposal struct {
+	Teams []Team `json:"teams"`
+	// Weeks is left out to take the computed length
+	Weeks int `json:"weeks,omitempty"`
+	// Format is left out for the double round robin
+	Format string `json:"format,omitempty"`
+	Zones  []Zone `json:"zones"`
+}
+
+// ProposalProblem is one thing wrong with a proposal, by the field it is in
+type ProposalProblem struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// ProposalReport is what setting up a proposal would give
+type ProposalReport struct {
+	Valid  bool   `json:"valid"`
+	Format string `json:"format"`
+	Teams  int    `json:"teams"`
+	// Weeks is the computed length of the fixture
+	Weeks          int `json:"weeks"`
+	Matches        int `json:"matches"`
+	MatchesPerWeek int `json:"matches_per_week"`
+	// ByeWeeks is how many weeks each team sits out, with an odd number of
+	// teams
+	ByeWeeks int `json:"bye_weeks"`
+	// FixtureFeasible is whether the scheduler can build a clash-free
+	// fixture for the teams
+	FixtureFeasible bool              `json:"fixture_feasible"`
+	Problems        []ProposalProblem `json:"problems"`
+}
+
+// ValidateProposal checks a proposed league the way setting it up would,
+// reporting every problem instead of stopping at the first. The fixture is
+// drawn in memory and checked against the scheduling rules.
+func ValidateProposal(p LeagueProposal) *ProposalReport {
+	n := len(p.Teams)
+	report := &ProposalReport{Format: scheduleFormat, Teams: n, Problems: []ProposalProblem{}}
+	add := func(field, format string, args ...any) {
+		report.Problems = append(report.Problems, ProposalProblem{Field: field, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if p.Format != "" && p.Format != scheduleFormat {
+		add("format", "unsupported format %q, only %q can be scheduled", p.Format, scheduleFormat)
+	}
+
+	if n < 2 {
+		add("teams", "a league needs at least 2 teams, got %d", n)
+	}
+	seen := make(map[string]bool)
+	for i, team := range p.Teams {
+		field := fmt.Sprintf("teams[%d]", i)
+		switch {
+		case team.Name == "":
+			add(field, "team name cannot be empty")
+		case seen[team.Name]:
+			add(field, "duplicate team %q", team.Name)
+		}
+		seen[team.Name] = true
+		if err := checkStrength(team.Strength); err != nil {
+			add(field, "%v", err)
+		}
+	}
+
+	if n >= 2 {
+		report.Weeks = FixtureWeeks(n)
+		report.Matches = n * (n - 1) / 2 * meetingsPerPair
+		report.MatchesPerWeek = n / 2
+		if n%2 == 1 {
+			report.ByeWeeks = meetingsPerPair
+		}
+		if p.Weeks != 0 && p.Weeks != report.Weeks {
+			add("weeks", "%d teams play a %s over %d weeks, got %d", n, scheduleFormat, report.Weeks, p.Weeks)
+		}
+
+		ids := make([]int, n)
+		for i := range ids {
+			ids[i] = i + 1
+		}
+		matches := scheduleFixture(ids)
+		var problems []error
+		if err := ValidateFixture(matches, ids, meetingsPerPair); err != nil {
+			problems = err.(interface{ Unwrap() []error }).Unwrap()
+		}
+		for _, m := range matches {
+			if m.Week > report.Weeks {
+				problems = append(problems, fmt.Errorf("%w: week %d is past the last week", ErrFixtureClash, m.Week))
+			}
+		}
+		report.FixtureFeasible = len(problems) == 0
+		for _, err := range problems {
+			add("fixture", "%v", err)
+		}
+	}
+
+	// zones in table order, each checked against the one reaching furthest
+	// down before it
+	zones := append([]Zone(nil), p.Zones...)
+	sort.SliceStable(zones, func(i, j int) bool { return zones[i].From < zones[j].From })
+	names := make(map[string]bool)
+	var above *Zone
+	for i, z := range zones {
+		field := fmt.Sprintf("zones.%s", z.Name)
+		switch {
+		case z.Name == "":
+			add("zones", "zone name cannot be empty")
+			continue
+		case names[z.Name]:
+			add(field, "duplicate zone %q", z.Name)
+		}
+		names[z.Name] = true
+		if z.From < 1 || z.To < z.From {
+			add(field, "invalid positions %d-%d", z.From, z.To)
+			continue
+		}
+		if n >= 2 && z.To > n {
+			add(field, "positions %d-%d run past the %d teams", z.From, z.To, n)
+		}
+		// a table row only shows the first zone it falls in
+		if above != nil && above.To >= z.From {
+			add(field, "overlaps %s at positions %d-%d", above.Name, z.From, min(z.To, above.To))
+		}
+		if above == nil || z.To > above.To {
+			above = &zones[i]
+		}
+	}
+
+	report.Valid = len(report.Problems) == 0
+	return report
+}
+
+// POST /leagues/validate checks a proposed league without creating anything:
+// {"teams": [{"name": "A", "strength": 70}, ...], "zones": [...]}
+func handleValidateProposal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var proposal LeagueProposal
+	if err := json.NewDecoder(r.Body).Decode(&proposal); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(ValidateProposal(proposal))
+}
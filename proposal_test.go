@@ -0,0 +1,57 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestValidateProposal(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 1)
+	proposal := insider.LeagueProposal{
+		Teams: []insider.Team{{Name: "A", Strength: 60}, {Name: "B", Strength: 70}, {Name: "C", Strength: 80}},
+		Zones: []insider.Zone{{Name: "champion", From: 1, To: 1}, {Name: "relegation", From: 3, To: 3}},
+	}
+	var report insider.ProposalReport
+	if status := h.Do(http.MethodPost, "/leagues/validate", proposal, false, &report); status != http.StatusOK {
+		t.Fatalf("POST /leagues/validate: status %d", status)
+	}
+	if !report.Valid || !report.FixtureFeasible || len(report.Problems) != 0 {
+		t.Fatalf("valid proposal reported %+v", report)
+	}
+	if report.Weeks != 6 || report.Matches != 6 || report.MatchesPerWeek != 1 || report.ByeWeeks != 2 {
+		t.Errorf("3 teams: %d weeks, %d matches, %d a week, %d byes", report.Weeks, report.Matches, report.MatchesPerWeek, report.ByeWeeks)
+	}
+
+	proposal.Teams = append(proposal.Teams, insider.Team{Name: "A", Strength: 101})
+	proposal.Weeks = 5
+	proposal.Format = "knockout"
+	proposal.Zones = append(proposal.Zones, insider.Zone{Name: "europe", From: 1, To: 2}, insider.Zone{Name: "playoff", From: 4, To: 5})
+	report = *insider.ValidateProposal(proposal)
+	if report.Valid || !report.FixtureFeasible {
+		t.Errorf("valid %v, fixture feasible %v", report.Valid, report.FixtureFeasible)
+	}
+	fields := make(map[string]int)
+	for _, p := range report.Problems {
+		fields[p.Field]++
+	}
+	// the duplicate name and the strength off the scale are both reported
+	want := map[string]int{"format": 1, "teams[3]": 2, "weeks": 1, "zones.europe": 1, "zones.playoff": 1}
+	for field, n := range want {
+		if fields[field] != n {
+			t.Errorf("%d problems in %s, want %d: %+v", fields[field], field, n, report.Problems)
+		}
+	}
+	if len(report.Problems) != 6 {
+		t.Errorf("%d problems: %+v", len(report.Problems), report.Problems)
+	}
+
+	if status := h.Do(http.MethodGet, "/leagues/validate", nil, false, nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d, want 405", status)
+	}
+	if n := len(h.League.Teams()); n != len(insider.SnapshotTeams) {
+		t.Errorf("validating changed the league to %d teams", n)
+	}
+}
@@ -0,0 +1,108 @@
+package insider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// With --check-query-plans the server asks SQLite at startup how it would
+// run the queries behind the busiest endpoints. A plan that reads a whole
+// table is fine while the table is small; on a table of --large-table-rows
+// rows or more it marks the server not ready until it is restarted with the
+// index back.
+
+// hotQuery is a query run on most requests, with the table it must reach
+// through an index
+type hotQuery struct {
+	name  string
+	query string
+	args  []any
+	table string
+	// alias is how the query names the table, as the plan reports it
+	alias string
+}
+
+var hotQueries = []hotQuery{
+	{"match by id", matchSelect + " WHERE m.id = ?", []any{1}, "matches", "m"},
+	{"matches to play in a week", matchSelect + " WHERE m.week = ? AND m.played = FALSE AND m.postponed = FALSE AND m.live = FALSE ORDER BY m.id", []any{1}, "matches", "m"},
+	{"team fixtures", matchSelect + " WHERE m.home_team_id = ? OR m.away_team_id = ? ORDER BY m.week, m.id", []any{1, 1}, "matches", "m"},
+	{"matches by date", matchSelect + " WHERE m.kickoff IS NOT NULL AND " + kickoffDate + " >= ? AND " + kickoffDate + " <= ? ORDER BY m.kickoff, m.id", []any{"2025-01-01", "2025-01-07"}, "matches", "m"},
+	{"events of a match", "SELECT e.minute, e.type FROM match_events e WHERE e.match_id = ? ORDER BY e.minute, e.id", []any{1}, "match_events", "e"},
+	{"guesses on a match", "SELECT p.user_id FROM user_predictions p WHERE p.match_id = ?", []any{1}, "user_predictions", "p"},
+	{"webhook deliveries due", "SELECT o.id FROM outbox o WHERE o.status = ? AND o.next_attempt_at <= ? ORDER BY o.id", []any{OutboxPending, "2025-01-01"}, "outbox", "o"},
+}
+
+// QueryPlanProblem is a hot query that would read a large table row by row
+type QueryPlanProblem struct {
+	Query string   `json:"query"`
+	Table string   `json:"table"`
+	Rows  int      `json:"rows"`
+	Plan  []string `json:"plan"`
+}
+
+// queryPlan returns the detail lines of EXPLAIN QUERY PLAN
+func (l *League) queryPlan(q hotQuery) ([]string, error) {
+	rows, err := l.db.Query("EXPLAIN QUERY PLAN "+q.query, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", q.name, err)
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			return nil, err
+		}
+		plan = append(plan, detail)
+	}
+	return plan, rows.Err()
+}
+
+// CheckQueryPlans lists the hot queries that scan a table of at least
+// minRows rows
+func (l *League) CheckQueryPlans(minRows int) ([]QueryPlanProblem, error) {
+	problems := []QueryPlanProblem{}
+	for _, q := range hotQueries {
+		plan, err := l.queryPlan(q)
+		if err != nil {
+			return nil, err
+		}
+		scans := false
+		for _, detail := range plan {
+			// "SCAN m" and "SCAN m USING INDEX ..." both read every row
+			if detail == "SCAN "+q.alias || strings.HasPrefix(detail, "SCAN "+q.alias+" ") {
+				scans = true
+			}
+		}
+		if !scans {
+			continue
+		}
+		var count int
+		if err := l.db.QueryRow("SELECT COUNT(*) FROM " + q.table).Scan(&count); err != nil {
+			return nil, err
+		}
+		if count >= minRows {
+			problems = append(problems, QueryPlanProblem{Query: q.name, Table: q.table, Rows: count, Plan: plan})
+		}
+	}
+	return problems, nil
+}
+
+// GET /readyz answers 200 once the server can take traffic, 503 with the
+// problems found at startup otherwise
+func (l *League) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(l.planProblems) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]any{"ready": false, "query_plans": l.planProblems})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"ready": true})
+}
@@ -0,0 +1,55 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider/leaguetest"
+)
+
+func TestHotQueriesUseIndexes(t *testing.T) {
+	h := leaguetest.New(t, nil, 1)
+	l := h.League
+	if err := l.SimulateAll(); err != nil {
+		t.Fatalf("simulate: %v", err)
+	}
+
+	// every table counts as large
+	problems, err := l.CheckQueryPlans(0)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	for _, p := range problems {
+		t.Errorf("%s scans %s: %v", p.Query, p.Table, p.Plan)
+	}
+
+	if _, err := l.DB().Exec("DROP INDEX idx_match_events_match"); err != nil {
+		t.Fatal(err)
+	}
+	if problems, err = l.CheckQueryPlans(0); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Table != "match_events" {
+		t.Fatalf("without the events index: %+v", problems)
+	}
+	if more, _ := l.CheckQueryPlans(problems[0].Rows + 1); len(more) != 0 {
+		t.Errorf("scan of a small table reported: %+v", more)
+	}
+
+	l.SetPlanProblems(problems)
+	if status := h.Do(http.MethodGet, "/readyz", nil, false, nil); status != http.StatusServiceUnavailable {
+		t.Errorf("readyz with a scan: status %d, want 503", status)
+	}
+
+	// the next start puts the index back
+	if err := l.CreateIndexes(); err != nil {
+		t.Fatalf("create indexes: %v", err)
+	}
+	if problems, err = l.CheckQueryPlans(0); err != nil || len(problems) != 0 {
+		t.Errorf("after createIndexes: %+v, %v", problems, err)
+	}
+	l.SetPlanProblems(problems)
+	if status := h.Do(http.MethodGet, "/readyz", nil, false, nil); status != http.StatusOK {
+		t.Errorf("readyz: status %d, want 200", status)
+	}
+}
@@ -0,0 +1,207 @@
+package insider
+
+import (
+	"bufio"
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The league draws from two streams, rng for results and flavor for events.
+// Either can be swapped for another rand.Source: a fixed seed, crypto/rand,
+// or a replay of numbers recorded from an earlier run, which reproduces a
+// bug report draw for draw as long as the same requests are made.
+// Predictions draw from a third stream, predict, so reading them between
+// weeks leaves the results alone.
+
+// stream names in a recording
+const (
+	streamRNG    = "rng"
+	streamFlavor = "flavor"
+)
+
+// SetRandSources replaces both random streams. It has to happen before the
+// league is used; Seed still works on sources that support it. The league
+// no longer knows a seed for the new streams, and predictions get a stream
+// seeded from the time, since they never reach the results.
+func (l *League) SetRandSources(rng, flavor rand.Source) {
+	l.rng = rand.New(&lockedSource{src: rng})
+	l.flavor = rand.New(&lockedSource{src: flavor})
+	l.predict = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
+	l.seed.Store(nil)
+}
+
+// randSourceSeed is the seed of a "seed:N" --rand-source
+func randSourceSeed(spec string) (int64, bool) {
+	arg, ok := strings.CutPrefix(spec, "seed:")
+	if !ok {
+		return 0, false
+	}
+	seed, err := strconv.ParseInt(arg, 10, 64)
+	return seed, err == nil
+}
+
+// seededStreams are fresh result and event streams for a one-off seed, or
+// the league's own without one
+func (l *League) seededStreams(seed *int64) (rng, flavor *rand.Rand) {
+	if seed == nil {
+		return l.rng, l.flavor
+	}
+	return rand.New(rand.NewSource(*seed)), rand.New(rand.NewSource(*seed + 1))
+}
+
+// predictionSeed is the seed of a prediction that should come out the same
+// every time it is asked for, derived from the league's seed and key without
+// drawing from any stream. A league without a seed draws it from predict.
+func (l *League) predictionSeed(key int64) int64 {
+	if seed := l.seed.Load(); seed != nil {
+		return rand.New(rand.NewSource(*seed + 2 + key)).Int63()
+	}
+	return l.predict.Int63()
+}
+
+// seedParam reads the optional ?seed= query parameter
+func seedParam(r *http.Request) (*int64, error) {
+	s := r.URL.Query().Get("seed")
+	if s == "" {
+		return nil, nil
+	}
+	seed, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid seed parameter")
+	}
+	return &seed, nil
+}
+
+// parseRandSource turns a --rand-source value into the two streams: "seed:N",
+// "crypto" or "replay:FILE"
+func parseRandSource(spec string) (rng, flavor rand.Source, err error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "seed":
+		seed, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid seed %q", arg)
+		}
+		// the same offset Seed uses
+		return rand.NewSource(seed), rand.NewSource(seed + 1), nil
+	case "crypto":
+		return cryptoSource{}, cryptoSource{}, nil
+	case "replay":
+		f, err := os.Open(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		return loadReplay(f)
+	}
+	return nil, nil, fmt.Errorf("unknown random source %q, expected seed:N, crypto or replay:FILE", spec)
+}
+
+// cryptoSource reads crypto/rand, it cannot be seeded
+type cryptoSource struct{}
+
+func (cryptoSource) Int63() int64 {
+	var b [8]byte
+	if _, err := cryptorand.Read(b[:]); err != nil {
+		panic(fmt.Errorf("crypto/rand failed: %v", err))
+	}
+	return int64(binary.BigEndian.Uint64(b[:]) &^ (1 << 63))
+}
+
+func (cryptoSource) Seed(int64) {}
+
+// randRecorder writes every draw as a "stream number" line
+type randRecorder struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (r *randRecorder) record(stream string, n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.w, "%s %d\n", stream, n)
+}
+
+// recordingSource passes draws through and records them
+type recordingSource struct {
+	src      rand.Source
+	stream   string
+	recorder *randRecorder
+}
+
+func (s *recordingSource) Int63() int64 {
+	n := s.src.Int63()
+	s.recorder.record(s.stream, n)
+	return n
+}
+
+func (s *recordingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+}
+
+// recordRandSources wraps both streams so every draw is written to w
+func recordRandSources(rng, flavor rand.Source, w io.Writer) (rand.Source, rand.Source) {
+	recorder := &randRecorder{w: w}
+	return &recordingSource{src: rng, stream: streamRNG, recorder: recorder},
+		&recordingSource{src: flavor, stream: streamFlavor, recorder: recorder}
+}
+
+// replaySource hands out recorded numbers in order. A replay that runs out
+// has gone past the recording: that is printed once and the stream carries
+// on from a fixed seed, so the server keeps working.
+type replaySource struct {
+	stream   string
+	values   []int64
+	next     int
+	fallback rand.Source
+}
+
+func (s *replaySource) Int63() int64 {
+	if s.next < len(s.values) {
+		n := s.values[s.next]
+		s.next++
+		return n
+	}
+	if s.fallback == nil {
+		fmt.Printf("Replay of %s ran out after %d draws, continuing from seed 0\n", s.stream, len(s.values))
+		s.fallback = rand.NewSource(0)
+	}
+	return s.fallback.Int63()
+}
+
+// Seed does nothing, a replay always gives the recorded numbers
+func (s *replaySource) Seed(int64) {}
+
+// loadReplay reads a recording made with --record-rand
+func loadReplay(r io.Reader) (rng, flavor rand.Source, err error) {
+	streams := map[string]*replaySource{
+		streamRNG:    {stream: streamRNG},
+		streamFlavor: {stream: streamFlavor},
+	}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		name, value, _ := strings.Cut(scanner.Text(), " ")
+		s, ok := streams[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("replay line %d: unknown stream %q", line, name)
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("replay line %d: %v", line, err)
+		}
+		s.values = append(s.values, n)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return streams[streamRNG], streams[streamFlavor], nil
+}
@@ -0,0 +1,35 @@
+package insider
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestReplayRandSource(t *testing.T) {
+	var recording bytes.Buffer
+	recorded := newTestLeague(t, snapshotTeams, 6, 0)
+	recorded.SetRandSources(recordRandSources(rand.NewSource(7), rand.NewSource(8), &recording))
+	if err := recorded.SimulateAll(); err != nil {
+		t.Fatalf("simulate recorded season: %v", err)
+	}
+
+	rng, flavor, err := loadReplay(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatalf("load replay: %v", err)
+	}
+	replayed := newTestLeague(t, snapshotTeams, 6, 0)
+	replayed.SetRandSources(rng, flavor)
+	if err := replayed.SimulateAll(); err != nil {
+		t.Fatalf("simulate replayed season: %v", err)
+	}
+
+	want, got := loadMatches(t, recorded), loadMatches(t, replayed)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replay differs from the recording:\ngot  %+v\nwant %+v", got, want)
+	}
+	if n := rng.(*replaySource).next; n != len(rng.(*replaySource).values) {
+		t.Errorf("replay used %d of %d recorded draws", n, len(rng.(*replaySource).values))
+	}
+}
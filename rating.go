@@ -0,0 +1,223 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+)
+
+// With dynamic strength on, every simulated week ends with an Elo update:
+// each side gains K times the gap between its result (1 for a win, 0.5 for
+// a draw) and the result its rating made likely, so a win over a stronger
+// side is worth more than one over a weaker side. The ratings are kept with
+// their fractions in rating_updates, and the teams' strengths follow them
+// rounded, as edits in the strength history. A strength edited by hand
+// since the last update starts the next update from the new value.
+
+// DynamicStrength is the "dynamic_strength" block of the config
+type DynamicStrength struct {
+	Enabled bool `json:"enabled"`
+	// K is the most strength a team can win or lose in one match
+	K float64 `json:"k"`
+	// Scale is the strength gap that makes the stronger side ten times as
+	// likely to win as the weaker one
+	Scale float64 `json:"scale"`
+}
+
+var defaultDynamicStrength = DynamicStrength{K: 2, Scale: 50}
+
+func (d DynamicStrength) Validate() error {
+	if d.K < 0 {
+		return fmt.Errorf("k cannot be negative, got %v", d.K)
+	}
+	if d.Scale <= 0 {
+		return fmt.Errorf("scale must be positive, got %v", d.Scale)
+	}
+	return nil
+}
+
+// expected is the home side's expected result against the away side
+func (d DynamicStrength) expected(home, away float64) float64 {
+	return 1 / (1 + math.Pow(10, (away-home)/d.Scale))
+}
+
+// RatingUpdate is one team's rating moving after (part of) a week
+type RatingUpdate struct {
+	Week      int       `json:"week"`
+	Matches   int       `json:"matches"`
+	Before    float64   `json:"before"`
+	After     float64   `json:"after"`
+	Strength  int       `json:"strength"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RatingHistory is the path of one team's rating through the season
+type RatingHistory struct {
+	Team    string         `json:"team"`
+	Updates []RatingUpdate `json:"updates"`
+}
+
+func (l *League) createRatingTable() error {
+	createRatings := `
+	CREATE TABLE IF NOT EXISTS rating_updates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+		week INTEGER NOT NULL,
+		matches INTEGER NOT NULL,
+		rating_before REAL NOT NULL,
+		rating_after REAL NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);`
+
+	if _, err := l.db.Exec(createRatings); err != nil {
+		return fmt.Errorf("error creating rating_updates table: %v", err)
+	}
+	return nil
+}
+
+// ratings are the teams' current ratings: the last update, or the strength
+// when there is none or the strength was edited since
+func (l *League) ratings() (map[int]float64, error) {
+	strengths := l.strengths()
+	ratings := make(map[int]float64, len(strengths))
+	for id, strength := range strengths {
+		ratings[id] = float64(strength)
+	}
+
+	rows, err := l.db.Query(`
+		SELECT team_id, rating_after FROM rating_updates
+		WHERE id IN (SELECT MAX(id) FROM rating_updates GROUP BY team_id)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var rating float64
+		if err := rows.Scan(&id, &rating); err != nil {
+			return nil, err
+		}
+		if strength, ok := strengths[id]; ok && int(math.Round(rating)) == strength {
+			ratings[id] = rating
+		}
+	}
+	return ratings, rows.Err()
+}
+
+// updateRatings moves the ratings of the teams in the matches just played
+// in week. Every match of the week is judged on the ratings before it.
+func (l *League) updateRatings(week int, matches []Match, cfg *Config) error {
+	d := cfg.DynamicStrength
+	ratings, err := l.ratings()
+	if err != nil {
+		return err
+	}
+
+	deltas := make(map[int]float64)
+	played := make(map[int]int)
+	for _, m := range matches {
+		var home float64
+		switch {
+		case m.HomeGoals > m.AwayGoals:
+			home = 1
+		case m.HomeGoals == m.AwayGoals:
+			home = 0.5
+		}
+		expected := d.expected(ratings[m.HomeTeamID]+float64(cfg.Simulation.HomeAdvantage), ratings[m.AwayTeamID])
+		deltas[m.HomeTeamID] += d.K * (home - expected)
+		deltas[m.AwayTeamID] -= d.K * (home - expected)
+		played[m.HomeTeamID]++
+		played[m.AwayTeamID]++
+	}
+
+	now := l.clock.Now().UTC()
+	names := make(map[int]string)
+	for _, t := range l.Teams() {
+		names[t.ID] = t.Name
+	}
+	for id, delta := range deltas {
+		before := ratings[id]
+		// the rating stays on the strength scale
+		after := math.Min(MaxStrength, math.Max(MinStrength, before+delta))
+		if _, err := l.db.Exec("INSERT INTO rating_updates (team_id, week, matches, rating_before, rating_after, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+			id, week, played[id], before, after, now); err != nil {
+			return err
+		}
+		reason := fmt.Sprintf("Elo update after week %d", week)
+		if _, err := l.SetStrength(names[id], int(math.Round(after)), "elo", reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RatingHistories lists every team's rating updates in the order they were
+// made
+func (l *League) RatingHistories() ([]RatingHistory, error) {
+	rows, err := l.db.Query(`
+		SELECT t.name, r.week, r.matches, r.rating_before, r.rating_after, r.updated_at
+		FROM rating_updates r JOIN teams t ON t.id = r.team_id
+		ORDER BY r.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	updates := make(map[string][]RatingUpdate)
+	for rows.Next() {
+		var name string
+		var u RatingUpdate
+		if err := rows.Scan(&name, &u.Week, &u.Matches, &u.Before, &u.After, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		u.Strength = int(math.Round(u.After))
+		updates[name] = append(updates[name], u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	histories := []RatingHistory{}
+	for _, t := range l.Teams() {
+		h := RatingHistory{Team: t.Name, Updates: updates[t.Name]}
+		if h.Updates == nil {
+			h.Updates = []RatingUpdate{}
+		}
+		histories = append(histories, h)
+	}
+	return histories, nil
+}
+
+// GET /ratings/history, ?team= for one team
+func (l *League) handleRatingHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	histories, err := l.RatingHistories()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if name := r.URL.Query().Get("team"); name != "" {
+		_, current, err := l.resolveTeam(name)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Team not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, h := range histories {
+			if h.Team == current {
+				json.NewEncoder(w).Encode(h)
+				return
+			}
+		}
+	}
+	json.NewEncoder(w).Encode(histories)
+}
@@ -0,0 +1,81 @@
+package insider_test
+
+import (
+	"math"
+	"net/url"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestDynamicStrengthFollowsResults(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 5)
+	cfg := *h.League.Config()
+	cfg.DynamicStrength = insider.DynamicStrength{Enabled: true, K: 10, Scale: 50}
+	h.League.StoreConfig(&cfg)
+
+	if err := h.League.SimulateWeek(1); err != nil {
+		t.Fatalf("simulate week 1: %v", err)
+	}
+	var histories []insider.RatingHistory
+	h.Get("/ratings/history", &histories)
+	if len(histories) != len(insider.SnapshotTeams) {
+		t.Fatalf("%d histories for %d teams", len(histories), len(insider.SnapshotTeams))
+	}
+
+	// what one side gains the other loses
+	total := 0.0
+	strengths := make(map[string]int)
+	for _, team := range h.League.Teams() {
+		strengths[team.Name] = team.Strength
+	}
+	playing := make(map[string]map[int]bool)
+	for _, m := range h.Matches() {
+		for _, name := range []string{m.HomeTeam, m.AwayTeam} {
+			if playing[name] == nil {
+				playing[name] = make(map[int]bool)
+			}
+			playing[name][m.Week] = true
+		}
+	}
+	for _, history := range histories {
+		want := 0
+		if playing[history.Team][1] {
+			want = 1
+		}
+		if len(history.Updates) != want {
+			t.Fatalf("%s: %d updates after week 1, want %d", history.Team, len(history.Updates), want)
+		}
+		if want == 0 {
+			continue
+		}
+		u := history.Updates[0]
+		total += u.After - u.Before
+		if u.Week != 1 || u.Strength != strengths[history.Team] || u.Strength != int(math.Round(u.After)) {
+			t.Errorf("%s: update %+v, strength %d", history.Team, u, strengths[history.Team])
+		}
+	}
+	if math.Abs(total) > 1e-9 {
+		t.Errorf("ratings moved by %v in total, want 0", total)
+	}
+
+	// a hand edit is where the next update starts
+	var edited string
+	for name, weeks := range playing {
+		if weeks[2] {
+			edited = name
+		}
+	}
+	if _, err := h.League.SetStrength(edited, 40, "admin", ""); err != nil {
+		t.Fatalf("set strength: %v", err)
+	}
+	if err := h.League.SimulateWeek(2); err != nil {
+		t.Fatalf("simulate week 2: %v", err)
+	}
+	var history insider.RatingHistory
+	h.Get("/ratings/history?team="+url.QueryEscape(edited), &history)
+	if n := len(history.Updates); n == 0 || history.Updates[n-1].Before != 40 {
+		t.Errorf("%s: week 2 did not start from 40: %+v", edited, history.Updates)
+	}
+}
@@ -0,0 +1,16 @@
+package insider
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDynamicStrengthExpectation(t *testing.T) {
+	d := defaultDynamicStrength
+	if e := d.expected(60, 60); e != 0.5 {
+		t.Errorf("level sides expect %v, want 0.5", e)
+	}
+	if e := d.expected(60+d.Scale, 60); math.Abs(e-10.0/11) > 1e-9 {
+		t.Errorf("a gap of one scale expects %v, want 10/11", e)
+	}
+}
@@ -0,0 +1,92 @@
+package insider
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// The matches table is mirrored in memory so that polling clients read the
+// mirror instead of queueing up behind writers on the SQLite file. Teams are
+// already kept in memory, see loadTeams. Every write to matches ends with
+// matchesChanged, which reloads the mirror in one query.
+
+// refreshMatches reloads the mirror. Refreshes are serialized, so the last
+// one to finish has seen every write committed before it started.
+func (l *League) refreshMatches() error {
+	l.mirrorMu.Lock()
+	defer l.mirrorMu.Unlock()
+
+	rows, err := l.db.Query(matchSelect + " ORDER BY m.id")
+	if err != nil {
+		l.mirror.Store(nil)
+		return err
+	}
+	defer rows.Close()
+
+	matches := []Match{}
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			l.mirror.Store(nil)
+			return err
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		l.mirror.Store(nil)
+		return err
+	}
+	l.mirror.Store(&matches)
+	return nil
+}
+
+// matchesChanged is called after every committed write to matches. If the
+// reload fails the mirror is dropped and the next read loads it again.
+func (l *League) matchesChanged() {
+	if err := l.refreshMatches(); err != nil {
+		fmt.Println("Refreshing matches failed:", err)
+	}
+}
+
+// Matches returns every match ordered by id from the mirror. The slice is
+// shared, callers must not modify it.
+func (l *League) Matches() ([]Match, error) {
+	if m := l.mirror.Load(); m != nil {
+		return *m, nil
+	}
+	if err := l.refreshMatches(); err != nil {
+		return nil, err
+	}
+	return l.Matches()
+}
+
+// streamMatches writes a JSON array one match at a time, so a big fixture is
+// never held in memory a second time as one encoded body
+func streamMatches(w http.ResponseWriter, matches []Match, fields fieldSelection) {
+	// the status is already sent once anything is written, so on an error
+	// the client sees a cut off array
+	write := func(data []byte) bool {
+		if _, err := w.Write(data); err != nil {
+			fmt.Println("Writing matches failed:", err)
+			return false
+		}
+		return true
+	}
+	if !write([]byte("[")) {
+		return
+	}
+	for i, m := range matches {
+		if i > 0 && !write([]byte(",")) {
+			return
+		}
+		data, err := fields.encode(m)
+		if err != nil {
+			fmt.Println("Encoding matches failed:", err)
+			return
+		}
+		if !write(data) {
+			return
+		}
+	}
+	write([]byte("]\n"))
+}
@@ -0,0 +1,66 @@
+package insider_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+// go test -run '^$' -bench League24 gives the latencies the README quotes,
+// on a 24-team, 46-week league (552 matches) served over HTTP
+func BenchmarkLeague24(b *testing.B) {
+	teams := make([]insider.Team, 24)
+	for i := range teams {
+		teams[i] = insider.Team{Name: fmt.Sprintf("Team %d", i+1), Strength: 40 + 2*i}
+	}
+	h := leaguetest.New(b, teams, 1)
+	// get reads a whole response, as a client would
+	get := func(b *testing.B, path string) {
+		resp, err := h.Server.Client().Get(h.Server.URL + path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+			b.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			b.Fatalf("GET %s: status %d", path, resp.StatusCode)
+		}
+	}
+
+	b.Run("simulate_all", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			if err := h.League.GenerateFixture(true); err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+			if status := h.Do(http.MethodPost, "/simulate/all", nil, false, nil); status != http.StatusOK {
+				b.Fatalf("POST /simulate/all: status %d", status)
+			}
+		}
+	})
+
+	// the reads run halfway through the season
+	if err := h.League.GenerateFixture(true); err != nil {
+		b.Fatal(err)
+	}
+	for week := 1; week <= 23; week++ {
+		if err := h.League.SimulateWeek(week); err != nil {
+			b.Fatal(err)
+		}
+	}
+	for _, path := range []string{"/matches", "/matches?week=30", "/weeks", "/standings"} {
+		b.Run(strings.TrimPrefix(path, "/"), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				get(b, path)
+			}
+		})
+	}
+}
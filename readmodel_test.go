@@ -0,0 +1,144 @@
+package insider
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// TestMirrorFollowsWrites checks the in-memory matches against the table
+// after every kind of write to it
+func TestMirrorFollowsWrites(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, FixtureWeeks(len(snapshotTeams)), 5)
+	check := func(step string, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		mirror, err := l.Matches()
+		if err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		table := loadMatches(t, l)
+		if len(mirror)+len(table) > 0 && !reflect.DeepEqual(mirror, table) {
+			t.Fatalf("after %s the mirror differs from the table:\n%+v\n%+v", step, mirror, table)
+		}
+	}
+	// unplayedIn is the id of an unplayed match of week
+	unplayedIn := func(week int) int {
+		for _, m := range loadMatches(t, l) {
+			if m.Week == week && !m.Played && !m.Live && !m.Postponed {
+				return m.ID
+			}
+		}
+		t.Fatalf("no unplayed match in week %d", week)
+		return 0
+	}
+	check("loading", nil)
+
+	check("simulating a week", l.SimulateWeek(1))
+	check("entering a result", l.UpdateMatchResult(unplayedIn(2), 2, 1, nil))
+	check("entering results", l.UpdateMatchResults([]ResultUpdate{{ID: unplayedIn(2), HomeGoals: 0, AwayGoals: 0}}))
+	_, err := l.SimulateMatch(unplayedIn(3), nil)
+	check("simulating a match", err)
+	check("a live score", l.UpdateLiveScore(unplayedIn(3), 30, 1, 0))
+	postponed := unplayedIn(4)
+	check("postponing", l.PostponeMatch(postponed))
+	check("rescheduling", l.RescheduleMatch(postponed, 4, "2026-06-01"))
+	_, err = l.Decide(unplayedIn(4), DecisionRequest{Action: ActionAward, Winner: "home", Reason: "walkover"})
+	check("a decision", err)
+	check("a rename", l.RenameTeam("Delta SC", "Delta Athletic"))
+	check("a new fixture", l.GenerateFixture(true))
+
+	doc, err := l.Schedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("uploading a schedule", l.UploadSchedule(*doc, true))
+
+	for week := 1; week <= FixtureWeeks(len(snapshotTeams)); week++ {
+		if err := l.SimulateWeek(week); err != nil {
+			t.Fatal(err)
+		}
+	}
+	next, err := l.ArchiveSeason("")
+	check("archiving the season", err)
+	_, err = l.StartSeason(next.ID)
+	check("starting the next season", err)
+}
+
+// TestTablesWithoutDepartedTeam plays on after a team left the division
+// halfway through, its matches still in the table
+func TestTablesWithoutDepartedTeam(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, FixtureWeeks(len(snapshotTeams)), 5)
+	if err := l.SimulateWeek(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.db.Exec("UPDATE teams SET active = FALSE WHERE name = 'Delta SC'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.loadTeams(); err != nil {
+		t.Fatal(err)
+	}
+
+	standings, err := l.CalculateStandings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(standings) != 3 {
+		t.Errorf("%d teams in the table, want 3", len(standings))
+	}
+	// of week 1 only the match without Delta SC counts
+	played := 0
+	for _, s := range standings {
+		played += s.Played
+	}
+	if played != 2 {
+		t.Errorf("%d sides of a match counted, want 2", played)
+	}
+	prediction, err := l.PredictMonteCarlo(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prediction.Teams) != 3 {
+		t.Errorf("%d teams predicted, want 3", len(prediction.Teams))
+	}
+}
+
+// failingWriter takes a number of writes and fails every one after
+type failingWriter struct {
+	httptest.ResponseRecorder
+	left, writes int
+}
+
+func (w *failingWriter) Write(data []byte) (int, error) {
+	w.writes++
+	if w.left == 0 {
+		return 0, errors.New("connection reset")
+	}
+	w.left--
+	return w.ResponseRecorder.Write(data)
+}
+
+func TestStreamMatchesStopsOnError(t *testing.T) {
+	matches := make([]Match, 10)
+	for i := range matches {
+		matches[i] = Match{ID: i + 1, HomeTeam: "Alpha FC", AwayTeam: "Bravo United"}
+	}
+
+	w := &failingWriter{ResponseRecorder: *httptest.NewRecorder(), left: 1000}
+	streamMatches(w, matches, nil)
+	var decoded []Match
+	if err := json.Unmarshal(w.Body.Bytes(), &decoded); err != nil || len(decoded) != len(matches) {
+		t.Fatalf("streamed %d matches (%v): %s", len(decoded), err, w.Body.String())
+	}
+
+	// "[", the first match, "," and the second match go through
+	w = &failingWriter{ResponseRecorder: *httptest.NewRecorder(), left: 4}
+	streamMatches(w, matches, nil)
+	if w.writes != 5 {
+		t.Errorf("%d writes, want 5: the stream goes on after a failed write", w.writes)
+	}
+}
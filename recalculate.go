@@ -0,0 +1,94 @@
+package insider
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// A result edited after later weeks were played, or one that replaces an
+// earlier result, changes history. Caches are dropped as for any result, but
+// clinches announced on the old history may no longer hold: those are
+// withdrawn, and a recalculation event tells consumers which weeks to
+// rebuild their own tables and tracking for.
+
+// ErrInvalidResult is returned for a result that cannot happen
+var ErrInvalidResult = errors.New("invalid result")
+
+// Recalculation is the data of a recalculation event. WeeksAffected runs
+// from the edited match's week to the latest week with a result.
+type Recalculation struct {
+	MatchID        int            `json:"match_id"`
+	Week           int            `json:"week"`
+	PreviousResult string         `json:"previous_result,omitempty"`
+	Result         string         `json:"result"`
+	WeeksAffected  []int          `json:"weeks_affected"`
+	Withdrawn      []Announcement `json:"withdrawn"`
+	StateVersion   int64          `json:"state_version"`
+	RecalculatedAt time.Time      `json:"recalculated_at"`
+}
+
+// retroactive reports whether writing a result to match m rewrites history:
+// it replaces a result, or later weeks already have results
+func retroactive(m Match, latestWeek int) bool {
+	return m.Played || m.Week < latestWeek
+}
+
+// recalculate follows a retroactive edit. It runs after the edit is
+// committed and the caches dropped, so failures are only printed.
+func (l *League) recalculate(m Match, previous *Match) {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		fmt.Println("Recalculation failed:", err)
+		return
+	}
+	withdrawn, err := l.withdrawClinches(state)
+	if err != nil {
+		fmt.Println("Withdrawing announcements failed:", err)
+	}
+
+	r := Recalculation{
+		MatchID:        m.ID,
+		Week:           m.Week,
+		Result:         fmt.Sprintf("%d-%d", m.HomeGoals, m.AwayGoals),
+		WeeksAffected:  []int{},
+		Withdrawn:      withdrawn,
+		StateVersion:   l.version.Load(),
+		RecalculatedAt: time.Now().UTC(),
+	}
+	if previous != nil && previous.Played {
+		r.PreviousResult = fmt.Sprintf("%d-%d", previous.HomeGoals, previous.AwayGoals)
+	}
+	for week := m.Week; week <= max(m.Week, state.latestWeek()); week++ {
+		r.WeeksAffected = append(r.WeeksAffected, week)
+	}
+	l.notifyWebhooks(EventTypeRecalculation, r)
+}
+
+// withdrawClinches deletes the champion and relegation announcements the
+// current table no longer backs
+func (l *League) withdrawClinches(state *seasonState) ([]Announcement, error) {
+	holds := make(map[string]bool)
+	for _, c := range state.clinches(l.relegationZone()) {
+		holds[c.kind+"/"+c.team] = true
+	}
+
+	announcements, err := l.Announcements()
+	if err != nil {
+		return nil, err
+	}
+	withdrawn := []Announcement{}
+	for _, a := range announcements {
+		if a.Kind != AnnouncementChampion && a.Kind != AnnouncementRelegated {
+			continue
+		}
+		if holds[a.Kind+"/"+a.TeamName] {
+			continue
+		}
+		if _, err := l.db.Exec("DELETE FROM announcements WHERE id = ?", a.ID); err != nil {
+			return withdrawn, err
+		}
+		withdrawn = append(withdrawn, a)
+	}
+	return withdrawn, nil
+}
@@ -0,0 +1,88 @@
+package insider_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+// recalculations decodes the queued recalculation events, oldest first
+func recalculations(t *testing.T, l *insider.League) []insider.Recalculation {
+	t.Helper()
+	var out []insider.Recalculation
+	for _, e := range insider.WebhookEvents(t, l, insider.EventTypeRecalculation) {
+		data, err := json.Marshal(e.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r insider.Recalculation
+		if err := json.Unmarshal(data, &r); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestRecalculationWithdrawsClinches(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 2)
+	cfg := *h.League.Config()
+	cfg.Zones = []insider.Zone{{Name: "relegation", From: 4, To: 4}}
+	cfg.Webhooks = []string{"http://127.0.0.1:1/hook"}
+	h.League.StoreConfig(&cfg)
+
+	// played in order nothing is retroactive; Alpha FC win the title on 18
+	// points, six clear of Bravo United, and Delta SC go down without one
+	insider.PlayByStrength(t, h.League, 1, 6)
+	if r := recalculations(t, h.League); len(r) != 0 {
+		t.Fatalf("recalculations for results in order: %+v", r)
+	}
+	if news := clinchNews(h); len(news) != 2 {
+		t.Fatalf("clinches %+v", news)
+	}
+
+	// Bravo United beating Alpha FC instead leaves them level on 15 points
+	var edited insider.Match
+	for _, m := range h.Matches() {
+		if (m.HomeTeam == "Alpha FC" && m.AwayTeam == "Bravo United") || (m.HomeTeam == "Bravo United" && m.AwayTeam == "Alpha FC") {
+			edited = m
+			break
+		}
+	}
+	home, away := 0, 2
+	if edited.HomeTeam == "Bravo United" {
+		home, away = 2, 0
+	}
+	if err := h.League.UpdateMatchResult(edited.ID, home, away, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	events := recalculations(t, h.League)
+	if len(events) != 1 {
+		t.Fatalf("%d recalculation events, want 1", len(events))
+	}
+	r := events[0]
+	var weeks []int
+	for week := edited.Week; week <= 6; week++ {
+		weeks = append(weeks, week)
+	}
+	if r.MatchID != edited.ID || r.Week != edited.Week || r.PreviousResult != fmt.Sprintf("%d-%d", edited.HomeGoals, edited.AwayGoals) || r.Result != fmt.Sprintf("%d-%d", home, away) || !slices.Equal(r.WeeksAffected, weeks) {
+		t.Errorf("recalculation %+v for match %+v", r, edited)
+	}
+	if r.StateVersion != h.League.Version() || r.RecalculatedAt.IsZero() {
+		t.Errorf("recalculation at version %d, league is at %d", r.StateVersion, h.League.Version())
+	}
+	if len(r.Withdrawn) != 1 || r.Withdrawn[0].Kind != insider.AnnouncementChampion || r.Withdrawn[0].TeamName != "Alpha FC" {
+		t.Errorf("withdrawn %+v, want the Alpha FC title", r.Withdrawn)
+	}
+
+	// the relegation still holds, the title is gone from the news
+	news := clinchNews(h)
+	if len(news) != 1 || news[0].Kind != insider.AnnouncementRelegated || news[0].TeamName != "Delta SC" {
+		t.Errorf("clinches after the edit %+v", news)
+	}
+}
@@ -0,0 +1,123 @@
+package insider
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"insider/matchengine"
+)
+
+// meetingsPerPair is how often every pair of teams meets in the fixture
+const meetingsPerPair = 2
+
+// PointsRules is what a result is worth
+type PointsRules struct {
+	Win  int `json:"win"`
+	Draw int `json:"draw"`
+	Loss int `json:"loss"`
+	// Multipliers lists the weeks whose results count more than once
+	Multipliers map[int]int `json:"multipliers,omitempty"`
+}
+
+// ScheduleRules describes the fixture format
+type ScheduleRules struct {
+	Format       string `json:"format"`
+	Teams        int    `json:"teams"`
+	Meetings     int    `json:"meetings"`
+	Weeks        int    `json:"weeks"`
+	TotalMatches int    `json:"total_matches"`
+}
+
+// SimulationRules is how results are produced
+type SimulationRules struct {
+	Live         bool      `json:"live"`
+	MatchMinutes int       `json:"match_minutes"`
+	TrackEvents  bool      `json:"track_events"`
+	Params       SimParams `json:"params"`
+	VARFrequency float64   `json:"var_frequency"`
+	Pressure     bool      `json:"pressure"`
+	Managers     bool      `json:"managers"`
+	Travel       Travel    `json:"travel"`
+}
+
+// TieRules is how two-legged ties are settled
+type TieRules struct {
+	AwayGoalsRule      bool    `json:"away_goals_rule"`
+	ExtraTimeMinutes   int     `json:"extra_time_minutes"`
+	ShootoutConversion float64 `json:"shootout_conversion"`
+}
+
+// Rules is the league's constitution: every rule the engine applies right
+// now, after the config file and any applied preset
+type Rules struct {
+	Sport       string          `json:"sport"`
+	Points      PointsRules     `json:"points"`
+	Tiebreakers []string        `json:"tiebreakers"`
+	SharedRanks bool            `json:"shared_ranks"`
+	Zones       []Zone          `json:"zones"`
+	Handicaps   []Handicap      `json:"handicaps"`
+	Schedule    ScheduleRules   `json:"schedule"`
+	Simulation  SimulationRules `json:"simulation"`
+	Ties        TieRules        `json:"ties"`
+}
+
+// Rules resolves the rule set from the running config and the league
+func (l *League) Rules() (*Rules, error) {
+	handicaps, err := l.Handicaps()
+	if err != nil {
+		return nil, err
+	}
+	cfg := l.config()
+	teams := len(l.Teams())
+
+	zones := cfg.Zones
+	if zones == nil {
+		zones = []Zone{}
+	}
+	return &Rules{
+		Sport: cfg.Sport.Name,
+		Points: PointsRules{
+			Win:  cfg.Sport.WinPoints,
+			Draw: cfg.Sport.DrawPoints,
+			Loss: cfg.Sport.LossPoints,
+
+			Multipliers: cfg.Sport.PointMultipliers,
+		},
+		Tiebreakers: append(append([]string(nil), cfg.Sport.tiebreakerOrder()...), "team_name"),
+		SharedRanks: cfg.SharedRanks,
+		Zones:       zones,
+		Handicaps:   handicaps,
+		Schedule: ScheduleRules{
+			Format:       scheduleFormat,
+			Teams:        teams,
+			Meetings:     meetingsPerPair,
+			Weeks:        l.weeks,
+			TotalMatches: teams * (teams - 1) / 2 * meetingsPerPair,
+		},
+		Simulation: SimulationRules{
+			Live:         cfg.Live,
+			MatchMinutes: cfg.Sport.MatchMinutes,
+			TrackEvents:  cfg.Sport.TrackEvents,
+			Params:       cfg.Simulation,
+			VARFrequency: cfg.VARFrequency,
+			Pressure:     cfg.Pressure,
+			Managers:     cfg.Managers,
+			Travel:       cfg.Travel,
+		},
+		Ties: TieRules{
+			AwayGoalsRule:      cfg.AwayGoalsRule,
+			ExtraTimeMinutes:   extraTimeMinutes,
+			ShootoutConversion: matchengine.DefaultConversion,
+		},
+	}, nil
+}
+
+// GET /rules
+func (l *League) handleRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := l.Rules()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(rules)
+}
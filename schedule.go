@@ -0,0 +1,226 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrMatchPlayed is returned when a played match would be moved
+	ErrMatchPlayed = errors.New("match has already been played")
+	// ErrScheduleConflict is returned when a team would play twice in a week
+	ErrScheduleConflict = errors.New("schedule conflict")
+	// ErrInvalidSchedule is returned for a target week or date that cannot exist
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	// ErrMatchNotPending is returned when a postponed or live match would be
+	// simulated
+	ErrMatchNotPending = errors.New("match is not pending")
+)
+
+// PostponeMatch keeps the match in its week but takes it out of simulation
+// until it is rescheduled. It simply stays unplayed for the standings.
+func (l *League) PostponeMatch(id int) error {
+	var played bool
+	if err := l.db.QueryRow("SELECT played FROM matches WHERE id = ?", id).Scan(&played); err != nil {
+		return err
+	}
+	if played {
+		return ErrMatchPlayed
+	}
+
+	if _, err := l.db.Exec("UPDATE matches SET postponed = TRUE WHERE id = ?", id); err != nil {
+		return err
+	}
+	l.touch()
+	return nil
+}
+
+// RescheduleMatch moves an unplayed match to a new week and optional kickoff.
+// Neither team may already have another match that week.
+func (l *League) RescheduleMatch(id, week int, kickoff string) error {
+	if week < 1 || week > l.weeks {
+		return fmt.Errorf("%w: week must be between 1 and %d", ErrInvalidSchedule, l.weeks)
+	}
+	if kickoff != "" {
+		parsed, err := parseKickoff(kickoff)
+		if err != nil {
+			return err
+		}
+		kickoff = parsed.Format(time.RFC3339)
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	m, err := scanMatch(tx.QueryRow(matchSelect+" WHERE m.id = ?", id))
+	if err != nil {
+		return err
+	}
+	if m.Played {
+		return ErrMatchPlayed
+	}
+
+	var clashes int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM matches
+		WHERE week = ? AND id != ? AND postponed = FALSE
+		AND (home_team_id IN (?, ?) OR away_team_id IN (?, ?))`,
+		week, id, m.HomeTeamID, m.AwayTeamID, m.HomeTeamID, m.AwayTeamID,
+	).Scan(&clashes)
+	if err != nil {
+		return err
+	}
+	if clashes > 0 {
+		return fmt.Errorf("%w: %s or %s already plays in week %d", ErrScheduleConflict, m.HomeTeam, m.AwayTeam, week)
+	}
+
+	_, err = tx.Exec(
+		"UPDATE matches SET week = ?, kickoff = NULLIF(?, ''), postponed = FALSE WHERE id = ?",
+		week, kickoff, id,
+	)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.touch()
+	return nil
+}
+
+// parseKickoff accepts a full RFC 3339 timestamp or a plain date
+func parseKickoff(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: date %q, expected YYYY-MM-DD or RFC 3339", ErrInvalidSchedule, value)
+	}
+	return t, nil
+}
+
+// writeScheduleError maps scheduling errors to HTTP status codes
+func writeScheduleError(w http.ResponseWriter, err error) {
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "Match not found", http.StatusNotFound)
+	case errors.Is(err, ErrMatchPlayed), errors.Is(err, ErrScheduleConflict), errors.Is(err, ErrMatchNotPending), errors.Is(err, ErrLiveMode):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrInvalidSchedule):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (l *League) handlePostpone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	if err := l.PostponeMatch(id); err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Match %d postponed", id)})
+}
+
+func (l *League) handleReschedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	var target struct {
+		Week int    `json:"week"`
+		Date string `json:"date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := l.RescheduleMatch(id, target.Week, target.Date); err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Match %d rescheduled to week %d", id, target.Week)})
+}
+
+// SimulateMatch plays a single pending match of its week, for matchdays
+// spread over several days. A seed makes the result repeatable; without
+// one the league's random stream is used like for a whole week.
+func (l *League) SimulateMatch(id int, seed *int64) (*Match, error) {
+	m, err := scanMatch(l.db.QueryRow(matchSelect+" WHERE m.id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case m.Played:
+		return nil, ErrMatchPlayed
+	case m.Postponed:
+		return nil, fmt.Errorf("%w: it is postponed, reschedule it first", ErrMatchNotPending)
+	case m.Live:
+		return nil, fmt.Errorf("%w: it is being played live", ErrMatchNotPending)
+	}
+
+	rng, flavor := l.seededStreams(seed)
+	only := func(candidate Match) bool {
+		return candidate.ID == id
+	}
+	if err := l.simulateWeek(m.Week, only, rng, flavor); err != nil {
+		return nil, err
+	}
+	m, err = scanMatch(l.db.QueryRow(matchSelect+" WHERE m.id = ?", id))
+	return &m, err
+}
+
+// POST /matches/{id}/simulate plays one match, ?seed=n to repeat a result
+func (l *League) handleSimulateMatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m, err := l.SimulateMatch(id, seed)
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(m)
+}
@@ -0,0 +1,119 @@
+package insider_test
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestSimulateSingleMatch(t *testing.T) {
+	a, b := leaguetest.New(t, insider.SnapshotTeams, 1), leaguetest.New(t, insider.SnapshotTeams, 2)
+	matches := a.Matches()
+	var week1 []insider.Match
+	for _, m := range matches {
+		if m.Week == 1 {
+			week1 = append(week1, m)
+		}
+	}
+	if len(week1) < 2 {
+		t.Fatalf("week 1 has %d matches", len(week1))
+	}
+	first, second := week1[0], week1[1]
+
+	// the same seed plays the same match the same way in any league
+	var fromA, fromB insider.Match
+	path := "/matches/" + strconv.Itoa(first.ID) + "/simulate?seed=42"
+	if status := a.Do(http.MethodPost, path, nil, false, &fromA); status != http.StatusOK {
+		t.Fatalf("simulate: status %d", status)
+	}
+	if status := b.Do(http.MethodPost, path, nil, false, &fromB); status != http.StatusOK {
+		t.Fatalf("simulate: status %d", status)
+	}
+	if !fromA.Played || fromA.HomeGoals != fromB.HomeGoals || fromA.AwayGoals != fromB.AwayGoals {
+		t.Errorf("seeded results differ: %d-%d and %d-%d", fromA.HomeGoals, fromA.AwayGoals, fromB.HomeGoals, fromB.AwayGoals)
+	}
+	for _, m := range a.Matches() {
+		if m.ID != first.ID && m.Played {
+			t.Errorf("match %d played too", m.ID)
+		}
+	}
+
+	if status := a.Do(http.MethodPost, path, nil, false, nil); status != http.StatusConflict {
+		t.Errorf("simulate a played match: status %d, want 409", status)
+	}
+	postponed := "/matches/" + strconv.Itoa(second.ID)
+	if status := a.Do(http.MethodPost, postponed+"/postpone", nil, false, nil); status != http.StatusOK {
+		t.Fatalf("postpone: status %d", status)
+	}
+	if status := a.Do(http.MethodPost, postponed+"/simulate", nil, false, nil); status != http.StatusConflict {
+		t.Errorf("simulate a postponed match: status %d, want 409", status)
+	}
+	if status := a.Do(http.MethodPost, "/matches/9999/simulate", nil, false, nil); status != http.StatusNotFound {
+		t.Errorf("simulate an unknown match: status %d, want 404", status)
+	}
+	if status := a.Do(http.MethodPost, "/matches/"+strconv.Itoa(second.ID)+"/simulate?seed=x", nil, false, nil); status != http.StatusBadRequest {
+		t.Errorf("bad seed: status %d, want 400", status)
+	}
+}
+
+func TestPostponeAndReschedule(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 2)
+	version := func() int64 {
+		var p insider.MonteCarloPrediction
+		h.Get("/predict?mode=montecarlo&runs=10", &p)
+		return p.StateVersion
+	}
+	byWeek := func(week int) []insider.Match {
+		var matches []insider.Match
+		for _, m := range h.Matches() {
+			if m.Week == week {
+				matches = append(matches, m)
+			}
+		}
+		return matches
+	}
+	moved := byWeek(1)[0]
+	path := "/matches/" + strconv.Itoa(moved.ID)
+
+	// every team plays every week, so the match only fits once week 2 is
+	// postponed, and each move makes the cached prediction stale
+	last := version()
+	for _, m := range append([]insider.Match{moved}, byWeek(2)...) {
+		h.Post("/matches/"+strconv.Itoa(m.ID)+"/postpone", nil, nil)
+		if v := version(); v <= last {
+			t.Errorf("postponing match %d left the prediction at version %d", m.ID, v)
+		} else {
+			last = v
+		}
+	}
+	if status := h.Do(http.MethodPost, path+"/reschedule", map[string]any{"week": 3}, false, nil); status != http.StatusConflict {
+		t.Errorf("reschedule into a full week: status %d, want 409", status)
+	}
+	for _, body := range []map[string]any{{"week": 0}, {"week": 99}, {"week": 2, "date": "next friday"}} {
+		if status := h.Do(http.MethodPost, path+"/reschedule", body, false, nil); status != http.StatusBadRequest {
+			t.Errorf("reschedule with %v: status %d, want 400", body, status)
+		}
+	}
+	if status := h.Do(http.MethodPost, "/matches/9999/reschedule", map[string]any{"week": 2}, false, nil); status != http.StatusNotFound {
+		t.Errorf("reschedule an unknown match: status %d, want 404", status)
+	}
+
+	h.Post(path+"/reschedule", map[string]any{"week": 2, "date": "2026-03-14"}, nil)
+	if v := version(); v <= last {
+		t.Errorf("rescheduling left the prediction at version %d", v)
+	}
+	for _, m := range h.Matches() {
+		if m.ID == moved.ID && (m.Week != 2 || m.Postponed || !strings.HasPrefix(m.Kickoff, "2026-03-14")) {
+			t.Errorf("rescheduled match %+v, want week 2 on 2026-03-14", m)
+		}
+	}
+
+	h.SimulateWeek(2)
+	if status := h.Do(http.MethodPost, path+"/reschedule", map[string]any{"week": 4}, false, nil); status != http.StatusConflict {
+		t.Errorf("reschedule a played match: status %d, want 409", status)
+	}
+}
@@ -0,0 +1,127 @@
+package insider
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrFixtureMeetings is reported for a pair of teams that does not meet
+	// the expected number of times, or a match that is not between two teams
+	// of the league
+	ErrFixtureMeetings = errors.New("wrong meetings")
+	// ErrFixtureClash is reported for a team playing twice in one week
+	ErrFixtureClash = errors.New("week clash")
+	// ErrFixtureBalance is reported for a team with more than one home match
+	// more than away matches, or the other way round
+	ErrFixtureBalance = errors.New("home and away unbalanced")
+)
+
+// FixtureWeeks is the length of a double round-robin for n teams. With an
+// odd number of teams one of them sits each week out, so every team gets a
+// week off per half.
+func FixtureWeeks(n int) int {
+	if n%2 == 1 {
+		n++
+	}
+	return 2 * (n - 1)
+}
+
+// scheduleFixture pairs every team with every other one twice, once at
+// home, over fixtureWeeks weeks with nobody playing twice in a week. The
+// first half is a round-robin by the circle method: the first team stays
+// put while the others rotate one place a week, and the teams opposite each
+// other meet. The second half replays it with home and away swapped.
+func scheduleFixture(teamIDs []int) []Match {
+	// a bye makes the count even, whoever draws it has the week off
+	const bye = -1
+	circle := append([]int(nil), teamIDs...)
+	if len(circle)%2 == 1 {
+		circle = append(circle, bye)
+	}
+	n := len(circle)
+	rounds := n - 1
+
+	var matches []Match
+	for round := 0; round < rounds; round++ {
+		for i := 0; i < n/2; i++ {
+			home, away := circle[i], circle[n-1-i]
+			// the fixed team alternates, and so does every other pair, so
+			// nobody plays a long run of home or away matches
+			if (i == 0 && round%2 == 1) || (i > 0 && i%2 == 1) {
+				home, away = away, home
+			}
+			if home == bye || away == bye {
+				continue
+			}
+			matches = append(matches, Match{HomeTeamID: home, AwayTeamID: away, Week: round + 1})
+		}
+		// rotate everyone but the first team one place
+		last := circle[n-1]
+		copy(circle[2:], circle[1:n-1])
+		circle[1] = last
+	}
+
+	firstHalf := len(matches)
+	for _, m := range matches[:firstHalf] {
+		matches = append(matches, Match{HomeTeamID: m.AwayTeamID, AwayTeamID: m.HomeTeamID, Week: m.Week + rounds})
+	}
+	return matches
+}
+
+// ValidateFixture checks a fixture for the teams against the scheduling
+// rules: every pair meets exactly meetings times, nobody plays twice in a
+// week and every team's home and away matches differ by at most one. All
+// problems are returned joined, each wrapping one of the ErrFixture errors.
+func ValidateFixture(matches []Match, teamIDs []int, meetings int) error {
+	type pair struct{ a, b int }
+	inLeague := make(map[int]bool, len(teamIDs))
+	for _, id := range teamIDs {
+		inLeague[id] = true
+	}
+
+	var problems []error
+	met := make(map[pair]int)
+	home := make(map[int]int)
+	away := make(map[int]int)
+	weekOf := make(map[pair]int)
+	for _, m := range matches {
+		if !inLeague[m.HomeTeamID] || !inLeague[m.AwayTeamID] || m.HomeTeamID == m.AwayTeamID {
+			problems = append(problems, fmt.Errorf("%w: match %d-%d in week %d is not between two teams of the league",
+				ErrFixtureMeetings, m.HomeTeamID, m.AwayTeamID, m.Week))
+			continue
+		}
+		p := pair{m.HomeTeamID, m.AwayTeamID}
+		if p.a > p.b {
+			p.a, p.b = p.b, p.a
+		}
+		met[p]++
+		home[m.HomeTeamID]++
+		away[m.AwayTeamID]++
+
+		for _, team := range []int{m.HomeTeamID, m.AwayTeamID} {
+			weekOf[pair{team, m.Week}]++
+			if weekOf[pair{team, m.Week}] == 2 {
+				problems = append(problems, fmt.Errorf("%w: team %d plays more than once in week %d", ErrFixtureClash, team, m.Week))
+			}
+		}
+	}
+
+	for i, a := range teamIDs {
+		for _, b := range teamIDs[i+1:] {
+			p := pair{a, b}
+			if p.a > p.b {
+				p.a, p.b = p.b, p.a
+			}
+			if met[p] != meetings {
+				problems = append(problems, fmt.Errorf("%w: teams %d and %d meet %d times, expected %d",
+					ErrFixtureMeetings, p.a, p.b, met[p], meetings))
+			}
+		}
+		if diff := home[a] - away[a]; diff > 1 || diff < -1 {
+			problems = append(problems, fmt.Errorf("%w: team %d has %d home and %d away matches",
+				ErrFixtureBalance, a, home[a], away[a]))
+		}
+	}
+	return errors.Join(problems...)
+}
@@ -0,0 +1,117 @@
+package insider
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// randomTeamIDs gives n distinct ids in random order, not starting at 1, so
+// nothing relies on ids being positions
+func randomTeamIDs(rng *rand.Rand, n int) []int {
+	ids := make([]int, n)
+	next := 1 + rng.Intn(100)
+	for i := range ids {
+		ids[i] = next
+		next += 1 + rng.Intn(5)
+	}
+	rng.Shuffle(n, func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+	return ids
+}
+
+// fixtureProblems splits a ValidateFixture error into its problems
+func fixtureProblems(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// checkFixture fails the test for every problem wrapping one of kinds
+func checkFixture(t *testing.T, matches []Match, teamIDs []int, kinds ...error) {
+	t.Helper()
+	for _, problem := range fixtureProblems(ValidateFixture(matches, teamIDs, 2)) {
+		for _, kind := range kinds {
+			if errors.Is(problem, kind) {
+				t.Errorf("%d teams: %v", len(teamIDs), problem)
+			}
+		}
+	}
+}
+
+func TestValidateFixture(t *testing.T) {
+	teams := []int{3, 7, 9}
+	valid := []Match{
+		{HomeTeamID: 3, AwayTeamID: 7, Week: 1},
+		{HomeTeamID: 9, AwayTeamID: 3, Week: 2},
+		{HomeTeamID: 7, AwayTeamID: 9, Week: 3},
+		{HomeTeamID: 7, AwayTeamID: 3, Week: 4},
+		{HomeTeamID: 3, AwayTeamID: 9, Week: 5},
+		{HomeTeamID: 9, AwayTeamID: 7, Week: 6},
+	}
+	if err := ValidateFixture(valid, teams, 2); err != nil {
+		t.Fatalf("valid fixture rejected: %v", err)
+	}
+
+	edit := func(change func([]Match) []Match) []Match {
+		return change(append([]Match(nil), valid...))
+	}
+	tests := []struct {
+		name    string
+		matches []Match
+		want    error
+	}{
+		{"missing match", valid[1:], ErrFixtureMeetings},
+		{"extra meeting", append(append([]Match(nil), valid...), Match{HomeTeamID: 3, AwayTeamID: 7, Week: 7}), ErrFixtureMeetings},
+		{"unknown team", edit(func(m []Match) []Match { m[0].AwayTeamID = 4; return m }), ErrFixtureMeetings},
+		{"team plays itself", edit(func(m []Match) []Match { m[0].AwayTeamID = 3; return m }), ErrFixtureMeetings},
+		{"two matches in a week", edit(func(m []Match) []Match { m[1].Week = 1; return m }), ErrFixtureClash},
+		{"all at home", edit(func(m []Match) []Match {
+			m[1].HomeTeamID, m[1].AwayTeamID = 3, 9
+			m[3].HomeTeamID, m[3].AwayTeamID = 3, 7
+			return m
+		}), ErrFixtureBalance},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFixture(tt.matches, teams, 2)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestScheduleFixtureProperties checks the pairing rules on random leagues
+func TestScheduleFixtureProperties(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		n := 2 + rng.Intn(19)
+		ids := randomTeamIDs(rng, n)
+		checkFixture(t, scheduleFixture(ids), ids, ErrFixtureMeetings, ErrFixtureBalance)
+	}
+}
+
+func TestScheduleFixtureWeeks(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for n := 2; n <= 20; n++ {
+		ids := randomTeamIDs(rng, n)
+		checkFixture(t, scheduleFixture(ids), ids, ErrFixtureClash)
+	}
+}
+
+func FuzzScheduleFixture(f *testing.F) {
+	for _, n := range []int{2, 3, 4, 5, 10, 20} {
+		f.Add(n, int64(n))
+	}
+	f.Fuzz(func(t *testing.T, n int, seed int64) {
+		if n < 2 || n > 64 {
+			t.Skip()
+		}
+		ids := randomTeamIDs(rand.New(rand.NewSource(seed)), n)
+		checkFixture(t, scheduleFixture(ids), ids, ErrFixtureMeetings, ErrFixtureClash, ErrFixtureBalance)
+	})
+}
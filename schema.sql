@@ -1,14 +1,358 @@
 CREATE TABLE IF NOT EXISTS teams (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
-    name TEXT,
-    strength INTEGER
+    name TEXT UNIQUE,
+    strength INTEGER,
+    metadata TEXT,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    popularity REAL NOT NULL DEFAULT 50
 );
 
 CREATE TABLE IF NOT EXISTS matches (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
-    home_team TEXT,
-    away_team TEXT,
+    home_team_id INTEGER NOT NULL,
+    away_team_id INTEGER NOT NULL,
+    home_goals INTEGER DEFAULT 0,
+    away_goals INTEGER DEFAULT 0,
+    played BOOLEAN DEFAULT FALSE,
+    week INTEGER,
+    commentary TEXT DEFAULT '',
+    postponed BOOLEAN DEFAULT FALSE,
+    kickoff TEXT,
+    live BOOLEAN DEFAULT FALSE,
+    minute INTEGER DEFAULT 0,
+    administrative TEXT,
+    FOREIGN KEY (home_team_id) REFERENCES teams(id) ON DELETE RESTRICT,
+    FOREIGN KEY (away_team_id) REFERENCES teams(id) ON DELETE RESTRICT
+);
+
+CREATE TABLE IF NOT EXISTS match_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    match_id INTEGER NOT NULL,
+    minute INTEGER,
+    team_id INTEGER NOT NULL,
+    type TEXT,
+    detail TEXT,
+    player_id INTEGER,
+    FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE,
+    FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE RESTRICT,
+    FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS fixture_archives (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reason TEXT,
+    archived_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS archived_matches (
+    archive_id INTEGER,
+    match_id INTEGER,
+    home_team_id INTEGER,
+    away_team_id INTEGER,
+    home_goals INTEGER,
+    away_goals INTEGER,
+    played BOOLEAN,
+    week INTEGER,
+    postponed BOOLEAN,
+    kickoff TEXT,
+    commentary TEXT,
+    FOREIGN KEY (archive_id) REFERENCES fixture_archives(id) ON DELETE CASCADE,
+    FOREIGN KEY (home_team_id) REFERENCES teams(id) ON DELETE RESTRICT,
+    FOREIGN KEY (away_team_id) REFERENCES teams(id) ON DELETE RESTRICT
+);
+
+CREATE TABLE IF NOT EXISTS archived_match_events (
+    archive_id INTEGER,
+    match_id INTEGER,
+    minute INTEGER,
+    team_id INTEGER,
+    type TEXT,
+    detail TEXT,
+    player_id INTEGER,
+    FOREIGN KEY (archive_id) REFERENCES fixture_archives(id) ON DELETE CASCADE,
+    FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE RESTRICT,
+    FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS team_aliases (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    team_id INTEGER,
+    name TEXT,
+    renamed_at TIMESTAMP,
+    FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE,
+    token_hash TEXT UNIQUE,
+    favorite_team_id INTEGER,
+    created_at TIMESTAMP,
+    FOREIGN KEY (favorite_team_id) REFERENCES teams(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS handicaps (
+    team_id INTEGER PRIMARY KEY,
+    points INTEGER NOT NULL,
+    FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS announcements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    team_id INTEGER,
+    message TEXT,
+    week INTEGER,
+    created_at TIMESTAMP,
+    UNIQUE (kind, team_id),
+    FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS match_scripts (
+    match_id INTEGER PRIMARY KEY,
     home_goals INTEGER,
     away_goals INTEGER,
-    played BOOLEAN
-);
\ No newline at end of file
+    result TEXT,
+    FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS multiverses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    universes INTEGER NOT NULL,
+    seed INTEGER NOT NULL,
+    state_version INTEGER NOT NULL,
+    played_matches INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS multiverse_universes (
+    multiverse_id INTEGER NOT NULL,
+    universe INTEGER NOT NULL,
+    seed INTEGER NOT NULL,
+    champion TEXT NOT NULL,
+    standings TEXT NOT NULL,
+    results TEXT NOT NULL,
+    PRIMARY KEY (multiverse_id, universe),
+    FOREIGN KEY (multiverse_id) REFERENCES multiverses(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS model_presets (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    sport TEXT NOT NULL,
+    params TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS players (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    team_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    position TEXT NOT NULL DEFAULT '',
+    rating INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (team_id, name),
+    FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS managers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    team_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    tactic INTEGER NOT NULL,
+    appointed_week INTEGER NOT NULL DEFAULT 0,
+    sacked_week INTEGER,
+    FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_predictions (
+    user_id INTEGER NOT NULL,
+    match_id INTEGER NOT NULL,
+    home_goals INTEGER NOT NULL,
+    away_goals INTEGER NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, match_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS administrative_decisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    match_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    awarded_to_team_id INTEGER,
+    reason TEXT NOT NULL,
+    previous_result TEXT,
+    result TEXT,
+    decided_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE,
+    FOREIGN KEY (awarded_to_team_id) REFERENCES teams(id) ON DELETE RESTRICT
+);
+
+CREATE TABLE IF NOT EXISTS storylines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    teams TEXT NOT NULL,
+    match_id INTEGER,
+    headline TEXT NOT NULL,
+    started_week INTEGER NOT NULL,
+    ended_week INTEGER,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS calendar_tokens (
+    token_hash TEXT PRIMARY KEY,
+    team_id INTEGER,
+    created_at TIMESTAMP,
+    FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    scopes TEXT NOT NULL,
+    rate_limit INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS match_probabilities (
+    match_id INTEGER PRIMARY KEY,
+    home_win REAL NOT NULL,
+    draw REAL NOT NULL,
+    away_win REAL NOT NULL,
+    home_expected_points REAL,
+    away_expected_points REAL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS strength_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    team_id INTEGER NOT NULL,
+    old_strength INTEGER NOT NULL,
+    new_strength INTEGER NOT NULL,
+    author TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    changed_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS season_certificates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    results_sha256 TEXT NOT NULL,
+    certificate TEXT NOT NULL,
+    public_key TEXT NOT NULL,
+    signature TEXT NOT NULL,
+    issued_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS rating_updates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    team_id INTEGER NOT NULL,
+    week INTEGER NOT NULL,
+    matches INTEGER NOT NULL,
+    rating_before REAL NOT NULL,
+    rating_after REAL NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_type TEXT NOT NULL,
+    target TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS seasons (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    status TEXT NOT NULL,
+    archive_id INTEGER,
+    created_at TIMESTAMP NOT NULL,
+    started_at TIMESTAMP,
+    ended_at TIMESTAMP,
+    FOREIGN KEY (archive_id) REFERENCES fixture_archives(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS leagues (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS cup_ties (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    round INTEGER NOT NULL,
+    slot INTEGER NOT NULL,
+    home_team_id INTEGER,
+    away_team_id INTEGER,
+    played BOOLEAN NOT NULL DEFAULT FALSE,
+    home_goals INTEGER NOT NULL DEFAULT 0,
+    away_goals INTEGER NOT NULL DEFAULT 0,
+    extra_home_goals INTEGER,
+    extra_away_goals INTEGER,
+    home_penalties INTEGER,
+    away_penalties INTEGER,
+    penalty_kicks TEXT,
+    events TEXT,
+    decided_by TEXT NOT NULL DEFAULT '',
+    winner_team_id INTEGER,
+    UNIQUE (round, slot),
+    FOREIGN KEY (home_team_id) REFERENCES teams(id),
+    FOREIGN KEY (away_team_id) REFERENCES teams(id),
+    FOREIGN KEY (winner_team_id) REFERENCES teams(id)
+);
+
+CREATE TABLE IF NOT EXISTS relegation_playoffs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    season_id INTEGER NOT NULL UNIQUE,
+    lower_league INTEGER NOT NULL,
+    upper_team TEXT NOT NULL,
+    upper_position INTEGER NOT NULL,
+    lower_team TEXT NOT NULL,
+    lower_position INTEGER NOT NULL,
+    home_goals INTEGER NOT NULL,
+    away_goals INTEGER NOT NULL,
+    extra_home_goals INTEGER,
+    extra_away_goals INTEGER,
+    home_penalties INTEGER,
+    away_penalties INTEGER,
+    penalty_kicks TEXT,
+    decided_by TEXT NOT NULL,
+    winner TEXT NOT NULL,
+    played_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (season_id) REFERENCES seasons(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_matches_week_played ON matches(week, played);
+CREATE INDEX IF NOT EXISTS idx_matches_played ON matches(played);
+CREATE INDEX IF NOT EXISTS idx_matches_home_team ON matches(home_team_id);
+CREATE INDEX IF NOT EXISTS idx_matches_away_team ON matches(away_team_id);
+CREATE INDEX IF NOT EXISTS idx_matches_kickoff_date ON matches(substr(kickoff, 1, 10));
+CREATE INDEX IF NOT EXISTS idx_match_events_match ON match_events(match_id);
+CREATE INDEX IF NOT EXISTS idx_match_events_team ON match_events(team_id);
+CREATE INDEX IF NOT EXISTS idx_match_events_player ON match_events(player_id);
+CREATE INDEX IF NOT EXISTS idx_managers_team ON managers(team_id);
+CREATE INDEX IF NOT EXISTS idx_user_predictions_match ON user_predictions(match_id);
+CREATE INDEX IF NOT EXISTS idx_administrative_decisions_match ON administrative_decisions(match_id);
+CREATE INDEX IF NOT EXISTS idx_team_aliases_team ON team_aliases(team_id);
+CREATE INDEX IF NOT EXISTS idx_team_aliases_name ON team_aliases(name);
+CREATE INDEX IF NOT EXISTS idx_archived_matches_home ON archived_matches(archive_id, home_team_id, home_goals, away_goals, played);
+CREATE INDEX IF NOT EXISTS idx_archived_matches_away ON archived_matches(archive_id, away_team_id, away_goals, home_goals, played);
+CREATE INDEX IF NOT EXISTS idx_outbox_status_next ON outbox(status, next_attempt_at);
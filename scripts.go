@@ -0,0 +1,257 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	ResultHomeWin = "home_win"
+	ResultDraw    = "draw"
+	ResultAwayWin = "away_win"
+)
+
+// ErrInvalidScript is returned for a script that names neither a score nor
+// a result, or an impossible one
+var ErrInvalidScript = errors.New("invalid script")
+
+// MatchScript fixes the outcome of a match before it is simulated: either an
+// exact score or only the result, in which case the simulated score is bent
+// to fit.
+type MatchScript struct {
+	MatchID   int    `json:"match_id"`
+	HomeGoals *int   `json:"home_goals,omitempty"`
+	AwayGoals *int   `json:"away_goals,omitempty"`
+	Result    string `json:"result,omitempty"`
+}
+
+// validate checks the script on its own. With overtime a level game goes on
+// until someone scores, so a draw cannot be scripted.
+func (s MatchScript) validate(overtime bool) error {
+	exact := s.HomeGoals != nil || s.AwayGoals != nil
+	switch {
+	case exact && s.Result != "":
+		return fmt.Errorf("%w: give a score or a result, not both", ErrInvalidScript)
+	case exact:
+		if s.HomeGoals == nil || s.AwayGoals == nil {
+			return fmt.Errorf("%w: both home_goals and away_goals are needed", ErrInvalidScript)
+		}
+		if *s.HomeGoals < 0 || *s.AwayGoals < 0 {
+			return fmt.Errorf("%w: goals cannot be negative", ErrInvalidScript)
+		}
+		if overtime && *s.HomeGoals == *s.AwayGoals {
+			return fmt.Errorf("%w: matches go to overtime, a level score cannot be scripted", ErrInvalidScript)
+		}
+	case s.Result == ResultDraw && overtime:
+		return fmt.Errorf("%w: matches go to overtime, a draw cannot be scripted", ErrInvalidScript)
+	case s.Result == ResultHomeWin, s.Result == ResultDraw, s.Result == ResultAwayWin:
+	case s.Result == "":
+		return fmt.Errorf("%w: give a score or a result", ErrInvalidScript)
+	default:
+		return fmt.Errorf("%w: result must be %s, %s or %s", ErrInvalidScript, ResultHomeWin, ResultDraw, ResultAwayWin)
+	}
+	return nil
+}
+
+// apply turns a simulated score into one that follows the script; the zero
+// script leaves it alone. A draw scripted before overtime was switched on
+// still needs a winner, so the side that won the simulated match scores once
+// more.
+func (s MatchScript) apply(homeGoals, awayGoals int, overtime bool) (int, int) {
+	home, away := s.bend(homeGoals, awayGoals)
+	if overtime && home == away {
+		if homeGoals > awayGoals {
+			home++
+		} else {
+			away++
+		}
+	}
+	return home, away
+}
+
+// bend keeps the simulated goals of a result script where it can: a reversed
+// score is swapped, a level one gets a goal for the winner and a draw takes
+// the lower count.
+func (s MatchScript) bend(homeGoals, awayGoals int) (int, int) {
+	if s.HomeGoals != nil {
+		return *s.HomeGoals, *s.AwayGoals
+	}
+	switch s.Result {
+	case ResultHomeWin:
+		if homeGoals < awayGoals {
+			return awayGoals, homeGoals
+		}
+		if homeGoals == awayGoals {
+			return homeGoals + 1, awayGoals
+		}
+	case ResultAwayWin:
+		if homeGoals > awayGoals {
+			return awayGoals, homeGoals
+		}
+		if homeGoals == awayGoals {
+			return homeGoals, awayGoals + 1
+		}
+	case ResultDraw:
+		low := min(homeGoals, awayGoals)
+		return low, low
+	}
+	return homeGoals, awayGoals
+}
+
+// same tells whether two scripts fix the same outcome
+func (s MatchScript) same(o MatchScript) bool {
+	if (s.HomeGoals == nil) != (o.HomeGoals == nil) || s.Result != o.Result {
+		return false
+	}
+	return s.HomeGoals == nil || *s.HomeGoals == *o.HomeGoals && *s.AwayGoals == *o.AwayGoals
+}
+
+func (l *League) createScriptTable() error {
+	createScripts := `
+	CREATE TABLE IF NOT EXISTS match_scripts (
+		match_id INTEGER PRIMARY KEY REFERENCES matches(id) ON DELETE CASCADE,
+		home_goals INTEGER,
+		away_goals INTEGER,
+		result TEXT
+	);`
+
+	if _, err := l.db.Exec(createScripts); err != nil {
+		return fmt.Errorf("error creating match_scripts table: %v", err)
+	}
+	return nil
+}
+
+// ScriptMatch sets or replaces the script of an unplayed match
+func (l *League) ScriptMatch(script MatchScript) error {
+	if err := script.validate(l.config().Simulation.Overtime); err != nil {
+		return err
+	}
+
+	var played bool
+	if err := l.db.QueryRow("SELECT played FROM matches WHERE id = ?", script.MatchID).Scan(&played); err != nil {
+		return err
+	}
+	if played {
+		return ErrMatchPlayed
+	}
+
+	_, err := l.db.Exec(
+		"INSERT OR REPLACE INTO match_scripts (match_id, home_goals, away_goals, result) VALUES (?, ?, ?, NULLIF(?, ''))",
+		script.MatchID, script.HomeGoals, script.AwayGoals, script.Result,
+	)
+	if err != nil {
+		return err
+	}
+	// the predictions follow scripts
+	l.touch()
+	return nil
+}
+
+// UnscriptMatch lets the simulator decide the match again
+func (l *League) UnscriptMatch(id int) error {
+	if _, err := l.db.Exec("DELETE FROM match_scripts WHERE match_id = ?", id); err != nil {
+		return err
+	}
+	l.touch()
+	return nil
+}
+
+// weekScripts loads the scripts of a week's matches by match id
+func weekScripts(tx *sql.Tx, week int) (map[int]MatchScript, error) {
+	rows, err := tx.Query(`
+		SELECT s.match_id, s.home_goals, s.away_goals, COALESCE(s.result, '')
+		FROM match_scripts s JOIN matches m ON m.id = s.match_id
+		WHERE m.week = ?`, week)
+	if err != nil {
+		return nil, err
+	}
+	return scanScripts(rows)
+}
+
+// unplayedScripts loads the scripts the simulations have to follow, by match
+// id. A played match keeps its row but its script has done its job.
+func (l *League) unplayedScripts() (map[int]MatchScript, error) {
+	rows, err := l.db.Query(`
+		SELECT s.match_id, s.home_goals, s.away_goals, COALESCE(s.result, '')
+		FROM match_scripts s JOIN matches m ON m.id = s.match_id
+		WHERE m.played = FALSE`)
+	if err != nil {
+		return nil, err
+	}
+	return scanScripts(rows)
+}
+
+func scanScripts(rows *sql.Rows) (map[int]MatchScript, error) {
+	defer rows.Close()
+
+	scripts := make(map[int]MatchScript)
+	for rows.Next() {
+		var s MatchScript
+		var home, away sql.NullInt64
+		if err := rows.Scan(&s.MatchID, &home, &away, &s.Result); err != nil {
+			return nil, err
+		}
+		if home.Valid && away.Valid {
+			h, a := int(home.Int64), int(away.Int64)
+			s.HomeGoals, s.AwayGoals = &h, &a
+		}
+		scripts[s.MatchID] = s
+	}
+	return scripts, rows.Err()
+}
+
+// POST /matches/{id}/script with {"home_goals": 2, "away_goals": 1} or
+// {"result": "draw"} scripts the match, DELETE removes the script. Admin only.
+func (l *League) handleMatchScript(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAdmin(r) {
+		http.Error(w, "Admin token required", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := l.UnscriptMatch(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Match %d is no longer scripted", id)})
+		return
+	}
+
+	var script MatchScript
+	if err := json.NewDecoder(r.Body).Decode(&script); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	script.MatchID = id
+
+	err = l.ScriptMatch(script)
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "Match not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrInvalidScript):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, ErrMatchPlayed):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(script)
+}
@@ -0,0 +1,82 @@
+package insider_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestScriptDrawWithOvertime(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 7)
+	cfg := *h.League.Config()
+	hockey := insider.SportPresets["hockey"]
+	cfg.Sport, cfg.Simulation = hockey.Sport, hockey.Simulation
+	h.League.StoreConfig(&cfg)
+
+	id := h.Matches()[0].ID
+	path := "/matches/" + strconv.Itoa(id) + "/script"
+	if status := h.Do(http.MethodPost, path, map[string]string{"result": insider.ResultDraw}, true, nil); status != http.StatusBadRequest {
+		t.Errorf("draw script with overtime: status %d, want 400", status)
+	}
+	if status := h.Do(http.MethodPost, path, map[string]int{"home_goals": 2, "away_goals": 2}, true, nil); status != http.StatusBadRequest {
+		t.Errorf("level score script with overtime: status %d, want 400", status)
+	}
+	h.Post(path, map[string]int{"home_goals": 3, "away_goals": 2}, nil)
+
+	// a draw scripted before overtime was switched on still gets a winner
+	draw := insider.MatchScript{Result: insider.ResultDraw}
+	for _, score := range [][2]int{{3, 2}, {1, 4}} {
+		home, away := draw.Apply(score[0], score[1], true)
+		if home == away || (home > away) != (score[0] > score[1]) {
+			t.Errorf("draw script on %d-%d with overtime gave %d-%d", score[0], score[1], home, away)
+		}
+	}
+}
+
+func TestMonteCarloFollowsScripts(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 7)
+	// the weakest team wins every match, the others draw among themselves
+	for _, m := range h.Matches() {
+		result := insider.ResultDraw
+		switch {
+		case m.HomeTeam == "Delta SC":
+			result = insider.ResultHomeWin
+		case m.AwayTeam == "Delta SC":
+			result = insider.ResultAwayWin
+		}
+		h.Post("/matches/"+strconv.Itoa(m.ID)+"/script", map[string]string{"result": result}, nil)
+	}
+
+	prediction, err := h.League.PredictMonteCarlo(200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, team := range prediction.Teams {
+		if team.TeamName == "Delta SC" && team.TitleProbability != 1 {
+			t.Errorf("Delta SC title probability %.2f with every win scripted", team.TitleProbability)
+		}
+	}
+
+	standings, err := h.League.PredictStandings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if standings[0].TeamName != "Delta SC" || standings[0].Wins != 6 {
+		t.Errorf("predicted leader %+v", standings[0])
+	}
+
+	// lifting a script is a change the cached prediction has to notice
+	if status := h.Do(http.MethodDelete, "/matches/"+strconv.Itoa(h.Matches()[0].ID)+"/script", nil, true, nil); status != http.StatusOK {
+		t.Fatalf("DELETE script: status %d", status)
+	}
+	again, err := h.League.PredictMonteCarlo(200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.StateVersion == prediction.StateVersion || again.ReusedMatches != len(h.Matches())-1 {
+		t.Errorf("after lifting a script: version %d (was %d), %d matches reused", again.StateVersion, prediction.StateVersion, again.ReusedMatches)
+	}
+}
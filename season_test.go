@@ -0,0 +1,133 @@
+package insider
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// go test -run TestSeasonSnapshot -update rewrites the golden files after an
+// intended change to the simulation model
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+var snapshotTeams = []Team{
+	{Name: "Alpha FC", Strength: 85},
+	{Name: "Bravo United", Strength: 70},
+	{Name: "Charlie Town", Strength: 60},
+	{Name: "Delta SC", Strength: 50},
+}
+
+type seasonSnapshot struct {
+	Seed      int64      `json:"seed"`
+	Matches   []Match    `json:"matches"`
+	Standings []Standing `json:"standings"`
+}
+
+// newTestLeague builds a league on a fresh database file under t.TempDir
+func newTestLeague(t testing.TB, teams []Team, weeks int, seed int64) *League {
+	t.Helper()
+
+	db, err := sql.Open(DriverName, filepath.Join(t.TempDir(), "league.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	league := NewLeague(db, teams, weeks, &seed, nil)
+	if err := league.InitDatabase(); err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+	return league
+}
+
+func loadMatches(t *testing.T, l *League) []Match {
+	t.Helper()
+
+	rows, err := l.db.Query(matchSelect + " ORDER BY m.id")
+	if err != nil {
+		t.Fatalf("query matches: %v", err)
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			t.Fatalf("scan match: %v", err)
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// assertGolden compares got with testdata/name, or rewrites it with -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file; if the change is intended run go test -update\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestSeasonSnapshot(t *testing.T) {
+	for _, seed := range []int64{1, 42, 2024} {
+		t.Run(fmt.Sprintf("seed_%d", seed), func(t *testing.T) {
+			league := newTestLeague(t, snapshotTeams, 6, seed)
+
+			if err := league.SimulateAll(); err != nil {
+				t.Fatalf("simulate season: %v", err)
+			}
+			standings, err := league.CalculateStandings()
+			if err != nil {
+				t.Fatalf("calculate standings: %v", err)
+			}
+
+			got, err := json.MarshalIndent(seasonSnapshot{
+				Seed:      seed,
+				Matches:   loadMatches(t, league),
+				Standings: standings,
+			}, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, fmt.Sprintf("season_seed_%d.golden.json", seed), append(got, '\n'))
+		})
+	}
+}
+
+func TestSeasonSnapshotIsReproducible(t *testing.T) {
+	first := newTestLeague(t, snapshotTeams, 6, 7)
+	second := newTestLeague(t, snapshotTeams, 6, 7)
+
+	for _, l := range []*League{first, second} {
+		if err := l.SimulateAll(); err != nil {
+			t.Fatalf("simulate season: %v", err)
+		}
+	}
+
+	a, _ := json.Marshal(loadMatches(t, first))
+	b, _ := json.Marshal(loadMatches(t, second))
+	if !bytes.Equal(a, b) {
+		t.Errorf("same seed produced different seasons:\n%s\n%s", a, b)
+	}
+}
@@ -0,0 +1,252 @@
+package insider
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SeasonExport is the JSON file of the season archive
+type SeasonExport struct {
+	ExportedAt time.Time     `json:"exported_at"`
+	Sport      Sport         `json:"sport"`
+	Finished   bool          `json:"finished"`
+	Teams      []Team        `json:"teams"`
+	Standings  []Standing    `json:"standings"`
+	Matches    []Match       `json:"matches"`
+	Awards     *SeasonAwards `json:"awards,omitempty"`
+}
+
+func (l *League) ExportSeason() (*SeasonExport, error) {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return nil, err
+	}
+	standings, err := l.CalculateStandings()
+	if err != nil {
+		return nil, err
+	}
+
+	export := &SeasonExport{
+		ExportedAt: l.clock.Now().UTC(),
+		Sport:      state.sport,
+		Teams:      l.Teams(),
+		Standings:  standings,
+		Matches:    state.matches,
+	}
+	awards, err := l.Awards()
+	switch {
+	case err == nil:
+		export.Finished = true
+		export.Awards = awards
+	case !errors.Is(err, ErrSeasonNotFinished):
+		return nil, err
+	}
+	return export, nil
+}
+
+// ExportArchivedSeason exports season id, the current season when it is
+// still being played. An archived season has no awards, and its teams carry
+// today's strengths: the archive keeps none.
+func (l *League) ExportArchivedSeason(id int) (*SeasonExport, error) {
+	archiveID, err := l.archivedSeason(id)
+	if err != nil {
+		return nil, err
+	}
+	if archiveID == 0 {
+		return l.ExportSeason()
+	}
+
+	standings, err := l.SeasonStandings(id)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := l.SeasonMatches(id)
+	if err != nil {
+		return nil, err
+	}
+	export := &SeasonExport{
+		ExportedAt: l.clock.Now().UTC(),
+		Sport:      l.config().Sport,
+		Finished:   true,
+		Teams:      []Team{},
+		Standings:  standings,
+		Matches:    matches,
+	}
+	for _, m := range matches {
+		if !m.Played {
+			export.Finished = false
+		}
+	}
+	for _, s := range standings {
+		team := Team{ID: s.TeamID, Name: s.TeamName}
+		if err := l.db.QueryRow("SELECT strength FROM teams WHERE id = ?", s.TeamID).Scan(&team.Strength); err != nil {
+			return nil, err
+		}
+		export.Teams = append(export.Teams, team)
+	}
+	return export, nil
+}
+
+// SeasonArchive zips a season, the current one when season is nil, as JSON,
+// CSV tables, an HTML report and an iCal calendar. It is built in memory; a
+// season is small.
+func (l *League) SeasonArchive(season *int) ([]byte, error) {
+	var export *SeasonExport
+	var err error
+	if season == nil {
+		export, err = l.ExportSeason()
+	} else {
+		export, err = l.ExportArchivedSeason(*season)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name  string
+		write func(io.Writer, *SeasonExport) error
+	}{
+		{"season.json", writeSeasonJSON},
+		{"standings.csv", writeStandingsCSV},
+		{"matches.csv", writeMatchesCSV},
+		{"report.html", writeSeasonReport},
+		{"fixtures.ics", writeSeasonCalendar},
+	}
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+		if err := f.write(w, export); err != nil {
+			return nil, fmt.Errorf("error writing %s: %v", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeSeasonJSON(w io.Writer, export *SeasonExport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(export)
+}
+
+func writeStandingsCSV(w io.Writer, export *SeasonExport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"position", "team", "played", "wins", "draws", "losses", "goals_for", "goals_against", "goal_difference", "points"})
+	for i, s := range export.Standings {
+		cw.Write([]string{
+			strconv.Itoa(i + 1), s.TeamName,
+			strconv.Itoa(s.Played), strconv.Itoa(s.Wins), strconv.Itoa(s.Draws), strconv.Itoa(s.Losses),
+			strconv.Itoa(s.GoalsFor), strconv.Itoa(s.GoalsAgainst), strconv.Itoa(s.GoalDifference),
+			strconv.Itoa(s.Points),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeMatchesCSV(w io.Writer, export *SeasonExport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "week", "kickoff", "home_team", "away_team", "home_goals", "away_goals", "status"})
+	for _, m := range export.Matches {
+		homeGoals, awayGoals := "", ""
+		if m.Played {
+			homeGoals, awayGoals = strconv.Itoa(m.HomeGoals), strconv.Itoa(m.AwayGoals)
+		}
+		cw.Write([]string{
+			strconv.Itoa(m.ID), strconv.Itoa(m.Week), m.Kickoff,
+			m.HomeTeam, m.AwayTeam, homeGoals, awayGoals, m.Status,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var seasonReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Season report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:nth-child(2), td:nth-child(2) { text-align: left; }
+</style>
+</head>
+<body>
+<h1>Season report</h1>
+<p>{{if .Finished}}Final table{{else}}Table so far{{end}}, exported {{.ExportedAt.Format "2006-01-02 15:04"}} UTC</p>
+{{with .Awards}}
+<h2>Awards</h2>
+<ul>
+<li>Champion: {{.Champion.TeamName}} ({{.Champion.Detail}})</li>
+<li>Best defense: {{.BestDefense.TeamName}} ({{.BestDefense.Detail}})</li>
+{{with .MostImproved}}<li>Most improved: {{.TeamName}} ({{.Detail}})</li>{{end}}
+</ul>
+{{end}}
+<h2>Standings</h2>
+<table>
+<tr><th>#</th><th>Team</th><th>P</th><th>W</th><th>D</th><th>L</th><th>GF</th><th>GA</th><th>GD</th><th>Pts</th></tr>
+{{range $i, $s := .Standings}}<tr><td>{{inc $i}}</td><td>{{$s.TeamName}}</td><td>{{$s.Played}}</td><td>{{$s.Wins}}</td><td>{{$s.Draws}}</td><td>{{$s.Losses}}</td><td>{{$s.GoalsFor}}</td><td>{{$s.GoalsAgainst}}</td><td>{{$s.GoalDifference}}</td><td>{{$s.Points}}</td></tr>
+{{end}}</table>
+<h2>Results</h2>
+<table>
+<tr><th>Week</th><th>Match</th><th>Score</th></tr>
+{{range .Matches}}<tr><td>{{.Week}}</td><td>{{.HomeTeam}} - {{.AwayTeam}}</td><td>{{if .Played}}{{.HomeGoals}}-{{.AwayGoals}}{{else}}{{.Status}}{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func writeSeasonReport(w io.Writer, export *SeasonExport) error {
+	return seasonReport.Execute(w, export)
+}
+
+func writeSeasonCalendar(w io.Writer, export *SeasonExport) error {
+	return writeCalendar(w, "Season", export.Sport, export.Matches, export.ExportedAt, storedKickoff)
+}
+
+// GET /seasons/{id}/archive.zip, id is a season id or "current"
+func (l *League) handleSeasonArchive(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var season *int
+	if id != "current" {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			http.Error(w, "Invalid season id", http.StatusBadRequest)
+			return
+		}
+		season = &n
+	}
+
+	data, err := l.SeasonArchive(season)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Season not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="season-%s.zip"`, id))
+	w.Write(data)
+}
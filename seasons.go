@@ -0,0 +1,385 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A league plays one season at a time. Once every match is played,
+// POST /seasons archives it, clears the fixture and plans the next season,
+// which gets its fixture from POST /seasons/{id}/start. Between the two the
+// teams, strengths and rules can be changed for the new season. A season's
+// matches go to the fixture archive like a replaced fixture, so the all-time
+// tables count it either way.
+
+const (
+	SeasonPlanned  = "planned"
+	SeasonActive   = "active"
+	SeasonArchived = "archived"
+)
+
+var (
+	// ErrSeasonUnfinished is returned when archiving a season with matches
+	// still to play
+	ErrSeasonUnfinished = errors.New("the season still has matches to play")
+	// ErrSeasonPlanned is returned when the next season is planned but not
+	// started
+	ErrSeasonPlanned = errors.New("the next season has not started")
+	// ErrSeasonNotPlanned is returned when starting a season that already
+	// started
+	ErrSeasonNotPlanned = errors.New("only a planned season can be started")
+)
+
+// Season is one season of the league
+type Season struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// ArchiveID is the fixture archive holding the matches of an archived
+	// season
+	ArchiveID int        `json:"archive_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+// createSeasonsTable creates the seasons table and, for a new database or one
+// from before seasons, the season being played
+func (l *League) createSeasonsTable() error {
+	createSeasons := `
+	CREATE TABLE IF NOT EXISTS seasons (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		status TEXT NOT NULL,
+		archive_id INTEGER REFERENCES fixture_archives(id) ON DELETE SET NULL,
+		created_at TIMESTAMP NOT NULL,
+		started_at TIMESTAMP,
+		ended_at TIMESTAMP
+	);`
+
+	if _, err := l.db.Exec(createSeasons); err != nil {
+		return fmt.Errorf("error creating seasons table: %v", err)
+	}
+
+	var count int
+	if err := l.db.QueryRow("SELECT COUNT(*) FROM seasons").Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		now := time.Now().UTC()
+		if _, err := l.db.Exec("INSERT INTO seasons (name, status, created_at, started_at) VALUES (?, ?, ?, ?)",
+			"Season 1", SeasonActive, now, now); err != nil {
+			return fmt.Errorf("error creating the first season: %v", err)
+		}
+	}
+	return nil
+}
+
+const seasonSelect = `SELECT id, name, status, COALESCE(archive_id, 0), created_at, started_at, ended_at FROM seasons`
+
+func scanSeason(row rowScanner) (Season, error) {
+	var s Season
+	var started, ended sql.NullTime
+	err := row.Scan(&s.ID, &s.Name, &s.Status, &s.ArchiveID, &s.CreatedAt, &started, &ended)
+	if started.Valid {
+		s.StartedAt = &started.Time
+	}
+	if ended.Valid {
+		s.EndedAt = &ended.Time
+	}
+	return s, err
+}
+
+// currentSeason is the latest season, the one the matches table belongs to
+func currentSeason(q interface {
+	QueryRow(string, ...any) *sql.Row
+}) (Season, error) {
+	return scanSeason(q.QueryRow(seasonSelect + " ORDER BY id DESC LIMIT 1"))
+}
+
+// Seasons lists every season, oldest first
+func (l *League) Seasons() ([]Season, error) {
+	rows, err := l.db.Query(seasonSelect + " ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seasons := []Season{}
+	for rows.Next() {
+		s, err := scanSeason(rows)
+		if err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, s)
+	}
+	return seasons, rows.Err()
+}
+
+// season finds a season by id, sql.ErrNoRows when there is none
+func (l *League) season(id int) (Season, error) {
+	return scanSeason(l.db.QueryRow(seasonSelect+" WHERE id = ?", id))
+}
+
+// ArchiveSeason closes the current season once all of its matches are played
+// and plans the next one under name, or "Season N" when name is empty
+func (l *League) ArchiveSeason(name string) (*Season, error) {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := currentSeason(tx)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status == SeasonPlanned {
+		return nil, fmt.Errorf("%w: start season %d first", ErrSeasonPlanned, current.ID)
+	}
+	var open int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM matches WHERE played = FALSE AND COALESCE(administrative, '') != 'annulled'").Scan(&open); err != nil {
+		return nil, err
+	}
+	if open > 0 {
+		return nil, fmt.Errorf("%w: %d left", ErrSeasonUnfinished, open)
+	}
+
+	if err := l.carryPopularity(tx); err != nil {
+		return nil, err
+	}
+	archiveID, err := archiveFixture(tx, "season "+current.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error archiving season: %v", err)
+	}
+	now := time.Now().UTC()
+	if _, err := tx.Exec("UPDATE seasons SET status = ?, archive_id = ?, ended_at = ? WHERE id = ?",
+		SeasonArchived, archiveID, now, current.ID); err != nil {
+		return nil, err
+	}
+	if err := clearSeason(tx); err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM seasons").Scan(&count); err != nil {
+			return nil, err
+		}
+		name = fmt.Sprintf("Season %d", count+1)
+	}
+	res, err := tx.Exec("INSERT INTO seasons (name, status, created_at) VALUES (?, ?, ?)", name, SeasonPlanned, now)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	next, err := scanSeason(tx.QueryRow(seasonSelect+" WHERE id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	l.touch()
+	return &next, nil
+}
+
+// StartSeason draws the fixture of a planned season and makes it the one
+// being played
+func (l *League) StartSeason(id int) (*Season, error) {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	season, err := scanSeason(tx.QueryRow(seasonSelect+" WHERE id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	if season.Status != SeasonPlanned {
+		return nil, fmt.Errorf("%w: season %d is %s", ErrSeasonNotPlanned, id, season.Status)
+	}
+	if err := l.generateFixture(tx, false); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if _, err := tx.Exec("UPDATE seasons SET status = ?, started_at = ? WHERE id = ?", SeasonActive, now, id); err != nil {
+		return nil, err
+	}
+	season.Status, season.StartedAt = SeasonActive, &now
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	l.touch()
+	return &season, nil
+}
+
+// seasonParam reads ?season=, nil for the current season (also "current")
+func seasonParam(r *http.Request) (*int, error) {
+	s := r.URL.Query().Get("season")
+	if s == "" || s == "current" {
+		return nil, nil
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid season parameter")
+	}
+	return &id, nil
+}
+
+// archivedSeason finds the archive of a past season. A season still being
+// played or planned has none: its matches are the current ones.
+func (l *League) archivedSeason(id int) (archiveID int, err error) {
+	season, err := l.season(id)
+	if err != nil {
+		return 0, err
+	}
+	if season.Status != SeasonArchived {
+		return 0, nil
+	}
+	return season.ArchiveID, nil
+}
+
+// SeasonStandings is the final table of an archived season, or the current
+// table for the season being played
+func (l *League) SeasonStandings(id int) ([]Standing, error) {
+	archiveID, err := l.archivedSeason(id)
+	if err != nil {
+		return nil, err
+	}
+	if archiveID == 0 {
+		return l.CalculateStandings()
+	}
+
+	seasons, err := l.seasons()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range seasons {
+		if s.ID == strconv.Itoa(archiveID) {
+			cfg := l.config()
+			cfg.Sport.rankStandings(s.Standings, cfg.SharedRanks)
+			return s.Standings, nil
+		}
+	}
+	return []Standing{}, nil
+}
+
+// SeasonMatches are the matches of an archived season, or the current ones
+// for the season being played
+func (l *League) SeasonMatches(id int) ([]Match, error) {
+	archiveID, err := l.archivedSeason(id)
+	if err != nil {
+		return nil, err
+	}
+	if archiveID == 0 {
+		return l.Matches()
+	}
+
+	rows, err := l.db.Query(`
+		SELECT m.match_id, m.home_team_id, h.name, m.away_team_id, a.name, COALESCE(m.home_goals, 0), COALESCE(m.away_goals, 0),
+			m.played, m.week, m.postponed, COALESCE(m.kickoff, '')
+		FROM archived_matches m
+		JOIN teams h ON h.id = m.home_team_id
+		JOIN teams a ON a.id = m.away_team_id
+		WHERE m.archive_id = ?
+		ORDER BY m.week, m.match_id`, archiveID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []Match{}
+	for rows.Next() {
+		var m Match
+		if err := rows.Scan(&m.ID, &m.HomeTeamID, &m.HomeTeam, &m.AwayTeamID, &m.AwayTeam, &m.HomeGoals, &m.AwayGoals,
+			&m.Played, &m.Week, &m.Postponed, &m.Kickoff); err != nil {
+			return nil, err
+		}
+		m.Status = matchStatus(m)
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// GET /seasons lists the seasons; POST archives the finished current season
+// and plans the next, {"name": "2026/27"} (admin only)
+func (l *League) handleSeasons(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		seasons, err := l.Seasons()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(seasons)
+	case http.MethodPost:
+		if !requireAdmin(w, r) {
+			return
+		}
+		var req struct {
+			Name string `json:"name"`
+		}
+		// the name is optional, and so is the body
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		next, err := l.ArchiveSeason(req.Name)
+		if errors.Is(err, ErrSeasonUnfinished) || errors.Is(err, ErrSeasonPlanned) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(next)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// POST /seasons/{id}/start draws the fixture of a planned season (admin only)
+func (l *League) handleStartSeason(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid season ID", http.StatusBadRequest)
+		return
+	}
+
+	season, err := l.StartSeason(id)
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "Season not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrSeasonNotPlanned):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(season)
+}
@@ -0,0 +1,177 @@
+package insider_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestSeasonLifecycle(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 6)
+	var seasons []insider.Season
+	h.Get("/seasons", &seasons)
+	if len(seasons) != 1 || seasons[0].Status != insider.SeasonActive {
+		t.Fatalf("a new league has seasons %+v", seasons)
+	}
+	first := seasons[0].ID
+
+	if status := h.Do(http.MethodPost, "/seasons", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("archive before the last match: status %d, want 409", status)
+	}
+	if err := h.League.SimulateAll(); err != nil {
+		t.Fatal(err)
+	}
+	final, err := h.League.CalculateStandings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var next insider.Season
+	if status := h.Do(http.MethodPost, "/seasons", map[string]string{"name": "Season two"}, true, &next); status != http.StatusCreated {
+		t.Fatalf("POST /seasons: status %d", status)
+	}
+	if next.Name != "Season two" || next.Status != insider.SeasonPlanned {
+		t.Errorf("next season %+v", next)
+	}
+	if len(h.Matches()) != 0 {
+		t.Errorf("a planned season has %d matches", len(h.Matches()))
+	}
+	if status := h.Do(http.MethodPost, "/fixture/generate", nil, false, nil); status != http.StatusConflict {
+		t.Errorf("generate a planned season's fixture: status %d, want 409", status)
+	}
+
+	// the archived season keeps its table and matches
+	var archived []insider.Standing
+	h.Get("/standings?season="+strconv.Itoa(first), &archived)
+	if len(archived) != len(final) {
+		t.Fatalf("archived table has %d rows, want %d", len(archived), len(final))
+	}
+	for i := range final {
+		if archived[i].TeamName != final[i].TeamName || archived[i].Points != final[i].Points || archived[i].Rank != i+1 {
+			t.Errorf("row %d: %+v, want %+v", i+1, archived[i], final[i])
+		}
+	}
+	var matches []insider.Match
+	h.Get("/matches?season="+strconv.Itoa(first), &matches)
+	if want := len(insider.SnapshotTeams) * (len(insider.SnapshotTeams) - 1); len(matches) != want || !matches[0].Played {
+		t.Errorf("archived season has %d matches, want %d played", len(matches), want)
+	}
+
+	path := "/seasons/" + strconv.Itoa(next.ID) + "/start"
+	if status := h.Do(http.MethodPost, path, nil, true, &next); status != http.StatusOK {
+		t.Fatalf("start: status %d", status)
+	}
+	if next.Status != insider.SeasonActive || next.StartedAt == nil {
+		t.Errorf("started season %+v", next)
+	}
+	if status := h.Do(http.MethodPost, path, nil, true, nil); status != http.StatusConflict {
+		t.Errorf("start twice: status %d, want 409", status)
+	}
+	var current []insider.Match
+	h.Get("/matches?season="+strconv.Itoa(next.ID), &current)
+	if len(current) != len(matches) || current[0].Played {
+		t.Errorf("new season has %d matches, first played %v", len(current), current[0].Played)
+	}
+
+	h.Get("/seasons", &seasons)
+	if len(seasons) != 2 || seasons[0].Status != insider.SeasonArchived || seasons[0].ArchiveID == 0 || seasons[0].EndedAt == nil {
+		t.Errorf("seasons %+v", seasons)
+	}
+	if status := h.Do(http.MethodGet, "/standings?season=99", nil, false, nil); status != http.StatusNotFound {
+		t.Errorf("unknown season: status %d, want 404", status)
+	}
+	if status := h.Do(http.MethodPost, "/seasons", nil, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("archive without a token: status %d, want 401", status)
+	}
+}
+
+// archiveZip fetches a season archive and reads back its season.json
+func archiveZip(t *testing.T, h *leaguetest.Harness, id string) (int, *insider.SeasonExport, []string) {
+	t.Helper()
+	resp, err := http.Get(h.Server.URL + "/seasons/" + id + "/archive.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil, nil
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var export insider.SeasonExport
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name != "season.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.NewDecoder(rc).Decode(&export)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, &export, names
+}
+
+func TestArchivedSeasonZip(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 6)
+	var seasons []insider.Season
+	h.Get("/seasons", &seasons)
+	first := strconv.Itoa(seasons[0].ID)
+	h.SimulateSeason()
+	final := h.Standings()
+	if status := h.Do(http.MethodPost, "/seasons", nil, true, nil); status != http.StatusCreated {
+		t.Fatalf("POST /seasons: status %d", status)
+	}
+
+	status, export, files := archiveZip(t, h, first)
+	if status != http.StatusOK {
+		t.Fatalf("archive of season %s: status %d", first, status)
+	}
+	if len(files) != 5 {
+		t.Errorf("archive files %v", files)
+	}
+	if !export.Finished || export.Awards != nil || len(export.Teams) != len(insider.SnapshotTeams) {
+		t.Errorf("archived export finished %v, awards %+v, %d teams", export.Finished, export.Awards, len(export.Teams))
+	}
+	if want := len(insider.SnapshotTeams) * (len(insider.SnapshotTeams) - 1); len(export.Matches) != want {
+		t.Errorf("archive has %d matches, want %d", len(export.Matches), want)
+	}
+	for i := range final {
+		if export.Standings[i].TeamName != final[i].TeamName || export.Standings[i].Points != final[i].Points {
+			t.Errorf("row %d: %+v, want %+v", i+1, export.Standings[i], final[i])
+		}
+	}
+
+	// the next season is planned, it has no matches yet
+	if status, export, _ = archiveZip(t, h, "current"); status != http.StatusOK {
+		t.Errorf("current archive: status %d", status)
+	} else if len(export.Matches) != 0 {
+		t.Errorf("current archive has %d matches before the season starts", len(export.Matches))
+	}
+	if status, _, _ := archiveZip(t, h, "99"); status != http.StatusNotFound {
+		t.Errorf("unknown season: status %d, want 404", status)
+	}
+	if status, _, _ := archiveZip(t, h, "last"); status != http.StatusBadRequest {
+		t.Errorf("season last: status %d, want 400", status)
+	}
+}
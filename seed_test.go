@@ -0,0 +1,55 @@
+package insider_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestSeedParameterReplaysAWeek(t *testing.T) {
+	a := leaguetest.New(t, insider.SnapshotTeams, 11)
+	b := leaguetest.New(t, insider.SnapshotTeams, 99)
+
+	var cfg struct {
+		Seed *int64 `json:"seed"`
+	}
+	a.Get("/config", &cfg)
+	if cfg.Seed == nil || *cfg.Seed != 11 {
+		t.Fatalf("seed = %v, want 11", cfg.Seed)
+	}
+
+	// the same seed plays the same week whatever the league was seeded with
+	for _, h := range []*leaguetest.Harness{a, b} {
+		if status := h.Do(http.MethodPost, "/simulate/week/1?seed=5", nil, true, nil); status != http.StatusOK {
+			t.Fatalf("simulate week 1: status %d", status)
+		}
+	}
+	if !reflect.DeepEqual(a.Matches(), b.Matches()) {
+		t.Error("week 1 differs between leagues simulated with seed 5")
+	}
+	var predictedA, predictedB []insider.Standing
+	a.Get("/predict?seed=3", &predictedA)
+	b.Get("/predict?seed=3", &predictedB)
+	if !reflect.DeepEqual(predictedA, predictedB) {
+		t.Error("predictions with seed 3 differ")
+	}
+
+	// reseeding both leagues makes their own streams agree too
+	for _, h := range []*leaguetest.Harness{a, b} {
+		if status := h.Do(http.MethodPost, "/config?seed=42", nil, true, &cfg); status != http.StatusOK || *cfg.Seed != 42 {
+			t.Fatalf("reseed: status %d, seed %v", status, cfg.Seed)
+		}
+		if err := h.League.SimulateWeek(2); err != nil {
+			t.Fatalf("simulate week 2: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(a.Matches(), b.Matches()) {
+		t.Error("week 2 differs after reseeding both leagues with 42")
+	}
+	if status := a.Do(http.MethodPost, "/config?seed=1", nil, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("reseed without a token: status %d, want 401", status)
+	}
+}
@@ -0,0 +1,168 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// The league's rules live in the settings table, so they travel with the
+// database instead of the command line. They are loaded over the --sport
+// preset and the flags when the database is opened, and a --config file
+// still goes on top of them for the fields it sets.
+
+// leagueRulesKey is the settings row holding the LeagueRules
+const leagueRulesKey = "league_rules"
+
+// ErrInvalidRules is returned for rules the config would not accept
+var ErrInvalidRules = errors.New("invalid rules")
+
+// LeagueRules are the rules a league keeps in its database
+type LeagueRules struct {
+	WinPoints     int      `json:"win_points"`
+	DrawPoints    int      `json:"draw_points"`
+	LossPoints    int      `json:"loss_points"`
+	Tiebreakers   []string `json:"tiebreakers"`
+	HomeAdvantage int      `json:"home_advantage"`
+}
+
+func (l *League) createSettingsTable() error {
+	createSettings := `
+	CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);`
+
+	if _, err := l.db.Exec(createSettings); err != nil {
+		return fmt.Errorf("error creating settings table: %v", err)
+	}
+	return nil
+}
+
+// rulesOf reads the LeagueRules out of a config. The tiebreakers are a copy:
+// decoding a PUT over them must not write into the config or the defaults.
+func rulesOf(cfg *Config) LeagueRules {
+	return LeagueRules{
+		WinPoints:     cfg.Sport.WinPoints,
+		DrawPoints:    cfg.Sport.DrawPoints,
+		LossPoints:    cfg.Sport.LossPoints,
+		Tiebreakers:   slices.Clone(cfg.Sport.tiebreakerOrder()),
+		HomeAdvantage: cfg.Simulation.HomeAdvantage,
+	}
+}
+
+// apply writes the rules into a config
+func (r LeagueRules) apply(cfg *Config) {
+	cfg.Sport.WinPoints = r.WinPoints
+	cfg.Sport.DrawPoints = r.DrawPoints
+	cfg.Sport.LossPoints = r.LossPoints
+	cfg.Sport.Tiebreakers = r.Tiebreakers
+	cfg.Simulation.HomeAdvantage = r.HomeAdvantage
+}
+
+// LeagueRules are the rules in effect
+func (l *League) LeagueRules() LeagueRules {
+	return rulesOf(l.config())
+}
+
+// loadLeagueRules puts the stored rules under the running config, if any
+// were stored
+func (l *League) loadLeagueRules() error {
+	var value string
+	err := l.db.QueryRow("SELECT value FROM settings WHERE key = ?", leagueRulesKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var rules LeagueRules
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return fmt.Errorf("invalid %s setting: %v", leagueRulesKey, err)
+	}
+	return l.useLeagueRules(rules)
+}
+
+// useLeagueRules makes rules the base of the config and rebuilds the running
+// config on it
+func (l *League) useLeagueRules(rules LeagueRules) error {
+	if err := l.updateBase(rules.apply); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+	return nil
+}
+
+// updateBase changes the base of the config and rebuilds the running config
+// on it: the config file on top when there is one. A change the config
+// would not accept is refused.
+func (l *League) updateBase(change func(cfg *Config)) error {
+	l.configMu.Lock()
+	base := l.baseConfig
+	change(&base)
+	if err := base.Validate(); err != nil {
+		l.configMu.Unlock()
+		return err
+	}
+	l.baseConfig = base
+	l.configMu.Unlock()
+
+	if l.configFile != "" {
+		return l.ReloadConfig()
+	}
+	l.cfg.Store(&base)
+	return nil
+}
+
+// SetLeagueRules stores new rules and puts them in effect
+func (l *League) SetLeagueRules(rules LeagueRules) error {
+	if err := l.useLeagueRules(rules); err != nil {
+		return err
+	}
+	value, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	_, err = l.db.Exec(`
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, leagueRulesKey, string(value))
+	if err != nil {
+		return err
+	}
+	// tables and predictions follow the new rules
+	l.touch()
+	return nil
+}
+
+// GET /settings/rules shows the points, tiebreakers and home advantage in
+// effect, PUT replaces the stored ones (admin only)
+func (l *League) handleLeagueRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(l.LeagueRules())
+	case http.MethodPut:
+		if !requireAdmin(w, r) {
+			return
+		}
+		// fields left out keep their current value
+		rules := l.LeagueRules()
+		if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err := l.SetLeagueRules(rules)
+		if errors.Is(err, ErrInvalidRules) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(l.LeagueRules())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,54 @@
+package insider_test
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestLeagueRulesAreStoredAndApplied(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 4)
+	rules := insider.LeagueRules{WinPoints: 2, DrawPoints: 1, Tiebreakers: []string{insider.TiebreakPoints, insider.TiebreakWins, insider.TiebreakGoalsAgainst}, HomeAdvantage: 4}
+	var got insider.LeagueRules
+	if status := h.Do(http.MethodPut, "/settings/rules", rules, true, &got); status != http.StatusOK {
+		t.Fatalf("PUT /settings/rules: status %d", status)
+	}
+	if h.League.Config().Simulation.HomeAdvantage != 4 || !slices.Equal(got.Tiebreakers, rules.Tiebreakers) {
+		t.Errorf("rules in effect: %+v", got)
+	}
+
+	if err := h.League.SimulateWeek(1); err != nil {
+		t.Fatalf("simulate week 1: %v", err)
+	}
+	standings, err := h.League.CalculateStandings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range standings {
+		if s.Points != 2*s.Wins+s.Draws {
+			t.Errorf("%s: %d points from %d wins and %d draws", s.TeamName, s.Points, s.Wins, s.Draws)
+		}
+	}
+
+	// a league opened on the same database picks the rules up
+	reopened := insider.NewLeague(h.League.DB(), insider.SnapshotTeams, insider.FixtureWeeks(len(insider.SnapshotTeams)), nil, nil)
+	if err := reopened.InitDatabase(); err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+	if got := reopened.LeagueRules(); got.WinPoints != 2 || got.HomeAdvantage != 4 || !slices.Equal(got.Tiebreakers, rules.Tiebreakers) {
+		t.Errorf("reopened league has %+v", got)
+	}
+
+	bad := rules
+	bad.Tiebreakers = []string{insider.TiebreakGoalDifference, insider.TiebreakPoints}
+	if err := h.League.SetLeagueRules(bad); !errors.Is(err, insider.ErrInvalidRules) {
+		t.Errorf("tiebreakers without points first: err = %v", err)
+	}
+	if status := h.Do(http.MethodPut, "/settings/rules", rules, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("PUT without a token: status %d, want 401", status)
+	}
+}
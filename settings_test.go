@@ -0,0 +1,28 @@
+package insider
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTiebreakerOrder(t *testing.T) {
+	table := []Standing{
+		{TeamName: "A", Points: 10, GoalDifference: 5, Wins: 2, GoalsAgainst: 6},
+		{TeamName: "B", Points: 10, GoalDifference: 2, Wins: 3, GoalsAgainst: 4},
+		{TeamName: "C", Points: 10, GoalDifference: 1, Wins: 3, GoalsAgainst: 3},
+	}
+	sport := defaultSport
+	sport.sortStandings(table)
+	if table[0].TeamName != "A" {
+		t.Errorf("goal difference first: %s on top", table[0].TeamName)
+	}
+	sport.Tiebreakers = []string{TiebreakPoints, TiebreakWins, TiebreakGoalsAgainst}
+	sport.sortStandings(table)
+	if names := []string{table[0].TeamName, table[1].TeamName, table[2].TeamName}; !slices.Equal(names, []string{"C", "B", "A"}) {
+		t.Errorf("wins then goals against: order %v", names)
+	}
+	sport.rankStandings(table, true)
+	if table[1].Rank != 2 {
+		t.Errorf("B is ranked %d, level with C only on wins", table[1].Rank)
+	}
+}
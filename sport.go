@@ -0,0 +1,211 @@
+package insider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Sport sets how long a match is and what a result is worth. How scores are
+// drawn, including overtime, is part of SimParams.
+type Sport struct {
+	Name         string `json:"name"`
+	MatchMinutes int    `json:"match_minutes"`
+	WinPoints    int    `json:"win_points"`
+	DrawPoints   int    `json:"draw_points"`
+	LossPoints   int    `json:"loss_points"`
+	// TrackEvents generates minute by minute events and commentary. High
+	// scoring sports only keep the final score.
+	TrackEvents bool `json:"track_events"`
+	// PointMultipliers makes the results of a week count several times,
+	// keyed by week; weeks not listed count once
+	PointMultipliers map[int]int `json:"point_multipliers,omitempty"`
+	// Tiebreakers orders teams level on points, see tiebreakerOrder
+	Tiebreakers []string `json:"tiebreakers,omitempty"`
+}
+
+// Tiebreakers a table can be ordered by. Fewer goals against is better, more
+// of everything else.
+const (
+	TiebreakPoints         = "points"
+	TiebreakGoalDifference = "goal_difference"
+	TiebreakGoalsFor       = "goals_for"
+	TiebreakGoalsAgainst   = "goals_against"
+	TiebreakWins           = "wins"
+)
+
+// defaultTiebreakers is the order without a tiebreakers list
+var defaultTiebreakers = []string{TiebreakPoints, TiebreakGoalDifference, TiebreakGoalsFor}
+
+// tiebreakerOrder is the order tables are sorted in. Points always come
+// first, since clinches and position ranges are worked out from points; the
+// team name always comes last.
+func (s Sport) tiebreakerOrder() []string {
+	if len(s.Tiebreakers) == 0 {
+		return defaultTiebreakers
+	}
+	return s.Tiebreakers
+}
+
+// compareStandings is negative when a ranks above b on the tiebreakers,
+// positive when below and 0 when they are level on all of them
+func (s Sport) compareStandings(a, b Standing) int {
+	for _, t := range s.tiebreakerOrder() {
+		var x, y int
+		switch t {
+		case TiebreakPoints:
+			x, y = a.Points, b.Points
+		case TiebreakGoalDifference:
+			x, y = a.GoalDifference, b.GoalDifference
+		case TiebreakGoalsFor:
+			x, y = a.GoalsFor, b.GoalsFor
+		case TiebreakGoalsAgainst:
+			x, y = b.GoalsAgainst, a.GoalsAgainst
+		case TiebreakWins:
+			x, y = a.Wins, b.Wins
+		}
+		if x != y {
+			if x > y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+type sportPreset struct {
+	Sport      Sport
+	Simulation SimParams
+}
+
+var sportPresets = map[string]sportPreset{
+	"football": {
+		Sport:      Sport{Name: "football", MatchMinutes: 90, WinPoints: 3, DrawPoints: 1, TrackEvents: true},
+		Simulation: defaultSimParams,
+	},
+	// 70 to roughly 115 points a side, level games go to overtime
+	"basketball": {
+		Sport:      Sport{Name: "basketball", MatchMinutes: 48, WinPoints: 2},
+		Simulation: SimParams{HomeAdvantage: 6, StrengthPerGoal: 2, BaseScore: 70, Overtime: true},
+	},
+	"hockey": {
+		Sport:      Sport{Name: "hockey", MatchMinutes: 60, WinPoints: 2, TrackEvents: true},
+		Simulation: SimParams{HomeAdvantage: 10, StrengthPerGoal: 15, Overtime: true},
+	},
+}
+
+var defaultSport = sportPresets["football"].Sport
+
+// lookupSport finds a preset by name
+func lookupSport(name string) (sportPreset, error) {
+	preset, ok := sportPresets[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(sportPresets))
+		for n := range sportPresets {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return sportPreset{}, fmt.Errorf("unknown sport %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return preset, nil
+}
+
+func (s Sport) Validate() error {
+	if s.MatchMinutes < 1 {
+		return fmt.Errorf("match_minutes must be at least 1")
+	}
+	if s.LossPoints < 0 || s.DrawPoints < s.LossPoints || s.WinPoints < s.DrawPoints {
+		return fmt.Errorf("points must satisfy win >= draw >= loss >= 0")
+	}
+	for week, m := range s.PointMultipliers {
+		if week < 1 || m < 1 {
+			return fmt.Errorf("point_multipliers: week %d counts %d times, weeks and multipliers start at 1", week, m)
+		}
+	}
+	if len(s.Tiebreakers) > 0 && s.Tiebreakers[0] != TiebreakPoints {
+		return fmt.Errorf("tiebreakers must start with %q", TiebreakPoints)
+	}
+	seen := make(map[string]bool)
+	for _, t := range s.Tiebreakers {
+		switch t {
+		case TiebreakPoints, TiebreakGoalDifference, TiebreakGoalsFor, TiebreakGoalsAgainst, TiebreakWins:
+		default:
+			return fmt.Errorf("unknown tiebreaker %q", t)
+		}
+		if seen[t] {
+			return fmt.Errorf("tiebreaker %q listed twice", t)
+		}
+		seen[t] = true
+	}
+	return nil
+}
+
+// points is what a team earns from a single match
+func (s Sport) points(goalsFor, goalsAgainst int) int {
+	switch {
+	case goalsFor > goalsAgainst:
+		return s.WinPoints
+	case goalsFor == goalsAgainst:
+		return s.DrawPoints
+	}
+	return s.LossPoints
+}
+
+// multiplier is how many times the results of week count
+func (s Sport) multiplier(week int) int {
+	if m, ok := s.PointMultipliers[week]; ok {
+		return m
+	}
+	return 1
+}
+
+// matchPoints is what a team earns from a match played in week
+func (s Sport) matchPoints(week, goalsFor, goalsAgainst int) int {
+	return s.multiplier(week) * s.points(goalsFor, goalsAgainst)
+}
+
+// pointsLeft is the most and the fewest points each team can still add
+// from the remaining matches
+func (s Sport) pointsLeft(remaining []Match) (most, fewest map[string]int) {
+	most, fewest = make(map[string]int), make(map[string]int)
+	for _, m := range remaining {
+		k := s.multiplier(m.Week)
+		for _, team := range []string{m.HomeTeam, m.AwayTeam} {
+			most[team] += k * s.WinPoints
+			fewest[team] += k * s.LossPoints
+		}
+	}
+	return most, fewest
+}
+
+// expectedPoints weighs the points of each outcome by its probability
+func (s Sport) expectedPoints(win, draw, loss float64) float64 {
+	return float64(s.WinPoints)*win + float64(s.DrawPoints)*draw + float64(s.LossPoints)*loss
+}
+
+// recordResult adds one played match of week to both teams' rows.
+// Goal difference is left to the caller once all matches are in.
+func (s Sport) recordResult(home, away *Standing, week, homeGoals, awayGoals int) {
+	home.Played++
+	away.Played++
+
+	home.GoalsFor += homeGoals
+	home.GoalsAgainst += awayGoals
+
+	away.GoalsFor += awayGoals
+	away.GoalsAgainst += homeGoals
+
+	if homeGoals > awayGoals {
+		home.Wins++
+		away.Losses++
+	} else if homeGoals < awayGoals {
+		away.Wins++
+		home.Losses++
+	} else {
+		home.Draws++
+		away.Draws++
+	}
+	home.Points += s.matchPoints(week, homeGoals, awayGoals)
+	away.Points += s.matchPoints(week, awayGoals, homeGoals)
+}
@@ -0,0 +1,55 @@
+package insider
+
+import "testing"
+
+func TestPointMultipliers(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, 6, 7)
+	cfg := *l.config()
+	cfg.Sport.PointMultipliers = map[int]int{3: 2}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	l.cfg.Store(&cfg)
+
+	if err := l.SimulateAll(); err != nil {
+		t.Fatalf("simulate: %v", err)
+	}
+	want := make(map[string]int)
+	var doubled []Match
+	appearances := make(map[string]int)
+	for _, m := range loadMatches(t, l) {
+		k := 1
+		if m.Week == 3 {
+			k = 2
+			doubled = append(doubled, m)
+			appearances[m.HomeTeam]++
+			appearances[m.AwayTeam]++
+		}
+		want[m.HomeTeam] += k * cfg.Sport.points(m.HomeGoals, m.AwayGoals)
+		want[m.AwayTeam] += k * cfg.Sport.points(m.AwayGoals, m.HomeGoals)
+	}
+	standings, err := l.CalculateStandings()
+	if err != nil {
+		t.Fatalf("standings: %v", err)
+	}
+	for _, st := range standings {
+		if st.Points != want[st.TeamName] {
+			t.Errorf("%s: %d points, want %d", st.TeamName, st.Points, want[st.TeamName])
+		}
+	}
+
+	if len(doubled) == 0 {
+		t.Fatal("no matches in week 3")
+	}
+	most, fewest := cfg.Sport.pointsLeft(doubled)
+	for team, n := range appearances {
+		if most[team] != 2*n*cfg.Sport.WinPoints || fewest[team] != 2*n*cfg.Sport.LossPoints {
+			t.Errorf("%s: %d to %d points left in week 3", team, fewest[team], most[team])
+		}
+	}
+
+	cfg.Sport.PointMultipliers = map[int]int{0: 2}
+	if err := cfg.Validate(); err == nil {
+		t.Error("week 0 multiplier accepted")
+	}
+}
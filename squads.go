@@ -0,0 +1,238 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+)
+
+// A generated squad has goalkeepers, defenders, midfielders and forwards in
+// the numbers of the config's "squads" block, named from its name pools.
+// Ratings are on the strength scale and centred on the team's strength: the
+// first choices of a position spread around it, the backups a few points
+// below, and the further up the pitch the wider the spread, so forwards
+// range from poachers to passengers while keepers are all much alike.
+// Squads are drawn from the flavor stream and never change a result.
+
+const (
+	PositionGoalkeeper = "goalkeeper"
+	PositionDefender   = "defender"
+	PositionMidfielder = "midfielder"
+	PositionForward    = "forward"
+)
+
+// ErrSquadExists is returned when generating a squad for a team that has one
+var ErrSquadExists = errors.New("the team already has a squad, use replace=true")
+
+// positionProfile is how the ratings of one position are drawn
+type positionProfile struct {
+	position string
+	// starters play most weeks, the others are backups
+	starters int
+	// spread is the standard deviation around the team's strength
+	spread float64
+	// offset moves the position's first choices off the team's strength
+	offset float64
+}
+
+var positionProfiles = []positionProfile{
+	{PositionGoalkeeper, 1, 3, 0},
+	{PositionDefender, 4, 5, -1},
+	{PositionMidfielder, 4, 6, 0},
+	{PositionForward, 2, 8, 1},
+}
+
+// backupDrop is how far below the first choices a backup is drawn
+const backupDrop = 4
+
+// SquadConfig is the "squads" block of the config
+type SquadConfig struct {
+	Goalkeepers int      `json:"goalkeepers"`
+	Defenders   int      `json:"defenders"`
+	Midfielders int      `json:"midfielders"`
+	Forwards    int      `json:"forwards"`
+	FirstNames  []string `json:"first_names"`
+	LastNames   []string `json:"last_names"`
+}
+
+var defaultSquadConfig = SquadConfig{
+	Goalkeepers: 3,
+	Defenders:   8,
+	Midfielders: 8,
+	Forwards:    5,
+	FirstNames: []string{
+		"Adam", "Ben", "Carlos", "Daniel", "Emre", "Felix", "Gabriel", "Hugo", "Ivan", "Jonas",
+		"Kerem", "Luca", "Marco", "Nico", "Oscar", "Pedro", "Rafael", "Samuel", "Tomas", "Victor",
+	},
+	LastNames: []string{
+		"Almeida", "Berg", "Costa", "Demir", "Eriksen", "Fischer", "Garcia", "Hansen", "Ito", "Jensen",
+		"Kaya", "Lindqvist", "Martin", "Novak", "Okafor", "Petrov", "Rossi", "Silva", "Thomsen", "Usman",
+		"Varga", "Weber", "Yilmaz", "Zielinski", "Moreau", "Nowak", "Keller", "Duarte", "Sato", "Horvat",
+	},
+}
+
+func (s SquadConfig) Validate() error {
+	for _, n := range []int{s.Goalkeepers, s.Defenders, s.Midfielders, s.Forwards} {
+		if n < 0 || n > 30 {
+			return fmt.Errorf("players per position must be between 0 and 30, got %d", n)
+		}
+	}
+	if s.Goalkeepers < 1 {
+		return fmt.Errorf("a squad needs a goalkeeper")
+	}
+	if len(s.FirstNames) == 0 || len(s.LastNames) == 0 {
+		return fmt.Errorf("first_names and last_names cannot be empty")
+	}
+	return nil
+}
+
+// count is how many players of a position a squad has
+func (s SquadConfig) count(position string) int {
+	switch position {
+	case PositionGoalkeeper:
+		return s.Goalkeepers
+	case PositionDefender:
+		return s.Defenders
+	case PositionMidfielder:
+		return s.Midfielders
+	default:
+		return s.Forwards
+	}
+}
+
+// generateSquad draws a squad for a team of the given strength. Names are
+// unique within the squad; once the pools run out a number tells players
+// apart.
+func generateSquad(rng *rand.Rand, cfg SquadConfig, strength int) []Player {
+	var squad []Player
+	taken := make(map[string]bool)
+	for _, p := range positionProfiles {
+		for i := 0; i < cfg.count(p.position); i++ {
+			mean := float64(strength) + p.offset
+			if i >= p.starters {
+				mean -= backupDrop
+			}
+			name := cfg.FirstNames[rng.Intn(len(cfg.FirstNames))] + " " + cfg.LastNames[rng.Intn(len(cfg.LastNames))]
+			for n := 2; taken[name]; n++ {
+				name = fmt.Sprintf("%s %s %d", cfg.FirstNames[rng.Intn(len(cfg.FirstNames))], cfg.LastNames[rng.Intn(len(cfg.LastNames))], n)
+			}
+			taken[name] = true
+			squad = append(squad, Player{
+				Name:     name,
+				Position: p.position,
+				Rating:   clampStrength(mean + rng.NormFloat64()*p.spread),
+			})
+		}
+	}
+	return squad
+}
+
+// GenerateSquad gives a team a generated squad. A team with players keeps
+// them and gets ErrSquadExists unless replace is set; replaced players'
+// goals still count, without a name.
+func (l *League) GenerateSquad(teamName string, replace bool) ([]Player, error) {
+	id, current, err := l.resolveTeam(teamName)
+	if err != nil {
+		return nil, err
+	}
+	var strength int
+	for _, t := range l.Teams() {
+		if t.ID == id {
+			strength = t.Strength
+		}
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var existing int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM players WHERE team_id = ?", id).Scan(&existing); err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		if !replace {
+			return nil, fmt.Errorf("%w: %s has %d players", ErrSquadExists, current, existing)
+		}
+		if _, err := tx.Exec("DELETE FROM players WHERE team_id = ?", id); err != nil {
+			return nil, err
+		}
+	}
+
+	rng := rand.New(rand.NewSource(l.flavor.Int63()))
+	for _, p := range generateSquad(rng, l.config().Squads, strength) {
+		if _, err := tx.Exec("INSERT INTO players (team_id, name, position, rating) VALUES (?, ?, ?, ?)",
+			id, p.Name, p.Position, p.Rating); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return l.Players(current)
+}
+
+// GenerateSquads gives every team without players a generated squad and
+// returns how many teams got one
+func (l *League) GenerateSquads() (int, error) {
+	generated := 0
+	for _, t := range l.Teams() {
+		_, err := l.GenerateSquad(t.Name, false)
+		if errors.Is(err, ErrSquadExists) {
+			continue
+		}
+		if err != nil {
+			return generated, err
+		}
+		generated++
+	}
+	return generated, nil
+}
+
+// POST /teams/{name}/players/generate draws a squad for the team,
+// ?replace=true replaces the one it has (admin only)
+func (l *League) handleGenerateSquad(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	players, err := l.GenerateSquad(r.PathValue("name"), r.URL.Query().Get("replace") == "true")
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrSquadExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(players)
+}
+
+// POST /squads/generate draws a squad for every team without one (admin only)
+func (l *League) handleGenerateSquads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	generated, err := l.GenerateSquads()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]int{"teams": generated})
+}
@@ -0,0 +1,38 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestGenerateSquadEndpoint(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 2)
+	var players []insider.Player
+	if status := h.Do(http.MethodPost, "/teams/Alpha FC/players/generate", nil, true, &players); status != http.StatusOK {
+		t.Fatalf("generate: status %d", status)
+	}
+	if len(players) != 24 || players[0].Position == "" || players[0].Rating == 0 {
+		t.Errorf("generated %d players, first %+v", len(players), players[0])
+	}
+	if status := h.Do(http.MethodPost, "/teams/Alpha FC/players/generate", nil, true, nil); status != http.StatusConflict {
+		t.Errorf("generate over a squad: status %d, want 409", status)
+	}
+	if status := h.Do(http.MethodPost, "/teams/Alpha FC/players/generate?replace=true", nil, true, nil); status != http.StatusOK {
+		t.Errorf("replace a squad: status %d", status)
+	}
+
+	var generated map[string]int
+	if status := h.Do(http.MethodPost, "/squads/generate", nil, true, &generated); status != http.StatusOK {
+		t.Fatalf("generate all: status %d", status)
+	}
+	if generated["teams"] != len(insider.SnapshotTeams)-1 {
+		t.Errorf("%d teams got a squad, want %d", generated["teams"], len(insider.SnapshotTeams)-1)
+	}
+	h.Get("/teams/Delta SC/players", &players)
+	if len(players) != 24 {
+		t.Errorf("Delta SC has %d players", len(players))
+	}
+}
@@ -0,0 +1,43 @@
+package insider
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestGeneratedSquadShape(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	cfg := defaultSquadConfig
+	cfg.FirstNames, cfg.LastNames = []string{"Al"}, []string{"Bo", "Cy"}
+
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for run := 0; run < 200; run++ {
+		squad := generateSquad(rng, cfg, 70)
+		if len(squad) != 24 {
+			t.Fatalf("squad of %d players, want 24", len(squad))
+		}
+		names := make(map[string]bool)
+		for _, p := range squad {
+			if names[p.Name] {
+				t.Fatalf("%q twice in one squad", p.Name)
+			}
+			names[p.Name] = true
+			if checkStrength(p.Rating) != nil {
+				t.Fatalf("%s rated %d, off the scale", p.Name, p.Rating)
+			}
+			sums[p.Position] += float64(p.Rating)
+			counts[p.Position]++
+		}
+	}
+	if counts[PositionGoalkeeper] != 200*3 || counts[PositionForward] != 200*5 {
+		t.Errorf("positions %v", counts)
+	}
+	for position, sum := range sums {
+		// backups pull every position a little below the team's strength
+		if mean := sum / float64(counts[position]); math.Abs(mean-68) > 2 {
+			t.Errorf("%s average %.1f, want about 68", position, mean)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package insider_test
+
+import (
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+// The predicted, adjusted and handicap tables re-sort the league table, and
+// their ranks and zones have to follow their own order
+func TestDerivedTablesRankInOrder(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 5)
+	cfg := *h.League.Config()
+	cfg.Zones = []insider.Zone{{Name: "champions", From: 1, To: 1}, {Name: "relegation", From: 4, To: 4}}
+	h.League.StoreConfig(&cfg)
+	// the weakest side goes top of the handicap table
+	h.Post("/handicaps", map[string]int{"Delta SC": 100}, nil)
+	insider.PlayByStrength(t, h.League, 1, 2)
+
+	for _, path := range []string{"/predict?seed=3", "/standings?adjusted=true", "/standings?handicap=true"} {
+		var table []insider.Standing
+		h.Get(path, &table)
+		if len(table) != len(insider.SnapshotTeams) {
+			t.Fatalf("%s: %d rows", path, len(table))
+		}
+		for i, s := range table {
+			if i == 0 && s.Rank != 1 || i > 0 && s.Rank < table[i-1].Rank {
+				t.Errorf("%s: %s ranked %d in row %d", path, s.TeamName, s.Rank, i+1)
+			}
+			zone := ""
+			switch i + 1 {
+			case 1:
+				zone = "champions"
+			case 4:
+				zone = "relegation"
+			}
+			if s.Zone != zone {
+				t.Errorf("%s: %s in zone %q in row %d, want %q", path, s.TeamName, s.Zone, i+1, zone)
+			}
+		}
+	}
+
+	var handicap []insider.Standing
+	h.Get("/standings?handicap=true", &handicap)
+	if handicap[0].TeamName != "Delta SC" {
+		t.Errorf("handicap table led by %s, want Delta SC", handicap[0].TeamName)
+	}
+}
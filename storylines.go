@@ -0,0 +1,322 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Storylines are what the season is about right now: a close title race, a
+// relegation six-pointer coming up, a long unbeaten run. They are looked for
+// after every result; one that starts goes to the news and the webhooks, one
+// that no longer holds is ended with the week it ended in.
+
+// Storyline kinds
+const (
+	StorylineTitleRace   = "title_race"
+	StorylineSixPointer  = "relegation_six_pointer"
+	StorylineUnbeatenRun = "unbeaten_run"
+)
+
+const (
+	// titleRaceGap is the most points a contender can trail the leader by
+	titleRaceGap = 3
+	// sixPointerGap is how far above the relegation zone a team is still in
+	// the fight
+	sixPointerGap = 3
+	// unbeatenRunLength is the shortest run worth a storyline
+	unbeatenRunLength = 5
+)
+
+// Storyline is one storyline, EndedWeek is only set once it is over
+type Storyline struct {
+	ID          int       `json:"id"`
+	Kind        string    `json:"kind"`
+	Teams       []string  `json:"teams"`
+	MatchID     int       `json:"match_id,omitempty"`
+	Headline    string    `json:"headline"`
+	Active      bool      `json:"active"`
+	StartedWeek int       `json:"started_week"`
+	EndedWeek   int       `json:"ended_week,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// subject tells storylines of the same kind apart: a team or a match
+	subject string
+}
+
+func (l *League) createStorylineTable() error {
+	createStorylines := `
+	CREATE TABLE IF NOT EXISTS storylines (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		teams TEXT NOT NULL,
+		match_id INTEGER REFERENCES matches(id) ON DELETE CASCADE,
+		headline TEXT NOT NULL,
+		started_week INTEGER NOT NULL,
+		ended_week INTEGER,
+		updated_at TIMESTAMP NOT NULL
+	);`
+
+	if _, err := l.db.Exec(createStorylines); err != nil {
+		return fmt.Errorf("error creating storylines table: %v", err)
+	}
+	return nil
+}
+
+// storylines finds the storylines the results so far back. ids maps team
+// names to ids, which keep a team's storylines going through a rename.
+func (s *seasonState) storylines(relegation *Zone, ids map[string]int) []Storyline {
+	played, remaining := s.current()
+	if len(played) == 0 {
+		return nil
+	}
+	standings := s.standings(played)
+
+	var found []Storyline
+	if race := s.titleRace(standings, remaining, relegation); race != nil {
+		found = append(found, *race)
+	}
+	found = append(found, sixPointers(standings, remaining, relegation)...)
+
+	// runs in week order, a defeat starts over
+	runs := make(map[string]int)
+	for _, m := range played {
+		runs[m.HomeTeam], runs[m.AwayTeam] = runs[m.HomeTeam]+1, runs[m.AwayTeam]+1
+		switch {
+		case m.HomeGoals > m.AwayGoals:
+			runs[m.AwayTeam] = 0
+		case m.HomeGoals < m.AwayGoals:
+			runs[m.HomeTeam] = 0
+		}
+	}
+	for _, st := range standings {
+		if run := runs[st.TeamName]; run >= unbeatenRunLength {
+			found = append(found, Storyline{
+				Kind:     StorylineUnbeatenRun,
+				Teams:    []string{st.TeamName},
+				Headline: fmt.Sprintf("%s are unbeaten in %d games", st.TeamName, run),
+				subject:  "team:" + strconv.Itoa(ids[st.TeamName]),
+			})
+		}
+	}
+	return found
+}
+
+// titleRace is the leader and everyone within titleRaceGap points of them,
+// while the title is still open
+func (s *seasonState) titleRace(standings []Standing, remaining []Match, relegation *Zone) *Storyline {
+	if len(remaining) == 0 {
+		return nil
+	}
+	for _, c := range s.clinches(relegation) {
+		if c.kind == AnnouncementChampion {
+			return nil
+		}
+	}
+
+	leader := standings[0]
+	var teams []string
+	for _, st := range standings {
+		if leader.Points-st.Points <= titleRaceGap {
+			teams = append(teams, st.TeamName)
+		}
+	}
+	if len(teams) < 2 {
+		return nil
+	}
+
+	gap := fmt.Sprintf("%d points", leader.Points-standings[1].Points)
+	if gap == "1 points" {
+		gap = "a point"
+	}
+	headline := fmt.Sprintf("Title race: %s lead %s by %s", leader.TeamName, teams[1], gap)
+	switch {
+	case len(teams) > 2:
+		headline = fmt.Sprintf("Title race: %s separated by %d points or fewer", joinTeams(teams), titleRaceGap)
+	case leader.Points == standings[1].Points:
+		headline = fmt.Sprintf("Title race: %s level on %d points", joinTeams(teams), leader.Points)
+	}
+	return &Storyline{Kind: StorylineTitleRace, Teams: teams, Headline: headline}
+}
+
+// sixPointers are next week's matches between two teams in or just above the
+// relegation zone. Early on every team is close to it, so only the bottom
+// half of the table counts.
+func sixPointers(standings []Standing, remaining []Match, relegation *Zone) []Storyline {
+	if relegation == nil || relegation.From > len(standings) {
+		return nil
+	}
+	nextWeek := 0
+	for _, m := range remaining {
+		if !m.Postponed && (nextWeek == 0 || m.Week < nextWeek) {
+			nextWeek = m.Week
+		}
+	}
+
+	// the top of the zone sets the line
+	line := standings[relegation.From-1].Points
+	inFight := make(map[string]bool)
+	for i, st := range standings {
+		inFight[st.TeamName] = 2*i >= len(standings) && (i+1 >= relegation.From || st.Points-line <= sixPointerGap)
+	}
+
+	var found []Storyline
+	for _, m := range remaining {
+		if m.Week != nextWeek || m.Postponed || !inFight[m.HomeTeam] || !inFight[m.AwayTeam] {
+			continue
+		}
+		found = append(found, Storyline{
+			Kind:     StorylineSixPointer,
+			Teams:    []string{m.HomeTeam, m.AwayTeam},
+			MatchID:  m.ID,
+			Headline: fmt.Sprintf("Relegation six-pointer: %s v %s in week %d", m.HomeTeam, m.AwayTeam, m.Week),
+			subject:  "match:" + strconv.Itoa(m.ID),
+		})
+	}
+	return found
+}
+
+// joinTeams lists names as "A, B and C"
+func joinTeams(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// updateStorylines starts the storylines found, refreshes the headlines of
+// those still running and ends the rest. Every start writes a news item,
+// the latest of each kind per team, and every start or end is a webhook.
+func (l *League) updateStorylines() error {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]int)
+	for _, t := range l.Teams() {
+		ids[t.Name] = t.ID
+	}
+	found := state.storylines(l.relegationZone(), ids)
+	week := state.latestWeek()
+	now := time.Now().UTC()
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	running, err := queryStorylines(tx, true)
+	if err != nil {
+		return err
+	}
+	active := make(map[string]Storyline, len(running))
+	for _, s := range running {
+		active[s.Kind+"/"+s.subject] = s
+	}
+
+	var changed []Storyline
+	for _, s := range found {
+		teams, err := json.Marshal(s.Teams)
+		if err != nil {
+			return err
+		}
+		key := s.Kind + "/" + s.subject
+		if current, ok := active[key]; ok {
+			delete(active, key)
+			if current.Headline != s.Headline {
+				if _, err := tx.Exec("UPDATE storylines SET teams = ?, headline = ?, updated_at = ? WHERE id = ?",
+					string(teams), s.Headline, now, current.ID); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		s.Active, s.StartedWeek, s.UpdatedAt = true, week, now
+		res, err := tx.Exec(`
+			INSERT INTO storylines (kind, subject, teams, match_id, headline, started_week, updated_at)
+			VALUES (?, ?, ?, NULLIF(?, 0), ?, ?, ?)`,
+			s.Kind, s.subject, string(teams), s.MatchID, s.Headline, s.StartedWeek, s.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		s.ID = int(id)
+		if _, err := tx.Exec(
+			"INSERT OR REPLACE INTO announcements (kind, team_id, message, week, created_at) VALUES (?, ?, ?, ?, ?)",
+			s.Kind, ids[s.Teams[0]], s.Headline, week, now,
+		); err != nil {
+			return err
+		}
+		changed = append(changed, s)
+	}
+
+	for _, s := range active {
+		if _, err := tx.Exec("UPDATE storylines SET ended_week = ?, updated_at = ? WHERE id = ?", week, now, s.ID); err != nil {
+			return err
+		}
+		s.Active, s.EndedWeek, s.UpdatedAt = false, week, now
+		changed = append(changed, s)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, s := range changed {
+		l.notifyWebhooks(EventTypeStoryline, s)
+	}
+	return nil
+}
+
+// queryStorylines lists storylines newest first, only the active ones if
+// activeOnly is set
+func queryStorylines(q interface {
+	Query(string, ...any) (*sql.Rows, error)
+}, activeOnly bool) ([]Storyline, error) {
+	query := `
+		SELECT id, kind, subject, teams, COALESCE(match_id, 0), headline, started_week, ended_week, updated_at
+		FROM storylines`
+	if activeOnly {
+		query += " WHERE ended_week IS NULL"
+	}
+	rows, err := q.Query(query + " ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	storylines := []Storyline{}
+	for rows.Next() {
+		var s Storyline
+		var teams string
+		var ended sql.NullInt64
+		if err := rows.Scan(&s.ID, &s.Kind, &s.subject, &teams, &s.MatchID, &s.Headline, &s.StartedWeek, &ended, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(teams), &s.Teams); err != nil {
+			return nil, err
+		}
+		s.Active, s.EndedWeek = !ended.Valid, int(ended.Int64)
+		storylines = append(storylines, s)
+	}
+	return storylines, rows.Err()
+}
+
+// GET /storylines lists the active storylines, newest first; ?all=true adds
+// the ended ones
+func (l *League) handleStorylines(w http.ResponseWriter, r *http.Request) {
+	storylines, err := queryStorylines(l.db, r.URL.Query().Get("all") != "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(storylines)
+}
@@ -0,0 +1,53 @@
+package insider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStorylines(t *testing.T) {
+	result := func(id, week int, home, away string, homeGoals, awayGoals int) Match {
+		return Match{ID: id, Week: week, HomeTeam: home, AwayTeam: away, HomeGoals: homeGoals, AwayGoals: awayGoals, Played: true}
+	}
+	state := &seasonState{
+		teams: []string{"Alpha FC", "Bravo United", "Charlie Town", "Delta SC"},
+		sport: defaultSport,
+		matches: []Match{
+			result(1, 1, "Alpha FC", "Delta SC", 2, 0),
+			result(2, 1, "Bravo United", "Charlie Town", 1, 0),
+			result(3, 2, "Alpha FC", "Charlie Town", 1, 1),
+			result(4, 2, "Delta SC", "Bravo United", 0, 0),
+			result(5, 3, "Alpha FC", "Bravo United", 0, 0),
+			result(6, 3, "Charlie Town", "Delta SC", 1, 1),
+			result(7, 4, "Delta SC", "Alpha FC", 0, 3),
+			result(8, 4, "Charlie Town", "Bravo United", 0, 2),
+			result(9, 5, "Charlie Town", "Alpha FC", 1, 1),
+			result(10, 5, "Bravo United", "Delta SC", 2, 0),
+			{ID: 11, Week: 6, HomeTeam: "Bravo United", AwayTeam: "Alpha FC"},
+			{ID: 12, Week: 6, HomeTeam: "Delta SC", AwayTeam: "Charlie Town"},
+		},
+	}
+	ids := map[string]int{"Alpha FC": 1, "Bravo United": 2, "Charlie Town": 3, "Delta SC": 4}
+
+	// Bravo 11, Alpha 9, Charlie 3, Delta 2
+	var got []string
+	for _, s := range state.storylines(&Zone{Name: "relegation", From: 4, To: 4}, ids) {
+		got = append(got, s.Kind+": "+s.Headline)
+	}
+	want := []string{
+		"title_race: Title race: Bravo United lead Alpha FC by 2 points",
+		"relegation_six_pointer: Relegation six-pointer: Delta SC v Charlie Town in week 6",
+		"unbeaten_run: Bravo United are unbeaten in 5 games",
+		"unbeaten_run: Alpha FC are unbeaten in 5 games",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("storylines\ngot:  %q\nwant: %q", got, want)
+	}
+
+	// without a relegation zone there are no six-pointers
+	for _, s := range state.storylines(nil, ids) {
+		if s.Kind == StorylineSixPointer {
+			t.Errorf("six-pointer without a relegation zone: %s", s.Headline)
+		}
+	}
+}
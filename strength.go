@@ -0,0 +1,255 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Strengths are edited over the API by admins. Every edit is kept with who
+// made it and when, so a team can be put back to what it was at an earlier
+// point; the rollback is an edit of its own and can be rolled back too.
+
+// ErrInvalidRollback is returned when a rollback names no point to go back to
+var ErrInvalidRollback = errors.New("invalid rollback")
+
+// StrengthChange is one edit of a team's strength
+type StrengthChange struct {
+	ID          int       `json:"id"`
+	Team        string    `json:"team"`
+	OldStrength int       `json:"old_strength"`
+	NewStrength int       `json:"new_strength"`
+	Author      string    `json:"author"`
+	Reason      string    `json:"reason,omitempty"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
+
+// StrengthRollback names the point to go back to: before the change with
+// ChangeID, or as things stood At
+type StrengthRollback struct {
+	ChangeID int        `json:"change_id,omitempty"`
+	At       *time.Time `json:"at,omitempty"`
+}
+
+func (l *League) createStrengthChangeTable() error {
+	createChanges := `
+	CREATE TABLE IF NOT EXISTS strength_changes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+		old_strength INTEGER NOT NULL,
+		new_strength INTEGER NOT NULL,
+		author TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		changed_at TIMESTAMP NOT NULL
+	);`
+
+	if _, err := l.db.Exec(createChanges); err != nil {
+		return fmt.Errorf("error creating strength_changes table: %v", err)
+	}
+	return nil
+}
+
+// SetStrength changes a team's strength and records the change. Setting the
+// strength it already has records nothing and returns nil.
+func (l *League) SetStrength(name string, strength int, author, reason string) (*StrengthChange, error) {
+	if err := checkStrength(strength); err != nil {
+		return nil, err
+	}
+	id, current, err := l.resolveTeam(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	c := &StrengthChange{Team: current, NewStrength: strength, Author: author, Reason: reason, ChangedAt: l.clock.Now().UTC()}
+	if err := tx.QueryRow("SELECT strength FROM teams WHERE id = ?", id).Scan(&c.OldStrength); err != nil {
+		return nil, err
+	}
+	if c.OldStrength == strength {
+		return nil, nil
+	}
+	res, err := tx.Exec("INSERT INTO strength_changes (team_id, old_strength, new_strength, author, reason, changed_at) VALUES (?, ?, ?, ?, ?, ?)",
+		id, c.OldStrength, c.NewStrength, c.Author, c.Reason, c.ChangedAt)
+	if err != nil {
+		return nil, err
+	}
+	changeID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	c.ID = int(changeID)
+	if _, err := tx.Exec("UPDATE teams SET strength = ? WHERE id = ?", strength, id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	l.touch()
+	return c, l.loadTeams()
+}
+
+// StrengthHistory lists the edits of a team's strength, newest first
+func (l *League) StrengthHistory(name string) (string, []StrengthChange, error) {
+	id, current, err := l.resolveTeam(name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	rows, err := l.db.Query(`
+		SELECT id, old_strength, new_strength, author, reason, changed_at
+		FROM strength_changes WHERE team_id = ?
+		ORDER BY id DESC`, id)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+
+	history := []StrengthChange{}
+	for rows.Next() {
+		c := StrengthChange{Team: current}
+		if err := rows.Scan(&c.ID, &c.OldStrength, &c.NewStrength, &c.Author, &c.Reason, &c.ChangedAt); err != nil {
+			return "", nil, err
+		}
+		history = append(history, c)
+	}
+	return current, history, rows.Err()
+}
+
+// RollbackStrength puts a team's strength back to what it was before a
+// change, or at a point in time. A change of another team is not found.
+func (l *League) RollbackStrength(name string, to StrengthRollback, author string) (*StrengthChange, error) {
+	if (to.ChangeID == 0) == (to.At == nil) {
+		return nil, fmt.Errorf("%w: give either change_id or at", ErrInvalidRollback)
+	}
+	id, _, err := l.resolveTeam(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var strength int
+	var reason string
+	if to.ChangeID != 0 {
+		err = l.db.QueryRow("SELECT old_strength FROM strength_changes WHERE id = ? AND team_id = ?", to.ChangeID, id).Scan(&strength)
+		reason = fmt.Sprintf("rollback to before change %d", to.ChangeID)
+	} else {
+		// the first change after the point still knows what it replaced
+		err = l.db.QueryRow(`
+			SELECT old_strength FROM strength_changes
+			WHERE team_id = ? AND changed_at > ?
+			ORDER BY id LIMIT 1`, id, to.At.UTC()).Scan(&strength)
+		if err == sql.ErrNoRows {
+			err = l.db.QueryRow("SELECT strength FROM teams WHERE id = ?", id).Scan(&strength)
+		}
+		reason = "rollback to " + to.At.UTC().Format(time.RFC3339)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l.SetStrength(name, strength, author, reason)
+}
+
+// requestAuthor names who made an admin request in the history
+func requestAuthor(r *http.Request) string {
+	if t := requestAPIToken(r); t != nil {
+		return "token:" + t.Name
+	}
+	return "admin"
+}
+
+func writeStrengthError(w http.ResponseWriter, err error) {
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "Team or change not found", http.StatusNotFound)
+	case errors.Is(err, ErrInvalidStrength), errors.Is(err, ErrInvalidRollback):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GET /teams/{name}/strength shows the strength and its edits, POST changes
+// it (admin only): {"strength": 72, "reason": "new signing"}
+func (l *League) handleTeamStrength(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		current, history, err := l.StrengthHistory(r.PathValue("name"))
+		if err != nil {
+			writeStrengthError(w, err)
+			return
+		}
+		strength := 0
+		for _, t := range l.Teams() {
+			if t.Name == current {
+				strength = t.Strength
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"team":     current,
+			"strength": strength,
+			"history":  history,
+		})
+	case http.MethodPost:
+		if !requireAdmin(w, r) {
+			return
+		}
+		var body struct {
+			Strength int    `json:"strength"`
+			Reason   string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		change, err := l.SetStrength(r.PathValue("name"), body.Strength, requestAuthor(r), body.Reason)
+		if err != nil {
+			writeStrengthError(w, err)
+			return
+		}
+		if change == nil {
+			json.NewEncoder(w).Encode(map[string]string{"message": "Strength unchanged"})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(change)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// POST /teams/{name}/strength/rollback puts the strength back (admin only):
+// {"change_id": 3} to before that change, {"at": "2025-09-01T00:00:00Z"} to
+// what it was then
+func (l *League) handleStrengthRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	var to StrengthRollback
+	if err := json.NewDecoder(r.Body).Decode(&to); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	change, err := l.RollbackStrength(r.PathValue("name"), to, requestAuthor(r))
+	if err != nil {
+		writeStrengthError(w, err)
+		return
+	}
+	if change == nil {
+		json.NewEncoder(w).Encode(map[string]string{"message": "Strength unchanged"})
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(change)
+}
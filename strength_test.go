@@ -0,0 +1,84 @@
+package insider_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+// manualClock is a Clock the test moves by hand
+type manualClock struct {
+	t time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.t
+}
+
+func TestStrengthHistoryAndRollback(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 1)
+	clock := &manualClock{t: time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)}
+	h.League.SetClock(clock)
+
+	var first, second insider.StrengthChange
+	if status := h.Do(http.MethodPost, "/teams/Delta SC/strength", map[string]any{"strength": 65, "reason": "new signing"}, true, &first); status != http.StatusCreated {
+		t.Fatalf("first edit: status %d", status)
+	}
+	clock.t = clock.t.Add(24 * time.Hour)
+	if status := h.Do(http.MethodPost, "/teams/Delta SC/strength", map[string]any{"strength": 40}, true, &second); status != http.StatusCreated {
+		t.Fatalf("second edit: status %d", status)
+	}
+	if first.OldStrength != 50 || second.OldStrength != 65 || second.Author != "admin" {
+		t.Errorf("changes: %+v, %+v", first, second)
+	}
+	if status := h.Do(http.MethodPost, "/teams/Delta SC/strength", map[string]any{"strength": 0}, true, nil); status != http.StatusBadRequest {
+		t.Errorf("strength 0: status %d, want 400", status)
+	}
+	if status := h.Do(http.MethodPost, "/teams/Delta SC/strength", map[string]any{"strength": 70}, false, nil); status != http.StatusUnauthorized {
+		t.Errorf("edit without admin: status %d, want 401", status)
+	}
+
+	strengthOf := func() int {
+		for _, team := range h.League.Teams() {
+			if team.Name == "Delta SC" {
+				return team.Strength
+			}
+		}
+		return 0
+	}
+	if got := strengthOf(); got != 40 {
+		t.Fatalf("strength %d, want 40", got)
+	}
+
+	// back to the evening of the first edit, then to before it
+	at := time.Date(2025, 9, 1, 20, 0, 0, 0, time.UTC)
+	clock.t = clock.t.Add(time.Hour)
+	if status := h.Do(http.MethodPost, "/teams/Delta SC/strength/rollback", insider.StrengthRollback{At: &at}, true, nil); status != http.StatusCreated {
+		t.Fatalf("rollback to time: status %d", status)
+	}
+	if got := strengthOf(); got != 65 {
+		t.Errorf("after rollback to %v: strength %d, want 65", at, got)
+	}
+	if status := h.Do(http.MethodPost, "/teams/Delta SC/strength/rollback", insider.StrengthRollback{ChangeID: first.ID}, true, nil); status != http.StatusCreated {
+		t.Fatalf("rollback to change: status %d", status)
+	}
+	if got := strengthOf(); got != 50 {
+		t.Errorf("after rollback of change %d: strength %d, want 50", first.ID, got)
+	}
+	if status := h.Do(http.MethodPost, "/teams/Alpha FC/strength/rollback", insider.StrengthRollback{ChangeID: first.ID}, true, nil); status != http.StatusNotFound {
+		t.Errorf("rollback of another team's change: status %d, want 404", status)
+	}
+
+	var view struct {
+		Strength int                      `json:"strength"`
+		History  []insider.StrengthChange `json:"history"`
+	}
+	h.Get("/teams/Delta SC/strength", &view)
+	if view.Strength != 50 || len(view.History) != 4 || view.History[0].Reason != "rollback to before change "+strconv.Itoa(first.ID) {
+		t.Errorf("history: %+v", view)
+	}
+}
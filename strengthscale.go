@@ -0,0 +1,55 @@
+package insider
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Strengths are on a scale of MinStrength to MaxStrength. The default teams
+// sit between 50 and 85 and the simulation is tuned for that range: with the
+// default strength_per_goal of 20 the scale spans six goal ranges in the
+// uniform model, so sides closer than 20 can play as equals there, while the
+// Poisson model tells every point apart. Teams files, API edits and Elo
+// updates are all held to the scale; ratings on a scale of their own are
+// rescaled onto it with NormalizeStrengths.
+const (
+	MinStrength = 1
+	MaxStrength = 100
+)
+
+// ErrInvalidStrength is returned for a strength off the scale
+var ErrInvalidStrength = errors.New("strength must be between 1 and 100")
+
+// checkStrength holds a strength to the scale
+func checkStrength(strength int) error {
+	if strength < MinStrength || strength > MaxStrength {
+		return fmt.Errorf("%w, got %d", ErrInvalidStrength, strength)
+	}
+	return nil
+}
+
+// clampStrength rounds a computed strength onto the scale
+func clampStrength(strength float64) int {
+	return min(MaxStrength, max(MinStrength, int(math.Round(strength))))
+}
+
+// NormalizeStrengths rescales ratings linearly so the lowest gets lo and the
+// highest hi, both on the scale. Only the order and the relative gaps of the
+// ratings carry over; when they are all level every team gets the middle.
+func NormalizeStrengths(ratings []float64, lo, hi int) []int {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, r := range ratings {
+		low, high = math.Min(low, r), math.Max(high, r)
+	}
+
+	strengths := make([]int, len(ratings))
+	for i, r := range ratings {
+		if high == low {
+			strengths[i] = clampStrength(float64(lo+hi) / 2)
+			continue
+		}
+		strengths[i] = clampStrength(float64(lo) + (r-low)/(high-low)*float64(hi-lo))
+	}
+	return strengths
+}
@@ -0,0 +1,83 @@
+package insider
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeStrengths(t *testing.T) {
+	got := NormalizeStrengths([]float64{1850, 1500, 2000, 1700}, 40, 90)
+	want := []int{75, 40, 90, 60}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("NormalizeStrengths = %v, want %v", got, want)
+		}
+	}
+	if level := NormalizeStrengths([]float64{7, 7}, 40, 90); level[0] != 65 || level[1] != 65 {
+		t.Errorf("level ratings = %v, want the middle of the range", level)
+	}
+
+	// points:N keeps the gaps and holds the outliers at the ends of the scale
+	scale, err := parseEloScale("points:1")
+	if err != nil {
+		t.Fatalf("parse scale: %v", err)
+	}
+	if got := scale.strengths([]float64{0, 200, 100}); got[0] != MinStrength || got[1] != MaxStrength || got[2] != eloAnchor {
+		t.Errorf("points:1 = %v", got)
+	}
+	if _, err := parseEloScale("linear:0-120"); err == nil {
+		t.Error("a range off the scale was accepted")
+	}
+}
+
+func TestStrengthsHeldToTheScale(t *testing.T) {
+	for _, strength := range []int{0, MaxStrength + 1} {
+		teams := []Team{{Name: "A", Strength: 50}, {Name: "B", Strength: strength}}
+		if err := validateTeams(teams); !errors.Is(err, ErrInvalidStrength) {
+			t.Errorf("strength %d: err = %v, want ErrInvalidStrength", strength, err)
+		}
+	}
+	l := newTestLeague(t, snapshotTeams, 6, 1)
+	if _, err := l.SetStrength("Alpha FC", MaxStrength+1, "admin", ""); !errors.Is(err, ErrInvalidStrength) {
+		t.Errorf("SetStrength over the scale: err = %v", err)
+	}
+	if _, err := l.SetStrength("Alpha FC", MaxStrength, "admin", ""); err != nil {
+		t.Errorf("SetStrength at the top of the scale: %v", err)
+	}
+}
+
+// The models have to make sense over the whole scale: more strength never
+// hurts, and the ends of the scale are far apart
+func TestModelsAcrossTheScale(t *testing.T) {
+	for _, model := range []string{"uniform", "poisson"} {
+		p := defaultSimParams
+		p.Model = model
+		lastHome, lastAway := -1.0, 2.0
+		levels := make(map[float64]bool)
+		for strength := MinStrength; strength <= MaxStrength; strength++ {
+			home, draw, away := p.Probabilities(strength, 50)
+			if total := home + draw + away; total < 0.999 || total > 1.001 {
+				t.Fatalf("%s: strength %d: odds add up to %f", model, strength, total)
+			}
+			if home < lastHome-1e-9 || away > lastAway+1e-9 {
+				t.Errorf("%s: strength %d does worse than %d", model, strength, strength-1)
+			}
+			lastHome, lastAway = home, away
+			levels[home] = true
+		}
+
+		strongest, _, _ := p.Probabilities(MaxStrength, MinStrength)
+		_, _, upset := p.Probabilities(MinStrength, MaxStrength)
+		if strongest < 0.8 || upset < 0.8 {
+			t.Errorf("%s: the top of the scale beats the bottom %.2f at home, %.2f away", model, strongest, upset)
+		}
+		// uniform sees a step every strength_per_goal, Poisson every point
+		want := MaxStrength / p.StrengthPerGoal
+		if model == "poisson" {
+			want = MaxStrength - MinStrength
+		}
+		if len(levels) < want {
+			t.Errorf("%s: %d distinct odds over the scale, want at least %d", model, len(levels), want)
+		}
+	}
+}
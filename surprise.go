@@ -0,0 +1,194 @@
+package insider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Every result is annotated with the odds the model gave before the match:
+// home win, draw and away win, from the strengths and the home advantage
+// learned from the weeks before. The odds are stored once and stay as they
+// were; a corrected score is judged against them again. The surprise of a
+// result is one minus the probability of the outcome it had. The points each
+// side was expected to take are stored with the odds, for the
+// overperformance table. Results decided off the pitch are not annotated.
+
+// MatchSurprise is a played match with its pre-match odds
+type MatchSurprise struct {
+	MatchID   int     `json:"match_id"`
+	Week      int     `json:"week"`
+	HomeTeam  string  `json:"home_team"`
+	AwayTeam  string  `json:"away_team"`
+	HomeGoals int     `json:"home_goals"`
+	AwayGoals int     `json:"away_goals"`
+	HomeWin   float64 `json:"home_win"`
+	Draw      float64 `json:"draw"`
+	AwayWin   float64 `json:"away_win"`
+	// ResultProbability is the pre-match probability of the outcome
+	ResultProbability float64 `json:"result_probability"`
+	Surprise          float64 `json:"surprise"`
+	// Upset is a win for the side less likely to win
+	Upset bool `json:"upset"`
+}
+
+func (l *League) createMatchProbabilityTable() error {
+	createProbabilities := `
+	CREATE TABLE IF NOT EXISTS match_probabilities (
+		match_id INTEGER PRIMARY KEY REFERENCES matches(id) ON DELETE CASCADE,
+		home_win REAL NOT NULL,
+		draw REAL NOT NULL,
+		away_win REAL NOT NULL,
+		home_expected_points REAL,
+		away_expected_points REAL,
+		created_at TIMESTAMP NOT NULL
+	);`
+
+	if _, err := l.db.Exec(createProbabilities); err != nil {
+		return fmt.Errorf("error creating match_probabilities table: %v", err)
+	}
+	if err := l.addColumnIfMissing("match_probabilities", "home_expected_points", "REAL"); err != nil {
+		return err
+	}
+	return l.addColumnIfMissing("match_probabilities", "away_expected_points", "REAL")
+}
+
+// annotateResults stores the pre-match odds of every played match that has
+// none yet
+func (l *League) annotateResults() error {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return err
+	}
+	known := make(map[int]bool)
+	rows, err := l.db.Query("SELECT match_id FROM match_probabilities")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		known[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	cfg := l.config()
+	// home advantages as they were going into each week
+	advantages := make(map[int]homeAdvantages)
+	now := time.Now().UTC()
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, m := range state.matches {
+		if !m.Played || m.Administrative == AdminAwarded || known[m.ID] {
+			continue
+		}
+		home, ok := advantages[m.Week]
+		if !ok {
+			before, _ := state.split(m.Week - 1)
+			home = newHomeAdvantages(cfg.HomeAdvantages.resolve(cfg.Simulation, state.teams, state.strengths, before))
+			advantages[m.Week] = home
+		}
+		homeWin, draw, awayWin := home.params(cfg.Simulation, m.HomeTeam).Probabilities(state.strengths[m.HomeTeam], state.strengths[m.AwayTeam])
+		k := float64(cfg.Sport.multiplier(m.Week))
+		homeExpected, awayExpected := k*cfg.Sport.expectedPoints(homeWin, draw, awayWin), k*cfg.Sport.expectedPoints(awayWin, draw, homeWin)
+		if _, err := tx.Exec(`INSERT INTO match_probabilities (match_id, home_win, draw, away_win, home_expected_points, away_expected_points, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			m.ID, homeWin, draw, awayWin, homeExpected, awayExpected, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Surprises lists the annotated results, most surprising first; week 0 is
+// every week
+func (l *League) Surprises(week int) ([]MatchSurprise, error) {
+	query := `
+		SELECT m.id, m.week, h.name, a.name, m.home_goals, m.away_goals, p.home_win, p.draw, p.away_win
+		FROM match_probabilities p
+		JOIN matches m ON m.id = p.match_id
+		JOIN teams h ON h.id = m.home_team_id
+		JOIN teams a ON a.id = m.away_team_id
+		WHERE m.played AND COALESCE(m.administrative, '') = ''`
+	var args []any
+	if week != 0 {
+		query += " AND m.week = ?"
+		args = append(args, week)
+	}
+	rows, err := l.db.Query(query+" ORDER BY m.week, m.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	surprises := []MatchSurprise{}
+	for rows.Next() {
+		var s MatchSurprise
+		if err := rows.Scan(&s.MatchID, &s.Week, &s.HomeTeam, &s.AwayTeam, &s.HomeGoals, &s.AwayGoals, &s.HomeWin, &s.Draw, &s.AwayWin); err != nil {
+			return nil, err
+		}
+		switch {
+		case s.HomeGoals > s.AwayGoals:
+			s.ResultProbability, s.Upset = s.HomeWin, s.HomeWin < s.AwayWin
+		case s.HomeGoals < s.AwayGoals:
+			s.ResultProbability, s.Upset = s.AwayWin, s.AwayWin < s.HomeWin
+		default:
+			s.ResultProbability = s.Draw
+		}
+		s.Surprise = 1 - s.ResultProbability
+		surprises = append(surprises, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(surprises, func(i, j int) bool {
+		return surprises[i].Surprise > surprises[j].Surprise
+	})
+	return surprises, nil
+}
+
+// upsetOfTheWeek is the most surprising upset of a week, nil when the
+// favourites never lost
+func (l *League) upsetOfTheWeek(week int) (*MatchSurprise, error) {
+	surprises, err := l.Surprises(week)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range surprises {
+		if s.Upset {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+// GET /surprises lists the results by how surprising they were, ?week=n
+// only that week's
+func (l *League) handleSurprises(w http.ResponseWriter, r *http.Request) {
+	week := 0
+	if s := r.URL.Query().Get("week"); s != "" {
+		var err error
+		if week, err = strconv.Atoi(s); err != nil || week < 1 {
+			http.Error(w, "Invalid week parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	surprises, err := l.Surprises(week)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(surprises)
+}
@@ -0,0 +1,43 @@
+package insider
+
+import "testing"
+
+func TestSurprisesKeepPreMatchOdds(t *testing.T) {
+	league := newTestLeague(t, snapshotTeams, 6, 7)
+	if err := league.SimulateWeek(1); err != nil {
+		t.Fatalf("simulate week 1: %v", err)
+	}
+	surprises, err := league.Surprises(1)
+	if err != nil {
+		t.Fatalf("surprises: %v", err)
+	}
+	if len(surprises) != len(snapshotTeams)/2 {
+		t.Fatalf("%d results annotated, want %d", len(surprises), len(snapshotTeams)/2)
+	}
+	for i, s := range surprises {
+		if total := s.HomeWin + s.Draw + s.AwayWin; total < 0.999 || total > 1.001 {
+			t.Errorf("match %d: odds add up to %f", s.MatchID, total)
+		}
+		if i > 0 && s.Surprise > surprises[i-1].Surprise {
+			t.Errorf("match %d is more surprising than the one before it", s.MatchID)
+		}
+	}
+
+	// a corrected score is judged against the same odds
+	s := surprises[0]
+	if err := league.UpdateMatchResult(s.MatchID, 0, 5, nil); err != nil {
+		t.Fatalf("update result: %v", err)
+	}
+	after, err := league.Surprises(1)
+	if err != nil {
+		t.Fatalf("surprises: %v", err)
+	}
+	for _, a := range after {
+		if a.MatchID != s.MatchID {
+			continue
+		}
+		if a.HomeWin != s.HomeWin || a.AwayWin != s.AwayWin || a.ResultProbability != a.AwayWin || a.Upset != (a.AwayWin < a.HomeWin) {
+			t.Errorf("after the correction: %+v, odds were %+v", a, s)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package insider
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// defaultTeams are used when no --teams file is given
+var defaultTeams = []Team{
+	{Name: "Alpha FC", Strength: 85},
+	{Name: "Bravo United", Strength: 70},
+	{Name: "Charlie Town", Strength: 60},
+	{Name: "Delta SC", Strength: 50},
+}
+
+// DefaultTeams is a copy of the teams the server starts with without --teams
+func DefaultTeams() []Team {
+	return slices.Clone(defaultTeams)
+}
+
+// LoadTeams reads a team list from a .json or .csv file.
+//
+// CSV files need a header row with "name" and "strength" columns; every other
+// column is kept as team metadata. JSON files hold an array of teams. With a
+// scale spec (as for --elo-scale) the strengths in the file are ratings of
+// their own and get rescaled onto the strength scale; without one they must
+// already be on it.
+func LoadTeams(path, scaleSpec string) ([]Team, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var teams []Team
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.NewDecoder(f).Decode(&teams); err != nil {
+			return nil, fmt.Errorf("error decoding %s: %v", path, err)
+		}
+	case ".csv":
+		teams, err = readTeamsCSV(f)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported teams file %s, expected .json or .csv", path)
+	}
+
+	if scaleSpec != "" {
+		scale, err := parseEloScale(scaleSpec)
+		if err != nil {
+			return nil, err
+		}
+		ratings := make([]float64, len(teams))
+		for i, t := range teams {
+			ratings[i] = float64(t.Strength)
+		}
+		for i, strength := range scale.strengths(ratings) {
+			teams[i].Strength = strength
+		}
+	}
+
+	if err := validateTeams(teams); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+func readTeamsCSV(r io.Reader) ([]Team, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header row: %v", err)
+	}
+	nameCol, strengthCol := -1, -1
+	for i, col := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(col))
+		switch header[i] {
+		case "name":
+			nameCol = i
+		case "strength":
+			strengthCol = i
+		}
+	}
+	if nameCol < 0 || strengthCol < 0 {
+		return nil, fmt.Errorf("header must contain name and strength columns")
+	}
+
+	var teams []Team
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		strength, err := strconv.Atoi(strings.TrimSpace(record[strengthCol]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid strength %q", line, record[strengthCol])
+		}
+		team := Team{Name: strings.TrimSpace(record[nameCol]), Strength: strength}
+		for i, value := range record {
+			if i == nameCol || i == strengthCol || value == "" {
+				continue
+			}
+			if team.Metadata == nil {
+				team.Metadata = make(map[string]string)
+			}
+			team.Metadata[header[i]] = value
+		}
+		teams = append(teams, team)
+	}
+	return teams, nil
+}
+
+func validateTeams(teams []Team) error {
+	if len(teams) < 2 {
+		return fmt.Errorf("a league needs at least 2 teams, got %d", len(teams))
+	}
+	seen := make(map[string]bool)
+	for _, team := range teams {
+		if team.Name == "" {
+			return fmt.Errorf("team name cannot be empty")
+		}
+		if seen[team.Name] {
+			return fmt.Errorf("duplicate team %q", team.Name)
+		}
+		if err := checkStrength(team.Strength); err != nil {
+			return fmt.Errorf("team %q: %w", team.Name, err)
+		}
+		seen[team.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+
+	"insider/matchengine"
+)
+
+// Two-legged ties are played on demand between league teams and are not
+// stored; the cup in cup.go is single legs. The first team hosts the first
+// leg.
+
+// extra time is thirty minutes, extraTimeShare scales the goal model down
+const (
+	extraTimeShare   = 3
+	extraTimeMinutes = 30
+)
+
+// maxTies caps the ties played in one request
+const maxTies = 64
+
+// how a tie was decided
+const (
+	DecidedByAggregate          = "aggregate"
+	DecidedByAwayGoals          = "away_goals"
+	DecidedByExtraTime          = "extra_time"
+	DecidedByExtraTimeAwayGoals = "extra_time_away_goals"
+	DecidedByPenalties          = "penalties"
+)
+
+// ErrInvalidTie is returned for a tie that cannot be played
+var ErrInvalidTie = errors.New("invalid tie")
+
+// ExtraTime is the score of the thirty extra minutes of the second leg
+type ExtraTime struct {
+	HomeGoals int `json:"home_goals"`
+	AwayGoals int `json:"away_goals"`
+}
+
+// Leg is one match of a tie. HomeGoals and AwayGoals are after normal time.
+type Leg struct {
+	HomeTeam  string     `json:"home_team"`
+	AwayTeam  string     `json:"away_team"`
+	HomeGoals int        `json:"home_goals"`
+	AwayGoals int        `json:"away_goals"`
+	ExtraTime *ExtraTime `json:"extra_time,omitempty"`
+	// Events are kept when the sport tracks them, extra time included
+	Events []MatchEvent `json:"events,omitempty"`
+}
+
+// PenaltyKick is one kick of a shootout
+type PenaltyKick struct {
+	Team   string `json:"team"`
+	Scored bool   `json:"scored"`
+}
+
+// Shootout ends a tie still level after extra time. The home side of the
+// second leg kicks first.
+type Shootout struct {
+	FirstGoals  int           `json:"first_goals"`
+	SecondGoals int           `json:"second_goals"`
+	Kicks       []PenaltyKick `json:"kicks"`
+}
+
+// TieResolution is a two-legged tie and how it was won
+type TieResolution struct {
+	First         string    `json:"first"`
+	Second        string    `json:"second"`
+	AwayGoalsRule bool      `json:"away_goals_rule"`
+	Legs          [2]Leg    `json:"legs"`
+	Aggregate     [2]int    `json:"aggregate"`
+	AwayGoals     [2]int    `json:"away_goals_scored"`
+	Shootout      *Shootout `json:"shootout,omitempty"`
+	DecidedBy     string    `json:"decided_by"`
+	Winner        string    `json:"winner"`
+}
+
+// tally adds up both legs, extra time included, from the first team's side
+func (t *TieResolution) tally() {
+	first, second := t.Legs[0], t.Legs[1]
+	t.Aggregate = [2]int{first.HomeGoals + second.AwayGoals, first.AwayGoals + second.HomeGoals}
+	t.AwayGoals = [2]int{second.AwayGoals, first.AwayGoals}
+	if et := second.ExtraTime; et != nil {
+		t.Aggregate[0] += et.AwayGoals
+		t.Aggregate[1] += et.HomeGoals
+		t.AwayGoals[0] += et.AwayGoals
+	}
+}
+
+// decide settles the tie on the current tally if it can. Away goals count
+// in extra time as well when the rule is on.
+func (t *TieResolution) decide(byGoals, byAwayGoals string) bool {
+	t.tally()
+	winner := func(scores [2]int) string {
+		switch {
+		case scores[0] > scores[1]:
+			return t.First
+		case scores[1] > scores[0]:
+			return t.Second
+		}
+		return ""
+	}
+	if w := winner(t.Aggregate); w != "" {
+		t.Winner, t.DecidedBy = w, byGoals
+		return true
+	}
+	if w := winner(t.AwayGoals); t.AwayGoalsRule && w != "" {
+		t.Winner, t.DecidedBy = w, byAwayGoals
+		return true
+	}
+	return false
+}
+
+// tieEngine plays the matches of ties, cup ties and playoffs through the
+// match engine, results from rng and events from flavor. A level match stays
+// level whatever the sport: extra time and penalties settle it instead of
+// the sport's overtime.
+type tieEngine struct {
+	cfg         *Config
+	rng, flavor *rand.Rand
+}
+
+// play plays normal time with params already set for the home side
+func (e tieEngine) play(params SimParams, home, away Team) (homeGoals, awayGoals int, events []MatchEvent) {
+	params.Overtime = false
+	engine := matchengine.Engine{
+		Params:       params,
+		Minutes:      e.cfg.Sport.MatchMinutes,
+		VARFrequency: e.cfg.VARFrequency,
+		Rand:         e.rng,
+		Flavor:       e.flavor,
+	}
+	result := engine.Play(home.Strength, away.Strength)
+	return result.HomeGoals, result.AwayGoals, e.events(home, away, result.Events, 0)
+}
+
+// extraTime plays thirty more minutes with params already set for the home
+// side; its events carry on from the last minute of normal time
+func (e tieEngine) extraTime(params SimParams, home, away Team) (*ExtraTime, []MatchEvent) {
+	params.Overtime = false
+	params.StrengthPerGoal *= extraTimeShare
+	params.BaseScore = 0
+	engine := matchengine.Engine{Params: params, Minutes: extraTimeMinutes, Rand: e.rng, Flavor: e.flavor}
+	result := engine.Play(home.Strength, away.Strength)
+	events := e.events(home, away, result.Events, e.cfg.Sport.MatchMinutes)
+	return &ExtraTime{HomeGoals: result.HomeGoals, AwayGoals: result.AwayGoals}, events
+}
+
+// events names the engine's events when the sport tracks them, offset by the
+// minutes played before
+func (e tieEngine) events(home, away Team, played []matchengine.Event, offset int) []MatchEvent {
+	if !e.cfg.Sport.TrackEvents {
+		return nil
+	}
+	events := matchEvents(Match{HomeTeam: home.Name, AwayTeam: away.Name}, played)
+	for i := range events {
+		events[i].Minute += offset
+	}
+	return events
+}
+
+// playTie plays both legs with params and the home side's advantage, then
+// extra time in the second leg and penalties as long as the tie is level.
+// Single legs can end level whatever the sport, the aggregate decides.
+func playTie(e tieEngine, params SimParams, advantages homeAdvantages, awayGoalsRule bool, first, second Team) TieResolution {
+	t := TieResolution{First: first.Name, Second: second.Name, AwayGoalsRule: awayGoalsRule}
+
+	t.Legs[0] = Leg{HomeTeam: first.Name, AwayTeam: second.Name}
+	t.Legs[0].HomeGoals, t.Legs[0].AwayGoals, t.Legs[0].Events = e.play(advantages.params(params, first.Name), first, second)
+	params = advantages.params(params, second.Name)
+	t.Legs[1] = Leg{HomeTeam: second.Name, AwayTeam: first.Name}
+	t.Legs[1].HomeGoals, t.Legs[1].AwayGoals, t.Legs[1].Events = e.play(params, second, first)
+	if t.decide(DecidedByAggregate, DecidedByAwayGoals) {
+		return t
+	}
+
+	var extraEvents []MatchEvent
+	t.Legs[1].ExtraTime, extraEvents = e.extraTime(params, second, first)
+	t.Legs[1].Events = append(t.Legs[1].Events, extraEvents...)
+	if t.decide(DecidedByExtraTime, DecidedByExtraTimeAwayGoals) {
+		return t
+	}
+
+	secondGoals, firstGoals, kicks, winner := penaltyShootout(e.rng, second.Name, first.Name)
+	t.Shootout = &Shootout{FirstGoals: firstGoals, SecondGoals: secondGoals, Kicks: kicks}
+	t.DecidedBy = DecidedByPenalties
+	t.Winner = winner
+	return t
+}
+
+// penaltyShootout plays penalties with the home side kicking first
+func penaltyShootout(rng *rand.Rand, home, away string) (homeGoals, awayGoals int, kicks []PenaltyKick, winner string) {
+	result := matchengine.Shootout(rng, matchengine.DefaultConversion)
+	kicks = []PenaltyKick{}
+	for _, k := range result.Kicks {
+		team := home
+		if k.Side == matchengine.Away {
+			team = away
+		}
+		kicks = append(kicks, PenaltyKick{Team: team, Scored: k.Scored})
+	}
+	winner = home
+	if result.Winner() == matchengine.Away {
+		winner = away
+	}
+	return result.HomeGoals, result.AwayGoals, kicks, winner
+}
+
+// TiePairing names the two teams of a tie, first hosting the first leg
+type TiePairing struct {
+	First  string `json:"first"`
+	Second string `json:"second"`
+}
+
+// SimulateTies plays every pairing with the current simulation settings
+func (l *League) SimulateTies(pairings []TiePairing, awayGoalsRule bool) ([]TieResolution, error) {
+	teams := make(map[string]Team)
+	for _, t := range l.Teams() {
+		teams[t.Name] = t
+	}
+	team := func(name string) (Team, error) {
+		_, current, err := l.resolveTeam(name)
+		if err == sql.ErrNoRows {
+			return Team{}, fmt.Errorf("%w: unknown team %s", ErrInvalidTie, name)
+		}
+		if err != nil {
+			return Team{}, err
+		}
+		// a team that left the league is still known by name
+		t, ok := teams[current]
+		if !ok {
+			return Team{}, fmt.Errorf("%w: %s is no longer in the league", ErrInvalidTie, current)
+		}
+		return t, nil
+	}
+
+	cfg := l.config()
+	advantages, err := l.homeAdvantages()
+	if err != nil {
+		return nil, err
+	}
+	engine := tieEngine{cfg: cfg, rng: rand.New(rand.NewSource(l.predict.Int63())), flavor: rand.New(rand.NewSource(l.predict.Int63()))}
+	ties := make([]TieResolution, 0, len(pairings))
+	for _, p := range pairings {
+		first, err := team(p.First)
+		if err != nil {
+			return nil, err
+		}
+		second, err := team(p.Second)
+		if err != nil {
+			return nil, err
+		}
+		if first.ID == second.ID {
+			return nil, fmt.Errorf("%w: %s cannot play itself", ErrInvalidTie, first.Name)
+		}
+		ties = append(ties, playTie(engine, cfg.Simulation, advantages, awayGoalsRule, first, second))
+	}
+	return ties, nil
+}
+
+// POST /ties/simulate {"ties":[{"first":"Alpha FC","second":"Delta SC"}]}
+// away_goals_rule defaults to the config, seed_by_coefficient reorders the
+// pairings by /alltime/coefficients
+func (l *League) handleSimulateTies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := struct {
+		Ties          []TiePairing `json:"ties"`
+		AwayGoalsRule bool         `json:"away_goals_rule"`
+		// SeedByCoefficient gives the better side by coefficient the
+		// second leg at home
+		SeedByCoefficient bool `json:"seed_by_coefficient"`
+	}{AwayGoalsRule: l.config().AwayGoalsRule}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Ties) == 0 || len(req.Ties) > maxTies {
+		http.Error(w, fmt.Sprintf("between 1 and %d ties are required", maxTies), http.StatusBadRequest)
+		return
+	}
+
+	if req.SeedByCoefficient {
+		seeded, err := l.seedPairings(req.Ties)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		req.Ties = seeded
+	}
+
+	ties, err := l.SimulateTies(req.Ties, req.AwayGoalsRule)
+	switch {
+	case errors.Is(err, ErrInvalidTie):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(ties)
+}
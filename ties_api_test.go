@@ -0,0 +1,42 @@
+package insider_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestSimulateTiesRefusesTeams(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 9)
+	if _, err := h.League.DB().Exec("UPDATE teams SET active = FALSE WHERE name = 'Delta SC'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.League.LoadTeams(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []insider.TiePairing{
+		{First: "Alpha FC", Second: "Nowhere Rovers"},
+		{First: "Delta SC", Second: "Alpha FC"},
+		{First: "Alpha FC", Second: "Alpha FC"},
+	} {
+		if _, err := h.League.SimulateTies([]insider.TiePairing{p}, false); !errors.Is(err, insider.ErrInvalidTie) {
+			t.Errorf("%s against %s: %v, want ErrInvalidTie", p.First, p.Second, err)
+		}
+		body := map[string]any{"ties": []insider.TiePairing{p}}
+		if status := h.Do(http.MethodPost, "/ties/simulate", body, false, nil); status != http.StatusBadRequest {
+			t.Errorf("POST /ties/simulate %s against %s: status %d, want 400", p.First, p.Second, status)
+		}
+	}
+
+	tooMany := make([]insider.TiePairing, insider.MaxTies+1)
+	for i := range tooMany {
+		tooMany[i] = insider.TiePairing{First: "Alpha FC", Second: "Bravo United"}
+	}
+	if status := h.Do(http.MethodPost, "/ties/simulate", map[string]any{"ties": tooMany}, false, nil); status != http.StatusBadRequest {
+		t.Errorf("%d ties: status %d, want 400", len(tooMany), status)
+	}
+}
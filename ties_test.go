@@ -0,0 +1,104 @@
+package insider
+
+import (
+	"testing"
+)
+
+func TestSimulateTies(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, FixtureWeeks(len(snapshotTeams)), 9)
+	pairings := make([]TiePairing, 0, 50)
+	for i := 0; i < 50; i++ {
+		pairings = append(pairings, TiePairing{First: "Alpha FC", Second: "Bravo United"}, TiePairing{First: "Charlie Town", Second: "Delta SC"})
+	}
+
+	for _, awayGoalsRule := range []bool{false, true} {
+		ties, err := l.SimulateTies(pairings, awayGoalsRule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decided := make(map[string]int)
+		for _, tie := range ties {
+			decided[tie.DecidedBy]++
+			first, second := tie.Legs[0], tie.Legs[1]
+			if first.HomeTeam != tie.First || second.HomeTeam != tie.Second || first.ExtraTime != nil {
+				t.Fatalf("legs %+v", tie.Legs)
+			}
+			if tie.Winner != tie.First && tie.Winner != tie.Second {
+				t.Fatalf("winner %q of %s against %s", tie.Winner, tie.First, tie.Second)
+			}
+
+			normal := [2]int{first.HomeGoals + second.AwayGoals, first.AwayGoals + second.HomeGoals}
+			switch tie.DecidedBy {
+			case DecidedByAggregate:
+				if normal[0] == normal[1] || second.ExtraTime != nil {
+					t.Errorf("won on aggregate at %v", normal)
+				}
+			case DecidedByAwayGoals:
+				if !awayGoalsRule || normal[0] != normal[1] || second.AwayGoals == first.AwayGoals {
+					t.Errorf("won on away goals at %v, legs %+v", normal, tie.Legs)
+				}
+			case DecidedByExtraTime, DecidedByExtraTimeAwayGoals:
+				if second.ExtraTime == nil || tie.Shootout != nil {
+					t.Errorf("decided in extra time without it: %+v", tie)
+				}
+			case DecidedByPenalties:
+				if second.ExtraTime == nil || tie.Shootout == nil || tie.Aggregate[0] != tie.Aggregate[1] {
+					t.Errorf("penalties after %v: %+v", tie.Aggregate, tie)
+				}
+				if tie.Shootout.FirstGoals == tie.Shootout.SecondGoals {
+					t.Errorf("shootout ended level")
+				}
+			default:
+				t.Errorf("decided by %q", tie.DecidedBy)
+			}
+			// football tracks events, every goal of the tie has one
+			goals := 0
+			for _, leg := range tie.Legs {
+				for _, e := range leg.Events {
+					if e.Type == EventGoal {
+						goals++
+					}
+				}
+			}
+			if goals != tie.Aggregate[0]+tie.Aggregate[1] {
+				t.Errorf("%d goal events for an aggregate of %v", goals, tie.Aggregate)
+			}
+		}
+		if decided[DecidedByAwayGoals] > 0 && !awayGoalsRule {
+			t.Error("away goals decided a tie without the rule")
+		}
+		if decided[DecidedByAggregate] == 0 || decided[DecidedByPenalties]+decided[DecidedByExtraTime]+decided[DecidedByExtraTimeAwayGoals] == 0 {
+			t.Errorf("100 ties decided %v", decided)
+		}
+	}
+}
+
+func TestTieDecide(t *testing.T) {
+	tie := TieResolution{First: "Alpha FC", Second: "Bravo United", AwayGoalsRule: true}
+	tie.Legs[0] = Leg{HomeTeam: "Alpha FC", AwayTeam: "Bravo United", HomeGoals: 1, AwayGoals: 2}
+	tie.Legs[1] = Leg{HomeTeam: "Bravo United", AwayTeam: "Alpha FC", HomeGoals: 1, AwayGoals: 0}
+	if !tie.decide(DecidedByAggregate, DecidedByAwayGoals) || tie.Winner != "Bravo United" || tie.DecidedBy != DecidedByAggregate {
+		t.Errorf("3-1 on aggregate: %+v", tie)
+	}
+
+	// 3-3 with Alpha FC scoring twice away
+	tie.Legs[0].HomeGoals, tie.Legs[0].AwayGoals = 1, 1
+	tie.Legs[1].HomeGoals, tie.Legs[1].AwayGoals = 2, 2
+	if !tie.decide(DecidedByAggregate, DecidedByAwayGoals) || tie.Winner != "Alpha FC" || tie.DecidedBy != DecidedByAwayGoals {
+		t.Errorf("away goals: %+v", tie)
+	}
+	tie.AwayGoalsRule = false
+	tie.Winner, tie.DecidedBy = "", ""
+	if tie.decide(DecidedByAggregate, DecidedByAwayGoals) {
+		t.Errorf("level tie decided without the away goals rule: %+v", tie)
+	}
+
+	// an away goal in extra time counts double as well
+	tie.AwayGoalsRule = true
+	tie.Legs[0].HomeGoals, tie.Legs[0].AwayGoals = 1, 0
+	tie.Legs[1].HomeGoals, tie.Legs[1].AwayGoals = 1, 0
+	tie.Legs[1].ExtraTime = &ExtraTime{HomeGoals: 1, AwayGoals: 1}
+	if !tie.decide(DecidedByExtraTime, DecidedByExtraTimeAwayGoals) || tie.Winner != "Alpha FC" || tie.DecidedBy != DecidedByExtraTimeAwayGoals {
+		t.Errorf("away goal in extra time: %+v", tie)
+	}
+}
@@ -0,0 +1,135 @@
+package insider
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// WeeklyProbability is a team's title chance once a given week was played
+type WeeklyProbability struct {
+	Week        int     `json:"week"`
+	Probability float64 `json:"probability"`
+}
+
+type TitleContender struct {
+	TeamName         string              `json:"team_name"`
+	Points           int                 `json:"points"`
+	Gap              int                 `json:"gap"`
+	RemainingMatches int                 `json:"remaining_matches"`
+	MaxPoints        int                 `json:"max_points"`
+	TitleProbability float64             `json:"title_probability"`
+	History          []WeeklyProbability `json:"history"`
+}
+
+// HeadToHead is a remaining meeting between two contenders
+type HeadToHead struct {
+	MatchID  int    `json:"match_id"`
+	Week     int    `json:"week"`
+	HomeTeam string `json:"home_team"`
+	AwayTeam string `json:"away_team"`
+}
+
+type TitleRace struct {
+	Leader     string           `json:"leader"`
+	Week       int              `json:"week"`
+	Decided    bool             `json:"decided"`
+	Runs       int              `json:"runs"`
+	Contenders []TitleContender `json:"contenders"`
+	HeadToHead []HeadToHead     `json:"head_to_head"`
+}
+
+// TitleRace lists every team that can still mathematically finish first,
+// with its title probability after each played week
+func (l *League) TitleRace(runs int) (*TitleRace, error) {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return nil, err
+	}
+
+	latest := state.latestWeek()
+	played, remaining := state.split(latest)
+	standings := state.standings(played)
+
+	remainingByTeam := make(map[string]int)
+	for _, m := range remaining {
+		remainingByTeam[m.HomeTeam]++
+		remainingByTeam[m.AwayTeam]++
+	}
+	most, _ := state.sport.pointsLeft(remaining)
+
+	race := &TitleRace{
+		Week:       latest,
+		Runs:       runs,
+		Contenders: []TitleContender{},
+		HeadToHead: []HeadToHead{},
+	}
+	if len(standings) == 0 {
+		return race, nil
+	}
+	leader := standings[0]
+	race.Leader = leader.TeamName
+
+	// A team is still in the race while it can reach the leader's points
+	alive := make(map[string]bool)
+	for _, s := range standings {
+		maxPoints := s.Points + most[s.TeamName]
+		if maxPoints < leader.Points {
+			continue
+		}
+		alive[s.TeamName] = true
+		race.Contenders = append(race.Contenders, TitleContender{
+			TeamName:         s.TeamName,
+			Points:           s.Points,
+			Gap:              leader.Points - s.Points,
+			RemainingMatches: remainingByTeam[s.TeamName],
+			MaxPoints:        maxPoints,
+			History:          []WeeklyProbability{},
+		})
+	}
+	race.Decided = len(race.Contenders) == 1
+
+	for _, m := range remaining {
+		if alive[m.HomeTeam] && alive[m.AwayTeam] {
+			race.HeadToHead = append(race.HeadToHead, HeadToHead{
+				MatchID:  m.ID,
+				Week:     m.Week,
+				HomeTeam: m.HomeTeam,
+				AwayTeam: m.AwayTeam,
+			})
+		}
+	}
+
+	// Replay the season week by week: week 0 is the pre-season estimate
+	params := l.config().Simulation
+	for week := 0; week <= latest; week++ {
+		weekPlayed, weekRemaining := state.split(week)
+		summary := monteCarlo(l.predict, params, state, weekPlayed, weekRemaining, runs)
+		for i := range race.Contenders {
+			c := &race.Contenders[i]
+			c.History = append(c.History, WeeklyProbability{
+				Week:        week,
+				Probability: summary.probability(c.TeamName, 1),
+			})
+			if week == latest {
+				c.TitleProbability = summary.probability(c.TeamName, 1)
+			}
+		}
+	}
+
+	return race, nil
+}
+
+func (l *League) handleTitleRace(w http.ResponseWriter, r *http.Request) {
+	runs, err := runsParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	race, err := l.TitleRace(runs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(race)
+}
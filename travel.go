@@ -0,0 +1,72 @@
+package insider
+
+import (
+	"fmt"
+	"math"
+)
+
+// With coordinates for the teams' cities in the config, the away side loses
+// strength in proportion to how far it travelled. Teams without coordinates
+// never tire, and a zero fatigue rate only shows the distances.
+
+// earthRadiusKm is the mean radius used for great circle distances
+const earthRadiusKm = 6371.0
+
+// Coordinates place a team's city
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Travel is the "travel" block of the config. Cities are keyed by team name.
+type Travel struct {
+	Cities map[string]Coordinates `json:"cities"`
+	// FatiguePer1000Km is the strength the away side loses per 1000 km
+	FatiguePer1000Km float64 `json:"fatigue_per_1000km"`
+	// MaxFatigue caps the loss however long the trip
+	MaxFatigue int `json:"max_fatigue"`
+}
+
+// MatchTravel is the away side's trip, shown in the match detail
+type MatchTravel struct {
+	DistanceKm float64 `json:"distance_km"`
+	Fatigue    int     `json:"fatigue"`
+}
+
+func (t Travel) Validate() error {
+	for team, c := range t.Cities {
+		if c.Lat < -90 || c.Lat > 90 || c.Lon < -180 || c.Lon > 180 {
+			return fmt.Errorf("%s: coordinates %v, %v out of range", team, c.Lat, c.Lon)
+		}
+	}
+	if t.FatiguePer1000Km < 0 || t.MaxFatigue < 0 {
+		return fmt.Errorf("fatigue_per_1000km and max_fatigue cannot be negative")
+	}
+	return nil
+}
+
+// distanceKm is the great circle distance between two cities
+func distanceKm(a, b Coordinates) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat, dLon := rad(b.Lat-a.Lat), rad(b.Lon-a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(a.Lat))*math.Cos(rad(b.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// trip is the away side's travel to a match, nil when either city is unknown
+func (t Travel) trip(homeTeam, awayTeam string) *MatchTravel {
+	home, ok := t.Cities[homeTeam]
+	if !ok {
+		return nil
+	}
+	away, ok := t.Cities[awayTeam]
+	if !ok {
+		return nil
+	}
+	km := distanceKm(away, home)
+	fatigue := int(math.Round(km / 1000 * t.FatiguePer1000Km))
+	if t.MaxFatigue > 0 {
+		fatigue = min(fatigue, t.MaxFatigue)
+	}
+	return &MatchTravel{DistanceKm: math.Round(km*10) / 10, Fatigue: fatigue}
+}
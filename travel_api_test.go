@@ -0,0 +1,30 @@
+package insider_test
+
+import (
+	"fmt"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestTravelInMatchDetail(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 3)
+	cfg := *h.League.Config()
+	cfg.Travel = insider.Travel{
+		Cities: map[string]insider.Coordinates{
+			"Alpha FC": {Lat: 41.01, Lon: 28.98}, "Bravo United": {Lat: 39.93, Lon: 32.86},
+			"Charlie Town": {Lat: 38.42, Lon: 27.14}, "Delta SC": {Lat: 38.49, Lon: 43.38},
+		},
+		FatiguePer1000Km: 10,
+	}
+	h.League.StoreConfig(&cfg)
+
+	m := h.Matches()[0]
+	var detail insider.MatchDetail
+	h.Get(fmt.Sprintf("/matches/%d", m.ID), &detail)
+	want := cfg.Travel.Trip(m.HomeTeam, m.AwayTeam)
+	if detail.Travel == nil || *detail.Travel != *want {
+		t.Errorf("match detail travel %+v, want %+v", detail.Travel, want)
+	}
+}
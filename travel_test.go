@@ -0,0 +1,36 @@
+package insider
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTravelTrip(t *testing.T) {
+	travel := Travel{
+		Cities: map[string]Coordinates{
+			"Istanbul": {Lat: 41.01, Lon: 28.98},
+			"Ankara":   {Lat: 39.93, Lon: 32.86},
+			"Van":      {Lat: 38.49, Lon: 43.38},
+		},
+		FatiguePer1000Km: 10,
+		MaxFatigue:       8,
+	}
+	short := travel.trip("Istanbul", "Ankara")
+	if short == nil || math.Abs(short.DistanceKm-350) > 10 || short.Fatigue != 3 {
+		t.Errorf("Ankara to Istanbul %+v, want about 350 km and 3 fatigue", short)
+	}
+	// the longest trips are capped
+	if long := travel.trip("Istanbul", "Van"); long == nil || long.Fatigue != 8 {
+		t.Errorf("Van to Istanbul %+v, want fatigue capped at 8", long)
+	}
+	if travel.trip("Istanbul", "Nowhere") != nil || travel.trip("Nowhere", "Ankara") != nil {
+		t.Error("trip without both cities")
+	}
+
+	if err := (Travel{Cities: map[string]Coordinates{"North": {Lat: 91}}}).Validate(); err == nil {
+		t.Error("latitude 91 validated")
+	}
+	if err := (Travel{FatiguePer1000Km: -1}).Validate(); err == nil {
+		t.Error("negative fatigue validated")
+	}
+}
@@ -0,0 +1,264 @@
+package insider
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Every request is counted in memory by client and endpoint, in one minute
+// buckets covering the last usageMinutes. A client is the API token the
+// request was made with, or the remote address for everything else.
+// Endpoints are route patterns, so the fixtures of every team count as one.
+// Requests turned away by the token checks, over the rate limit say, count
+// too.
+
+// usageMinutes is how far back usage is kept
+const usageMinutes = 60
+
+// EndpointUsage is what one client asked of one endpoint
+type EndpointUsage struct {
+	Endpoint  string  `json:"endpoint"`
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// ClientUsage sums up one client, busiest endpoint first
+type ClientUsage struct {
+	Client    string          `json:"client"`
+	Requests  int             `json:"requests"`
+	Errors    int             `json:"errors"`
+	ErrorRate float64         `json:"error_rate"`
+	Endpoints []EndpointUsage `json:"endpoints"`
+}
+
+// UsageReport lists the top consumers of a window
+type UsageReport struct {
+	Minutes  int           `json:"minutes"`
+	Requests int           `json:"requests"`
+	Errors   int           `json:"errors"`
+	Clients  []ClientUsage `json:"clients"`
+}
+
+type usageKey struct {
+	client, endpoint string
+}
+
+// usageCount counts requests and the ones answered with 4xx or 5xx
+type usageCount struct {
+	requests, errors int
+}
+
+type usageBucket struct {
+	minute int64
+	counts map[usageKey]*usageCount
+}
+
+// usageTracker is a ring of buckets, one per minute
+type usageTracker struct {
+	mu      sync.Mutex
+	buckets [usageMinutes]usageBucket
+}
+
+func (u *usageTracker) record(client, endpoint string, status int, now time.Time) {
+	minute := now.Unix() / 60
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	b := &u.buckets[minute%usageMinutes]
+	if b.minute != minute || b.counts == nil {
+		*b = usageBucket{minute: minute, counts: make(map[usageKey]*usageCount)}
+	}
+	key := usageKey{client, endpoint}
+	c := b.counts[key]
+	if c == nil {
+		c = &usageCount{}
+		b.counts[key] = c
+	}
+	c.requests++
+	if status >= 400 {
+		c.errors++
+	}
+}
+
+func (u *usageTracker) reset() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.buckets = [usageMinutes]usageBucket{}
+}
+
+func errorRate(errors, requests int) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(errors) / float64(requests)
+}
+
+// report sums the last minutes up to now, top clients by requests; an
+// endpoint other than "" leaves out the rest
+func (u *usageTracker) report(now time.Time, minutes, top int, endpoint string) UsageReport {
+	current := now.Unix() / 60
+	totals := make(map[usageKey]usageCount)
+	u.mu.Lock()
+	for _, b := range u.buckets {
+		if b.counts == nil || b.minute <= current-int64(minutes) || b.minute > current {
+			continue
+		}
+		for key, c := range b.counts {
+			if endpoint != "" && key.endpoint != endpoint {
+				continue
+			}
+			t := totals[key]
+			t.requests += c.requests
+			t.errors += c.errors
+			totals[key] = t
+		}
+	}
+	u.mu.Unlock()
+
+	report := UsageReport{Minutes: minutes, Clients: []ClientUsage{}}
+	clients := make(map[string]*ClientUsage)
+	for key, c := range totals {
+		cu := clients[key.client]
+		if cu == nil {
+			cu = &ClientUsage{Client: key.client}
+			clients[key.client] = cu
+		}
+		cu.Requests += c.requests
+		cu.Errors += c.errors
+		cu.Endpoints = append(cu.Endpoints, EndpointUsage{
+			Endpoint: key.endpoint, Requests: c.requests, Errors: c.errors,
+			ErrorRate: errorRate(c.errors, c.requests),
+		})
+		report.Requests += c.requests
+		report.Errors += c.errors
+	}
+	for _, cu := range clients {
+		cu.ErrorRate = errorRate(cu.Errors, cu.Requests)
+		sort.Slice(cu.Endpoints, func(i, j int) bool {
+			a, b := cu.Endpoints[i], cu.Endpoints[j]
+			if a.Requests != b.Requests {
+				return a.Requests > b.Requests
+			}
+			return a.Endpoint < b.Endpoint
+		})
+		report.Clients = append(report.Clients, *cu)
+	}
+	sort.Slice(report.Clients, func(i, j int) bool {
+		a, b := report.Clients[i], report.Clients[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Client < b.Client
+	})
+	if len(report.Clients) > top {
+		report.Clients = report.Clients[:top]
+	}
+	return report
+}
+
+// usageClient is filled in by apiTokens when the request has a token
+type usageClient struct {
+	name string
+}
+
+type usageClientKey struct{}
+
+// setUsageClient counts the request under name instead of its address
+func setUsageClient(r *http.Request, name string) {
+	if c, ok := r.Context().Value(usageClientKey{}).(*usageClient); ok {
+		c.name = name
+	}
+}
+
+// statusWriter remembers the status a handler answered with
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// trackUsage counts every request passed on to next, under the route mux
+// would pick for it
+func (l *League) trackUsage(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := &usageClient{}
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), usageClientKey{}, client)))
+
+		if client.name == "" {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			client.name = "ip:" + host
+		}
+		_, endpoint := mux.Handler(r)
+		if endpoint == "" {
+			endpoint = "unmatched"
+		}
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		l.usage.record(client.name, endpoint, status, time.Now())
+	})
+}
+
+// GET /admin/usage lists the top consumers of the last hour with their
+// error rates: ?minutes=5 narrows the window, ?endpoint=/predict counts that
+// route alone, ?top=n keeps n clients (default 10). DELETE starts over.
+func (l *League) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		minutes, top := usageMinutes, 10
+		if s := q.Get("minutes"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > usageMinutes {
+				http.Error(w, "minutes must be between 1 and 60", http.StatusBadRequest)
+				return
+			}
+			minutes = n
+		}
+		if s := q.Get("top"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				http.Error(w, "Invalid top parameter", http.StatusBadRequest)
+				return
+			}
+			top = n
+		}
+		json.NewEncoder(w).Encode(l.usage.report(time.Now(), minutes, top, q.Get("endpoint")))
+	case http.MethodDelete:
+		l.usage.reset()
+		json.NewEncoder(w).Encode(map[string]string{"message": "Usage reset"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,57 @@
+package insider_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestUsage(t *testing.T) {
+	h := leaguetest.New(t, nil, 1)
+
+	var tok insider.APIToken
+	if status := h.Do(http.MethodPost, "/admin/tokens", insider.APIToken{Name: "scoreboard", Scopes: []string{insider.ScopeRead}, RateLimit: 2}, true, &tok); status != http.StatusCreated {
+		t.Fatalf("create token: status %d", status)
+	}
+	for i := 0; i < 3; i++ {
+		h.DoWithToken(http.MethodGet, "/standings", nil, tok.Token, nil)
+	}
+	h.Do(http.MethodGet, "/teams/Alpha FC/fixtures", nil, false, nil)
+	h.Do(http.MethodGet, "/teams/Bravo United/fixtures", nil, false, nil)
+
+	var report insider.UsageReport
+	if status := h.Do(http.MethodGet, "/admin/usage?endpoint=/standings", nil, true, &report); status != http.StatusOK {
+		t.Fatalf("usage: status %d", status)
+	}
+	if len(report.Clients) != 1 {
+		t.Fatalf("clients of /standings: %+v", report.Clients)
+	}
+	c := report.Clients[0]
+	if c.Client != "token:"+strconv.Itoa(tok.ID)+":scoreboard" || c.Requests != 3 || c.Errors != 1 {
+		t.Errorf("token usage: %+v", c)
+	}
+
+	if status := h.Do(http.MethodGet, "/admin/usage?top=1", nil, true, &report); status != http.StatusOK {
+		t.Fatalf("usage: status %d", status)
+	}
+	if len(report.Clients) != 1 || report.Clients[0].Client != "ip:127.0.0.1" {
+		t.Fatalf("top client: %+v", report.Clients)
+	}
+	// both fixtures requests go to one route
+	found := false
+	for _, e := range report.Clients[0].Endpoints {
+		if e.Endpoint == "/teams/{name}/fixtures" {
+			found = e.Requests == 2
+		}
+	}
+	if !found {
+		t.Errorf("fixtures not counted under their route: %+v", report.Clients[0].Endpoints)
+	}
+
+	if status := h.Do(http.MethodGet, "/admin/usage?minutes=61", nil, true, nil); status != http.StatusBadRequest {
+		t.Errorf("minutes=61: status %d, want 400", status)
+	}
+}
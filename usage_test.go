@@ -0,0 +1,25 @@
+package insider
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUsageWindow(t *testing.T) {
+	var u usageTracker
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	u.record("ip:a", "/predict", http.StatusOK, start)
+	u.record("ip:a", "/predict", http.StatusInternalServerError, start.Add(30*time.Minute))
+
+	if r := u.report(start.Add(30*time.Minute), 5, 10, ""); r.Requests != 1 || r.Errors != 1 {
+		t.Errorf("last 5 minutes: %+v", r)
+	}
+	if r := u.report(start.Add(30*time.Minute), usageMinutes, 10, ""); r.Requests != 2 || r.Clients[0].ErrorRate != 0.5 {
+		t.Errorf("last hour: %+v", r)
+	}
+	// an hour on, the first request has dropped out
+	if r := u.report(start.Add(time.Hour), usageMinutes, 10, ""); r.Requests != 1 {
+		t.Errorf("an hour later: %+v", r)
+	}
+}
@@ -0,0 +1,202 @@
+package insider
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrUserNameTaken is returned when signing up with a name already in use
+var ErrUserNameTaken = errors.New("user name already in use")
+
+// User is someone following the league. FavoriteTeam is empty until chosen.
+type User struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	FavoriteTeam string `json:"favorite_team,omitempty"`
+	favoriteID   sql.NullInt64
+}
+
+func (l *League) createUserTable() error {
+	createUsers := `
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT UNIQUE,
+		token_hash TEXT UNIQUE,
+		favorite_team_id INTEGER REFERENCES teams(id) ON DELETE SET NULL,
+		created_at TIMESTAMP
+	);`
+
+	if _, err := l.db.Exec(createUsers); err != nil {
+		return fmt.Errorf("error creating users table: %v", err)
+	}
+	return nil
+}
+
+// only a hash of the token is stored, the user keeps the token itself
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateUser signs a user up and returns the bearer token they authenticate
+// with. The token is not stored and cannot be shown again.
+func (l *League) CreateUser(name string) (*User, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("name cannot be empty")
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	var taken int
+	if err := l.db.QueryRow("SELECT COUNT(*) FROM users WHERE name = ?", name).Scan(&taken); err != nil {
+		return nil, "", err
+	}
+	if taken > 0 {
+		return nil, "", fmt.Errorf("%w: %s", ErrUserNameTaken, name)
+	}
+
+	res, err := l.db.Exec("INSERT INTO users (name, token_hash, created_at) VALUES (?, ?, ?)", name, hashToken(token), time.Now().UTC())
+	if err != nil {
+		return nil, "", err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, "", err
+	}
+	return &User{ID: int(id), Name: name}, token, nil
+}
+
+// currentUser finds the user of the "Authorization: Bearer <token>" header,
+// nil when there is none or the token is unknown
+func (l *League) currentUser(r *http.Request) (*User, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, nil
+	}
+
+	var u User
+	var favorite sql.NullString
+	err := l.db.QueryRow(`
+		SELECT u.id, u.name, u.favorite_team_id, t.name
+		FROM users u
+		LEFT JOIN teams t ON t.id = u.favorite_team_id
+		WHERE u.token_hash = ?`, hashToken(token)).Scan(&u.ID, &u.Name, &u.favoriteID, &favorite)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.FavoriteTeam = favorite.String
+	return &u, nil
+}
+
+// requireUser writes a 401 and returns nil when the request is not signed in
+func (l *League) requireUser(w http.ResponseWriter, r *http.Request) *User {
+	user, err := l.currentUser(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil
+	}
+	if user == nil {
+		http.Error(w, "User token required", http.StatusUnauthorized)
+		return nil
+	}
+	return user
+}
+
+// SetFavoriteTeam accepts a current or former team name
+func (l *League) SetFavoriteTeam(user *User, team string) error {
+	id, current, err := l.resolveTeam(team)
+	if err != nil {
+		return err
+	}
+	if _, err := l.db.Exec("UPDATE users SET favorite_team_id = ? WHERE id = ?", id, user.ID); err != nil {
+		return err
+	}
+	user.favoriteID = sql.NullInt64{Int64: int64(id), Valid: true}
+	user.FavoriteTeam = current
+	return nil
+}
+
+// POST /users with {"name": "..."} signs up and returns the token once
+func (l *League) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, token, err := l.CreateUser(body.Name)
+	if errors.Is(err, ErrUserNameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":  user,
+		"token": token,
+	})
+}
+
+func (l *League) handleMe(w http.ResponseWriter, r *http.Request) {
+	if user := l.requireUser(w, r); user != nil {
+		json.NewEncoder(w).Encode(user)
+	}
+}
+
+// POST /me/favorite with {"team": "..."}
+func (l *League) handleFavorite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := l.requireUser(w, r)
+	if user == nil {
+		return
+	}
+
+	var body struct {
+		Team string `json:"team"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := l.SetFavoriteTeam(user, body.Team)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}
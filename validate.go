@@ -0,0 +1,144 @@
+package insider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+const (
+	ProblemDuplicate      = "duplicate"
+	ProblemDoubleBooked   = "double_booked"
+	ProblemMissingReverse = "missing_reverse"
+	ProblemMissingFixture = "missing_fixture"
+	ProblemOverfullWeek   = "overfull_week"
+)
+
+// FixtureProblem is one thing wrong with the stored schedule
+type FixtureProblem struct {
+	Type     string   `json:"type"`
+	Week     int      `json:"week,omitempty"`
+	MatchIDs []int    `json:"match_ids,omitempty"`
+	Teams    []string `json:"teams"`
+	Detail   string   `json:"detail"`
+}
+
+type FixtureReport struct {
+	Valid    bool             `json:"valid"`
+	Matches  int              `json:"matches"`
+	Problems []FixtureProblem `json:"problems"`
+}
+
+// ValidateFixture checks the schedule for a double round robin: every pair
+// meets once at each ground, nobody plays twice in a week and no week holds
+// more matches than the teams allow. Postponed matches have no week, so they
+// only count for the pairings.
+func (l *League) ValidateFixture() (*FixtureReport, error) {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return nil, err
+	}
+	report := &FixtureReport{Matches: len(state.matches), Problems: []FixtureProblem{}}
+	add := func(p FixtureProblem) {
+		report.Problems = append(report.Problems, p)
+	}
+
+	// each home/away pairing once
+	pairings := make(map[[2]string][]int)
+	for _, m := range state.matches {
+		key := [2]string{m.HomeTeam, m.AwayTeam}
+		pairings[key] = append(pairings[key], m.ID)
+	}
+	for i, home := range state.teams {
+		for j, away := range state.teams {
+			if i == j {
+				continue
+			}
+			ids := pairings[[2]string{home, away}]
+			reverse := pairings[[2]string{away, home}]
+			switch {
+			case len(ids) > 1:
+				add(FixtureProblem{
+					Type:     ProblemDuplicate,
+					MatchIDs: ids,
+					Teams:    []string{home, away},
+					Detail:   fmt.Sprintf("%s host %s %d times", home, away, len(ids)),
+				})
+			case len(ids) == 1 && len(reverse) == 0:
+				add(FixtureProblem{
+					Type:     ProblemMissingReverse,
+					MatchIDs: ids,
+					Teams:    []string{home, away},
+					Detail:   fmt.Sprintf("%s host %s but never play at %s", home, away, away),
+				})
+			case len(ids) == 0 && len(reverse) == 0 && i < j:
+				add(FixtureProblem{
+					Type:   ProblemMissingFixture,
+					Teams:  []string{home, away},
+					Detail: fmt.Sprintf("%s and %s never meet", home, away),
+				})
+			}
+		}
+	}
+
+	// per week: how many matches and who plays in them
+	weekMatches := make(map[int][]Match)
+	for _, m := range state.matches {
+		if !m.Postponed {
+			weekMatches[m.Week] = append(weekMatches[m.Week], m)
+		}
+	}
+	weeks := make([]int, 0, len(weekMatches))
+	for week := range weekMatches {
+		weeks = append(weeks, week)
+	}
+	sort.Ints(weeks)
+
+	perWeek := len(state.teams) / 2
+	for _, week := range weeks {
+		matches := weekMatches[week]
+		if len(matches) > perWeek {
+			ids := make([]int, 0, len(matches))
+			for _, m := range matches {
+				ids = append(ids, m.ID)
+			}
+			add(FixtureProblem{
+				Type:     ProblemOverfullWeek,
+				Week:     week,
+				MatchIDs: ids,
+				Teams:    []string{},
+				Detail:   fmt.Sprintf("%d matches, at most %d fit", len(matches), perWeek),
+			})
+		}
+
+		byTeam := make(map[string][]int)
+		for _, m := range matches {
+			byTeam[m.HomeTeam] = append(byTeam[m.HomeTeam], m.ID)
+			byTeam[m.AwayTeam] = append(byTeam[m.AwayTeam], m.ID)
+		}
+		for _, team := range state.teams {
+			if ids := byTeam[team]; len(ids) > 1 {
+				add(FixtureProblem{
+					Type:     ProblemDoubleBooked,
+					Week:     week,
+					MatchIDs: ids,
+					Teams:    []string{team},
+					Detail:   fmt.Sprintf("%s play %d times in week %d", team, len(ids), week),
+				})
+			}
+		}
+	}
+
+	report.Valid = len(report.Problems) == 0
+	return report, nil
+}
+
+func (l *League) handleValidateFixture(w http.ResponseWriter, r *http.Request) {
+	report, err := l.ValidateFixture()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,181 @@
+package insider
+
+import (
+	"math/rand"
+	"slices"
+)
+
+// A Monte Carlo prediction after every result would play the whole rest of
+// the season runs times over. The runs are kept instead: the score every
+// remaining match got in each run, and what those scores add up to per team
+// and run. When a result comes in, that match is taken back out of every run
+// and only matches whose odds or script changed are drawn again; a final
+// table is the new base table plus the kept totals, which costs a run per
+// team rather than per match. Matches are drawn independently of each other, so the
+// runs left are still fair draws of what is left of the season.
+
+// runTotal is what the remaining matches of one run add to a team
+type runTotal struct {
+	points, goalDifference, goalsFor, goalsAgainst, wins int32
+}
+
+// warmMatch is a remaining match with its score in every run, drawn with
+// params and the strengths as they were then
+type warmMatch struct {
+	week                       int
+	home, away                 int
+	homeStrength, awayStrength int
+	params                     SimParams
+	script                     MatchScript
+	// scores holds home and away goals, two per run
+	scores []uint16
+}
+
+// warmRuns are the runs of the latest prediction, valid for one config, run
+// count and list of teams
+type warmRuns struct {
+	cfg   *Config
+	runs  int
+	teams []string
+	index map[string]int
+	// matches by id
+	matches map[int]*warmMatch
+	// totals[run*len(teams)+team] sums the team's matches in that run
+	totals []runTotal
+	// homePoints and awayPoints sum every run, by team
+	homePoints, awayPoints []int
+}
+
+func newWarmRuns(cfg *Config, runs int, teams []string) *warmRuns {
+	w := &warmRuns{
+		cfg:        cfg,
+		runs:       runs,
+		teams:      teams,
+		index:      make(map[string]int, len(teams)),
+		matches:    make(map[int]*warmMatch),
+		totals:     make([]runTotal, runs*len(teams)),
+		homePoints: make([]int, len(teams)),
+		awayPoints: make([]int, len(teams)),
+	}
+	for i, name := range teams {
+		w.index[name] = i
+	}
+	return w
+}
+
+// fits tells whether the runs can be carried over to a prediction
+func (w *warmRuns) fits(cfg *Config, runs int, teams []string) bool {
+	return w != nil && w.cfg == cfg && w.runs == runs && slices.Equal(w.teams, teams)
+}
+
+// add counts the scores of a match into every run, or takes them out again
+// with sign -1
+func (w *warmRuns) add(sport Sport, m *warmMatch, sign int) {
+	n := len(w.teams)
+	for run := 0; run < w.runs; run++ {
+		homeGoals, awayGoals := int(m.scores[2*run]), int(m.scores[2*run+1])
+		homePoints, awayPoints := sport.matchPoints(m.week, homeGoals, awayGoals), sport.matchPoints(m.week, awayGoals, homeGoals)
+
+		home, away := &w.totals[run*n+m.home], &w.totals[run*n+m.away]
+		home.points += int32(sign * homePoints)
+		home.goalDifference += int32(sign * (homeGoals - awayGoals))
+		home.goalsFor += int32(sign * homeGoals)
+		home.goalsAgainst += int32(sign * awayGoals)
+		away.points += int32(sign * awayPoints)
+		away.goalDifference += int32(sign * (awayGoals - homeGoals))
+		away.goalsFor += int32(sign * awayGoals)
+		away.goalsAgainst += int32(sign * homeGoals)
+		switch {
+		case homeGoals > awayGoals:
+			home.wins += int32(sign)
+		case awayGoals > homeGoals:
+			away.wins += int32(sign)
+		}
+
+		w.homePoints[m.home] += sign * homePoints
+		w.awayPoints[m.away] += sign * awayPoints
+	}
+}
+
+// sameParams compares the numbers of two params. The simulator is left out:
+// it may be a value Go cannot compare, and the runs are dropped anyway when
+// the config, and with it the simulator, changes.
+func sameParams(a, b SimParams) bool {
+	a.Simulator, b.Simulator = nil, nil
+	return a == b
+}
+
+// update brings the runs in line with the remaining matches: played ones
+// and ones whose odds or script changed are taken out, new ones drawn. It returns how
+// many remaining matches kept their scores.
+func (w *warmRuns) update(rng *rand.Rand, params SimParams, state *seasonState, remaining []Match) int {
+	current := make(map[int]warmMatch, len(remaining))
+	for _, m := range remaining {
+		homeStrength, awayStrength := state.matchStrengths(m)
+		current[m.ID] = warmMatch{
+			week:         m.Week,
+			home:         w.index[m.HomeTeam],
+			away:         w.index[m.AwayTeam],
+			homeStrength: homeStrength,
+			awayStrength: awayStrength,
+			params:       state.home.params(params, m.HomeTeam),
+			script:       state.scripts[m.ID],
+		}
+	}
+
+	kept := 0
+	for id, m := range w.matches {
+		if c, ok := current[id]; ok && c.week == m.week && c.home == m.home && c.away == m.away &&
+			c.homeStrength == m.homeStrength && c.awayStrength == m.awayStrength && sameParams(c.params, m.params) && c.script.same(m.script) {
+			kept++
+			continue
+		}
+		w.add(state.sport, m, -1)
+		delete(w.matches, id)
+	}
+
+	for _, r := range remaining {
+		if w.matches[r.ID] != nil {
+			continue
+		}
+		m := current[r.ID]
+		m.scores = make([]uint16, 2*w.runs)
+		for run := 0; run < w.runs; run++ {
+			homeGoals, awayGoals := m.params.Score(rng, m.homeStrength, m.awayStrength)
+			homeGoals, awayGoals = m.script.apply(homeGoals, awayGoals, m.params.Overtime)
+			m.scores[2*run], m.scores[2*run+1] = uint16(homeGoals), uint16(awayGoals)
+		}
+		w.add(state.sport, &m, 1)
+		w.matches[r.ID] = &m
+	}
+	return kept
+}
+
+// summary puts the kept totals on top of the base table, run by run
+func (w *warmRuns) summary(base []Standing) *simulationSummary {
+	summary := newSimulationSummary(w.teams)
+	n := len(w.teams)
+	table := make([]Standing, len(base))
+	for run := 0; run < w.runs; run++ {
+		copy(table, base)
+		for i := range table {
+			t := w.totals[run*n+w.index[table[i].TeamName]]
+			table[i].Points += int(t.points)
+			table[i].GoalDifference += int(t.goalDifference)
+			table[i].GoalsFor += int(t.goalsFor)
+			table[i].GoalsAgainst += int(t.goalsAgainst)
+			table[i].Wins += int(t.wins)
+		}
+		w.cfg.Sport.sortStandings(table)
+		for i, st := range table {
+			summary.Positions[st.TeamName][i]++
+			summary.Points[st.TeamName] += st.Points
+		}
+		summary.Runs++
+	}
+	for i, name := range w.teams {
+		summary.HomePoints[name] = w.homePoints[i]
+		summary.AwayPoints[name] = w.awayPoints[i]
+	}
+	return summary
+}
@@ -0,0 +1,42 @@
+package insider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var webhookClient = &http.Client{Timeout: 5 * time.Second}
+
+// WebhookPayload is the body posted to every configured webhook, see
+// eventSchemas for what data holds
+type WebhookPayload struct {
+	EventType     string      `json:"event_type"`
+	SchemaVersion int         `json:"schema_version"`
+	Data          interface{} `json:"data"`
+	// Event repeats event_type for consumers written before versioning
+	Event string `json:"event"`
+}
+
+// notifyWebhooks queues an event for the webhooks of the current config,
+// see outbox.go for the delivery
+func (l *League) notifyWebhooks(eventType string, data interface{}) {
+	targets := l.config().Webhooks
+	if len(targets) == 0 {
+		return
+	}
+	body, err := json.Marshal(WebhookPayload{
+		EventType:     eventType,
+		SchemaVersion: schemaVersion(eventType),
+		Data:          data,
+		Event:         eventType,
+	})
+	if err != nil {
+		fmt.Println("Webhook payload failed:", err)
+		return
+	}
+	if err := l.enqueue(eventType, body, targets); err != nil {
+		fmt.Println("Webhook enqueue failed:", err)
+	}
+}
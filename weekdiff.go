@@ -0,0 +1,128 @@
+package insider
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+)
+
+// ErrWeekNotFound is returned for a week without matches
+var ErrWeekNotFound = errors.New("week not found")
+
+// TeamWeekDiff is what one week did to a team. Rank is after the week,
+// RankChange is positive for places climbed. The probability changes come
+// from Monte Carlo runs before and after the week.
+type TeamWeekDiff struct {
+	Team             string   `json:"team"`
+	Rank             int      `json:"rank"`
+	RankChange       int      `json:"rank_change"`
+	Points           int      `json:"pts"`
+	PointsChange     int      `json:"pts_change"`
+	GoalDiffChange   int      `json:"gd_change"`
+	TitleChange      float64  `json:"title_change"`
+	RelegationChange *float64 `json:"relegation_change,omitempty"`
+}
+
+// WeekDiff is GET /weeks/{n}/diff, teams in the table order after the week
+type WeekDiff struct {
+	Week   int            `json:"week"`
+	Played int            `json:"played"`
+	Runs   int            `json:"runs"`
+	Teams  []TeamWeekDiff `json:"teams"`
+	// UpsetOfTheWeek is the win the favourites saw least coming
+	UpsetOfTheWeek *MatchSurprise `json:"upset_of_the_week,omitempty"`
+}
+
+// WeekDiff compares the league after week with the league before it, taking
+// results up to and including each week. Both predictions draw from the same
+// seed so the probability changes come from the results, not from sampling,
+// and the seed follows from the league's so a week diffs the same every time.
+func (l *League) WeekDiff(week, runs int) (*WeekDiff, error) {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &WeekDiff{Week: week, Runs: runs, Teams: []TeamWeekDiff{}}
+	scheduled := false
+	for _, m := range state.matches {
+		if m.Week == week {
+			scheduled = true
+			if m.Played {
+				diff.Played++
+			}
+		}
+	}
+	if !scheduled {
+		return nil, ErrWeekNotFound
+	}
+
+	playedBefore, remainingBefore := state.split(week - 1)
+	playedAfter, remainingAfter := state.split(week)
+	seed := l.predictionSeed(int64(week))
+	params := l.config().Simulation
+	summaryBefore := monteCarlo(rand.New(rand.NewSource(seed)), params, state, playedBefore, remainingBefore, runs)
+	summaryAfter := monteCarlo(rand.New(rand.NewSource(seed)), params, state, playedAfter, remainingAfter, runs)
+
+	before := make(map[string]Standing)
+	for _, s := range state.standings(playedBefore) {
+		before[s.TeamName] = s
+	}
+	relegation := l.relegationZone()
+	zoneProbability := func(summary *simulationSummary, team string) float64 {
+		p := 0.0
+		for pos := relegation.From; pos <= relegation.To && pos <= len(state.teams); pos++ {
+			p += summary.probability(team, pos)
+		}
+		return p
+	}
+
+	for _, s := range state.standings(playedAfter) {
+		b := before[s.TeamName]
+		d := TeamWeekDiff{
+			Team:           s.TeamName,
+			Rank:           s.Rank,
+			RankChange:     b.Rank - s.Rank,
+			Points:         s.Points,
+			PointsChange:   s.Points - b.Points,
+			GoalDiffChange: s.GoalDifference - b.GoalDifference,
+			TitleChange:    summaryAfter.probability(s.TeamName, 1) - summaryBefore.probability(s.TeamName, 1),
+		}
+		if relegation != nil {
+			change := zoneProbability(summaryAfter, s.TeamName) - zoneProbability(summaryBefore, s.TeamName)
+			d.RelegationChange = &change
+		}
+		diff.Teams = append(diff.Teams, d)
+	}
+	if diff.UpsetOfTheWeek, err = l.upsetOfTheWeek(week); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// GET /weeks/{n}/diff?runs=n
+func (l *League) handleWeekDiff(w http.ResponseWriter, r *http.Request) {
+	week, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil {
+		http.Error(w, "Invalid week", http.StatusBadRequest)
+		return
+	}
+	runs, err := runsParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	diff, err := l.WeekDiff(week, runs)
+	switch {
+	case errors.Is(err, ErrWeekNotFound):
+		http.Error(w, "Week not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(diff)
+}
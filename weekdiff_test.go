@@ -0,0 +1,117 @@
+package insider_test
+
+import (
+	"math"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestWeekDiff(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 11)
+	cfg := *h.League.Config()
+	cfg.Zones = []insider.Zone{{Name: "relegation", From: 4, To: 4}}
+	h.League.StoreConfig(&cfg)
+
+	insider.PlayByStrength(t, h.League, 1, 1)
+	before := make(map[string]insider.Standing)
+	for _, s := range h.Standings() {
+		before[s.TeamName] = s
+	}
+
+	// week 2 goes against the form: the weaker side wins 3-0
+	strength := make(map[string]int)
+	for _, team := range h.League.Teams() {
+		strength[team.Name] = team.Strength
+	}
+	won := make(map[string]bool)
+	for _, m := range h.Matches() {
+		if m.Week != 2 {
+			continue
+		}
+		home, away := 3, 0
+		if strength[m.HomeTeam] > strength[m.AwayTeam] {
+			home, away = 0, 3
+		}
+		won[m.HomeTeam], won[m.AwayTeam] = home > away, away > home
+		if err := h.League.UpdateMatchResult(m.ID, home, away, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after := make(map[string]insider.Standing)
+	for _, s := range h.Standings() {
+		after[s.TeamName] = s
+	}
+
+	var diff insider.WeekDiff
+	h.Get("/weeks/2/diff?runs=300", &diff)
+	if diff.Week != 2 || diff.Played != 2 || diff.Runs != 300 || len(diff.Teams) != len(insider.SnapshotTeams) {
+		t.Fatalf("diff %+v", diff)
+	}
+	title, relegation := 0.0, 0.0
+	for i, d := range diff.Teams {
+		b, a := before[d.Team], after[d.Team]
+		if d.Rank != i+1 || d.Rank != a.Rank || d.RankChange != b.Rank-a.Rank {
+			t.Errorf("%s rank %d, change %d; table has %d, was %d", d.Team, d.Rank, d.RankChange, a.Rank, b.Rank)
+		}
+		points, goalDiff := 0, -3
+		if won[d.Team] {
+			points, goalDiff = 3, 3
+		}
+		if d.Points != a.Points || d.PointsChange != points || d.GoalDiffChange != goalDiff {
+			t.Errorf("%s pts %d, change %d, gd change %d; want %d, %d, %d", d.Team, d.Points, d.PointsChange, d.GoalDiffChange, a.Points, points, goalDiff)
+		}
+		if d.RelegationChange == nil {
+			t.Fatalf("%s has no relegation change with a relegation zone", d.Team)
+		}
+		title += d.TitleChange
+		relegation += *d.RelegationChange
+	}
+	// every run crowns one team and relegates one, before and after alike
+	if math.Abs(title) > 1e-9 || math.Abs(relegation) > 1e-9 {
+		t.Errorf("title changes add up to %g, relegation changes to %g", title, relegation)
+	}
+
+	// an unplayed week changes nothing, and the shared seed keeps sampling
+	// noise out of the probabilities
+	h.Get("/weeks/3/diff?runs=300", &diff)
+	for _, d := range diff.Teams {
+		if d.RankChange != 0 || d.PointsChange != 0 || d.GoalDiffChange != 0 || d.TitleChange != 0 || *d.RelegationChange != 0 {
+			t.Errorf("unplayed week changed %+v", d)
+		}
+	}
+	if status := h.Do(http.MethodGet, "/weeks/99/diff", nil, false, nil); status != http.StatusNotFound {
+		t.Errorf("GET /weeks/99/diff: status %d, want 404", status)
+	}
+}
+
+func TestPollingPredictionsKeepsTheResults(t *testing.T) {
+	quiet, polled := leaguetest.New(t, insider.SnapshotTeams, 8), leaguetest.New(t, insider.SnapshotTeams, 8)
+	weeks := insider.FixtureWeeks(len(insider.SnapshotTeams))
+
+	var first, again insider.WeekDiff
+	for week := 1; week <= weeks; week++ {
+		quiet.SimulateWeek(week)
+		polled.SimulateWeek(week)
+
+		// every read-only prediction of the league, none may touch the results
+		polled.Get("/weeks/1/diff", &first)
+		polled.Get("/weeks/1/diff", &again)
+		polled.Get("/predict", nil)
+		polled.Get("/predict?mode=montecarlo&runs=50", nil)
+		polled.Get("/titlerace?runs=20", nil)
+		if !reflect.DeepEqual(first.Teams, again.Teams) {
+			t.Errorf("week 1 diffed twice after week %d: %+v, then %+v", week, first.Teams, again.Teams)
+		}
+	}
+
+	want, got := quiet.Matches(), polled.Matches()
+	for i := range want {
+		if want[i].HomeGoals != got[i].HomeGoals || want[i].AwayGoals != got[i].AwayGoals {
+			t.Errorf("match %d: %d-%d, polled between weeks %d-%d", want[i].ID, want[i].HomeGoals, want[i].AwayGoals, got[i].HomeGoals, got[i].AwayGoals)
+		}
+	}
+}
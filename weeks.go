@@ -0,0 +1,154 @@
+package insider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ErrInvalidHalf is returned for a half of the season other than 1 or 2
+var ErrInvalidHalf = errors.New("invalid half")
+
+// WeekMatches is one week of the fixture. A week is complete once every
+// match in it has been played; a postponed one keeps it open.
+type WeekMatches struct {
+	IsComplete bool    `json:"is_complete"`
+	Matches    []Match `json:"matches"`
+}
+
+// MatchesByWeek groups the whole fixture by week
+func (l *League) MatchesByWeek() (map[int]*WeekMatches, error) {
+	matches, err := l.Matches()
+	if err != nil {
+		return nil, err
+	}
+
+	weeks := make(map[int]*WeekMatches)
+	for _, m := range matches {
+		week := weeks[m.Week]
+		if week == nil {
+			week = &WeekMatches{IsComplete: true}
+			weeks[m.Week] = week
+		}
+		week.Matches = append(week.Matches, m)
+		week.IsComplete = week.IsComplete && m.Played
+	}
+	return weeks, nil
+}
+
+// WeekSummary describes one week of the season. Generated weeks are the ones
+// the season is laid out over, scheduled ones have matches; a week can be
+// generated and empty, or hold rescheduled matches past the last generated
+// week. Dates are the kickoff days, from the virtual clock for matches
+// without a kickoff of their own when the clock runs.
+type WeekSummary struct {
+	Week       int      `json:"week"`
+	Generated  bool     `json:"generated"`
+	Scheduled  bool     `json:"scheduled"`
+	Matches    int      `json:"matches"`
+	Played     int      `json:"played"`
+	Postponed  int      `json:"postponed"`
+	Live       int      `json:"live"`
+	IsComplete bool     `json:"is_complete"`
+	Dates      []string `json:"dates"`
+}
+
+// Weeks summarizes every week in order
+func (l *League) Weeks() ([]WeekSummary, error) {
+	matches, err := l.Matches()
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[int]*WeekSummary)
+	week := func(n int) *WeekSummary {
+		if summaries[n] == nil {
+			summaries[n] = &WeekSummary{Week: n, Generated: n >= 1 && n <= l.weeks, Dates: []string{}}
+		}
+		return summaries[n]
+	}
+	for n := 1; n <= l.weeks; n++ {
+		week(n)
+	}
+
+	dates := make(map[int]map[string]bool)
+	for _, m := range matches {
+		w := week(m.Week)
+		w.Scheduled = true
+		w.Matches++
+		switch {
+		case m.Played:
+			w.Played++
+		case m.Postponed:
+			w.Postponed++
+		case m.Live:
+			w.Live++
+		}
+
+		kickoff := m.Kickoff
+		if kickoff == "" && l.virtual != nil {
+			kickoff = l.virtual.kickoff(m).Format(time.RFC3339)
+		}
+		if len(kickoff) >= len(dateLayout) {
+			if dates[m.Week] == nil {
+				dates[m.Week] = make(map[string]bool)
+			}
+			dates[m.Week][kickoff[:len(dateLayout)]] = true
+		}
+	}
+
+	weeks := make([]WeekSummary, 0, len(summaries))
+	for n, w := range summaries {
+		w.IsComplete = w.Played == w.Matches
+		for d := range dates[n] {
+			w.Dates = append(w.Dates, d)
+		}
+		sort.Strings(w.Dates)
+		weeks = append(weeks, *w)
+	}
+	sort.Slice(weeks, func(i, j int) bool { return weeks[i].Week < weeks[j].Week })
+	return weeks, nil
+}
+
+// HalfStandings is the table of one half of the season only: the first half
+// is the generated weeks up to the middle, the second half everything after,
+// matches rescheduled past the last generated week included
+func (l *League) HalfStandings(half int) ([]Standing, error) {
+	if half != 1 && half != 2 {
+		return nil, fmt.Errorf("%w: %d, expected 1 or 2", ErrInvalidHalf, half)
+	}
+	matches, err := l.Matches()
+	if err != nil {
+		return nil, err
+	}
+	var inHalf []Match
+	for _, m := range matches {
+		if (m.Week <= l.weeks/2) == (half == 1) {
+			inHalf = append(inHalf, m)
+		}
+	}
+	return l.standingsFrom(inHalf, false), nil
+}
+
+// GET /weeks
+func (l *League) handleWeeks(w http.ResponseWriter, r *http.Request) {
+	weeks, err := l.Weeks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(weeks)
+}
+
+// GET /matches/by-week, keyed by week number
+func (l *League) handleMatchesByWeek(w http.ResponseWriter, r *http.Request) {
+	weeks, err := l.MatchesByWeek()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(weeks)
+}
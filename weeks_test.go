@@ -0,0 +1,68 @@
+package insider_test
+
+import (
+	"net/http"
+	"testing"
+
+	"insider"
+	"insider/leaguetest"
+)
+
+func TestHalfStandings(t *testing.T) {
+	h := leaguetest.New(t, insider.SnapshotTeams, 5)
+	// the stronger side wins the first half and the weaker one, which has
+	// the later name, the second
+	insider.PlayByStrength(t, h.League, 1, 3)
+	for _, m := range h.Matches() {
+		if m.Week < 4 {
+			continue
+		}
+		home, away := 0, 2
+		if m.HomeTeam > m.AwayTeam {
+			home, away = 2, 0
+		}
+		if err := h.League.UpdateMatchResult(m.ID, home, away, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	points := func(table []insider.Standing) map[string]int {
+		byTeam := make(map[string]int)
+		for _, s := range table {
+			if s.Played != 3 {
+				t.Errorf("%s played %d in a half, want 3", s.TeamName, s.Played)
+			}
+			byTeam[s.TeamName] = s.Points
+		}
+		return byTeam
+	}
+
+	var first, second, season []insider.Standing
+	h.Get("/standings?half=1", &first)
+	h.Get("/standings?half=2", &second)
+	h.Get("/standings", &season)
+	if first[0].TeamName != "Alpha FC" || first[len(first)-1].TeamName != "Delta SC" {
+		t.Errorf("first half led by %s with %s last, want Alpha FC and Delta SC", first[0].TeamName, first[len(first)-1].TeamName)
+	}
+	if second[0].TeamName != "Delta SC" || second[len(second)-1].TeamName != "Alpha FC" {
+		t.Errorf("second half led by %s with %s last, want Delta SC and Alpha FC", second[0].TeamName, second[len(second)-1].TeamName)
+	}
+	firstPoints, secondPoints := points(first), points(second)
+	for _, s := range season {
+		if s.Points != firstPoints[s.TeamName]+secondPoints[s.TeamName] {
+			t.Errorf("%s has %d points, %d and %d in the halves", s.TeamName, s.Points, firstPoints[s.TeamName], secondPoints[s.TeamName])
+		}
+	}
+
+	var fields []map[string]any
+	h.Get("/standings?half=2&fields=team_name,points", &fields)
+	if len(fields) != len(second) || len(fields[0]) != 2 || fields[0]["team_name"] != "Delta SC" {
+		t.Errorf("second half with fields %v", fields)
+	}
+
+	for _, half := range []string{"0", "3", "first"} {
+		if status := h.Do(http.MethodGet, "/standings?half="+half, nil, false, nil); status != http.StatusBadRequest {
+			t.Errorf("half=%s: status %d, want %d", half, status, http.StatusBadRequest)
+		}
+	}
+}
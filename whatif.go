@@ -0,0 +1,444 @@
+package insider
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// maxWhatIfMatches caps the open matches the requirement search will try
+// every outcome of
+const maxWhatIfMatches = 12
+
+// maxWhatIfSteps caps the scenarios one search may look at, partial ones
+// included. The bounds prune most of them, but a season where everyone is
+// still level can need far more than the cap on matches suggests.
+const maxWhatIfSteps = 2_000_000
+
+// ErrInvalidTarget is returned for a position outside the table
+var ErrInvalidTarget = errors.New("invalid target position")
+
+// ErrTooManyOpenMatches is returned when too much of the season is still
+// open for an exhaustive search
+var ErrTooManyOpenMatches = fmt.Errorf("more than %d matches still matter, try again later in the season", maxWhatIfMatches)
+
+// ErrSearchTooLong is returned when the search runs out of steps
+var ErrSearchTooLong = fmt.Errorf("no answer within %d scenarios, try again later in the season", maxWhatIfSteps)
+
+// match outcomes, as bits so a condition can allow several
+const (
+	outcomeHomeWin = 1 << iota
+	outcomeDraw
+	outcomeAwayWin
+)
+
+// Condition is one thing that has to happen in a remaining match
+type Condition struct {
+	MatchID  int      `json:"match_id"`
+	Week     int      `json:"week"`
+	HomeTeam string   `json:"home_team"`
+	AwayTeam string   `json:"away_team"`
+	Outcomes []string `json:"outcomes"`
+	Text     string   `json:"text"`
+}
+
+// Requirements answers what a team needs to finish at or above a position
+type Requirements struct {
+	Team     string `json:"team"`
+	Target   int    `json:"target"`
+	Possible bool   `json:"possible"`
+	// Secured is true when the target is reached whatever happens
+	Secured bool `json:"secured"`
+	// OnTiebreak is set when the target can only be reached level on points,
+	// so goal difference will decide
+	OnTiebreak bool        `json:"on_tiebreak"`
+	Conditions []Condition `json:"conditions"`
+}
+
+// whatIf is the search over the matches that can still change the team's
+// place relative to its rivals. Everything else is settled: surelyAbove
+// teams finish ahead whatever happens, the rest behind.
+type whatIf struct {
+	sport   Sport
+	team    string
+	target  int
+	matches []Match
+	base    map[string]int
+	// contested teams can still end up on either side of the team
+	contested   map[string]bool
+	surelyAbove int
+	// level counts a rival on the same points as behind
+	level bool
+	// most[i] and fewest[i] are the points left per team after the first i
+	// matches are decided
+	most, fewest []map[string]int
+	// steps counts the scenarios looked at, against maxWhatIfSteps
+	steps int
+}
+
+func newWhatIf(sport Sport, team string, target int, base map[string]int) *whatIf {
+	return &whatIf{sport: sport, team: team, target: target, base: base, contested: make(map[string]bool)}
+}
+
+// prepare works out the points left after every match, once the matches
+// are known
+func (w *whatIf) prepare() {
+	w.most = make([]map[string]int, len(w.matches)+1)
+	w.fewest = make([]map[string]int, len(w.matches)+1)
+	for i := range w.most {
+		w.most[i], w.fewest[i] = w.sport.pointsLeft(w.matches[i:])
+	}
+}
+
+// exhausted counts a step and tells whether the budget is used up. Once it
+// is, the searches unwind with meaningless answers the caller throws away.
+func (w *whatIf) exhausted() bool {
+	w.steps++
+	return w.steps > maxWhatIfSteps
+}
+
+// points adds the outcomes of the first n matches to the base points of the
+// team and its rivals
+func (w *whatIf) points(outcomes []int, n int) map[string]int {
+	points := make(map[string]int, len(w.contested)+1)
+	points[w.team] = w.base[w.team]
+	for name := range w.contested {
+		points[name] = w.base[name]
+	}
+	for i, m := range w.matches[:n] {
+		k := w.sport.multiplier(m.Week)
+		switch outcomes[i] {
+		case outcomeHomeWin:
+			points[m.HomeTeam] += k * w.sport.WinPoints
+			points[m.AwayTeam] += k * w.sport.LossPoints
+		case outcomeDraw:
+			points[m.HomeTeam] += k * w.sport.DrawPoints
+			points[m.AwayTeam] += k * w.sport.DrawPoints
+		case outcomeAwayWin:
+			points[m.HomeTeam] += k * w.sport.LossPoints
+			points[m.AwayTeam] += k * w.sport.WinPoints
+		}
+	}
+	return points
+}
+
+// ahead counts the teams finishing above the team on these points
+func (w *whatIf) ahead(points map[string]int) int {
+	ahead := w.surelyAbove
+	for name := range w.contested {
+		if points[name] > points[w.team] || (points[name] == points[w.team] && !w.level) {
+			ahead++
+		}
+	}
+	return ahead
+}
+
+// reaches tells whether the team makes the target with these outcomes
+func (w *whatIf) reaches(outcomes []int) bool {
+	return w.ahead(w.points(outcomes, len(w.matches))) < w.target
+}
+
+// bounds looks at the first n outcomes only. The team is sure of the target
+// if it makes it taking the fewest points left while every rival takes the
+// most, and out of reach if it misses it the other way round.
+func (w *whatIf) bounds(outcomes []int, n int) (sure, outOfReach bool) {
+	points := w.points(outcomes, n)
+	worst := make(map[string]int, len(points))
+	best := make(map[string]int, len(points))
+	for name, p := range points {
+		if name == w.team {
+			worst[name], best[name] = p+w.fewest[n][name], p+w.most[n][name]
+		} else {
+			worst[name], best[name] = p+w.most[n][name], p+w.fewest[n][name]
+		}
+	}
+	return w.ahead(worst) < w.target, w.ahead(best) >= w.target
+}
+
+// guaranteed checks every combination of the allowed outcomes
+func (w *whatIf) guaranteed(allowed []int) bool {
+	outcomes := make([]int, len(w.matches))
+	var try func(i int) bool
+	try = func(i int) bool {
+		if w.exhausted() {
+			return false
+		}
+		if i == len(w.matches) {
+			return w.reaches(outcomes)
+		}
+		if sure, outOfReach := w.bounds(outcomes, i); sure || outOfReach {
+			return sure
+		}
+		for _, o := range []int{outcomeHomeWin, outcomeDraw, outcomeAwayWin} {
+			if allowed[i]&o == 0 {
+				continue
+			}
+			outcomes[i] = o
+			if !try(i + 1) {
+				return false
+			}
+		}
+		return true
+	}
+	return try(0)
+}
+
+// preference orders a match's outcomes from best to worst for the team:
+// its own wins first, then its rivals dropping points
+func (w *whatIf) preference(m Match) []int {
+	switch {
+	case m.HomeTeam == w.team:
+		return []int{outcomeHomeWin, outcomeDraw, outcomeAwayWin}
+	case m.AwayTeam == w.team:
+		return []int{outcomeAwayWin, outcomeDraw, outcomeHomeWin}
+	case w.contested[m.HomeTeam] && w.contested[m.AwayTeam]:
+		return []int{outcomeDraw, outcomeHomeWin, outcomeAwayWin}
+	case w.contested[m.HomeTeam]:
+		return []int{outcomeAwayWin, outcomeDraw, outcomeHomeWin}
+	}
+	return []int{outcomeHomeWin, outcomeDraw, outcomeAwayWin}
+}
+
+// first is the most preferred of the possible outcomes of a match
+func (w *whatIf) first(m Match, possible int) int {
+	for _, o := range w.preference(m) {
+		if possible&o != 0 {
+			return o
+		}
+	}
+	return 0
+}
+
+// bestScenario is the first scenario in order of preference that reaches
+// the target, nil if none does
+func (w *whatIf) bestScenario(possible int) []int {
+	outcomes := make([]int, len(w.matches))
+	var try func(i int) bool
+	try = func(i int) bool {
+		if w.exhausted() {
+			return false
+		}
+		if i == len(w.matches) {
+			return w.reaches(outcomes)
+		}
+		sure, outOfReach := w.bounds(outcomes, i)
+		if outOfReach {
+			return false
+		}
+		if sure {
+			// every way on works, so the most preferred one is the answer
+			for j := i; j < len(w.matches); j++ {
+				outcomes[j] = w.first(w.matches[j], possible)
+			}
+			return true
+		}
+		for _, o := range w.preference(w.matches[i]) {
+			if possible&o == 0 {
+				continue
+			}
+			outcomes[i] = o
+			if try(i + 1) {
+				return true
+			}
+		}
+		return false
+	}
+	if !try(0) {
+		return nil
+	}
+	return outcomes
+}
+
+// requirements relaxes the best scenario one match at a time, rivals' matches
+// first, until no condition can be loosened without losing the guarantee.
+// The result is minimal in that sense, not necessarily the fewest conditions.
+func (w *whatIf) requirements(possible int) []int {
+	allowed := w.bestScenario(possible)
+	if allowed == nil {
+		return nil
+	}
+
+	order := make([]int, 0, len(w.matches))
+	for _, own := range []bool{false, true} {
+		for i, m := range w.matches {
+			if (m.HomeTeam == w.team || m.AwayTeam == w.team) == own {
+				order = append(order, i)
+			}
+		}
+	}
+	for _, i := range order {
+		chosen := allowed[i]
+		allowed[i] = possible
+		if w.guaranteed(allowed) {
+			continue
+		}
+		// allow the next best outcome as well, e.g. "must not lose"
+		allowed[i] = chosen
+		for _, o := range w.preference(w.matches[i]) {
+			if o&possible == 0 || o&chosen != 0 {
+				continue
+			}
+			allowed[i] = chosen | o
+			if !w.guaranteed(allowed) {
+				allowed[i] = chosen
+			}
+			break
+		}
+	}
+	return allowed
+}
+
+// RequiredResults finds what has to happen in the remaining matches for a
+// team to finish at or above target. Only points count: when the target can
+// only be reached level with a rival, OnTiebreak is set.
+func (l *League) RequiredResults(teamName string, target int) (*Requirements, error) {
+	state, err := l.loadSeasonState()
+	if err != nil {
+		return nil, err
+	}
+	if target < 1 || target > len(state.teams) {
+		return nil, fmt.Errorf("%w: must be between 1 and %d", ErrInvalidTarget, len(state.teams))
+	}
+
+	played, remaining := state.current()
+	base := make(map[string]int)
+	for _, s := range state.standings(played) {
+		base[s.TeamName] = s.Points
+	}
+	most, fewest := state.sport.pointsLeft(remaining)
+	maxPoints := func(team string) int {
+		return base[team] + most[team]
+	}
+	minPoints := func(team string) int {
+		return base[team] + fewest[team]
+	}
+
+	w := newWhatIf(state.sport, teamName, target, base)
+	for _, other := range state.teams {
+		switch {
+		case other == teamName:
+		case minPoints(other) > maxPoints(teamName):
+			w.surelyAbove++
+		case maxPoints(other) < minPoints(teamName):
+		default:
+			w.contested[other] = true
+		}
+	}
+	for _, m := range remaining {
+		if m.HomeTeam == teamName || m.AwayTeam == teamName || w.contested[m.HomeTeam] || w.contested[m.AwayTeam] {
+			w.matches = append(w.matches, m)
+		}
+	}
+	if len(w.matches) > maxWhatIfMatches {
+		return nil, ErrTooManyOpenMatches
+	}
+	w.prepare()
+
+	possible := outcomeHomeWin | outcomeDraw | outcomeAwayWin
+	// level games go to overtime, there are no draws left to hope for
+	if l.config().Simulation.Overtime {
+		possible &^= outcomeDraw
+	}
+
+	req := &Requirements{Team: teamName, Target: target, Conditions: []Condition{}}
+	allowed := w.requirements(possible)
+	if allowed == nil {
+		w.level = true
+		req.OnTiebreak = true
+		allowed = w.requirements(possible)
+	}
+	if w.steps > maxWhatIfSteps {
+		return nil, ErrSearchTooLong
+	}
+	if allowed == nil {
+		req.OnTiebreak = false
+		return req, nil
+	}
+
+	req.Possible = true
+	for i, m := range w.matches {
+		if allowed[i] == possible {
+			continue
+		}
+		req.Conditions = append(req.Conditions, condition(m, allowed[i], possible, teamName))
+	}
+	req.Secured = len(req.Conditions) == 0
+	return req, nil
+}
+
+// condition describes the allowed outcomes of a match in words
+func condition(m Match, allowed, possible int, team string) Condition {
+	c := Condition{MatchID: m.ID, Week: m.Week, HomeTeam: m.HomeTeam, AwayTeam: m.AwayTeam, Outcomes: []string{}}
+	names := map[int]string{outcomeHomeWin: ResultHomeWin, outcomeDraw: ResultDraw, outcomeAwayWin: ResultAwayWin}
+	for _, o := range []int{outcomeHomeWin, outcomeDraw, outcomeAwayWin} {
+		if allowed&o != 0 {
+			c.Outcomes = append(c.Outcomes, names[o])
+		}
+	}
+
+	// the team's own matches are told from its side
+	first, second, win, loss := m.HomeTeam, m.AwayTeam, outcomeHomeWin, outcomeAwayWin
+	if m.AwayTeam == team {
+		first, second, win, loss = m.AwayTeam, m.HomeTeam, outcomeAwayWin, outcomeHomeWin
+	}
+	switch allowed {
+	case win:
+		c.Text = fmt.Sprintf("%s must beat %s", first, second)
+	case loss:
+		c.Text = fmt.Sprintf("%s must beat %s", second, first)
+	case outcomeDraw:
+		c.Text = fmt.Sprintf("%s and %s must draw", first, second)
+	case possible &^ loss:
+		c.Text = fmt.Sprintf("%s must not lose to %s", first, second)
+	case possible &^ win:
+		c.Text = fmt.Sprintf("%s must not lose to %s", second, first)
+	case possible &^ outcomeDraw:
+		c.Text = fmt.Sprintf("%s and %s must not draw", first, second)
+	}
+	c.Text += fmt.Sprintf(" (week %d)", m.Week)
+	return c
+}
+
+// GET /whatif/requirements?team=Charlie Town&target=1
+func (l *League) handleRequirements(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("team")
+	if name == "" {
+		http.Error(w, "team is required", http.StatusBadRequest)
+		return
+	}
+	_, team, err := l.resolveTeam(name)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	target := 1
+	if t := r.URL.Query().Get("target"); t != "" {
+		target, err = strconv.Atoi(t)
+		if err != nil {
+			http.Error(w, "Invalid target", http.StatusBadRequest)
+			return
+		}
+	}
+
+	req, err := l.RequiredResults(team, target)
+	switch {
+	case errors.Is(err, ErrInvalidTarget):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, ErrTooManyOpenMatches), errors.Is(err, ErrSearchTooLong):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(req)
+}
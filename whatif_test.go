@@ -0,0 +1,137 @@
+package insider
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRequiredResults(t *testing.T) {
+	// the stronger side wins every match 2-0 up to week played
+	tests := []struct {
+		name       string
+		played     int
+		team       string
+		target     int
+		possible   bool
+		secured    bool
+		onTiebreak bool
+		conditions []string
+	}{
+		{"clinched", 4, "Alpha FC", 2, true, true, false, nil},
+		{"second place clinched", 5, "Bravo United", 2, true, true, false, nil},
+		{"out of reach", 4, "Delta SC", 2, false, false, false, nil},
+		{"out of reach on the last day", 5, "Charlie Town", 2, false, false, false, nil},
+		{"own result", 4, "Alpha FC", 1, true, false, false, []string{"Alpha FC must beat Bravo United (week 6)"}},
+		{"must not lose", 5, "Alpha FC", 1, true, false, false, []string{"Alpha FC must not lose to Bravo United (week 6)"}},
+		{"only level on points", 5, "Bravo United", 1, true, false, true, []string{"Bravo United must beat Alpha FC (week 6)"}},
+		{"rivals must drop points", 4, "Bravo United", 1, true, false, false, []string{
+			"Alpha FC and Charlie Town must draw (week 5)",
+			"Bravo United must beat Delta SC (week 5)",
+			"Bravo United must beat Alpha FC (week 6)",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLeague(t, snapshotTeams, FixtureWeeks(len(snapshotTeams)), 1)
+			playByStrength(t, l, 1, tt.played)
+
+			req, err := l.RequiredResults(tt.team, tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Possible != tt.possible || req.Secured != tt.secured || req.OnTiebreak != tt.onTiebreak {
+				t.Errorf("possible %v, secured %v, on tiebreak %v; want %v, %v, %v",
+					req.Possible, req.Secured, req.OnTiebreak, tt.possible, tt.secured, tt.onTiebreak)
+			}
+			var texts []string
+			for _, c := range req.Conditions {
+				texts = append(texts, c.Text)
+			}
+			if !slices.Equal(texts, tt.conditions) {
+				t.Errorf("conditions %q, want %q", texts, tt.conditions)
+			}
+		})
+	}
+
+	l := newTestLeague(t, snapshotTeams, FixtureWeeks(len(snapshotTeams)), 1)
+	if _, err := l.RequiredResults("Alpha FC", 5); err == nil {
+		t.Error("target below the last place accepted")
+	}
+}
+
+// TestRequiredResultsExhaustive checks the pruned search against every
+// ending of a season that is still wide open
+func TestRequiredResultsExhaustive(t *testing.T) {
+	l := newTestLeague(t, snapshotTeams, FixtureWeeks(len(snapshotTeams)), 1)
+	playByStrength(t, l, 1, 2)
+	state, err := l.loadSeasonState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	played, remaining := state.current()
+	base := make(map[string]int)
+	for _, s := range state.standings(played) {
+		base[s.TeamName] = s.Points
+	}
+	sport := state.sport
+
+	// every ending with the points of each team
+	var endings []map[string]int
+	outcomes := make([]int, len(remaining))
+	var enumerate func(i int)
+	enumerate = func(i int) {
+		if i == len(remaining) {
+			points := make(map[string]int)
+			for name, p := range base {
+				points[name] = p
+			}
+			for j, m := range remaining {
+				home, away := sport.LossPoints, sport.WinPoints
+				switch outcomes[j] {
+				case outcomeHomeWin:
+					home, away = sport.WinPoints, sport.LossPoints
+				case outcomeDraw:
+					home, away = sport.DrawPoints, sport.DrawPoints
+				}
+				points[m.HomeTeam] += home
+				points[m.AwayTeam] += away
+			}
+			endings = append(endings, points)
+			return
+		}
+		for _, o := range []int{outcomeHomeWin, outcomeDraw, outcomeAwayWin} {
+			outcomes[i] = o
+			enumerate(i + 1)
+		}
+	}
+	enumerate(0)
+	// rank counts the teams ahead, level ones too unless level is set
+	rank := func(points map[string]int, team string, level bool) int {
+		ahead := 0
+		for name, p := range points {
+			if name != team && (p > points[team] || p == points[team] && !level) {
+				ahead++
+			}
+		}
+		return ahead + 1
+	}
+
+	for _, team := range state.teams {
+		for target := 1; target <= len(state.teams); target++ {
+			req, err := l.RequiredResults(team, target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outright, level, secured := false, false, true
+			for _, points := range endings {
+				outright = outright || rank(points, team, false) <= target
+				level = level || rank(points, team, true) <= target
+				secured = secured && rank(points, team, false) <= target
+			}
+			if req.Possible != level || req.OnTiebreak != (level && !outright) || req.Secured != secured {
+				t.Errorf("%s to finish %d: %+v; possible %v, outright %v, secured %v",
+					team, target, req, level, outright, secured)
+			}
+		}
+	}
+}